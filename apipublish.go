@@ -0,0 +1,462 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Publish methods.
+const (
+	publishMethodNpm = "npm"
+	publishMethodAPI = "api"
+)
+
+// packMtime is the fixed modification time npm gives tarball entries, making
+// tarballs reproducible.
+var packMtime = time.Date(1985, time.October, 26, 8, 15, 0, 0, time.UTC)
+
+// alwaysIgnoredDirs are never packed, whatever files or .npmignore say.
+var alwaysIgnoredDirs = map[string]bool{".git": true, "node_modules": true, ".svn": true, ".hg": true, "CVS": true}
+
+// alwaysIgnoredFiles are never packed.
+var alwaysIgnoredFiles = regexp.MustCompile(`^(\.npmrc|package-lock\.json|\.DS_Store|npm-debug\.log|\.lock-wscript|config\.gypi|\.wafpickle-\d+|.*\.orig|\..*\.swp)$`)
+
+// alwaysIncludedFiles are packed from the package root even when files does
+// not list them.
+var alwaysIncludedFiles = regexp.MustCompile(`(?i)^(package\.json|readme(\..*)?|licen[cs]e(\..*)?)$`)
+
+// apiIncompatibleOptions returns the configured options that still run npm
+// and so cannot be combined with publish_method api.
+func apiIncompatibleOptions(cfg *Config) []string {
+	var names []string
+	for _, o := range []struct {
+		name string
+		set  bool
+	}{
+		{"lock", cfg.Lock},
+		{"binaries", len(cfg.Binaries.Platforms) > 0},
+		{"graduate_tags", cfg.GraduateTags},
+		{"major_tag", cfg.MajorTag != ""},
+		{"readme_badge", cfg.ReadmeBadge != ""},
+		{"bundled_deps", cfg.BundledDeps != ""},
+		{"test_registry", cfg.TestRegistry},
+		{"end_of_life", cfg.EndOfLife.Enabled || cfg.SupersededBy != ""},
+		{"provenance", cfg.Provenance},
+		{"types_package", cfg.TypesPackage.enabled()},
+	} {
+		if o.set {
+			names = append(names, o.name)
+		}
+	}
+	return names
+}
+
+// ignoreRule is one line of a .npmignore or files list.
+type ignoreRule struct {
+	re     *regexp.Regexp
+	negate bool
+}
+
+// compileIgnoreRules compiles gitignore-style patterns. Patterns without a
+// slash match at any depth; a leading slash anchors to the package root.
+func compileIgnoreRules(lines []string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		negate := strings.HasPrefix(line, "!")
+		line = strings.TrimSuffix(strings.TrimPrefix(line, "!"), "/")
+		if strings.HasPrefix(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		re, err := compileGlob(line)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, ignoreRule{re: re, negate: negate})
+	}
+	return rules
+}
+
+// matchRules reports whether rel matches the rules: the last rule matching
+// the path itself decides, otherwise the nearest parent directory a rule
+// matches.
+func matchRules(rules []ignoreRule, rel string) bool {
+	for p := rel; p != "."; p = path.Dir(p) {
+		decided, matched := false, false
+		for _, r := range rules {
+			if r.re.MatchString(p) {
+				decided, matched = true, !r.negate
+			}
+		}
+		if decided {
+			return matched
+		}
+	}
+	return false
+}
+
+// readIgnoreFile returns the lines of the package's .npmignore, falling back
+// to .gitignore as npm does.
+func readIgnoreFile(packageDir string) []string {
+	for _, name := range []string{".npmignore", ".gitignore"} {
+		if data, err := os.ReadFile(filepath.Join(packageDir, name)); err == nil {
+			return append(strings.Split(string(data), "\n"), ".npmignore", ".gitignore")
+		}
+	}
+	return nil
+}
+
+// collectPackFiles selects the files npm would pack from packageDir: the
+// files list when the manifest has one, otherwise everything not excluded by
+// the root .npmignore (or .gitignore). Paths are slash-separated and sorted.
+func collectPackFiles(packageDir string, filesList []string) ([]string, error) {
+	// files entries are relative to the package root
+	anchored := make([]string, len(filesList))
+	for i, f := range filesList {
+		negate := strings.HasPrefix(f, "!")
+		f = "/" + strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(f, "!"), "./"), "/")
+		if negate {
+			f = "!" + f
+		}
+		anchored[i] = f
+	}
+	include := compileIgnoreRules(anchored)
+	ignore := compileIgnoreRules(readIgnoreFile(packageDir))
+
+	var files []string
+	err := filepath.WalkDir(packageDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(packageDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel != "." && alwaysIgnoredDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || alwaysIgnoredFiles.MatchString(d.Name()) {
+			return nil
+		}
+		switch {
+		case !strings.Contains(rel, "/") && alwaysIncludedFiles.MatchString(rel):
+		case filesList != nil:
+			if !matchRules(include, rel) {
+				return nil
+			}
+		case matchRules(ignore, rel):
+			return nil
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect package files: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// readManifestFiles returns the manifest's files list, or nil without one.
+func readManifestFiles(manifest map[string]any) []string {
+	raw, ok := manifest["files"].([]any)
+	if !ok {
+		return nil
+	}
+	files := []string{}
+	for _, f := range raw {
+		if s, ok := f.(string); ok {
+			files = append(files, s)
+		}
+	}
+	return files
+}
+
+// apiPackFiles lists the files publish_method api packs, in the form of npm
+// pack --json.
+func apiPackFiles(packageDir string) ([]packFile, error) {
+	manifest, err := readManifest(packageDir)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := collectPackFiles(packageDir, readManifestFiles(manifest))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]packFile, 0, len(paths))
+	for _, rel := range paths {
+		info, err := os.Stat(filepath.Join(packageDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, packFile{Path: rel, Size: info.Size(), Mode: int(packMode(info.Mode()))})
+	}
+	return files, nil
+}
+
+// packMode returns the tarball mode npm uses: 0755 for executables, else 0644.
+func packMode(mode fs.FileMode) int64 {
+	if mode&0111 != 0 {
+		return 0755
+	}
+	return 0644
+}
+
+// readManifest reads package.json as a generic document, keeping every field
+// for the published version manifest.
+func readManifest(packageDir string) (map[string]any, error) {
+	data, err := os.ReadFile(filepath.Join(packageDir, "package.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	var manifest map[string]any
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+	return manifest, nil
+}
+
+// tarballFilename returns npm's tarball name: "@scope/pkg" at 1.0.0 becomes
+// "scope-pkg-1.0.0.tgz".
+func tarballFilename(name, version string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(name, "@"), "/", "-") + "-" + version + ".tgz"
+}
+
+// buildTarball packs files from packageDir into a gzipped tarball under the
+// "package/" prefix, reproducibly.
+func buildTarball(packageDir string, files []string) ([]byte, []packFile, int64, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	packed := make([]packFile, 0, len(files))
+	var unpacked int64
+	for _, rel := range files {
+		data, err := os.ReadFile(filepath.Join(packageDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		info, err := os.Stat(filepath.Join(packageDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		mode := packMode(info.Mode())
+		hdr := &tar.Header{
+			Name:     "package/" + rel,
+			Mode:     mode,
+			Size:     int64(len(data)),
+			ModTime:  packMtime,
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to write tarball: %w", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to write tarball: %w", err)
+		}
+		packed = append(packed, packFile{Path: rel, Size: int64(len(data)), Mode: int(mode)})
+		unpacked += int64(len(data))
+	}
+	if err := tw.Close(); err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to write tarball: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to write tarball: %w", err)
+	}
+	return buf.Bytes(), packed, unpacked, nil
+}
+
+// tarballManifest reads package/package.json from a packed tarball.
+func tarballManifest(tarball []byte) (map[string]any, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(tarball))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tarball: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("tarball has no package/package.json")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tarball: %w", err)
+		}
+		if hdr.Name != "package/package.json" {
+			continue
+		}
+		var manifest map[string]any
+		if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse package.json in tarball: %w", err)
+		}
+		return manifest, nil
+	}
+}
+
+// apiTarball returns the tarball publish_method api uploads and the manifest
+// it holds: the input tarball from an earlier plugin as-is, otherwise a pack
+// of packageDir, also written to pack_destination when configured.
+func apiTarball(cfg *Config, packageDir string) ([]byte, map[string]any, publishResult, error) {
+	var result publishResult
+	if cfg.inputTarball != "" {
+		data, err := os.ReadFile(cfg.inputTarball)
+		if err != nil {
+			return nil, nil, result, fmt.Errorf("failed to read tarball: %w", err)
+		}
+		manifest, err := tarballManifest(data)
+		if err != nil {
+			return nil, nil, result, err
+		}
+		result.Filename = filepath.Base(cfg.inputTarball)
+		return data, manifest, result, nil
+	}
+
+	manifest, err := readManifest(packageDir)
+	if err != nil {
+		return nil, nil, result, err
+	}
+	paths, err := collectPackFiles(packageDir, readManifestFiles(manifest))
+	if err != nil {
+		return nil, nil, result, err
+	}
+	data, files, unpacked, err := buildTarball(packageDir, paths)
+	if err != nil {
+		return nil, nil, result, err
+	}
+	name, _ := manifest["name"].(string)
+	version, _ := manifest["version"].(string)
+	result = publishResult{
+		Filename:     tarballFilename(name, version),
+		UnpackedSize: unpacked,
+		Files:        files,
+	}
+	if cfg.PackDestination != "" {
+		if err := os.MkdirAll(cfg.PackDestination, 0755); err != nil {
+			return nil, nil, result, fmt.Errorf("failed to create pack destination: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(cfg.PackDestination, result.Filename), data, 0644); err != nil {
+			return nil, nil, result, fmt.Errorf("failed to write tarball: %w", err)
+		}
+	}
+	return data, manifest, result, nil
+}
+
+// apiAuthToken returns the token publish_method api authenticates with: a
+// token minted for the publish (trusted publishing, token exchange) or the
+// test registry's, then the SSO token, then the ephemeral npmrc token, else
+// NPM_TOKEN.
+func apiAuthToken(cfg *Config) string {
+	if cfg.authToken != "" {
+		return cfg.authToken
+	}
+	if token := cfg.SSO.token(); token != "" {
+		return token
+	}
+	if token := cfg.EphemeralNpmrc.token(); token != "" {
+		return token
+	}
+	return registryToken()
+}
+
+// publishViaAPI packs the package and PUTs it to the registry's publish
+// endpoint, as npm publish does, without needing npm. Lifecycle scripts do
+// not run. It returns the result in the shape of npm publish --json.
+func publishViaAPI(ctx context.Context, cfg *Config, packageDir string) (publishResult, error) {
+	tarball, manifest, result, err := apiTarball(cfg, packageDir)
+	if err != nil {
+		return result, err
+	}
+	name, _ := manifest["name"].(string)
+	version, _ := manifest["version"].(string)
+	if name == "" || version == "" {
+		return result, fmt.Errorf("package.json needs a name and version")
+	}
+
+	sha1Sum := sha1.Sum(tarball)
+	result.Name = name
+	result.Version = version
+	result.Size = int64(len(tarball))
+	result.Shasum = hex.EncodeToString(sha1Sum[:])
+	result.Integrity = tarballIntegrity(tarball)
+
+	registry := publishRegistry(cfg)
+	if registry == "" {
+		registry = defaultRegistry
+	}
+	manifest["_id"] = name + "@" + version
+	manifest["dist"] = map[string]any{
+		"integrity": result.Integrity,
+		"shasum":    result.Shasum,
+		"tarball":   strings.TrimSuffix(registry, "/") + "/" + name + "/-/" + result.Filename,
+	}
+	doc := map[string]any{
+		"_id":         name,
+		"name":        name,
+		"description": manifest["description"],
+		"dist-tags":   map[string]string{cfg.Tag: version},
+		"versions":    map[string]any{version: manifest},
+		"_attachments": map[string]any{
+			result.Filename: map[string]any{
+				"content_type": "application/octet-stream",
+				"data":         base64.StdEncoding.EncodeToString(tarball),
+				"length":       len(tarball),
+			},
+		},
+	}
+	if cfg.Access != "" {
+		doc["access"] = cfg.Access
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal publish document: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, packumentURL(registry, name), bytes.NewReader(body))
+	if err != nil {
+		return result, fmt.Errorf("failed to create publish request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("npm-command", "publish")
+	setAPIAuth(req, cfg)
+	if otp := otpArgs(cfg); len(otp) == 2 {
+		req.Header.Set("npm-otp", otp[1])
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return result, fmt.Errorf("publish request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return result, &httpStatusError{
+			StatusCode: resp.StatusCode,
+			msg:        fmt.Sprintf("registry returned %d publishing %s@%s: %s", resp.StatusCode, name, version, strings.TrimSpace(string(msg))),
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestCollectPackFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"package.json", "README.md", "LICENSE", "index.js", "dist/index.js", "dist/index.d.ts",
+		"src/index.ts", "test/index.test.js", "node_modules/dep/index.js", ".npmrc", "docs/guide.md",
+	} {
+		writeFile(t, filepath.Join(dir, name), "x")
+	}
+
+	got, err := collectPackFiles(dir, []string{"dist", "!dist/*.d.ts", "index.js"})
+	if err != nil {
+		t.Fatalf("collectPackFiles() error = %v", err)
+	}
+	want := []string{"LICENSE", "README.md", "dist/index.js", "index.js", "package.json"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("files list: got %v, want %v", got, want)
+	}
+
+	writeFile(t, filepath.Join(dir, ".npmignore"), "# sources\nsrc/\ntest\n*.md\n!README.md\n")
+	got, err = collectPackFiles(dir, nil)
+	if err != nil {
+		t.Fatalf("collectPackFiles() error = %v", err)
+	}
+	want = []string{"LICENSE", "README.md", "dist/index.d.ts", "dist/index.js", "index.js", "package.json"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(".npmignore: got %v, want %v", got, want)
+	}
+}
+
+func TestTarballFilename(t *testing.T) {
+	if got := tarballFilename("@acme/lib", "1.2.3"); got != "acme-lib-1.2.3.tgz" {
+		t.Errorf("tarballFilename() = %q", got)
+	}
+	if got := tarballFilename("lib", "1.0.0-beta.1"); got != "lib-1.0.0-beta.1.tgz" {
+		t.Errorf("tarballFilename() = %q", got)
+	}
+}
+
+func TestValidateConfigPublishMethod(t *testing.T) {
+	p := &NpmPlugin{}
+	cfg := p.parseConfig(map[string]any{"publish_method": "api", "lock": true, "major_tag": "latest-{{.Major}}"})
+	err := p.validateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "lock, major_tag") {
+		t.Errorf("validateConfig() error = %v", err)
+	}
+	if err := p.validateConfig(p.parseConfig(map[string]any{"publish_method": "api"})); err != nil {
+		t.Errorf("validateConfig() error = %v", err)
+	}
+}
+
+func TestAPIAuthToken(t *testing.T) {
+	t.Setenv("NPM_TOKEN", "npm_env")
+	t.Setenv("SSO_TOKEN", "")
+	cfg := &Config{authArgs: []string{"--//npm.example.com/:_authToken=npm_argv"}}
+	if got := apiAuthToken(cfg); got != "npm_env" {
+		t.Errorf("apiAuthToken() = %q, want NPM_TOKEN rather than a token read back from npm flags", got)
+	}
+	cfg.EphemeralNpmrc = EphemeralNpmrc{Enabled: true, Token: "npm_npmrc"}
+	if got := apiAuthToken(cfg); got != "npm_npmrc" {
+		t.Errorf("apiAuthToken() = %q, want the ephemeral npmrc token", got)
+	}
+	t.Setenv("SSO_TOKEN", "npm_sso")
+	cfg.SSO = SSO{TokenEnv: "SSO_TOKEN"}
+	if got := apiAuthToken(cfg); got != "npm_sso" {
+		t.Errorf("apiAuthToken() = %q, want the SSO token", got)
+	}
+	cfg.authToken = "npm_minted"
+	if got := apiAuthToken(cfg); got != "npm_minted" {
+		t.Errorf("apiAuthToken() = %q, want the minted token", got)
+	}
+}
+
+func TestPublishViaAPI(t *testing.T) {
+	var doc map[string]any
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.EscapedPath() != "/@acme%2Flib" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+		}
+		header = r.Header
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &doc)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(server.Close)
+
+	// No npm on PATH: the api method must not need it
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("NPM_TOKEN", "secret")
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"@acme/lib","version":"1.2.0","description":"A lib","files":["lib"]}`)
+	writeFile(t, filepath.Join(dir, "lib", "index.js"), "module.exports = 1\n")
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"publish_method":   "api",
+			"registry":         server.URL,
+			"tag":              "next",
+			"access":           "public",
+			"pack_destination": "out",
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("Authorization = %q", header.Get("Authorization"))
+	}
+	if doc["access"] != "public" || !reflect.DeepEqual(doc["dist-tags"], map[string]any{"next": "1.2.0"}) {
+		t.Errorf("document = %v", doc)
+	}
+	version := doc["versions"].(map[string]any)["1.2.0"].(map[string]any)
+	dist := version["dist"].(map[string]any)
+	if dist["integrity"] != resp.Outputs["integrity"] || dist["tarball"] != server.URL+"/@acme/lib/-/acme-lib-1.2.0.tgz" {
+		t.Errorf("dist = %v, outputs = %v", dist, resp.Outputs)
+	}
+
+	attachment := doc["_attachments"].(map[string]any)["acme-lib-1.2.0.tgz"].(map[string]any)
+	tarball, err := base64.StdEncoding.DecodeString(attachment["data"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := os.ReadFile(filepath.Join(dir, "out", "acme-lib-1.2.0.tgz"))
+	if err != nil || string(written) != string(tarball) {
+		t.Errorf("pack_destination tarball differs from the uploaded one: %v", err)
+	}
+	manifest, err := tarballManifest(tarball)
+	if err != nil || manifest["name"] != "@acme/lib" {
+		t.Errorf("tarballManifest() = %v, %v", manifest, err)
+	}
+}
+
+func TestPublishViaAPIRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("npm-otp") != "123456" {
+			t.Errorf("npm-otp = %q", r.Header.Get("npm-otp"))
+		}
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":"You cannot publish over the previously published versions: 1.0.0."}`))
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	_, err := publishViaAPI(context.Background(), &Config{Registry: server.URL, Tag: "latest", OTP: "123456"}, dir)
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "previously published") {
+		t.Errorf("publishViaAPI() error = %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// publishPlanKeyEnv holds the HMAC key signing publish plans.
+const publishPlanKeyEnv = "RELICTA_NPM_PLAN_KEY"
+
+// approvedPlan is the signed artifact written by pre-publish and executed by
+// post-publish. Between the two, it can be reviewed and approved; post-publish
+// refuses to publish anything the plan does not describe.
+type approvedPlan struct {
+	Package       string    `json:"package"`
+	Version       string    `json:"version"`
+	Tag           string    `json:"tag"`
+	Registry      string    `json:"registry"`
+	Access        string    `json:"access,omitempty"`
+	Target        string    `json:"target"`
+	ArtifactStore string    `json:"artifact_store,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	Signature     string    `json:"signature,omitempty"`
+}
+
+// newApprovedPlan describes what post-publish would do for the package.
+func newApprovedPlan(cfg *Config, name, version string, now time.Time) *approvedPlan {
+	registry := publishRegistry(cfg)
+	if registry == "" {
+		registry = defaultRegistry
+	}
+	target := cfg.PublishTarget
+	if target == "" {
+		target = publishTargetRegistry
+	}
+	return &approvedPlan{
+		Package:       name,
+		Version:       version,
+		Tag:           cfg.Tag,
+		Registry:      registry,
+		Access:        cfg.Access,
+		Target:        target,
+		ArtifactStore: cfg.ArtifactStore,
+		CreatedAt:     now.UTC(),
+	}
+}
+
+// sign returns the plan's HMAC-SHA256 signature over its unsigned JSON.
+func (pp approvedPlan) sign(key []byte) (string, error) {
+	pp.Signature = ""
+	data, err := json.Marshal(pp)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal publish plan: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// publishPlanKey returns the signing key.
+func publishPlanKey() ([]byte, error) {
+	key := os.Getenv(publishPlanKeyEnv)
+	if key == "" {
+		return nil, fmt.Errorf("%s must be set to sign and verify publish plans", publishPlanKeyEnv)
+	}
+	return []byte(key), nil
+}
+
+// writeApprovedPlan signs the plan and writes it to path.
+func writeApprovedPlan(path string, pp *approvedPlan) error {
+	key, err := publishPlanKey()
+	if err != nil {
+		return err
+	}
+	if pp.Signature, err = pp.sign(key); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(pp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal publish plan: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create publish plan directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write publish plan: %w", err)
+	}
+	return nil
+}
+
+// readApprovedPlan reads the plan at path and verifies its signature.
+func readApprovedPlan(path string) (*approvedPlan, error) {
+	key, err := publishPlanKey()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read publish plan: %w", err)
+	}
+	var pp approvedPlan
+	if err := json.Unmarshal(data, &pp); err != nil {
+		return nil, fmt.Errorf("failed to parse publish plan: %w", err)
+	}
+	want, err := pp.sign(key)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(pp.Signature), []byte(want)) {
+		return nil, fmt.Errorf("publish plan %s has an invalid signature", path)
+	}
+	return &pp, nil
+}
+
+// checkApprovedPlan verifies that what post-publish is about to do is what the
+// approved plan describes.
+func checkApprovedPlan(approved, actual *approvedPlan) error {
+	var diffs []string
+	for _, f := range []struct{ field, approved, actual string }{
+		{"package", approved.Package, actual.Package},
+		{"version", approved.Version, actual.Version},
+		{"tag", approved.Tag, actual.Tag},
+		{"registry", approved.Registry, actual.Registry},
+		{"access", approved.Access, actual.Access},
+		{"target", approved.Target, actual.Target},
+		{"artifact_store", approved.ArtifactStore, actual.ArtifactStore},
+	} {
+		if f.approved != f.actual {
+			diffs = append(diffs, fmt.Sprintf("%s is %q but the plan has %q", f.field, f.actual, f.approved))
+		}
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("publish does not match the approved plan: %s", strings.Join(diffs, "; "))
+	}
+	return nil
+}
+
+// checkPublishPlan loads the approved plan and compares it with the publish
+// about to happen. Dry runs tolerate a plan that has not been written yet.
+func checkPublishPlan(cfg *Config, name, version string, dryRun bool) error {
+	if dryRun {
+		if _, err := os.Stat(cfg.PublishPlan); os.IsNotExist(err) {
+			return nil
+		}
+	}
+	approved, err := readApprovedPlan(cfg.PublishPlan)
+	if err != nil {
+		return err
+	}
+	return checkApprovedPlan(approved, newApprovedPlan(cfg, name, version, approved.CreatedAt))
+}
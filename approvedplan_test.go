@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestApprovedPlanSignature(t *testing.T) {
+	t.Setenv(publishPlanKeyEnv, "secret")
+	path := filepath.Join(t.TempDir(), "plans", "plan.json")
+
+	pp := newApprovedPlan(&Config{Tag: "next", Access: "public"}, "@acme/lib", "1.2.0", time.Now())
+	if err := writeApprovedPlan(path, pp); err != nil {
+		t.Fatalf("writeApprovedPlan() error = %v", err)
+	}
+	got, err := readApprovedPlan(path)
+	if err != nil {
+		t.Fatalf("readApprovedPlan() error = %v", err)
+	}
+	if got.Package != "@acme/lib" || got.Registry != defaultRegistry || got.Target != publishTargetRegistry {
+		t.Errorf("plan = %+v", got)
+	}
+
+	t.Run("tampered", func(t *testing.T) {
+		data, _ := os.ReadFile(path)
+		writeFile(t, path, strings.Replace(string(data), `"1.2.0"`, `"1.3.0"`, 1))
+		if _, err := readApprovedPlan(path); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+			t.Errorf("readApprovedPlan() error = %v, want invalid signature", err)
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		writeApprovedPlan(path, pp)
+		t.Setenv(publishPlanKeyEnv, "other")
+		if _, err := readApprovedPlan(path); err == nil {
+			t.Error("expected signature error with a different key")
+		}
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		t.Setenv(publishPlanKeyEnv, "")
+		if err := writeApprovedPlan(path, pp); err == nil {
+			t.Error("expected error without a signing key")
+		}
+	})
+}
+
+func TestCheckApprovedPlan(t *testing.T) {
+	now := time.Now()
+	approved := newApprovedPlan(&Config{Tag: "latest"}, "pkg", "1.0.0", now)
+
+	if err := checkApprovedPlan(approved, newApprovedPlan(&Config{Tag: "latest"}, "pkg", "1.0.0", now)); err != nil {
+		t.Errorf("checkApprovedPlan() error = %v", err)
+	}
+	err := checkApprovedPlan(approved, newApprovedPlan(&Config{Tag: "next", Registry: "https://npm.example.com/"}, "pkg", "1.0.0", now))
+	if err == nil || !strings.Contains(err.Error(), `tag is "next"`) || !strings.Contains(err.Error(), "registry is") {
+		t.Errorf("checkApprovedPlan() error = %v", err)
+	}
+}
+
+func TestPublishPlanHooks(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{"id":"pkg@1.0.0","name":"pkg","version":"1.0.0"}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"1.0.0"}`)
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv(publishPlanKeyEnv, "secret")
+
+	p := &NpmPlugin{}
+	run := func(hook plugin.Hook, config map[string]any) *plugin.ExecuteResponse {
+		t.Helper()
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    hook,
+			Config:  config,
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+		})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		return resp
+	}
+
+	if resp := run(plugin.HookPostPublish, map[string]any{"publish_plan": "plan.json"}); resp.Success {
+		t.Fatal("expected post-publish to fail without a plan")
+	}
+
+	if resp := run(plugin.HookPrePublish, map[string]any{"publish_plan": "plan.json", "tag": "next"}); !resp.Success {
+		t.Fatalf("pre-publish failed: %s", resp.Error)
+	}
+
+	resp := run(plugin.HookPostPublish, map[string]any{"publish_plan": "plan.json", "tag": "latest"})
+	if resp.Success || !strings.Contains(resp.Error, "approved plan") {
+		t.Errorf("expected plan mismatch, got %+v", resp)
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 0 {
+		t.Errorf("npm ran despite mismatch: %v", calls)
+	}
+
+	if resp := run(plugin.HookPostPublish, map[string]any{"publish_plan": "plan.json", "tag": "next"}); !resp.Success {
+		t.Errorf("post-publish failed: %s", resp.Error)
+	}
+}
+
+func TestPublishPlanScopeRegistries(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{"id":"@mycorp/lib@1.0.0","name":"@mycorp/lib","version":"1.0.0"}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"@mycorp/lib","version":"1.0.0"}`)
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv(publishPlanKeyEnv, "secret")
+
+	config := map[string]any{
+		"publish_plan":     "plan.json",
+		"scope_registries": []any{map[string]any{"scope": "@mycorp", "registry": "https://npm.pkg.github.com/"}},
+	}
+	p := &NpmPlugin{}
+	for _, hook := range []plugin.Hook{plugin.HookPrePublish, plugin.HookPostPublish} {
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    hook,
+			Config:  config,
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("%s: unexpected failure: %v %+v", hook, err, resp)
+		}
+	}
+	calls := npmCalls(t, logPath)
+	if len(calls) != 1 || !strings.Contains(calls[0], "--registry https://npm.pkg.github.com/") {
+		t.Errorf("npm calls = %v, want a publish to the scope registry", calls)
+	}
+}
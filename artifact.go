@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Publish targets.
+const (
+	publishTargetRegistry      = "registry"
+	publishTargetArtifactStore = "artifact_store"
+)
+
+// artifactStoreTokenEnv holds the bearer token for HTTP artifact stores.
+const artifactStoreTokenEnv = "ARTIFACT_STORE_TOKEN"
+
+// artifactStoreCommands are the CLIs used to copy tarballs to object stores,
+// keyed by URL scheme. They use the ambient cloud credentials.
+var artifactStoreCommands = map[string][]string{
+	"s3": {"aws", "s3", "cp"},
+	"gs": {"gcloud", "storage", "cp"},
+}
+
+// validateArtifactStore checks an artifact_store URL template. Templates are
+// validated after rendering; here only the scheme is checked.
+func validateArtifactStore(store string) error {
+	if store == "" {
+		return nil
+	}
+	scheme, _, ok := strings.Cut(store, "://")
+	if !ok {
+		return fmt.Errorf("artifact_store must be an s3://, gs:// or https:// URL")
+	}
+	switch scheme {
+	case "s3", "gs", "https", "http":
+		return nil
+	}
+	return fmt.Errorf("unsupported artifact_store scheme %q", scheme)
+}
+
+// artifactStoreURL renders the artifact_store template for a package. A
+// trailing slash means "this directory", so the tarball name is appended.
+func artifactStoreURL(store string, data templateData, filename string) (string, error) {
+	rendered, err := renderTemplate(store, data)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(rendered, "/") {
+		rendered += filename
+	}
+	u, err := url.Parse(rendered)
+	if err != nil {
+		return "", fmt.Errorf("invalid artifact_store URL: %w", err)
+	}
+	if _, ok := artifactStoreCommands[u.Scheme]; ok {
+		if u.Host == "" || strings.ContainsAny(rendered, "\n\r\t") {
+			return "", fmt.Errorf("invalid artifact_store URL %q", rendered)
+		}
+		return rendered, nil
+	}
+	if err := validateEndpointURL(rendered, "artifact_store"); err != nil {
+		return "", err
+	}
+	return rendered, nil
+}
+
+// uploadArtifact copies the tarball to dest: object stores through their CLI,
+// anything else with an HTTP PUT.
+func uploadArtifact(ctx context.Context, tarball, dest string, result publishResult) error {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return fmt.Errorf("invalid artifact_store URL: %w", err)
+	}
+	if command, ok := artifactStoreCommands[u.Scheme]; ok {
+		args := append(append([]string{}, command[1:]...), tarball, dest)
+		cmd := exec.CommandContext(ctx, command[0], args...)
+		start := time.Now()
+		out, err := cmd.CombinedOutput()
+		recordCommand(ctx, "", command[0], args, start, err)
+		if err != nil {
+			return fmt.Errorf("%s failed: %v\n%s", strings.Join(command, " "), err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+
+	f, err := os.Open(tarball)
+	if err != nil {
+		return fmt.Errorf("failed to open tarball: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat tarball: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, dest, f)
+	if err != nil {
+		return fmt.Errorf("failed to create upload request: %w", err)
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/gzip")
+	if result.Shasum != "" {
+		req.Header.Set("X-Checksum-Sha1", result.Shasum)
+	}
+	if token := os.Getenv(artifactStoreTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("artifact store returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// publishToArtifactStore packs the package and uploads the tarball to the
+// artifact store instead of the registry, filling the same outputs a
+// registry publish would.
+func publishToArtifactStore(ctx context.Context, cfg *Config, packageDir string, data templateData, outputs map[string]any) error {
+	dest := cfg.PackDestination
+	if dest == "" {
+		tmp, err := os.MkdirTemp("", "relicta-npm-pack-")
+		if err != nil {
+			return fmt.Errorf("failed to create pack directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tmp) }()
+		dest = tmp
+	}
+
+	result, tarball, err := packForPublish(ctx, cfg, packageDir, dest, data, outputs)
+	if err != nil {
+		return err
+	}
+	storeURL, err := artifactStoreURL(cfg.ArtifactStore, data, filepath.Base(tarball))
+	if err != nil {
+		return err
+	}
+	if err := uploadArtifact(ctx, tarball, storeURL, result); err != nil {
+		return err
+	}
+
+	outputs["package"] = data.Name
+	outputs["version"] = data.Version
+	outputs["tag"] = cfg.Tag
+	outputs["artifact_url"] = storeURL
+	if cfg.PackDestination != "" {
+		outputs["tarball"] = tarball
+	}
+	if result.Integrity != "" {
+		outputs["integrity"] = result.Integrity
+	}
+	if result.Shasum != "" {
+		outputs["shasum"] = result.Shasum
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateArtifactStore(t *testing.T) {
+	tests := []struct {
+		store   string
+		wantErr bool
+	}{
+		{"", false},
+		{"s3://bucket/npm/", false},
+		{"gs://bucket/npm/{{.Name}}/", false},
+		{"https://artifacts.example.com/npm/", false},
+		{"ftp://artifacts.example.com/", true},
+		{"bucket/npm", true},
+	}
+	for _, tt := range tests {
+		if err := validateArtifactStore(tt.store); (err != nil) != tt.wantErr {
+			t.Errorf("validateArtifactStore(%q) error = %v, wantErr %v", tt.store, err, tt.wantErr)
+		}
+	}
+}
+
+func TestArtifactStoreURL(t *testing.T) {
+	data := templateData{Name: "@acme/lib", Version: "1.2.0"}
+	tests := []struct {
+		store   string
+		want    string
+		wantErr bool
+	}{
+		{"s3://bucket/npm/", "s3://bucket/npm/acme-lib-1.2.0.tgz", false},
+		{"https://artifacts.example.com/{{.Name}}/{{.Version}}.tgz", "https://artifacts.example.com/@acme/lib/1.2.0.tgz", false},
+		{"http://artifacts.example.com/", "", true},
+		{"s3:///npm/", "", true},
+		{"https://artifacts.example.com/{{.Nope}}", "", true},
+	}
+	for _, tt := range tests {
+		got, err := artifactStoreURL(tt.store, data, "acme-lib-1.2.0.tgz")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("artifactStoreURL(%q) error = %v, wantErr %v", tt.store, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("artifactStoreURL(%q) = %q, want %q", tt.store, got, tt.want)
+		}
+	}
+}
+
+func TestUploadArtifactHTTP(t *testing.T) {
+	var gotBody, gotAuth, gotSum string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		gotBody, gotAuth, gotSum = string(body), r.Header.Get("Authorization"), r.Header.Get("X-Checksum-Sha1")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	t.Setenv(artifactStoreTokenEnv, "secret")
+
+	tarball := filepath.Join(t.TempDir(), "pkg-1.0.0.tgz")
+	writeFile(t, tarball, "tarball")
+	if err := uploadArtifact(context.Background(), tarball, srv.URL+"/pkg-1.0.0.tgz", publishResult{Shasum: "abc"}); err != nil {
+		t.Fatalf("uploadArtifact() error = %v", err)
+	}
+	if gotBody != "tarball" || gotAuth != "Bearer secret" || gotSum != "abc" {
+		t.Errorf("unexpected upload: body=%q auth=%q sha1=%q", gotBody, gotAuth, gotSum)
+	}
+
+	if err := uploadArtifact(context.Background(), tarball, srv.URL+"/", publishResult{}); err != nil {
+		t.Fatalf("uploadArtifact() error = %v", err)
+	}
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	})
+	if err := uploadArtifact(context.Background(), tarball, srv.URL+"/x.tgz", publishResult{}); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected 403 error, got %v", err)
+	}
+}
+
+func TestPublishToArtifactStoreS3(t *testing.T) {
+	// The fake npm packs a tarball; a fake aws CLI logs the copy.
+	logPath := fakeNpm(t, `echo tarball > "$4/pkg-1.0.0.tgz"
+echo '[{"name":"pkg","version":"1.0.0","filename":"pkg-1.0.0.tgz","shasum":"abc","integrity":"sha512-xyz"}]'`)
+	binDir := filepath.Dir(logPath)
+	awsLog := filepath.Join(binDir, "aws.log")
+	if err := os.WriteFile(filepath.Join(binDir, "aws"), []byte("#!/bin/sh\necho \"$@\" >> "+awsLog+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"1.0.0"}`)
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	p := &NpmPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"publish_target": publishTargetArtifactStore,
+			"artifact_store": "s3://releases/npm/{{.Name}}/",
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %s", err, resp.Error)
+	}
+	if resp.Outputs["artifact_url"] != "s3://releases/npm/pkg/pkg-1.0.0.tgz" || resp.Outputs["integrity"] != "sha512-xyz" {
+		t.Errorf("unexpected outputs: %v", resp.Outputs)
+	}
+	data, err := os.ReadFile(awsLog)
+	if err != nil || !strings.HasPrefix(string(data), "s3 cp ") || !strings.Contains(string(data), "pkg-1.0.0.tgz s3://releases/npm/pkg/pkg-1.0.0.tgz") {
+		t.Errorf("unexpected aws invocation: %q (%v)", data, err)
+	}
+	for _, call := range npmCalls(t, logPath) {
+		if strings.HasPrefix(call, "publish") {
+			t.Errorf("npm publish should not run: %q", call)
+		}
+	}
+}
+
+func TestArtifactStoreConfigValidation(t *testing.T) {
+	p := &NpmPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{"publish_target": publishTargetArtifactStore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Valid {
+		t.Error("expected publish_target artifact_store without artifact_store to be invalid")
+	}
+}
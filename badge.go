@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readmeBadgeMarker tags the injected badge line so a later publish replaces
+// it instead of stacking badges.
+const readmeBadgeMarker = "<!-- relicta-npm-badge -->"
+
+// readmeBadgePresets are the built-in badges; any other readme_badge value
+// is a Markdown template.
+var readmeBadgePresets = map[string]func(data templateData) string{
+	"version": func(data templateData) string {
+		return fmt.Sprintf("[![npm](https://img.shields.io/badge/npm-%s-blue)](%s)", shieldsEscape(data.Version), npmPackagePage(data.Name, data.Version))
+	},
+	"provenance": func(data templateData) string {
+		return fmt.Sprintf("[![provenance](https://img.shields.io/badge/provenance-npm-brightgreen)](%s#provenance)", npmPackagePage(data.Name, data.Version))
+	},
+}
+
+// shieldsEscape escapes a shields.io badge segment, where "-" and "_" are
+// separators.
+func shieldsEscape(s string) string {
+	return strings.NewReplacer("-", "--", "_", "__").Replace(s)
+}
+
+// npmPackagePage returns the npmjs.com page of a package version.
+func npmPackagePage(name, version string) string {
+	return "https://www.npmjs.com/package/" + name + "/v/" + version
+}
+
+// renderReadmeBadge renders the badge line for a package.
+func renderReadmeBadge(badge string, data templateData) (string, error) {
+	if preset, ok := readmeBadgePresets[badge]; ok {
+		return preset(data), nil
+	}
+	return renderTemplate(badge, data)
+}
+
+// injectReadmeBadge removes any previously injected badge and adds badge at
+// the top of the README, below a leading "# Title" heading.
+func injectReadmeBadge(content []byte, badge string) []byte {
+	lines := strings.SplitAfter(string(content), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.Contains(line, readmeBadgeMarker) {
+			kept = append(kept, line)
+		}
+	}
+
+	insert := 0
+	if len(kept) > 0 && strings.HasPrefix(kept[0], "# ") {
+		insert = 1
+		if !strings.HasSuffix(kept[0], "\n") {
+			kept[0] += "\n"
+		}
+	}
+	line := badge + " " + readmeBadgeMarker + "\n"
+	var b bytes.Buffer
+	for _, l := range kept[:insert] {
+		b.WriteString(l)
+	}
+	b.WriteString(line)
+	for _, l := range kept[insert:] {
+		b.WriteString(l)
+	}
+	return b.Bytes()
+}
+
+// withReadmeBadge runs fn (which packs the package) with the badge injected
+// into the package README, restoring the original afterwards so only the
+// tarball carries the badge. It reports false when there is no README.
+func withReadmeBadge(packageDir, badge string, fn func() error) (bool, error) {
+	path := filepath.Join(packageDir, "README.md")
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, fn()
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat README.md: %w", err)
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read README.md: %w", err)
+	}
+
+	if err := os.WriteFile(path, injectReadmeBadge(original, badge), info.Mode().Perm()); err != nil {
+		return false, fmt.Errorf("failed to write README.md: %w", err)
+	}
+	fnErr := fn()
+	if err := os.WriteFile(path, original, info.Mode().Perm()); err != nil {
+		return true, fmt.Errorf("failed to restore README.md: %w", err)
+	}
+	return true, fnErr
+}
+
+// packForPublish packs the package into dest for publishing, injecting the
+// README badge into the tarball when one is configured.
+func packForPublish(ctx context.Context, cfg *Config, packageDir, dest string, data templateData, outputs map[string]any) (publishResult, string, error) {
+	if cfg.ReadmeBadge == "" {
+		return packTarball(ctx, cfg, packageDir, dest)
+	}
+
+	badge, err := renderReadmeBadge(cfg.ReadmeBadge, data)
+	if err != nil {
+		return publishResult{}, "", err
+	}
+	var result publishResult
+	var tarball string
+	injected, err := withReadmeBadge(packageDir, badge, func() error {
+		var err error
+		result, tarball, err = packTarball(ctx, cfg, packageDir, dest)
+		return err
+	})
+	if err != nil {
+		return publishResult{}, "", err
+	}
+	if injected {
+		outputs["readme_badge"] = badge
+	} else {
+		appendWarning(outputs, "readme_badge: no README.md to add the badge to")
+	}
+	return result, tarball, nil
+}
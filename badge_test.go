@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestRenderReadmeBadge(t *testing.T) {
+	data := templateData{Name: "@acme/lib", Version: "1.0.0-beta.1"}
+	tests := []struct {
+		badge string
+		want  string
+	}{
+		{"version", "[![npm](https://img.shields.io/badge/npm-1.0.0--beta.1-blue)](https://www.npmjs.com/package/@acme/lib/v/1.0.0-beta.1)"},
+		{"provenance", "[![provenance](https://img.shields.io/badge/provenance-npm-brightgreen)](https://www.npmjs.com/package/@acme/lib/v/1.0.0-beta.1#provenance)"},
+		{"![v{{.Version}}](https://badges.example.com/{{.Name}}.svg)", "![v1.0.0-beta.1](https://badges.example.com/@acme/lib.svg)"},
+	}
+	for _, tt := range tests {
+		got, err := renderReadmeBadge(tt.badge, data)
+		if err != nil {
+			t.Fatalf("renderReadmeBadge(%q) error = %v", tt.badge, err)
+		}
+		if got != tt.want {
+			t.Errorf("renderReadmeBadge(%q) = %q, want %q", tt.badge, got, tt.want)
+		}
+	}
+	if _, err := renderReadmeBadge("{{.Nope}}", data); err == nil {
+		t.Error("expected error for unknown template field")
+	}
+}
+
+func TestInjectReadmeBadge(t *testing.T) {
+	badge := "![b](x)"
+	line := badge + " " + readmeBadgeMarker + "\n"
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"below title", "# Lib\n\nText\n", "# Lib\n" + line + "\nText\n"},
+		{"no title", "Text\n", line + "Text\n"},
+		{"title only", "# Lib", "# Lib\n" + line},
+		{"refresh", "# Lib\n![old](y) " + readmeBadgeMarker + "\nText\n", "# Lib\n" + line + "Text\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(injectReadmeBadge([]byte(tt.content), badge)); got != tt.want {
+				t.Errorf("injectReadmeBadge() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithReadmeBadge(t *testing.T) {
+	dir := t.TempDir()
+	readme := filepath.Join(dir, "README.md")
+	writeFile(t, readme, "# Lib\n")
+
+	var during string
+	injected, err := withReadmeBadge(dir, "![b](x)", func() error {
+		data, _ := os.ReadFile(readme)
+		during = string(data)
+		return errors.New("pack failed")
+	})
+	if !injected || err == nil || err.Error() != "pack failed" {
+		t.Fatalf("withReadmeBadge() = %v, %v", injected, err)
+	}
+	if !strings.Contains(during, "![b](x) "+readmeBadgeMarker) {
+		t.Errorf("badge missing while packing: %q", during)
+	}
+	if data, _ := os.ReadFile(readme); string(data) != "# Lib\n" {
+		t.Errorf("README not restored: %q", data)
+	}
+
+	injected, err = withReadmeBadge(t.TempDir(), "![b](x)", func() error { return nil })
+	if injected || err != nil {
+		t.Errorf("withReadmeBadge() without README = %v, %v", injected, err)
+	}
+}
+
+func TestPublishReadmeBadge(t *testing.T) {
+	// The fake npm captures the README as packed and publishes the tarball.
+	logPath := fakeNpm(t, `if [ "$1" = pack ]; then
+  cp README.md "$4/packed-README.md"; touch "$4/pkg-1.0.0.tgz"
+  echo '[{"name":"pkg","version":"1.0.0","filename":"pkg-1.0.0.tgz"}]'
+else
+  echo '{"id":"pkg@1.0.0"}'
+fi`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "README.md"), "# pkg\n")
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	p := &NpmPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"readme_badge": "version", "pack_destination": "out"},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %s", err, resp.Error)
+	}
+
+	packed, err := os.ReadFile(filepath.Join(dir, "out", "packed-README.md"))
+	if err != nil || !strings.Contains(string(packed), "img.shields.io/badge/npm-1.0.0-blue") {
+		t.Errorf("packed README missing badge: %q (%v)", packed, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "README.md")); string(data) != "# pkg\n" {
+		t.Errorf("repository README modified: %q", data)
+	}
+	calls := npmCalls(t, logPath)
+	if len(calls) != 2 || !strings.HasPrefix(calls[1], "publish "+filepath.Join(dir, "out", "pkg-1.0.0.tgz")) {
+		t.Errorf("expected tarball publish, got %q", calls)
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	binaryModeOptionalDeps  = "optional_dependencies"
+	binaryModeInstallScript = "install_script"
+)
+
+// binaryOSes and binaryCPUs are the process.platform and process.arch values
+// platform packages can target.
+var (
+	binaryOSes = []string{"aix", "android", "darwin", "freebsd", "linux", "netbsd", "openbsd", "sunos", "win32"}
+	binaryCPUs = []string{"arm", "arm64", "ia32", "loong64", "mips64el", "ppc64", "riscv64", "s390x", "x64"}
+)
+
+// binaryNamePattern restricts command names to safe file names.
+var binaryNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// BinaryDist publishes prebuilt binaries with the package. In
+// optional_dependencies mode (default) each binary becomes a platform package
+// ("<name>-<os>-<cpu>") the main package lists as an optional dependency; in
+// install_script mode all binaries ship inside the main package and a
+// postinstall script checks the current platform is covered.
+type BinaryDist struct {
+	// Name is the command name; it defaults to the unscoped package name.
+	Name string `json:"name,omitempty"`
+	// Mode is optional_dependencies or install_script.
+	Mode string `json:"mode,omitempty"`
+	// Platforms lists the prebuilt binaries.
+	Platforms []BinaryPlatform `json:"platforms,omitempty"`
+}
+
+// BinaryPlatform is a prebuilt binary for one os/cpu pair, using Node's
+// process.platform and process.arch names.
+type BinaryPlatform struct {
+	OS   string `json:"os"`
+	CPU  string `json:"cpu"`
+	Path string `json:"path"`
+}
+
+// key returns the "<os>-<cpu>" suffix for the platform.
+func (b BinaryPlatform) key() string {
+	return b.OS + "-" + b.CPU
+}
+
+// fileName returns the binary's file name on the platform.
+func (b BinaryPlatform) fileName(command string) string {
+	if b.OS == "win32" {
+		return command + ".exe"
+	}
+	return command
+}
+
+// binaryMode returns the effective distribution mode.
+func binaryMode(dist BinaryDist) string {
+	if dist.Mode == "" {
+		return binaryModeOptionalDeps
+	}
+	return dist.Mode
+}
+
+// platformPackageNames lists the platform packages optional_dependencies
+// mode publishes; install_script mode publishes none.
+func platformPackageNames(dist BinaryDist, pkgName string) []string {
+	if binaryMode(dist) != binaryModeOptionalDeps {
+		return nil
+	}
+	names := make([]string, len(dist.Platforms))
+	for i, b := range dist.Platforms {
+		names[i] = platformPackageName(pkgName, b)
+	}
+	return names
+}
+
+// binaryCommand returns the command name for the package.
+func binaryCommand(dist BinaryDist, pkgName string) string {
+	if dist.Name != "" {
+		return dist.Name
+	}
+	if i := strings.LastIndex(pkgName, "/"); i >= 0 {
+		return pkgName[i+1:]
+	}
+	return pkgName
+}
+
+// platformPackageName returns the platform package name for a binary.
+func platformPackageName(pkgName string, b BinaryPlatform) string {
+	return pkgName + "-" + b.key()
+}
+
+// validateBinaryDist checks the binaries configuration.
+func validateBinaryDist(dist BinaryDist) error {
+	if dist.Name == "" && dist.Mode == "" && len(dist.Platforms) == 0 {
+		return nil
+	}
+	switch dist.Mode {
+	case "", binaryModeOptionalDeps, binaryModeInstallScript:
+	default:
+		return fmt.Errorf("unknown mode %q", dist.Mode)
+	}
+	if dist.Name != "" && !binaryNamePattern.MatchString(dist.Name) {
+		return fmt.Errorf("invalid command name %q", dist.Name)
+	}
+	if len(dist.Platforms) == 0 {
+		return fmt.Errorf("at least one platform is required")
+	}
+	seen := map[string]bool{}
+	for _, b := range dist.Platforms {
+		if !containsString(binaryOSes, b.OS) {
+			return fmt.Errorf("unknown os %q", b.OS)
+		}
+		if !containsString(binaryCPUs, b.CPU) {
+			return fmt.Errorf("unknown cpu %q", b.CPU)
+		}
+		if seen[b.key()] {
+			return fmt.Errorf("duplicate platform %s", b.key())
+		}
+		seen[b.key()] = true
+		if b.Path == "" {
+			return fmt.Errorf("%s: path is required", b.key())
+		}
+		if err := validateOutputPath(b.Path); err != nil {
+			return fmt.Errorf("%s: %w", b.key(), err)
+		}
+	}
+	return nil
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// copyBinary copies a prebuilt binary to dest, making it executable.
+func copyBinary(src, dest string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read binary: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create binary directory: %w", err)
+	}
+	if err := os.WriteFile(dest, data, 0755); err != nil {
+		return fmt.Errorf("failed to write binary: %w", err)
+	}
+	return nil
+}
+
+// writePlatformPackage lays out the platform package for one binary in dir.
+func writePlatformPackage(dir, pkgName, version, command string, b BinaryPlatform) error {
+	manifest := map[string]any{
+		"name":            platformPackageName(pkgName, b),
+		"version":         version,
+		"description":     fmt.Sprintf("The %s binary for %s", command, b.key()),
+		"os":              []string{b.OS},
+		"cpu":             []string{b.CPU},
+		"files":           []string{"bin"},
+		"preferUnplugged": true,
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal platform package.json: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write platform package.json: %w", err)
+	}
+	return copyBinary(b.Path, filepath.Join(dir, "bin", b.fileName(command)))
+}
+
+// publishPlatformPackages publishes one package per binary. They go out
+// before the main package so its optional dependencies resolve.
+func publishPlatformPackages(ctx context.Context, cfg *Config, pkgName, version string) ([]string, error) {
+	command := binaryCommand(cfg.Binaries, pkgName)
+	var published []string
+	for _, b := range cfg.Binaries.Platforms {
+		dir, err := os.MkdirTemp("", "relicta-npm-platform-")
+		if err != nil {
+			return published, fmt.Errorf("failed to create platform package directory: %w", err)
+		}
+		err = writePlatformPackage(dir, pkgName, version, command, b)
+		if err == nil {
+			err = publishGeneratedPackage(ctx, cfg, dir)
+		}
+		_ = os.RemoveAll(dir)
+		if err != nil {
+			return published, fmt.Errorf("failed to publish %s: %w", platformPackageName(pkgName, b), err)
+		}
+		published = append(published, platformPackageName(pkgName, b))
+	}
+	return published, nil
+}
+
+// publishGeneratedPackage publishes a package the plugin generated in dir
+// with the configured registry, dist-tag and access.
+func publishGeneratedPackage(ctx context.Context, cfg *Config, dir string) error {
+	args := append([]string{"publish"}, registryArgs(cfg)...)
+	if cfg.Tag != "" {
+		args = append(args, "--tag", cfg.Tag)
+	}
+	if cfg.Access != "" {
+		args = append(args, "--access", cfg.Access)
+	}
+	_, err := runNpm(ctx, dir, args...)
+	return err
+}
+
+// binaryLauncher returns the bin script that runs the platform's binary.
+func binaryLauncher(dist BinaryDist, pkgName, command string) string {
+	resolve := fmt.Sprintf("require.resolve(`%s-${platform}/bin/%s${ext}`)", pkgName, command)
+	if binaryMode(dist) == binaryModeInstallScript {
+		resolve = fmt.Sprintf("path.join(__dirname, \"..\", \"binaries\", platform, `%s${ext}`)", command)
+	}
+	return fmt.Sprintf(`#!/usr/bin/env node
+"use strict";
+const path = require("path");
+const { spawnSync } = require("child_process");
+const platform = process.platform + "-" + process.arch;
+const ext = process.platform === "win32" ? ".exe" : "";
+let bin;
+try {
+  bin = %s;
+} catch {
+  console.error("%s: no prebuilt binary for " + platform);
+  process.exit(1);
+}
+const result = spawnSync(bin, process.argv.slice(2), { stdio: "inherit" });
+if (result.error) {
+  console.error("%s: " + result.error.message);
+  process.exit(1);
+}
+process.exit(result.status === null ? 1 : result.status);
+`, resolve, command, command)
+}
+
+// binaryInstallScript returns the postinstall script for install_script mode,
+// failing the install on platforms without a bundled binary.
+func binaryInstallScript(command string) string {
+	return fmt.Sprintf(`"use strict";
+const fs = require("fs");
+const path = require("path");
+const platform = process.platform + "-" + process.arch;
+const ext = process.platform === "win32" ? ".exe" : "";
+const bin = path.join(__dirname, "binaries", platform, "%s" + ext);
+if (!fs.existsSync(bin)) {
+  console.error("%s: no prebuilt binary for " + platform);
+  process.exit(1);
+}
+if (ext === "") {
+  fs.chmodSync(bin, 0o755);
+}
+`, command, command)
+}
+
+// scaffoldBinaries adds the launcher, package.json entries and (in
+// install_script mode) bundled binaries to the main package. The returned
+// restore func undoes the changes and is safe to call more than once.
+func scaffoldBinaries(packageDir, pkgName, version string, dist BinaryDist) (func() error, error) {
+	command := binaryCommand(dist, pkgName)
+	manifestPath := filepath.Join(packageDir, "package.json")
+	info, err := os.Stat(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat package.json: %w", err)
+	}
+	original, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	var manifest map[string]any
+	if err := json.Unmarshal(original, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	launcher := filepath.Join("bin", command+".js")
+	created := []string{launcher}
+	files := map[string]string{launcher: binaryLauncher(dist, pkgName, command)}
+	if binaryMode(dist) == binaryModeInstallScript {
+		files["install.js"] = binaryInstallScript(command)
+		created = append(created, "install.js", "binaries")
+		scripts, _ := manifest["scripts"].(map[string]any)
+		if scripts == nil {
+			scripts = map[string]any{}
+		}
+		scripts["postinstall"] = "node install.js"
+		manifest["scripts"] = scripts
+	} else {
+		optional, _ := manifest["optionalDependencies"].(map[string]any)
+		if optional == nil {
+			optional = map[string]any{}
+		}
+		for _, b := range dist.Platforms {
+			optional[platformPackageName(pkgName, b)] = version
+		}
+		manifest["optionalDependencies"] = optional
+	}
+	manifest["bin"] = map[string]any{command: filepath.ToSlash(launcher)}
+	if list, ok := manifest["files"].([]any); ok {
+		for _, path := range created {
+			list = append(list, filepath.ToSlash(path))
+		}
+		manifest["files"] = list
+	}
+
+	for _, path := range created {
+		if _, err := os.Stat(filepath.Join(packageDir, path)); err == nil {
+			return nil, fmt.Errorf("binaries: %s already exists in the package directory", path)
+		}
+	}
+	createdBin := false
+	if _, err := os.Stat(filepath.Join(packageDir, "bin")); os.IsNotExist(err) {
+		createdBin = true
+	}
+	cleanup := func() {
+		for _, path := range created {
+			_ = os.RemoveAll(filepath.Join(packageDir, path))
+		}
+		if createdBin {
+			_ = os.RemoveAll(filepath.Join(packageDir, "bin"))
+		}
+	}
+
+	restored := false
+	restore := func() error {
+		if restored {
+			return nil
+		}
+		restored = true
+		cleanup()
+		if err := os.WriteFile(manifestPath, original, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to restore package.json: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Join(packageDir, "bin"), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create bin directory: %w", err)
+	}
+	for path, content := range files {
+		if err := os.WriteFile(filepath.Join(packageDir, path), []byte(content), 0755); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	if binaryMode(dist) == binaryModeInstallScript {
+		for _, b := range dist.Platforms {
+			if err := copyBinary(b.Path, filepath.Join(packageDir, "binaries", b.key(), b.fileName(command))); err != nil {
+				cleanup()
+				return nil, err
+			}
+		}
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to marshal package.json: %w", err)
+	}
+	if err := os.WriteFile(manifestPath, append(data, '\n'), info.Mode().Perm()); err != nil {
+		_ = restore()
+		return nil, fmt.Errorf("failed to write package.json: %w", err)
+	}
+	return restore, nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateBinaryDist(t *testing.T) {
+	linux := BinaryPlatform{OS: "linux", CPU: "x64", Path: "dist/tool-linux"}
+	tests := []struct {
+		name    string
+		dist    BinaryDist
+		wantErr bool
+	}{
+		{"unset", BinaryDist{}, false},
+		{"valid", BinaryDist{Name: "tool", Platforms: []BinaryPlatform{linux, {OS: "win32", CPU: "arm64", Path: "dist/tool.exe"}}}, false},
+		{"install script", BinaryDist{Mode: binaryModeInstallScript, Platforms: []BinaryPlatform{linux}}, false},
+		{"no platforms", BinaryDist{Name: "tool"}, true},
+		{"unknown mode", BinaryDist{Mode: "postinstall", Platforms: []BinaryPlatform{linux}}, true},
+		{"bad name", BinaryDist{Name: "../tool", Platforms: []BinaryPlatform{linux}}, true},
+		{"unknown os", BinaryDist{Platforms: []BinaryPlatform{{OS: "macos", CPU: "x64", Path: "a"}}}, true},
+		{"unknown cpu", BinaryDist{Platforms: []BinaryPlatform{{OS: "linux", CPU: "amd64", Path: "a"}}}, true},
+		{"duplicate", BinaryDist{Platforms: []BinaryPlatform{linux, linux}}, true},
+		{"missing path", BinaryDist{Platforms: []BinaryPlatform{{OS: "linux", CPU: "x64"}}}, true},
+		{"path outside cwd", BinaryDist{Platforms: []BinaryPlatform{{OS: "linux", CPU: "x64", Path: "../tool"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateBinaryDist(tt.dist); (err != nil) != tt.wantErr {
+				t.Errorf("validateBinaryDist() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWritePlatformPackage(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "tool.exe")
+	writeFile(t, src, "MZ")
+	dir := t.TempDir()
+
+	if err := writePlatformPackage(dir, "@acme/tool", "1.4.0", "tool", BinaryPlatform{OS: "win32", CPU: "x64", Path: src}); err != nil {
+		t.Fatalf("writePlatformPackage() error = %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "package.json"))
+	var manifest struct {
+		Name    string   `json:"name"`
+		Version string   `json:"version"`
+		OS      []string `json:"os"`
+		CPU     []string `json:"cpu"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("invalid package.json: %v", err)
+	}
+	if manifest.Name != "@acme/tool-win32-x64" || manifest.Version != "1.4.0" || manifest.OS[0] != "win32" || manifest.CPU[0] != "x64" {
+		t.Errorf("manifest = %+v", manifest)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bin", "tool.exe")); err != nil {
+		t.Errorf("binary not copied: %v", err)
+	}
+}
+
+func TestScaffoldBinaries(t *testing.T) {
+	original := `{"name":"@acme/tool","version":"1.4.0","files":["lib"]}`
+
+	t.Run("optional dependencies", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "package.json"), original)
+		dist := BinaryDist{Platforms: []BinaryPlatform{{OS: "linux", CPU: "x64", Path: "unused"}, {OS: "darwin", CPU: "arm64", Path: "unused"}}}
+
+		restore, err := scaffoldBinaries(dir, "@acme/tool", "1.4.0", dist)
+		if err != nil {
+			t.Fatalf("scaffoldBinaries() error = %v", err)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, "package.json"))
+		var manifest map[string]any
+		_ = json.Unmarshal(data, &manifest)
+		optional, _ := manifest["optionalDependencies"].(map[string]any)
+		if optional["@acme/tool-linux-x64"] != "1.4.0" || optional["@acme/tool-darwin-arm64"] != "1.4.0" {
+			t.Errorf("optionalDependencies = %v", manifest["optionalDependencies"])
+		}
+		if bin, _ := manifest["bin"].(map[string]any); bin["tool"] != "bin/tool.js" {
+			t.Errorf("bin = %v", manifest["bin"])
+		}
+		if files, _ := manifest["files"].([]any); len(files) != 2 || files[1] != "bin/tool.js" {
+			t.Errorf("files = %v", manifest["files"])
+		}
+		launcher, _ := os.ReadFile(filepath.Join(dir, "bin", "tool.js"))
+		if !strings.Contains(string(launcher), "require.resolve(`@acme/tool-${platform}/bin/tool${ext}`)") {
+			t.Errorf("launcher = %s", launcher)
+		}
+
+		if err := restore(); err != nil {
+			t.Fatalf("restore() error = %v", err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, "package.json")); string(data) != original {
+			t.Errorf("package.json not restored: %s", data)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "bin")); !os.IsNotExist(err) {
+			t.Errorf("bin directory left behind: %v", err)
+		}
+		if err := restore(); err != nil {
+			t.Errorf("second restore() error = %v", err)
+		}
+	})
+
+	t.Run("existing launcher", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "package.json"), original)
+		writeFile(t, filepath.Join(dir, "bin", "tool.js"), "custom")
+		dist := BinaryDist{Platforms: []BinaryPlatform{{OS: "linux", CPU: "x64", Path: "unused"}}}
+		if _, err := scaffoldBinaries(dir, "@acme/tool", "1.4.0", dist); err == nil {
+			t.Error("expected error for an existing bin/tool.js")
+		}
+	})
+
+	t.Run("install script runs", func(t *testing.T) {
+		node, err := exec.LookPath("node")
+		if err != nil {
+			t.Skip("node not available")
+		}
+		platform, err := exec.Command(node, "-p", "process.platform + ' ' + process.arch").Output()
+		if err != nil {
+			t.Fatalf("node failed: %v", err)
+		}
+		osName, cpu, _ := strings.Cut(strings.TrimSpace(string(platform)), " ")
+		if osName == "win32" {
+			t.Skip("fake binary requires a POSIX shell")
+		}
+		src := filepath.Join(t.TempDir(), "tool")
+		writeFile(t, src, "#!/bin/sh\necho \"tool $*\"\n")
+
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "package.json"), original)
+		dist := BinaryDist{Mode: binaryModeInstallScript, Platforms: []BinaryPlatform{{OS: osName, CPU: cpu, Path: src}}}
+		restore, err := scaffoldBinaries(dir, "@acme/tool", "1.4.0", dist)
+		if err != nil {
+			t.Fatalf("scaffoldBinaries() error = %v", err)
+		}
+		defer restore()
+
+		if out, err := exec.Command(node, filepath.Join(dir, "install.js")).CombinedOutput(); err != nil {
+			t.Fatalf("install.js failed: %v\n%s", err, out)
+		}
+		out, err := exec.Command(node, filepath.Join(dir, "bin", "tool.js"), "--version").CombinedOutput()
+		if err != nil || strings.TrimSpace(string(out)) != "tool --version" {
+			t.Errorf("launcher output = %q (%v)", out, err)
+		}
+	})
+}
+
+func TestPublishBinaries(t *testing.T) {
+	logPath := fakeNpm(t, `if [ -f package.json ]; then tr -d '\n ' < package.json >> "$(dirname "$0")/manifests"; echo >> "$(dirname "$0")/manifests"; fi
+echo '{"id":"@acme/tool@1.4.0","name":"@acme/tool","version":"1.4.0"}'`)
+	dir := t.TempDir()
+	original := `{"name":"@acme/tool","version":"1.4.0"}`
+	writeFile(t, filepath.Join(dir, "package.json"), original)
+	writeFile(t, filepath.Join(dir, "dist", "tool-linux"), "ELF")
+	writeFile(t, filepath.Join(dir, "dist", "tool-mac"), "MACHO")
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"access": "public",
+			"binaries": map[string]any{
+				"platforms": []any{
+					map[string]any{"os": "linux", "cpu": "x64", "path": "dist/tool-linux"},
+					map[string]any{"os": "darwin", "cpu": "arm64", "path": "dist/tool-mac"},
+				},
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "1.4.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if got := resp.Outputs["platform_packages"].([]string); len(got) != 2 || got[0] != "@acme/tool-linux-x64" {
+		t.Errorf("platform_packages = %v", got)
+	}
+
+	calls := npmCalls(t, logPath)
+	if len(calls) != 3 || !strings.HasPrefix(calls[2], "publish --json") {
+		t.Fatalf("npm calls = %v", calls)
+	}
+	manifests, _ := os.ReadFile(filepath.Join(filepath.Dir(logPath), "manifests"))
+	lines := strings.Split(strings.TrimSpace(string(manifests)), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"name":"@acme/tool-linux-x64"`) || !strings.Contains(lines[2], `"optionalDependencies":{"@acme/tool-darwin-arm64":"1.4.0","@acme/tool-linux-x64":"1.4.0"}`) {
+		t.Errorf("published manifests = %s", manifests)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "package.json")); string(data) != original {
+		t.Errorf("package.json not restored: %s", data)
+	}
+}
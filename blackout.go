@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	// Runners and slim containers often lack a zoneinfo database
+	_ "time/tzdata"
+)
+
+// Blackout modes.
+const (
+	blackoutRefuse = "refuse"
+	blackoutWait   = "wait"
+)
+
+// blackoutDays maps weekday names to weekdays.
+var blackoutDays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// BlackoutWindow is a period during which nothing is published: either a
+// fixed range (Start/End) such as a holiday change freeze, or a recurring
+// window (Days, From/To) such as weekends or a nightly maintenance slot.
+type BlackoutWindow struct {
+	// Start and End bound a fixed window, as "2006-01-02" or
+	// "2006-01-02T15:04". A date-only End includes that whole day.
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	// Days are the weekdays (mon, tue, ...) a recurring window applies on;
+	// empty means every day.
+	Days []string `json:"days,omitempty"`
+	// From and To are the daily "15:04" times of a recurring window; empty
+	// means the whole day. A To before From ends the next day.
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Timezone is the IANA zone the times are in (default UTC).
+	Timezone string `json:"timezone,omitempty"`
+	// Reason is shown when a publish is refused.
+	Reason string `json:"reason,omitempty"`
+}
+
+// fixed reports whether the window is a fixed range.
+func (w BlackoutWindow) fixed() bool {
+	return w.Start != "" || w.End != ""
+}
+
+// location returns the window's time zone.
+func (w BlackoutWindow) location() (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(w.Timezone)
+}
+
+// parseBlackoutTime parses a fixed window bound. Date-only ends are moved to
+// the end of the day.
+func parseBlackoutTime(s string, loc *time.Location, end bool) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02T15:04", s, loc); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, want 2006-01-02 or 2006-01-02T15:04", s)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// parseClock parses a "15:04" time of day into an offset from midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, want 15:04", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// validateBlackoutWindows checks every window.
+func validateBlackoutWindows(windows []BlackoutWindow) error {
+	for i, w := range windows {
+		if err := validateBlackoutWindow(w); err != nil {
+			return fmt.Errorf("window %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateBlackoutWindow checks that a window is either fixed or recurring
+// and its times parse.
+func validateBlackoutWindow(w BlackoutWindow) error {
+	loc, err := w.location()
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q", w.Timezone)
+	}
+	if w.fixed() {
+		if len(w.Days) > 0 || w.From != "" || w.To != "" {
+			return fmt.Errorf("start/end cannot be combined with days, from or to")
+		}
+		if w.Start == "" || w.End == "" {
+			return fmt.Errorf("start and end are both required")
+		}
+		start, err := parseBlackoutTime(w.Start, loc, false)
+		if err != nil {
+			return err
+		}
+		end, err := parseBlackoutTime(w.End, loc, true)
+		if err != nil {
+			return err
+		}
+		if !end.After(start) {
+			return fmt.Errorf("end must be after start")
+		}
+		return nil
+	}
+
+	if len(w.Days) == 0 && w.From == "" && w.To == "" {
+		return fmt.Errorf("set start/end or days/from/to")
+	}
+	for _, d := range w.Days {
+		if _, ok := blackoutDays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("unknown day %q, want mon, tue, wed, thu, fri, sat or sun", d)
+		}
+	}
+	if (w.From == "") != (w.To == "") {
+		return fmt.Errorf("from and to must be set together")
+	}
+	if w.From != "" {
+		from, err := parseClock(w.From)
+		if err != nil {
+			return err
+		}
+		to, err := parseClock(w.To)
+		if err != nil {
+			return err
+		}
+		if from == to {
+			return fmt.Errorf("from and to must differ")
+		}
+	}
+	return nil
+}
+
+// windowEnd returns when w ends if now is inside it.
+func windowEnd(w BlackoutWindow, now time.Time) (time.Time, bool) {
+	loc, err := w.location()
+	if err != nil {
+		return time.Time{}, false
+	}
+	if w.fixed() {
+		start, err1 := parseBlackoutTime(w.Start, loc, false)
+		end, err2 := parseBlackoutTime(w.End, loc, true)
+		if err1 != nil || err2 != nil || now.Before(start) || !now.Before(end) {
+			return time.Time{}, false
+		}
+		return end, true
+	}
+
+	var from, to time.Duration = 0, 24 * time.Hour
+	if w.From != "" {
+		from, _ = parseClock(w.From)
+		to, _ = parseClock(w.To)
+		if to <= from {
+			to += 24 * time.Hour
+		}
+	}
+	local := now.In(loc)
+	// An occurrence that started yesterday can still be running
+	for _, offset := range []int{0, -1} {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, loc)
+		if len(w.Days) > 0 && !blackoutOnDay(w.Days, day.Weekday()) {
+			continue
+		}
+		start := day.Add(from)
+		end := day.Add(to)
+		if !now.Before(start) && now.Before(end) {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// blackoutOnDay reports whether days includes weekday.
+func blackoutOnDay(days []string, weekday time.Weekday) bool {
+	for _, d := range days {
+		if blackoutDays[strings.ToLower(d)] == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// activeBlackout returns the window now falls in and when publishing is
+// possible again, following back-to-back and overlapping windows (e.g. sat
+// and sun) to the end of the freeze.
+func activeBlackout(windows []BlackoutWindow, now time.Time) (*BlackoutWindow, time.Time, bool) {
+	var active *BlackoutWindow
+	until := now
+	// Bounded so adjacent recurring windows covering every day still return
+	for i := 0; i < 32; i++ {
+		extended := false
+		for j := range windows {
+			if end, ok := windowEnd(windows[j], until); ok && end.After(until) {
+				if active == nil {
+					active = &windows[j]
+				}
+				until = end
+				extended = true
+			}
+		}
+		if !extended {
+			break
+		}
+	}
+	return active, until, active != nil
+}
+
+// blackoutMessage describes the freeze a publish ran into.
+func blackoutMessage(w *BlackoutWindow, until time.Time) string {
+	msg := fmt.Sprintf("publishing is frozen until %s", until.UTC().Format(time.RFC3339))
+	if w.Reason != "" {
+		msg += ": " + w.Reason
+	}
+	return msg
+}
+
+// checkBlackout refuses or delays a publish inside a blackout window. In
+// wait mode it sleeps until the window ends when that is within maxWait,
+// returning how long it waited.
+func checkBlackout(ctx context.Context, cfg *Config, now time.Time) (time.Duration, error) {
+	w, until, ok := activeBlackout(cfg.BlackoutWindows, now)
+	if !ok {
+		return 0, nil
+	}
+	wait := until.Sub(now)
+	if cfg.BlackoutMode != blackoutWait || wait > time.Duration(cfg.BlackoutMaxWait)*time.Second {
+		return 0, fmt.Errorf("%s", blackoutMessage(w, until))
+	}
+	if err := retrySleep(ctx, wait); err != nil {
+		return 0, err
+	}
+	return wait, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateBlackoutWindows(t *testing.T) {
+	tests := []struct {
+		name    string
+		w       BlackoutWindow
+		wantErr bool
+	}{
+		{name: "fixed dates", w: BlackoutWindow{Start: "2024-12-20", End: "2025-01-02"}},
+		{name: "fixed times", w: BlackoutWindow{Start: "2024-12-20T18:00", End: "2024-12-21T06:00", Timezone: "Europe/Berlin"}},
+		{name: "weekend", w: BlackoutWindow{Days: []string{"sat", "Sun"}}},
+		{name: "nightly", w: BlackoutWindow{From: "22:00", To: "02:00"}},
+		{name: "missing end", w: BlackoutWindow{Start: "2024-12-20"}, wantErr: true},
+		{name: "end before start", w: BlackoutWindow{Start: "2024-12-20", End: "2024-12-19"}, wantErr: true},
+		{name: "bad date", w: BlackoutWindow{Start: "20/12/2024", End: "2025-01-02"}, wantErr: true},
+		{name: "fixed and recurring", w: BlackoutWindow{Start: "2024-12-20", End: "2025-01-02", Days: []string{"fri"}}, wantErr: true},
+		{name: "empty", w: BlackoutWindow{Reason: "freeze"}, wantErr: true},
+		{name: "unknown day", w: BlackoutWindow{Days: []string{"friday"}}, wantErr: true},
+		{name: "from without to", w: BlackoutWindow{From: "22:00"}, wantErr: true},
+		{name: "bad clock", w: BlackoutWindow{From: "25:00", To: "02:00"}, wantErr: true},
+		{name: "empty range", w: BlackoutWindow{From: "02:00", To: "02:00"}, wantErr: true},
+		{name: "unknown timezone", w: BlackoutWindow{Days: []string{"fri"}, Timezone: "Mars/Olympus"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateBlackoutWindows([]BlackoutWindow{tt.w}); (err != nil) != tt.wantErr {
+				t.Errorf("validateBlackoutWindows() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestActiveBlackout(t *testing.T) {
+	// 2024-05-10 is a Friday
+	friday := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 10, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name      string
+		windows   []BlackoutWindow
+		now       time.Time
+		wantOK    bool
+		wantUntil time.Time
+	}{
+		{
+			name:      "inside fixed window",
+			windows:   []BlackoutWindow{{Start: "2024-05-09", End: "2024-05-10"}},
+			now:       friday(12, 0),
+			wantOK:    true,
+			wantUntil: time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "after fixed window",
+			windows: []BlackoutWindow{{Start: "2024-05-01T00:00", End: "2024-05-10T09:00"}},
+			now:     friday(12, 0),
+		},
+		{
+			name:      "overnight window started yesterday",
+			windows:   []BlackoutWindow{{From: "22:00", To: "02:00"}},
+			now:       friday(1, 30),
+			wantOK:    true,
+			wantUntil: friday(2, 0),
+		},
+		{
+			name:    "outside overnight window",
+			windows: []BlackoutWindow{{From: "22:00", To: "02:00"}},
+			now:     friday(12, 0),
+		},
+		{
+			name:    "other weekday",
+			windows: []BlackoutWindow{{Days: []string{"sat", "sun"}}},
+			now:     friday(12, 0),
+		},
+		{
+			name:      "weekend runs to monday",
+			windows:   []BlackoutWindow{{Days: []string{"sat"}}, {Days: []string{"sun"}}},
+			now:       time.Date(2024, 5, 11, 9, 0, 0, 0, time.UTC),
+			wantOK:    true,
+			wantUntil: time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "friday afternoon freeze in timezone",
+			windows:   []BlackoutWindow{{Days: []string{"fri"}, From: "15:00", To: "23:59", Timezone: "America/New_York"}},
+			now:       friday(20, 0),
+			wantOK:    true,
+			wantUntil: time.Date(2024, 5, 11, 3, 59, 0, 0, time.UTC),
+		},
+		{
+			name:    "before window in timezone",
+			windows: []BlackoutWindow{{Days: []string{"fri"}, From: "15:00", To: "23:59", Timezone: "America/New_York"}},
+			now:     friday(16, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, until, ok := activeBlackout(tt.windows, tt.now)
+			if ok != tt.wantOK {
+				t.Fatalf("activeBlackout() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && !until.Equal(tt.wantUntil) {
+				t.Errorf("activeBlackout() until = %v, want %v", until.UTC(), tt.wantUntil)
+			}
+		})
+	}
+}
+
+func TestCheckBlackout(t *testing.T) {
+	now := time.Date(2024, 5, 10, 1, 30, 0, 0, time.UTC)
+	windows := []BlackoutWindow{{From: "22:00", To: "02:00", Reason: "nightly maintenance"}}
+
+	t.Run("refuse", func(t *testing.T) {
+		sleeps := recordSleeps(t)
+		_, err := checkBlackout(context.Background(), &Config{BlackoutWindows: windows, BlackoutMode: "refuse"}, now)
+		if err == nil || !strings.Contains(err.Error(), "2024-05-10T02:00:00Z: nightly maintenance") {
+			t.Errorf("checkBlackout() error = %v", err)
+		}
+		if len(*sleeps) != 0 {
+			t.Errorf("slept %v", *sleeps)
+		}
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		sleeps := recordSleeps(t)
+		waited, err := checkBlackout(context.Background(), &Config{BlackoutWindows: windows, BlackoutMode: "wait", BlackoutMaxWait: 3600}, now)
+		if err != nil {
+			t.Fatalf("checkBlackout() error = %v", err)
+		}
+		if waited != 30*time.Minute || len(*sleeps) != 1 || (*sleeps)[0] != 30*time.Minute {
+			t.Errorf("waited %v, sleeps %v", waited, *sleeps)
+		}
+	})
+
+	t.Run("wait too long", func(t *testing.T) {
+		recordSleeps(t)
+		_, err := checkBlackout(context.Background(), &Config{BlackoutWindows: windows, BlackoutMode: "wait", BlackoutMaxWait: 600}, now)
+		if err == nil {
+			t.Error("expected refusal beyond blackout_max_wait")
+		}
+	})
+
+	t.Run("outside window", func(t *testing.T) {
+		waited, err := checkBlackout(context.Background(), &Config{BlackoutWindows: windows}, now.Add(time.Hour))
+		if err != nil || waited != 0 {
+			t.Errorf("checkBlackout() = %v, %v", waited, err)
+		}
+	})
+}
+
+func TestBlackoutRefusesPublish(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+	// Every day, all day
+	config := map[string]any{
+		"blackout_windows": []any{map[string]any{
+			"days":   []any{"mon", "tue", "wed", "thu", "fri", "sat", "sun"},
+			"reason": "year-end freeze",
+		}},
+	}
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "year-end freeze") {
+		t.Errorf("expected refusal, got %+v", resp)
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 0 {
+		t.Errorf("npm called during blackout: %v", calls)
+	}
+
+	resp, err = (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+		DryRun:  true,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("dry run failed: %v %+v", err, resp)
+	}
+	warnings, _ := resp.Outputs["warnings"].([]string)
+	if len(warnings) == 0 || !strings.Contains(strings.Join(warnings, "\n"), "publish would be refused") {
+		t.Errorf("warnings = %v", resp.Outputs["warnings"])
+	}
+}
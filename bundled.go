@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Bundled dependency modes.
+const (
+	bundledDepsCheck  = "check"
+	bundledDepsVendor = "vendor"
+)
+
+// bundledIssue describes a bundled dependency npm pack would not include
+// correctly. Source is where the dependency was actually found, if anywhere.
+type bundledIssue struct {
+	Name   string
+	Source string
+	Reason string
+}
+
+// String formats the issue with the remedy, for error messages.
+func (i bundledIssue) String() string {
+	return fmt.Sprintf("bundled dependency %q is %s; npm pack only bundles node_modules inside the package "+
+		"(install it there, e.g. with nohoist or node-linker=hoisted, or set bundled_deps: vendor)", i.Name, i.Reason)
+}
+
+// bundledDependencies returns the bundled dependency names from package.json.
+// Both spellings are supported, as is "true" meaning all dependencies.
+func bundledDependencies(packageDir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(packageDir, "package.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	var raw struct {
+		Bundle       json.RawMessage   `json:"bundleDependencies"`
+		Bundled      json.RawMessage   `json:"bundledDependencies"`
+		Dependencies map[string]string `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	field := raw.Bundle
+	if len(field) == 0 {
+		field = raw.Bundled
+	}
+	if len(field) == 0 {
+		return nil, nil
+	}
+
+	var all bool
+	if err := json.Unmarshal(field, &all); err == nil {
+		if !all {
+			return nil, nil
+		}
+		names := make([]string, 0, len(raw.Dependencies))
+		for name := range raw.Dependencies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+
+	var names []string
+	if err := json.Unmarshal(field, &names); err != nil {
+		return nil, fmt.Errorf("invalid bundleDependencies in package.json: %w", err)
+	}
+	return names, nil
+}
+
+// resolveModule finds name the way Node does, walking up from dir through
+// each ancestor's node_modules. It returns "" when not found.
+func resolveModule(dir, name string) string {
+	for {
+		candidate := filepath.Join(dir, "node_modules", filepath.FromSlash(name))
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// checkBundledDeps reports bundled dependencies that are hoisted out of the
+// package (npm/yarn workspaces) or symlinked into it (pnpm, yarn link).
+func checkBundledDeps(packageDir string) ([]bundledIssue, error) {
+	names, err := bundledDependencies(packageDir)
+	if err != nil {
+		return nil, err
+	}
+	absDir, err := filepath.Abs(packageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []bundledIssue
+	for _, name := range names {
+		local := filepath.Join(absDir, "node_modules", filepath.FromSlash(name))
+		info, err := os.Lstat(local)
+		switch {
+		case err == nil && info.Mode()&os.ModeSymlink != 0:
+			target, err := filepath.EvalSymlinks(local)
+			if err != nil {
+				issues = append(issues, bundledIssue{Name: name, Reason: "a broken symlink"})
+				continue
+			}
+			issues = append(issues, bundledIssue{Name: name, Source: target, Reason: "symlinked to " + target})
+		case err == nil:
+			// Installed inside the package; npm pack bundles it
+		case os.IsNotExist(err):
+			if hoisted := resolveModule(filepath.Dir(absDir), name); hoisted != "" {
+				issues = append(issues, bundledIssue{Name: name, Source: hoisted, Reason: "hoisted to " + hoisted})
+			} else {
+				issues = append(issues, bundledIssue{Name: name, Reason: "not installed"})
+			}
+		default:
+			return nil, fmt.Errorf("failed to stat %s: %w", local, err)
+		}
+	}
+	return issues, nil
+}
+
+// vendorBundledDeps copies each misplaced bundled dependency into the
+// package's node_modules, together with the production dependencies it
+// resolves from its original location that are not already present. It
+// returns the vendored package names.
+func vendorBundledDeps(packageDir string, issues []bundledIssue) ([]string, error) {
+	absDir, err := filepath.Abs(packageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var vendored []string
+	seen := map[string]bool{}
+	var vendor func(name, source string) error
+	vendor = func(name, source string) error {
+		if seen[name] {
+			return nil
+		}
+		seen[name] = true
+
+		source, err := filepath.EvalSymlinks(source)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+		dest := filepath.Join(absDir, "node_modules", filepath.FromSlash(name))
+		if err := os.RemoveAll(dest); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dest, err)
+		}
+		if err := copyDir(source, dest); err != nil {
+			return fmt.Errorf("failed to vendor %s: %w", name, err)
+		}
+		vendored = append(vendored, name)
+
+		pkg, err := readPackageJSON(source)
+		if err != nil {
+			return err
+		}
+		for dep := range pkg.Dependencies {
+			if _, err := os.Stat(filepath.Join(dest, "node_modules", filepath.FromSlash(dep))); err == nil {
+				continue // nested copy already vendored with the package
+			}
+			if _, err := os.Lstat(filepath.Join(absDir, "node_modules", filepath.FromSlash(dep))); err == nil {
+				continue
+			}
+			depSource := resolveModule(source, dep)
+			if depSource == "" {
+				return fmt.Errorf("dependency %s of bundled %s not found", dep, name)
+			}
+			if err := vendor(dep, depSource); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, issue := range issues {
+		if issue.Source == "" {
+			return vendored, fmt.Errorf("cannot vendor: %s", issue)
+		}
+		if err := vendor(issue.Name, issue.Source); err != nil {
+			return vendored, err
+		}
+	}
+	sort.Strings(vendored)
+	return vendored, nil
+}
+
+// copyDir copies src to dest, dereferencing symlinks.
+func copyDir(src, dest string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		from, to := filepath.Join(src, entry.Name()), filepath.Join(dest, entry.Name())
+		info, err := os.Stat(from) // follows symlinks
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if err := copyDir(from, to); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := copyFile(from, to, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies a single regular file.
+func copyFile(src, dest string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBundledDependencies(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []string
+	}{
+		{"none", `{"name":"pkg"}`, nil},
+		{"list", `{"bundleDependencies":["a","b"]}`, []string{"a", "b"}},
+		{"alt_spelling", `{"bundledDependencies":["a"]}`, []string{"a"}},
+		{"true", `{"dependencies":{"b":"1","a":"1"},"bundleDependencies":true}`, []string{"a", "b"}},
+		{"false", `{"dependencies":{"a":"1"},"bundleDependencies":false}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "package.json"), tt.json)
+			got, err := bundledDependencies(dir)
+			if err != nil {
+				t.Fatalf("bundledDependencies returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bundledDependencies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAndVendorBundledDeps(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on Windows")
+	}
+
+	root := t.TempDir()
+	pkgDir := filepath.Join(root, "packages", "pkg")
+	writeFile(t, filepath.Join(pkgDir, "package.json"), `{"name":"pkg","dependencies":{"hoisted":"1","linked":"1","local":"1"},"bundleDependencies":true}`)
+	writeFile(t, filepath.Join(pkgDir, "node_modules", "local", "package.json"), `{"name":"local"}`)
+
+	// npm/yarn workspaces hoist to the root node_modules
+	writeFile(t, filepath.Join(root, "node_modules", "hoisted", "package.json"), `{"name":"hoisted","dependencies":{"transitive":"1"}}`)
+	writeFile(t, filepath.Join(root, "node_modules", "hoisted", "index.js"), "module.exports = 1\n")
+	writeFile(t, filepath.Join(root, "node_modules", "transitive", "package.json"), `{"name":"transitive"}`)
+
+	// pnpm symlinks into its store
+	store := filepath.Join(root, "node_modules", ".pnpm", "linked@1.0.0", "node_modules", "linked")
+	writeFile(t, filepath.Join(store, "package.json"), `{"name":"linked"}`)
+	if err := os.Symlink(store, filepath.Join(pkgDir, "node_modules", "linked")); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := checkBundledDeps(pkgDir)
+	if err != nil {
+		t.Fatalf("checkBundledDeps returned error: %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %v", issues)
+	}
+	if issues[0].Name != "hoisted" || !strings.HasPrefix(issues[0].Reason, "hoisted to ") {
+		t.Errorf("unexpected issue: %+v", issues[0])
+	}
+	if issues[1].Name != "linked" || !strings.HasPrefix(issues[1].Reason, "symlinked to ") {
+		t.Errorf("unexpected issue: %+v", issues[1])
+	}
+	if !strings.Contains(issues[0].String(), "bundled_deps: vendor") {
+		t.Errorf("issue message should suggest a fix: %s", issues[0])
+	}
+
+	vendored, err := vendorBundledDeps(pkgDir, issues)
+	if err != nil {
+		t.Fatalf("vendorBundledDeps returned error: %v", err)
+	}
+	if want := []string{"hoisted", "linked", "transitive"}; !reflect.DeepEqual(vendored, want) {
+		t.Errorf("vendored = %v, want %v", vendored, want)
+	}
+
+	info, err := os.Lstat(filepath.Join(pkgDir, "node_modules", "linked"))
+	if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("expected symlink replaced by a copy, got %v, %v", info, err)
+	}
+	if _, err := os.Stat(filepath.Join(pkgDir, "node_modules", "hoisted", "index.js")); err != nil {
+		t.Errorf("hoisted dependency not copied: %v", err)
+	}
+
+	if issues, _ := checkBundledDeps(pkgDir); len(issues) != 0 {
+		t.Errorf("expected no issues after vendoring, got %v", issues)
+	}
+}
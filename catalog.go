@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// Catalog is an internal package index or developer portal told about each
+// published version.
+type Catalog struct {
+	// URL receives a JSON POST of the package metadata.
+	URL string `json:"url,omitempty"`
+	// TokenEnv names the environment variable holding the catalog token.
+	TokenEnv string `json:"token_env,omitempty"`
+	// Header is the header carrying the token; it defaults to
+	// Authorization, sent as "Bearer <token>". Other headers get the bare
+	// token (e.g. X-API-Key).
+	Header string `json:"header,omitempty"`
+}
+
+// catalogEntry is the metadata posted to the catalog.
+type catalogEntry struct {
+	publishRecord
+	Description string   `json:"description,omitempty"`
+	License     string   `json:"license,omitempty"`
+	Homepage    string   `json:"homepage,omitempty"`
+	Repository  string   `json:"repository,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	CommitSHA   string   `json:"commit_sha,omitempty"`
+	Branch      string   `json:"branch,omitempty"`
+}
+
+// validateCatalog checks the endpoint and token settings.
+func validateCatalog(c Catalog) error {
+	if c.URL == "" {
+		if c.TokenEnv != "" || c.Header != "" {
+			return fmt.Errorf("url is required")
+		}
+		return nil
+	}
+	if err := validateEndpointURL(c.URL, "catalog url"); err != nil {
+		return err
+	}
+	if c.TokenEnv != "" && !inputNamePattern.MatchString(c.TokenEnv) {
+		return fmt.Errorf("token_env %q is not a valid environment variable name", c.TokenEnv)
+	}
+	if c.Header != "" && c.TokenEnv == "" {
+		return fmt.Errorf("header requires token_env")
+	}
+	return nil
+}
+
+// newCatalogEntry describes the published version from its record and
+// package.json.
+func newCatalogEntry(rec *publishRecord, manifest map[string]any, commitSHA, branch string) catalogEntry {
+	entry := catalogEntry{publishRecord: *rec, CommitSHA: commitSHA, Branch: branch}
+	entry.Description, _ = manifest["description"].(string)
+	entry.License, _ = manifest["license"].(string)
+	entry.Homepage, _ = manifest["homepage"].(string)
+	switch repo := manifest["repository"].(type) {
+	case string:
+		entry.Repository = repo
+	case map[string]any:
+		entry.Repository, _ = repo["url"].(string)
+	}
+	if keywords, ok := manifest["keywords"].([]any); ok {
+		for _, k := range keywords {
+			if s, ok := k.(string); ok {
+				entry.Keywords = append(entry.Keywords, s)
+			}
+		}
+	}
+	return entry
+}
+
+// updateCatalog posts entry to the catalog endpoint.
+func updateCatalog(ctx context.Context, c Catalog, entry catalogEntry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal catalog entry: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create catalog request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.TokenEnv != "" {
+		if token := os.Getenv(c.TokenEnv); token != "" {
+			if c.Header == "" || http.CanonicalHeaderKey(c.Header) == "Authorization" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			} else {
+				req.Header.Set(c.Header, token)
+			}
+		}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("catalog request failed: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("catalog returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateCatalog(t *testing.T) {
+	tests := []struct {
+		name    string
+		catalog Catalog
+		wantErr bool
+	}{
+		{name: "unset", catalog: Catalog{}},
+		{name: "url only", catalog: Catalog{URL: "https://portal.example.com/api/packages"}},
+		{name: "api key header", catalog: Catalog{URL: "https://portal.example.com/api/packages", TokenEnv: "PORTAL_KEY", Header: "X-API-Key"}},
+		{name: "insecure", catalog: Catalog{URL: "http://portal.example.com/api"}, wantErr: true},
+		{name: "token without url", catalog: Catalog{TokenEnv: "PORTAL_KEY"}, wantErr: true},
+		{name: "header without token", catalog: Catalog{URL: "https://portal.example.com/api", Header: "X-API-Key"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateCatalog(tt.catalog); (err != nil) != tt.wantErr {
+				t.Errorf("validateCatalog() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCatalogUpdate(t *testing.T) {
+	var entry map[string]any
+	var apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get("X-API-Key")
+		_ = json.NewDecoder(r.Body).Decode(&entry)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+
+	fakeNpm(t, `echo '{"integrity":"sha512-abc"}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("PORTAL_KEY", "key-123")
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0","description":"A lib","keywords":["a","b"],"repository":{"type":"git","url":"https://github.com/acme/lib"}}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"catalog": map[string]any{"url": server.URL, "token_env": "PORTAL_KEY", "header": "X-API-Key"}},
+		Context: plugin.ReleaseContext{Version: "1.0.0", CommitSHA: "deadbeef"},
+	})
+	if err != nil || !resp.Success || resp.Outputs["catalog_updated"] != true {
+		t.Fatalf("unexpected response: %v %+v", err, resp)
+	}
+	if apiKey != "key-123" {
+		t.Errorf("X-API-Key = %q", apiKey)
+	}
+	if entry["name"] != "lib" || entry["version"] != "1.0.0" || entry["integrity"] != "sha512-abc" || entry["description"] != "A lib" ||
+		entry["repository"] != "https://github.com/acme/lib" || entry["commit_sha"] != "deadbeef" || !reflect.DeepEqual(entry["keywords"], []any{"a", "b"}) {
+		t.Errorf("entry = %v", entry)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// cdnPresets maps well-known CDN names to their purge/refresh URL templates.
+// jsDelivr caches tag aliases, so the tag URL is purged; unpkg has no purge
+// API, so the new version is requested to warm its cache.
+var cdnPresets = map[string]string{
+	"jsdelivr": "https://purge.jsdelivr.net/npm/{{.Name}}@{{.Tag}}",
+	"unpkg":    "https://unpkg.com/{{.Name}}@{{.Version}}/package.json",
+}
+
+// cdnPurgeResult records the outcome of a single CDN purge request.
+type cdnPurgeResult struct {
+	URL    string `json:"url"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// cdnPurgeURLs expands the configured CDN presets and URL templates.
+func cdnPurgeURLs(targets []string, data templateData) ([]string, error) {
+	urls := make([]string, 0, len(targets))
+	for _, target := range targets {
+		tmpl, ok := cdnPresets[target]
+		if !ok {
+			tmpl = target
+		}
+
+		rendered, err := renderTemplate(tmpl, data)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateEndpointURL(rendered, "cdn_purge"); err != nil {
+			return nil, err
+		}
+		urls = append(urls, rendered)
+	}
+	return urls, nil
+}
+
+// purgeCDNs requests each URL and records the result. Failures are reported
+// in the results only; a slow CDN must never fail an otherwise good release.
+func purgeCDNs(ctx context.Context, urls []string) []cdnPurgeResult {
+	results := make([]cdnPurgeResult, 0, len(urls))
+	for _, u := range urls {
+		result := cdnPurgeResult{URL: u}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		result.Status = resp.StatusCode
+		if resp.StatusCode >= 400 {
+			result.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCDNPurgeURLs(t *testing.T) {
+	data := templateData{Name: "@scope/pkg", Version: "1.2.3", Tag: "latest"}
+
+	tests := []struct {
+		name    string
+		targets []string
+		want    []string
+		wantErr bool
+	}{
+		{"none", nil, []string{}, false},
+		{"jsdelivr_preset", []string{"jsdelivr"}, []string{"https://purge.jsdelivr.net/npm/@scope/pkg@latest"}, false},
+		{"unpkg_preset", []string{"unpkg"}, []string{"https://unpkg.com/@scope/pkg@1.2.3/package.json"}, false},
+		{"custom_template", []string{"https://cdn.example.com/purge?p={{.Name}}&v={{.Version}}"}, []string{"https://cdn.example.com/purge?p=@scope/pkg&v=1.2.3"}, false},
+		{"http_rejected", []string{"http://cdn.example.com/{{.Name}}"}, nil, true},
+		{"unknown_field", []string{"https://cdn.example.com/{{.Nope}}"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cdnPurgeURLs(tt.targets, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("cdnPurgeURLs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("cdnPurgeURLs() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("url[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPurgeCDNs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	results := purgeCDNs(context.Background(), []string{server.URL + "/ok", server.URL + "/fail"})
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Status != http.StatusOK || results[0].Error != "" {
+		t.Errorf("unexpected result for /ok: %+v", results[0])
+	}
+	if results[1].Status != http.StatusInternalServerError || results[1].Error == "" {
+		t.Errorf("unexpected result for /fail: %+v", results[1])
+	}
+}
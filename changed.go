@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// defaultTagPrefix is assumed for release tags when the current tag does not
+// reveal the prefix.
+const defaultTagPrefix = "v"
+
+// releaseChanges are the files changed since the previous release, relative
+// to the working directory.
+type releaseChanges struct {
+	Base  string
+	Files []string
+}
+
+// previousReleaseTag derives the previous release's tag by applying the
+// current tag's prefix (e.g. "v" or "pkg@") to the previous version.
+func previousReleaseTag(releaseCtx plugin.ReleaseContext) string {
+	prefix := defaultTagPrefix
+	if releaseCtx.TagName != "" && releaseCtx.Version != "" && strings.HasSuffix(releaseCtx.TagName, releaseCtx.Version) {
+		prefix = strings.TrimSuffix(releaseCtx.TagName, releaseCtx.Version)
+	}
+	return prefix + releaseCtx.PreviousVersion
+}
+
+// diffSinceRelease lists the files changed between the previous release tag
+// and the release commit. It returns nil for a first release, when every
+// package counts as changed.
+func diffSinceRelease(ctx context.Context, releaseCtx plugin.ReleaseContext) (*releaseChanges, error) {
+	if releaseCtx.PreviousVersion == "" {
+		return nil, nil
+	}
+	base := previousReleaseTag(releaseCtx)
+	if _, err := runGit(ctx, ".", "rev-parse", "--verify", "--quiet", base+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("previous release tag %s not found", base)
+	}
+	head := releaseCtx.CommitSHA
+	if head == "" {
+		head = "HEAD"
+	}
+	out, err := runGit(ctx, ".", "diff", "--name-only", "--relative", base, head)
+	if err != nil {
+		return nil, err
+	}
+	changes := &releaseChanges{Base: base}
+	for _, line := range strings.Split(out, "\n") {
+		if line != "" {
+			changes.Files = append(changes.Files, line)
+		}
+	}
+	return changes, nil
+}
+
+// touches reports whether any changed file is inside dir (relative to the
+// working directory). Nil changes touch everything.
+func (c *releaseChanges) touches(dir string) bool {
+	if c == nil {
+		return true
+	}
+	if filepath.IsAbs(dir) {
+		if cwd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(cwd, dir); err == nil {
+				dir = rel
+			}
+		}
+	}
+	dir = filepath.ToSlash(filepath.Clean(dir))
+	for _, file := range c.Files {
+		if dir == "." || file == dir || strings.HasPrefix(file, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestPreviousReleaseTag(t *testing.T) {
+	tests := []struct {
+		release plugin.ReleaseContext
+		want    string
+	}{
+		{plugin.ReleaseContext{Version: "1.1.0", PreviousVersion: "1.0.0", TagName: "v1.1.0"}, "v1.0.0"},
+		{plugin.ReleaseContext{Version: "1.1.0", PreviousVersion: "1.0.0", TagName: "pkg@1.1.0"}, "pkg@1.0.0"},
+		{plugin.ReleaseContext{Version: "1.1.0", PreviousVersion: "1.0.0", TagName: "1.1.0"}, "1.0.0"},
+		{plugin.ReleaseContext{Version: "1.1.0", PreviousVersion: "1.0.0"}, "v1.0.0"},
+	}
+	for _, tt := range tests {
+		if got := previousReleaseTag(tt.release); got != tt.want {
+			t.Errorf("previousReleaseTag(%+v) = %q, want %q", tt.release, got, tt.want)
+		}
+	}
+}
+
+// commitAll commits the working tree of the test repository in dir.
+func commitAll(t *testing.T, dir, message string) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := runGit(ctx, dir, "add", "-A"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(ctx, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", message); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// changedMonorepo creates a repository tagged v1.0.0 with two packages and a
+// later commit changing only packages/core.
+func changedMonorepo(t *testing.T) string {
+	t.Helper()
+	dir, _ := initGitRepo(t)
+	writeFile(t, filepath.Join(dir, "packages", "core", "package.json"), `{"name":"core","version":"1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "packages", "ui", "package.json"), `{"name":"ui","version":"1.0.0","dependencies":{"core":"^1.0.0"}}`)
+	commitAll(t, dir, "initial packages")
+	if _, err := runGit(context.Background(), dir, "tag", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "packages", "core", "index.js"), "module.exports = 1\n")
+	commitAll(t, dir, "feat: core entry point")
+	return dir
+}
+
+func TestDiffSinceRelease(t *testing.T) {
+	dir := changedMonorepo(t)
+	chdir(t, dir)
+	ctx := context.Background()
+
+	changes, err := diffSinceRelease(ctx, plugin.ReleaseContext{Version: "1.1.0", PreviousVersion: "1.0.0", TagName: "v1.1.0"})
+	if err != nil {
+		t.Fatalf("diffSinceRelease() error = %v", err)
+	}
+	if changes.Base != "v1.0.0" || !reflect.DeepEqual(changes.Files, []string{"packages/core/index.js"}) {
+		t.Errorf("changes = %+v", changes)
+	}
+	for dir, want := range map[string]bool{"packages/core": true, "./packages/core/": true, "packages/ui": false, "packages/co": false, ".": true} {
+		if got := changes.touches(dir); got != want {
+			t.Errorf("touches(%q) = %v, want %v", dir, got, want)
+		}
+	}
+
+	if changes, err := diffSinceRelease(ctx, plugin.ReleaseContext{Version: "1.0.0"}); err != nil || !changes.touches("packages/ui") {
+		t.Errorf("a first release should touch everything: %+v %v", changes, err)
+	}
+	if _, err := diffSinceRelease(ctx, plugin.ReleaseContext{Version: "3.0.0", PreviousVersion: "2.0.0"}); err == nil {
+		t.Error("expected an error for a missing previous tag")
+	}
+}
+
+func TestOnlyChangedPostPublish(t *testing.T) {
+	fakeNpm(t, `echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	chdir(t, changedMonorepo(t))
+	release := plugin.ReleaseContext{Version: "1.1.0", PreviousVersion: "1.0.0", TagName: "v1.1.0"}
+
+	run := func(config map[string]any) *plugin.ExecuteResponse {
+		t.Helper()
+		config["only_changed"] = true
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  config,
+			Context: release,
+			DryRun:  true,
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("unexpected failure: %v %+v", err, resp)
+		}
+		return resp
+	}
+
+	if resp := run(map[string]any{"package_dir": "packages/ui"}); resp.Outputs["skip_reason"] != "unchanged" {
+		t.Errorf("unchanged package was not skipped: %v", resp.Outputs)
+	}
+	if resp := run(map[string]any{"package_dir": "packages/core"}); resp.Outputs["skipped"] == true {
+		t.Errorf("changed package was skipped: %v", resp.Outputs)
+	}
+	resp := run(map[string]any{"workspaces": []any{"packages/*"}})
+	if got := resp.Outputs["unchanged_packages"]; !reflect.DeepEqual(got, []string{"ui"}) {
+		t.Errorf("unchanged_packages = %v", got)
+	}
+	if resp.Outputs["changed_since"] != "v1.0.0" {
+		t.Errorf("changed_since = %v", resp.Outputs["changed_since"])
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// changelogFiles are the changelog names looked for when none is configured.
+var changelogFiles = []string{"CHANGELOG.md", "HISTORY.md", "CHANGES.md"}
+
+// changelogHeadingRegexp matches a markdown heading mentioning version, e.g.
+// "## [1.2.3] - 2024-01-01", "# v1.2.3" or "### 1.2.3 (2024-01-01)".
+func changelogHeadingRegexp(version string) *regexp.Regexp {
+	return regexp.MustCompile(`(?m)^#{1,6}[ \t]+(?:.*[^\w.-])?v?` + regexp.QuoteMeta(version) + `(?:[^\w.+-]|$)`)
+}
+
+// findChangelog returns the changelog path in packageDir: file when set,
+// otherwise the first of changelogFiles that exists. It returns "" when
+// none is found.
+func findChangelog(packageDir, file string) (string, error) {
+	candidates := changelogFiles
+	if file != "" {
+		candidates = []string{file}
+	}
+	for _, name := range candidates {
+		path := filepath.Join(packageDir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to stat %s: %w", name, err)
+		}
+	}
+	return "", nil
+}
+
+// checkChangelog returns a description of the problem when the package
+// changelog is missing or has no heading for version, or "" when it is current.
+func checkChangelog(packageDir, file, version string) (string, error) {
+	path, err := findChangelog(packageDir, file)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		if file != "" {
+			return fmt.Sprintf("changelog %s not found", file), nil
+		}
+		return "no changelog found in package directory", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	if !changelogHeadingRegexp(version).Match(data) {
+		return fmt.Sprintf("%s has no entry for version %s", filepath.Base(path), version), nil
+	}
+	return "", nil
+}
+
+// validateChangelogFile ensures the changelog path stays inside the package
+// directory.
+func validateChangelogFile(file string) error {
+	if file == "" {
+		return nil
+	}
+	if filepath.IsAbs(file) {
+		return fmt.Errorf("must be relative to package_dir")
+	}
+	clean := filepath.Clean(file)
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("path traversal not allowed")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestCheckChangelog(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		content   string
+		version   string
+		wantIssue bool
+	}{
+		{"keepachangelog", "CHANGELOG.md", "# Changelog\n\n## [1.2.3] - 2024-01-01\n", "1.2.3", false},
+		{"v_prefix", "CHANGELOG.md", "## v1.2.3\n", "1.2.3", false},
+		{"history", "HISTORY.md", "### 1.2.3 (2024-01-01)\n", "1.2.3", false},
+		{"prerelease_heading", "CHANGELOG.md", "## 1.2.3-beta.1\n", "1.2.3-beta.1", false},
+		{"only_in_body", "CHANGELOG.md", "## 1.2.2\n\nUpgrade to 1.2.3 soon\n", "1.2.3", true},
+		{"longer_version", "CHANGELOG.md", "## 1.2.30\n", "1.2.3", true},
+		{"prerelease_not_release", "CHANGELOG.md", "## 1.2.3-beta.1\n", "1.2.3", true},
+		{"missing", "", "", "1.2.3", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.file != "" {
+				if err := os.WriteFile(filepath.Join(dir, tt.file), []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			problem, err := checkChangelog(dir, "", tt.version)
+			if err != nil {
+				t.Fatalf("checkChangelog returned error: %v", err)
+			}
+			if (problem != "") != tt.wantIssue {
+				t.Errorf("checkChangelog() problem = %q, wantIssue %v", problem, tt.wantIssue)
+			}
+		})
+	}
+}
+
+func TestValidateChangelogFile(t *testing.T) {
+	for _, file := range []string{"", "CHANGELOG.md", "docs/CHANGES.md"} {
+		if err := validateChangelogFile(file); err != nil {
+			t.Errorf("validateChangelogFile(%q) unexpected error: %v", file, err)
+		}
+	}
+	for _, file := range []string{"/etc/passwd", "../CHANGELOG.md", "docs/../../CHANGELOG.md"} {
+		if err := validateChangelogFile(file); err == nil {
+			t.Errorf("validateChangelogFile(%q) expected error", file)
+		}
+	}
+}
+
+func TestPrePublishChangelogCheck(t *testing.T) {
+	p := &NpmPlugin{}
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name":"pkg","version":"1.0.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "CHANGELOG.md"), []byte("## [1.0.0]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, tmpDir)
+
+	t.Run("warn", func(t *testing.T) {
+		cfg := &Config{ChangelogCheck: "warn"}
+		resp, err := p.prePublish(ctx, cfg, plugin.ReleaseContext{Version: "1.1.0"}, true)
+		if err != nil || !resp.Success {
+			t.Fatalf("unexpected failure: %v %s", err, resp.Error)
+		}
+		if resp.Outputs["changelog_current"] != false {
+			t.Errorf("changelog_current = %v, want false", resp.Outputs["changelog_current"])
+		}
+		if warnings, _ := resp.Outputs["warnings"].([]string); len(warnings) != 1 {
+			t.Errorf("expected one warning, got %v", resp.Outputs["warnings"])
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		cfg := &Config{ChangelogCheck: "fail"}
+		resp, _ := p.prePublish(ctx, cfg, plugin.ReleaseContext{Version: "1.1.0"}, true)
+		if resp.Success {
+			t.Error("expected failure for missing changelog entry")
+		}
+	})
+
+	t.Run("current", func(t *testing.T) {
+		cfg := &Config{ChangelogCheck: "fail"}
+		resp, _ := p.prePublish(ctx, cfg, plugin.ReleaseContext{Version: "1.0.0"}, true)
+		if !resp.Success || resp.Outputs["changelog_current"] != true {
+			t.Errorf("expected success with changelog_current, got %+v", resp)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// registryPresetCodeArtifact configures publishing to AWS CodeArtifact.
+const registryPresetCodeArtifact = "codeartifact"
+
+// codeArtifactTokenMargin is how much lifetime a cached CodeArtifact token
+// must have left to be reused; older ones are refreshed.
+const codeArtifactTokenMargin = 15 * time.Minute
+
+// Bounds of the token lifetime CodeArtifact accepts, in seconds.
+const (
+	codeArtifactMinDuration = 900
+	codeArtifactMaxDuration = 43200
+)
+
+// awsAccountPattern matches an AWS account ID, the CodeArtifact domain owner.
+var awsAccountPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+// codeArtifactDomainPattern and codeArtifactRepositoryPattern match the
+// names CodeArtifact accepts. Both end up in the endpoint URL.
+var (
+	codeArtifactDomainPattern     = regexp.MustCompile(`^[a-z][a-z0-9-]{0,48}[a-z0-9]$`)
+	codeArtifactRepositoryPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{1,99}$`)
+)
+
+// providerTokenHooks are the hooks that run npm against the registry and so
+// need a minted token. The others at most read package metadata.
+var providerTokenHooks = map[plugin.Hook]bool{
+	plugin.HookPostPublish: true,
+	plugin.HookOnSuccess:   true,
+	plugin.HookOnError:     true,
+}
+
+// registryProvider is a hosted registry service that knows its npm endpoint
+// and mints the short-lived tokens npm authenticates with.
+type registryProvider interface {
+	// endpoint returns the npm registry URL.
+	endpoint() (string, error)
+	// token returns an auth token and when it expires.
+	token(ctx context.Context) (string, time.Time, error)
+}
+
+// CodeArtifact locates an AWS CodeArtifact npm repository.
+type CodeArtifact struct {
+	Domain string `json:"domain,omitempty"`
+	// DomainOwner is the AWS account ID owning the domain.
+	DomainOwner string `json:"domain_owner,omitempty"`
+	Repository  string `json:"repository,omitempty"`
+	// Region defaults to AWS_REGION, then AWS_DEFAULT_REGION.
+	Region string `json:"region,omitempty"`
+	// DurationSeconds is the token lifetime; CodeArtifact defaults to 12
+	// hours.
+	DurationSeconds int `json:"duration_seconds,omitempty"`
+}
+
+// enabled reports whether any CodeArtifact setting is configured.
+func (c CodeArtifact) enabled() bool {
+	return c != CodeArtifact{}
+}
+
+// region returns the configured or ambient AWS region.
+func (c CodeArtifact) region() string {
+	if c.Region != "" {
+		return c.Region
+	}
+	if r := os.Getenv("AWS_REGION"); r != "" {
+		return r
+	}
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+// validateCodeArtifact checks the codeartifact settings against the preset.
+func validateCodeArtifact(cfg *Config) error {
+	c := cfg.CodeArtifact
+	if cfg.RegistryPreset != registryPresetCodeArtifact {
+		if c.enabled() {
+			return fmt.Errorf("requires registry_preset %q", registryPresetCodeArtifact)
+		}
+		return nil
+	}
+	if c.Domain == "" || c.Repository == "" {
+		return fmt.Errorf("domain and repository are required")
+	}
+	if !codeArtifactDomainPattern.MatchString(c.Domain) {
+		return fmt.Errorf("domain %q is not a valid CodeArtifact domain name", c.Domain)
+	}
+	if !codeArtifactRepositoryPattern.MatchString(c.Repository) {
+		return fmt.Errorf("repository %q is not a valid CodeArtifact repository name", c.Repository)
+	}
+	if !awsAccountPattern.MatchString(c.DomainOwner) {
+		return fmt.Errorf("domain_owner %q is not an AWS account ID", c.DomainOwner)
+	}
+	if c.DurationSeconds != 0 && (c.DurationSeconds < codeArtifactMinDuration || c.DurationSeconds > codeArtifactMaxDuration) {
+		return fmt.Errorf("duration_seconds must be between %d and %d", codeArtifactMinDuration, codeArtifactMaxDuration)
+	}
+	e := cfg.EphemeralNpmrc
+	if !cfg.providerAuth && (e.TokenEnv != "" || e.Token != "") {
+		return fmt.Errorf("cannot be combined with an ephemeral_npmrc token, the token is minted")
+	}
+	if cfg.UserConfig != "" {
+		return fmt.Errorf("cannot be combined with userconfig")
+	}
+	if cfg.TrustedPublishing || cfg.TokenExchange {
+		return fmt.Errorf("cannot be combined with trusted_publishing or token_exchange")
+	}
+	return nil
+}
+
+// codeArtifactProvider mints CodeArtifact tokens through the AWS CLI, which
+// picks up the runner's AWS credentials however they are configured.
+type codeArtifactProvider struct {
+	c CodeArtifact
+}
+
+// endpoint builds the repository's npm endpoint, as
+// get-repository-endpoint would return it.
+func (p codeArtifactProvider) endpoint() (string, error) {
+	region := p.c.region()
+	if region == "" {
+		return "", fmt.Errorf("no AWS region; set codeartifact.region or AWS_REGION")
+	}
+	return fmt.Sprintf("https://%s-%s.d.codeartifact.%s.amazonaws.com/npm/%s/", p.c.Domain, p.c.DomainOwner, region, p.c.Repository), nil
+}
+
+// token calls GetAuthorizationToken.
+func (p codeArtifactProvider) token(ctx context.Context) (string, time.Time, error) {
+	args := []string{
+		"codeartifact", "get-authorization-token",
+		"--domain", p.c.Domain,
+		"--domain-owner", p.c.DomainOwner,
+		"--output", "json",
+	}
+	if region := p.c.region(); region != "" {
+		args = append(args, "--region", region)
+	}
+	if p.c.DurationSeconds != 0 {
+		args = append(args, "--duration-seconds", strconv.Itoa(p.c.DurationSeconds))
+	}
+	cmd := exec.CommandContext(ctx, "aws", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	start := time.Now()
+	err := cmd.Run()
+	recordCommand(ctx, "", "aws", args, start, err)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("aws codeartifact get-authorization-token failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	var out struct {
+		AuthorizationToken string `json:"authorizationToken"`
+		Expiration         string `json:"expiration"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to decode the CodeArtifact token: %w", err)
+	}
+	if out.AuthorizationToken == "" {
+		return "", time.Time{}, fmt.Errorf("CodeArtifact returned no token")
+	}
+	expires, err := time.Parse(time.RFC3339, out.Expiration)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid CodeArtifact token expiration %q", out.Expiration)
+	}
+	return out.AuthorizationToken, expires, nil
+}
+
+// registryProviderFor returns the provider behind the registry preset, if
+// any.
+func registryProviderFor(cfg *Config) registryProvider {
+	if cfg.RegistryPreset == registryPresetCodeArtifact {
+		return codeArtifactProvider{c: cfg.CodeArtifact}
+	}
+	return nil
+}
+
+// providerTokens caches minted tokens per registry, so repeated hooks in one
+// process reuse a token until it nears expiry.
+var providerTokens = struct {
+	sync.Mutex
+	m map[string]trustedToken
+}{m: map[string]trustedToken{}}
+
+// providerToken returns a cached token for registry with enough lifetime
+// left, minting a new one otherwise.
+func providerToken(ctx context.Context, p registryProvider, registry string) (string, error) {
+	providerTokens.Lock()
+	defer providerTokens.Unlock()
+	if tok, ok := providerTokens.m[registry]; ok && time.Until(tok.expires) > codeArtifactTokenMargin {
+		return tok.token, nil
+	}
+	delete(providerTokens.m, registry)
+	token, expires, err := p.token(ctx)
+	if err != nil {
+		return "", err
+	}
+	providerTokens.m[registry] = trustedToken{token: token, expires: expires}
+	return token, nil
+}
+
+// applyRegistryProvider points the registry at the provider's endpoint,
+// unless one is configured. In the hooks that run npm against the registry
+// it also authenticates npm through an ephemeral npmrc holding a freshly
+// minted token; a dry run that cannot mint one goes ahead unauthenticated.
+// A token configured in ephemeral_npmrc is left for validateCodeArtifact to
+// reject.
+func applyRegistryProvider(ctx context.Context, cfg *Config, hook plugin.Hook, dryRun bool) error {
+	p := registryProviderFor(cfg)
+	if p == nil {
+		return nil
+	}
+	endpoint, err := p.endpoint()
+	if err != nil {
+		return err
+	}
+	if cfg.Registry == "" {
+		cfg.Registry = endpoint
+		if err := checkRegistryPolicy(endpoint, cfg.AllowedRegistries); err != nil {
+			return err
+		}
+	}
+	if !providerTokenHooks[hook] || cfg.EphemeralNpmrc.TokenEnv != "" || cfg.EphemeralNpmrc.Token != "" {
+		return nil
+	}
+	token, err := providerToken(ctx, p, endpoint)
+	if err != nil && !dryRun {
+		return err
+	}
+	cfg.EphemeralNpmrc.Enabled = true
+	cfg.EphemeralNpmrc.Token = token
+	cfg.providerAuth = true
+	return nil
+}
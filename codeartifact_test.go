@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// fakeAWS puts an aws script running body on PATH and returns its log path.
+func fakeAWS(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake aws script requires a POSIX shell")
+	}
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "aws.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + logPath + "\n" + body + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "aws"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake aws: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+func TestValidateCodeArtifact(t *testing.T) {
+	repo := CodeArtifact{Domain: "acme", DomainOwner: "123456789012", Repository: "npm"}
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "unused", cfg: Config{}},
+		{name: "valid", cfg: Config{RegistryPreset: registryPresetCodeArtifact, CodeArtifact: repo}},
+		{name: "without preset", cfg: Config{CodeArtifact: repo}, wantErr: true},
+		{name: "missing repository", cfg: Config{RegistryPreset: registryPresetCodeArtifact, CodeArtifact: CodeArtifact{Domain: "acme", DomainOwner: "123456789012"}}, wantErr: true},
+		{name: "bad domain", cfg: Config{RegistryPreset: registryPresetCodeArtifact, CodeArtifact: CodeArtifact{Domain: "acme.evil.com/x", DomainOwner: "123456789012", Repository: "npm"}}, wantErr: true},
+		{name: "bad repository", cfg: Config{RegistryPreset: registryPresetCodeArtifact, CodeArtifact: CodeArtifact{Domain: "acme", DomainOwner: "123456789012", Repository: "npm/../x"}}, wantErr: true},
+		{name: "bad owner", cfg: Config{RegistryPreset: registryPresetCodeArtifact, CodeArtifact: CodeArtifact{Domain: "acme", DomainOwner: "acme", Repository: "npm"}}, wantErr: true},
+		{name: "short duration", cfg: Config{RegistryPreset: registryPresetCodeArtifact, CodeArtifact: CodeArtifact{Domain: "acme", DomainOwner: "123456789012", Repository: "npm", DurationSeconds: 60}}, wantErr: true},
+		{name: "npmrc token", cfg: Config{RegistryPreset: registryPresetCodeArtifact, CodeArtifact: repo, EphemeralNpmrc: EphemeralNpmrc{Enabled: true, TokenEnv: "TOKEN"}}, wantErr: true},
+		{name: "token exchange", cfg: Config{RegistryPreset: registryPresetCodeArtifact, CodeArtifact: repo, TokenExchange: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateCodeArtifact(&tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateCodeArtifact() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCodeArtifactEndpoint(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "eu-west-1")
+	p := codeArtifactProvider{c: CodeArtifact{Domain: "acme", DomainOwner: "123456789012", Repository: "npm"}}
+	got, err := p.endpoint()
+	if want := "https://acme-123456789012.d.codeartifact.eu-west-1.amazonaws.com/npm/npm/"; err != nil || got != want {
+		t.Errorf("endpoint() = %q, %v; want %q", got, err, want)
+	}
+
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	if _, err := p.endpoint(); err == nil {
+		t.Error("endpoint() succeeded without a region")
+	}
+}
+
+func TestCodeArtifactToken(t *testing.T) {
+	logPath := fakeAWS(t, `echo '{"authorizationToken":"ca-token","expiration":"2026-03-01T12:00:00+00:00"}'`)
+	p := codeArtifactProvider{c: CodeArtifact{Domain: "acme", DomainOwner: "123456789012", Repository: "npm", Region: "us-east-1", DurationSeconds: 3600}}
+	token, expires, err := p.token(context.Background())
+	if err != nil || token != "ca-token" || !expires.Equal(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("token() = %q, %v, %v", token, expires, err)
+	}
+	calls := npmCalls(t, logPath)
+	want := "codeartifact get-authorization-token --domain acme --domain-owner 123456789012 --output json --region us-east-1 --duration-seconds 3600"
+	if len(calls) != 1 || calls[0] != want {
+		t.Errorf("aws calls = %v, want %q", calls, want)
+	}
+
+	fakeAWS(t, `echo "Unable to locate credentials" >&2; exit 255`)
+	if _, _, err := p.token(context.Background()); err == nil || !strings.Contains(err.Error(), "Unable to locate credentials") {
+		t.Errorf("token() error = %v, want the aws error", err)
+	}
+}
+
+// stubProvider mints numbered tokens that expire after ttl.
+type stubProvider struct {
+	ttl    time.Duration
+	minted int
+	err    error
+}
+
+func (s *stubProvider) endpoint() (string, error) { return "https://npm.example.com/", nil }
+
+func (s *stubProvider) token(context.Context) (string, time.Time, error) {
+	if s.err != nil {
+		return "", time.Time{}, s.err
+	}
+	s.minted++
+	return "token-" + strconv.Itoa(s.minted), time.Now().Add(s.ttl), nil
+}
+
+func TestProviderTokenRefresh(t *testing.T) {
+	fresh := &stubProvider{ttl: 12 * time.Hour}
+	for i := 0; i < 2; i++ {
+		if token, err := providerToken(context.Background(), fresh, "https://fresh.example.com/"); err != nil || token != "token-1" {
+			t.Errorf("providerToken() = %q, %v; want the cached token-1", token, err)
+		}
+	}
+
+	expiring := &stubProvider{ttl: time.Minute}
+	for _, want := range []string{"token-1", "token-2"} {
+		if token, err := providerToken(context.Background(), expiring, "https://expiring.example.com/"); err != nil || token != want {
+			t.Errorf("providerToken() = %q, %v; want a refreshed %s", token, err, want)
+		}
+	}
+
+	failing := &stubProvider{err: errors.New("denied")}
+	if _, err := providerToken(context.Background(), failing, "https://failing.example.com/"); err == nil {
+		t.Error("providerToken() hid the provider error")
+	}
+}
+
+func TestCodeArtifactPublish(t *testing.T) {
+	fakeAWS(t, `echo '{"authorizationToken":"ca-publish-token","expiration":"`+time.Now().Add(12*time.Hour).UTC().Format(time.RFC3339)+`"}'`)
+	npmrcLog := filepath.Join(t.TempDir(), "npmrc")
+	fakeNpm(t, `if [ "$1" = publish ]; then
+  while [ $# -gt 0 ]; do
+    if [ "$1" = --userconfig ]; then cat "$2" > `+npmrcLog+`; fi
+    shift
+  done
+fi
+echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"registry":        "https://npm.example.com/",
+			"registry_preset": "codeartifact",
+			"codeartifact":    map[string]any{"domain": "acme", "domain_owner": "123456789012", "repository": "publish", "region": "us-east-1"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	content, err := os.ReadFile(npmrcLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "//npm.example.com/:_authToken=ca-publish-token\n" {
+		t.Errorf("npmrc = %q", content)
+	}
+}
+
+func TestCodeArtifactTokenFailure(t *testing.T) {
+	fakeAWS(t, `echo "AccessDeniedException" >&2; exit 254`)
+	fakeNpm(t, `echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"registry_preset": "codeartifact",
+			"codeartifact":    map[string]any{"domain": "acme", "domain_owner": "123456789012", "repository": "denied", "region": "us-east-1"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "AccessDeniedException") {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestCodeArtifactTokenHooks(t *testing.T) {
+	logPath := fakeAWS(t, `echo '{"authorizationToken":"ca-token","expiration":"`+time.Now().Add(12*time.Hour).UTC().Format(time.RFC3339)+`"}'`)
+	fakeNpm(t, `echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+	config := map[string]any{
+		"registry_preset": "codeartifact",
+		"codeartifact":    map[string]any{"domain": "acme", "domain_owner": "123456789012", "repository": "hooks", "region": "us-east-1"},
+	}
+
+	for _, hook := range []plugin.Hook{plugin.HookPostNotes, plugin.HookPrePublish} {
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    hook,
+			Config:  config,
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("%s: unexpected failure: %v %+v", hook, err, resp)
+		}
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 0 {
+		t.Errorf("tokens minted outside the registry hooks: %v", calls)
+	}
+
+	config["ephemeral_npmrc"] = map[string]any{"enabled": true, "token_env": "NPM_TOKEN"}
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "token is minted") {
+		t.Errorf("configured ephemeral_npmrc token was overwritten: %+v", resp)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// defaultConsumerEventType is the repository_dispatch event type sent to
+// consumer repositories.
+const defaultConsumerEventType = "npm_package_published"
+
+// consumerRepoPattern matches "owner/name" repository references.
+var consumerRepoPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// Consumers are repositories notified after a publish so they can bump to
+// the new version.
+type Consumers struct {
+	// Repos are the consumer repositories as "owner/name".
+	Repos []string `json:"repos,omitempty"`
+	// URL is the endpoint template called for each repo, with {{.Repo}} set
+	// to the repository. It defaults to GitHub's repository_dispatch API.
+	URL string `json:"url,omitempty"`
+	// EventType is the dispatch event type (default npm_package_published).
+	EventType string `json:"event_type,omitempty"`
+	// TokenEnv names the environment variable holding the bearer token
+	// (default GITHUB_TOKEN).
+	TokenEnv string `json:"token_env,omitempty"`
+}
+
+// consumerDispatch is the body posted to each consumer endpoint, shaped as
+// a GitHub repository_dispatch request.
+type consumerDispatch struct {
+	EventType     string         `json:"event_type"`
+	ClientPayload consumerUpdate `json:"client_payload"`
+}
+
+// consumerUpdate tells a consumer repository what to bump to.
+type consumerUpdate struct {
+	updateHint
+	PreviousVersion string `json:"previous_version,omitempty"`
+	Source          string `json:"source,omitempty"`
+}
+
+// consumerResult records the outcome of notifying one consumer.
+type consumerResult struct {
+	Repo   string `json:"repo"`
+	URL    string `json:"url"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// consumerURLTemplate returns the endpoint template for consumers.
+func consumerURLTemplate(c Consumers) string {
+	if c.URL != "" {
+		return c.URL
+	}
+	return strings.TrimSuffix(githubAPIURL, "/") + "/repos/{{.Repo}}/dispatches"
+}
+
+// validateConsumers checks the repository references and that the URL
+// template renders to an allowed endpoint.
+func validateConsumers(c Consumers) error {
+	if len(c.Repos) == 0 {
+		if c.URL != "" || c.EventType != "" || c.TokenEnv != "" {
+			return fmt.Errorf("repos is required")
+		}
+		return nil
+	}
+	for _, repo := range c.Repos {
+		if !consumerRepoPattern.MatchString(repo) {
+			return fmt.Errorf("repo %q must be owner/name", repo)
+		}
+	}
+	if c.TokenEnv != "" && !inputNamePattern.MatchString(c.TokenEnv) {
+		return fmt.Errorf("token_env %q is not a valid environment variable name", c.TokenEnv)
+	}
+	_, err := consumerURLs(c, templateData{Name: "pkg", Version: "1.0.0"})
+	return err
+}
+
+// consumerURLs renders the endpoint for each consumer repo.
+func consumerURLs(c Consumers, data templateData) ([]string, error) {
+	urls := make([]string, 0, len(c.Repos))
+	for _, repo := range c.Repos {
+		data.Repo = repo
+		rendered, err := renderTemplate(consumerURLTemplate(c), data)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateEndpointURL(rendered, "consumers url"); err != nil {
+			return nil, err
+		}
+		urls = append(urls, rendered)
+	}
+	return urls, nil
+}
+
+// notifyConsumers posts the update to every consumer endpoint and records
+// the results. Failures are reported, never fatal: the release has already
+// succeeded and the bumps can be triggered by hand.
+func notifyConsumers(ctx context.Context, c Consumers, urls []string, update consumerUpdate) []consumerResult {
+	eventType := c.EventType
+	if eventType == "" {
+		eventType = defaultConsumerEventType
+	}
+	tokenEnv := c.TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = "GITHUB_TOKEN"
+	}
+	token := os.Getenv(tokenEnv)
+	body, _ := json.Marshal(consumerDispatch{EventType: eventType, ClientPayload: update})
+
+	results := make([]consumerResult, 0, len(urls))
+	for i, u := range urls {
+		result := consumerResult{Repo: c.Repos[i], URL: u}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		result.Status = resp.StatusCode
+		if resp.StatusCode >= 400 {
+			result.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		}
+		results = append(results, result)
+	}
+	return results
+}
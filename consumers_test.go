@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateConsumers(t *testing.T) {
+	tests := []struct {
+		name      string
+		consumers Consumers
+		wantErr   bool
+	}{
+		{name: "unset", consumers: Consumers{}},
+		{name: "github default", consumers: Consumers{Repos: []string{"acme/web", "acme/api"}}},
+		{name: "custom endpoint", consumers: Consumers{Repos: []string{"acme/web"}, URL: "https://bots.example.com/bump/{{.Repo}}?v={{.Version}}"}},
+		{name: "bad repo", consumers: Consumers{Repos: []string{"acme"}}, wantErr: true},
+		{name: "insecure endpoint", consumers: Consumers{Repos: []string{"acme/web"}, URL: "http://bots.example.com/{{.Repo}}"}, wantErr: true},
+		{name: "unknown field", consumers: Consumers{Repos: []string{"acme/web"}, URL: "https://bots.example.com/{{.Nope}}"}, wantErr: true},
+		{name: "bad token env", consumers: Consumers{Repos: []string{"acme/web"}, TokenEnv: "BAD-NAME"}, wantErr: true},
+		{name: "settings without repos", consumers: Consumers{EventType: "bump"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateConsumers(tt.consumers); (err != nil) != tt.wantErr {
+				t.Errorf("validateConsumers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNotifyConsumers(t *testing.T) {
+	var mu sync.Mutex
+	dispatched := map[string]consumerDispatch{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer bot-token" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Path == "/repos/acme/broken/dispatches" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body consumerDispatch
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		dispatched[r.URL.Path] = body
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	fakeNpm(t, `echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("BOT_TOKEN", "bot-token")
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.3.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{"consumers": map[string]any{
+			"repos":     []any{"acme/web", "acme/broken"},
+			"url":       server.URL + "/repos/{{.Repo}}/dispatches",
+			"token_env": "BOT_TOKEN",
+		}},
+		Context: plugin.ReleaseContext{Version: "1.3.0", PreviousVersion: "1.2.0", RepositoryOwner: "acme", RepositoryName: "lib"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+
+	got := dispatched["/repos/acme/web/dispatches"]
+	want := consumerUpdate{updateHint: newUpdateHint("lib", "1.3.0", "1.2.0", "", "latest"), PreviousVersion: "1.2.0", Source: "acme/lib"}
+	if got.EventType != defaultConsumerEventType || !reflect.DeepEqual(got.ClientPayload, want) {
+		t.Errorf("dispatch = %+v", got)
+	}
+	results := resp.Outputs["consumers"].([]consumerResult)
+	if len(results) != 2 || results[0].Error != "" || results[1].Status != http.StatusNotFound {
+		t.Errorf("results = %+v", results)
+	}
+	if resp.Outputs["warnings"] == nil {
+		t.Error("expected a warning for the failed consumer")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// validateCopyFiles checks that copy_files names files inside the working
+// directory with distinct base names, as each lands in the package root.
+func validateCopyFiles(files []string) error {
+	seen := map[string]string{}
+	for _, f := range files {
+		if f == "" || !filepath.IsLocal(f) {
+			return fmt.Errorf("%q must be a path inside the working directory", f)
+		}
+		base := filepath.Base(f)
+		if other, ok := seen[base]; ok {
+			return fmt.Errorf("%q and %q would both be copied to %s", other, f, base)
+		}
+		seen[base] = f
+	}
+	return nil
+}
+
+// copyIntoPackage copies files from the working directory into the root of
+// packageDir so they are packed with it. Files the package already has are
+// left alone, so a package-specific LICENSE wins. It returns the copied names
+// and a func removing them again.
+func copyIntoPackage(packageDir string, files []string) ([]string, func() error, error) {
+	var copied []string
+	remove := func() error {
+		var errs []error
+		for _, name := range copied {
+			if err := os.Remove(filepath.Join(packageDir, name)); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("failed to remove copied %s: %w", name, err))
+			}
+		}
+		copied = nil
+		return errors.Join(errs...)
+	}
+	for _, f := range files {
+		dest := filepath.Join(packageDir, filepath.Base(f))
+		if _, err := os.Lstat(dest); err == nil {
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			_ = remove()
+			return nil, nil, fmt.Errorf("copy_files: %w", err)
+		}
+		if err := os.WriteFile(dest, data, 0644); err != nil {
+			_ = remove()
+			return nil, nil, fmt.Errorf("copy_files: failed to write %s: %w", dest, err)
+		}
+		copied = append(copied, filepath.Base(f))
+	}
+	names := append([]string(nil), copied...)
+	return names, remove, nil
+}
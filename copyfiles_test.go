@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateCopyFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		wantErr bool
+	}{
+		{name: "root files", files: []string{"LICENSE", "legal/NOTICE"}},
+		{name: "outside", files: []string{"../LICENSE"}, wantErr: true},
+		{name: "absolute", files: []string{"/etc/passwd"}, wantErr: true},
+		{name: "same base name", files: []string{"LICENSE", "legal/LICENSE"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateCopyFiles(tt.files); (err != nil) != tt.wantErr {
+				t.Errorf("validateCopyFiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublishCopyFiles(t *testing.T) {
+	logPath := fakeNpm(t, `[ "$1" = publish ] && echo "$(basename "$PWD") $(cat LICENSE) $(cat NOTICE)" >> "$(dirname "$0")/packed.log"
+echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "LICENSE"), "MIT")
+	writeFile(t, filepath.Join(dir, "legal", "NOTICE"), "notice")
+	writeFile(t, filepath.Join(dir, "packages", "a", "package.json"), `{"name":"a","version":"1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "packages", "b", "package.json"), `{"name":"b","version":"1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "packages", "b", "LICENSE"), "Apache-2.0")
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"workspaces": []any{"packages/*"}, "copy_files": []any{"LICENSE", "legal/NOTICE"}},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	packed := npmCalls(t, filepath.Join(filepath.Dir(logPath), "packed.log"))
+	if want := []string{"a MIT notice", "b Apache-2.0 notice"}; !reflect.DeepEqual(packed, want) {
+		t.Errorf("packed = %v, want %v", packed, want)
+	}
+	for _, f := range []string{"packages/a/LICENSE", "packages/a/NOTICE", "packages/b/NOTICE"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed after publishing", f)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "packages", "b", "LICENSE")); string(data) != "Apache-2.0" {
+		t.Errorf("package LICENSE was replaced: %s", data)
+	}
+}
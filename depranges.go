@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dependent range strategies.
+const (
+	dependentRangesExact = "exact"
+	dependentRangesCaret = "caret"
+	dependentRangesTilde = "tilde"
+)
+
+// dependentRangeGroups are the dependency fields whose ranges follow a
+// sibling's release. optionalDependencies are left alone, as npm tolerates
+// them failing to resolve.
+var dependentRangeGroups = []string{"dependencies", "devDependencies", "peerDependencies"}
+
+// dependentRange returns the range a dependent uses for version.
+func dependentRange(strategy, version string) string {
+	switch strategy {
+	case dependentRangesCaret:
+		return "^" + version
+	case dependentRangesTilde:
+		return "~" + version
+	}
+	return version
+}
+
+// updateDependentRanges sets the range every other workspace package uses
+// for name to version, following strategy. Specs that are not semver
+// ranges, such as workspace: protocols, dist-tags or git URLs, are left as
+// they are. Files are edited in place so their formatting is kept. It
+// returns the "dependent/group" entries that changed; dry runs only report
+// them.
+func updateDependentRanges(cfg *Config, name, version string, dryRun bool) ([]string, error) {
+	pkgs, err := loadWorkspacePackages(cfg.Workspaces)
+	if err != nil {
+		return nil, err
+	}
+	want := dependentRange(cfg.DependentRanges, version)
+	var updated []string
+	for _, pkg := range pkgs {
+		if pkg.Name == name {
+			continue
+		}
+		path := filepath.Join(pkg.Dir, "package.json")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return updated, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var manifest workspaceManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return updated, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		specs := map[string]map[string]string{
+			"dependencies":     manifest.Dependencies,
+			"devDependencies":  manifest.DevDependencies,
+			"peerDependencies": manifest.PeerDependencies,
+		}
+		changed := false
+		for _, group := range dependentRangeGroups {
+			spec, ok := specs[group][name]
+			if !ok || spec == want || !dependentRangeSpec(spec) {
+				continue
+			}
+			next, ok, err := replaceJSONString(data, []string{group, name}, want)
+			if err != nil {
+				return updated, fmt.Errorf("failed to update %s: %w", path, err)
+			}
+			if ok {
+				data, changed = next, true
+				updated = append(updated, pkg.Name+"/"+group)
+			}
+		}
+		if changed && !dryRun {
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				return updated, fmt.Errorf("failed to write %s: %w", path, err)
+			}
+		}
+	}
+	return updated, nil
+}
+
+// dependentRangeSpec reports whether spec is a registry semver range that
+// a release should move. "*" and empty specs already accept every version
+// and are kept.
+func dependentRangeSpec(spec string) bool {
+	if spec = strings.TrimSpace(spec); spec == "" || spec == "*" {
+		return false
+	}
+	for _, protocol := range localProtocols {
+		if strings.HasPrefix(spec, protocol) {
+			return false
+		}
+	}
+	_, err := parseRange(spec)
+	return err == nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestDependentRangeSpec(t *testing.T) {
+	tests := map[string]bool{
+		"^1.0.0":         true,
+		"~1.2":           true,
+		"1.0.0":          true,
+		">=1.0.0 <2.0.0": true,
+		"*":              false,
+		"":               false,
+		"workspace:^":    false,
+		"file:../core":   false,
+		"latest":         false,
+		"github:org/rep": false,
+	}
+	for spec, want := range tests {
+		if got := dependentRangeSpec(spec); got != want {
+			t.Errorf("dependentRangeSpec(%q) = %v, want %v", spec, got, want)
+		}
+	}
+}
+
+func TestUpdateDependentRanges(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     string
+	}{
+		{dependentRangesExact, "2.0.0"},
+		{dependentRangesCaret, "^2.0.0"},
+		{dependentRangesTilde, "~2.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			dir := t.TempDir()
+			chdir(t, dir)
+			writeFile(t, filepath.Join(dir, "packages", "core", "package.json"), `{"name":"core","version":"1.0.0"}`)
+			app := filepath.Join(dir, "packages", "app", "package.json")
+			writeFile(t, app, `{
+    "name": "app",
+    "version": "1.0.0",
+    "dependencies": {"core": "^1.0.0", "left-pad": "^1.3.0"},
+    "devDependencies": {"core": "workspace:*"},
+    "peerDependencies": {"core": "*"}
+}
+`)
+			cfg := &Config{Workspaces: []string{"packages/*"}, DependentRanges: tt.strategy}
+
+			updated, err := updateDependentRanges(cfg, "core", "2.0.0", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(updated, []string{"app/dependencies"}) {
+				t.Errorf("updated = %v", updated)
+			}
+			if data, _ := os.ReadFile(app); string(data) != `{
+    "name": "app",
+    "version": "1.0.0",
+    "dependencies": {"core": "^1.0.0", "left-pad": "^1.3.0"},
+    "devDependencies": {"core": "workspace:*"},
+    "peerDependencies": {"core": "*"}
+}
+` {
+				t.Errorf("dry run wrote package.json:\n%s", data)
+			}
+
+			if _, err := updateDependentRanges(cfg, "core", "2.0.0", false); err != nil {
+				t.Fatal(err)
+			}
+			want := `{
+    "name": "app",
+    "version": "1.0.0",
+    "dependencies": {"core": "` + tt.want + `", "left-pad": "^1.3.0"},
+    "devDependencies": {"core": "workspace:*"},
+    "peerDependencies": {"core": "*"}
+}
+`
+			if data, _ := os.ReadFile(app); string(data) != want {
+				t.Errorf("package.json =\n%s\nwant\n%s", data, want)
+			}
+		})
+	}
+}
+
+func TestPrePublishDependentRanges(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+	writeFile(t, filepath.Join(dir, "packages", "core", "package.json"), `{"name":"core","version":"1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "packages", "app", "package.json"), `{"name":"app","version":"1.0.0","dependencies":{"core":"^1.0.0"},"peerDependencies":{"core":"^1.0.0"}}`)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPrePublish,
+		Config:  map[string]any{"workspaces": []any{"packages/*"}, "dependent_ranges": "exact"},
+		Context: plugin.ReleaseContext{Version: "1.1.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	pkg, err := readPackageJSON(filepath.Join(dir, "packages", "app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest workspaceManifest
+	data, _ := os.ReadFile(filepath.Join(dir, "packages", "app", "package.json"))
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Version != "1.1.0" || manifest.Dependencies["core"] != "1.1.0" || manifest.PeerDependencies["core"] != "1.1.0" {
+		t.Errorf("app package.json = %s", data)
+	}
+}
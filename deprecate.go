@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// defaultDeprecateMessage is the deprecation message template for
+// superseded versions.
+const defaultDeprecateMessage = "Superseded by {{.Name}}@{{.Version}}"
+
+// Deprecate marks older versions as deprecated after a publish, e.g. every
+// version below a security fix.
+type Deprecate struct {
+	// Ranges are semver ranges of versions to deprecate ("<1.4.2", "1.x").
+	// The version just published is never deprecated.
+	Ranges []string `json:"ranges,omitempty"`
+	// Message is the deprecation message template; it defaults to
+	// "Superseded by {{.Name}}@{{.Version}}".
+	Message string `json:"message,omitempty"`
+}
+
+// validateDeprecate checks the ranges and message template.
+func validateDeprecate(d Deprecate) error {
+	if len(d.Ranges) == 0 {
+		if d.Message != "" {
+			return fmt.Errorf("ranges is required")
+		}
+		return nil
+	}
+	for _, r := range d.Ranges {
+		if _, err := parseRange(r); err != nil {
+			return err
+		}
+	}
+	_, err := deprecateMessage(d, templateData{})
+	return err
+}
+
+// deprecateMessage renders the deprecation message.
+func deprecateMessage(d Deprecate, data templateData) (string, error) {
+	message := d.Message
+	if message == "" {
+		message = defaultDeprecateMessage
+	}
+	return renderTemplate(message, data)
+}
+
+// versionsToDeprecate returns the published versions matching any range,
+// sorted, except the new version and those already carrying message.
+func versionsToDeprecate(doc *packument, ranges []string, published, message string) ([]string, error) {
+	parsed := make([]semverRange, 0, len(ranges))
+	for _, s := range ranges {
+		r, err := parseRange(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, r)
+	}
+
+	var matched []semver
+	for s, meta := range doc.Versions {
+		v, err := parseSemver(s)
+		if err != nil || s == published || meta.Deprecated == message {
+			continue
+		}
+		for _, r := range parsed {
+			if r.Contains(v) {
+				matched = append(matched, v)
+				break
+			}
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Compare(matched[j]) < 0 })
+	versions := make([]string, len(matched))
+	for i, v := range matched {
+		versions[i] = v.String()
+	}
+	return versions, nil
+}
+
+// deprecateViaAPI sets the deprecation message on versions by updating the
+// full package document, as npm deprecate does.
+func deprecateViaAPI(ctx context.Context, cfg *Config, name string, versions []string, message string) error {
+	registry := publishRegistry(cfg)
+	if registry == "" {
+		registry = defaultRegistry
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, packumentURL(registry, name), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create registry request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	setAPIAuth(req, cfg)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("registry request failed: %w", err)
+	}
+	var doc map[string]any
+	err = json.NewDecoder(resp.Body).Decode(&doc)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry returned %d fetching %s", resp.StatusCode, name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode registry response: %w", err)
+	}
+
+	docVersions, _ := doc["versions"].(map[string]any)
+	for _, v := range versions {
+		manifest, ok := docVersions[v].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s@%s is not in the registry document", name, v)
+		}
+		manifest["deprecated"] = message
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal package document: %w", err)
+	}
+
+	target := packumentURL(registry, name)
+	if rev, _ := doc["_rev"].(string); rev != "" {
+		target += "/-rev/" + rev
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create deprecate request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("npm-command", "deprecate")
+	setAPIAuth(req, cfg)
+	if otp := otpArgs(cfg); len(otp) == 2 {
+		req.Header.Set("npm-otp", otp[1])
+	}
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("deprecate request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("registry returned %d deprecating %s: %s", resp.StatusCode, name, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// deprecateSuperseded deprecates the versions matching cfg.Deprecate after
+// publishing version. Dry runs only report them. Failures are warnings: the
+// release itself has already succeeded.
+func deprecateSuperseded(ctx context.Context, cfg *Config, outputs map[string]any, packageDir, name, version string, data templateData, dryRun bool) {
+	message, err := deprecateMessage(cfg.Deprecate, data)
+	if err != nil {
+		appendWarning(outputs, fmt.Sprintf("superseded versions not deprecated: %v", err))
+		return
+	}
+	doc, err := fetchPackument(ctx, registryURL(cfg), name)
+	if errors.Is(err, errPackageNotFound) {
+		doc = &packument{}
+	} else if err != nil {
+		appendWarning(outputs, fmt.Sprintf("superseded versions not deprecated: %v", err))
+		return
+	}
+	versions, err := versionsToDeprecate(doc, cfg.Deprecate.Ranges, version, message)
+	if err != nil {
+		appendWarning(outputs, fmt.Sprintf("superseded versions not deprecated: %v", err))
+		return
+	}
+	outputs["deprecation_message"] = message
+	if dryRun || len(versions) == 0 {
+		outputs["deprecated"] = versions
+		return
+	}
+
+	if cfg.PublishMethod == publishMethodAPI {
+		if err := deprecateViaAPI(ctx, cfg, name, versions, message); err != nil {
+			appendWarning(outputs, fmt.Sprintf("superseded versions not deprecated: %v", err))
+			versions = []string{}
+		}
+		outputs["deprecated"] = versions
+		return
+	}
+
+	deprecated := []string{}
+	var failed []string
+	for _, v := range versions {
+		args := append([]string{"deprecate", name + "@" + v, message}, registryArgs(cfg)...)
+		if _, err := runNpm(ctx, packageDir, args...); err != nil {
+			failed = append(failed, v)
+			continue
+		}
+		deprecated = append(deprecated, v)
+	}
+	if len(failed) > 0 {
+		appendWarning(outputs, fmt.Sprintf("failed to deprecate: %s", strings.Join(failed, ", ")))
+	}
+	outputs["deprecated"] = deprecated
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestVersionsToDeprecate(t *testing.T) {
+	doc := &packument{Versions: map[string]packumentVersion{
+		"1.4.0":        {},
+		"1.4.1":        {},
+		"1.4.2":        {},
+		"1.3.9":        {Deprecated: "Superseded by lib@1.4.2"},
+		"1.5.0-beta.1": {},
+		"2.0.0":        {},
+	}}
+	got, err := versionsToDeprecate(doc, []string{"<1.4.2"}, "1.4.2", "Superseded by lib@1.4.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1.4.0", "1.4.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("versionsToDeprecate() = %v, want %v", got, want)
+	}
+
+	got, _ = versionsToDeprecate(doc, []string{"1.x"}, "1.4.2", "x")
+	if want := []string{"1.3.9", "1.4.0", "1.4.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("versionsToDeprecate(1.x) = %v, want %v", got, want)
+	}
+}
+
+func TestVersionsToDeprecatePartialRanges(t *testing.T) {
+	doc := &packument{Versions: map[string]packumentVersion{
+		"0.9.0":        {},
+		"1.0.0":        {},
+		"1.2.0":        {},
+		"1.2.5":        {},
+		"1.3.0":        {},
+		"1.9.9":        {},
+		"2.0.0-beta.1": {},
+		"2.0.0":        {},
+		"2.5.0":        {},
+		"3.0.0":        {},
+	}}
+	tests := []struct {
+		rng  string
+		want []string
+	}{
+		{"<2", []string{"0.9.0", "1.0.0", "1.2.0", "1.2.5", "1.3.0", "1.9.9"}},
+		{"<=1.2", []string{"0.9.0", "1.0.0", "1.2.0", "1.2.5"}},
+		{">1", []string{"2.0.0", "2.5.0"}},
+		{"<1.2", []string{"0.9.0", "1.0.0"}},
+		{">1.2 <2", []string{"1.3.0", "1.9.9"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rng, func(t *testing.T) {
+			got, err := versionsToDeprecate(doc, []string{tt.rng}, "3.0.0", "x")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("versionsToDeprecate(%q) = %v, want %v", tt.rng, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDeprecate(t *testing.T) {
+	tests := []struct {
+		name    string
+		d       Deprecate
+		wantErr bool
+	}{
+		{name: "unset", d: Deprecate{}},
+		{name: "ranges", d: Deprecate{Ranges: []string{"<1.4.2", "1.x"}, Message: "Upgrade to {{.Version}} for a security fix"}},
+		{name: "bad range", d: Deprecate{Ranges: []string{"not a range"}}, wantErr: true},
+		{name: "bad template", d: Deprecate{Ranges: []string{"<2"}, Message: "{{.Nope}}"}, wantErr: true},
+		{name: "message without ranges", d: Deprecate{Message: "old"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateDeprecate(tt.d); (err != nil) != tt.wantErr {
+				t.Errorf("validateDeprecate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeprecateViaNpm(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	server := newTestRegistry(t, map[string]*packument{"lib": {Versions: map[string]packumentVersion{"1.4.0": {}, "1.4.1": {}, "1.4.2": {}}}})
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.4.2"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"registry": server.URL, "deprecate": map[string]any{"ranges": []any{"<1.4.2"}}},
+		Context: plugin.ReleaseContext{Version: "1.4.2"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	var calls []string
+	for _, call := range npmCalls(t, logPath) {
+		if strings.HasPrefix(call, "deprecate") {
+			calls = append(calls, call)
+		}
+	}
+	want := []string{
+		"deprecate lib@1.4.0 Superseded by lib@1.4.2 --registry " + server.URL,
+		"deprecate lib@1.4.1 Superseded by lib@1.4.2 --registry " + server.URL,
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("deprecate calls = %q, want %q", calls, want)
+	}
+	if !reflect.DeepEqual(resp.Outputs["deprecated"], []string{"1.4.0", "1.4.1"}) {
+		t.Errorf("deprecated = %v", resp.Outputs["deprecated"])
+	}
+}
+
+func TestDeprecateViaAPI(t *testing.T) {
+	var updated map[string]any
+	var updatePath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`{"_rev":"3-abc","name":"lib","versions":{"1.0.0":{"version":"1.0.0"},"2.0.0":{"version":"2.0.0"}}}`))
+		case r.Method == http.MethodPut && r.Header.Get("npm-command") == "deprecate":
+			updatePath = r.URL.Path
+			_ = json.NewDecoder(r.Body).Decode(&updated)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("NPM_TOKEN", "secret")
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"2.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"publish_method": "api",
+			"registry":       server.URL,
+			"deprecate":      map[string]any{"ranges": []any{"1.x"}, "message": "Security fix in {{.Version}}"},
+		},
+		Context: plugin.ReleaseContext{Version: "2.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if updatePath != "/lib/-rev/3-abc" {
+		t.Errorf("update path = %q", updatePath)
+	}
+	versions := updated["versions"].(map[string]any)
+	if versions["1.0.0"].(map[string]any)["deprecated"] != "Security fix in 2.0.0" || versions["2.0.0"].(map[string]any)["deprecated"] != nil {
+		t.Errorf("versions = %v", versions)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// dependencyChange is a single production dependency difference between two
+// package versions. From is empty for added and To for removed dependencies.
+type dependencyChange struct {
+	Name string `json:"name"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// dependencyChanges groups dependency differences by kind.
+type dependencyChanges struct {
+	Added    []dependencyChange `json:"added,omitempty"`
+	Removed  []dependencyChange `json:"removed,omitempty"`
+	Upgraded []dependencyChange `json:"upgraded,omitempty"`
+}
+
+// Empty reports whether there are no dependency differences.
+func (c dependencyChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Upgraded) == 0
+}
+
+// diffDependencies compares two dependency maps (name to range). Results are
+// sorted by name so notes are stable between runs.
+func diffDependencies(prev, next map[string]string) dependencyChanges {
+	var c dependencyChanges
+	for name, to := range next {
+		from, ok := prev[name]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, dependencyChange{Name: name, To: to})
+		case from != to:
+			c.Upgraded = append(c.Upgraded, dependencyChange{Name: name, From: from, To: to})
+		}
+	}
+	for name, from := range prev {
+		if _, ok := next[name]; !ok {
+			c.Removed = append(c.Removed, dependencyChange{Name: name, From: from})
+		}
+	}
+
+	for _, list := range [][]dependencyChange{c.Added, c.Removed, c.Upgraded} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	}
+	return c
+}
+
+// Markdown renders the changes as a "Dependency changes" release notes section.
+func (c dependencyChanges) Markdown() string {
+	var b strings.Builder
+	b.WriteString("## Dependency changes\n")
+	if len(c.Added) > 0 {
+		b.WriteString("\n### Added\n\n")
+		for _, d := range c.Added {
+			fmt.Fprintf(&b, "- `%s` %s\n", d.Name, d.To)
+		}
+	}
+	if len(c.Removed) > 0 {
+		b.WriteString("\n### Removed\n\n")
+		for _, d := range c.Removed {
+			fmt.Fprintf(&b, "- `%s` %s\n", d.Name, d.From)
+		}
+	}
+	if len(c.Upgraded) > 0 {
+		b.WriteString("\n### Upgraded\n\n")
+		for _, d := range c.Upgraded {
+			fmt.Fprintf(&b, "- `%s` %s → %s\n", d.Name, d.From, d.To)
+		}
+	}
+	return b.String()
+}
+
+// previousPublishedVersion picks the version to diff against: the release's
+// previous version when it was published, otherwise the "latest" dist-tag.
+func previousPublishedVersion(doc *packument, releaseCtx plugin.ReleaseContext) (packumentVersion, bool) {
+	if v, ok := doc.Versions[releaseCtx.PreviousVersion]; ok && releaseCtx.PreviousVersion != "" {
+		return v, true
+	}
+	if latest := doc.DistTags["latest"]; latest != "" && latest != releaseCtx.Version {
+		v, ok := doc.Versions[latest]
+		return v, ok
+	}
+	return packumentVersion{}, false
+}
+
+// dependencyNotes runs in the notes hook and contributes a "Dependency
+// changes" section comparing the package's production dependencies with the
+// previously published version. The amended notes are returned in the
+// "release_notes" output.
+func (p *NpmPlugin) dependencyNotes(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext) (*plugin.ExecuteResponse, error) {
+	if !cfg.DependencyNotes {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: "Dependency notes disabled",
+		}, nil
+	}
+
+	if err := p.validateConfig(cfg); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("configuration validation failed: %v", err),
+		}, nil
+	}
+
+	packageDir, err := validatePackageDir(cfg.PackageDir)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid package directory: %v", err),
+		}, nil
+	}
+
+	pkg, err := readPackageJSON(packageDir)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	if pkg.Private {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: "Package is private, skipping dependency notes",
+		}, nil
+	}
+
+	doc, err := fetchPackument(ctx, registryURL(cfg), pkg.Name)
+	if errors.Is(err, errPackageNotFound) {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("%s has not been published before, skipping dependency notes", pkg.Name),
+		}, nil
+	}
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to fetch %s from registry: %v", pkg.Name, err),
+		}, nil
+	}
+
+	prev, ok := previousPublishedVersion(doc, releaseCtx)
+	if !ok {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("No previous published version of %s, skipping dependency notes", pkg.Name),
+		}, nil
+	}
+
+	changes := diffDependencies(prev.Dependencies, pkg.Dependencies)
+	outputs := map[string]any{
+		"package":            pkg.Name,
+		"compared_version":   prev.Version,
+		"dependency_changes": changes,
+	}
+	if changes.Empty() {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("No dependency changes since %s@%s", pkg.Name, prev.Version),
+			Outputs: outputs,
+		}, nil
+	}
+
+	section := changes.Markdown()
+	notes := section
+	if existing := strings.TrimRight(releaseCtx.ReleaseNotes, "\n"); existing != "" {
+		notes = existing + "\n\n" + section
+	}
+	outputs["dependency_notes"] = section
+	outputs["release_notes"] = notes
+
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Added dependency changes since %s@%s to release notes", pkg.Name, prev.Version),
+		Outputs: outputs,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestDiffDependencies(t *testing.T) {
+	prev := map[string]string{"a": "^1.0.0", "b": "^2.0.0", "c": "~3.1.0"}
+	next := map[string]string{"a": "^1.0.0", "b": "^2.1.0", "d": "^4.0.0"}
+
+	got := diffDependencies(prev, next)
+	want := dependencyChanges{
+		Added:    []dependencyChange{{Name: "d", To: "^4.0.0"}},
+		Removed:  []dependencyChange{{Name: "c", From: "~3.1.0"}},
+		Upgraded: []dependencyChange{{Name: "b", From: "^2.0.0", To: "^2.1.0"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffDependencies() = %+v, want %+v", got, want)
+	}
+
+	if !diffDependencies(prev, prev).Empty() {
+		t.Error("expected no changes for identical dependencies")
+	}
+}
+
+func TestDependencyNotes(t *testing.T) {
+	p := &NpmPlugin{}
+	ctx := context.Background()
+
+	server := newTestRegistry(t, map[string]*packument{
+		"pkg": {
+			Name:     "pkg",
+			DistTags: map[string]string{"latest": "1.1.0"},
+			Versions: map[string]packumentVersion{
+				"1.0.0": {Version: "1.0.0", Dependencies: map[string]string{"left-pad": "^1.0.0"}},
+				"1.1.0": {Version: "1.1.0", Dependencies: map[string]string{"left-pad": "^1.3.0", "old": "^1.0.0"}},
+			},
+		},
+	})
+
+	tmpDir := t.TempDir()
+	pkgJSON := `{"name":"pkg","version":"1.1.0","dependencies":{"left-pad":"^1.3.0","chalk":"^5.0.0"},"devDependencies":{"jest":"^29.0.0"}}`
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(pkgJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, tmpDir)
+
+	t.Run("disabled", func(t *testing.T) {
+		resp, err := p.dependencyNotes(ctx, &Config{}, plugin.ReleaseContext{Version: "1.2.0"})
+		if err != nil || !resp.Success {
+			t.Fatalf("unexpected failure: %v %s", err, resp.Error)
+		}
+		if resp.Outputs != nil {
+			t.Errorf("expected no outputs when disabled, got %v", resp.Outputs)
+		}
+	})
+
+	t.Run("appends_section", func(t *testing.T) {
+		cfg := &Config{DependencyNotes: true, Registry: server.URL}
+		releaseCtx := plugin.ReleaseContext{Version: "1.2.0", PreviousVersion: "1.1.0", ReleaseNotes: "## Features\n\n- thing\n"}
+
+		resp, err := p.dependencyNotes(ctx, cfg, releaseCtx)
+		if err != nil || !resp.Success {
+			t.Fatalf("unexpected failure: %v %s", err, resp.Error)
+		}
+		if resp.Outputs["compared_version"] != "1.1.0" {
+			t.Errorf("compared_version = %v, want 1.1.0", resp.Outputs["compared_version"])
+		}
+		notes, _ := resp.Outputs["release_notes"].(string)
+		if !strings.HasPrefix(notes, "## Features\n\n- thing\n\n## Dependency changes") {
+			t.Errorf("release_notes not appended to existing notes:\n%s", notes)
+		}
+		for _, want := range []string{"### Added\n\n- `chalk` ^5.0.0", "### Removed\n\n- `old` ^1.0.0"} {
+			if !strings.Contains(notes, want) {
+				t.Errorf("release_notes missing %q:\n%s", want, notes)
+			}
+		}
+		if strings.Contains(notes, "jest") {
+			t.Error("dev dependencies must not be reported")
+		}
+	})
+
+	t.Run("falls_back_to_latest", func(t *testing.T) {
+		cfg := &Config{DependencyNotes: true, Registry: server.URL}
+		resp, _ := p.dependencyNotes(ctx, cfg, plugin.ReleaseContext{Version: "1.2.0", PreviousVersion: "0.9.0"})
+		if resp.Outputs["compared_version"] != "1.1.0" {
+			t.Errorf("compared_version = %v, want 1.1.0", resp.Outputs["compared_version"])
+		}
+	})
+
+	t.Run("unpublished_package", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name":"new-pkg","version":"0.1.0"}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg := &Config{DependencyNotes: true, Registry: server.URL}
+		resp, _ := p.dependencyNotes(ctx, cfg, plugin.ReleaseContext{Version: "0.1.0"})
+		if !resp.Success || resp.Outputs != nil {
+			t.Errorf("expected successful skip, got %+v", resp)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+)
+
+// validateDistDir rejects options that conflict with publishing a build
+// output tree.
+func validateDistDir(cfg *Config) error {
+	if cfg.DistDir == "" {
+		return nil
+	}
+	switch {
+	case len(cfg.Workspaces) > 0:
+		return fmt.Errorf("dist_dir cannot be combined with workspaces")
+	case cfg.Inputs.Tarball != "":
+		return fmt.Errorf("dist_dir cannot be combined with inputs.tarball")
+	}
+	return nil
+}
+
+// applyDistDir points cfg at the build output tree for publishing, after
+// checking that its package.json is the one the source package_dir was
+// released as: same name and version. The build system may stamp or rewrite
+// the manifest, but must not publish something else.
+func applyDistDir(cfg *Config) error {
+	sourceDir, err := validatePackageDir(cfg.PackageDir)
+	if err != nil {
+		return fmt.Errorf("invalid package directory: %w", err)
+	}
+	distDir, err := validatePackageDir(cfg.DistDir)
+	if err != nil {
+		return fmt.Errorf("invalid dist_dir: %w", err)
+	}
+	source, err := readPackageJSON(sourceDir)
+	if err != nil {
+		return err
+	}
+	dist, err := readPackageJSON(distDir)
+	if err != nil {
+		return fmt.Errorf("dist_dir: %w", err)
+	}
+	if dist.Name != source.Name {
+		return fmt.Errorf("dist_dir package %q does not match source package %q", dist.Name, source.Name)
+	}
+	if dist.Version != source.Version {
+		return fmt.Errorf("dist_dir version %s does not match source version %s; rebuild after the version update", dist.Version, source.Version)
+	}
+	cfg.PackageDir = cfg.DistDir
+	return nil
+}
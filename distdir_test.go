@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestPublishDistDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		dist     string
+		wantErr  string
+		wantDist bool
+	}{
+		{name: "matching manifest", dist: `{"name":"lib","version":"1.2.0","main":"index.js"}`, wantDist: true},
+		{name: "stale version", dist: `{"name":"lib","version":"1.1.0"}`, wantErr: "does not match source version 1.2.0"},
+		{name: "other package", dist: `{"name":"other","version":"1.2.0"}`, wantErr: `source package "lib"`},
+		{name: "not built", wantErr: "dist_dir"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "src", "lib", "package.json"), `{"name":"lib","version":"1.2.0"}`)
+			if tt.dist != "" {
+				writeFile(t, filepath.Join(dir, "out", "lib", "package.json"), tt.dist)
+			}
+			chdir(t, dir)
+
+			resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  map[string]any{"package_dir": "src/lib", "dist_dir": "out/lib"},
+				Context: plugin.ReleaseContext{Version: "1.2.0"},
+				DryRun:  true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" {
+				if resp.Success || !strings.Contains(resp.Error, tt.wantErr) {
+					t.Fatalf("expected error %q, got %+v", tt.wantErr, resp)
+				}
+				return
+			}
+			if !resp.Success {
+				t.Fatalf("unexpected failure: %s", resp.Error)
+			}
+			if got, _ := resp.Outputs["package_dir"].(string); filepath.Base(filepath.Dir(got)) != "out" {
+				t.Errorf("published from %s, want the dist_dir", got)
+			}
+		})
+	}
+}
+
+func TestValidateDistDir(t *testing.T) {
+	if err := validateDistDir(&Config{DistDir: "out", Workspaces: []string{"packages/*"}}); err == nil {
+		t.Error("validateDistDir() accepted workspaces")
+	}
+	if err := validateDistDir(&Config{DistDir: "out", Inputs: PluginInputs{Tarball: "TARBALL"}}); err == nil {
+		t.Error("validateDistDir() accepted inputs.tarball")
+	}
+	if err := validateDistDir(&Config{DistDir: "out"}); err != nil {
+		t.Errorf("validateDistDir() error = %v", err)
+	}
+}
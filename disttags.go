@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DistTags are dist-tags managed after publishing, in addition to the
+// publish tag.
+type DistTags struct {
+	// Add are dist-tags pointed at the published version, created or moved
+	// from wherever they point now.
+	Add []string `json:"add,omitempty"`
+	// Remove are dist-tags deleted from the package.
+	Remove []string `json:"remove,omitempty"`
+}
+
+// enabled reports whether any dist-tag changes are configured.
+func (d DistTags) enabled() bool {
+	return len(d.Add) > 0 || len(d.Remove) > 0
+}
+
+// validateDistTags checks that every tag is a valid dist-tag npm accepts,
+// that none is both added and removed, and that the publish tag is not
+// removed.
+func validateDistTags(d DistTags, publishTag string) error {
+	seen := map[string]string{}
+	for _, list := range []struct {
+		op   string
+		tags []string
+	}{{"add", d.Add}, {"remove", d.Remove}} {
+		for _, tag := range list.tags {
+			if err := validateTag(tag); err != nil {
+				return err
+			}
+			if op, ok := seen[tag]; ok {
+				return fmt.Errorf("dist-tag %q is listed in both %s and %s", tag, op, list.op)
+			}
+			seen[tag] = list.op
+		}
+	}
+	for _, tag := range d.Remove {
+		if tag == publishTag || tag == "latest" {
+			return fmt.Errorf("cannot remove the %q dist-tag", tag)
+		}
+	}
+	return nil
+}
+
+// distTagURL returns the registry endpoint for one dist-tag of a package.
+func distTagURL(registry, name, tag string) string {
+	return strings.TrimSuffix(registry, "/") + "/-/package/" + strings.Replace(url.PathEscape(name), "%40", "@", 1) + "/dist-tags/" + url.PathEscape(tag)
+}
+
+// setDistTagViaAPI points tag at version, or deletes it when version is
+// empty, through the registry's dist-tag endpoint.
+func setDistTagViaAPI(ctx context.Context, cfg *Config, name, tag, version string) error {
+	registry := publishRegistry(cfg)
+	if registry == "" {
+		registry = defaultRegistry
+	}
+	method, body := http.MethodDelete, []byte(nil)
+	if version != "" {
+		method = http.MethodPut
+		body, _ = json.Marshal(version)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, distTagURL(registry, name, tag), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create dist-tag request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("npm-command", "dist-tag")
+	setAPIAuth(req, cfg)
+	if otp := otpArgs(cfg); len(otp) == 2 {
+		req.Header.Set("npm-otp", otp[1])
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("dist-tag request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &httpStatusError{
+			StatusCode: resp.StatusCode,
+			msg:        fmt.Sprintf("registry returned %d updating dist-tag %q: %s", resp.StatusCode, tag, strings.TrimSpace(string(msg))),
+		}
+	}
+	return nil
+}
+
+// setDistTag points tag at version, or removes it when version is empty,
+// using the registry API under publish_method api and npm dist-tag
+// otherwise.
+func setDistTag(ctx context.Context, cfg *Config, packageDir, name, tag, version string) error {
+	if cfg.PublishMethod == publishMethodAPI {
+		return setDistTagViaAPI(ctx, cfg, name, tag, version)
+	}
+	args := []string{"dist-tag", "rm", name, tag}
+	if version != "" {
+		args = []string{"dist-tag", "add", name + "@" + version, tag}
+	}
+	_, err := runNpm(ctx, packageDir, append(args, registryArgs(cfg)...)...)
+	return err
+}
+
+// applyDistTags adds and removes the configured dist-tags after publishing.
+// Dry runs only report the changes. Failures are warnings: the release
+// itself has already succeeded.
+func applyDistTags(ctx context.Context, cfg *Config, outputs map[string]any, packageDir, name, version string, dryRun bool) {
+	added := map[string]string{}
+	removed := []string{}
+	var failed []string
+	for _, tag := range cfg.DistTags.Add {
+		if !dryRun {
+			if err := setDistTag(ctx, cfg, packageDir, name, tag, version); err != nil {
+				failed = append(failed, tag)
+				continue
+			}
+		}
+		added[tag] = version
+	}
+	for _, tag := range cfg.DistTags.Remove {
+		if !dryRun {
+			if err := setDistTag(ctx, cfg, packageDir, name, tag, ""); err != nil {
+				failed = append(failed, tag)
+				continue
+			}
+		}
+		removed = append(removed, tag)
+	}
+	if len(failed) > 0 {
+		appendWarning(outputs, fmt.Sprintf("failed to update dist-tags: %s", strings.Join(failed, ", ")))
+	}
+	outputs["dist_tags"] = added
+	outputs["removed_dist_tags"] = removed
+}
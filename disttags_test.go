@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+
+	"github.com/relicta-tech/plugin-npm/registrytest"
+)
+
+func TestValidateDistTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    DistTags
+		wantErr bool
+	}{
+		{name: "add and remove", tags: DistTags{Add: []string{"next", "v2-latest"}, Remove: []string{"beta"}}},
+		{name: "invalid tag", tags: DistTags{Add: []string{"has space"}}, wantErr: true},
+		{name: "semver range", tags: DistTags{Add: []string{"v2"}}, wantErr: true},
+		{name: "added and removed", tags: DistTags{Add: []string{"next"}, Remove: []string{"next"}}, wantErr: true},
+		{name: "remove publish tag", tags: DistTags{Remove: []string{"stable"}}, wantErr: true},
+		{name: "remove latest", tags: DistTags{Remove: []string{"latest"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDistTags(tt.tags, "stable")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDistTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDistTagURL(t *testing.T) {
+	got := distTagURL("https://registry.npmjs.org/", "@acme/lib", "next")
+	if want := "https://registry.npmjs.org/-/package/@acme%2Flib/dist-tags/next"; got != want {
+		t.Errorf("distTagURL() = %q, want %q", got, want)
+	}
+}
+
+func TestDistTagsViaNpm(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"2.1.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"dist_tags": map[string]any{"add": []any{"next", "v2-latest"}, "remove": []any{"beta"}}},
+		Context: plugin.ReleaseContext{Version: "2.1.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	var tagCalls []string
+	for _, call := range npmCalls(t, logPath) {
+		if strings.HasPrefix(call, "dist-tag") {
+			tagCalls = append(tagCalls, call)
+		}
+	}
+	want := []string{"dist-tag add lib@2.1.0 next", "dist-tag add lib@2.1.0 v2-latest", "dist-tag rm lib beta"}
+	if !reflect.DeepEqual(tagCalls, want) {
+		t.Errorf("dist-tag calls = %q, want %q", tagCalls, want)
+	}
+	if !reflect.DeepEqual(resp.Outputs["dist_tags"], map[string]string{"next": "2.1.0", "v2-latest": "2.1.0"}) {
+		t.Errorf("dist_tags = %v", resp.Outputs["dist_tags"])
+	}
+}
+
+func TestDistTagsViaAPI(t *testing.T) {
+	reg := registrytest.New()
+	t.Cleanup(reg.Close)
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("NPM_TOKEN", registrytest.Token)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"@acme/lib","version":"2.1.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"publish_method": "api",
+			"registry":       reg.URL(),
+			"dist_tags":      map[string]any{"add": []any{"next"}, "remove": []any{"beta"}},
+		},
+		Context: plugin.ReleaseContext{Version: "2.1.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if resp.Outputs["warnings"] != nil {
+		t.Errorf("warnings = %v", resp.Outputs["warnings"])
+	}
+	if got := reg.DistTags("@acme/lib"); !reflect.DeepEqual(got, map[string]string{"latest": "2.1.0", "next": "2.1.0"}) {
+		t.Errorf("dist-tags = %v", got)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// DocsTarball packs a documentation directory into a second tarball,
+// "<name>-docs-<version>.tgz", released with the package.
+type DocsTarball struct {
+	// Dir holds the documentation, relative to the package directory.
+	Dir string `json:"dir,omitempty"`
+	// Destination is the directory the tarball is written to; it defaults
+	// to the working directory.
+	Destination string `json:"destination,omitempty"`
+	// Publish also publishes the tarball as the "<name>-docs" package at
+	// the same version, tag and access as the package.
+	Publish bool `json:"publish,omitempty"`
+	// Attach lists the tarball in the response's artifacts so a release
+	// asset plugin can upload it.
+	Attach bool `json:"attach,omitempty"`
+}
+
+// enabled reports whether a docs tarball is configured.
+func (d DocsTarball) enabled() bool {
+	return d.Dir != ""
+}
+
+// validateDocsTarball checks the docs_tarball configuration.
+func validateDocsTarball(d DocsTarball) error {
+	if !d.enabled() {
+		if d.Destination != "" || d.Publish || d.Attach {
+			return fmt.Errorf("dir is required")
+		}
+		return nil
+	}
+	if !filepath.IsLocal(d.Dir) {
+		return fmt.Errorf("dir %q must be inside the package directory", d.Dir)
+	}
+	if err := validateOutputPath(d.Destination); err != nil {
+		return fmt.Errorf("destination: %w", err)
+	}
+	return nil
+}
+
+// docsPackageName returns the docs package name for pkgName.
+func docsPackageName(pkgName string) string {
+	return pkgName + "-docs"
+}
+
+// docsTarballPath returns where the docs tarball of pkgName@version is
+// written.
+func docsTarballPath(d DocsTarball, pkgName, version string) string {
+	dest := d.Destination
+	if dest == "" {
+		dest = "."
+	}
+	return filepath.Join(dest, tarballFilename(docsPackageName(pkgName), version))
+}
+
+// prepareDocsPackage collects the documentation files and builds the manifest
+// of the docs package for pkgName@version, so a missing or empty docs
+// directory fails before the package is published.
+func prepareDocsPackage(packageDir, pkgName, version string, d DocsTarball) (*generatedPackage, error) {
+	data, err := os.ReadFile(filepath.Join(packageDir, "package.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	var runtime map[string]any
+	if err := json.Unmarshal(data, &runtime); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+	root := filepath.Join(packageDir, d.Dir)
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	// The generated manifest replaces any package.json in the docs directory
+	files, err := collectFiles(root, nil)
+	if err != nil {
+		return nil, err
+	}
+	files = removeString(files, "package.json")
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files in %s", root)
+	}
+
+	name := docsPackageName(pkgName)
+	manifest := map[string]any{
+		"name":        name,
+		"version":     version,
+		"description": fmt.Sprintf("Documentation for %s", pkgName),
+	}
+	for _, key := range typesManifestInherited {
+		if v, ok := runtime[key]; ok {
+			manifest[key] = v
+		}
+	}
+	return &generatedPackage{name: name, root: root, files: files, manifest: manifest}, nil
+}
+
+// removeString returns list without s.
+func removeString(list []string, s string) []string {
+	out := list[:0]
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// writeDocsTarball packs the docs package reproducibly and writes it to dest,
+// returning it as an artifact.
+func writeDocsTarball(gp *generatedPackage, dest string) (plugin.Artifact, error) {
+	dir, err := os.MkdirTemp("", "relicta-npm-docs-")
+	if err != nil {
+		return plugin.Artifact{}, fmt.Errorf("failed to create docs package directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	if err := gp.write(dir); err != nil {
+		return plugin.Artifact{}, err
+	}
+	files := append([]string{"package.json"}, gp.files...)
+	sort.Strings(files)
+	data, _, _, err := buildTarball(dir, files)
+	if err != nil {
+		return plugin.Artifact{}, err
+	}
+	if parent := filepath.Dir(dest); parent != "." {
+		if err := os.MkdirAll(parent, 0755); err != nil {
+			return plugin.Artifact{}, fmt.Errorf("failed to create %s: %w", parent, err)
+		}
+	}
+	if err := os.WriteFile(dest, data, 0644); err != nil {
+		return plugin.Artifact{}, fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	checksum, err := integrityDigest(tarballIntegrity(data))
+	if err != nil {
+		return plugin.Artifact{}, err
+	}
+	return plugin.Artifact{
+		Name:     filepath.Base(dest),
+		Path:     dest,
+		Type:     "file",
+		Size:     int64(len(data)),
+		Checksum: checksum,
+	}, nil
+}
+
+// publishDocsTarball publishes the written docs tarball, so the registry
+// serves the same bytes as the attached artifact.
+func publishDocsTarball(ctx context.Context, cfg *Config, tarball string) error {
+	abs, err := filepath.Abs(tarball)
+	if err != nil {
+		return err
+	}
+	args := append([]string{"publish", abs}, registryArgs(cfg)...)
+	if cfg.Tag != "" {
+		args = append(args, "--tag", cfg.Tag)
+	}
+	if cfg.Access != "" {
+		args = append(args, "--access", cfg.Access)
+	}
+	_, err = runNpm(ctx, filepath.Dir(abs), args...)
+	return err
+}
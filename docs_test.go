@@ -0,0 +1,168 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateDocsTarball(t *testing.T) {
+	tests := []struct {
+		name    string
+		docs    DocsTarball
+		wantErr bool
+	}{
+		{name: "disabled", docs: DocsTarball{}},
+		{name: "dir", docs: DocsTarball{Dir: "docs", Destination: "out", Publish: true, Attach: true}},
+		{name: "no dir", docs: DocsTarball{Attach: true}, wantErr: true},
+		{name: "dir escapes", docs: DocsTarball{Dir: "../docs"}, wantErr: true},
+		{name: "destination escapes", docs: DocsTarball{Dir: "docs", Destination: "../out"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateDocsTarball(tt.docs); (err != nil) != tt.wantErr {
+				t.Errorf("validateDocsTarball() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDocsTarballPath(t *testing.T) {
+	if got, want := docsTarballPath(DocsTarball{Dir: "docs"}, "@acme/lib", "1.2.0"), "acme-lib-docs-1.2.0.tgz"; got != want {
+		t.Errorf("docsTarballPath() = %q, want %q", got, want)
+	}
+	if got, want := docsTarballPath(DocsTarball{Dir: "docs", Destination: "out"}, "lib", "1.2.0"), filepath.Join("out", "lib-docs-1.2.0.tgz"); got != want {
+		t.Errorf("docsTarballPath() = %q, want %q", got, want)
+	}
+}
+
+func TestPrepareDocsPackage(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0","license":"MIT","dependencies":{"x":"1"}}`)
+	writeFile(t, filepath.Join(dir, "docs", "index.html"), "<h1>lib</h1>\n")
+	writeFile(t, filepath.Join(dir, "docs", "api", "lib.md"), "# lib\n")
+	writeFile(t, filepath.Join(dir, "docs", "package.json"), `{"name":"site"}`)
+	writeFile(t, filepath.Join(dir, "docs", ".cache", "x"), "")
+	writeFile(t, filepath.Join(dir, "empty", "package.json"), `{}`)
+
+	gp, err := prepareDocsPackage(dir, "lib", "1.0.0", DocsTarball{Dir: "docs"})
+	if err != nil {
+		t.Fatalf("prepareDocsPackage() error = %v", err)
+	}
+	if want := []string{"api/lib.md", "index.html"}; !reflect.DeepEqual(gp.files, want) {
+		t.Errorf("files = %v, want %v", gp.files, want)
+	}
+	want := map[string]any{"name": "lib-docs", "version": "1.0.0", "description": "Documentation for lib", "license": "MIT"}
+	if !reflect.DeepEqual(gp.manifest, want) {
+		t.Errorf("manifest = %v, want %v", gp.manifest, want)
+	}
+
+	if _, err := prepareDocsPackage(dir, "lib", "1.0.0", DocsTarball{Dir: "missing"}); err == nil {
+		t.Error("prepareDocsPackage() accepted a missing directory")
+	}
+	if _, err := prepareDocsPackage(dir, "lib", "1.0.0", DocsTarball{Dir: "empty"}); err == nil || !strings.Contains(err.Error(), "no files") {
+		t.Errorf("prepareDocsPackage() error = %v, want no files", err)
+	}
+}
+
+// tarballEntries lists the file names in a gzipped tarball.
+func tarballEntries(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return names
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+}
+
+func TestDocsTarball(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name": "lib", "version": "1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "docs", "index.html"), "<h1>lib</h1>\n")
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"docs_tarball": map[string]any{"dir": "docs", "destination": "out", "publish": true, "attach": true}},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected response: %v %+v", err, resp)
+	}
+	tarball := filepath.Join("out", "lib-docs-1.0.0.tgz")
+	if resp.Outputs["docs_tarball"] != tarball || resp.Outputs["docs_package"] != "lib-docs" {
+		t.Errorf("outputs = %v", resp.Outputs)
+	}
+	if want := []string{"package/index.html", "package/package.json"}; !reflect.DeepEqual(tarballEntries(t, tarball), want) {
+		t.Errorf("tarball entries = %v, want %v", tarballEntries(t, tarball), want)
+	}
+	if len(resp.Artifacts) != 1 || resp.Artifacts[0].Path != tarball || resp.Artifacts[0].Type != "file" || !strings.HasPrefix(resp.Artifacts[0].Checksum, "sha512:") {
+		t.Errorf("artifacts = %+v", resp.Artifacts)
+	}
+
+	abs, _ := filepath.Abs(tarball)
+	found := false
+	for _, call := range npmCalls(t, logPath) {
+		if strings.HasPrefix(call, "publish "+abs) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("docs tarball not published: %v", npmCalls(t, logPath))
+	}
+}
+
+func TestDocsTarballDryRun(t *testing.T) {
+	fakeNpm(t, `echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name": "lib", "version": "1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "docs", "index.html"), "<h1>lib</h1>\n")
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"docs_tarball": map[string]any{"dir": "docs", "attach": true}},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+		DryRun:  true,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected response: %v %+v", err, resp)
+	}
+	if resp.Outputs["docs_tarball"] != "lib-docs-1.0.0.tgz" || resp.Outputs["docs_package"] != nil {
+		t.Errorf("outputs = %v", resp.Outputs)
+	}
+	if _, err := os.Stat("lib-docs-1.0.0.tgz"); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote the docs tarball: %v", err)
+	}
+	if len(resp.Artifacts) != 0 {
+		t.Errorf("dry run artifacts = %+v", resp.Artifacts)
+	}
+}
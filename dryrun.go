@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// Dry-run levels.
+const (
+	// dryRunValidate runs the checks and reports what would happen.
+	dryRunValidate = "validate"
+	// dryRunPack also builds the tarball and reports its contents.
+	dryRunPack = "pack"
+	// dryRunFull runs npm publish --dry-run.
+	dryRunFull = "full"
+)
+
+// parseDryRun reads dry_run: a level, or a boolean kept as an alias for a
+// validate dry run. It returns whether to dry-run and the level, empty for
+// the default.
+func parseDryRun(v any) (bool, string, error) {
+	switch v := v.(type) {
+	case nil:
+		return false, "", nil
+	case bool:
+		return v, "", nil
+	case string:
+		switch v {
+		case "", "false":
+			return false, "", nil
+		case "true":
+			return true, "", nil
+		case dryRunValidate, dryRunPack, dryRunFull:
+			return true, v, nil
+		}
+	}
+	return false, "", fmt.Errorf("dry_run must be a boolean or one of %s, %s, %s (got %v)", dryRunValidate, dryRunPack, dryRunFull, v)
+}
+
+// dryRunLevel returns the level dry runs use; a dry run requested by the
+// release itself rather than dry_run is a validate one.
+func dryRunLevel(cfg *Config) string {
+	if cfg.DryRunLevel == "" {
+		return dryRunValidate
+	}
+	return cfg.DryRunLevel
+}
+
+// addPackReport adds the contents of a packed tarball to outputs.
+func addPackReport(outputs map[string]any, result publishResult) {
+	files := make([]string, len(result.Files))
+	for i, f := range result.Files {
+		files[i] = f.Path
+	}
+	outputs["tarball_files"] = files
+	outputs["tarball_size"] = result.Size
+	outputs["tarball_unpacked_size"] = result.UnpackedSize
+	if result.Integrity != "" {
+		outputs["integrity"] = result.Integrity
+		outputs["shasum"] = result.Shasum
+	}
+}
+
+// packInProcess builds the tarball in memory, as API publishes do, and
+// returns its contents and checksums.
+func packInProcess(cfg *Config, packageDir string) (publishResult, error) {
+	tarball, _, result, err := apiTarball(cfg, packageDir)
+	if err != nil {
+		return publishResult{}, err
+	}
+	sum := sha1.Sum(tarball)
+	result.Size = int64(len(tarball))
+	result.Shasum = hex.EncodeToString(sum[:])
+	result.Integrity = tarballIntegrity(tarball)
+	return result, nil
+}
+
+// packContents reports what a validate dry run would ship without writing
+// a tarball: npm pack --dry-run, or the in-process packer for API publishes
+// and prebuilt tarballs. The validate level runs no lifecycle scripts, so
+// npm lists the files with --ignore-scripts; files prepack would generate
+// are missing from the report.
+func packContents(ctx context.Context, cfg *Config, packageDir string) (publishResult, error) {
+	if cfg.PublishMethod == publishMethodAPI || cfg.inputTarball != "" {
+		return packInProcess(cfg, packageDir)
+	}
+	listCfg := *cfg
+	listCfg.IgnoreScripts = true
+	return packDryRun(ctx, &listCfg, packageDir)
+}
+
+// dryRunPackage builds the tarball for a pack dry run and reports its
+// contents. It is kept in pack_destination when set and otherwise removed.
+func dryRunPackage(ctx context.Context, cfg *Config, packageDir string, data templateData, outputs map[string]any) error {
+	if cfg.PublishMethod == publishMethodAPI || cfg.inputTarball != "" {
+		result, err := packInProcess(cfg, packageDir)
+		if err != nil {
+			return err
+		}
+		addPackReport(outputs, result)
+		return nil
+	}
+
+	dest := cfg.PackDestination
+	if dest == "" {
+		tmp, err := os.MkdirTemp("", "relicta-npm-pack-")
+		if err != nil {
+			return fmt.Errorf("failed to create pack directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tmp) }()
+		dest = tmp
+	}
+	result, tarball, err := packForPublish(ctx, cfg, packageDir, dest, data, outputs)
+	if err != nil {
+		return err
+	}
+	if cfg.PackDestination != "" {
+		outputs["tarball"] = tarball
+	}
+	addPackReport(outputs, result)
+	return nil
+}
+
+// dryRunPublish runs npm publish --dry-run, which also runs the publish
+// lifecycle scripts, and reports what it would upload.
+func dryRunPublish(ctx context.Context, cfg *Config, packageDir, name string, args []string, outputs map[string]any) error {
+	stdout, err := runScriptedNpm(ctx, cfg, packageDir, args...)
+	if err != nil {
+		return err
+	}
+	outputs["stdout"] = stdout
+	addPackReport(outputs, parsePublishOutput(stdout, name))
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestParseDryRun(t *testing.T) {
+	tests := []struct {
+		value     any
+		wantDry   bool
+		wantLevel string
+		wantErr   bool
+	}{
+		{value: nil},
+		{value: false},
+		{value: true, wantDry: true},
+		{value: "true", wantDry: true},
+		{value: "false"},
+		{value: "validate", wantDry: true, wantLevel: "validate"},
+		{value: "pack", wantDry: true, wantLevel: "pack"},
+		{value: "full", wantDry: true, wantLevel: "full"},
+		{value: "everything", wantErr: true},
+		{value: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		dry, level, err := parseDryRun(tt.value)
+		if (err != nil) != tt.wantErr || dry != tt.wantDry || level != tt.wantLevel {
+			t.Errorf("parseDryRun(%v) = %v, %q, %v", tt.value, dry, level, err)
+		}
+	}
+}
+
+func TestDryRunLevels(t *testing.T) {
+	tests := []struct {
+		name        string
+		dryRun      any
+		wantCalls   []string
+		wantFiles   []string
+		wantMessage string
+	}{
+		{
+			name:        "boolean",
+			dryRun:      true,
+			wantCalls:   []string{"pack --dry-run --json --ignore-scripts"},
+			wantFiles:   []string{"package.json", "index.js"},
+			wantMessage: "Would run: npm publish",
+		},
+		{
+			name:        "validate",
+			dryRun:      "validate",
+			wantCalls:   []string{"pack --dry-run --json --ignore-scripts"},
+			wantFiles:   []string{"package.json", "index.js"},
+			wantMessage: "Would run: npm publish",
+		},
+		{
+			name:        "pack",
+			dryRun:      "pack",
+			wantCalls:   []string{"pack --json --pack-destination"},
+			wantFiles:   []string{"package.json", "index.js"},
+			wantMessage: "Would run: npm publish",
+		},
+		{
+			name:        "full",
+			dryRun:      "full",
+			wantCalls:   []string{"publish --json --tag latest --dry-run"},
+			wantFiles:   []string{"package.json", "index.js"},
+			wantMessage: "Ran: npm publish",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := `{"name":"lib","version":"1.0.0","filename":"lib-1.0.0.tgz","size":321,"unpackedSize":654,"integrity":"sha512-abc","shasum":"def","files":[{"path":"package.json"},{"path":"index.js"}]}`
+			logPath := fakeNpm(t, `if [ "$1" = pack ]; then echo '[`+result+`]'; else echo '`+result+`'; fi`)
+			t.Setenv("TMPDIR", t.TempDir())
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+			chdir(t, dir)
+
+			resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  map[string]any{"dry_run": tt.dryRun},
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("unexpected failure: %v %+v", err, resp)
+			}
+			if !strings.HasPrefix(resp.Message, tt.wantMessage) {
+				t.Errorf("message = %q, want prefix %q", resp.Message, tt.wantMessage)
+			}
+			calls := npmCalls(t, logPath)
+			if len(calls) != len(tt.wantCalls) {
+				t.Fatalf("npm calls = %v, want %v", calls, tt.wantCalls)
+			}
+			for i, want := range tt.wantCalls {
+				if !strings.HasPrefix(calls[i], want) {
+					t.Errorf("npm call %d = %q, want prefix %q", i, calls[i], want)
+				}
+			}
+			if got := resp.Outputs["tarball_files"]; !reflect.DeepEqual(got, tt.wantFiles) {
+				t.Errorf("tarball_files = %v, want %v", got, tt.wantFiles)
+			}
+			if resp.Outputs["tarball_size"] != int64(321) || resp.Outputs["tarball_unpacked_size"] != int64(654) || resp.Outputs["integrity"] != "sha512-abc" {
+				t.Errorf("outputs = %v", resp.Outputs)
+			}
+		})
+	}
+}
+
+func TestDryRunPackAPI(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0","files":["index.js"]}`)
+	writeFile(t, filepath.Join(dir, "index.js"), "module.exports = 1\n")
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"dry_run": "full", "publish_method": "api"},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	files, _ := resp.Outputs["tarball_files"].([]string)
+	if !reflect.DeepEqual(files, []string{"index.js", "package.json"}) && !reflect.DeepEqual(files, []string{"package.json", "index.js"}) {
+		t.Errorf("tarball_files = %v", files)
+	}
+	if integrity, _ := resp.Outputs["integrity"].(string); !strings.HasPrefix(integrity, "sha512-") {
+		t.Errorf("integrity = %v", resp.Outputs["integrity"])
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 0 {
+		t.Errorf("npm called with publish_method api: %v", calls)
+	}
+}
+
+func TestDryRunInvalidLevel(t *testing.T) {
+	resp, err := (&NpmPlugin{}).Validate(context.Background(), map[string]any{"dry_run": "everything"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Valid {
+		t.Error("expected dry_run level to be rejected")
+	}
+}
+
+func TestDryRunContentsUnavailable(t *testing.T) {
+	fakeNpm(t, `echo 'npm ERR! prepack failed' >&2; exit 1`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"dry_run": true},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	warnings, _ := resp.Outputs["warnings"].([]string)
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "could not list the package contents") {
+		t.Errorf("warnings = %v", resp.Outputs["warnings"])
+	}
+}
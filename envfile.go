@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Env file formats.
+const (
+	envFileDotenv       = "dotenv"
+	envFileGitHubOutput = "github_output"
+)
+
+// envFileVars returns the variables written to the env file from the publish
+// outputs. Variables without a value are omitted.
+func envFileVars(cfg *Config, outputs map[string]any) map[string]string {
+	vars := map[string]string{}
+	set := func(key string, v any) {
+		if s, ok := v.(string); ok && s != "" {
+			vars[key] = s
+		}
+	}
+	set("PACKAGE_NAME", outputs["package"])
+	set("PACKAGE_VERSION", outputs["version"])
+	set("PACKAGE_TAG", outputs["tag"])
+	set("TARBALL_PATH", outputs["tarball"])
+	if hint, ok := outputs["update_hint"].(updateHint); ok {
+		set("PACKAGE_RANGE", hint.Range)
+	}
+	if u, ok := outputs["artifact_url"]; ok {
+		set("PACKAGE_URL", u)
+	} else if name, _ := outputs["package"].(string); name != "" {
+		version, _ := outputs["version"].(string)
+		set("PACKAGE_URL", packageURL(cfg, name, version))
+	}
+	return vars
+}
+
+// packageURL returns where a published version can be viewed: its npmjs.com
+// page on the public registry, otherwise its registry metadata URL.
+func packageURL(cfg *Config, name, version string) string {
+	registry := publishRegistry(cfg)
+	if registry == "" || registry == defaultRegistry {
+		return npmPackagePage(name, version)
+	}
+	return packumentURL(registry, name) + "/" + version
+}
+
+// envFilePath returns the file the env vars are written to. The
+// github_output format defaults to the runner's $GITHUB_OUTPUT.
+func envFilePath(cfg *Config) string {
+	if cfg.EnvFile == "" && cfg.EnvFileFormat == envFileGitHubOutput {
+		return os.Getenv("GITHUB_OUTPUT")
+	}
+	return cfg.EnvFile
+}
+
+// writeEnvFile writes vars in the configured format. A dotenv file is
+// replaced; a GitHub output file is shared by all steps and is appended to.
+func writeEnvFile(cfg *Config, vars map[string]string) (string, error) {
+	path := envFilePath(cfg)
+	if path == "" {
+		return "", fmt.Errorf("GITHUB_OUTPUT is not set")
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		v := vars[k]
+		switch {
+		case cfg.EnvFileFormat == envFileGitHubOutput:
+			fmt.Fprintf(&b, "%s=%s\n", k, strings.ReplaceAll(v, "\n", " "))
+		case strings.ContainsAny(v, " \t\"'#$\\\n"):
+			fmt.Fprintf(&b, "%s=%q\n", k, v)
+		default:
+			fmt.Fprintf(&b, "%s=%s\n", k, v)
+		}
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create env file directory: %w", err)
+		}
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if cfg.EnvFileFormat == envFileGitHubOutput {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open env file: %w", err)
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("failed to write env file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write env file: %w", err)
+	}
+	return path, nil
+}
+
+// addEnvFile writes the env file for a successful publish, recording the
+// path in the outputs or a warning if it could not be written.
+func addEnvFile(cfg *Config, outputs map[string]any) {
+	path, err := writeEnvFile(cfg, envFileVars(cfg, outputs))
+	if err != nil {
+		appendWarning(outputs, fmt.Sprintf("env file not written: %v", err))
+		return
+	}
+	outputs["env_file"] = path
+}
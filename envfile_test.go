@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestEnvFileVars(t *testing.T) {
+	outputs := map[string]any{"package": "@acme/lib", "version": "1.2.0", "tag": "latest", "tarball": "dist/acme-lib-1.2.0.tgz"}
+
+	vars := envFileVars(&Config{}, outputs)
+	want := map[string]string{
+		"PACKAGE_NAME":    "@acme/lib",
+		"PACKAGE_VERSION": "1.2.0",
+		"PACKAGE_TAG":     "latest",
+		"TARBALL_PATH":    "dist/acme-lib-1.2.0.tgz",
+		"PACKAGE_URL":     "https://www.npmjs.com/package/@acme/lib/v/1.2.0",
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("%s = %q, want %q", k, vars[k], v)
+		}
+	}
+
+	vars = envFileVars(&Config{Registry: "https://npm.example.com/"}, outputs)
+	if vars["PACKAGE_URL"] != "https://npm.example.com/@acme%2Flib/1.2.0" {
+		t.Errorf("PACKAGE_URL = %q", vars["PACKAGE_URL"])
+	}
+
+	outputs["artifact_url"] = "s3://releases/acme-lib-1.2.0.tgz"
+	if vars := envFileVars(&Config{}, outputs); vars["PACKAGE_URL"] != "s3://releases/acme-lib-1.2.0.tgz" {
+		t.Errorf("PACKAGE_URL = %q, want artifact URL", vars["PACKAGE_URL"])
+	}
+}
+
+func TestWriteEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	vars := map[string]string{"PACKAGE_NAME": "pkg", "PACKAGE_VERSION": "1.0.0", "TARBALL_PATH": "my dist/pkg.tgz"}
+
+	t.Run("dotenv replaces", func(t *testing.T) {
+		path := filepath.Join(dir, "out", "publish.env")
+		writeFile(t, filepath.Join(dir, "out", "keep"), "")
+		writeFile(t, path, "OLD=1\n")
+		if _, err := writeEnvFile(&Config{EnvFile: path}, vars); err != nil {
+			t.Fatalf("writeEnvFile() error = %v", err)
+		}
+		data, _ := os.ReadFile(path)
+		want := "PACKAGE_NAME=pkg\nPACKAGE_VERSION=1.0.0\nTARBALL_PATH=\"my dist/pkg.tgz\"\n"
+		if string(data) != want {
+			t.Errorf("env file = %q, want %q", data, want)
+		}
+	})
+
+	t.Run("github output appends", func(t *testing.T) {
+		path := filepath.Join(dir, "github_output")
+		writeFile(t, path, "other=1\n")
+		t.Setenv("GITHUB_OUTPUT", path)
+		got, err := writeEnvFile(&Config{EnvFileFormat: envFileGitHubOutput}, vars)
+		if err != nil || got != path {
+			t.Fatalf("writeEnvFile() = %q, %v", got, err)
+		}
+		data, _ := os.ReadFile(path)
+		if !strings.HasPrefix(string(data), "other=1\nPACKAGE_NAME=pkg\n") || !strings.Contains(string(data), "TARBALL_PATH=my dist/pkg.tgz\n") {
+			t.Errorf("github output = %q", data)
+		}
+	})
+
+	t.Run("github output unset", func(t *testing.T) {
+		t.Setenv("GITHUB_OUTPUT", "")
+		if _, err := writeEnvFile(&Config{EnvFileFormat: envFileGitHubOutput}, vars); err == nil {
+			t.Error("expected error without GITHUB_OUTPUT")
+		}
+	})
+}
+
+func TestPublishWritesEnvFile(t *testing.T) {
+	fakeNpm(t, `echo '{"id":"pkg@1.0.0","name":"pkg","version":"1.0.0"}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"1.0.0"}`)
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	p := &NpmPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"env_file": "publish.env"},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %s", err, resp.Error)
+	}
+	if resp.Outputs["env_file"] != "publish.env" {
+		t.Errorf("env_file output = %v", resp.Outputs["env_file"])
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "publish.env"))
+	if err != nil || !strings.Contains(string(data), "PACKAGE_NAME=pkg\n") || !strings.Contains(string(data), "PACKAGE_VERSION=1.0.0\n") {
+		t.Errorf("env file = %q (%v)", data, err)
+	}
+}
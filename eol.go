@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// EndOfLife retires a package: post-publish deprecates every version instead
+// of publishing, optionally after publishing a final stub version.
+type EndOfLife struct {
+	// Enabled switches post-publish to end-of-life mode.
+	Enabled bool `json:"enabled"`
+	// Message is the deprecation message template; it defaults to a notice
+	// naming the successor.
+	Message string `json:"message,omitempty"`
+	// Successor is the package replacing this one.
+	Successor string `json:"successor,omitempty"`
+	// PublishStub publishes the release version as a stub whose README points
+	// at the successor, so the registry page explains the move.
+	PublishStub bool `json:"publish_stub,omitempty"`
+	// DependOnSuccessor makes the stub depend on the successor's latest
+	// version, so existing installs pull in the renamed package.
+	DependOnSuccessor bool `json:"depend_on_successor,omitempty"`
+}
+
+// endOfLifeSettings returns the effective end-of-life settings. superseded_by
+// is shorthand for retiring the package behind a stub that depends on its
+// successor.
+func endOfLifeSettings(cfg *Config) EndOfLife {
+	eol := cfg.EndOfLife
+	if cfg.SupersededBy != "" {
+		eol.Enabled = true
+		eol.Successor = cfg.SupersededBy
+		eol.PublishStub = true
+		eol.DependOnSuccessor = true
+	}
+	return eol
+}
+
+// successorRange returns the dependency range on the successor's latest
+// version. The successor must already be published.
+func successorRange(ctx context.Context, cfg *Config, successor string) (string, error) {
+	doc, err := fetchPackument(ctx, registryURL(cfg), successor)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch successor %s: %w", successor, err)
+	}
+	latest := doc.DistTags["latest"]
+	if latest == "" {
+		return "", fmt.Errorf("successor %s has no latest version", successor)
+	}
+	return "^" + latest, nil
+}
+
+// validateEndOfLife checks the successor name and message template.
+func validateEndOfLife(eol EndOfLife) error {
+	if eol.Successor != "" && !packageNamePattern.MatchString(eol.Successor) {
+		return fmt.Errorf("invalid successor package name %q", eol.Successor)
+	}
+	if eol.DependOnSuccessor && eol.Successor == "" {
+		return fmt.Errorf("depend_on_successor requires successor")
+	}
+	if eol.Message != "" {
+		if _, err := renderTemplate(eol.Message, templateData{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// endOfLifeMessage renders the deprecation message.
+func endOfLifeMessage(eol EndOfLife, data templateData) (string, error) {
+	if eol.Message != "" {
+		return renderTemplate(eol.Message, data)
+	}
+	if eol.Successor != "" {
+		return fmt.Sprintf("%s is no longer maintained; use %s instead", data.Name, eol.Successor), nil
+	}
+	return fmt.Sprintf("%s is no longer maintained", data.Name), nil
+}
+
+// writeEndOfLifeStub writes the stub package to dir. A non-empty
+// successorRange adds the dependency on the successor.
+func writeEndOfLifeStub(dir, name, version, message string, eol EndOfLife, successorRange string) error {
+	manifest := map[string]any{
+		"name":        name,
+		"version":     version,
+		"description": message,
+	}
+	if successorRange != "" {
+		manifest["dependencies"] = map[string]string{eol.Successor: successorRange}
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal stub package.json: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write stub package.json: %w", err)
+	}
+
+	readme := fmt.Sprintf("# %s\n\n**Deprecated:** %s\n", name, message)
+	if eol.Successor != "" {
+		readme += fmt.Sprintf("\nInstall [%s](https://www.npmjs.com/package/%s) instead:\n\n```sh\nnpm install %s\n```\n", eol.Successor, eol.Successor, eol.Successor)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte(readme), 0644); err != nil {
+		return fmt.Errorf("failed to write stub README.md: %w", err)
+	}
+	return nil
+}
+
+// endOfLife retires the package: it publishes the stub version if configured
+// and then deprecates all versions.
+func (p *NpmPlugin) endOfLife(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
+	if err := p.validateConfig(cfg); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("configuration validation failed: %v", err),
+		}, nil
+	}
+
+	packageDir, err := validatePackageDir(cfg.PackageDir)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid package directory: %v", err),
+		}, nil
+	}
+	pkg, err := readPackageJSON(packageDir)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	if pkg.Private {
+		resp := skipResponse("private", "Package is private, skipping end-of-life deprecation")
+		resp.Outputs["package"] = pkg.Name
+		return resp, nil
+	}
+	cfg = scopedConfig(cfg, pkg.Name)
+
+	eol := endOfLifeSettings(cfg)
+	message, err := endOfLifeMessage(eol, newTemplateData(pkg.Name, cfg, releaseCtx))
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid end_of_life message: %v", err),
+		}, nil
+	}
+
+	outputs := map[string]any{
+		"package":     pkg.Name,
+		"end_of_life": true,
+		"deprecation": message,
+	}
+	if eol.Successor != "" {
+		outputs["successor"] = eol.Successor
+	}
+	if eol.PublishStub {
+		outputs["stub_version"] = releaseCtx.Version
+	}
+
+	var depRange string
+	if eol.PublishStub && eol.DependOnSuccessor && eol.Successor != "" {
+		depRange, err = successorRange(ctx, cfg, eol.Successor)
+		if err != nil && !dryRun {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		if err != nil {
+			appendWarning(outputs, err.Error())
+		} else {
+			outputs["successor_range"] = depRange
+		}
+	}
+
+	if dryRun {
+		msg := fmt.Sprintf("Would deprecate all versions of %s: %q", pkg.Name, message)
+		if eol.PublishStub {
+			msg = fmt.Sprintf("Would publish stub %s@%s and deprecate all versions: %q", pkg.Name, releaseCtx.Version, message)
+		}
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: msg,
+			Outputs: outputs,
+		}, nil
+	}
+
+	done, err := useEphemeralNpmrc(cfg, outputs, dryRun)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	defer done()
+
+	if eol.PublishStub {
+		stubDir, err := os.MkdirTemp("", "relicta-npm-stub-")
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to create stub directory: %v", err),
+			}, nil
+		}
+		defer func() { _ = os.RemoveAll(stubDir) }()
+
+		if err := writeEndOfLifeStub(stubDir, pkg.Name, releaseCtx.Version, message, eol, depRange); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		if err := publishGeneratedPackage(ctx, cfg, stubDir); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to publish end-of-life stub: %v", err),
+			}, nil
+		}
+	}
+
+	// A bare package name deprecates every version, prereleases included
+	args := append([]string{"deprecate", pkg.Name, message}, registryArgs(cfg)...)
+	if _, err := runNpm(ctx, packageDir, args...); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to deprecate %s: %v", pkg.Name, err),
+			Outputs: outputs,
+		}, nil
+	}
+
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Deprecated all versions of %s", pkg.Name),
+		Outputs: outputs,
+	}, nil
+}
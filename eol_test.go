@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestEndOfLifeMessage(t *testing.T) {
+	data := templateData{Name: "old-lib", Version: "3.0.0"}
+	tests := []struct {
+		eol  EndOfLife
+		want string
+	}{
+		{EndOfLife{}, "old-lib is no longer maintained"},
+		{EndOfLife{Successor: "@acme/new-lib"}, "old-lib is no longer maintained; use @acme/new-lib instead"},
+		{EndOfLife{Message: "{{.Name}} ends at {{.Version}}"}, "old-lib ends at 3.0.0"},
+	}
+	for _, tt := range tests {
+		got, err := endOfLifeMessage(tt.eol, data)
+		if err != nil || got != tt.want {
+			t.Errorf("endOfLifeMessage(%+v) = %q, %v; want %q", tt.eol, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidateEndOfLife(t *testing.T) {
+	tests := []struct {
+		name    string
+		eol     EndOfLife
+		wantErr bool
+	}{
+		{"empty", EndOfLife{}, false},
+		{"scoped successor", EndOfLife{Successor: "@acme/new-lib"}, false},
+		{"invalid successor", EndOfLife{Successor: "--registry=evil"}, true},
+		{"bad template", EndOfLife{Message: "{{.Nope}}"}, true},
+		{"dependency without successor", EndOfLife{DependOnSuccessor: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateEndOfLife(tt.eol); (err != nil) != tt.wantErr {
+				t.Errorf("validateEndOfLife() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteEndOfLifeStub(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeEndOfLifeStub(dir, "old-lib", "3.0.0", "use new-lib", EndOfLife{Successor: "new-lib"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := readPackageJSON(dir)
+	if err != nil || pkg.Name != "old-lib" || pkg.Version != "3.0.0" {
+		t.Errorf("stub package.json = %+v, %v", pkg, err)
+	}
+	readme, _ := os.ReadFile(filepath.Join(dir, "README.md"))
+	if !strings.Contains(string(readme), "npm install new-lib") {
+		t.Errorf("stub README = %q", readme)
+	}
+}
+
+func TestEndOfLifeExecute(t *testing.T) {
+	config := map[string]any{
+		"end_of_life": map[string]any{"enabled": true, "successor": "new-lib", "publish_stub": true},
+	}
+	newPackage := func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "package.json"), `{"name":"old-lib","version":"2.0.0"}`)
+		chdir(t, dir)
+		t.Setenv("TMPDIR", t.TempDir())
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		logPath := fakeNpm(t, "")
+		newPackage(t)
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish, Config: config, Context: plugin.ReleaseContext{Version: "3.0.0"}, DryRun: true,
+		})
+		if err != nil || !resp.Success || !strings.Contains(resp.Message, "Would publish stub old-lib@3.0.0") {
+			t.Fatalf("unexpected response: %+v %v", resp, err)
+		}
+		if calls := npmCalls(t, logPath); len(calls) != 0 {
+			t.Errorf("npm should not run in dry run, got %q", calls)
+		}
+	})
+
+	t.Run("publish stub and deprecate", func(t *testing.T) {
+		logPath := fakeNpm(t, `pwd >> "$(dirname "$0")/dirs.log"`)
+		newPackage(t)
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish, Config: config, Context: plugin.ReleaseContext{Version: "3.0.0"},
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("unexpected failure: %+v %v", resp, err)
+		}
+		calls := npmCalls(t, logPath)
+		if len(calls) != 2 || !strings.HasPrefix(calls[0], "publish") || !strings.HasPrefix(calls[1], "deprecate old-lib old-lib is no longer maintained; use new-lib instead") {
+			t.Errorf("unexpected npm calls: %q", calls)
+		}
+		dirs, _ := os.ReadFile(filepath.Join(filepath.Dir(logPath), "dirs.log"))
+		if !strings.Contains(strings.Split(string(dirs), "\n")[0], "relicta-npm-stub-") {
+			t.Errorf("stub not published from its own directory: %q", dirs)
+		}
+		if resp.Outputs["stub_version"] != "3.0.0" || resp.Outputs["successor"] != "new-lib" {
+			t.Errorf("unexpected outputs: %v", resp.Outputs)
+		}
+	})
+}
+
+func TestSupersededBy(t *testing.T) {
+	server := newTestRegistry(t, map[string]*packument{
+		"@acme/new-lib": {Name: "@acme/new-lib", DistTags: map[string]string{"latest": "1.4.0"}},
+	})
+	// The fake npm keeps the stub manifest it was asked to publish
+	logPath := fakeNpm(t, `if [ "$1" = publish ]; then cp package.json "$(dirname "$0")/stub.json"; fi`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"old-lib","version":"2.0.0"}`)
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"superseded_by": "@acme/new-lib", "registry": server.URL},
+		Context: plugin.ReleaseContext{Version: "2.0.1"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %+v %v", resp, err)
+	}
+	stub, err := os.ReadFile(filepath.Join(filepath.Dir(logPath), "stub.json"))
+	if err != nil || !strings.Contains(string(stub), `"@acme/new-lib": "^1.4.0"`) || !strings.Contains(string(stub), `"version": "2.0.1"`) {
+		t.Errorf("unexpected stub manifest: %s (%v)", stub, err)
+	}
+	calls := npmCalls(t, logPath)
+	if len(calls) != 2 || !strings.HasPrefix(calls[1], "deprecate old-lib ") {
+		t.Errorf("unexpected npm calls: %q", calls)
+	}
+
+	resp, err = (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"superseded_by": "@acme/unpublished", "registry": server.URL},
+		Context: plugin.ReleaseContext{Version: "2.0.1"},
+	})
+	if err != nil || resp.Success || !strings.Contains(resp.Error, "successor") {
+		t.Errorf("expected failure for unpublished successor, got %+v %v", resp, err)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// errPublishedDiffers is returned when the version is already published with
+// other contents.
+var errPublishedDiffers = errors.New("already published with different contents")
+
+// tarballIntegrity returns the SRI string npm records for a tarball.
+func tarballIntegrity(tarball []byte) string {
+	sum := sha512.Sum512(tarball)
+	return "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// localIntegrity returns the integrity of the tarball the publish would
+// upload, without writing it anywhere: the input tarball's, the plugin's own
+// pack with publish_method api, otherwise npm pack --dry-run's. npm packs
+// deterministically, so an unchanged package matches its published tarball.
+func localIntegrity(ctx context.Context, cfg *Config, packageDir string) (string, error) {
+	if cfg.inputTarball != "" {
+		data, err := os.ReadFile(cfg.inputTarball)
+		if err != nil {
+			return "", fmt.Errorf("failed to read tarball: %w", err)
+		}
+		return tarballIntegrity(data), nil
+	}
+	if cfg.PublishMethod == publishMethodAPI {
+		manifest, err := readManifest(packageDir)
+		if err != nil {
+			return "", err
+		}
+		paths, err := collectPackFiles(packageDir, readManifestFiles(manifest))
+		if err != nil {
+			return "", err
+		}
+		data, _, _, err := buildTarball(packageDir, paths)
+		if err != nil {
+			return "", err
+		}
+		return tarballIntegrity(data), nil
+	}
+	args := append([]string{"pack", "--dry-run", "--json"}, npmConfigArgs(cfg)...)
+	stdout, err := runScriptedNpm(ctx, cfg, packageDir, append(args, scriptArgs(cfg)...)...)
+	if err != nil {
+		return "", err
+	}
+	var results []publishResult
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil || len(results) == 0 || results[0].Integrity == "" {
+		return "", fmt.Errorf("failed to parse npm pack output: %q", stdout)
+	}
+	return results[0].Integrity, nil
+}
+
+// checkExistingVersion looks name@version up in the registry before
+// publishing. It reports true when that exact tarball is already published,
+// so a re-run release can succeed without publishing, and returns
+// errPublishedDiffers when the version holds different contents, which npm
+// would refuse anyway.
+func checkExistingVersion(ctx context.Context, cfg *Config, packageDir, name, version string) (bool, error) {
+	registry := publishRegistry(cfg)
+	if registry == "" {
+		registry = registryURL(cfg)
+	}
+	doc, err := fetchPackument(ctx, registry, name)
+	if errors.Is(err, errPackageNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check the registry for %s@%s: %w", name, version, err)
+	}
+	published, ok := doc.Versions[version]
+	if !ok {
+		return false, nil
+	}
+	local, err := localIntegrity(ctx, cfg, packageDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to compute the tarball integrity: %w", err)
+	}
+	if published.Dist.Integrity != local {
+		return false, fmt.Errorf("%s@%s is %w (registry %s, local %s)", name, version, errPublishedDiffers, published.Dist.Integrity, local)
+	}
+	return true, nil
+}
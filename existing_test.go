@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestSkipExisting(t *testing.T) {
+	tests := []struct {
+		name      string
+		versions  map[string]packumentVersion
+		wantSkip  bool
+		wantError string
+	}{
+		{
+			name:     "same tarball",
+			versions: map[string]packumentVersion{"1.0.0": {Dist: packumentDist{Integrity: "sha512-local"}}},
+			wantSkip: true,
+		},
+		{
+			name:      "different tarball",
+			versions:  map[string]packumentVersion{"1.0.0": {Dist: packumentDist{Integrity: "sha512-other"}}},
+			wantError: "already published with different contents",
+		},
+		{
+			name:     "new version",
+			versions: map[string]packumentVersion{"0.9.0": {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := fakeNpm(t, `if [ "$1" = pack ]; then echo '[{"integrity":"sha512-local"}]'; else echo '{}'; fi`)
+			t.Setenv("TMPDIR", t.TempDir())
+			server := newTestRegistry(t, map[string]*packument{"lib": {Versions: tt.versions}})
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+			chdir(t, dir)
+
+			resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  map[string]any{"registry": server.URL, "skip_existing": true},
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantError != "" {
+				if resp.Success || !strings.Contains(resp.Error, tt.wantError) {
+					t.Fatalf("expected error %q, got %+v", tt.wantError, resp)
+				}
+				return
+			}
+			if !resp.Success {
+				t.Fatalf("unexpected failure: %s", resp.Error)
+			}
+			published := false
+			for _, call := range npmCalls(t, logPath) {
+				published = published || strings.HasPrefix(call, "publish")
+			}
+			if skipped := resp.Outputs["skip_reason"] == "already_published"; skipped != tt.wantSkip || published == tt.wantSkip {
+				t.Errorf("skipped = %v, published = %v, want skip %v", skipped, published, tt.wantSkip)
+			}
+		})
+	}
+}
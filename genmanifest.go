@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+const (
+	missingManifestFail     = "fail"
+	missingManifestSkip     = "skip"
+	missingManifestGenerate = "generate"
+)
+
+// errUnknownMissingManifest reports a missing_manifest value that is not one
+// of the modes.
+var errUnknownMissingManifest = errors.New("unknown mode")
+
+// missingManifestMode returns the effective missing_manifest mode; a
+// manifest_template implies generate.
+func missingManifestMode(cfg *Config) string {
+	if cfg.MissingManifest == "" && cfg.ManifestTemplate != "" {
+		return missingManifestGenerate
+	}
+	if cfg.MissingManifest == "" {
+		return missingManifestFail
+	}
+	return cfg.MissingManifest
+}
+
+// manifestTemplateText returns the manifest template source: inline JSON
+// when it starts with "{", otherwise the contents of the file it names.
+func manifestTemplateText(tmpl string) (string, error) {
+	if strings.HasPrefix(strings.TrimSpace(tmpl), "{") {
+		return tmpl, nil
+	}
+	if err := validateOutputPath(tmpl); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to read manifest template: %w", err)
+	}
+	return string(data), nil
+}
+
+// renderManifest renders the manifest template with the release and checks
+// that the result is a package.json with a name. A missing version is filled
+// from the release.
+func renderManifest(cfg *Config, releaseCtx plugin.ReleaseContext) (map[string]any, error) {
+	text, err := manifestTemplateText(cfg.ManifestTemplate)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := renderTemplate(text, newTemplateData(cfg.PackageName, cfg, releaseCtx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render manifest template: %w", err)
+	}
+	var manifest map[string]any
+	if err := json.Unmarshal([]byte(rendered), &manifest); err != nil {
+		return nil, fmt.Errorf("manifest template did not render valid JSON: %w", err)
+	}
+	name, _ := manifest["name"].(string)
+	if !packageNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("manifest template rendered invalid package name %q", name)
+	}
+	if _, ok := manifest["version"]; !ok {
+		manifest["version"] = releaseCtx.Version
+	}
+	return manifest, nil
+}
+
+// manifestMissing reports whether the package directory has no package.json.
+func manifestMissing(cfg *Config) bool {
+	packageDir, err := validatePackageDir(cfg.PackageDir)
+	if err != nil {
+		return false // reported by the hook itself
+	}
+	_, err = os.Stat(filepath.Join(packageDir, "package.json"))
+	return errors.Is(err, os.ErrNotExist)
+}
+
+// generateManifest writes a package.json for artifact-only packages whose
+// source tree has none: rendered from ManifestTemplate, or a minimal one.
+func generateManifest(cfg *Config, releaseCtx plugin.ReleaseContext) error {
+	packageDir, err := validatePackageDir(cfg.PackageDir)
+	if err != nil {
+		return fmt.Errorf("invalid package directory: %w", err)
+	}
+	manifest := map[string]any{
+		"name":    cfg.PackageName,
+		"version": releaseCtx.Version,
+	}
+	if cfg.ManifestTemplate != "" {
+		if manifest, err = renderManifest(cfg, releaseCtx); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal package.json: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(packageDir, "package.json"), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write package.json: %w", err)
+	}
+	return nil
+}
+
+// validateMissingManifest checks the missing_manifest mode and that
+// generating has a package name or template to use.
+func validateMissingManifest(cfg *Config) error {
+	switch missingManifestMode(cfg) {
+	case missingManifestFail, missingManifestSkip:
+		if cfg.ManifestTemplate != "" {
+			return fmt.Errorf("manifest_template requires missing_manifest %q", missingManifestGenerate)
+		}
+	case missingManifestGenerate:
+		if cfg.PackageName == "" && cfg.ManifestTemplate == "" {
+			return fmt.Errorf("missing_manifest %q requires package_name or manifest_template", missingManifestGenerate)
+		}
+		if cfg.VersionSource == versionSourcePackageJSON {
+			return fmt.Errorf("missing_manifest %q cannot be used with version_source %q", missingManifestGenerate, versionSourcePackageJSON)
+		}
+	default:
+		return fmt.Errorf("missing_manifest validation failed: %w %q", errUnknownMissingManifest, cfg.MissingManifest)
+	}
+	if cfg.ManifestTemplate != "" {
+		text, err := manifestTemplateText(cfg.ManifestTemplate)
+		if err != nil {
+			return fmt.Errorf("manifest_template validation failed: %w", err)
+		}
+		if _, err := template.New("manifest").Parse(text); err != nil {
+			return fmt.Errorf("manifest_template validation failed: %w", err)
+		}
+	}
+	if cfg.PackageName != "" && !packageNamePattern.MatchString(cfg.PackageName) {
+		return fmt.Errorf("package_name validation failed: invalid package name %q", cfg.PackageName)
+	}
+	return nil
+}
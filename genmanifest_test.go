@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateMissingManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"default", Config{}, false},
+		{"skip", Config{MissingManifest: missingManifestSkip}, false},
+		{"generate", Config{MissingManifest: missingManifestGenerate, PackageName: "@acme/schemas"}, false},
+		{"generate without name", Config{MissingManifest: missingManifestGenerate}, true},
+		{"generate with package_json version", Config{MissingManifest: missingManifestGenerate, PackageName: "pkg", VersionSource: versionSourcePackageJSON}, true},
+		{"invalid name", Config{PackageName: "Bad Name"}, true},
+		{"unknown", Config{MissingManifest: "ignore"}, true},
+		{"template implies generate", Config{ManifestTemplate: `{"name":"{{.RepoName}}"}`}, false},
+		{"template with skip", Config{MissingManifest: missingManifestSkip, ManifestTemplate: `{"name":"x"}`}, true},
+		{"template parse error", Config{ManifestTemplate: `{"name":"{{.Name"}`}, true},
+		{"template file outside cwd", Config{ManifestTemplate: "../manifest.json"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMissingManifest(&tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateMissingManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err := validateMissingManifest(&Config{MissingManifest: "ignore"}); !errors.Is(err, errUnknownMissingManifest) {
+		t.Errorf("validateMissingManifest() error = %v, want errUnknownMissingManifest", err)
+	}
+}
+
+func TestValidateMissingManifestMode(t *testing.T) {
+	resp, err := (&NpmPlugin{}).Validate(context.Background(), map[string]any{"missing_manifest": "ignore"})
+	if err != nil || resp.Valid {
+		t.Fatalf("Validate() = %+v, %v; want the unknown mode rejected", resp, err)
+	}
+	var found int
+	for _, e := range resp.Errors {
+		if e.Field == "missing_manifest" {
+			found++
+		}
+	}
+	if found != 1 {
+		t.Errorf("missing_manifest errors = %v, want the unknown mode reported once", resp.Errors)
+	}
+}
+
+func TestMissingManifest(t *testing.T) {
+	run := func(t *testing.T, hook plugin.Hook, config map[string]any) *plugin.ExecuteResponse {
+		t.Helper()
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    hook,
+			Config:  config,
+			Context: plugin.ReleaseContext{Version: "2.1.0"},
+			DryRun:  true,
+		})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		return resp
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		chdir(t, t.TempDir())
+		if resp := run(t, plugin.HookPostPublish, map[string]any{}); resp.Success {
+			t.Error("expected failure without package.json")
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		chdir(t, t.TempDir())
+		resp := run(t, plugin.HookPrePublish, map[string]any{"missing_manifest": "skip", "update_version": true})
+		if !resp.Success || resp.Outputs["skip_reason"] != "missing_manifest" {
+			t.Errorf("response = %+v", resp)
+		}
+	})
+
+	t.Run("generate", func(t *testing.T) {
+		dir := t.TempDir()
+		chdir(t, dir)
+		fakeNpm(t, `echo '{}'`)
+		t.Setenv("TMPDIR", t.TempDir())
+		resp := run(t, plugin.HookPostPublish, map[string]any{"missing_manifest": "generate", "package_name": "@acme/schemas"})
+		if !resp.Success || resp.Outputs["manifest_generated"] != true || resp.Outputs["package"] != "@acme/schemas" {
+			t.Fatalf("response = %+v", resp)
+		}
+		pkg, err := readPackageJSON(dir)
+		if err != nil || pkg.Name != "@acme/schemas" || pkg.Version != "2.1.0" {
+			t.Errorf("generated package.json = %+v (%v)", pkg, err)
+		}
+	})
+
+	t.Run("existing manifest kept", func(t *testing.T) {
+		dir := t.TempDir()
+		chdir(t, dir)
+		writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"2.1.0"}`)
+		resp := run(t, plugin.HookPrePublish, map[string]any{"missing_manifest": "generate", "package_name": "@acme/schemas"})
+		if !resp.Success || resp.Outputs["manifest_generated"] != nil {
+			t.Errorf("response = %+v", resp)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, "package.json")); string(data) != `{"name":"pkg","version":"2.1.0"}` {
+			t.Errorf("package.json rewritten: %s", data)
+		}
+	})
+}
+
+func TestRenderManifest(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+	writeFile(t, filepath.Join(dir, "manifest.tmpl.json"), `{"name":"@{{.RepoOwner}}/{{.RepoName}}-bin","version":"{{.Version}}","bin":{"tool":"tool"},"os":["linux"]}`)
+	release := plugin.ReleaseContext{Version: "3.0.0", RepositoryOwner: "acme", RepositoryName: "tool"}
+
+	manifest, err := renderManifest(&Config{ManifestTemplate: "manifest.tmpl.json"}, release)
+	if err != nil {
+		t.Fatalf("renderManifest() error = %v", err)
+	}
+	if manifest["name"] != "@acme/tool-bin" || manifest["version"] != "3.0.0" || manifest["bin"] == nil {
+		t.Errorf("manifest = %v", manifest)
+	}
+
+	manifest, err = renderManifest(&Config{ManifestTemplate: `{"name":"{{.Name}}"}`, PackageName: "schemas"}, release)
+	if err != nil || manifest["name"] != "schemas" || manifest["version"] != "3.0.0" {
+		t.Errorf("inline manifest = %v (%v)", manifest, err)
+	}
+
+	for _, tmpl := range []string{`{"name":`, `{"version":"1.0.0"}`, `{"name":"{{.Unknown}}"}`} {
+		if _, err := renderManifest(&Config{ManifestTemplate: tmpl}, release); err == nil {
+			t.Errorf("renderManifest(%q) expected error", tmpl)
+		}
+	}
+}
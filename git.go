@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// runGit runs git with args in dir and returns its trimmed stdout.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	recordCommand(ctx, dir, "git", args, start, err)
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v\nstderr: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// verifyCheckout ensures the working tree is the checkout the release context
+// describes: HEAD must be the release commit and, when a branch is given, the
+// checked-out branch. CI systems usually check out a detached HEAD; that is
+// accepted when HEAD was matched against the release commit, or otherwise
+// when it is the tip of the branch, locally or on a remote. This catches
+// stale workspaces reused across jobs.
+func verifyCheckout(ctx context.Context, dir string, releaseCtx plugin.ReleaseContext) error {
+	var head string
+	if releaseCtx.CommitSHA != "" {
+		var err error
+		head, err = runGit(ctx, dir, "rev-parse", "HEAD")
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(head, releaseCtx.CommitSHA) {
+			return fmt.Errorf("checkout is at %s but release is for commit %s", head, releaseCtx.CommitSHA)
+		}
+	}
+
+	if releaseCtx.Branch != "" {
+		branch, err := runGit(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return err
+		}
+		if branch == "HEAD" {
+			if head != "" {
+				return nil
+			}
+			return verifyDetachedBranch(ctx, dir, releaseCtx.Branch)
+		}
+		if branch != releaseCtx.Branch {
+			return fmt.Errorf("checkout is on branch %s but release is for branch %s", branch, releaseCtx.Branch)
+		}
+	}
+	return nil
+}
+
+// verifyDetachedBranch checks that a detached HEAD is the tip of branch, as
+// refs/heads/<branch> or refs/remotes/*/<branch>.
+func verifyDetachedBranch(ctx context.Context, dir, branch string) error {
+	head, err := runGit(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	tips, err := runGit(ctx, dir, "for-each-ref", "--format=%(objectname)", "refs/heads/"+branch, "refs/remotes/*/"+branch)
+	if err != nil {
+		return err
+	}
+	for _, tip := range strings.Fields(tips) {
+		if tip == head {
+			return nil
+		}
+	}
+	return fmt.Errorf("checkout is a detached HEAD at %s, not the tip of branch %s", head, branch)
+}
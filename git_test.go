@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// initGitRepo creates a repository with one commit on branch main and returns
+// its directory and HEAD commit.
+func initGitRepo(t *testing.T) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	ctx := context.Background()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if _, err := runGit(ctx, dir, args...); err != nil {
+			t.Fatalf("git setup failed: %v", err)
+		}
+	}
+	head, err := runGit(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatalf("git rev-parse failed: %v", err)
+	}
+	return dir, head
+}
+
+func TestVerifyCheckout(t *testing.T) {
+	ctx := context.Background()
+	dir, head := initGitRepo(t)
+
+	tests := []struct {
+		name       string
+		releaseCtx plugin.ReleaseContext
+		wantErr    string
+	}{
+		{"matches", plugin.ReleaseContext{Branch: "main", CommitSHA: head}, ""},
+		{"short_sha", plugin.ReleaseContext{Branch: "main", CommitSHA: head[:7]}, ""},
+		{"empty_context", plugin.ReleaseContext{}, ""},
+		{"wrong_commit", plugin.ReleaseContext{CommitSHA: "0000000"}, "release is for commit"},
+		{"wrong_branch", plugin.ReleaseContext{Branch: "release"}, "on branch main"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyCheckout(ctx, dir, tt.releaseCtx)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("detached_head", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"checkout", "-q", "--detach"},
+			{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "next"},
+		} {
+			if _, err := runGit(ctx, dir, args...); err != nil {
+				t.Fatalf("git %v failed: %v", args, err)
+			}
+		}
+		next, err := runGit(ctx, dir, "rev-parse", "HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := verifyCheckout(ctx, dir, plugin.ReleaseContext{Branch: "main", CommitSHA: next}); err != nil {
+			t.Errorf("detached HEAD at the release commit: %v", err)
+		}
+		err = verifyCheckout(ctx, dir, plugin.ReleaseContext{Branch: "main"})
+		if err == nil || !strings.Contains(err.Error(), "not the tip of branch main") {
+			t.Errorf("expected detached HEAD error, got %v", err)
+		}
+
+		if _, err := runGit(ctx, dir, "update-ref", "refs/remotes/origin/release", next); err != nil {
+			t.Fatal(err)
+		}
+		if err := verifyCheckout(ctx, dir, plugin.ReleaseContext{Branch: "release"}); err != nil {
+			t.Errorf("detached HEAD at the remote branch tip: %v", err)
+		}
+
+		if _, err := runGit(ctx, dir, "checkout", "-q", "--detach", head); err != nil {
+			t.Fatal(err)
+		}
+		if err := verifyCheckout(ctx, dir, plugin.ReleaseContext{Branch: "main"}); err != nil {
+			t.Errorf("detached HEAD at the local branch tip: %v", err)
+		}
+	})
+}
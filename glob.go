@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// compileGlob converts a slash-separated glob to a regular expression.
+// "*" and "?" do not cross directories; "**" matches any number of them.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty glob")
+	}
+	pattern = strings.TrimPrefix(path.Clean(pattern), "./")
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// compileGlobs compiles each glob, naming the config key in errors.
+func compileGlobs(key string, patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := compileGlob(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid glob %q: %w", key, p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"dist/**/*.min.js", "dist/index.min.js", true},
+		{"dist/**/*.min.js", "dist/esm/deep/index.min.js", true},
+		{"dist/**/*.min.js", "dist/index.js", false},
+		{"dist/*.js", "dist/esm/index.js", false},
+		{"./lib/?.js", "lib/a.js", true},
+		{"**/*.d.ts", "index.d.ts", true},
+		{"dist/index.js", "dist/indexXjs", false},
+	}
+
+	for _, tt := range tests {
+		re, err := compileGlob(tt.pattern)
+		if err != nil {
+			t.Fatalf("compileGlob(%q) returned error: %v", tt.pattern, err)
+		}
+		if got := re.MatchString(tt.path); got != tt.want {
+			t.Errorf("glob %q match %q = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
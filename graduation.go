@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// tagMove records a dist-tag moved (or to be moved) off a superseded prerelease.
+type tagMove struct {
+	Tag  string `json:"tag"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// graduationReport describes what a stable release superseded, for release
+// notes and notification plugins.
+type graduationReport struct {
+	Version    string    `json:"version"`
+	Superseded []string  `json:"superseded"`
+	MovedTags  []tagMove `json:"moved_tags,omitempty"`
+	StaleTags  []tagMove `json:"stale_tags,omitempty"`
+	Deprecated []string  `json:"deprecated,omitempty"`
+}
+
+// newGraduationReport lists the prereleases of stable's major.minor.patch
+// and the dist-tags (other than latest and the release tag) still pointing
+// at them. Tags are reported as stale; the caller moves them if configured.
+func newGraduationReport(doc *packument, stable semver, releaseTag string) *graduationReport {
+	report := &graduationReport{Version: stable.String(), Superseded: []string{}}
+	superseded := map[string]bool{}
+
+	var versions []semver
+	for published := range doc.Versions {
+		v, err := parseSemver(published)
+		if err != nil || !v.IsPrerelease() || v.Major != stable.Major || v.Minor != stable.Minor || v.Patch != stable.Patch {
+			continue
+		}
+		versions = append(versions, v)
+		superseded[published] = true
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Compare(versions[j]) < 0 })
+	for _, v := range versions {
+		report.Superseded = append(report.Superseded, v.String())
+	}
+
+	for tag, version := range doc.DistTags {
+		if tag == "latest" || tag == releaseTag || !superseded[version] {
+			continue
+		}
+		report.StaleTags = append(report.StaleTags, tagMove{Tag: tag, From: version, To: report.Version})
+	}
+	sort.Slice(report.StaleTags, func(i, j int) bool { return report.StaleTags[i].Tag < report.StaleTags[j].Tag })
+	return report
+}
+
+// graduate builds the graduation report for a stable release and, when
+// GraduateTags is set, moves stale prerelease dist-tags to it. It returns
+// nil for prereleases.
+func graduate(ctx context.Context, cfg *Config, packageDir, name, version string, dryRun bool) (*graduationReport, error) {
+	stable, err := parseSemver(version)
+	if err != nil || stable.IsPrerelease() {
+		return nil, nil
+	}
+
+	doc, err := fetchPackument(ctx, registryURL(cfg), name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s from registry: %w", name, err)
+	}
+
+	report := newGraduationReport(doc, stable, cfg.Tag)
+	if !cfg.GraduateTags || dryRun {
+		return report, nil
+	}
+
+	var stale []tagMove
+	for _, move := range report.StaleTags {
+		args := append([]string{"dist-tag", "add", name + "@" + version, move.Tag}, registryArgs(cfg)...)
+		if _, err := runNpm(ctx, packageDir, args...); err != nil {
+			stale = append(stale, move)
+			continue
+		}
+		report.MovedTags = append(report.MovedTags, move)
+	}
+	report.StaleTags = stale
+	return report, nil
+}
+
+// addGraduationReport adds the "graduation" output. Failures are warnings:
+// the release itself has already succeeded or is only being previewed.
+func addGraduationReport(ctx context.Context, cfg *Config, outputs map[string]any, packageDir, name, version string, dryRun bool) {
+	report, err := graduate(ctx, cfg, packageDir, name, version, dryRun)
+	if err != nil {
+		appendWarning(outputs, fmt.Sprintf("graduation report unavailable: %v", err))
+		return
+	}
+	if report == nil {
+		return
+	}
+	if cfg.GraduateTags && !dryRun && len(report.StaleTags) > 0 {
+		tags := make([]string, len(report.StaleTags))
+		for i, move := range report.StaleTags {
+			tags[i] = move.Tag
+		}
+		appendWarning(outputs, fmt.Sprintf("failed to move dist-tags: %s", strings.Join(tags, ", ")))
+	}
+	outputs["graduation"] = report
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func graduationPackument() *packument {
+	return &packument{
+		Name: "pkg",
+		DistTags: map[string]string{
+			"latest": "1.0.0",
+			"next":   "2.0.0-rc.1",
+			"beta":   "2.0.0-beta.3",
+			"canary": "2.1.0-canary.0",
+		},
+		Versions: map[string]packumentVersion{
+			"1.0.0":          {},
+			"2.0.0-beta.3":   {},
+			"2.0.0-rc.1":     {},
+			"2.0.0-rc.0":     {},
+			"2.1.0-canary.0": {},
+		},
+	}
+}
+
+func TestNewGraduationReport(t *testing.T) {
+	stable, _ := parseSemver("2.0.0")
+	report := newGraduationReport(graduationPackument(), stable, "latest")
+
+	if want := []string{"2.0.0-beta.3", "2.0.0-rc.0", "2.0.0-rc.1"}; !reflect.DeepEqual(report.Superseded, want) {
+		t.Errorf("Superseded = %v, want %v", report.Superseded, want)
+	}
+	want := []tagMove{{Tag: "beta", From: "2.0.0-beta.3", To: "2.0.0"}, {Tag: "next", From: "2.0.0-rc.1", To: "2.0.0"}}
+	if !reflect.DeepEqual(report.StaleTags, want) {
+		t.Errorf("StaleTags = %v, want %v", report.StaleTags, want)
+	}
+}
+
+func TestGraduateMovesTags(t *testing.T) {
+	server := newTestRegistry(t, map[string]*packument{"pkg": graduationPackument()})
+	logPath := fakeNpm(t, "")
+	ctx := context.Background()
+	cfg := &Config{Registry: server.URL, Tag: "latest", GraduateTags: true}
+
+	outputs := map[string]any{}
+	addGraduationReport(ctx, cfg, outputs, t.TempDir(), "pkg", "2.0.0", false)
+
+	report, ok := outputs["graduation"].(*graduationReport)
+	if !ok {
+		t.Fatalf("expected graduation output, got %v", outputs)
+	}
+	if len(report.MovedTags) != 2 || len(report.StaleTags) != 0 {
+		t.Errorf("expected both tags moved, got %+v", report)
+	}
+	calls := npmCalls(t, logPath)
+	if len(calls) != 2 || !strings.HasPrefix(calls[0], "dist-tag add pkg@2.0.0 beta") || !strings.HasPrefix(calls[1], "dist-tag add pkg@2.0.0 next") {
+		t.Errorf("unexpected npm calls: %v", calls)
+	}
+
+	outputs = map[string]any{}
+	addGraduationReport(ctx, cfg, outputs, t.TempDir(), "pkg", "2.1.0-canary.1", false)
+	if _, ok := outputs["graduation"]; ok {
+		t.Error("expected no report for a prerelease")
+	}
+}
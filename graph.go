@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// workspacePackage is one package of a multi-package publish.
+type workspacePackage struct {
+	Dir     string
+	Name    string
+	Version string
+	Private bool
+	// Deps are the names of the other workspace packages this one depends on.
+	Deps []string
+}
+
+// workspaceManifest is the subset of package.json the dependency graph needs.
+// Every dependency kind orders the publish, as any of them can make a
+// dependent unusable while its dependency is missing from the registry.
+type workspaceManifest struct {
+	Name                 string            `json:"name"`
+	Version              string            `json:"version"`
+	Private              bool              `json:"private"`
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+}
+
+// loadWorkspacePackages reads the packages matched by the workspace patterns
+// (directories or globs such as "packages/*"). Matches without a package.json
+// are skipped, as npm does; a pattern matching nothing is an error.
+func loadWorkspacePackages(patterns []string) ([]workspacePackage, error) {
+	var manifests []workspaceManifest
+	var dirs []string
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("workspaces: invalid pattern %q: %w", pattern, err)
+		}
+		found := false
+		for _, match := range matches {
+			dir, err := validatePackageDir(match)
+			if err != nil {
+				return nil, fmt.Errorf("workspaces: %s: %w", match, err)
+			}
+			data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("workspaces: %w", err)
+			}
+			found = true
+			if seen[dir] {
+				continue
+			}
+			seen[dir] = true
+			var m workspaceManifest
+			if err := json.Unmarshal(data, &m); err != nil {
+				return nil, fmt.Errorf("workspaces: failed to parse %s/package.json: %w", match, err)
+			}
+			if m.Name == "" {
+				return nil, fmt.Errorf("workspaces: %s/package.json has no name", match)
+			}
+			manifests = append(manifests, m)
+			dirs = append(dirs, match)
+		}
+		if !found {
+			return nil, fmt.Errorf("workspaces: %q matches no package", pattern)
+		}
+	}
+
+	dirByName := map[string]string{}
+	for i, m := range manifests {
+		if other, ok := dirByName[m.Name]; ok {
+			return nil, fmt.Errorf("workspaces: %s is defined in both %s and %s", m.Name, other, dirs[i])
+		}
+		dirByName[m.Name] = dirs[i]
+	}
+
+	pkgs := make([]workspacePackage, 0, len(manifests))
+	for i, m := range manifests {
+		deps := map[string]bool{}
+		for _, group := range []map[string]string{m.Dependencies, m.DevDependencies, m.PeerDependencies, m.OptionalDependencies} {
+			for dep := range group {
+				if _, ok := dirByName[dep]; ok && dep != m.Name {
+					deps[dep] = true
+				}
+			}
+		}
+		pkg := workspacePackage{Dir: dirs[i], Name: m.Name, Version: m.Version, Private: m.Private}
+		for dep := range deps {
+			pkg.Deps = append(pkg.Deps, dep)
+		}
+		sort.Strings(pkg.Deps)
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+// publishOrder sorts packages so every package follows the workspace packages
+// it depends on. Packages whose order is free are sorted by name, keeping the
+// order stable between runs. A dependency cycle is an error naming the cycle.
+func publishOrder(pkgs []workspacePackage) ([]workspacePackage, error) {
+	byName := make(map[string]workspacePackage, len(pkgs))
+	pending := make(map[string]int, len(pkgs))
+	dependents := map[string][]string{}
+	for _, pkg := range pkgs {
+		byName[pkg.Name] = pkg
+		pending[pkg.Name] = len(pkg.Deps)
+		for _, dep := range pkg.Deps {
+			dependents[dep] = append(dependents[dep], pkg.Name)
+		}
+	}
+
+	var ready []string
+	for name, n := range pending {
+		if n == 0 {
+			ready = append(ready, name)
+		}
+	}
+	ordered := make([]workspacePackage, 0, len(pkgs))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, byName[name])
+		for _, dependent := range dependents[name] {
+			if pending[dependent]--; pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(ordered) < len(pkgs) {
+		return nil, fmt.Errorf("dependency cycle between workspace packages: %s", strings.Join(findCycle(byName, pending), " -> "))
+	}
+	return ordered, nil
+}
+
+// findCycle returns one dependency cycle among the packages left unordered
+// (pending > 0), starting and ending with the same package.
+func findCycle(byName map[string]workspacePackage, pending map[string]int) []string {
+	var start string
+	for name, n := range pending {
+		if n > 0 && (start == "" || name < start) {
+			start = name
+		}
+	}
+	// Every unordered package has an unordered dependency, so following
+	// them must revisit a package.
+	index := map[string]int{}
+	var path []string
+	for name := start; ; {
+		if i, ok := index[name]; ok {
+			return append(path[i:], name)
+		}
+		index[name] = len(path)
+		path = append(path, name)
+		for _, dep := range byName[name].Deps {
+			if pending[dep] > 0 {
+				name = dep
+				break
+			}
+		}
+	}
+}
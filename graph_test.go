@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPublishOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		pkgs    []workspacePackage
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "chain",
+			pkgs: []workspacePackage{
+				{Name: "app", Deps: []string{"ui"}},
+				{Name: "ui", Deps: []string{"core"}},
+				{Name: "core"},
+			},
+			want: []string{"core", "ui", "app"},
+		},
+		{
+			name: "independent packages by name",
+			pkgs: []workspacePackage{
+				{Name: "b"},
+				{Name: "c", Deps: []string{"a"}},
+				{Name: "a"},
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "cycle",
+			pkgs: []workspacePackage{
+				{Name: "core"},
+				{Name: "a", Deps: []string{"b", "core"}},
+				{Name: "b", Deps: []string{"c"}},
+				{Name: "c", Deps: []string{"a"}},
+			},
+			wantErr: "dependency cycle between workspace packages: a -> b -> c -> a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := publishOrder(tt.pkgs)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("publishOrder() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("publishOrder() error = %v", err)
+			}
+			var got []string
+			for _, pkg := range ordered {
+				got = append(got, pkg.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("publishOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadWorkspacePackages(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+	writeFile(t, filepath.Join(dir, "packages", "core", "package.json"), `{"name":"@acme/core","version":"1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "packages", "ui", "package.json"), `{"name":"@acme/ui","version":"1.0.0","dependencies":{"@acme/core":"^1.0.0","react":"^18.0.0"},"devDependencies":{"@acme/ui":"*"}}`)
+	writeFile(t, filepath.Join(dir, "packages", "docs", "README.md"), "no manifest")
+	writeFile(t, filepath.Join(dir, "tools", "cli", "package.json"), `{"name":"@acme/cli","peerDependencies":{"@acme/ui":"*"}}`)
+
+	pkgs, err := loadWorkspacePackages([]string{"packages/*", "tools/cli", "packages/core"})
+	if err != nil {
+		t.Fatalf("loadWorkspacePackages() error = %v", err)
+	}
+	deps := map[string][]string{}
+	for _, pkg := range pkgs {
+		deps[pkg.Name] = pkg.Deps
+	}
+	want := map[string][]string{"@acme/core": nil, "@acme/ui": {"@acme/core"}, "@acme/cli": {"@acme/ui"}}
+	if !reflect.DeepEqual(deps, want) {
+		t.Errorf("deps = %v, want %v", deps, want)
+	}
+
+	if _, err := loadWorkspacePackages([]string{"apps/*"}); err == nil {
+		t.Error("expected an error for a pattern matching no package")
+	}
+	writeFile(t, filepath.Join(dir, "fork", "package.json"), `{"name":"@acme/core"}`)
+	if _, err := loadWorkspacePackages([]string{"packages/core", "fork"}); err == nil || !strings.Contains(err.Error(), "both") {
+		t.Errorf("expected a duplicate name error, got %v", err)
+	}
+}
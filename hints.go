@@ -0,0 +1,44 @@
+package main
+
+import "fmt"
+
+// updateHint tells automation that bumps consumers (Renovate, Dependabot,
+// internal bots) what range to depend on after a release.
+type updateHint struct {
+	Package string `json:"package"`
+	Version string `json:"version"`
+	Tag     string `json:"tag"`
+	// Range is the recommended dependency range: a caret range for stable
+	// releases, the exact version for prereleases, which ranges only match
+	// deliberately.
+	Range string `json:"range"`
+	// TildeRange accepts patch releases only.
+	TildeRange string `json:"tilde_range"`
+	// Breaking is set when consumers on the previous caret range do not
+	// receive this version: a major bump, or a minor bump below 1.0.0.
+	Breaking bool `json:"breaking"`
+	// Install is the package spec to install, e.g. "lib@^1.2.0".
+	Install string `json:"install"`
+}
+
+// newUpdateHint builds the update hint for a published version. Versions
+// that are not semver get the exact version as their range.
+func newUpdateHint(name, version, previous, releaseType, tag string) updateHint {
+	hint := updateHint{Package: name, Version: version, Tag: tag, Range: version, TildeRange: version}
+	v, err := parseSemver(version)
+	if err != nil {
+		hint.Install = fmt.Sprintf("%s@%s", name, hint.Range)
+		return hint
+	}
+	if !v.IsPrerelease() {
+		hint.Range = "^" + version
+		hint.TildeRange = "~" + version
+	}
+	if prev, err := parseSemver(previous); err == nil {
+		hint.Breaking = v.Major != prev.Major || (v.Major == 0 && v.Minor != prev.Minor)
+	} else {
+		hint.Breaking = releaseType == "major"
+	}
+	hint.Install = fmt.Sprintf("%s@%s", name, hint.Range)
+	return hint
+}
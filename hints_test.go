@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewUpdateHint(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		previous    string
+		releaseType string
+		want        updateHint
+	}{
+		{
+			name: "minor", version: "1.3.0", previous: "1.2.4", releaseType: "minor",
+			want: updateHint{Range: "^1.3.0", TildeRange: "~1.3.0", Install: "lib@^1.3.0"},
+		},
+		{
+			name: "major", version: "2.0.0", previous: "1.9.0", releaseType: "major",
+			want: updateHint{Range: "^2.0.0", TildeRange: "~2.0.0", Breaking: true, Install: "lib@^2.0.0"},
+		},
+		{
+			name: "zero minor", version: "0.4.0", previous: "0.3.2", releaseType: "minor",
+			want: updateHint{Range: "^0.4.0", TildeRange: "~0.4.0", Breaking: true, Install: "lib@^0.4.0"},
+		},
+		{
+			name: "prerelease", version: "2.0.0-rc.1", releaseType: "major",
+			want: updateHint{Range: "2.0.0-rc.1", TildeRange: "2.0.0-rc.1", Breaking: true, Install: "lib@2.0.0-rc.1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Package, tt.want.Version, tt.want.Tag = "lib", tt.version, "latest"
+			got := newUpdateHint("lib", tt.version, tt.previous, tt.releaseType, "latest")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newUpdateHint() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvFileVarsRange(t *testing.T) {
+	outputs := map[string]any{"package": "lib", "version": "1.3.0", "update_hint": newUpdateHint("lib", "1.3.0", "", "minor", "latest")}
+	if got := envFileVars(&Config{}, outputs)["PACKAGE_RANGE"]; got != "^1.3.0" {
+		t.Errorf("PACKAGE_RANGE = %q, want ^1.3.0", got)
+	}
+}
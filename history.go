@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// lastPublish is the most recent publish of a package before this release.
+type lastPublish struct {
+	Version     string
+	PublishedAt time.Time
+}
+
+// findLastPublish returns the most recently published version other than
+// version, using the packument publish times. Versions that have since been
+// unpublished are ignored.
+func findLastPublish(doc *packument, version string) (lastPublish, bool) {
+	var last lastPublish
+	for v, ts := range doc.Time {
+		if v == version {
+			continue
+		}
+		if _, ok := doc.Versions[v]; !ok {
+			continue // "created", "modified" or an unpublished version
+		}
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			continue
+		}
+		if t.After(last.PublishedAt) {
+			last = lastPublish{Version: v, PublishedAt: t}
+		}
+	}
+	return last, last.Version != ""
+}
+
+// addPublishHistory queries the registry for the previously published
+// version and adds it with the time elapsed since to outputs, for
+// notifications and dashboards. Registry failures become warnings; they
+// never block a publish.
+func addPublishHistory(ctx context.Context, cfg *Config, outputs map[string]any, name, version string, now time.Time) {
+	doc, err := fetchPackument(ctx, registryURL(cfg), name)
+	if errors.Is(err, errPackageNotFound) {
+		outputs["previous_published_version"] = ""
+		return
+	}
+	if err != nil {
+		appendWarning(outputs, fmt.Sprintf("previous published version unavailable: %v", err))
+		return
+	}
+
+	last, ok := findLastPublish(doc, version)
+	if !ok {
+		outputs["previous_published_version"] = ""
+		return
+	}
+
+	since := now.Sub(last.PublishedAt).Round(time.Second)
+	outputs["previous_published_version"] = last.Version
+	outputs["previous_published_at"] = last.PublishedAt.UTC().Format(time.RFC3339)
+	outputs["seconds_since_last_publish"] = int64(since / time.Second)
+	outputs["time_since_last_publish"] = since.String()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestFindLastPublish(t *testing.T) {
+	doc := &packument{
+		Versions: map[string]packumentVersion{"1.0.0": {}, "1.1.0": {}, "2.0.0-beta.1": {}},
+		Time: map[string]string{
+			"created":      "2024-01-01T00:00:00Z",
+			"modified":     "2024-06-01T00:00:00Z",
+			"1.0.0":        "2024-01-01T00:00:00Z",
+			"1.1.0":        "2024-03-01T00:00:00Z",
+			"2.0.0-beta.1": "2024-02-01T00:00:00Z",
+			"1.2.0":        "2024-05-01T00:00:00Z", // since unpublished
+		},
+	}
+
+	last, ok := findLastPublish(doc, "1.3.0")
+	if !ok || last.Version != "1.1.0" {
+		t.Errorf("findLastPublish() = %+v, %v, want 1.1.0", last, ok)
+	}
+
+	if _, ok := findLastPublish(&packument{}, "1.0.0"); ok {
+		t.Error("expected no previous publish for empty packument")
+	}
+}
+
+func TestAddPublishHistory(t *testing.T) {
+	ctx := context.Background()
+	server := newTestRegistry(t, map[string]*packument{
+		"pkg": {
+			Name:     "pkg",
+			Versions: map[string]packumentVersion{"1.0.0": {}},
+			Time:     map[string]string{"1.0.0": "2024-01-01T00:00:00Z"},
+		},
+	})
+	cfg := &Config{Registry: server.URL}
+	now := time.Date(2024, 1, 2, 1, 0, 0, 0, time.UTC)
+
+	outputs := map[string]any{}
+	addPublishHistory(ctx, cfg, outputs, "pkg", "1.1.0", now)
+	if outputs["previous_published_version"] != "1.0.0" {
+		t.Errorf("previous_published_version = %v", outputs["previous_published_version"])
+	}
+	if outputs["seconds_since_last_publish"] != int64(25*3600) {
+		t.Errorf("seconds_since_last_publish = %v", outputs["seconds_since_last_publish"])
+	}
+	if outputs["time_since_last_publish"] != "25h0m0s" {
+		t.Errorf("time_since_last_publish = %v", outputs["time_since_last_publish"])
+	}
+
+	outputs = map[string]any{}
+	addPublishHistory(ctx, cfg, outputs, "new-pkg", "0.1.0", now)
+	if v, ok := outputs["previous_published_version"]; !ok || v != "" {
+		t.Errorf("expected empty previous version for unpublished package, got %v", outputs)
+	}
+}
+
+func TestPublishPackageHistory(t *testing.T) {
+	p := &NpmPlugin{}
+	ctx := context.Background()
+	server := newTestRegistry(t, map[string]*packument{
+		"pkg": {
+			Name:     "pkg",
+			Versions: map[string]packumentVersion{"1.0.0": {}},
+			Time:     map[string]string{"1.0.0": "2024-01-01T00:00:00Z"},
+		},
+	})
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name":"pkg","version":"1.1.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, tmpDir)
+
+	cfg := &Config{Registry: server.URL, Tag: "latest", PublishHistory: true}
+	resp, err := p.publishPackage(ctx, cfg, plugin.ReleaseContext{Version: "1.1.0"}, true)
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %s", err, resp.Error)
+	}
+	if resp.Outputs["previous_published_version"] != "1.0.0" {
+		t.Errorf("previous_published_version = %v", resp.Outputs["previous_published_version"])
+	}
+	if _, ok := resp.Outputs["previous_published_at"]; !ok {
+		t.Error("expected previous_published_at output")
+	}
+}
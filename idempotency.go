@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Idempotency modes.
+const (
+	// idempotencySkip answers a duplicate execution with a successful no-op.
+	idempotencySkip = "skip"
+	// idempotencyRefuse fails a duplicate execution.
+	idempotencyRefuse = "refuse"
+)
+
+// idempotentHooks are the hooks with side effects worth guarding: version
+// bumps, uploads, dist-tag moves and rollbacks.
+var idempotentHooks = map[plugin.Hook]bool{
+	plugin.HookPrePublish:  true,
+	plugin.HookPostPublish: true,
+	plugin.HookOnSuccess:   true,
+	plugin.HookOnError:     true,
+}
+
+// idempotencyState is the executions recorded for one package, by key.
+type idempotencyState struct {
+	Executions map[string]time.Time `json:"executions"`
+}
+
+// idempotencyGuard records a hook execution once it has succeeded.
+type idempotencyGuard struct {
+	cfg  *Config
+	name string
+	key  string
+}
+
+// releaseID returns the id identifying the release: release_id when set,
+// otherwise the tag and commit, falling back to the version.
+func releaseID(cfg *Config, releaseCtx plugin.ReleaseContext) string {
+	if cfg.ReleaseID != "" {
+		return cfg.ReleaseID
+	}
+	id := releaseCtx.TagName
+	if id == "" {
+		id = releaseCtx.Version
+	}
+	if releaseCtx.CommitSHA != "" {
+		id += "@" + releaseCtx.CommitSHA
+	}
+	return id
+}
+
+// idempotencyKey joins the release id, hook and package into the key an
+// execution is recorded under.
+func idempotencyKey(release string, hook plugin.Hook, name string) string {
+	return strings.Join([]string{release, string(hook), name}, "/")
+}
+
+// checkIdempotency looks up the execution of hook for the package in
+// packageDir. It returns the response for a duplicate, or a guard to record
+// the execution with once it succeeds.
+func checkIdempotency(cfg *Config, hook plugin.Hook, releaseCtx plugin.ReleaseContext) (*plugin.ExecuteResponse, *idempotencyGuard, error) {
+	name := cfg.PackageDir
+	if packageDir, err := validatePackageDir(cfg.PackageDir); err == nil {
+		if pkg, err := readPackageJSON(packageDir); err == nil && pkg.Name != "" {
+			name = pkg.Name
+		}
+	}
+	if name == "" {
+		name = "."
+	}
+	key := idempotencyKey(releaseID(cfg, releaseCtx), hook, name)
+
+	var state idempotencyState
+	if _, err := loadState(cfg, name, "idempotency", &state); err != nil {
+		return nil, nil, err
+	}
+	at, ok := state.Executions[key]
+	if !ok {
+		return nil, &idempotencyGuard{cfg: cfg, name: name, key: key}, nil
+	}
+
+	msg := fmt.Sprintf("%s already ran for %s at %s", hook, name, at.UTC().Format(time.RFC3339))
+	if cfg.Idempotency == idempotencyRefuse {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("duplicate execution refused: %s", msg),
+			Outputs: map[string]any{"idempotency_key": key},
+		}, nil, nil
+	}
+	resp := skipResponse("duplicate", fmt.Sprintf("Skipping duplicate execution: %s", msg))
+	resp.Outputs["idempotency_key"] = key
+	return resp, nil, nil
+}
+
+// record saves the execution and adds its key to resp. A failure to save is
+// a warning: the hook itself has already succeeded.
+func (g *idempotencyGuard) record(resp *plugin.ExecuteResponse, now time.Time) {
+	setOutput(resp, "idempotency_key", g.key)
+	var state idempotencyState
+	if _, err := loadState(g.cfg, g.name, "idempotency", &state); err != nil {
+		appendWarning(resp.Outputs, err.Error())
+		return
+	}
+	if state.Executions == nil {
+		state.Executions = map[string]time.Time{}
+	}
+	state.Executions[g.key] = now
+	if err := saveState(g.cfg, g.name, "idempotency", state); err != nil {
+		appendWarning(resp.Outputs, err.Error())
+	}
+}
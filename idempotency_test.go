@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestReleaseID(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        Config
+		releaseCtx plugin.ReleaseContext
+		want       string
+	}{
+		{name: "configured", cfg: Config{ReleaseID: "run-42"}, releaseCtx: plugin.ReleaseContext{TagName: "v1.0.0"}, want: "run-42"},
+		{name: "tag and commit", releaseCtx: plugin.ReleaseContext{Version: "1.0.0", TagName: "v1.0.0", CommitSHA: "abc123"}, want: "v1.0.0@abc123"},
+		{name: "version only", releaseCtx: plugin.ReleaseContext{Version: "1.0.0"}, want: "1.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := releaseID(&tt.cfg, tt.releaseCtx); got != tt.want {
+				t.Errorf("releaseID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdempotentPublish(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	logPath := fakeNpm(t, `if [ -f fail ]; then exit 1; fi; echo '{}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	execute := func(mode, release string) *plugin.ExecuteResponse {
+		t.Helper()
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"idempotency": mode, "release_id": release},
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	publishes := func() int {
+		n := 0
+		for _, call := range npmCalls(t, logPath) {
+			if strings.HasPrefix(call, "publish") {
+				n++
+			}
+		}
+		return n
+	}
+
+	// A failed execution is not recorded, so the retry publishes
+	writeFile(t, filepath.Join(dir, "fail"), "")
+	if resp := execute("skip", "run-1"); resp.Success {
+		t.Fatalf("expected publish failure, got %+v", resp)
+	}
+	if err := os.Remove(filepath.Join(dir, "fail")); err != nil {
+		t.Fatal(err)
+	}
+	resp := execute("skip", "run-1")
+	if !resp.Success || resp.Outputs["idempotency_key"] != "run-1/post-publish/lib" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	resp = execute("skip", "run-1")
+	if !resp.Success || resp.Outputs["skip_reason"] != "duplicate" {
+		t.Errorf("expected duplicate to be skipped, got %+v", resp)
+	}
+	resp = execute("refuse", "run-1")
+	if resp.Success || !strings.HasPrefix(resp.Error, "duplicate execution refused: post-publish already ran for lib") {
+		t.Errorf("expected duplicate to be refused, got %+v", resp)
+	}
+	if n := publishes(); n != 2 {
+		t.Errorf("publish ran %d times, want 2", n)
+	}
+
+	if resp := execute("skip", "run-2"); !resp.Success || resp.Outputs["skipped"] == true {
+		t.Errorf("expected a new release to publish, got %+v", resp)
+	}
+	if n := publishes(); n != 3 {
+		t.Errorf("publish ran %d times, want 3", n)
+	}
+}
+
+func TestIdempotencyIgnoresDryRuns(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	fakeNpm(t, `echo '[{"files":[{"path":"package.json"}]}]'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	for i := 0; i < 2; i++ {
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"idempotency": "refuse", "release_id": "run-1", "dry_run": true},
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("dry run %d failed: %v %+v", i, err, resp)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// inputNamePattern matches the environment variable names inputs are read from.
+var inputNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// PluginInputs names variables set by earlier plugins (e.g. a build plugin's
+// artifact directory) that supply paths at release time instead of static
+// config. Each is looked up in the release context environment, then in the
+// process environment.
+type PluginInputs struct {
+	// PackageDir overrides package_dir.
+	PackageDir string `json:"package_dir,omitempty"`
+	// Tarball is a prebuilt tarball published as-is instead of packing
+	// package_dir.
+	Tarball string `json:"tarball,omitempty"`
+}
+
+// validatePluginInputs checks that inputs name valid variables.
+func validatePluginInputs(in PluginInputs) error {
+	for field, name := range map[string]string{"package_dir": in.PackageDir, "tarball": in.Tarball} {
+		if name != "" && !inputNamePattern.MatchString(name) {
+			return fmt.Errorf("%s: invalid variable name %q", field, name)
+		}
+	}
+	return nil
+}
+
+// lookupInput returns the value of an input variable.
+func lookupInput(releaseCtx plugin.ReleaseContext, name string) string {
+	if v := releaseCtx.Environment[name]; v != "" {
+		return v
+	}
+	return os.Getenv(name)
+}
+
+// applyPluginInputs resolves the configured inputs into cfg and returns the
+// values used, for the outputs. A configured input that is unset is an error,
+// as publishing a stale static path would be worse.
+func applyPluginInputs(cfg *Config, releaseCtx plugin.ReleaseContext) (map[string]string, error) {
+	if err := validatePluginInputs(cfg.Inputs); err != nil {
+		return nil, fmt.Errorf("inputs validation failed: %w", err)
+	}
+	resolved := map[string]string{}
+	if name := cfg.Inputs.PackageDir; name != "" {
+		dir := lookupInput(releaseCtx, name)
+		if dir == "" {
+			return nil, fmt.Errorf("input package_dir: %s is not set", name)
+		}
+		cfg.PackageDir = dir
+		resolved["package_dir"] = dir
+	}
+	if name := cfg.Inputs.Tarball; name != "" {
+		tarball := lookupInput(releaseCtx, name)
+		if tarball == "" {
+			return nil, fmt.Errorf("input tarball: %s is not set", name)
+		}
+		abs, err := resolveTarball(tarball)
+		if err != nil {
+			return nil, fmt.Errorf("input tarball: %w", err)
+		}
+		cfg.inputTarball = abs
+		resolved["tarball"] = tarball
+	}
+	return resolved, nil
+}
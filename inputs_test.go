@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestApplyPluginInputs(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+	writeFile(t, filepath.Join(dir, "out", "pkg-1.0.0.tgz"), "tgz")
+
+	t.Run("release context first", func(t *testing.T) {
+		t.Setenv("BUILD_DIR", "from-env")
+		cfg := &Config{PackageDir: "static", Inputs: PluginInputs{PackageDir: "BUILD_DIR", Tarball: "BUILD_TARBALL"}}
+		release := plugin.ReleaseContext{Environment: map[string]string{"BUILD_DIR": "out", "BUILD_TARBALL": "out/pkg-1.0.0.tgz"}}
+		got, err := applyPluginInputs(cfg, release)
+		if err != nil {
+			t.Fatalf("applyPluginInputs() error = %v", err)
+		}
+		if cfg.PackageDir != "out" || got["package_dir"] != "out" {
+			t.Errorf("package_dir = %q (%v)", cfg.PackageDir, got)
+		}
+		if cfg.inputTarball != filepath.Join(dir, "out", "pkg-1.0.0.tgz") {
+			t.Errorf("inputTarball = %q", cfg.inputTarball)
+		}
+	})
+
+	t.Run("process environment fallback", func(t *testing.T) {
+		t.Setenv("BUILD_DIR", "from-env")
+		cfg := &Config{Inputs: PluginInputs{PackageDir: "BUILD_DIR"}}
+		if _, err := applyPluginInputs(cfg, plugin.ReleaseContext{}); err != nil || cfg.PackageDir != "from-env" {
+			t.Errorf("package_dir = %q, err = %v", cfg.PackageDir, err)
+		}
+	})
+
+	for name, in := range map[string]PluginInputs{
+		"unset":           {PackageDir: "UNSET_BUILD_DIR"},
+		"invalid name":    {PackageDir: "BUILD-DIR"},
+		"missing tarball": {Tarball: "MISSING_TARBALL"},
+		"tarball outside": {Tarball: "OUTSIDE_TARBALL"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("MISSING_TARBALL", "out/none.tgz")
+			t.Setenv("OUTSIDE_TARBALL", "../pkg.tgz")
+			if _, err := applyPluginInputs(&Config{Inputs: in}, plugin.ReleaseContext{}); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestPublishInputTarball(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{"id":"pkg@1.0.0","name":"pkg","version":"1.0.0"}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "build", "package.json"), `{"name":"pkg","version":"1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "build", "pkg-1.0.0.tgz"), "tgz")
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:   plugin.HookPostPublish,
+		Config: map[string]any{"inputs": map[string]any{"package_dir": "BUILD_DIR", "tarball": "BUILD_TARBALL"}},
+		Context: plugin.ReleaseContext{
+			Version:     "1.0.0",
+			Environment: map[string]string{"BUILD_DIR": "build", "BUILD_TARBALL": "build/pkg-1.0.0.tgz"},
+		},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	tarball := filepath.Join(dir, "build", "pkg-1.0.0.tgz")
+	calls := npmCalls(t, logPath)
+	if len(calls) != 1 || !strings.HasPrefix(calls[0], "publish "+tarball+" --json") {
+		t.Errorf("npm calls = %v", calls)
+	}
+	inputs, _ := resp.Outputs["inputs"].(map[string]string)
+	if inputs["package_dir"] != "build" || resp.Outputs["tarball"] != tarball {
+		t.Errorf("outputs = %v", resp.Outputs)
+	}
+	if _, err := os.Stat(tarball); err != nil {
+		t.Errorf("tarball removed: %v", err)
+	}
+}
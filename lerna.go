@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// lernaIndependent is lerna.json's version in independent mode, where every
+// package carries its own version.
+const lernaIndependent = "independent"
+
+// lernaDefaultPackages are the package globs lerna uses when neither
+// lerna.json nor package.json lists any.
+var lernaDefaultPackages = []string{"packages/*"}
+
+// lernaVersionRegexp matches the version field of lerna.json, which is
+// rewritten in place to keep the file's formatting.
+var lernaVersionRegexp = regexp.MustCompile(`("version"\s*:\s*)"[^"]*"`)
+
+// lernaConfig is the subset of lerna.json the lerna mode reads.
+type lernaConfig struct {
+	Version  string   `json:"version"`
+	Packages []string `json:"packages"`
+	Command  struct {
+		Publish struct {
+			Registry string `json:"registry"`
+			DistTag  string `json:"distTag"`
+		} `json:"publish"`
+	} `json:"command"`
+}
+
+// independent reports whether packages are versioned independently.
+func (l *lernaConfig) independent() bool {
+	return l.Version == lernaIndependent
+}
+
+// readLernaConfig reads lerna.json from the working directory.
+func readLernaConfig() (*lernaConfig, error) {
+	data, err := os.ReadFile("lerna.json")
+	if err != nil {
+		return nil, fmt.Errorf("lerna mode requires lerna.json: %w", err)
+	}
+	var l lernaConfig
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("failed to parse lerna.json: %w", err)
+	}
+	if l.Version == "" {
+		return nil, fmt.Errorf("lerna.json has no version")
+	}
+	return &l, nil
+}
+
+// rootWorkspaces returns the workspace globs of the root package.json, in
+// either the array or the {"packages": [...]} form.
+func rootWorkspaces() []string {
+	data, err := os.ReadFile("package.json")
+	if err != nil {
+		return nil
+	}
+	var pkg struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if json.Unmarshal(data, &pkg) != nil || len(pkg.Workspaces) == 0 {
+		return nil
+	}
+	var globs []string
+	if json.Unmarshal(pkg.Workspaces, &globs) == nil {
+		return globs
+	}
+	var obj struct {
+		Packages []string `json:"packages"`
+	}
+	_ = json.Unmarshal(pkg.Workspaces, &obj)
+	return obj.Packages
+}
+
+// applyLernaConfig carries the lerna.json conventions over to cfg: its
+// packages (falling back to the root workspaces) become the workspaces, and
+// its publish registry and dist-tag apply unless configured explicitly.
+func applyLernaConfig(cfg *Config) error {
+	l, err := readLernaConfig()
+	if err != nil {
+		return err
+	}
+	cfg.lerna = l
+	if len(cfg.Workspaces) == 0 {
+		cfg.Workspaces = l.Packages
+		if len(cfg.Workspaces) == 0 {
+			cfg.Workspaces = rootWorkspaces()
+		}
+		if len(cfg.Workspaces) == 0 {
+			cfg.Workspaces = lernaDefaultPackages
+		}
+	}
+	if cfg.Registry == "" {
+		cfg.Registry = l.Command.Publish.Registry
+	}
+	if cfg.Tag == "latest" && l.Command.Publish.DistTag != "" {
+		cfg.Tag = l.Command.Publish.DistTag
+	}
+	return nil
+}
+
+// writeLernaVersion sets the fixed version in lerna.json.
+func writeLernaVersion(version string) error {
+	data, err := os.ReadFile("lerna.json")
+	if err != nil {
+		return fmt.Errorf("failed to read lerna.json: %w", err)
+	}
+	loc := lernaVersionRegexp.FindSubmatchIndex(data)
+	if loc == nil {
+		return fmt.Errorf("lerna.json has no version")
+	}
+	updated := append(append(append([]byte{}, data[:loc[3]]...), fmt.Sprintf("%q", version)...), data[loc[1]:]...)
+	if err := os.WriteFile("lerna.json", updated, 0644); err != nil {
+		return fmt.Errorf("failed to write lerna.json: %w", err)
+	}
+	return nil
+}
+
+// publishedVersion reports whether name@version is already in the registry,
+// which `lerna publish from-package` skips.
+func publishedVersion(ctx context.Context, cfg *Config, name, version string) (bool, error) {
+	doc, err := fetchPackument(ctx, registryURL(cfg), name)
+	if errors.Is(err, errPackageNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	_, ok := doc.Versions[version]
+	return ok, nil
+}
+
+// lernaPrePublish runs pre-publish for every lerna package. In fixed mode the
+// release version is applied to the packages and lerna.json; in independent
+// mode each package keeps the version it has, so no version is written.
+func (p *NpmPlugin) lernaPrePublish(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
+	mode := "fixed"
+	if cfg.lerna.independent() {
+		mode = lernaIndependent
+		cfg.UpdateVersion = false
+	}
+	resp, err := p.runWorkspaces(ctx, cfg, releaseCtx, dryRun, workspaceScope{}, p.prePublish)
+	if err != nil || !resp.Success {
+		return resp, err
+	}
+	if cfg.UpdateVersion && !dryRun {
+		if err := writeLernaVersion(releaseCtx.Version); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+	}
+	setOutput(resp, "lerna_mode", mode)
+	return resp, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestApplyLernaConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		cfg        Config
+		workspaces []string
+		registry   string
+		tag        string
+		wantErr    bool
+	}{
+		{
+			name:       "lerna packages and publish config",
+			files:      map[string]string{"lerna.json": `{"version":"1.0.0","packages":["libs/*"],"command":{"publish":{"registry":"https://npm.example.com","distTag":"next"}}}`},
+			cfg:        Config{Tag: "latest"},
+			workspaces: []string{"libs/*"},
+			registry:   "https://npm.example.com",
+			tag:        "next",
+		},
+		{
+			name: "root workspaces object",
+			files: map[string]string{
+				"lerna.json":   `{"version":"independent"}`,
+				"package.json": `{"workspaces":{"packages":["modules/*"]}}`,
+			},
+			cfg:        Config{Tag: "latest"},
+			workspaces: []string{"modules/*"},
+			tag:        "latest",
+		},
+		{
+			name:       "explicit config wins",
+			files:      map[string]string{"lerna.json": `{"version":"1.0.0","command":{"publish":{"registry":"https://npm.example.com","distTag":"next"}}}`},
+			cfg:        Config{Tag: "beta", Registry: "https://other.example.com", Workspaces: []string{"pkgs/*"}},
+			workspaces: []string{"pkgs/*"},
+			registry:   "https://other.example.com",
+			tag:        "beta",
+		},
+		{
+			name:       "default packages",
+			files:      map[string]string{"lerna.json": `{"version":"1.0.0"}`},
+			cfg:        Config{Tag: "latest"},
+			workspaces: []string{"packages/*"},
+			tag:        "latest",
+		},
+		{name: "missing lerna.json", wantErr: true},
+		{name: "no version", files: map[string]string{"lerna.json": `{}`}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+			chdir(t, dir)
+			cfg := tt.cfg
+			err := applyLernaConfig(&cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyLernaConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(cfg.Workspaces, tt.workspaces) || cfg.Registry != tt.registry || cfg.Tag != tt.tag {
+				t.Errorf("cfg = workspaces %v, registry %q, tag %q", cfg.Workspaces, cfg.Registry, cfg.Tag)
+			}
+		})
+	}
+}
+
+func TestLernaPrePublishFixed(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "lerna.json"), "{\n  \"$schema\": \"node_modules/lerna/schemas/lerna-schema.json\",\n  \"version\": \"1.0.0\"\n}\n")
+	writeFile(t, filepath.Join(dir, "packages", "core", "package.json"), `{"name":"core","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPrePublish,
+		Config:  map[string]any{"lerna": true},
+		Context: plugin.ReleaseContext{Version: "1.1.0"},
+	})
+	if err != nil || !resp.Success || resp.Outputs["lerna_mode"] != "fixed" {
+		t.Fatalf("unexpected response: %v %+v", err, resp)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "lerna.json"))
+	if want := "{\n  \"$schema\": \"node_modules/lerna/schemas/lerna-schema.json\",\n  \"version\": \"1.1.0\"\n}\n"; string(data) != want {
+		t.Errorf("lerna.json = %s", data)
+	}
+	if pkg, _ := readPackageJSON(filepath.Join(dir, "packages", "core")); pkg.Version != "1.1.0" {
+		t.Errorf("package version = %s", pkg.Version)
+	}
+}
+
+func TestLernaFromPackage(t *testing.T) {
+	logPath := fakeNpm(t, `[ "$1" = publish ] && basename "$PWD" >> "$(dirname "$0")/published.log"
+echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	server := newTestRegistry(t, map[string]*packument{
+		"core": {Versions: map[string]packumentVersion{"1.0.0": {}}},
+	})
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "lerna.json"), `{"version":"independent","command":{"publish":{"registry":"`+server.URL+`"}}}`)
+	writeFile(t, filepath.Join(dir, "packages", "core", "package.json"), `{"name":"core","version":"1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "packages", "ui", "package.json"), `{"name":"ui","version":"2.3.0","dependencies":{"core":"^1.0.0"}}`)
+	writeFile(t, filepath.Join(dir, "packages", "site", "package.json"), `{"name":"site","version":"0.0.1","private":true}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"lerna": true},
+		Context: plugin.ReleaseContext{Version: "9.9.9"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	published := npmCalls(t, filepath.Join(filepath.Dir(logPath), "published.log"))
+	if !reflect.DeepEqual(published, []string{"ui"}) {
+		t.Errorf("published = %v, want only ui", published)
+	}
+	results := resp.Outputs["packages"].([]map[string]any)
+	reasons := map[string]any{}
+	for _, r := range results {
+		reasons[r["name"].(string)] = r["skip_reason"]
+		if r["name"] == "ui" && r["outputs"].(map[string]any)["version"] != "2.3.0" {
+			t.Errorf("ui published at %v, want its package.json version", r["outputs"].(map[string]any)["version"])
+		}
+	}
+	if reasons["core"] != "already_published" || reasons["site"] != "private" {
+		t.Errorf("skip reasons = %v", reasons)
+	}
+}
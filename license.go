@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// License check modes.
+const (
+	licenseCheck = "check"
+	licenseFix   = "fix"
+)
+
+// spdxLicenseIDs are the SPDX license identifiers accepted without an
+// allowlist: the licenses npm packages commonly use. Others can be allowed
+// through allowed_licenses.
+var spdxLicenseIDs = []string{
+	"0BSD", "AFL-3.0", "AGPL-3.0-only", "AGPL-3.0-or-later", "Apache-1.1",
+	"Apache-2.0", "Artistic-1.0", "Artistic-2.0", "BlueOak-1.0.0",
+	"BSD-1-Clause", "BSD-2-Clause", "BSD-2-Clause-Patent", "BSD-3-Clause",
+	"BSD-3-Clause-Clear", "BSD-4-Clause", "BSL-1.0", "BUSL-1.1", "CC-BY-3.0",
+	"CC-BY-4.0", "CC-BY-SA-4.0", "CC-BY-NC-4.0", "CC-BY-NC-SA-4.0", "CC0-1.0",
+	"CDDL-1.0", "CDDL-1.1", "CECILL-2.1", "ECL-2.0", "EPL-1.0", "EPL-2.0",
+	"EUPL-1.1", "EUPL-1.2", "GPL-2.0", "GPL-2.0-only", "GPL-2.0-or-later",
+	"GPL-3.0", "GPL-3.0-only", "GPL-3.0-or-later", "Hippocratic-2.1", "ISC",
+	"LGPL-2.0-only", "LGPL-2.0-or-later", "LGPL-2.1", "LGPL-2.1-only",
+	"LGPL-2.1-or-later", "LGPL-3.0", "LGPL-3.0-only", "LGPL-3.0-or-later",
+	"MIT", "MIT-0", "MPL-1.1", "MPL-2.0", "MPL-2.0-no-copyleft-exception",
+	"MS-PL", "MS-RL", "MulanPSL-2.0", "NCSA", "ODbL-1.0", "OFL-1.1",
+	"OSL-3.0", "PostgreSQL", "Python-2.0", "Unicode-DFS-2016", "Unlicense",
+	"UPL-1.0", "W3C", "WTFPL", "X11", "Zlib", "ZPL-2.1",
+}
+
+// spdxExceptionIDs are the SPDX exceptions accepted after WITH.
+var spdxExceptionIDs = []string{
+	"Classpath-exception-2.0", "GCC-exception-3.1", "LLVM-exception",
+	"OpenJDK-assembly-exception-1.0", "Autoconf-exception-3.0",
+	"Bison-exception-2.2", "Font-exception-2.0",
+}
+
+// licenseAliases map normalized spellings that are not SPDX identifiers
+// themselves to the identifier they mean.
+var licenseAliases = map[string]string{
+	"apache":    "Apache-2.0",
+	"apache2":   "Apache-2.0",
+	"asl2.0":    "Apache-2.0",
+	"mpl2":      "MPL-2.0",
+	"bsd2":      "BSD-2-Clause",
+	"bsd3":      "BSD-3-Clause",
+	"newbsd":    "BSD-3-Clause",
+	"simplebsd": "BSD-2-Clause",
+	"cc0":       "CC0-1.0",
+	"zlib":      "Zlib",
+	"wtfpl2":    "WTFPL",
+	"unlicence": "Unlicense",
+}
+
+// licenseKeyWords are dropped when normalizing a license spelling.
+var licenseKeyWords = strings.NewReplacer("license", "", "licence", "", "version", "", "the", "")
+
+// licenseKey normalizes a license spelling for lookup: lower case, without
+// filler words, spaces or punctuation other than dots.
+func licenseKey(s string) string {
+	s = licenseKeyWords.Replace(strings.ToLower(s))
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' {
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimSuffix(b.String(), ".")
+}
+
+// knownLicenses indexes the accepted identifiers by licenseKey.
+var knownLicenses = func() map[string]string {
+	m := map[string]string{}
+	for _, id := range spdxLicenseIDs {
+		m[licenseKey(id)] = id
+	}
+	for k, id := range licenseAliases {
+		m[k] = id
+	}
+	return m
+}()
+
+// licenseIDSet returns ids as a set.
+func licenseIDSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+var (
+	spdxLicenseSet   = licenseIDSet(spdxLicenseIDs)
+	spdxExceptionSet = licenseIDSet(spdxExceptionIDs)
+)
+
+// tokenizeLicense splits an SPDX expression into identifiers, operators and
+// parentheses.
+func tokenizeLicense(expr string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(expr) {
+		for field != "" {
+			i := strings.IndexAny(field, "()")
+			switch {
+			case i < 0:
+				tokens = append(tokens, field)
+				field = ""
+			case i > 0:
+				tokens = append(tokens, field[:i])
+				field = field[i:]
+			default:
+				tokens = append(tokens, field[:1])
+				field = field[1:]
+			}
+		}
+	}
+	return tokens
+}
+
+// parseLicenseExpression parses an SPDX license expression and returns the
+// license identifiers it names. "+" suffixes are stripped.
+func parseLicenseExpression(expr string) ([]string, error) {
+	tokens := tokenizeLicense(expr)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty license expression")
+	}
+	var ids []string
+	pos := 0
+	var parseExpr func() error
+	parseTerm := func() error {
+		if pos >= len(tokens) {
+			return fmt.Errorf("unexpected end of license expression")
+		}
+		tok := tokens[pos]
+		pos++
+		if tok == "(" {
+			if err := parseExpr(); err != nil {
+				return err
+			}
+			if pos >= len(tokens) || tokens[pos] != ")" {
+				return fmt.Errorf("unbalanced parentheses")
+			}
+			pos++
+			return nil
+		}
+		switch tok {
+		case ")", "AND", "OR", "WITH":
+			return fmt.Errorf("unexpected %q", tok)
+		}
+		ids = append(ids, strings.TrimSuffix(tok, "+"))
+		if pos < len(tokens) && tokens[pos] == "WITH" {
+			if pos+1 >= len(tokens) {
+				return fmt.Errorf("missing exception after WITH")
+			}
+			if !spdxExceptionSet[tokens[pos+1]] {
+				return fmt.Errorf("unknown license exception %q", tokens[pos+1])
+			}
+			pos += 2
+		}
+		return nil
+	}
+	parseExpr = func() error {
+		if err := parseTerm(); err != nil {
+			return err
+		}
+		for pos < len(tokens) && (tokens[pos] == "AND" || tokens[pos] == "OR") {
+			pos++
+			if err := parseTerm(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := parseExpr(); err != nil {
+		return nil, err
+	}
+	if pos != len(tokens) {
+		return nil, fmt.Errorf("unexpected %q", tokens[pos])
+	}
+	return ids, nil
+}
+
+// checkLicenseExpression validates expr against the SPDX identifiers, or
+// against allowed when an allowlist is configured. UNLICENSED and
+// "SEE LICENSE IN <file>" are npm's forms for proprietary licenses.
+func checkLicenseExpression(expr string, allowed []string) error {
+	if expr == "UNLICENSED" || strings.HasPrefix(expr, "SEE LICENSE IN ") {
+		if len(allowed) > 0 && !containsString(allowed, expr) && !(expr != "UNLICENSED" && containsString(allowed, "SEE LICENSE IN")) {
+			return fmt.Errorf("license %q is not allowed", expr)
+		}
+		return nil
+	}
+	ids, err := parseLicenseExpression(expr)
+	if err != nil {
+		return fmt.Errorf("license %q is not a valid SPDX expression: %w", expr, err)
+	}
+	for _, id := range ids {
+		if len(allowed) > 0 {
+			if !containsString(allowed, id) {
+				return fmt.Errorf("license %s is not allowed", id)
+			}
+			continue
+		}
+		if !spdxLicenseSet[id] && !strings.HasPrefix(id, "LicenseRef-") {
+			return fmt.Errorf("license %q is not a valid SPDX expression: unknown identifier %q", expr, id)
+		}
+	}
+	return nil
+}
+
+// normalizeLicense rewrites common misspellings of a license expression
+// ("Apache 2.0", "mit", "BSD 3-Clause License") to SPDX identifiers. It
+// returns the input unchanged when it does not recognize it.
+func normalizeLicense(expr string) string {
+	if id, ok := knownLicenses[licenseKey(expr)]; ok {
+		return id
+	}
+	tokens := tokenizeLicense(expr)
+	for i, tok := range tokens {
+		switch tok {
+		case "(", ")", "AND", "OR", "WITH":
+			continue
+		}
+		if upper := strings.ToUpper(tok); upper == "AND" || upper == "OR" || upper == "WITH" {
+			tokens[i] = upper
+			continue
+		}
+		if i > 0 && tokens[i-1] == "WITH" {
+			continue
+		}
+		if id, ok := knownLicenses[licenseKey(tok)]; ok {
+			tokens[i] = id
+		}
+	}
+	out := strings.Join(tokens, " ")
+	out = strings.ReplaceAll(strings.ReplaceAll(out, "( ", "("), " )", ")")
+	return out
+}
+
+// isPublicPackage reports whether the package is published publicly:
+// unscoped packages always are, scoped ones with access public.
+func isPublicPackage(cfg *Config, name string) bool {
+	return !strings.HasPrefix(name, "@") || cfg.Access == "public"
+}
+
+// applyLicensePolicy checks the manifest's license field. In fix mode the
+// legacy object forms and common misspellings are rewritten in manifest,
+// reporting true when it changed. A missing license fails only for public
+// packages.
+func applyLicensePolicy(manifest map[string]any, mode string, allowed []string, public bool) (bool, error) {
+	changed := false
+	license, legacy := manifest["license"], false
+	if license == nil {
+		if list, ok := manifest["licenses"].([]any); ok && len(list) == 1 {
+			license, legacy = list[0], true
+		}
+	}
+	if obj, ok := license.(map[string]any); ok {
+		license, legacy = obj["type"], true
+	}
+	expr, _ := license.(string)
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		if license != nil && !legacy {
+			return false, fmt.Errorf("license must be a string SPDX expression")
+		}
+		if public {
+			return false, fmt.Errorf("license is missing; public packages must declare one")
+		}
+		return false, nil
+	}
+	if legacy {
+		if mode != licenseFix {
+			return false, fmt.Errorf("license uses the deprecated object form; use an SPDX expression string")
+		}
+		delete(manifest, "licenses")
+		manifest["license"] = expr
+		changed = true
+	}
+	err := checkLicenseExpression(expr, allowed)
+	if err != nil && mode == licenseFix {
+		if fixed := normalizeLicense(expr); fixed != expr && checkLicenseExpression(fixed, allowed) == nil {
+			manifest["license"] = fixed
+			return true, nil
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	return changed, nil
+}
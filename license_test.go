@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestCheckLicenseExpression(t *testing.T) {
+	tests := []struct {
+		expr    string
+		allowed []string
+		wantErr bool
+	}{
+		{expr: "MIT"},
+		{expr: "(MIT OR Apache-2.0)"},
+		{expr: "GPL-2.0-or-later WITH Classpath-exception-2.0"},
+		{expr: "LGPL-2.1+ AND LicenseRef-Custom"},
+		{expr: "UNLICENSED"},
+		{expr: "SEE LICENSE IN LICENSE.txt"},
+		{expr: "Apache 2.0", wantErr: true},
+		{expr: "MIT OR", wantErr: true},
+		{expr: "(MIT", wantErr: true},
+		{expr: "MIT WITH Bogus-exception", wantErr: true},
+		{expr: "MIT OR ISC", allowed: []string{"MIT", "ISC"}},
+		{expr: "MIT OR GPL-3.0-only", allowed: []string{"MIT"}, wantErr: true},
+		{expr: "Internal-1.0", allowed: []string{"Internal-1.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			err := checkLicenseExpression(tt.expr, tt.allowed)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkLicenseExpression(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeLicense(t *testing.T) {
+	tests := map[string]string{
+		"Apache 2.0":                  "Apache-2.0",
+		"Apache License, Version 2.0": "Apache-2.0",
+		"mit":                         "MIT",
+		"BSD 3-Clause License":        "BSD-3-Clause",
+		"(mit or apache2)":            "(MIT OR Apache-2.0)",
+		"Proprietary":                 "Proprietary",
+	}
+	for in, want := range tests {
+		if got := normalizeLicense(in); got != want {
+			t.Errorf("normalizeLicense(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestApplyLicensePolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		manifest    map[string]any
+		mode        string
+		public      bool
+		wantLicense any
+		wantChanged bool
+		wantErr     string
+	}{
+		{name: "valid", manifest: map[string]any{"license": "MIT"}, mode: licenseCheck, public: true, wantLicense: "MIT"},
+		{name: "missing public", manifest: map[string]any{}, mode: licenseCheck, public: true, wantErr: "missing"},
+		{name: "missing restricted", manifest: map[string]any{}, mode: licenseCheck},
+		{name: "misspelled check", manifest: map[string]any{"license": "Apache 2.0"}, mode: licenseCheck, wantErr: "not a valid SPDX expression"},
+		{name: "misspelled fix", manifest: map[string]any{"license": "Apache 2.0"}, mode: licenseFix, wantLicense: "Apache-2.0", wantChanged: true},
+		{name: "legacy check", manifest: map[string]any{"license": map[string]any{"type": "MIT"}}, mode: licenseCheck, wantErr: "deprecated object form"},
+		{name: "legacy fix", manifest: map[string]any{"licenses": []any{map[string]any{"type": "ISC"}}}, mode: licenseFix, wantLicense: "ISC", wantChanged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed, err := applyLicensePolicy(tt.manifest, tt.mode, nil, tt.public)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != tt.wantChanged || tt.manifest["license"] != tt.wantLicense {
+				t.Errorf("changed = %v, license = %v; want %v, %v", changed, tt.manifest["license"], tt.wantChanged, tt.wantLicense)
+			}
+		})
+	}
+}
+
+func TestLicenseFixRestoresManifest(t *testing.T) {
+	manifestLog := filepath.Join(t.TempDir(), "published.json")
+	logPath := fakeNpm(t, `if [ "$1" = publish ]; then cat package.json > `+manifestLog+`; fi; echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	original := `{"name":"lib","version":"1.0.0","license":"Apache 2.0"}`
+	writeFile(t, filepath.Join(dir, "package.json"), original)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"license_check": "fix"},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success || resp.Outputs["license"] != "Apache-2.0" || resp.Outputs["license_fixed"] != true {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if len(npmCalls(t, logPath)) == 0 {
+		t.Fatal("npm was not called")
+	}
+	published, err := os.ReadFile(manifestLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(published), `"license": "Apache-2.0"`) {
+		t.Errorf("published manifest = %s", published)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "package.json")); string(got) != original {
+		t.Errorf("package.json not restored: %s", got)
+	}
+}
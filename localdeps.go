@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// dependencyGroups are the package.json fields holding dependency specs.
+var dependencyGroups = []string{"dependencies", "devDependencies", "peerDependencies", "optionalDependencies"}
+
+// localProtocols are the dependency spec prefixes that point into the
+// repository and cannot be installed from the registry.
+var localProtocols = []string{"workspace:", "file:", "link:"}
+
+// workspaceVersionLookup returns the package.json version of a workspace
+// package by name.
+type workspaceVersionLookup func(name string) (string, error)
+
+// newWorkspaceVersionLookup looks packages up in the configured workspaces,
+// or the root package.json workspaces when none are configured. The
+// packages are only loaded on first use.
+func newWorkspaceVersionLookup(cfg *Config) workspaceVersionLookup {
+	var versions map[string]string
+	var loadErr error
+	return func(name string) (string, error) {
+		if versions == nil && loadErr == nil {
+			patterns := cfg.Workspaces
+			if len(patterns) == 0 {
+				patterns = rootWorkspaces()
+			}
+			versions = map[string]string{}
+			if len(patterns) > 0 {
+				pkgs, err := loadWorkspacePackages(patterns)
+				if err != nil {
+					loadErr = err
+				}
+				for _, pkg := range pkgs {
+					versions[pkg.Name] = pkg.Version
+				}
+			}
+		}
+		if loadErr != nil {
+			return "", loadErr
+		}
+		version, ok := versions[name]
+		if !ok {
+			return "", fmt.Errorf("%s is not a workspace package", name)
+		}
+		return version, nil
+	}
+}
+
+// localPackageVersion returns the version in dir/package.json, relative to
+// packageDir.
+func localPackageVersion(packageDir, dir string) (string, error) {
+	if filepath.IsAbs(dir) || strings.HasSuffix(dir, ".tgz") || strings.HasSuffix(dir, ".tar.gz") {
+		return "", fmt.Errorf("only relative package directories can be resolved")
+	}
+	data, err := os.ReadFile(filepath.Join(packageDir, filepath.FromSlash(dir), "package.json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read package.json: %w", err)
+	}
+	var pkg struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return "", fmt.Errorf("failed to parse package.json: %w", err)
+	}
+	if pkg.Version == "" {
+		return "", fmt.Errorf("package.json has no version")
+	}
+	return pkg.Version, nil
+}
+
+// resolveLocalSpec returns the registry spec for a workspace:, file: or
+// link: dependency spec, following the pnpm and yarn conventions:
+// workspace:* becomes the exact version, workspace:^ and workspace:~ the
+// caret and tilde ranges, and workspace:<range> the range itself. file: and
+// link: paths become the exact version of the package there.
+func resolveLocalSpec(packageDir, name, spec string, lookup workspaceVersionLookup) (string, error) {
+	if rest, ok := strings.CutPrefix(spec, "workspace:"); ok {
+		switch {
+		case rest == "*" || rest == "^" || rest == "~":
+			version, err := lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimPrefix(rest, "*") + version, nil
+		case strings.HasPrefix(rest, ".") || strings.HasPrefix(rest, "/"):
+			return localPackageVersion(packageDir, rest)
+		}
+		if _, err := parseRange(rest); err != nil {
+			return "", fmt.Errorf("unsupported spec %q", spec)
+		}
+		return rest, nil
+	}
+	for _, protocol := range []string{"file:", "link:"} {
+		if rest, ok := strings.CutPrefix(spec, protocol); ok {
+			return localPackageVersion(packageDir, rest)
+		}
+	}
+	return spec, nil
+}
+
+// resolveLocalDeps rewrites the local protocol dependencies of manifest to
+// registry specs and returns the rewritten specs by dependency name.
+func resolveLocalDeps(manifest map[string]any, packageDir string, lookup workspaceVersionLookup) (map[string]string, error) {
+	resolved := map[string]string{}
+	for _, group := range dependencyGroups {
+		deps, ok := manifest[group].(map[string]any)
+		if !ok {
+			continue
+		}
+		names := make([]string, 0, len(deps))
+		for name := range deps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			spec, _ := deps[name].(string)
+			if !hasLocalProtocol(spec) {
+				continue
+			}
+			registrySpec, err := resolveLocalSpec(packageDir, name, spec, lookup)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s@%s: %w", group, name, spec, err)
+			}
+			deps[name] = registrySpec
+			resolved[name] = registrySpec
+		}
+	}
+	return resolved, nil
+}
+
+// hasLocalProtocol reports whether spec points into the repository.
+func hasLocalProtocol(spec string) bool {
+	for _, protocol := range localProtocols {
+		if strings.HasPrefix(spec, protocol) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestResolveLocalSpec(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "pkg", "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "utils", "package.json"), `{"name":"utils","version":"2.3.4"}`)
+	pkgDir := filepath.Join(dir, "pkg")
+	lookup := func(name string) (string, error) {
+		if name == "core" {
+			return "1.5.0", nil
+		}
+		return "", fmt.Errorf("%s is not a workspace package", name)
+	}
+	tests := []struct {
+		name    string
+		dep     string
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{name: "workspace star", dep: "core", spec: "workspace:*", want: "1.5.0"},
+		{name: "workspace caret", dep: "core", spec: "workspace:^", want: "^1.5.0"},
+		{name: "workspace tilde", dep: "core", spec: "workspace:~", want: "~1.5.0"},
+		{name: "workspace range", dep: "core", spec: "workspace:^1.2.0", want: "^1.2.0"},
+		{name: "workspace path", dep: "utils", spec: "workspace:../utils", want: "2.3.4"},
+		{name: "file", dep: "utils", spec: "file:../utils", want: "2.3.4"},
+		{name: "link", dep: "utils", spec: "link:../utils", want: "2.3.4"},
+		{name: "registry spec", dep: "left-pad", spec: "^1.3.0", want: "^1.3.0"},
+		{name: "unknown workspace package", dep: "other", spec: "workspace:*", wantErr: true},
+		{name: "missing directory", dep: "x", spec: "file:../missing", wantErr: true},
+		{name: "tarball", dep: "x", spec: "file:../x-1.0.0.tgz", wantErr: true},
+		{name: "absolute path", dep: "x", spec: "file:/srv/x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveLocalSpec(pkgDir, tt.dep, tt.spec, lookup)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveLocalSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveLocalSpec() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspacesResolveLocalDeps(t *testing.T) {
+	logPath := fakeNpm(t, `[ "$1" = publish ] && cp package.json "$(dirname "$0")/$(basename "$PWD").json"
+echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	chdir(t, dir)
+	app := `{"name":"app","version":"1.2.0","dependencies":{"core":"workspace:^","left-pad":"^1.3.0"},"devDependencies":{"tools":"file:../tools"}}`
+	writeFile(t, filepath.Join(dir, "packages", "app", "package.json"), app)
+	writeFile(t, filepath.Join(dir, "packages", "core", "package.json"), `{"name":"core","version":"1.2.0"}`)
+	writeFile(t, filepath.Join(dir, "packages", "tools", "package.json"), `{"name":"tools","version":"0.4.0","private":true}`)
+	server := newTestRegistry(t, map[string]*packument{})
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"workspaces": []any{"packages/*"}, "registry": server.URL},
+		Context: plugin.ReleaseContext{Version: "1.2.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(logPath), "app.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var published struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(data, &published); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"core": "^1.2.0", "left-pad": "^1.3.0"}; !reflect.DeepEqual(published.Dependencies, want) {
+		t.Errorf("published dependencies = %v, want %v", published.Dependencies, want)
+	}
+	if published.DevDependencies["tools"] != "0.4.0" {
+		t.Errorf("published devDependencies = %v", published.DevDependencies)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "packages", "app", "package.json")); string(got) != app {
+		t.Errorf("package.json not restored: %s", got)
+	}
+}
+
+func TestResolveLocalDepsDisabled(t *testing.T) {
+	manifestLog := filepath.Join(t.TempDir(), "published.json")
+	fakeNpm(t, `if [ "$1" = publish ]; then cat package.json > `+manifestLog+`; fi; echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	original := `{"name":"lib","version":"1.0.0","dependencies":{"core":"workspace:*"}}`
+	writeFile(t, filepath.Join(dir, "package.json"), original)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"resolve_local_deps": false},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if got, _ := os.ReadFile(manifestLog); string(got) != original {
+		t.Errorf("published manifest = %s", got)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultLockTag is the prefix of the dist-tags used as release lock tickets.
+const defaultLockTag = "releasing"
+
+// defaultLockTTL is how long a lock ticket is honored, in seconds, before it
+// is treated as left behind by a crashed pipeline and removed.
+const defaultLockTTL = 3600
+
+// lockPollInterval is how often a held lock is re-checked while waiting.
+var lockPollInterval = 5 * time.Second
+
+// releaseLock is a held registry lock. Each pipeline adds its own ticket
+// dist-tag, "<lock_tag>-<unix seconds>-<random hex>", and holds the lock
+// while its ticket is the only live one. A dist-tag must point at an existing
+// version, so tickets are attached to the current latest version; their
+// presence, not their target, is what marks a release in progress.
+type releaseLock struct {
+	name   string
+	ticket string
+	dir    string
+	cfg    *Config
+}
+
+// lockTicket is a ticket dist-tag found on the package.
+type lockTicket struct {
+	tag     string
+	created time.Time
+}
+
+// lockTag returns the prefix of the ticket dist-tags used as the release lock.
+func lockTag(cfg *Config) string {
+	if cfg.LockTag != "" {
+		return cfg.LockTag
+	}
+	return defaultLockTag
+}
+
+// lockTTL returns how long a ticket is honored before it is considered stale.
+func lockTTL(cfg *Config) time.Duration {
+	if cfg.LockTTL > 0 {
+		return time.Duration(cfg.LockTTL) * time.Second
+	}
+	return defaultLockTTL * time.Second
+}
+
+// newLockTicket returns a ticket tag unique to this acquirer.
+func newLockTicket(tag string, now time.Time) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lock ticket: %w", err)
+	}
+	return tag + "-" + strconv.FormatInt(now.Unix(), 10) + "-" + hex.EncodeToString(b), nil
+}
+
+// lockTickets returns the ticket dist-tags for tag on doc, oldest first.
+func lockTickets(doc *packument, tag string) []lockTicket {
+	var tickets []lockTicket
+	for t := range doc.DistTags {
+		rest, ok := strings.CutPrefix(t, tag+"-")
+		if !ok {
+			continue
+		}
+		secs, id, ok := strings.Cut(rest, "-")
+		if !ok || id == "" {
+			continue
+		}
+		unix, err := strconv.ParseInt(secs, 10, 64)
+		if err != nil {
+			continue
+		}
+		tickets = append(tickets, lockTicket{tag: t, created: time.Unix(unix, 0)})
+	}
+	sort.Slice(tickets, func(i, j int) bool {
+		if !tickets[i].created.Equal(tickets[j].created) {
+			return tickets[i].created.Before(tickets[j].created)
+		}
+		return tickets[i].tag < tickets[j].tag
+	})
+	return tickets
+}
+
+// liveTickets drops the tickets older than ttl.
+func liveTickets(tickets []lockTicket, now time.Time, ttl time.Duration) []lockTicket {
+	var live []lockTicket
+	for _, t := range tickets {
+		if now.Sub(t.created) < ttl {
+			live = append(live, t)
+		}
+	}
+	return live
+}
+
+// acquireReleaseLock adds a ticket and re-reads the dist-tags: the lock is
+// ours only when our ticket is the sole live one. Two pipelines adding
+// tickets at once both see each other's and back off, retrying after a
+// jittered poll interval until cfg.LockTimeout seconds have passed. Tickets
+// older than lock_ttl are removed along the way. It returns nil without
+// error when the package has never been published, since dist-tags need a
+// version.
+func acquireReleaseLock(ctx context.Context, cfg *Config, dir, name string) (*releaseLock, error) {
+	tag := lockTag(cfg)
+	ttl := lockTTL(cfg)
+	deadline := time.Now().Add(time.Duration(cfg.LockTimeout) * time.Second)
+
+	for {
+		doc, err := fetchPackument(ctx, registryURL(cfg), name)
+		if errors.Is(err, errPackageNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check release lock: %w", err)
+		}
+
+		now := time.Now()
+		tickets := lockTickets(doc, tag)
+		live := liveTickets(tickets, now, ttl)
+		if len(live) < len(tickets) {
+			removeStaleTickets(ctx, cfg, dir, name, tickets, now, ttl)
+		}
+
+		if len(live) == 0 {
+			target := doc.DistTags["latest"]
+			if target == "" {
+				for v := range doc.Versions {
+					target = v
+					break
+				}
+			}
+			if target == "" {
+				return nil, nil
+			}
+
+			ticket, err := newLockTicket(tag, now)
+			if err != nil {
+				return nil, err
+			}
+			args := append([]string{"dist-tag", "add", name + "@" + target, ticket}, registryArgs(cfg)...)
+			if _, err := runNpm(ctx, dir, args...); err != nil {
+				return nil, fmt.Errorf("failed to acquire release lock: %w", err)
+			}
+			lock := &releaseLock{name: name, ticket: ticket, dir: dir, cfg: cfg}
+
+			doc, err := fetchPackument(ctx, registryURL(cfg), name)
+			if err != nil {
+				_ = lock.withdraw(ctx)
+				return nil, fmt.Errorf("failed to confirm release lock: %w", err)
+			}
+			live = liveTickets(lockTickets(doc, tag), time.Now(), ttl)
+			if len(live) == 1 && live[0].tag == ticket {
+				return lock, nil
+			}
+			if err := lock.withdraw(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("release in progress: %s is locked by another pipeline (dist-tag %q)", name, otherTicket(live, tag))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval + time.Duration(mathrand.Int63n(int64(lockPollInterval)/2+1))):
+		}
+	}
+}
+
+// otherTicket names a live ticket for the "release in progress" error.
+func otherTicket(live []lockTicket, tag string) string {
+	if len(live) == 0 {
+		return tag
+	}
+	return live[0].tag
+}
+
+// removeStaleTickets removes the tickets older than ttl. It is best effort:
+// another pipeline may be removing the same ones.
+func removeStaleTickets(ctx context.Context, cfg *Config, dir, name string, tickets []lockTicket, now time.Time, ttl time.Duration) {
+	for _, t := range tickets {
+		if now.Sub(t.created) >= ttl {
+			args := append([]string{"dist-tag", "rm", name, t.tag}, registryArgs(cfg)...)
+			_, _ = runNpm(ctx, dir, args...)
+		}
+	}
+}
+
+// withdraw removes our ticket, even when ctx has been canceled.
+func (l *releaseLock) withdraw(ctx context.Context) error {
+	args := append([]string{"dist-tag", "rm", l.name, l.ticket}, registryArgs(l.cfg)...)
+	if _, err := runNpm(context.WithoutCancel(ctx), l.dir, args...); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// release removes our ticket if it is still on the package; a ticket that
+// expired and was cleared by another pipeline is left alone. It runs even
+// when ctx has been canceled, so an aborted release does not keep the lock
+// until it goes stale.
+func (l *releaseLock) release(ctx context.Context) error {
+	ctx = context.WithoutCancel(ctx)
+	doc, err := fetchPackument(ctx, registryURL(l.cfg), l.name)
+	if err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	if _, ok := doc.DistTags[l.ticket]; !ok {
+		return nil
+	}
+	return l.withdraw(ctx)
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTagRegistry serves name@1.0.0 with the dist-tags stored as files in the
+// returned directory, and puts an npm on PATH whose dist-tag add and rm edit
+// them, so lock tickets round-trip through the registry. It returns the
+// server, the tag directory and the npm log path.
+func newTagRegistry(t *testing.T, name string, tags map[string]string) (*httptest.Server, string, string) {
+	t.Helper()
+	tagDir := t.TempDir()
+	for tag, version := range tags {
+		writeFile(t, filepath.Join(tagDir, tag), version)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimPrefix(r.URL.Path, "/") != name {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		doc := packument{Name: name, DistTags: map[string]string{}, Versions: map[string]packumentVersion{"1.0.0": {}}}
+		entries, _ := os.ReadDir(tagDir)
+		for _, e := range entries {
+			if version, err := os.ReadFile(filepath.Join(tagDir, e.Name())); err == nil {
+				doc.DistTags[e.Name()] = strings.TrimSpace(string(version))
+			}
+		}
+		_ = json.NewEncoder(w).Encode(doc)
+	}))
+	t.Cleanup(server.Close)
+	logPath := fakeNpm(t, `if [ "$1" = dist-tag ]; then
+  case "$2" in
+    add) echo "${3##*@}" > `+tagDir+`/"$4" ;;
+    rm) rm -f `+tagDir+`/"$4" ;;
+  esac
+fi`)
+	return server, tagDir, logPath
+}
+
+// ticketAt returns a lock ticket created at the given time.
+func ticketAt(at time.Time, id string) string {
+	return "releasing-" + strconv.FormatInt(at.Unix(), 10) + "-" + id
+}
+
+func TestAcquireReleaseLock(t *testing.T) {
+	ctx := context.Background()
+	orig := lockPollInterval
+	lockPollInterval = 10 * time.Millisecond
+	defer func() { lockPollInterval = orig }()
+
+	t.Run("acquire_and_release", func(t *testing.T) {
+		server, tagDir, logPath := newTagRegistry(t, "free", map[string]string{"latest": "1.0.0"})
+		cfg := &Config{Registry: server.URL, Lock: true}
+		lock, err := acquireReleaseLock(ctx, cfg, t.TempDir(), "free")
+		if err != nil {
+			t.Fatalf("acquireReleaseLock returned error: %v", err)
+		}
+		if lock == nil {
+			t.Fatal("expected lock to be acquired")
+		}
+		if _, err := os.Stat(filepath.Join(tagDir, lock.ticket)); err != nil {
+			t.Errorf("ticket %q was not added: %v", lock.ticket, err)
+		}
+		if err := lock.release(ctx); err != nil {
+			t.Fatalf("release returned error: %v", err)
+		}
+
+		calls := npmCalls(t, logPath)
+		want := []*regexp.Regexp{
+			regexp.MustCompile(`^dist-tag add free@1\.0\.0 releasing-[0-9]+-[0-9a-f]{8} --registry ` + regexp.QuoteMeta(server.URL) + `$`),
+			regexp.MustCompile(`^dist-tag rm free releasing-[0-9]+-[0-9a-f]{8} --registry ` + regexp.QuoteMeta(server.URL) + `$`),
+		}
+		if len(calls) != len(want) {
+			t.Fatalf("calls = %v, want an add and a rm", calls)
+		}
+		for i, re := range want {
+			if !re.MatchString(calls[i]) {
+				t.Errorf("call %d = %q, want %s", i, calls[i], re)
+			}
+		}
+		if entries, _ := os.ReadDir(tagDir); len(entries) != 1 {
+			t.Errorf("dist-tags left behind: %v", entries)
+		}
+	})
+
+	t.Run("held_fails_fast", func(t *testing.T) {
+		held := ticketAt(time.Now(), "0badc0de")
+		server, _, logPath := newTagRegistry(t, "held", map[string]string{"latest": "1.0.0", held: "1.0.0"})
+		_, err := acquireReleaseLock(ctx, &Config{Registry: server.URL, Lock: true}, t.TempDir(), "held")
+		if err == nil || !strings.Contains(err.Error(), "release in progress") || !strings.Contains(err.Error(), held) {
+			t.Errorf("expected release in progress error naming %s, got %v", held, err)
+		}
+		if calls := npmCalls(t, logPath); len(calls) != 0 {
+			t.Errorf("expected no npm calls, got %v", calls)
+		}
+	})
+
+	t.Run("held_waits_until_timeout", func(t *testing.T) {
+		server, _, _ := newTagRegistry(t, "held", map[string]string{"latest": "1.0.0", ticketAt(time.Now(), "0badc0de"): "1.0.0"})
+		waitCfg := &Config{Registry: server.URL, Lock: true, LockTimeout: 1}
+		start := time.Now()
+		_, err := acquireReleaseLock(ctx, waitCfg, t.TempDir(), "held")
+		if err == nil {
+			t.Fatal("expected error for held lock")
+		}
+		if time.Since(start) < time.Second {
+			t.Errorf("expected to wait for lock_timeout, returned after %v", time.Since(start))
+		}
+	})
+
+	t.Run("stale_ticket_expires", func(t *testing.T) {
+		stale := ticketAt(time.Now().Add(-2*time.Hour), "0badc0de")
+		server, tagDir, _ := newTagRegistry(t, "crashed", map[string]string{"latest": "1.0.0", stale: "1.0.0"})
+		lock, err := acquireReleaseLock(ctx, &Config{Registry: server.URL, Lock: true}, t.TempDir(), "crashed")
+		if err != nil || lock == nil {
+			t.Fatalf("acquireReleaseLock() = %v, %v; want the stale ticket ignored", lock, err)
+		}
+		if _, err := os.Stat(filepath.Join(tagDir, stale)); !os.IsNotExist(err) {
+			t.Errorf("stale ticket %s was not removed", stale)
+		}
+
+		short := ticketAt(time.Now().Add(-2*time.Minute), "0badc0de")
+		server, _, _ = newTagRegistry(t, "short", map[string]string{"latest": "1.0.0", short: "1.0.0"})
+		if lock, err := acquireReleaseLock(ctx, &Config{Registry: server.URL, Lock: true, LockTTL: 60}, t.TempDir(), "short"); err != nil || lock == nil {
+			t.Errorf("acquireReleaseLock() = %v, %v; want lock_ttl honored", lock, err)
+		}
+	})
+
+	t.Run("release_checks_owner", func(t *testing.T) {
+		server, tagDir, logPath := newTagRegistry(t, "taken", map[string]string{"latest": "1.0.0"})
+		cfg := &Config{Registry: server.URL, Lock: true}
+		lock, err := acquireReleaseLock(ctx, cfg, t.TempDir(), "taken")
+		if err != nil || lock == nil {
+			t.Fatalf("acquireReleaseLock() = %v, %v", lock, err)
+		}
+		// Another pipeline expired our ticket and took the lock.
+		_ = os.Remove(filepath.Join(tagDir, lock.ticket))
+		other := ticketAt(time.Now(), "0badc0de")
+		writeFile(t, filepath.Join(tagDir, other), "1.0.0")
+
+		if err := lock.release(ctx); err != nil {
+			t.Fatalf("release returned error: %v", err)
+		}
+		for _, call := range npmCalls(t, logPath) {
+			if strings.HasPrefix(call, "dist-tag rm") {
+				t.Errorf("release removed a ticket it no longer owns: %s", call)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(tagDir, other)); err != nil {
+			t.Errorf("other pipeline's ticket was removed: %v", err)
+		}
+	})
+
+	t.Run("release_after_cancel", func(t *testing.T) {
+		server, tagDir, _ := newTagRegistry(t, "canceled", map[string]string{"latest": "1.0.0"})
+		cancelCtx, cancel := context.WithCancel(ctx)
+		lock, err := acquireReleaseLock(cancelCtx, &Config{Registry: server.URL, Lock: true}, t.TempDir(), "canceled")
+		if err != nil || lock == nil {
+			t.Fatalf("acquireReleaseLock() = %v, %v", lock, err)
+		}
+		cancel()
+		if err := lock.release(cancelCtx); err != nil {
+			t.Fatalf("release returned error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(tagDir, lock.ticket)); !os.IsNotExist(err) {
+			t.Errorf("ticket %s survived a canceled release", lock.ticket)
+		}
+	})
+
+	t.Run("concurrent_acquirers", func(t *testing.T) {
+		server, tagDir, _ := newTagRegistry(t, "contended", map[string]string{"latest": "1.0.0"})
+		cfg := &Config{Registry: server.URL, Lock: true, LockTimeout: 30}
+		var holders, maxHolders atomic.Int32
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				lock, err := acquireReleaseLock(ctx, cfg, t.TempDir(), "contended")
+				if err != nil || lock == nil {
+					errs[i] = fmt.Errorf("acquireReleaseLock() = %v, %v", lock, err)
+					return
+				}
+				n := holders.Add(1)
+				for {
+					m := maxHolders.Load()
+					if n <= m || maxHolders.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(50 * time.Millisecond)
+				holders.Add(-1)
+				errs[i] = lock.release(ctx)
+			}(i)
+		}
+		wg.Wait()
+		for i, err := range errs {
+			if err != nil {
+				t.Errorf("acquirer %d: %v", i, err)
+			}
+		}
+		if maxHolders.Load() != 1 {
+			t.Errorf("%d pipelines held the lock at once, want 1", maxHolders.Load())
+		}
+		if entries, _ := os.ReadDir(tagDir); len(entries) != 1 {
+			t.Errorf("dist-tags left behind: %v", entries)
+		}
+	})
+
+	t.Run("unpublished_package_skips_lock", func(t *testing.T) {
+		server, _, _ := newTagRegistry(t, "free", nil)
+		lock, err := acquireReleaseLock(ctx, &Config{Registry: server.URL, Lock: true}, t.TempDir(), "new-package")
+		if err != nil || lock != nil {
+			t.Errorf("expected no lock and no error, got %v, %v", lock, err)
+		}
+	})
+}
+
+func TestLockTickets(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	doc := &packument{DistTags: map[string]string{
+		"latest":                            "1.0.0",
+		"releasing":                         "1.0.0",
+		"releasing-next":                    "1.0.0",
+		ticketAt(now, "bb"):                 "1.0.0",
+		ticketAt(now.Add(-time.Hour), "aa"): "1.0.0",
+		"other-1700000000-cc":               "1.0.0",
+	}}
+	tickets := lockTickets(doc, "releasing")
+	if len(tickets) != 2 || tickets[0].tag != ticketAt(now.Add(-time.Hour), "aa") || tickets[1].tag != ticketAt(now, "bb") {
+		t.Fatalf("lockTickets() = %+v, want the two tickets oldest first", tickets)
+	}
+	if live := liveTickets(tickets, now, 30*time.Minute); len(live) != 1 || live[0].tag != ticketAt(now, "bb") {
+		t.Errorf("liveTickets() = %+v, want the recent ticket", live)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// lockfiles are the npm lockfiles whose version fields mirror package.json.
+var lockfiles = []string{"package-lock.json", "npm-shrinkwrap.json"}
+
+// lockfileVersionPaths are the fields holding the root package's version:
+// the top-level version and, from lockfileVersion 2, packages[""].version.
+var lockfileVersionPaths = [][]string{
+	{"version"},
+	{"packages", "", "version"},
+}
+
+// updateLockfileVersions sets the root package's version in the lockfiles
+// present in packageDir, so the committed lockfile does not drift from the
+// manifest. It returns the lockfiles that changed; dry runs only report them.
+func updateLockfileVersions(packageDir, version string, dryRun bool) ([]string, error) {
+	var updated []string
+	for _, name := range lockfiles {
+		path := filepath.Join(packageDir, name)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return updated, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		changed := false
+		for _, field := range lockfileVersionPaths {
+			next, ok, err := replaceJSONString(data, field, version)
+			if err != nil {
+				return updated, fmt.Errorf("failed to parse %s: %w", name, err)
+			}
+			if ok && !bytes.Equal(next, data) {
+				data, changed = next, true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if !dryRun {
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				return updated, fmt.Errorf("failed to write %s: %w", name, err)
+			}
+		}
+		updated = append(updated, name)
+	}
+	return updated, nil
+}
+
+// jsonFrame is an open object or array while walking a JSON document.
+type jsonFrame struct {
+	object    bool
+	expectKey bool
+	key       string
+}
+
+// replaceJSONString replaces the string at path, a list of object keys, in
+// data, leaving every other byte of the document as it was; re-marshalling
+// would reorder and reformat the whole file. It reports false when path
+// does not hold a string.
+func replaceJSONString(data []byte, path []string, value string) ([]byte, bool, error) {
+	if !json.Valid(data) {
+		return nil, false, fmt.Errorf("invalid JSON")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var stack []jsonFrame
+	for {
+		before := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return data, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if top.object && top.expectKey {
+				if key, ok := tok.(string); ok {
+					top.key, top.expectKey = key, false
+					continue
+				}
+			}
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, jsonFrame{object: true, expectKey: true})
+			continue
+		case json.Delim('['):
+			stack = append(stack, jsonFrame{})
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		default:
+			if s, ok := tok.(string); ok && atJSONPath(stack, path) {
+				start := before + int64(bytes.IndexByte(data[before:], '"'))
+				end := dec.InputOffset()
+				if s == value {
+					return data, true, nil
+				}
+				encoded, _ := json.Marshal(value)
+				out := append(append(append([]byte{}, data[:start]...), encoded...), data[end:]...)
+				return out, true, nil
+			}
+		}
+		// A value, scalar or container, is complete: the enclosing object
+		// expects its next key
+		if len(stack) > 0 && stack[len(stack)-1].object {
+			stack[len(stack)-1].expectKey = true
+		}
+	}
+}
+
+// atJSONPath reports whether the keys of the open objects are path.
+func atJSONPath(stack []jsonFrame, path []string) bool {
+	if len(stack) != len(path) {
+		return false
+	}
+	for i, frame := range stack {
+		if !frame.object || frame.key != path[i] {
+			return false
+		}
+	}
+	return true
+}
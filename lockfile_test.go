@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestReplaceJSONString(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		path   []string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "top level",
+			data:   `{"name": "lib", "version": "1.0.0", "lockfileVersion": 3}`,
+			path:   []string{"version"},
+			want:   `{"name": "lib", "version": "2.0.0", "lockfileVersion": 3}`,
+			wantOK: true,
+		},
+		{
+			name:   "root package only",
+			data:   "{\n  \"packages\": {\n    \"node_modules/dep\": {\"version\": \"1.0.0\"},\n    \"\": {\"name\": \"lib\", \"version\": \"1.0.0\"}\n  },\n  \"version\": \"1.0.0\"\n}\n",
+			path:   []string{"packages", "", "version"},
+			want:   "{\n  \"packages\": {\n    \"node_modules/dep\": {\"version\": \"1.0.0\"},\n    \"\": {\"name\": \"lib\", \"version\": \"2.0.0\"}\n  },\n  \"version\": \"1.0.0\"\n}\n",
+			wantOK: true,
+		},
+		{
+			name: "nested value skipped",
+			data: `{"dependencies": {"version": {"version": "1.0.0"}}, "list": ["version"]}`,
+			path: []string{"version"},
+			want: `{"dependencies": {"version": {"version": "1.0.0"}}, "list": ["version"]}`,
+		},
+		{
+			name: "not a string",
+			data: `{"version": 1}`,
+			path: []string{"version"},
+			want: `{"version": 1}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := replaceJSONString([]byte(tt.data), tt.path, "2.0.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK || string(got) != tt.want {
+				t.Errorf("replaceJSONString() = %s, %v, want %s, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	if _, _, err := replaceJSONString([]byte(`{"version": `), []string{"version"}, "2.0.0"); err == nil {
+		t.Error("expected an error for truncated JSON")
+	}
+}
+
+func TestUpdateVersionLockfiles(t *testing.T) {
+	lock := "{\n  \"name\": \"lib\",\n  \"version\": \"0.9.0\",\n  \"lockfileVersion\": 3,\n  \"packages\": {\n    \"\": {\n      \"name\": \"lib\",\n      \"version\": \"0.9.0\"\n    }\n  }\n}\n"
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"0.9.0"}`)
+	writeFile(t, filepath.Join(dir, "package-lock.json"), lock)
+	writeFile(t, filepath.Join(dir, "npm-shrinkwrap.json"), `{"name":"lib","version":"1.0.0","lockfileVersion":1}`)
+	chdir(t, dir)
+
+	execute := func(dryRun bool) *plugin.ExecuteResponse {
+		t.Helper()
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPrePublish,
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+			DryRun:  dryRun,
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("unexpected failure: %v %+v", err, resp)
+		}
+		return resp
+	}
+
+	resp := execute(true)
+	if got := resp.Outputs["lockfiles_updated"]; !reflect.DeepEqual(got, []string{"package-lock.json"}) {
+		t.Errorf("dry run lockfiles_updated = %v", got)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "package-lock.json")); string(data) != lock {
+		t.Errorf("dry run wrote package-lock.json:\n%s", data)
+	}
+
+	resp = execute(false)
+	if got := resp.Outputs["lockfiles_updated"]; !reflect.DeepEqual(got, []string{"package-lock.json"}) {
+		t.Errorf("lockfiles_updated = %v", got)
+	}
+	want := "{\n  \"name\": \"lib\",\n  \"version\": \"1.0.0\",\n  \"lockfileVersion\": 3,\n  \"packages\": {\n    \"\": {\n      \"name\": \"lib\",\n      \"version\": \"1.0.0\"\n    }\n  }\n}\n"
+	if data, _ := os.ReadFile(filepath.Join(dir, "package-lock.json")); string(data) != want {
+		t.Errorf("package-lock.json =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestUpdateVersionBrokenLockfile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"0.9.0"}`)
+	writeFile(t, filepath.Join(dir, "package-lock.json"), `{"version": "0.9.0",`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPrePublish,
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success {
+		t.Fatalf("expected failure, got %+v", resp)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "package.json")); string(data) != `{"name":"lib","version":"0.9.0"}` {
+		t.Errorf("package.json written despite the broken lockfile: %s", data)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// majorTagData is the data major_tag templates are rendered with.
+type majorTagData struct {
+	Major int
+}
+
+// renderMajorTag renders the major-line dist-tag for major.
+func renderMajorTag(format string, major int) (string, error) {
+	tmpl, err := template.New("major_tag").Option("missingkey=error").Parse(format)
+	if err != nil {
+		return "", fmt.Errorf("invalid major_tag template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, majorTagData{Major: major}); err != nil {
+		return "", fmt.Errorf("failed to render major_tag: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// validateMajorTag checks that the template renders distinct, valid
+// dist-tags. npm rejects tags that parse as semver ranges, which rules out
+// "v1" and "1.x".
+func validateMajorTag(format string) error {
+	if format == "" {
+		return nil
+	}
+	one, err := renderMajorTag(format, 1)
+	if err != nil {
+		return err
+	}
+	two, _ := renderMajorTag(format, 2)
+	if one == two {
+		return fmt.Errorf("major_tag %q must include {{.Major}}", format)
+	}
+	if err := validateTag(one); err != nil {
+		return fmt.Errorf("major_tag renders an invalid dist-tag: %w", err)
+	}
+	return nil
+}
+
+// semverRangeLike reports whether npm would parse tag as a semver range: a
+// possibly partial version ("v1", "1.x"), or a full version with a
+// prerelease ("1.2.3-beta").
+func semverRangeLike(tag string) bool {
+	m := partialRegexp.FindStringSubmatch(tag)
+	if m == nil {
+		return false
+	}
+	if m[4] == "" {
+		return true
+	}
+	return semverRegexp.MatchString(tag)
+}
+
+// majorTagMoves returns the major-line dist-tags that do not point at the
+// newest stable version of their line, mapped to that version. The version
+// just published is included even if the registry does not list it yet.
+func majorTagMoves(doc *packument, published, format string) (map[string]string, error) {
+	newest := map[int]semver{}
+	consider := func(s string) {
+		v, err := parseSemver(s)
+		if err != nil || v.IsPrerelease() {
+			return
+		}
+		if cur, ok := newest[v.Major]; !ok || v.Compare(cur) > 0 {
+			newest[v.Major] = v
+		}
+	}
+	for s := range doc.Versions {
+		consider(s)
+	}
+	consider(published)
+
+	moves := map[string]string{}
+	for major, v := range newest {
+		tag, err := renderMajorTag(format, major)
+		if err != nil {
+			return nil, err
+		}
+		if doc.DistTags[tag] != v.String() {
+			moves[tag] = v.String()
+		}
+	}
+	return moves, nil
+}
+
+// reconcileMajorTags points every major-line dist-tag at the newest stable
+// version of its line. Dry runs only report the moves. Failures are warnings:
+// the release itself has already succeeded.
+func reconcileMajorTags(ctx context.Context, cfg *Config, outputs map[string]any, packageDir, name, version string, dryRun bool) {
+	if v, err := parseSemver(version); err != nil || v.IsPrerelease() {
+		return
+	}
+	doc, err := fetchPackument(ctx, registryURL(cfg), name)
+	if errors.Is(err, errPackageNotFound) {
+		doc = &packument{}
+	} else if err != nil {
+		appendWarning(outputs, fmt.Sprintf("major-line tags not reconciled: %v", err))
+		return
+	}
+	moves, err := majorTagMoves(doc, version, cfg.MajorTag)
+	if err != nil {
+		appendWarning(outputs, fmt.Sprintf("major-line tags not reconciled: %v", err))
+		return
+	}
+	if dryRun {
+		outputs["major_tags"] = moves
+		return
+	}
+
+	tags := make([]string, 0, len(moves))
+	for tag := range moves {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	moved := map[string]string{}
+	var failed []string
+	for _, tag := range tags {
+		args := append([]string{"dist-tag", "add", name + "@" + moves[tag], tag}, registryArgs(cfg)...)
+		if _, err := runNpm(ctx, packageDir, args...); err != nil {
+			failed = append(failed, tag)
+			continue
+		}
+		moved[tag] = moves[tag]
+	}
+	if len(failed) > 0 {
+		appendWarning(outputs, fmt.Sprintf("failed to move major-line tags: %s", strings.Join(failed, ", ")))
+	}
+	outputs["major_tags"] = moved
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateMajorTag(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{"", false},
+		{"latest-{{.Major}}", false},
+		{"v{{.Major}}-latest", false},
+		{"v{{.Major}}", true},
+		{"{{.Major}}.x", true},
+		{"latest", true},
+		{"latest {{.Major}}", true},
+		{"{{.Minor}}", true},
+		{"{{.Major", true},
+	}
+	for _, tt := range tests {
+		if err := validateMajorTag(tt.format); (err != nil) != tt.wantErr {
+			t.Errorf("validateMajorTag(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMajorTagMoves(t *testing.T) {
+	doc := &packument{
+		DistTags: map[string]string{"latest": "2.1.0", "latest-1": "1.4.0", "latest-2": "2.0.0"},
+		Versions: map[string]packumentVersion{
+			"1.4.0": {}, "1.5.0": {}, "1.6.0-beta.1": {},
+			"2.0.0": {}, "2.1.0": {},
+			"3.0.0-rc.1": {},
+		},
+	}
+
+	// Publishing a 1.x patch moves latest-1 off 1.4.0 and repairs latest-2
+	moves, err := majorTagMoves(doc, "1.5.1", "latest-{{.Major}}")
+	if err != nil {
+		t.Fatalf("majorTagMoves() error = %v", err)
+	}
+	want := map[string]string{"latest-1": "1.5.1", "latest-2": "2.1.0"}
+	if !reflect.DeepEqual(moves, want) {
+		t.Errorf("majorTagMoves() = %v, want %v", moves, want)
+	}
+
+	// An older patch of a line does not steal its tag
+	doc.DistTags["latest-1"] = "1.5.0"
+	doc.DistTags["latest-2"] = "2.1.0"
+	if moves, _ := majorTagMoves(doc, "1.4.1", "latest-{{.Major}}"); len(moves) != 0 {
+		t.Errorf("majorTagMoves() = %v, want none", moves)
+	}
+}
+
+func TestPublishReconcilesMajorTags(t *testing.T) {
+	server := newTestRegistry(t, map[string]*packument{
+		"pkg": {
+			Name:     "pkg",
+			DistTags: map[string]string{"latest": "2.0.0", "latest-1": "1.0.0"},
+			Versions: map[string]packumentVersion{"1.0.0": {}, "2.0.0": {}},
+		},
+	})
+	logPath := fakeNpm(t, `echo '{"id":"pkg@1.1.0","name":"pkg","version":"1.1.0"}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"1.1.0"}`)
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"registry": server.URL, "tag": "v1-maintenance", "major_tag": "latest-{{.Major}}"},
+		Context: plugin.ReleaseContext{Version: "1.1.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	want := map[string]string{"latest-1": "1.1.0", "latest-2": "2.0.0"}
+	if got := resp.Outputs["major_tags"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("major_tags = %v, want %v", got, want)
+	}
+	calls := npmCalls(t, logPath)
+	if len(calls) != 3 || !strings.HasPrefix(calls[1], "dist-tag add pkg@1.1.0 latest-1 ") || !strings.HasPrefix(calls[2], "dist-tag add pkg@2.0.0 latest-2 ") {
+		t.Errorf("npm calls = %v", calls)
+	}
+}
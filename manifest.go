@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// packFile is a single file entry reported by npm pack/publish --json.
+type packFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	Mode int    `json:"mode"`
+}
+
+// packManifest is the canonical description of a published tarball, written
+// for downstream signing and attestation. Field order is fixed and files are
+// sorted by path so identical tarballs produce byte-identical manifests.
+type packManifest struct {
+	Name         string     `json:"name"`
+	Version      string     `json:"version"`
+	Filename     string     `json:"filename"`
+	Size         int64      `json:"size"`
+	UnpackedSize int64      `json:"unpacked_size"`
+	Shasum       string     `json:"shasum"`
+	Integrity    string     `json:"integrity"`
+	Digest       string     `json:"digest"`
+	Files        []packFile `json:"files"`
+}
+
+// newPackManifest builds the canonical manifest from a publish result.
+func newPackManifest(result publishResult) (*packManifest, error) {
+	digest, err := integrityDigest(result.Integrity)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]packFile, len(result.Files))
+	copy(files, result.Files)
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	return &packManifest{
+		Name:         result.Name,
+		Version:      result.Version,
+		Filename:     result.Filename,
+		Size:         result.Size,
+		UnpackedSize: result.UnpackedSize,
+		Shasum:       result.Shasum,
+		Integrity:    result.Integrity,
+		Digest:       digest,
+		Files:        files,
+	}, nil
+}
+
+// integrityDigest converts an SRI integrity string ("sha512-<base64>") into a
+// hex digest ("sha512:<hex>").
+func integrityDigest(integrity string) (string, error) {
+	algo, b64, ok := strings.Cut(integrity, "-")
+	if !ok {
+		return "", fmt.Errorf("invalid integrity %q", integrity)
+	}
+	sum, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return "", fmt.Errorf("invalid integrity %q: %w", integrity, err)
+	}
+	return algo + ":" + hex.EncodeToString(sum), nil
+}
+
+// writePackManifest writes the manifest as indented JSON.
+func writePackManifest(path string, m *packManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal pack manifest: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create pack manifest directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write pack manifest: %w", err)
+	}
+	return nil
+}
+
+// validateOutputPath ensures a file the plugin writes stays within the
+// current working directory.
+func validateOutputPath(path string) error {
+	if path == "" {
+		return nil
+	}
+	cleanPath := filepath.Clean(path)
+	if filepath.IsAbs(cleanPath) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		rel, err := filepath.Rel(cwd, cleanPath)
+		if err != nil {
+			return fmt.Errorf("path must be within the current working directory")
+		}
+		cleanPath = rel
+	}
+	if cleanPath == ".." || strings.HasPrefix(cleanPath, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("path must be within the current working directory")
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestIntegrityDigest(t *testing.T) {
+	tests := []struct {
+		name      string
+		integrity string
+		want      string
+		wantErr   bool
+	}{
+		{"sha512", "sha512-3q2+7w==", "sha512:deadbeef", false},
+		{"missing_algo", "3q2+7w==", "", true},
+		{"bad_base64", "sha512-!!!", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := integrityDigest(tt.integrity)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("integrityDigest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("integrityDigest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOutputPath(t *testing.T) {
+	cwd, _ := os.Getwd()
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"relative", "out/manifest.json", false},
+		{"absolute_inside", filepath.Join(cwd, "manifest.json"), false},
+		{"traversal", "../manifest.json", true},
+		{"absolute_outside", "/etc/manifest.json", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateOutputPath(tt.path); (err != nil) != tt.wantErr {
+				t.Errorf("validateOutputPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublishWritesPackManifest(t *testing.T) {
+	p := &NpmPlugin{}
+	t.Setenv("TMPDIR", t.TempDir())
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name":"pkg","version":"1.0.0"}`), 0644); err != nil {
+		t.Fatalf("failed to write package.json: %v", err)
+	}
+	chdir(t, tmpDir)
+
+	fakeNpm(t, `cat <<'EOF'
+{"name":"pkg","version":"1.0.0","filename":"pkg-1.0.0.tgz","size":10,"unpackedSize":20,"shasum":"abc","integrity":"sha512-3q2+7w==","files":[{"path":"package.json","size":15,"mode":420},{"path":"index.js","size":5,"mode":420}]}
+EOF`)
+
+	cfg := &Config{PackageDir: ".", Tag: "latest", PackManifest: "dist/manifest.json"}
+	resp, err := p.publishPackage(context.Background(), cfg, plugin.ReleaseContext{Version: "1.0.0"}, false)
+	if err != nil {
+		t.Fatalf("publishPackage returned error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got error: %s", resp.Error)
+	}
+	if resp.Outputs["pack_digest"] != "sha512:deadbeef" {
+		t.Errorf("unexpected pack_digest: %v", resp.Outputs["pack_digest"])
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "dist", "manifest.json"))
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	var m packManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("failed to parse manifest: %v", err)
+	}
+	if len(m.Files) != 2 || m.Files[0].Path != "index.js" {
+		t.Errorf("expected files sorted by path, got %+v", m.Files)
+	}
+
+	rec, err := loadRecord(cfg, "pkg")
+	if err != nil || rec == nil {
+		t.Fatalf("expected publish record, got %v, %v", rec, err)
+	}
+	if rec.Integrity != "sha512-3q2+7w==" {
+		t.Errorf("unexpected recorded integrity: %q", rec.Integrity)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultLeakPatterns match strings that point at internal infrastructure:
+// private network hosts, registry overrides and inline credentials.
+var defaultLeakPatterns = []string{
+	`https?://(?:localhost|127\.|10\.|192\.168\.|172\.(?:1[6-9]|2\d|3[01])\.)`,
+	`https?://[^/\s"']+\.(?:internal|local|corp|lan|intranet)\b`,
+	`--registry[= ]`,
+	`_authToken|_auth=`,
+}
+
+// ManifestLeaks checks the published package.json for fields and strings
+// that expose internal infrastructure.
+type ManifestLeaks struct {
+	// Enabled runs the check with the built-in patterns only.
+	Enabled bool `json:"enabled,omitempty"`
+	// Fields are dotted paths that must not be published, e.g.
+	// "devDependencies" or "scripts.deploy".
+	Fields []string `json:"fields,omitempty"`
+	// Patterns are regular expressions no string in the manifest may match,
+	// in addition to the built-in ones.
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+// enabled reports whether the check runs.
+func (m ManifestLeaks) enabled() bool {
+	return m.Enabled || len(m.Fields) > 0 || len(m.Patterns) > 0
+}
+
+// validateManifestLeaks checks the field paths and patterns.
+func validateManifestLeaks(m ManifestLeaks) error {
+	for _, field := range m.Fields {
+		if err := validateFieldPath(field); err != nil {
+			return err
+		}
+	}
+	_, err := compileLeakPatterns(m.Patterns)
+	return err
+}
+
+// compileLeakPatterns compiles the built-in and configured patterns.
+func compileLeakPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(defaultLeakPatterns)+len(patterns))
+	for _, p := range append(append([]string{}, defaultLeakPatterns...), patterns...) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// manifestField returns the value at a dotted path in manifest.
+func manifestField(manifest map[string]any, field string) (any, bool) {
+	var v any = manifest
+	for _, part := range strings.Split(field, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// findManifestLeaks returns the blocked fields present in manifest and the
+// strings matching a pattern, as "path: description". Matched values are
+// not repeated, as they may be credentials.
+func findManifestLeaks(manifest map[string]any, m ManifestLeaks) ([]string, error) {
+	patterns, err := compileLeakPatterns(m.Patterns)
+	if err != nil {
+		return nil, err
+	}
+	leaks := []string{}
+	for _, field := range m.Fields {
+		if _, ok := manifestField(manifest, field); ok {
+			leaks = append(leaks, fmt.Sprintf("%s: field is blocked", field))
+		}
+	}
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch v := v.(type) {
+		case string:
+			for _, re := range patterns {
+				if re.MatchString(v) {
+					leaks = append(leaks, fmt.Sprintf("%s: matches %q", path, re.String()))
+					break
+				}
+			}
+		case []any:
+			for i, item := range v {
+				walk(fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				next := k
+				if path != "" {
+					next = path + "." + k
+				}
+				walk(next, v[k])
+			}
+		}
+	}
+	walk("", manifest)
+	return leaks, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestFindManifestLeaks(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		leaks    ManifestLeaks
+		want     []string
+	}{
+		{
+			name:     "clean",
+			manifest: `{"name":"lib","repository":"https://github.com/acme/lib","scripts":{"test":"vitest"}}`,
+			leaks:    ManifestLeaks{Enabled: true},
+		},
+		{
+			name:     "registry in script",
+			manifest: `{"scripts":{"postinstall":"npm install --registry=https://npm.example.com/ helper"}}`,
+			leaks:    ManifestLeaks{Enabled: true},
+			want:     []string{"scripts.postinstall: matches"},
+		},
+		{
+			name:     "internal host",
+			manifest: `{"bugs":{"url":"https://jira.acme.internal/browse/LIB"},"files":["dist","http://10.0.4.2/x"]}`,
+			leaks:    ManifestLeaks{Enabled: true},
+			want:     []string{"bugs.url: matches", "files[1]: matches"},
+		},
+		{
+			name:     "blocked fields",
+			manifest: `{"devDependencies":{"vitest":"^1.0.0"},"scripts":{"deploy":"./deploy.sh"}}`,
+			leaks:    ManifestLeaks{Fields: []string{"devDependencies", "scripts.deploy", "scripts.release"}},
+			want:     []string{"devDependencies: field is blocked", "scripts.deploy: field is blocked"},
+		},
+		{
+			name:     "custom pattern",
+			manifest: `{"description":"Client for build-farm-3.acme.io"}`,
+			leaks:    ManifestLeaks{Patterns: []string{`\.acme\.io\b`}},
+			want:     []string{"description: matches"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var manifest map[string]any
+			if err := json.Unmarshal([]byte(tt.manifest), &manifest); err != nil {
+				t.Fatal(err)
+			}
+			got, err := findManifestLeaks(manifest, tt.leaks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("findManifestLeaks() = %q, want %q", got, tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(got[i], want) {
+					t.Errorf("leak %d = %q, want prefix %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateManifestLeaks(t *testing.T) {
+	if err := validateManifestLeaks(ManifestLeaks{Fields: []string{"scripts..x"}}); err == nil {
+		t.Error("expected an error for an invalid field path")
+	}
+	if err := validateManifestLeaks(ManifestLeaks{Patterns: []string{"("}}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+	if err := validateManifestLeaks(ManifestLeaks{Fields: []string{"devDependencies"}, Patterns: []string{`\.acme\.io`}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestManifestLeaksChecksStrippedManifest(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0","devDependencies":{"vitest":"^1.0.0"},"scripts":{"release":"npm publish --registry https://npm.acme.corp/"}}`)
+	chdir(t, dir)
+
+	config := map[string]any{"manifest_leaks": map[string]any{"fields": []any{"devDependencies"}}}
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "devDependencies: field is blocked") || !strings.Contains(resp.Error, "scripts.release") {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	for _, call := range npmCalls(t, logPath) {
+		if strings.HasPrefix(call, "publish") {
+			t.Fatal("published despite leaks")
+		}
+	}
+
+	config["strip_fields"] = []any{"devDependencies", "scripts.release"}
+	resp, err = (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+		DryRun:  true,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"text/template"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// MessageTemplates overrides the human-readable publish messages. Templates
+// receive the same fields as other templates plus .Message (the default
+// message) and .Error (the failure reason).
+type MessageTemplates struct {
+	// Published replaces the success message after a real publish.
+	Published string `json:"published,omitempty"`
+	// Skipped replaces the message when publishing is intentionally skipped.
+	Skipped string `json:"skipped,omitempty"`
+	// Failed replaces the error message when publishing fails.
+	Failed string `json:"failed,omitempty"`
+}
+
+// validateMessageTemplates checks that all configured templates parse.
+func validateMessageTemplates(m MessageTemplates) error {
+	for field, text := range map[string]string{"published": m.Published, "skipped": m.Skipped, "failed": m.Failed} {
+		if text == "" {
+			continue
+		}
+		if _, err := template.New(field).Parse(text); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// skipResponse returns a successful response for an intentional skip. The
+// "skipped" output lets callers tell a skip from a publish.
+func skipResponse(reason, message string) *plugin.ExecuteResponse {
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: message,
+		Outputs: map[string]any{
+			"skipped":     true,
+			"skip_reason": reason,
+		},
+	}
+}
+
+// applyMessageTemplates rewrites a publish response's message or error using
+// the configured templates. Dry runs keep their "Would run" messages. A
+// template that fails to render leaves the default text in place and adds a
+// warning.
+func applyMessageTemplates(cfg *Config, releaseCtx plugin.ReleaseContext, resp *plugin.ExecuteResponse, dryRun bool) {
+	if resp == nil || dryRun {
+		return
+	}
+
+	name, _ := resp.Outputs["package"].(string)
+	data := newTemplateData(name, cfg, releaseCtx)
+	data.Message = resp.Message
+	data.Error = resp.Error
+
+	skipped, _ := resp.Outputs["skipped"].(bool)
+	var text string
+	var target *string
+	switch {
+	case !resp.Success:
+		text, target = cfg.Messages.Failed, &resp.Error
+	case skipped:
+		text, target = cfg.Messages.Skipped, &resp.Message
+	default:
+		text, target = cfg.Messages.Published, &resp.Message
+	}
+	if text == "" {
+		return
+	}
+
+	rendered, err := renderTemplate(text, data)
+	if err != nil {
+		if resp.Outputs == nil {
+			resp.Outputs = map[string]any{}
+		}
+		appendWarning(resp.Outputs, fmt.Sprintf("message template not applied: %v", err))
+		return
+	}
+	*target = rendered
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestApplyMessageTemplates(t *testing.T) {
+	cfg := &Config{
+		Tag: "latest",
+		Messages: MessageTemplates{
+			Published: "{{.Name}}@{{.Version}} is live on {{.Tag}} (REL-42)",
+			Skipped:   "Skipped {{.Name}}: {{.Message}}",
+			Failed:    "Publish failed, see https://runbooks.example.com/npm: {{.Error}}",
+		},
+	}
+	releaseCtx := plugin.ReleaseContext{Version: "1.2.3"}
+
+	tests := []struct {
+		name        string
+		resp        *plugin.ExecuteResponse
+		dryRun      bool
+		wantMessage string
+		wantError   string
+	}{
+		{
+			name:        "published",
+			resp:        &plugin.ExecuteResponse{Success: true, Message: "Published pkg@1.2.3 to npm", Outputs: map[string]any{"package": "pkg"}},
+			wantMessage: "pkg@1.2.3 is live on latest (REL-42)",
+		},
+		{
+			name:        "skipped",
+			resp:        skipResponse("private", "Package is private"),
+			wantMessage: "Skipped : Package is private",
+		},
+		{
+			name:      "failed",
+			resp:      &plugin.ExecuteResponse{Success: false, Error: "E403"},
+			wantError: "Publish failed, see https://runbooks.example.com/npm: E403",
+		},
+		{
+			name:        "dry_run_untouched",
+			resp:        &plugin.ExecuteResponse{Success: true, Message: "Would run: npm publish"},
+			dryRun:      true,
+			wantMessage: "Would run: npm publish",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyMessageTemplates(cfg, releaseCtx, tt.resp, tt.dryRun)
+			if tt.resp.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", tt.resp.Message, tt.wantMessage)
+			}
+			if tt.resp.Error != tt.wantError {
+				t.Errorf("Error = %q, want %q", tt.resp.Error, tt.wantError)
+			}
+		})
+	}
+
+	t.Run("render_error_keeps_default", func(t *testing.T) {
+		badCfg := &Config{Messages: MessageTemplates{Published: "{{.Missing}}"}}
+		resp := &plugin.ExecuteResponse{Success: true, Message: "Published pkg@1.2.3 to npm"}
+		applyMessageTemplates(badCfg, releaseCtx, resp, false)
+		if resp.Message != "Published pkg@1.2.3 to npm" {
+			t.Errorf("expected default message, got %q", resp.Message)
+		}
+		warnings, _ := resp.Outputs["warnings"].([]string)
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "message template") {
+			t.Errorf("expected template warning, got %v", warnings)
+		}
+	})
+}
+
+func TestValidateMessageTemplates(t *testing.T) {
+	if err := validateMessageTemplates(MessageTemplates{Published: "{{.Name}}"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateMessageTemplates(MessageTemplates{Failed: "{{.Error"}); err == nil {
+		t.Error("expected error for unterminated template")
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Module formats.
+const (
+	formatESM = "esm"
+	formatCJS = "cjs"
+)
+
+// esmSyntaxRegexp matches top-level import and export statements.
+var esmSyntaxRegexp = regexp.MustCompile(`(?m)^\s*(?:import(?:\s+[\w{*]|\s*['"{*])|export\s+(?:default|const|let|var|function|class|async|\{|\*))`)
+
+// cjsSyntaxRegexp matches module.exports, exports.x assignments and require
+// calls.
+var cjsSyntaxRegexp = regexp.MustCompile(`\bmodule\.exports\b|(?m)^\s*exports\.\w+\s*=|\brequire\(\s*['"]`)
+
+// moduleStateRegexp matches top-level declarations that hold state, which
+// is duplicated when a module is loaded both as ESM and as CommonJS.
+var moduleStateRegexp = regexp.MustCompile(`(?m)^(?:export\s+)?(?:let|var|class)\s|^(?:export\s+)?const\s+\w+\s*=\s*(?:new\s+(?:Map|Set|WeakMap|WeakSet)\b|Symbol\()`)
+
+// entryPoint is a JavaScript file a package.json field or export condition
+// resolves to.
+type entryPoint struct {
+	// Field is where the entry point is declared, e.g. `main` or
+	// `exports["."].import`.
+	Field string
+	// Subpath is the exports subpath, empty for main.
+	Subpath string
+	// Conditions are the export conditions leading to Path.
+	Conditions []string
+	Path       string
+}
+
+// hasCondition reports whether the entry point is selected by condition.
+func (e entryPoint) hasCondition(condition string) bool {
+	return containsString(e.Conditions, condition)
+}
+
+// moduleEntryPoints returns the JavaScript entry points Node resolves: main
+// and exports. The bundler-only module field is not checked, and subpath
+// patterns are skipped.
+func moduleEntryPoints(manifest map[string]any) []entryPoint {
+	var entries []entryPoint
+	if p, ok := manifest["main"].(string); ok && p != "" {
+		entries = append(entries, entryPoint{Field: "main", Path: p})
+	}
+
+	exports, ok := manifest["exports"]
+	if !ok {
+		return entries
+	}
+	subpaths, isMap := exports.(map[string]any)
+	if !isMap || !hasSubpathKeys(subpaths) {
+		return collectExports(entries, exports, ".", nil)
+	}
+	keys := make([]string, 0, len(subpaths))
+	for k := range subpaths {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.Contains(k, "*") {
+			continue
+		}
+		entries = collectExports(entries, subpaths[k], k, nil)
+	}
+	return entries
+}
+
+// hasSubpathKeys reports whether an exports object maps subpaths rather
+// than conditions.
+func hasSubpathKeys(m map[string]any) bool {
+	for k := range m {
+		if strings.HasPrefix(k, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// collectExports appends the JavaScript targets of an exports value.
+func collectExports(entries []entryPoint, v any, subpath string, conditions []string) []entryPoint {
+	switch v := v.(type) {
+	case string:
+		if !strings.Contains(v, "*") && isScriptPath(v) {
+			field := fmt.Sprintf("exports[%q]", subpath)
+			for _, c := range conditions {
+				field += "." + c
+			}
+			entries = append(entries, entryPoint{Field: field, Subpath: subpath, Conditions: conditions, Path: v})
+		}
+	case []any:
+		for _, item := range v {
+			entries = collectExports(entries, item, subpath, conditions)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if k == "types" {
+				continue
+			}
+			next := append(append([]string{}, conditions...), k)
+			entries = collectExports(entries, v[k], subpath, next)
+		}
+	}
+	return entries
+}
+
+// isScriptPath reports whether p names a JavaScript file.
+func isScriptPath(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".js", ".mjs", ".cjs":
+		return true
+	}
+	return false
+}
+
+// moduleFormat returns how Node loads the packed file p: by extension for
+// .mjs and .cjs, otherwise by the "type" of the nearest packed package.json.
+func moduleFormat(packageDir, p string, packed map[string]bool) string {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".mjs":
+		return formatESM
+	case ".cjs":
+		return formatCJS
+	}
+	for dir := path.Dir(p); ; dir = path.Dir(dir) {
+		manifest := path.Join(dir, "package.json")
+		if dir == "." || packed[manifest] {
+			var pkg struct {
+				Type string `json:"type"`
+			}
+			data, err := os.ReadFile(filepath.Join(packageDir, filepath.FromSlash(manifest)))
+			if err == nil && json.Unmarshal(data, &pkg) == nil && pkg.Type == "module" {
+				return formatESM
+			}
+			return formatCJS
+		}
+	}
+}
+
+// syntaxFormat guesses the module syntax of source, returning "" when it
+// uses both or neither.
+func syntaxFormat(source string) string {
+	esm := esmSyntaxRegexp.MatchString(source)
+	cjs := cjsSyntaxRegexp.MatchString(source)
+	switch {
+	case esm && !cjs:
+		return formatESM
+	case cjs && !esm:
+		return formatCJS
+	}
+	return ""
+}
+
+// checkModuleFormats looks for ESM/CommonJS mistakes in the packed entry
+// points:
+//
+//   - a file whose syntax does not match how Node loads it, usually a
+//     missing or wrong "type" field
+//   - a require condition resolving to an ES module
+//   - a dual package hazard: import and require resolving to separate
+//     implementations that hold module state, which is then duplicated when
+//     both are loaded
+//
+// It returns the problems found.
+func checkModuleFormats(packageDir string, manifest map[string]any, files []packFile) ([]string, error) {
+	packed := make(map[string]bool, len(files))
+	for _, f := range files {
+		packed[f.Path] = true
+	}
+	sources := map[string]string{}
+	read := func(p string) (string, bool, error) {
+		if src, ok := sources[p]; ok {
+			return src, true, nil
+		}
+		if !packed[p] {
+			return "", false, nil
+		}
+		data, err := os.ReadFile(filepath.Join(packageDir, filepath.FromSlash(p)))
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		sources[p] = string(data)
+		return sources[p], true, nil
+	}
+
+	var problems []string
+	reported := map[string]bool{}
+	imports := map[string]string{}
+	requires := map[string]string{}
+	defaults := map[string]string{}
+	seen := map[string]bool{}
+	var subpaths []string
+	for _, e := range moduleEntryPoints(manifest) {
+		p := path.Clean(strings.TrimPrefix(e.Path, "./"))
+		src, ok, err := read(p)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		format := moduleFormat(packageDir, p, packed)
+		if syntax := syntaxFormat(src); syntax != "" && syntax != format && !reported[p] {
+			reported[p] = true
+			hint := `set "type": "module" or use the .mjs extension`
+			if syntax == formatCJS {
+				hint = `use the .cjs extension or drop "type": "module"`
+			}
+			problems = append(problems, fmt.Sprintf("%s: %s is loaded as %s but uses %s syntax; %s", e.Field, p, formatName(format), formatName(syntax), hint))
+		}
+		if e.hasCondition("require") && format == formatESM {
+			problems = append(problems, fmt.Sprintf("%s: require resolves to ES module %s", e.Field, p))
+		}
+
+		if e.Subpath == "" {
+			continue
+		}
+		if !seen[e.Subpath] {
+			seen[e.Subpath] = true
+			subpaths = append(subpaths, e.Subpath)
+		}
+		if e.hasCondition("import") && imports[e.Subpath] == "" {
+			imports[e.Subpath] = p
+		}
+		if e.hasCondition("require") && requires[e.Subpath] == "" {
+			requires[e.Subpath] = p
+		}
+		if e.hasCondition("default") && !e.hasCondition("import") && defaults[e.Subpath] == "" {
+			defaults[e.Subpath] = p
+		}
+	}
+
+	for _, subpath := range subpaths {
+		esm, cjs := imports[subpath], requires[subpath]
+		if cjs == "" {
+			// require falls through to default
+			cjs = defaults[subpath]
+		}
+		if esm == "" || cjs == "" || esm == cjs {
+			continue
+		}
+		esmSrc, cjsSrc := sources[esm], sources[cjs]
+		// An ES module wrapper re-exporting the CommonJS build shares its
+		// state, which is the usual way around the hazard
+		if strings.Contains(esmSrc, path.Base(cjs)) {
+			continue
+		}
+		if moduleStateRegexp.MatchString(esmSrc) || moduleStateRegexp.MatchString(cjsSrc) {
+			problems = append(problems, fmt.Sprintf("exports[%q]: dual package hazard, import (%s) and require (%s) are separate implementations with module state", subpath, esm, cjs))
+		}
+	}
+	return problems, nil
+}
+
+// formatName returns the display name of a module format.
+func formatName(format string) string {
+	if format == formatESM {
+		return "ESM"
+	}
+	return "CommonJS"
+}
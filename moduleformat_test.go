@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestModuleEntryPoints(t *testing.T) {
+	var manifest map[string]any
+	err := json.Unmarshal([]byte(`{
+		"main": "./dist/index.cjs",
+		"module": "./dist/index.js",
+		"exports": {
+			".": {"types": "./dist/index.d.ts", "import": "./dist/index.mjs", "require": "./dist/index.cjs"},
+			"./utils": {"node": {"import": "./dist/utils.mjs"}, "default": "./dist/utils.cjs"},
+			"./features/*": "./dist/features/*.js",
+			"./package.json": "./package.json"
+		}
+	}`), &manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range moduleEntryPoints(manifest) {
+		got = append(got, e.Field+"="+e.Path)
+	}
+	want := []string{
+		"main=./dist/index.cjs",
+		`exports["."].import=./dist/index.mjs`,
+		`exports["."].require=./dist/index.cjs`,
+		`exports["./utils"].default=./dist/utils.cjs`,
+		`exports["./utils"].node.import=./dist/utils.mjs`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("moduleEntryPoints() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckModuleFormats(t *testing.T) {
+	const esm = "import { x } from './x.js'\nexport const y = x\n"
+	const cjs = "const x = require('./x')\nmodule.exports = { y: x }\n"
+	tests := []struct {
+		name     string
+		files    map[string]string
+		problems []string
+	}{
+		{
+			name: "commonjs main",
+			files: map[string]string{
+				"package.json": `{"main": "index.js"}`,
+				"index.js":     cjs,
+			},
+		},
+		{
+			name: "esm without type",
+			files: map[string]string{
+				"package.json": `{"main": "index.js"}`,
+				"index.js":     esm,
+			},
+			problems: []string{`main: index.js is loaded as CommonJS but uses ESM syntax; set "type": "module"`},
+		},
+		{
+			name: "commonjs with type module",
+			files: map[string]string{
+				"package.json": `{"type": "module", "exports": "./index.js"}`,
+				"index.js":     cjs,
+			},
+			problems: []string{`exports["."]: index.js is loaded as ESM but uses CommonJS syntax`},
+		},
+		{
+			name: "nested type module",
+			files: map[string]string{
+				"package.json":          `{"exports": {"import": "./dist/esm/index.js", "require": "./dist/cjs/index.js"}}`,
+				"dist/esm/package.json": `{"type": "module"}`,
+				"dist/esm/index.js":     esm,
+				"dist/cjs/index.js":     cjs,
+			},
+		},
+		{
+			name: "require resolves to esm",
+			files: map[string]string{
+				"package.json": `{"exports": {"require": "./index.mjs"}}`,
+				"index.mjs":    esm,
+			},
+			problems: []string{`exports["."].require: require resolves to ES module index.mjs`},
+		},
+		{
+			name: "dual package hazard",
+			files: map[string]string{
+				"package.json": `{"exports": {".": {"import": "./index.mjs", "require": "./index.cjs"}}}`,
+				"index.mjs":    "let cache = new Map()\nexport function get(k) { return cache.get(k) }\n",
+				"index.cjs":    "let cache = new Map()\nmodule.exports.get = (k) => cache.get(k)\n",
+			},
+			problems: []string{`exports["."]: dual package hazard, import (index.mjs) and require (index.cjs)`},
+		},
+		{
+			name: "dual package hazard via default",
+			files: map[string]string{
+				"package.json": `{"exports": {"import": "./index.mjs", "default": "./index.cjs"}}`,
+				"index.mjs":    "export class Client {}\n",
+				"index.cjs":    "class Client {}\nmodule.exports = { Client }\n",
+			},
+			problems: []string{`exports["."]: dual package hazard`},
+		},
+		{
+			name: "stateless dual package",
+			files: map[string]string{
+				"package.json": `{"exports": {"import": "./index.mjs", "require": "./index.cjs"}}`,
+				"index.mjs":    "export const add = (a, b) => a + b\n",
+				"index.cjs":    "module.exports.add = (a, b) => a + b\n",
+			},
+		},
+		{
+			name: "esm wrapper",
+			files: map[string]string{
+				"package.json": `{"exports": {"import": "./wrapper.mjs", "require": "./index.cjs"}}`,
+				"wrapper.mjs":  "import cjs from './index.cjs'\nexport const { Client } = cjs\n",
+				"index.cjs":    "class Client {}\nmodule.exports = { Client }\n",
+			},
+		},
+		{
+			name: "entry point not packed",
+			files: map[string]string{
+				"package.json": `{"main": "dist/index.js"}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := writePackFiles(t, dir, tt.files)
+			manifest, err := readManifest(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			problems, err := checkModuleFormats(dir, manifest, files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(problems) != len(tt.problems) {
+				t.Fatalf("problems = %q, want %q", problems, tt.problems)
+			}
+			for i, want := range tt.problems {
+				if !strings.HasPrefix(problems[i], want) {
+					t.Errorf("problem %d = %q, want prefix %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
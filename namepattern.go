@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// remoteRepoRegexp extracts owner and repository from an https or ssh git
+// remote URL.
+var remoteRepoRegexp = regexp.MustCompile(`[/:]([^/:]+)/([^/]+?)(?:\.git)?/?$`)
+
+// compileNamePattern compiles a name_pattern. Patterns containing "{{" are
+// templates rendered with data whose "*" matches any run of characters other
+// than "/"; anything else is a regular expression matching the whole name.
+func compileNamePattern(pattern string, data templateData) (*regexp.Regexp, error) {
+	if !strings.Contains(pattern, "{{") {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid name_pattern: %w", err)
+		}
+		return re, nil
+	}
+
+	rendered, err := renderTemplate(pattern, data)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(rendered, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, "[^/]*") + "$"), nil
+}
+
+// checkPackageName enforces name_pattern on a package name.
+func checkPackageName(name, pattern string, data templateData) error {
+	re, err := compileNamePattern(pattern, data)
+	if err != nil {
+		return err
+	}
+	if !re.MatchString(name) {
+		return fmt.Errorf("package name %q does not match name_pattern %q", name, pattern)
+	}
+	return nil
+}
+
+// remoteRepo returns the owner and name of the origin remote's repository,
+// used for name_pattern when the release context does not provide them.
+func remoteRepo(ctx context.Context, dir string) (owner, name string) {
+	remote, err := runGit(ctx, dir, "remote", "get-url", "origin")
+	if err != nil {
+		return "", ""
+	}
+	m := remoteRepoRegexp.FindStringSubmatch(remote)
+	if m == nil {
+		return "", ""
+	}
+	return m[1], m[2]
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestCheckPackageName(t *testing.T) {
+	data := templateData{RepoOwner: "acme", RepoName: "widgets"}
+	tests := []struct {
+		name    string
+		pkg     string
+		pattern string
+		wantErr bool
+	}{
+		{"template match", "@acme/widgets-core", "@{{.RepoOwner}}/{{.RepoName}}-*", false},
+		{"template bare", "@acme/widgets-", "@{{.RepoOwner}}/{{.RepoName}}-*", false},
+		{"template wrong scope", "@other/widgets-core", "@{{.RepoOwner}}/{{.RepoName}}-*", true},
+		{"template star stops at slash", "@acme/widgets-a/b", "@acme/{{.RepoName}}-*", true},
+		{"template dot is literal", "@acme/widgetsXcore", "@acme/{{.RepoName}}.core", true},
+		{"regex match", "@acme/ui-button", `@acme/(ui|core)-[a-z]+`, false},
+		{"regex anchored", "evil-@acme/ui-button", `@acme/ui-[a-z]+`, true},
+		{"invalid regex", "x", `(`, true},
+		{"unknown template field", "x", "{{.Nope}}", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkPackageName(tt.pkg, tt.pattern, data); (err != nil) != tt.wantErr {
+				t.Errorf("checkPackageName(%q, %q) error = %v, wantErr %v", tt.pkg, tt.pattern, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRemoteRepo(t *testing.T) {
+	for _, remote := range []string{
+		"https://github.com/acme/widgets.git",
+		"git@github.com:acme/widgets.git",
+		"https://github.com/acme/widgets",
+	} {
+		dir, _ := initGitRepo(t)
+		if _, err := runGit(context.Background(), dir, "remote", "add", "origin", remote); err != nil {
+			t.Fatal(err)
+		}
+		if owner, name := remoteRepo(context.Background(), dir); owner != "acme" || name != "widgets" {
+			t.Errorf("remoteRepo(%q) = %q, %q", remote, owner, name)
+		}
+	}
+	if owner, name := remoteRepo(context.Background(), t.TempDir()); owner != "" || name != "" {
+		t.Errorf("expected no repo outside git, got %q, %q", owner, name)
+	}
+}
+
+func TestNamePatternValidate(t *testing.T) {
+	dir, _ := initGitRepo(t)
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"@acme/legacy","version":"1.0.0"}`)
+	if _, err := runGit(context.Background(), dir, "remote", "add", "origin", "https://github.com/acme/widgets.git"); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, dir)
+
+	p := &NpmPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{"name_pattern": "@acme/{{.RepoName}}-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Valid {
+		t.Error("expected mismatched package name to be invalid")
+	}
+
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"@acme/widgets-core","version":"1.0.0"}`)
+	resp, err = p.Validate(context.Background(), map[string]any{"name_pattern": "@acme/{{.RepoName}}-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range resp.Errors {
+		if e.Field == "name_pattern" {
+			t.Errorf("unexpected name_pattern error: %v", e)
+		}
+	}
+}
+
+func TestPublishNamePattern(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"widgets","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	p := &NpmPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"name_pattern": "@{{.RepoOwner}}/{{.RepoName}}"},
+		Context: plugin.ReleaseContext{Version: "1.0.0", RepositoryOwner: "acme", RepositoryName: "widgets"},
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "does not match name_pattern") {
+		t.Errorf("expected name_pattern failure, got %+v", resp)
+	}
+}
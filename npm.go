@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// runNpm runs npm with args in dir and returns its stdout. On failure the
+// returned error includes stderr.
+func runNpm(ctx context.Context, dir string, args ...string) (string, error) {
+	return runCommand(ctx, dir, "npm", args, args[0])
+}
+
+// runCommand runs name with args in dir, reporting failures as the npm
+// command (e.g. "publish") it executes.
+func runCommand(ctx context.Context, dir, name string, args []string, command string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	recordCommand(ctx, dir, name, args, start, err)
+	if err != nil {
+		return stdout.String(), newNpmError(command, err, stdout.String(), stderr.String())
+	}
+	return stdout.String(), nil
+}
+
+// npmError is a failed npm invocation. When a lifecycle script caused the
+// failure, Script names it and ScriptOutput holds what it printed, so the
+// error shows the actual build failure rather than npm's generic summary.
+type npmError struct {
+	Command      string
+	Err          error
+	Stderr       string
+	Script       string
+	ScriptOutput string
+}
+
+// newNpmError builds an npmError, extracting lifecycle script output.
+func newNpmError(command string, err error, stdout, stderr string) *npmError {
+	e := &npmError{Command: command, Err: err, Stderr: strings.TrimSpace(stderr)}
+	e.Script, e.ScriptOutput = scriptFailure(stdout, stderr)
+	return e
+}
+
+func (e *npmError) Error() string {
+	if e.Script != "" {
+		return fmt.Sprintf("npm %s failed: lifecycle script %q failed: %v\nscript output:\n%s", e.Command, e.Script, e.Err, e.ScriptOutput)
+	}
+	return fmt.Sprintf("npm %s failed: %v\nstderr: %s", e.Command, e.Err, e.Stderr)
+}
+
+func (e *npmError) Unwrap() error {
+	return e.Err
+}
+
+// publishRegistry returns the registry URL used for writes: publish_url
+// when set, otherwise the configured registry (which may be empty, leaving
+// npm's own configuration in effect).
+func publishRegistry(cfg *Config) string {
+	if cfg.PublishURL != "" {
+		return cfg.PublishURL
+	}
+	return cfg.Registry
+}
+
+// setFlag sets the value following flag in args, appending the flag when it
+// is absent.
+func setFlag(args []string, flag, value string) []string {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == flag {
+			args[i+1] = value
+			return args
+		}
+	}
+	return append(args, flag, value)
+}
+
+// npmConfigArgs returns the --userconfig/--globalconfig, preset scope and
+// test registry auth flags passed to every npm invocation. Relative paths are resolved against the working directory
+// because npm itself runs in the package directory.
+func npmConfigArgs(cfg *Config) []string {
+	var args []string
+	for _, f := range []struct{ flag, path string }{
+		{"--userconfig", cfg.UserConfig},
+		{"--globalconfig", cfg.GlobalConfig},
+	} {
+		if f.path == "" {
+			continue
+		}
+		path := f.path
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		args = append(args, f.flag, path)
+	}
+	args = append(args, scopeRegistryArgs(cfg)...)
+	return append(args, cfg.authArgs...)
+}
+
+// validateNpmrcPath validates a userconfig/globalconfig path. The path is
+// passed to npm as a flag value, so option-like and control characters are
+// rejected; an existing path must be a regular file.
+func validateNpmrcPath(path string) error {
+	if path == "" {
+		return nil
+	}
+	if strings.HasPrefix(path, "-") {
+		return fmt.Errorf("path must not start with '-'")
+	}
+	if strings.IndexFunc(path, unicode.IsControl) >= 0 {
+		return fmt.Errorf("path contains control characters")
+	}
+	info, err := os.Stat(path)
+	if err == nil && !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+	return nil
+}
+
+// registryArgs returns the flags shared by commands that write to the registry.
+func registryArgs(cfg *Config) []string {
+	args := npmConfigArgs(cfg)
+	if registry := publishRegistry(cfg); registry != "" {
+		args = append(args, "--registry", registry)
+	}
+	return append(args, otpArgs(cfg)...)
+}
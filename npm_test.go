@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestMain serves the public registry lookup locally, reporting every
+// package as published, so tests never reach registry.npmjs.org.
+func TestMain(m *testing.M) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"versions":{}}`))
+	}))
+	publicRegistry = server.URL + "/"
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
+
+// fakeNpm installs an "npm" shell script at the front of PATH. Each
+// invocation's arguments are appended to the returned log file, then body runs.
+func fakeNpm(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake npm script requires a POSIX shell")
+	}
+
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "npm.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + logPath + "\n" + body + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "npm"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake npm: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+// npmCalls returns the logged fake npm invocations.
+func npmCalls(t *testing.T, logPath string) []string {
+	t.Helper()
+	data, err := os.ReadFile(logPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("failed to read npm log: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestRunNpm(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success_returns_stdout", func(t *testing.T) {
+		logPath := fakeNpm(t, "echo out")
+		stdout, err := runNpm(ctx, t.TempDir(), "view", "pkg")
+		if err != nil {
+			t.Fatalf("runNpm returned error: %v", err)
+		}
+		if strings.TrimSpace(stdout) != "out" {
+			t.Errorf("stdout = %q, want %q", stdout, "out")
+		}
+		if calls := npmCalls(t, logPath); len(calls) != 1 || calls[0] != "view pkg" {
+			t.Errorf("unexpected calls: %v", calls)
+		}
+	})
+
+	t.Run("failure_includes_stderr", func(t *testing.T) {
+		fakeNpm(t, "echo 'E403 forbidden' >&2; exit 1")
+		_, err := runNpm(ctx, t.TempDir(), "publish")
+		if err == nil || !strings.Contains(err.Error(), "E403 forbidden") {
+			t.Errorf("expected stderr in error, got %v", err)
+		}
+	})
+}
+
+func TestRegistryArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"empty", Config{}, ""},
+		{"registry", Config{Registry: "https://npm.example.com"}, "--registry https://npm.example.com"},
+		{"publish_url_overrides", Config{Registry: "https://npm.example.com/meta", PublishURL: "https://npm.example.com/publish"}, "--registry https://npm.example.com/publish"},
+		{"otp", Config{OTP: "123456"}, "--otp 123456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(registryArgs(&tt.cfg), " "); got != tt.want {
+				t.Errorf("registryArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNpmConfigArgs(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+	wd, _ := os.Getwd()
+
+	cfg := &Config{UserConfig: "ci.npmrc", GlobalConfig: "/etc/npmrc"}
+	want := "--userconfig " + filepath.Join(wd, "ci.npmrc") + " --globalconfig /etc/npmrc"
+	if got := strings.Join(npmConfigArgs(cfg), " "); got != want {
+		t.Errorf("npmConfigArgs() = %q, want %q", got, want)
+	}
+	if args := npmConfigArgs(&Config{}); len(args) != 0 {
+		t.Errorf("expected no args, got %v", args)
+	}
+}
+
+func TestSetFlag(t *testing.T) {
+	args := []string{"publish", "--userconfig", "a", "--tag", "next"}
+	if got := setFlag(args, "--userconfig", "b"); strings.Join(got, " ") != "publish --userconfig b --tag next" {
+		t.Errorf("setFlag() = %v, want the value replaced", got)
+	}
+	if got := setFlag([]string{"publish"}, "--userconfig", "b"); strings.Join(got, " ") != "publish --userconfig b" {
+		t.Errorf("setFlag() = %v, want the flag appended", got)
+	}
+}
+
+func TestValidateNpmrcPath(t *testing.T) {
+	dir := t.TempDir()
+	npmrc := filepath.Join(dir, ".npmrc")
+	if err := os.WriteFile(npmrc, []byte("registry=https://npm.example.com/\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{"", false},
+		{npmrc, false},
+		{filepath.Join(dir, "missing.npmrc"), false},
+		{dir, true},
+		{"--registry=https://evil.example.com", true},
+		{"ci.npmrc\n--otp", true},
+	}
+
+	for _, tt := range tests {
+		if err := validateNpmrcPath(tt.path); (err != nil) != tt.wantErr {
+			t.Errorf("validateNpmrcPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultNpmrcTokenEnv is the variable the ephemeral npmrc reads the token
+// from by default.
+const defaultNpmrcTokenEnv = "NPM_TOKEN"
+
+// EphemeralNpmrc replaces the runner's npmrc with a temporary one holding
+// only an auth token scoped to the publish registry.
+type EphemeralNpmrc struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// TokenEnv names the environment variable holding the token (default
+	// NPM_TOKEN). The npmrc references the variable, so the token itself
+	// is never written to disk.
+	TokenEnv string `json:"token_env,omitempty"`
+	// Token is a literal token, written to the file with owner-only
+	// permissions. Prefer TokenEnv.
+	Token string `json:"token,omitempty"`
+}
+
+// validateEphemeralNpmrc checks the token source and that no userconfig is
+// configured alongside.
+func validateEphemeralNpmrc(e EphemeralNpmrc, userConfig string) error {
+	if !e.Enabled {
+		if e.TokenEnv != "" || e.Token != "" {
+			return fmt.Errorf("enabled is required")
+		}
+		return nil
+	}
+	if userConfig != "" {
+		return fmt.Errorf("cannot be combined with userconfig")
+	}
+	if e.TokenEnv != "" && e.Token != "" {
+		return fmt.Errorf("token_env and token are mutually exclusive")
+	}
+	if e.TokenEnv != "" && !inputNamePattern.MatchString(e.TokenEnv) {
+		return fmt.Errorf("token_env %q is not a valid environment variable name", e.TokenEnv)
+	}
+	if strings.ContainsAny(e.Token, "\r\n") {
+		return fmt.Errorf("token must be a single line")
+	}
+	return nil
+}
+
+// tokenEnv returns the variable the token is read from.
+func (e EphemeralNpmrc) tokenEnv() string {
+	if e.TokenEnv != "" {
+		return e.TokenEnv
+	}
+	return defaultNpmrcTokenEnv
+}
+
+// token returns the configured token, or "" when disabled.
+func (e EphemeralNpmrc) token() string {
+	if !e.Enabled {
+		return ""
+	}
+	if e.Token != "" {
+		return e.Token
+	}
+	return os.Getenv(e.tokenEnv())
+}
+
+// npmrcRegistries returns the registries npm writes to: the publish
+// registry, the registry used for lookups, after a regional override the
+// primary registry failover publishes to, and the scope registries.
+func npmrcRegistries(cfg *Config) []string {
+	var registries []string
+	candidates := []string{publishRegistry(cfg), registryURL(cfg), cfg.primaryRegistry}
+	for _, s := range cfg.ScopeRegistries {
+		candidates = append(candidates, s.Registry)
+	}
+	for _, r := range candidates {
+		if r != "" && !containsString(registries, r) {
+			registries = append(registries, r)
+		}
+	}
+	if len(registries) == 0 {
+		registries = append(registries, defaultRegistry)
+	}
+	return registries
+}
+
+// npmrcToken returns the _authToken value for registry: a reference to the
+// token_env of a scope routed there, otherwise ephemeral_npmrc's token.
+func npmrcToken(cfg *Config, registry string) string {
+	for _, s := range cfg.ScopeRegistries {
+		if s.TokenEnv != "" && sameRegistry(s.Registry, registry) {
+			return "${" + s.TokenEnv + "}"
+		}
+	}
+	if cfg.EphemeralNpmrc.Token != "" {
+		return cfg.EphemeralNpmrc.Token
+	}
+	return "${" + cfg.EphemeralNpmrc.tokenEnv() + "}"
+}
+
+// ephemeralNpmrcContent renders the npmrc: a registry line per routed scope
+// and one _authToken line per registry, scoped to its host and path so each
+// token is never sent elsewhere.
+func ephemeralNpmrcContent(cfg *Config) string {
+	var b strings.Builder
+	for _, s := range scopeRegistries(cfg) {
+		fmt.Fprintf(&b, "%s:registry=%s\n", s.Scope, s.Registry)
+	}
+	for _, registry := range npmrcRegistries(cfg) {
+		// registryAuthArg yields "--//host/path/:_authToken=<value>"
+		b.WriteString(strings.TrimPrefix(registryAuthArg(registry, npmrcToken(cfg, registry)), "--"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// writeEphemeralNpmrc writes the ephemeral npmrc to a private temporary
+// directory and points npm at it through cfg.UserConfig. The returned func
+// deletes it; callers defer it. A missing token is an error unless dryRun.
+func writeEphemeralNpmrc(cfg *Config, dryRun bool) (func() error, error) {
+	if cfg.EphemeralNpmrc.token() == "" && !dryRun {
+		return nil, fmt.Errorf("%s is not set", cfg.EphemeralNpmrc.tokenEnv())
+	}
+	dir, err := os.MkdirTemp("", "relicta-npmrc-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create npmrc directory: %w", err)
+	}
+	cleanup := func() error {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove ephemeral npmrc: %w", err)
+		}
+		return nil
+	}
+	path := filepath.Join(dir, ".npmrc")
+	if err := os.WriteFile(path, []byte(ephemeralNpmrcContent(cfg)), 0600); err != nil {
+		_ = cleanup()
+		return nil, fmt.Errorf("failed to write ephemeral npmrc: %w", err)
+	}
+	cfg.UserConfig = path
+	return cleanup, nil
+}
+
+// useEphemeralNpmrc writes the ephemeral npmrc when enabled and returns a
+// func deleting it, which callers defer. Cleanup failures become warnings.
+func useEphemeralNpmrc(cfg *Config, outputs map[string]any, dryRun bool) (func(), error) {
+	if !cfg.EphemeralNpmrc.Enabled {
+		return func() {}, nil
+	}
+	cleanup, err := writeEphemeralNpmrc(cfg, dryRun)
+	if err != nil {
+		return nil, fmt.Errorf("ephemeral npmrc: %w", err)
+	}
+	outputs["ephemeral_npmrc"] = true
+	return func() {
+		if err := cleanup(); err != nil {
+			appendWarning(outputs, err.Error())
+		}
+	}, nil
+}
+
+// userNpmrc returns the user npmrc npm reads: the configured userconfig,
+// then npm_config_userconfig, else ~/.npmrc.
+func userNpmrc(cfg *Config) string {
+	if cfg.UserConfig != "" {
+		return cfg.UserConfig
+	}
+	for _, env := range []string{"npm_config_userconfig", "NPM_CONFIG_USERCONFIG"} {
+		if path := os.Getenv(env); path != "" {
+			return path
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".npmrc")
+}
+
+// useAuthToken authenticates later npm calls to registry with a token minted
+// for this publish, keeping it out of npm's arguments. It writes an
+// owner-only copy of the user npmrc with the token's _authToken line
+// appended, so the rest of the user config stays in effect and the token
+// overrides any earlier one, and points cfg.UserConfig at it. The returned
+// func deletes the copy; callers defer it.
+func useAuthToken(cfg *Config, registry, token string) (func() error, error) {
+	var base []byte
+	if path := userNpmrc(cfg); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read npmrc: %w", err)
+		}
+		base = data
+	}
+	dir, err := os.MkdirTemp("", "relicta-npmrc-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create npmrc directory: %w", err)
+	}
+	cleanup := func() error {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove token npmrc: %w", err)
+		}
+		return nil
+	}
+	var b strings.Builder
+	b.Write(base)
+	if len(base) > 0 && base[len(base)-1] != '\n' {
+		b.WriteString("\n")
+	}
+	b.WriteString(strings.TrimPrefix(registryAuthArg(registry, token), "--"))
+	b.WriteString("\n")
+	path := filepath.Join(dir, ".npmrc")
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		_ = cleanup()
+		return nil, fmt.Errorf("failed to write token npmrc: %w", err)
+	}
+	cfg.UserConfig = path
+	cfg.authToken = token
+	return cleanup, nil
+}
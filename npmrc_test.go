@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateEphemeralNpmrc(t *testing.T) {
+	tests := []struct {
+		name       string
+		e          EphemeralNpmrc
+		userConfig string
+		wantErr    bool
+	}{
+		{name: "unset"},
+		{name: "enabled", e: EphemeralNpmrc{Enabled: true}},
+		{name: "token env", e: EphemeralNpmrc{Enabled: true, TokenEnv: "GITHUB_TOKEN"}},
+		{name: "token", e: EphemeralNpmrc{Enabled: true, Token: "npm_abc"}},
+		{name: "not enabled", e: EphemeralNpmrc{TokenEnv: "GITHUB_TOKEN"}, wantErr: true},
+		{name: "userconfig", e: EphemeralNpmrc{Enabled: true}, userConfig: ".npmrc", wantErr: true},
+		{name: "both sources", e: EphemeralNpmrc{Enabled: true, TokenEnv: "X", Token: "y"}, wantErr: true},
+		{name: "bad env name", e: EphemeralNpmrc{Enabled: true, TokenEnv: "NPM-TOKEN"}, wantErr: true},
+		{name: "multiline token", e: EphemeralNpmrc{Enabled: true, Token: "a\nregistry=https://evil.example.com/"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateEphemeralNpmrc(tt.e, tt.userConfig); (err != nil) != tt.wantErr {
+				t.Errorf("validateEphemeralNpmrc() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEphemeralNpmrcContent(t *testing.T) {
+	cfg := &Config{
+		Registry:       "https://npm-eu.example.com/",
+		PublishURL:     "https://npm.example.com/publish/",
+		EphemeralNpmrc: EphemeralNpmrc{Enabled: true},
+	}
+	want := "//npm.example.com/publish/:_authToken=${NPM_TOKEN}\n//npm-eu.example.com/:_authToken=${NPM_TOKEN}\n"
+	if got := ephemeralNpmrcContent(cfg); got != want {
+		t.Errorf("ephemeralNpmrcContent() =\n%s\nwant\n%s", got, want)
+	}
+
+	cfg = &Config{EphemeralNpmrc: EphemeralNpmrc{Enabled: true, Token: "npm_abc"}}
+	if got := ephemeralNpmrcContent(cfg); got != "//registry.npmjs.org/:_authToken=npm_abc\n" {
+		t.Errorf("ephemeralNpmrcContent() = %q", got)
+	}
+}
+
+func TestEphemeralNpmrcPublish(t *testing.T) {
+	npmrcLog := filepath.Join(t.TempDir(), "npmrc")
+	logPath := fakeNpm(t, `if [ "$1" = publish ]; then
+  while [ $# -gt 0 ]; do
+    if [ "$1" = --userconfig ]; then echo "$2" > `+npmrcLog+`.path; cat "$2" > `+npmrcLog+`; fi
+    shift
+  done
+fi
+echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("PUBLISH_TOKEN", "npm_secret")
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"registry":        "https://npm.example.com/",
+			"ephemeral_npmrc": map[string]any{"enabled": true, "token_env": "PUBLISH_TOKEN"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if len(npmCalls(t, logPath)) == 0 {
+		t.Fatal("npm was not called")
+	}
+	content, err := os.ReadFile(npmrcLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "//npm.example.com/:_authToken=${PUBLISH_TOKEN}\n" {
+		t.Errorf("npmrc = %q", content)
+	}
+	path, _ := os.ReadFile(npmrcLog + ".path")
+	if _, err := os.Stat(strings.TrimSpace(string(path))); !os.IsNotExist(err) {
+		t.Errorf("ephemeral npmrc %s not removed: %v", path, err)
+	}
+	if resp.Outputs["ephemeral_npmrc"] != true {
+		t.Errorf("outputs = %v", resp.Outputs)
+	}
+}
+
+func TestEphemeralNpmrcMissingToken(t *testing.T) {
+	fakeNpm(t, `echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("NPM_TOKEN", "")
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"ephemeral_npmrc": map[string]any{"enabled": true}},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "NPM_TOKEN is not set") {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestUseAuthToken(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	base := filepath.Join(t.TempDir(), "ci.npmrc")
+	writeFile(t, base, "//npm.example.com/:_authToken=${NPM_TOKEN}\nfund=false")
+	cfg := &Config{UserConfig: base}
+	cleanup, err := useAuthToken(cfg, "https://npm.example.com/", "npm_minted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(cfg.UserConfig)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("token npmrc = %v, %v; want an owner-only file", info, err)
+	}
+	content, _ := os.ReadFile(cfg.UserConfig)
+	want := "//npm.example.com/:_authToken=${NPM_TOKEN}\nfund=false\n//npm.example.com/:_authToken=npm_minted\n"
+	if string(content) != want {
+		t.Errorf("token npmrc = %q, want %q", content, want)
+	}
+	if cfg.authToken != "npm_minted" || apiAuthToken(cfg) != "npm_minted" {
+		t.Errorf("authToken = %q, want the minted token", cfg.authToken)
+	}
+	if err := cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cfg.UserConfig); !os.IsNotExist(err) {
+		t.Errorf("token npmrc survived cleanup: %v", err)
+	}
+
+	t.Setenv("npm_config_userconfig", filepath.Join(t.TempDir(), "missing"))
+	cfg = &Config{}
+	cleanup, err = useAuthToken(cfg, "https://registry.npmjs.org/", "npm_minted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cleanup() }()
+	if content, _ := os.ReadFile(cfg.UserConfig); string(content) != "//registry.npmjs.org/:_authToken=npm_minted\n" {
+		t.Errorf("token npmrc without a user npmrc = %q", content)
+	}
+}
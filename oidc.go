@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gitLabIDTokenEnv holds the OIDC ID token a GitLab job declares under
+// id_tokens for npm trusted publishing.
+const gitLabIDTokenEnv = "NPM_ID_TOKEN"
+
+// trustedTokenTTL bounds how long an exchanged publish token is reused when
+// the registry does not say when it expires.
+const trustedTokenTTL = 5 * time.Minute
+
+// trustedTokenMargin is how much lifetime a cached token must have left to be
+// reused, so it does not expire mid-publish.
+const trustedTokenMargin = 30 * time.Second
+
+// trustedToken is a publish token obtained by OIDC exchange.
+type trustedToken struct {
+	token   string
+	expires time.Time
+}
+
+// trustedTokens caches exchanged tokens per registry and package, so
+// retries and repeated hooks in one process do not exchange again.
+var trustedTokens = struct {
+	sync.Mutex
+	m map[string]trustedToken
+}{m: map[string]trustedToken{}}
+
+// oidcAudience returns the audience npm expects on ID tokens for registry,
+// e.g. "npm:registry.npmjs.org".
+func oidcAudience(registry string) string {
+	if u, err := url.Parse(registry); err == nil && u.Host != "" {
+		return "npm:" + u.Host
+	}
+	return "npm:" + strings.TrimSuffix(registry, "/")
+}
+
+// oidcExchangeURL returns the registry endpoint trading an ID token for a
+// publish token for the named package.
+func oidcExchangeURL(registry, name string) string {
+	return strings.TrimSuffix(registry, "/") + "/-/npm/v1/oidc/token/exchange/package/" + url.PathEscape(name)
+}
+
+// trustedPublishingProvider reports whether the CI job can mint an ID token
+// for trusted publishing: `id-token: write` on GitHub Actions, an id_tokens
+// entry named NPM_ID_TOKEN on GitLab.
+func trustedPublishingProvider() (string, error) {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		if os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL") == "" || os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN") == "" {
+			return "", fmt.Errorf("trusted publishing on GitHub Actions requires the id-token: write permission")
+		}
+		return provenanceGitHubActions, nil
+	case os.Getenv("GITLAB_CI") == "true":
+		if os.Getenv(gitLabIDTokenEnv) == "" {
+			return "", fmt.Errorf("trusted publishing on GitLab CI requires an id_tokens entry named %s", gitLabIDTokenEnv)
+		}
+		return provenanceGitLab, nil
+	}
+	return "", fmt.Errorf("trusted publishing requires GitHub Actions or GitLab CI with OIDC enabled")
+}
+
+// ciIDToken returns an OIDC ID token for audience from the CI provider.
+func ciIDToken(ctx context.Context, audience string) (string, error) {
+	provider, err := trustedPublishingProvider()
+	if err != nil {
+		return "", err
+	}
+	if provider == provenanceGitLab {
+		return os.Getenv(gitLabIDTokenEnv), nil
+	}
+
+	u, err := url.Parse(os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"))
+	if err != nil {
+		return "", fmt.Errorf("invalid ACTIONS_ID_TOKEN_REQUEST_URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("audience", audience)
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create ID token request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN"))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ID token request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("GitHub returned %d for the ID token: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var body struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode ID token response: %w", err)
+	}
+	if body.Value == "" {
+		return "", fmt.Errorf("GitHub returned an empty ID token")
+	}
+	return body.Value, nil
+}
+
+// jwtExpiry returns the exp claim of a JWT. The signature is not checked;
+// the registry does that, this only avoids exchanging an expired token.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("ID token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid ID token payload: %w", err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("invalid ID token claims: %w", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, fmt.Errorf("ID token has no expiry")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
+
+// exchangeIDToken trades an ID token for a publish token for one package.
+func exchangeIDToken(ctx context.Context, registry, idToken, name string) (trustedToken, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, oidcExchangeURL(registry, name), nil)
+	if err != nil {
+		return trustedToken{}, fmt.Errorf("failed to create exchange request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+idToken)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return trustedToken{}, fmt.Errorf("exchange request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return trustedToken{}, fmt.Errorf("registry returned %d exchanging the ID token for %s (is a trusted publisher configured?): %s", resp.StatusCode, name, strings.TrimSpace(string(msg)))
+	}
+	var body struct {
+		Token   string    `json:"token"`
+		Expires time.Time `json:"expires"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return trustedToken{}, fmt.Errorf("failed to decode exchange response: %w", err)
+	}
+	if body.Token == "" {
+		return trustedToken{}, fmt.Errorf("registry returned no token")
+	}
+	expires := time.Now().Add(trustedTokenTTL)
+	if !body.Expires.IsZero() && body.Expires.Before(expires) {
+		expires = body.Expires
+	}
+	return trustedToken{token: body.Token, expires: expires}, nil
+}
+
+// trustedPublishToken returns a publish token for name obtained through
+// npm's trusted publisher flow, reusing a cached one while it has enough
+// lifetime left.
+func trustedPublishToken(ctx context.Context, cfg *Config, name string) (string, error) {
+	registry := publishRegistry(cfg)
+	if registry == "" {
+		registry = defaultRegistry
+	}
+	key := registry + " " + name
+
+	trustedTokens.Lock()
+	defer trustedTokens.Unlock()
+	if tok, ok := trustedTokens.m[key]; ok && time.Until(tok.expires) > trustedTokenMargin {
+		return tok.token, nil
+	}
+	delete(trustedTokens.m, key)
+
+	idToken, err := ciIDToken(ctx, oidcAudience(registry))
+	if err != nil {
+		return "", err
+	}
+	exp, err := jwtExpiry(idToken)
+	if err != nil {
+		return "", err
+	}
+	if time.Until(exp) <= 0 {
+		return "", fmt.Errorf("ID token expired at %s", exp.UTC().Format(time.RFC3339))
+	}
+	tok, err := exchangeIDToken(ctx, registry, idToken, name)
+	if err != nil {
+		return "", err
+	}
+	trustedTokens.m[key] = tok
+	return tok.token, nil
+}
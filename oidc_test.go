@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// testJWT returns an unsigned JWT expiring at exp.
+func testJWT(exp time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+	return "eyJhbGciOiJub25lIn0." + payload + ".sig"
+}
+
+// oidcServer fakes both the GitHub ID token endpoint and the registry's
+// exchange endpoint, counting exchanges.
+func oidcServer(t *testing.T, idToken string, exchangeStatus int) (*httptest.Server, *int) {
+	t.Helper()
+	var mu sync.Mutex
+	exchanges := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/idtoken":
+			if r.Header.Get("Authorization") != "Bearer request-token" || !strings.HasPrefix(r.URL.Query().Get("audience"), "npm:127.0.0.1") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"value":%q}`, idToken)
+		case r.Method == http.MethodPost && r.URL.EscapedPath() == "/-/npm/v1/oidc/token/exchange/package/@acme%2Flib":
+			if r.Header.Get("Authorization") != "Bearer "+idToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			exchanges++
+			w.WriteHeader(exchangeStatus)
+			_, _ = w.Write([]byte(`{"token":"npm_oidc"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		trustedTokens.Lock()
+		trustedTokens.m = map[string]trustedToken{}
+		trustedTokens.Unlock()
+	})
+	setCIEnv(t, map[string]string{
+		"GITHUB_ACTIONS":                 "true",
+		"ACTIONS_ID_TOKEN_REQUEST_URL":   server.URL + "/idtoken?api-version=2.0",
+		"ACTIONS_ID_TOKEN_REQUEST_TOKEN": "request-token",
+	})
+	return server, &exchanges
+}
+
+func TestOIDCAudience(t *testing.T) {
+	if got := oidcAudience("https://registry.npmjs.org/"); got != "npm:registry.npmjs.org" {
+		t.Errorf("oidcAudience() = %q", got)
+	}
+}
+
+func TestTrustedPublishing(t *testing.T) {
+	server, exchanges := oidcServer(t, testJWT(time.Now().Add(time.Hour)), http.StatusCreated)
+	npmrcLog := filepath.Join(t.TempDir(), "npmrc")
+	logPath := fakeNpm(t, `while [ $# -gt 0 ]; do
+  if [ "$1" = --userconfig ]; then cat "$2" >> `+npmrcLog+`; fi
+  shift
+done
+echo '{"id":"@acme/lib@1.0.0"}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"@acme/lib","version":"1.0.0"}`)
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+	userNpmrc := filepath.Join(t.TempDir(), ".npmrc")
+	writeFile(t, userNpmrc, "fund=false")
+	t.Setenv("npm_config_userconfig", userNpmrc)
+
+	for i := 0; i < 2; i++ {
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"registry": server.URL, "trusted_publishing": true},
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+		})
+		if err != nil || !resp.Success || resp.Outputs["trusted_publishing"] != true {
+			t.Fatalf("unexpected failure: %v %+v", err, resp)
+		}
+	}
+	if *exchanges != 1 {
+		t.Errorf("exchanges = %d, want the cached token reused", *exchanges)
+	}
+	calls := npmCalls(t, logPath)
+	if len(calls) != 2 || strings.Contains(strings.Join(calls, "\n"), "npm_oidc") {
+		t.Errorf("npm calls = %v, want two publishes without the token in their arguments", calls)
+	}
+	npmrc, err := os.ReadFile(npmrcLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "fund=false\n" + strings.TrimPrefix(registryAuthArg(server.URL, "npm_oidc"), "--") + "\n"
+	if string(npmrc) != want+want {
+		t.Errorf("publish npmrc = %q, want the user npmrc plus the exchanged token", npmrc)
+	}
+	if left, _ := filepath.Glob(filepath.Join(os.Getenv("TMPDIR"), "relicta-npmrc-*")); len(left) != 0 {
+		t.Errorf("token npmrc left behind: %v", left)
+	}
+}
+
+func TestTrustedPublishingFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		idToken string
+		status  int
+		wantErr string
+	}{
+		{name: "expired ID token", idToken: testJWT(time.Now().Add(-time.Minute)), status: http.StatusCreated, wantErr: "ID token expired"},
+		{name: "no trusted publisher", idToken: testJWT(time.Now().Add(time.Hour)), status: http.StatusForbidden, wantErr: "is a trusted publisher configured"},
+		{name: "not a JWT", idToken: "opaque", status: http.StatusCreated, wantErr: "not a JWT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := oidcServer(t, tt.idToken, tt.status)
+			logPath := fakeNpm(t, `echo '{}'`)
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "package.json"), `{"name":"@acme/lib","version":"1.0.0"}`)
+			chdir(t, dir)
+
+			resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  map[string]any{"registry": server.URL, "trusted_publishing": true},
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil || resp.Success || !strings.Contains(resp.Error, tt.wantErr) {
+				t.Fatalf("expected error %q, got %v %+v", tt.wantErr, err, resp)
+			}
+			if calls := npmCalls(t, logPath); len(calls) != 0 {
+				t.Errorf("npm ran without a publish token: %v", calls)
+			}
+		})
+	}
+}
+
+func TestValidateTrustedPublishing(t *testing.T) {
+	setCIEnv(t, map[string]string{"GITLAB_CI": "true"})
+	t.Setenv(gitLabIDTokenEnv, "")
+	p := &NpmPlugin{}
+	if err := p.validateConfig(p.parseConfig(map[string]any{"trusted_publishing": true})); err == nil || !strings.Contains(err.Error(), gitLabIDTokenEnv) {
+		t.Errorf("validateConfig() error = %v, want missing %s", err, gitLabIDTokenEnv)
+	}
+	t.Setenv(gitLabIDTokenEnv, "t")
+	if err := p.validateConfig(p.parseConfig(map[string]any{"trusted_publishing": true, "token_exchange": true})); err == nil {
+		t.Error("validateConfig() accepted token_exchange with trusted_publishing")
+	}
+}
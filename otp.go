@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// OTPPolicy configures which one-time passwords are accepted. The zero
+// value is npm's 6-8 digit TOTP format.
+type OTPPolicy struct {
+	// MinLength and MaxLength bound the OTP length (defaults 6 and 8).
+	MinLength int `json:"min_length,omitempty"`
+	MaxLength int `json:"max_length,omitempty"`
+	// Alphanumeric accepts letters as well as digits.
+	Alphanumeric bool `json:"alphanumeric,omitempty"`
+	// BypassMarker is an OTP value meaning "no code": --otp is not passed
+	// and the registry's own flow (e.g. WebAuthn or SSO) authorizes writes.
+	BypassMarker string `json:"bypass_marker,omitempty"`
+}
+
+// bounds returns the effective length range.
+func (p OTPPolicy) bounds() (int, int) {
+	lo, hi := p.MinLength, p.MaxLength
+	if lo == 0 {
+		lo = 6
+	}
+	if hi == 0 {
+		hi = 8
+		if lo > hi {
+			hi = lo
+		}
+	}
+	return lo, hi
+}
+
+// validateOTPPolicy checks the policy itself is sensible.
+func validateOTPPolicy(p OTPPolicy) error {
+	lo, hi := p.bounds()
+	if lo < 4 || hi > 64 || lo > hi {
+		return fmt.Errorf("OTP length range %d-%d must be within 4-64", lo, hi)
+	}
+	if p.BypassMarker != "" && !tagPattern.MatchString(p.BypassMarker) {
+		return fmt.Errorf("bypass_marker %q must be alphanumeric (hyphens, underscores and dots allowed)", p.BypassMarker)
+	}
+	return nil
+}
+
+// validateOTPWithPolicy validates an OTP against the policy.
+func validateOTPWithPolicy(otp string, p OTPPolicy) error {
+	if otp == "" || (p.BypassMarker != "" && otp == p.BypassMarker) {
+		return nil
+	}
+	lo, hi := p.bounds()
+	class, desc := `\d`, "digits"
+	if p.Alphanumeric {
+		class, desc = `[A-Za-z0-9]`, "letters or digits"
+	}
+	if !regexp.MustCompile(fmt.Sprintf(`^%s{%d,%d}$`, class, lo, hi)).MatchString(otp) {
+		return fmt.Errorf("OTP must be %d-%d %s", lo, hi, desc)
+	}
+	return nil
+}
+
+// otpArgs returns the --otp flag, omitted when the OTP is the bypass marker.
+func otpArgs(cfg *Config) []string {
+	if cfg.OTP == "" || (cfg.OTPPolicy.BypassMarker != "" && cfg.OTP == cfg.OTPPolicy.BypassMarker) {
+		return nil
+	}
+	return []string{"--otp", cfg.OTP}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestValidateOTPWithPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		otp     string
+		policy  OTPPolicy
+		wantErr bool
+	}{
+		{"default accepts 6 digits", "123456", OTPPolicy{}, false},
+		{"default rejects 10 digits", "1234567890", OTPPolicy{}, true},
+		{"widened range", "1234567890", OTPPolicy{MaxLength: 10}, false},
+		{"raised minimum", "123456", OTPPolicy{MinLength: 7, MaxLength: 8}, true},
+		{"minimum above default max", "1234567890", OTPPolicy{MinLength: 10}, false},
+		{"letters rejected by default", "abc123", OTPPolicy{}, true},
+		{"alphanumeric", "abc123", OTPPolicy{Alphanumeric: true}, false},
+		{"alphanumeric rejects symbols", "abc-12", OTPPolicy{Alphanumeric: true}, true},
+		{"bypass marker", "webauthn", OTPPolicy{BypassMarker: "webauthn"}, false},
+		{"empty", "", OTPPolicy{MinLength: 10}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOTPWithPolicy(tt.otp, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateOTPWithPolicy(%q) error = %v, wantErr %v", tt.otp, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateOTPPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  OTPPolicy
+		wantErr bool
+	}{
+		{"default", OTPPolicy{}, false},
+		{"custom", OTPPolicy{MinLength: 4, MaxLength: 12}, false},
+		{"inverted", OTPPolicy{MinLength: 9, MaxLength: 7}, true},
+		{"too short", OTPPolicy{MinLength: 2}, true},
+		{"too long", OTPPolicy{MaxLength: 100}, true},
+		{"bad marker", OTPPolicy{BypassMarker: "--registry"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateOTPPolicy(tt.policy); (err != nil) != tt.wantErr {
+				t.Errorf("validateOTPPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOTPArgs(t *testing.T) {
+	cfg := &Config{OTP: "123456"}
+	if got := otpArgs(cfg); !reflect.DeepEqual(got, []string{"--otp", "123456"}) {
+		t.Errorf("otpArgs() = %v", got)
+	}
+	cfg = &Config{OTP: "webauthn", OTPPolicy: OTPPolicy{BypassMarker: "webauthn"}}
+	if got := otpArgs(cfg); got != nil {
+		t.Errorf("otpArgs() with bypass marker = %v, want nil", got)
+	}
+}
+
+func TestOTPPolicyValidate(t *testing.T) {
+	p := &NpmPlugin{}
+	tests := []struct {
+		name   string
+		config map[string]any
+		valid  bool
+	}{
+		{"long otp rejected by default", map[string]any{"otp": "1234567890"}, false},
+		{"long otp allowed by policy", map[string]any{"otp": "1234567890", "otp_policy": map[string]any{"max_length": 10}}, true},
+		{"bypass marker", map[string]any{"otp": "sso", "otp_policy": map[string]any{"bypass_marker": "sso"}}, true},
+		{"invalid policy", map[string]any{"otp_policy": map[string]any{"min_length": 9, "max_length": 7}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := p.Validate(context.Background(), tt.config)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if resp.Valid != tt.valid {
+				t.Errorf("Validate() valid = %v, want %v (%v)", resp.Valid, tt.valid, resp.Errors)
+			}
+		})
+	}
+}
+
+func TestOTPFromEnv(t *testing.T) {
+	t.Setenv("NPM_OTP", "654321")
+	p := &NpmPlugin{}
+	cfg := p.parseConfig(map[string]any{})
+	if got := otpArgs(cfg); !reflect.DeepEqual(got, []string{"--otp", "654321"}) {
+		t.Errorf("otpArgs() = %v, want the NPM_OTP code", got)
+	}
+	if cfg := p.parseConfig(map[string]any{"otp": "123456"}); cfg.OTP != "123456" {
+		t.Errorf("OTP = %q, want the configured code over NPM_OTP", cfg.OTP)
+	}
+
+	t.Setenv("NPM_OTP", "1234567890")
+	resp, err := p.Validate(context.Background(), map[string]any{})
+	if err != nil || resp.Valid {
+		t.Errorf("Validate() = %+v, %v; want the NPM_OTP code rejected", resp, err)
+	}
+	if err := p.validateConfig(p.parseConfig(map[string]any{})); err == nil {
+		t.Error("validateConfig() accepted the NPM_OTP code Validate rejects")
+	}
+	resp, err = p.Validate(context.Background(), map[string]any{"otp_policy": map[string]any{"max_length": 10}})
+	if err != nil || !resp.Valid {
+		t.Errorf("Validate() = %+v, %v; want the NPM_OTP code allowed by policy", resp, err)
+	}
+}
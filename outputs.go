@@ -0,0 +1,31 @@
+package main
+
+import "fmt"
+
+// checkExpectedOutputs verifies that each glob matches at least one packed
+// file and that every matched file is non-empty, catching builds that
+// silently produced nothing.
+func checkExpectedOutputs(files []packFile, globs []string) ([]string, error) {
+	res, err := compileGlobs("expected_outputs", globs)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for i, re := range res {
+		matched := 0
+		for _, f := range files {
+			if !re.MatchString(f.Path) {
+				continue
+			}
+			matched++
+			if f.Size == 0 {
+				problems = append(problems, fmt.Sprintf("%s is empty", f.Path))
+			}
+		}
+		if matched == 0 {
+			problems = append(problems, fmt.Sprintf("no packed files match %q", globs[i]))
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestCheckExpectedOutputs(t *testing.T) {
+	files := []packFile{
+		{Path: "dist/index.min.js", Size: 120},
+		{Path: "dist/esm/index.min.js", Size: 0},
+		{Path: "package.json", Size: 80},
+	}
+
+	problems, err := checkExpectedOutputs(files, []string{"dist/**/*.min.js", "dist/**/*.d.ts"})
+	if err != nil {
+		t.Fatalf("checkExpectedOutputs returned error: %v", err)
+	}
+	want := []string{"dist/esm/index.min.js is empty", `no packed files match "dist/**/*.d.ts"`}
+	if strings.Join(problems, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems = %v, want %v", problems, want)
+	}
+
+	if problems, _ := checkExpectedOutputs(files, []string{"dist/*.min.js"}); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
+
+func TestPublishExpectedOutputs(t *testing.T) {
+	p := &NpmPlugin{}
+	tmpDir := t.TempDir()
+	writePackFiles(t, tmpDir, map[string]string{"package.json": `{"name":"pkg","version":"1.0.0"}`})
+	chdir(t, tmpDir)
+	fakeNpm(t, `echo '[{"name":"pkg","files":[{"path":"package.json","size":30}]}]'`)
+
+	cfg := &Config{Tag: "latest", ExpectedOutputs: []string{"dist/**/*.min.js"}}
+	resp, err := p.publishPackage(context.Background(), cfg, plugin.ReleaseContext{Version: "1.0.0"}, true)
+	if err != nil {
+		t.Fatalf("publishPackage returned error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "expected build outputs missing") {
+		t.Errorf("expected missing outputs failure, got %+v", resp)
+	}
+}
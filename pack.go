@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// scriptArgs returns the lifecycle-script flags shared by pack and publish.
+func scriptArgs(cfg *Config) []string {
+	var args []string
+	if cfg.IgnoreScripts {
+		args = append(args, "--ignore-scripts")
+	}
+	if cfg.ForegroundScripts {
+		args = append(args, "--foreground-scripts")
+	}
+	return args
+}
+
+// packArgs returns the npm pack arguments writing the tarball to dest.
+func packArgs(cfg *Config, dest string) []string {
+	args := append([]string{"pack", "--json", "--pack-destination", dest}, npmConfigArgs(cfg)...)
+	return append(args, scriptArgs(cfg)...)
+}
+
+// packTarball runs npm pack in packageDir, writing the tarball to dest, and
+// returns the pack result and the tarball path.
+func packTarball(ctx context.Context, cfg *Config, packageDir, dest string) (publishResult, string, error) {
+	absDest, err := filepath.Abs(dest)
+	if err != nil {
+		return publishResult{}, "", fmt.Errorf("invalid pack destination: %w", err)
+	}
+	if err := os.MkdirAll(absDest, 0755); err != nil {
+		return publishResult{}, "", fmt.Errorf("failed to create pack destination: %w", err)
+	}
+
+	stdout, err := runScriptedNpm(ctx, cfg, packageDir, packArgs(cfg, absDest)...)
+	if err != nil {
+		return publishResult{}, "", err
+	}
+
+	var results []publishResult
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil || len(results) == 0 {
+		return publishResult{}, "", fmt.Errorf("failed to parse npm pack output: %q", stdout)
+	}
+
+	result := results[0]
+	return result, filepath.Join(absDest, result.Filename), nil
+}
+
+// needsPackFiles reports whether any pre-publish check inspects the files
+// that will be packed.
+func needsPackFiles(cfg *Config) bool {
+	return cfg.CodeScan != "" || cfg.Sourcemaps != "" || cfg.ModuleCheck != "" || len(cfg.ExpectedOutputs) > 0
+}
+
+// listPackFiles returns the files npm would include in the tarball, using
+// npm pack --dry-run so nothing is written. With publish_method api the
+// plugin's own packer lists them.
+func listPackFiles(ctx context.Context, cfg *Config, packageDir string) ([]packFile, error) {
+	if cfg.PublishMethod == publishMethodAPI {
+		return apiPackFiles(packageDir)
+	}
+	result, err := packDryRun(ctx, cfg, packageDir)
+	if err != nil {
+		return nil, err
+	}
+	return result.Files, nil
+}
+
+// packDryRun runs npm pack --dry-run and returns what npm would pack: the
+// files and the tarball and unpacked sizes.
+func packDryRun(ctx context.Context, cfg *Config, packageDir string) (publishResult, error) {
+	args := append([]string{"pack", "--dry-run", "--json"}, npmConfigArgs(cfg)...)
+	stdout, err := runScriptedNpm(ctx, cfg, packageDir, append(args, scriptArgs(cfg)...)...)
+	if err != nil {
+		return publishResult{}, err
+	}
+
+	var results []publishResult
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil || len(results) == 0 {
+		return publishResult{}, fmt.Errorf("failed to parse npm pack output: %q", stdout)
+	}
+	return results[0], nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestScriptArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"none", Config{}, ""},
+		{"ignore", Config{IgnoreScripts: true}, "--ignore-scripts"},
+		{"both", Config{IgnoreScripts: true, ForegroundScripts: true}, "--ignore-scripts --foreground-scripts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(scriptArgs(&tt.cfg), " "); got != tt.want {
+				t.Errorf("scriptArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishWithPackDestination(t *testing.T) {
+	p := &NpmPlugin{}
+	t.Setenv("TMPDIR", t.TempDir())
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name":"pkg","version":"1.0.0"}`), 0644); err != nil {
+		t.Fatalf("failed to write package.json: %v", err)
+	}
+	chdir(t, tmpDir)
+
+	logPath := fakeNpm(t, `case "$1" in
+pack) echo '[{"name":"pkg","version":"1.0.0","filename":"pkg-1.0.0.tgz","integrity":"sha512-abc"}]' ;;
+publish) echo '{"name":"pkg","version":"1.0.0","integrity":"sha512-abc"}' ;;
+esac`)
+
+	cfg := &Config{PackageDir: ".", Tag: "latest", PackDestination: "artifacts", ForegroundScripts: true, UserConfig: "ci.npmrc"}
+	resp, err := p.publishPackage(context.Background(), cfg, plugin.ReleaseContext{Version: "1.0.0"}, false)
+	if err != nil {
+		t.Fatalf("publishPackage returned error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got error: %s", resp.Error)
+	}
+
+	wantTarball := filepath.Join(tmpDir, "artifacts", "pkg-1.0.0.tgz")
+	if resolved, err := filepath.EvalSymlinks(tmpDir); err == nil {
+		wantTarball = filepath.Join(resolved, "artifacts", "pkg-1.0.0.tgz")
+	}
+	if resp.Outputs["tarball"] != wantTarball {
+		t.Errorf("tarball = %v, want %s", resp.Outputs["tarball"], wantTarball)
+	}
+
+	calls := npmCalls(t, logPath)
+	if len(calls) != 2 {
+		t.Fatalf("expected pack and publish calls, got %v", calls)
+	}
+	if !strings.HasPrefix(calls[0], "pack --json --pack-destination ") || !strings.HasSuffix(calls[0], "--foreground-scripts") {
+		t.Errorf("unexpected pack call: %q", calls[0])
+	}
+	if !strings.HasPrefix(calls[1], "publish "+wantTarball+" --json") || !strings.Contains(calls[1], "--foreground-scripts") {
+		t.Errorf("unexpected publish call: %q", calls[1])
+	}
+	for _, call := range calls {
+		if !strings.Contains(call, "--userconfig ") {
+			t.Errorf("expected --userconfig in %q", call)
+		}
+	}
+}
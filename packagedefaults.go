@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// packageDefaultFields are the settings a package can default in the
+// "relicta.npm" section of its package.json, each copying the parsed value
+// into the run's config. They describe the package itself; settings that
+// shape the whole run (workspaces, hooks, credentials) stay pipeline-level.
+var packageDefaultFields = map[string]func(dst, src *Config){
+	"tag":            func(dst, src *Config) { dst.Tag = src.Tag },
+	"access":         func(dst, src *Config) { dst.Access = src.Access },
+	"registry":       func(dst, src *Config) { dst.Registry = src.Registry },
+	"provenance":     func(dst, src *Config) { dst.Provenance = src.Provenance },
+	"dist_dir":       func(dst, src *Config) { dst.DistDir = src.DistDir },
+	"update_version": func(dst, src *Config) { dst.UpdateVersion = src.UpdateVersion },
+	"skip_existing":  func(dst, src *Config) { dst.SkipExisting = src.SkipExisting },
+	"verify_publish": func(dst, src *Config) { dst.VerifyPublish = src.VerifyPublish },
+}
+
+// readPackageDefaults returns the "relicta.npm" section of the package.json
+// in packageDir, or nil when there is none.
+func readPackageDefaults(packageDir string) (map[string]any, error) {
+	data, err := os.ReadFile(filepath.Join(packageDir, "package.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	var pkg struct {
+		Relicta struct {
+			Npm json.RawMessage `json:"npm"`
+		} `json:"relicta"`
+	}
+	if json.Unmarshal(data, &pkg) != nil || len(pkg.Relicta.Npm) == 0 {
+		return nil, nil
+	}
+	var defaults map[string]any
+	if err := json.Unmarshal(pkg.Relicta.Npm, &defaults); err != nil {
+		return nil, fmt.Errorf("relicta.npm in package.json must be an object")
+	}
+	var unsupported []string
+	for key := range defaults {
+		if _, ok := packageDefaultFields[key]; !ok {
+			unsupported = append(unsupported, key)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return nil, fmt.Errorf("relicta.npm in package.json cannot set %s", strings.Join(unsupported, ", "))
+	}
+	return defaults, nil
+}
+
+// applyPackageDefaults applies the package.json defaults of packageDir
+// beneath the pipeline config: a key the pipeline sets wins, as do a tag
+// chosen by tag_policy or tag_map and a registry chosen by registry_preset.
+// It returns the keys applied.
+func (p *NpmPlugin) applyPackageDefaults(cfg *Config, packageDir string) ([]string, error) {
+	defaults, err := readPackageDefaults(packageDir)
+	if err != nil || len(defaults) == 0 {
+		return nil, err
+	}
+	parsed := p.parseConfig(defaults)
+	if len(parsed.parseErrors) > 0 {
+		return nil, fmt.Errorf("relicta.npm in package.json: %w", errors.Join(parsed.parseErrors...))
+	}
+	var applied []string
+	for key := range defaults {
+		if pipelineSets(cfg, key) {
+			continue
+		}
+		packageDefaultFields[key](cfg, parsed)
+		applied = append(applied, key)
+	}
+	sort.Strings(applied)
+	return applied, nil
+}
+
+// pipelineSets reports whether the pipeline config decides key.
+func pipelineSets(cfg *Config, key string) bool {
+	if _, set := cfg.raw[key]; set {
+		return true
+	}
+	switch key {
+	case "tag":
+		return cfg.policyTag
+	case "registry":
+		return cfg.RegistryPreset != ""
+	}
+	return false
+}
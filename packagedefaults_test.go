@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestReadPackageDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     map[string]any
+		wantErr  string
+	}{
+		{name: "none", manifest: `{"name":"lib"}`},
+		{name: "other tools", manifest: `{"name":"lib","relicta":{"github":{"draft":true}}}`},
+		{name: "defaults", manifest: `{"name":"lib","relicta":{"npm":{"tag":"next","access":"public"}}}`, want: map[string]any{"tag": "next", "access": "public"}},
+		{name: "not an object", manifest: `{"name":"lib","relicta":{"npm":"next"}}`, wantErr: "relicta.npm in package.json must be an object"},
+		{name: "pipeline settings", manifest: `{"name":"lib","relicta":{"npm":{"workspaces":["*"],"id":"x"}}}`, wantErr: "relicta.npm in package.json cannot set id, workspaces"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "package.json"), tt.manifest)
+			got, err := readPackageDefaults(dir)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("readPackageDefaults() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readPackageDefaults() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackageDefaults(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]any
+		wantArgs    string
+		wantApplied []string
+	}{
+		{name: "applied", config: map[string]any{}, wantArgs: "--tag next --access public", wantApplied: []string{"access", "tag"}},
+		{name: "pipeline wins", config: map[string]any{"tag": "beta"}, wantArgs: "--tag beta --access public", wantApplied: []string{"access"}},
+		{name: "tag policy wins", config: map[string]any{"tag_policy": []any{map[string]any{"range": ">=1.0.0", "tag": "stable"}}}, wantArgs: "--tag stable --access public", wantApplied: []string{"access"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := fakeNpm(t, `echo '{}'`)
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0","relicta":{"npm":{"tag":"next","access":"public"}}}`)
+			chdir(t, dir)
+
+			resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  tt.config,
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("unexpected failure: %v %+v", err, resp)
+			}
+			if calls := npmCalls(t, logPath); len(calls) != 1 || !strings.Contains(calls[0], tt.wantArgs) {
+				t.Errorf("npm calls = %v, want %q", calls, tt.wantArgs)
+			}
+			if got := resp.Outputs["package_defaults"]; !reflect.DeepEqual(got, tt.wantApplied) {
+				t.Errorf("package_defaults = %v, want %v", got, tt.wantApplied)
+			}
+		})
+	}
+}
+
+func TestWorkspacePackageDefaults(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	chdir(t, dir)
+	writeFile(t, filepath.Join(dir, "packages", "cli", "package.json"), `{"name":"cli","version":"1.0.0","relicta":{"npm":{"tag":"canary"}}}`)
+	writeFile(t, filepath.Join(dir, "packages", "core", "package.json"), `{"name":"core","version":"1.0.0"}`)
+	server := newTestRegistry(t, map[string]*packument{})
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"workspaces": []any{"packages/*"}, "registry": server.URL},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	calls := npmCalls(t, logPath)
+	if len(calls) != 2 || !strings.Contains(calls[0], "--tag canary") || !strings.Contains(calls[1], "--tag latest") {
+		t.Errorf("npm calls = %v", calls)
+	}
+}
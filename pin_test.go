@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func stubLookupIPAddr(t *testing.T, ips ...string) {
+	t.Helper()
+	orig := lookupIPAddr
+	lookupIPAddr = func(context.Context, string) ([]net.IPAddr, error) {
+		addrs := make([]net.IPAddr, len(ips))
+		for i, ip := range ips {
+			addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+		}
+		return addrs, nil
+	}
+	t.Cleanup(func() { lookupIPAddr = orig })
+}
+
+func TestValidateRegistryPin(t *testing.T) {
+	valid := strings.Repeat("ab", 32)
+	tests := []struct {
+		name    string
+		pin     RegistryPin
+		wantErr bool
+	}{
+		{"empty", RegistryPin{}, false},
+		{"cidrs and ips", RegistryPin{IPRanges: []string{"104.16.0.0/12", "10.1.2.3", "2606:4700::/32"}}, false},
+		{"bad cidr", RegistryPin{IPRanges: []string{"10.0.0.0/33"}}, true},
+		{"fingerprint", RegistryPin{CertSHA256: []string{valid}}, false},
+		{"colon fingerprint", RegistryPin{CertSHA256: []string{strings.ToUpper(strings.Repeat("AB:", 31) + "AB")}}, false},
+		{"short fingerprint", RegistryPin{CertSHA256: []string{"abcd"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRegistryPin(tt.pin); (err != nil) != tt.wantErr {
+				t.Errorf("validateRegistryPin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistryPinIPRanges(t *testing.T) {
+	pin := RegistryPin{IPRanges: []string{"104.16.0.0/12"}}
+
+	stubLookupIPAddr(t, "104.16.1.34", "104.16.2.34")
+	if err := verifyRegistryPin(context.Background(), "https://registry.npmjs.org/", pin); err != nil {
+		t.Errorf("expected pinned addresses to pass: %v", err)
+	}
+
+	stubLookupIPAddr(t, "104.16.1.34", "203.0.113.7")
+	err := verifyRegistryPin(context.Background(), "https://registry.npmjs.org/", pin)
+	if err == nil || !strings.Contains(err.Error(), "203.0.113.7") {
+		t.Errorf("expected error naming the unpinned address, got %v", err)
+	}
+}
+
+func TestVerifyRegistryPinCertificate(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	sum := sha256.Sum256(srv.Certificate().Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	if err := verifyRegistryPin(context.Background(), srv.URL, RegistryPin{CertSHA256: []string{strings.ToUpper(fingerprint)}}); err != nil {
+		t.Errorf("expected matching fingerprint to pass: %v", err)
+	}
+	if err := verifyRegistryPin(context.Background(), srv.URL, RegistryPin{CertSHA256: []string{strings.Repeat("00", 32)}}); err == nil {
+		t.Error("expected mismatched fingerprint to fail")
+	}
+	if err := verifyRegistryPin(context.Background(), "http://localhost:4873", RegistryPin{CertSHA256: []string{fingerprint}}); err == nil {
+		t.Error("expected certificate pin on http registry to fail")
+	}
+}
+
+func TestPublishRegistryPinMismatch(t *testing.T) {
+	stubLookupIPAddr(t, "203.0.113.7")
+	logPath := fakeNpm(t, "")
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"1.0.0"}`)
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	p := &NpmPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"registry":     "https://registry.example.com",
+			"registry_pin": map[string]any{"ip_ranges": []any{"10.0.0.0/8"}},
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "registry pin verification failed") {
+		t.Errorf("expected pin failure, got %+v", resp)
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 0 {
+		t.Errorf("npm should not run, got %q", calls)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// planStep is one action a real run would take, reported in dry-run as the
+// "plan" output. Steps the dry run itself performed carry their duration.
+type planStep struct {
+	Hook       string `json:"hook"`
+	Step       string `json:"step"`
+	Action     string `json:"action"`
+	Command    string `json:"command,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+}
+
+// prePublishPlan returns the steps of the pre-publish hook.
+func prePublishPlan(cfg *Config, version string) []planStep {
+	var plan []planStep
+	if cfg.UpdateVersion && useYarnVersion(cfg) {
+		plan = append(plan, planStep{Hook: "pre-publish", Step: "version", Action: fmt.Sprintf("Apply version %s with yarn version and check constraints", version), Command: "yarn version apply"})
+	} else if cfg.UpdateVersion {
+		plan = append(plan, planStep{Hook: "pre-publish", Step: "version", Action: fmt.Sprintf("Set package.json version to %s", version)})
+	}
+	if cfg.ReadmeVersions != "" {
+		action := "Check README.md for stale version references"
+		if cfg.ReadmeVersions == "update" {
+			action = fmt.Sprintf("Update stale README.md version references to %s", version)
+		}
+		plan = append(plan, planStep{Hook: "pre-publish", Step: "readme_versions", Action: action})
+	}
+	if cfg.ChangelogCheck != "" {
+		plan = append(plan, planStep{Hook: "pre-publish", Step: "changelog", Action: fmt.Sprintf("Check the changelog has an entry for %s", version)})
+	}
+	if cfg.Preflight != "" {
+		plan = append(plan, planStep{Hook: "pre-publish", Step: "preflight", Action: "Check the package has a README and a license and that its files, main and exports entries are packed"})
+	}
+	return plan
+}
+
+// publishPlan returns the steps of the post-publish, on-success and on-error
+// hooks. checks is how long the dry run spent on the pre-publish checks it
+// ran.
+func publishPlan(cfg *Config, name, version, publishCmd string, purgeURLs []string, checks time.Duration) []planStep {
+	step := func(s, action, command string) planStep {
+		return planStep{Hook: "post-publish", Step: s, Action: action, Command: command}
+	}
+
+	var plan []planStep
+	var checked []string
+	if cfg.BundledDeps != "" {
+		checked = append(checked, "bundled_deps")
+	}
+	if cfg.CodeScan != "" {
+		checked = append(checked, "code_scan")
+	}
+	if cfg.Sourcemaps != "" {
+		checked = append(checked, "sourcemaps")
+	}
+	if cfg.ModuleCheck != "" {
+		checked = append(checked, "module_check")
+	}
+	if cfg.ManifestLeaks.enabled() {
+		checked = append(checked, "manifest_leaks")
+	}
+	if len(cfg.ExpectedOutputs) > 0 {
+		checked = append(checked, "expected_outputs")
+	}
+	if len(checked) > 0 {
+		s := step("checks", "Check the package contents: "+strings.Join(checked, ", "), "")
+		s.DurationMs = checks.Milliseconds()
+		plan = append(plan, s)
+	}
+
+	if len(cfg.BlackoutWindows) > 0 {
+		action := "Refuse to publish"
+		if cfg.BlackoutMode == blackoutWait {
+			action = fmt.Sprintf("Wait up to %ds before publishing", cfg.BlackoutMaxWait)
+		}
+		plan = append(plan, step("blackout", fmt.Sprintf("%s inside %d blackout window(s)", action, len(cfg.BlackoutWindows)), ""))
+	}
+
+	if cfg.PublishAt != "" {
+		plan = append(plan, step("publish_at", fmt.Sprintf("Wait until %s before uploading", cfg.PublishAt), ""))
+	}
+	if t := cfg.ReleaseTrain; t.URL != "" || t.File != "" {
+		signal := t.URL
+		if signal == "" {
+			signal = t.File
+		}
+		plan = append(plan, step("release_train", "Wait for the release train to signal ready", signal))
+	}
+
+	if cfg.PublishTarget != publishTargetArtifactStore {
+		if len(cfg.RegistryPin.IPRanges) > 0 || len(cfg.RegistryPin.CertSHA256) > 0 {
+			plan = append(plan, step("registry_pin", "Verify the registry address and certificate pins", ""))
+		}
+		if cfg.EphemeralNpmrc.Enabled {
+			plan = append(plan, step("ephemeral_npmrc", fmt.Sprintf("Authenticate npm with a temporary npmrc holding a %s token scoped to %s", cfg.EphemeralNpmrc.tokenEnv(), strings.Join(npmrcRegistries(cfg), ", ")), ""))
+		}
+		if cfg.TrustedPublishing {
+			plan = append(plan, step("trusted_publishing", fmt.Sprintf("Exchange the CI ID token for a publish token for %s", name), ""))
+		}
+		if cfg.SSO.Detect {
+			plan = append(plan, step("sso", "Check the registry accepts the credentials without an SSO web login", ""))
+		}
+		if cfg.TokenExchange {
+			plan = append(plan, step("token_exchange", fmt.Sprintf("Mint a short-lived publish token scoped to %s", name), ""))
+		}
+		if cfg.Lock {
+			plan = append(plan, step("lock", fmt.Sprintf("Acquire the %q release lock dist-tag", lockTag(cfg)), ""))
+		}
+		for _, platformPkg := range platformPackageNames(cfg.Binaries, name) {
+			plan = append(plan, step("platform_package", fmt.Sprintf("Publish platform package %s@%s", platformPkg, version), ""))
+		}
+		if len(cfg.Binaries.Platforms) > 0 {
+			plan = append(plan, step("binaries", fmt.Sprintf("Add the %s launcher and %s entries to package.json", binaryCommand(cfg.Binaries, name), binaryMode(cfg.Binaries)), ""))
+		}
+	}
+
+	if cfg.PublishMethod == publishMethodAPI && cfg.PublishTarget != publishTargetArtifactStore {
+		plan = append(plan, step("pack", "Pack the tarball without npm; lifecycle scripts do not run", ""))
+	} else if cfg.PackDestination != "" || cfg.ReadmeBadge != "" || cfg.PublishTarget == publishTargetArtifactStore {
+		dest := cfg.PackDestination
+		if dest == "" {
+			dest = "<temporary directory>"
+		}
+		action := "Pack the tarball"
+		if cfg.ReadmeBadge != "" {
+			action += " with the README badge"
+		}
+		plan = append(plan, step("pack", action, "npm "+strings.Join(redactArgs(packArgs(cfg, dest)), " ")))
+	}
+
+	if cfg.PublishTarget == publishTargetArtifactStore {
+		plan = append(plan, step("upload", fmt.Sprintf("Upload %s@%s to %s", name, version, cfg.ArtifactStore), ""))
+	} else {
+		plan = append(plan, step("publish", fmt.Sprintf("Publish %s@%s with dist-tag %q", name, version, cfg.Tag), publishCmd))
+		if cfg.VerifyPublish {
+			plan = append(plan, step("verify_publish", fmt.Sprintf("Wait up to %ds for %s@%s to appear in the registry", cfg.VerifyTimeout, name, version), ""))
+		}
+		if cfg.Catalog.URL != "" {
+			plan = append(plan, step("catalog", "Post the package metadata to the catalog", cfg.Catalog.URL))
+		}
+		if cfg.TypesPackage.enabled() {
+			plan = append(plan, step("types_package", fmt.Sprintf("Publish types package %s@%s", typesPackageName(cfg.TypesPackage, name), version), ""))
+		}
+		if cfg.PackManifest != "" {
+			plan = append(plan, step("pack_manifest", fmt.Sprintf("Write the pack manifest to %s", cfg.PackManifest), ""))
+		}
+		for _, u := range purgeURLs {
+			plan = append(plan, step("cdn_purge", "Purge CDN cache", u))
+		}
+		for _, repo := range cfg.Consumers.Repos {
+			plan = append(plan, step("consumers", fmt.Sprintf("Notify %s to bump to %s", repo, version), ""))
+		}
+		if cfg.GraduateTags {
+			plan = append(plan, step("dist_tags", "Move dist-tags still pointing at superseded prereleases", ""))
+		}
+		if cfg.MajorTag != "" {
+			plan = append(plan, step("major_tags", fmt.Sprintf("Point %q dist-tags at the newest stable version of each major line", cfg.MajorTag), ""))
+		}
+		for _, tag := range cfg.DistTags.Add {
+			plan = append(plan, step("dist_tags", fmt.Sprintf("Point dist-tag %q at %s", tag, version), ""))
+		}
+		for _, tag := range cfg.DistTags.Remove {
+			plan = append(plan, step("dist_tags", fmt.Sprintf("Remove dist-tag %q", tag), ""))
+		}
+		if len(cfg.Deprecate.Ranges) > 0 {
+			plan = append(plan, step("deprecate", fmt.Sprintf("Deprecate versions matching %s other than %s", strings.Join(cfg.Deprecate.Ranges, ", "), version), ""))
+		}
+	}
+
+	if cfg.EnvFile != "" || cfg.EnvFileFormat != "" {
+		plan = append(plan, step("env_file", "Write publish results to the env file", ""))
+	}
+	if cfg.Lock && cfg.PublishTarget != publishTargetArtifactStore {
+		plan = append(plan, step("unlock", fmt.Sprintf("Remove the %q release lock dist-tag", lockTag(cfg)), ""))
+	}
+	if cfg.TokenExchange && cfg.PublishTarget != publishTargetArtifactStore {
+		plan = append(plan, step("token_revoke", "Revoke the publish token", ""))
+	}
+
+	if cfg.VerifyLatest {
+		action := fmt.Sprintf("Verify dist-tag %q and tarball integrity", cfg.Tag)
+		if cfg.ReplicationLagThreshold > 0 {
+			action += fmt.Sprintf(", waiting up to %ds for replication", 2*cfg.ReplicationLagThreshold)
+		}
+		plan = append(plan, planStep{Hook: "on-success", Step: "verify", Action: action})
+	}
+	if cfg.Rollback.Action != "" {
+		action := fmt.Sprintf("If the release fails, %s %s@%s", cfg.Rollback.Action, name, version)
+		if cfg.Rollback.Action == rollbackUnpublish {
+			action += " (deprecate outside the 72h window)"
+		}
+		plan = append(plan, planStep{Hook: "on-error", Step: "rollback", Action: action})
+	}
+	return plan
+}
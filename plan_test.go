@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func planSteps(plan []planStep) []string {
+	steps := make([]string, len(plan))
+	for i, s := range plan {
+		steps[i] = s.Hook + ":" + s.Step
+	}
+	return steps
+}
+
+func TestPrePublishPlan(t *testing.T) {
+	cfg := &Config{UpdateVersion: true, ReadmeVersions: "update", ChangelogCheck: "fail"}
+	plan := prePublishPlan(cfg, "1.2.0")
+	want := "pre-publish:version pre-publish:readme_versions pre-publish:changelog"
+	if got := strings.Join(planSteps(plan), " "); got != want {
+		t.Errorf("prePublishPlan() = %s, want %s", got, want)
+	}
+	if plan[0].Action != "Set package.json version to 1.2.0" {
+		t.Errorf("unexpected version action: %q", plan[0].Action)
+	}
+	if plan := prePublishPlan(&Config{}, "1.2.0"); len(plan) != 0 {
+		t.Errorf("expected empty plan, got %+v", plan)
+	}
+}
+
+func TestPublishPlan(t *testing.T) {
+	cfg := &Config{
+		Tag:                     "latest",
+		CodeScan:                "warn",
+		Lock:                    true,
+		PackDestination:         "out",
+		OTP:                     "123456",
+		GraduateTags:            true,
+		EnvFile:                 "publish.env",
+		VerifyLatest:            true,
+		ReplicationLagThreshold: 30,
+	}
+	plan := publishPlan(cfg, "pkg", "1.2.0", "npm publish out/pkg-1.2.0.tgz", []string{"https://purge.example.com/pkg"}, 42*time.Millisecond)
+	want := "post-publish:checks post-publish:lock post-publish:pack post-publish:publish post-publish:cdn_purge " +
+		"post-publish:dist_tags post-publish:env_file post-publish:unlock on-success:verify"
+	if got := strings.Join(planSteps(plan), " "); got != want {
+		t.Errorf("publishPlan() = %s\nwant %s", got, want)
+	}
+	if plan[0].DurationMs != 42 {
+		t.Errorf("checks duration = %d, want 42", plan[0].DurationMs)
+	}
+	if !strings.HasPrefix(plan[2].Command, "npm pack --json --pack-destination out") {
+		t.Errorf("unexpected pack command: %q", plan[2].Command)
+	}
+	if !strings.Contains(plan[len(plan)-1].Action, "60s") {
+		t.Errorf("verify step should mention the replication wait: %q", plan[len(plan)-1].Action)
+	}
+
+	cfg = &Config{PublishTarget: publishTargetArtifactStore, ArtifactStore: "s3://b/", Lock: true}
+	if got := strings.Join(planSteps(publishPlan(cfg, "pkg", "1.2.0", "", nil, 0)), " "); got != "post-publish:pack post-publish:upload" {
+		t.Errorf("artifact store plan = %s", got)
+	}
+}
+
+func TestDryRunPlanOutput(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"otp": "123456"},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+		DryRun:  true,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %+v %v", resp, err)
+	}
+	plan, ok := resp.Outputs["plan"].([]planStep)
+	if !ok || len(plan) != 1 || plan[0].Step != "publish" {
+		t.Fatalf("unexpected plan: %#v", resp.Outputs["plan"])
+	}
+	if strings.Contains(plan[0].Command, "--dry-run") || strings.Contains(plan[0].Command, "123456") {
+		t.Errorf("plan should show the redacted real command, got %q", plan[0].Command)
+	}
+}
@@ -2,9 +2,9 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
 	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
@@ -21,38 +22,461 @@ import (
 var (
 	// tagPattern validates npm dist-tags (alphanumeric, hyphens, underscores, dots)
 	tagPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
-	// otpPattern validates OTP codes (6-8 digits)
-	otpPattern = regexp.MustCompile(`^\d{6,8}$`)
 	// allowedAccessLevels are the only valid npm access levels
 	allowedAccessLevels = map[string]bool{"public": true, "restricted": true, "": true}
 )
 
+// packageNamePattern validates npm package names (optionally scoped,
+// lowercase URL-safe characters).
+var packageNamePattern = regexp.MustCompile(`^(?:@[a-z0-9~-][a-z0-9._~-]*/)?[a-z0-9~-][a-z0-9._~-]*$`)
+
+// idPattern validates plugin instance ids, which are used in file paths.
+var idPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,63}$`)
+
 // NpmPlugin implements the npm publish plugin.
 type NpmPlugin struct{}
 
 // Config represents the npm plugin configuration.
 type Config struct {
+	// ID distinguishes multiple instances of the plugin in one release; all
+	// internal state (temp files, records) is namespaced by it.
+	ID string `json:"id,omitempty"`
+	// Idempotency guards against the release engine running a hook twice:
+	// each successful execution is recorded under its release id, hook and
+	// package, and a repeat is skipped (skip) or failed (refuse).
+	Idempotency string `json:"idempotency,omitempty"`
+	// ReleaseID identifies the release in idempotency keys; the tag and
+	// commit are used when empty.
+	ReleaseID string `json:"release_id,omitempty"`
 	// Registry is the npm registry URL.
 	Registry string `json:"registry,omitempty"`
+	// RegistryPreset configures a well-known registry (github,
+	// codeartifact).
+	RegistryPreset string `json:"registry_preset,omitempty"`
+	// CodeArtifact locates the AWS CodeArtifact repository of the
+	// codeartifact preset.
+	CodeArtifact CodeArtifact `json:"codeartifact,omitempty"`
+	// PublishURL overrides the registry used for publishing and other writes
+	// when it differs from the metadata URL (some proxy layouts).
+	PublishURL string `json:"publish_url,omitempty"`
+	// AllowedRegistries restricts the registry to these hosts (hostnames,
+	// "*.domain" wildcards or URLs). Empty allows any non-denied host.
+	AllowedRegistries []string `json:"allowed_registries,omitempty"`
+	// RegionalRegistries maps region names to registry replicas. When
+	// Region names one of them it is used instead of Registry, failing over
+	// to Registry if it rejects the publish.
+	RegionalRegistries map[string]string `json:"regional_registries,omitempty"`
+	// Region selects the regional registry (NPM_REGISTRY_REGION).
+	Region string `json:"region,omitempty"`
+	// ScopeRegistries route scoped packages to their own registries; other
+	// packages use Registry.
+	ScopeRegistries []ScopeRegistry `json:"scope_registries,omitempty"`
+	// Binaries publishes prebuilt binaries with the package, as platform
+	// packages or bundled behind an install script.
+	Binaries BinaryDist `json:"binaries,omitempty"`
+	// TypesPackage publishes the declaration files as a companion
+	// "<name>-types" or "@types/" package at the same version.
+	TypesPackage TypesPackage `json:"types_package,omitempty"`
+	// DocsTarball packs a documentation directory into a second tarball,
+	// optionally published as "<name>-docs" or attached as an artifact.
+	DocsTarball DocsTarball `json:"docs_tarball,omitempty"`
+	// DistTags adds, moves and removes dist-tags after publishing, in
+	// addition to Tag.
+	DistTags DistTags `json:"dist_tags,omitempty"`
+	// Catalog is an internal package index updated after publishing.
+	Catalog Catalog `json:"catalog,omitempty"`
+	// Rollback deprecates or unpublishes the released version from the
+	// on-error hook when a later release step fails.
+	Rollback Rollback `json:"rollback,omitempty"`
+	// Deprecate marks older versions as deprecated after publishing.
+	Deprecate Deprecate `json:"deprecate,omitempty"`
+	// TokenExchange uses the NPM_ADMIN_TOKEN only to mint a short-lived
+	// granular token scoped to this package, publishes with it and revokes
+	// it afterwards.
+	TokenExchange bool `json:"token_exchange,omitempty"`
+	// SSO handles registries behind enterprise single sign-on: it detects
+	// web login demands early and authenticates with a pre-authorized token.
+	SSO SSO `json:"sso,omitempty"`
+	// TrustedPublishing exchanges the CI job's OIDC ID token for a
+	// short-lived publish token (npm trusted publishers), so no long-lived
+	// NPM_TOKEN is needed.
+	TrustedPublishing bool `json:"trusted_publishing,omitempty"`
+	// TokenCheck asks the registry about the publish token before
+	// publishing: that it is valid, not read-only or expired, and that its
+	// user may publish the package.
+	TokenCheck bool `json:"token_check,omitempty"`
+	// EphemeralNpmrc authenticates npm through a temporary npmrc scoped to
+	// the publish registry, deleted after the hook, instead of the runner's.
+	EphemeralNpmrc EphemeralNpmrc `json:"ephemeral_npmrc,omitempty"`
+	// SkipExisting looks the version up before publishing and succeeds
+	// without publishing when the registry already holds the same tarball,
+	// so a re-run release is idempotent.
+	SkipExisting bool `json:"skip_existing,omitempty"`
+	// AcknowledgePublicPublish allows the first publish of a package to the
+	// public npm registry, which is otherwise refused.
+	AcknowledgePublicPublish bool `json:"acknowledge_public_publish,omitempty"`
+	// Inputs reads package_dir or a prebuilt tarball from variables set by
+	// earlier plugins instead of static paths.
+	Inputs PluginInputs `json:"inputs,omitempty"`
+	// RegistryDiff makes dry runs report a field-level diff (dependencies,
+	// engines, exports, file count) against the published predecessor.
+	RegistryDiff bool `json:"registry_diff,omitempty"`
+	// SkipOn skips publishing for matching releases; the version update still
+	// runs.
+	SkipOn SkipOn `json:"skip_on,omitempty"`
+	// EndOfLife retires the package instead of publishing it.
+	EndOfLife EndOfLife `json:"end_of_life,omitempty"`
+	// SimulateFailure injects a failure at a release step for pipeline
+	// rehearsals. It is deliberately left out of the config schema.
+	SimulateFailure SimulatedFailure `json:"simulate_failure,omitempty"`
+	// DebugTranscript adds every executed command (secrets redacted) with its
+	// exit code and duration to the "transcript" output.
+	DebugTranscript bool `json:"debug_transcript"`
+	// SupersededBy names the package this one was renamed to: the release
+	// publishes a final stub depending on it and deprecates this package.
+	SupersededBy string `json:"superseded_by,omitempty"`
+	// ReadmeBadge adds a badge line ("version", "provenance" or a Markdown
+	// template) to the top of the README in the published tarball; the
+	// repository copy is left untouched.
+	ReadmeBadge string `json:"readme_badge,omitempty"`
+	// NamePattern is a regular expression or a template such as
+	// "@myorg/{{.RepoName}}-*" the package name must match.
+	NamePattern string `json:"name_pattern,omitempty"`
+	// EnvFile writes PACKAGE_NAME, PACKAGE_VERSION, PACKAGE_TAG, TARBALL_PATH
+	// and PACKAGE_URL to this file after publishing, for later CI steps.
+	EnvFile string `json:"env_file,omitempty"`
+	// EnvFileFormat is "dotenv" (default, replaces the file) or
+	// "github_output" (appends, defaulting to $GITHUB_OUTPUT).
+	EnvFileFormat string `json:"env_file_format,omitempty"`
+	// PublishPlan is the signed plan file pre-publish writes and post-publish
+	// executes, so a release can be approved between the two phases.
+	PublishPlan string `json:"publish_plan,omitempty"`
+	// PublishMethod is how the package is published: "npm" runs npm
+	// publish, "api" packs the tarball itself and PUTs it to the registry
+	// without needing npm. Lifecycle scripts do not run with api.
+	PublishMethod string `json:"publish_method,omitempty"`
+	// Provenance publishes with --provenance so npm attaches a signed
+	// statement linking the package to the CI run that built it.
+	Provenance bool `json:"provenance"`
+	// PublishTarget is where post-publish uploads: "registry" (default) or
+	// "artifact_store", which uploads the packed tarball to ArtifactStore
+	// instead, for packages whose registry publishing is disabled.
+	PublishTarget string `json:"publish_target,omitempty"`
+	// ArtifactStore is the s3://, gs:// or https:// URL template the tarball
+	// is uploaded to; a trailing slash appends the tarball file name.
+	ArtifactStore string `json:"artifact_store,omitempty"`
+	// RegistryPin pins the publish registry to IP ranges or a certificate
+	// fingerprint, verified before uploading.
+	RegistryPin RegistryPin `json:"registry_pin,omitempty"`
 	// Tag is the npm dist-tag to use.
 	Tag string `json:"tag,omitempty"`
 	// Access is the package access level (public, restricted).
 	Access string `json:"access,omitempty"`
 	// OTP is the one-time password for 2FA.
 	OTP string `json:"otp,omitempty"`
-	// DryRun performs a dry-run publish.
+	// OTPPolicy overrides the accepted OTP format.
+	OTPPolicy OTPPolicy `json:"otp_policy,omitempty"`
+	// DryRun performs a dry-run publish. dry_run also accepts a level,
+	// stored in DryRunLevel.
 	DryRun bool `json:"dry_run"`
+	// DryRunLevel is how far a dry run goes: validate (default) runs the
+	// checks, pack also builds the tarball and full runs npm publish
+	// --dry-run.
+	DryRunLevel string `json:"dry_run_level,omitempty"`
 	// PackageDir is the directory containing package.json.
 	PackageDir string `json:"package_dir,omitempty"`
+	// DistDir is a build output tree (e.g. from Bazel or Please) published
+	// instead of PackageDir. Its package.json must match the source name and
+	// version; the version update still applies to PackageDir.
+	DistDir string `json:"dist_dir,omitempty"`
+	// TarballPath is a prebuilt .tgz, e.g. from a separate build job,
+	// published as-is instead of packing PackageDir. It must hold the
+	// package being released.
+	TarballPath string `json:"tarball_path,omitempty"`
+	// Workspaces are package directories or globs (e.g. "packages/*")
+	// published together, dependencies before their dependents.
+	Workspaces []string `json:"workspaces,omitempty"`
+	// Lerna reads lerna.json for the packages, registry and dist-tag and
+	// publishes like `lerna publish from-package`: every package whose
+	// package.json version is not in the registry yet.
+	Lerna bool `json:"lerna"`
+	// Rush publishes the rush.json projects marked shouldPublish or governed
+	// by a version policy, honoring lock-step and individual policies.
+	Rush bool `json:"rush"`
+	// OnlyChanged publishes only packages whose directories changed since
+	// the previous release tag; the version update still runs.
+	OnlyChanged bool `json:"only_changed"`
+	// DependentRanges, when set, moves the ranges other workspace packages
+	// use for a package being versioned in pre-publish: "exact" (1.2.3),
+	// "caret" (^1.2.3) or "tilde" (~1.2.3).
+	DependentRanges string `json:"dependent_ranges,omitempty"`
 	// UpdateVersion updates package.json version before publishing.
 	UpdateVersion bool `json:"update_version"`
+	// VersionCheck compares package.json with the release version when
+	// UpdateVersion is disabled: "lenient" (default) as semver, "strict"
+	// as strings, or "off".
+	VersionCheck string `json:"version_check,omitempty"`
+	// VersionTool applies the version update: "npm" edits package.json
+	// directly, "yarn" uses Yarn's version plugin and constraints, and "auto"
+	// picks yarn in Yarn Berry projects.
+	VersionTool string `json:"version_tool,omitempty"`
+	// ExpectCurrentVersion fails the version update unless package.json holds
+	// this version first: "previous" (or true) for the release's previous
+	// version, or a literal version. Empty disables the check.
+	ExpectCurrentVersion string `json:"expect_current_version,omitempty"`
+	// ReadmeVersions controls stale "name@version" references in README.md
+	// (update, fail). Empty disables the check.
+	ReadmeVersions string `json:"readme_versions,omitempty"`
+	// ChangelogCheck verifies the changelog has a heading for the released
+	// version (warn, fail). Empty disables the check.
+	ChangelogCheck string `json:"changelog_check,omitempty"`
+	// Preflight checks on pre-publish that the package has a README and a
+	// license, that its files, main and exports entries are packed and that
+	// it is not empty (warn, fail). Empty disables the checks.
+	Preflight string `json:"preflight,omitempty"`
+	// ChangelogFile is the changelog path relative to PackageDir; by default
+	// CHANGELOG.md, HISTORY.md and CHANGES.md are tried.
+	ChangelogFile string `json:"changelog_file,omitempty"`
+	// CDNPurge lists CDN presets (jsdelivr, unpkg) or URL templates to request
+	// after a successful publish.
+	CDNPurge []string `json:"cdn_purge,omitempty"`
+	// Consumers are repositories notified after publishing so they can bump
+	// to the new version.
+	Consumers Consumers `json:"consumers,omitempty"`
+	// VerifyLatest re-checks the dist-tag and tarball integrity on release success.
+	VerifyLatest bool `json:"verify_latest"`
+	// VerifyPublish polls the registry after publishing until the new
+	// version appears, failing post-publish after VerifyTimeout seconds.
+	VerifyPublish bool `json:"verify_publish"`
+	// VerifyTimeout bounds VerifyPublish, in seconds.
+	VerifyTimeout int `json:"verify_timeout,omitempty"`
+	// Verification picks the post-publish checks that decide success and
+	// those that are only advisory, reported as one verdict.
+	Verification Verification `json:"verification,omitempty"`
+	// PublishAt is an RFC 3339 time to delay the upload until, for
+	// coordinated launches; checks still run straight away.
+	PublishAt string `json:"publish_at,omitempty"`
+	// PublishAtMaxWait bounds the PublishAt delay, in seconds.
+	PublishAtMaxWait int `json:"publish_at_max_wait,omitempty"`
+	// ReleaseTrain holds the upload until the other ecosystems of a
+	// multi-language release signal they are ready.
+	ReleaseTrain ReleaseTrain `json:"release_train,omitempty"`
+	// ReplicationLagThreshold makes verification wait for the publish to
+	// propagate and warn when that takes longer than this many seconds.
+	ReplicationLagThreshold int `json:"replication_lag_threshold,omitempty"`
+	// ReplicationLagWebhook receives a JSON POST when the threshold is
+	// exceeded.
+	ReplicationLagWebhook string `json:"replication_lag_webhook,omitempty"`
+	// Retries is how many times a publish failing with a transient network
+	// or registry error is tried again; auth and conflict errors are not.
+	Retries int `json:"retries,omitempty"`
+	// RetryDelay is the wait in seconds before the first retry, doubling
+	// for each further one.
+	RetryDelay int `json:"retry_delay,omitempty"`
+	// Lock sets a sentinel dist-tag while publishing so concurrent pipelines
+	// cannot publish the same package simultaneously.
+	Lock bool `json:"lock"`
+	// AllowSemverTags accepts publish tags npm would parse as a semver
+	// range, for legacy registries that take them.
+	AllowSemverTags bool `json:"allow_semver_tags"`
+	// LockTag is the prefix of the lock ticket dist-tags (default
+	// "releasing").
+	LockTag string `json:"lock_tag,omitempty"`
+	// LockTimeout is how long to wait for another release's lock, in seconds.
+	// Zero fails immediately.
+	LockTimeout int `json:"lock_timeout,omitempty"`
+	// LockTTL is how old a lock ticket may get, in seconds, before it is
+	// treated as left behind by a crashed pipeline (default 3600).
+	LockTTL int `json:"lock_ttl,omitempty"`
+	// PackManifest is a path to write the canonical JSON manifest of the
+	// published tarball to, for downstream signing.
+	PackManifest string `json:"pack_manifest,omitempty"`
+	// BlackoutWindows are change freezes during which publishes are
+	// refused, or delayed with BlackoutMode "wait". Dry runs are unaffected.
+	BlackoutWindows []BlackoutWindow `json:"blackout_windows,omitempty"`
+	// BlackoutMode is "refuse" (default) or "wait".
+	BlackoutMode string `json:"blackout_mode,omitempty"`
+	// BlackoutMaxWait bounds how long "wait" mode waits, in seconds; a
+	// window ending later is refused.
+	BlackoutMaxWait int `json:"blackout_max_wait,omitempty"`
+	// TagPolicy selects the dist-tag from the release; the first matching rule
+	// overrides Tag.
+	TagPolicy []TagRule `json:"tag_policy,omitempty"`
+	// TagMap selects the dist-tag from the branch and release type when no
+	// TagPolicy rule matches.
+	TagMap TagMap `json:"tag_map,omitempty"`
+	// MissingManifest is what the hooks do when package_dir has no
+	// package.json: fail (default), skip, or generate a minimal one from
+	// PackageName and the release version.
+	MissingManifest string `json:"missing_manifest,omitempty"`
+	// PackageName names the generated package.json.
+	PackageName string `json:"package_name,omitempty"`
+	// ManifestTemplate is an inline JSON template or a file path rendered
+	// with the release to produce the generated package.json, for publishing
+	// binaries or schema bundles that are not npm packages in source.
+	ManifestTemplate string `json:"manifest_template,omitempty"`
+	// VersionSource selects where the published version comes from:
+	// context (default), package_json, env or command.
+	VersionSource string `json:"version_source,omitempty"`
+	// VersionEnv is the environment variable read when VersionSource is env.
+	VersionEnv string `json:"version_env,omitempty"`
+	// VersionCommand is the argv run when VersionSource is command; its
+	// trimmed stdout is the version.
+	VersionCommand []string `json:"version_command,omitempty"`
+	// VersionTemplate derives the published version from the release, e.g.
+	// "${version}-${branch}.${sha:0:7}" for branch builds.
+	VersionTemplate string `json:"version_template,omitempty"`
+	// PrereleaseIteration raises the "-id.N" iteration of prerelease versions
+	// above any already in the registry.
+	PrereleaseIteration bool `json:"prerelease_iteration"`
+	// GraduationReport outputs the prereleases a stable release supersedes
+	// and the dist-tags still pointing at them.
+	GraduationReport bool `json:"graduation_report"`
+	// MajorTag is a dist-tag template such as "latest-{{.Major}}" kept
+	// pointing at the newest stable version of each major line.
+	MajorTag string `json:"major_tag,omitempty"`
+	// GraduateTags moves dist-tags off superseded prereleases to the new
+	// stable version (implies GraduationReport).
+	GraduateTags bool `json:"graduate_tags"`
+	// VerifyCheckout fails the publish when the git checkout does not match
+	// the release branch and commit.
+	VerifyCheckout bool `json:"verify_checkout"`
+	// PackDestination packs the tarball into this directory first and
+	// publishes that file, keeping the exact published artifact.
+	PackDestination string `json:"pack_destination,omitempty"`
+	// UserConfig is passed to npm as --userconfig, replacing ~/.npmrc.
+	UserConfig string `json:"userconfig,omitempty"`
+	// GlobalConfig is passed to npm as --globalconfig.
+	GlobalConfig string `json:"globalconfig,omitempty"`
+	// CodeScan scans packed JavaScript for debugger statements and
+	// BannedPatterns before publishing (warn, fail). Empty disables the scan.
+	CodeScan string `json:"code_scan,omitempty"`
+	// BannedPatterns are regular expressions reported by CodeScan.
+	BannedPatterns []string `json:"banned_patterns,omitempty"`
+	// Sourcemaps enforces how source maps ship (include, exclude, external).
+	// Empty disables the check.
+	Sourcemaps string `json:"sourcemaps,omitempty"`
+	// ModuleCheck checks the main and exports entry points for ESM/CommonJS
+	// mismatches and dual package hazards (warn, fail). Empty disables it.
+	ModuleCheck string `json:"module_check,omitempty"`
+	// ExpectedOutputs are globs (e.g. "dist/**/*.min.js") that must each
+	// match at least one non-empty packed file.
+	ExpectedOutputs []string `json:"expected_outputs,omitempty"`
+	// CopyFiles are files in the working directory (e.g. a monorepo's root
+	// LICENSE and NOTICE) copied into the package before packing and
+	// removed afterwards. Files the package already has are kept.
+	CopyFiles []string `json:"copy_files,omitempty"`
+	// ResolveLocalDeps rewrites workspace:, file: and link: dependency specs
+	// in the published package.json to the versions of the packages they
+	// point at. Enabled by default; the file is restored after publishing.
+	ResolveLocalDeps bool `json:"resolve_local_deps"`
+	// LicenseCheck validates the license field as an SPDX expression: "check"
+	// fails on invalid values, "fix" also rewrites common mistakes (e.g.
+	// "Apache 2.0") in the published package.json. Empty disables the check.
+	LicenseCheck string `json:"license_check,omitempty"`
+	// AllowedLicenses, when set, are the only license identifiers
+	// LicenseCheck accepts.
+	AllowedLicenses []string `json:"allowed_licenses,omitempty"`
+	// StripFields are package.json fields removed from the published
+	// manifest, as dotted paths (e.g. "overrides", "scripts.prepare"). The
+	// file is restored after publishing.
+	StripFields []string `json:"strip_fields,omitempty"`
+	// ManifestLeaks fails the publish when the published package.json holds
+	// blocked fields or strings pointing at internal infrastructure.
+	ManifestLeaks ManifestLeaks `json:"manifest_leaks,omitempty"`
+	// BundledDeps checks that bundleDependencies are installed inside the
+	// package rather than hoisted or symlinked by a workspace tool: "check"
+	// fails, "vendor" copies them into the package. Empty disables the check.
+	BundledDeps string `json:"bundled_deps,omitempty"`
+	// IgnoreScripts passes --ignore-scripts to pack and publish.
+	IgnoreScripts bool `json:"ignore_scripts"`
+	// ForegroundScripts passes --foreground-scripts to pack and publish.
+	ForegroundScripts bool `json:"foreground_scripts"`
+	// Sandbox runs pack and publish under bubblewrap (Linux) or sandbox-exec
+	// (macOS), limiting lifecycle scripts to the package directory, the npm
+	// cache and the npmrc files: "auto" uses it when available, "required"
+	// fails without it. Empty disables it.
+	Sandbox string `json:"sandbox,omitempty"`
+	// DependencyNotes adds a "Dependency changes" section to the release notes
+	// comparing dependencies with the previously published version.
+	DependencyNotes bool `json:"dependency_notes"`
+	// PublishHistory adds the previously published version and the time since
+	// it was published to the publish outputs.
+	PublishHistory bool `json:"publish_history"`
+	// TestRegistry publishes to a throwaway registry instead of the configured
+	// one and verifies the package installs from it.
+	TestRegistry bool `json:"test_registry"`
+	// TestRegistryURL uses an existing registry (e.g. Verdaccio) for
+	// TestRegistry instead of the embedded one.
+	TestRegistryURL string `json:"test_registry_url,omitempty"`
+	// Messages overrides the publish success, skip and failure messages.
+	Messages MessageTemplates `json:"messages,omitempty"`
+
+	// parseErrors collects errors from decoding structured config values.
+	parseErrors []error
+	// raw is the config as given, telling keys the pipeline sets from
+	// package.json defaults.
+	raw map[string]any
+	// policyTag is set when TagPolicy or TagMap chose Tag.
+	policyTag bool
+	// presetScope is the package scope mapped to the registry by RegistryPreset.
+	presetScope string
+	// providerAuth is set once the registry provider has put its minted
+	// token in EphemeralNpmrc.
+	providerAuth bool
+	// authArgs are npm flags authenticating to the embedded test registry,
+	// and SSO's --auth-type. Tokens for real registries never go here.
+	authArgs []string
+	// authToken is the token minted or pre-authorized for this publish,
+	// which npm reads from the npmrc useAuthToken writes, or the embedded
+	// test registry's.
+	authToken string
+	// primaryRegistry is the registry a regional publish fails over to.
+	primaryRegistry string
+	// inputTarball is the absolute path of a tarball from Inputs.Tarball.
+	inputTarball string
+	// lerna is the lerna.json read in lerna mode.
+	lerna *lernaConfig
+	// rush is the Rush configuration read in Rush mode.
+	rush *rushConfig
+	// sandbox is the sandbox tool resolved for this run.
+	sandbox string
 }
 
 // PackageJSON represents a package.json file.
 type PackageJSON struct {
-	Name    string `json:"name"`
-	Version string `json:"version"`
-	Private bool   `json:"private"`
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	Private      bool              `json:"private"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+// readPackageJSON reads and parses package.json from the given directory.
+func readPackageJSON(packageDir string) (*PackageJSON, error) {
+	data, err := os.ReadFile(filepath.Join(packageDir, "package.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+
+	var pkg PackageJSON
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+	return &pkg, nil
+}
+
+// setOutput sets a single output value on resp, allocating Outputs if needed.
+func setOutput(resp *plugin.ExecuteResponse, key string, value any) {
+	if resp.Outputs == nil {
+		resp.Outputs = map[string]any{}
+	}
+	resp.Outputs[key] = value
+}
+
+// appendWarning adds a non-fatal warning to the "warnings" output.
+func appendWarning(outputs map[string]any, msg string) {
+	warnings, _ := outputs["warnings"].([]string)
+	outputs["warnings"] = append(warnings, msg)
 }
 
 // GetInfo returns plugin metadata.
@@ -63,40 +487,652 @@ func (p *NpmPlugin) GetInfo() plugin.Info {
 		Description: "Publish packages to npm registry",
 		Author:      "Relicta Team",
 		Hooks: []plugin.Hook{
+			plugin.HookPostNotes,
 			plugin.HookPrePublish,
 			plugin.HookPostPublish,
+			plugin.HookOnSuccess,
+			plugin.HookOnError,
 		},
 		ConfigSchema: `{
 			"type": "object",
 			"properties": {
+				"id": {"type": "string", "description": "Instance id when the plugin is configured more than once"},
+				"idempotency": {"type": "string", "enum": ["skip", "refuse"], "description": "Skip or refuse a hook that already succeeded for this release and package"},
+				"release_id": {"type": "string", "description": "Release id used in idempotency keys (or use RELICTA_RELEASE_ID env); defaults to the tag and commit"},
 				"registry": {"type": "string", "description": "npm registry URL"},
+				"registry_preset": {"type": "string", "enum": ["github", "codeartifact"], "description": "Well-known registry preset; github publishes to GitHub Packages under the repository owner's scope, codeartifact to an AWS CodeArtifact repository"},
+				"codeartifact": {
+					"type": "object",
+					"description": "AWS CodeArtifact repository for registry_preset codeartifact; tokens are minted with the AWS CLI",
+					"properties": {
+						"domain": {"type": "string", "description": "CodeArtifact domain"},
+						"domain_owner": {"type": "string", "description": "AWS account ID owning the domain"},
+						"repository": {"type": "string", "description": "CodeArtifact repository"},
+						"region": {"type": "string", "description": "AWS region; defaults to AWS_REGION"},
+						"duration_seconds": {"type": "integer", "minimum": 900, "maximum": 43200, "description": "Token lifetime; defaults to 12 hours"}
+					},
+					"required": ["domain", "domain_owner", "repository"]
+				},
+				"publish_url": {"type": "string", "description": "Registry URL for publishing when it differs from registry"},
+				"allowed_registries": {"type": "array", "items": {"type": "string"}, "description": "Registry hosts the plugin may publish to"},
+				"regional_registries": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Registry replicas by region; the publish fails over to registry if the regional one rejects it"},
+				"region": {"type": "string", "description": "Region selecting a regional_registries entry (or NPM_REGISTRY_REGION env var)"},
+				"scope_registries": {
+					"type": "array",
+					"description": "Registries for scoped packages; other packages use registry",
+					"items": {
+						"type": "object",
+						"properties": {
+							"scope": {"type": "string", "description": "npm scope, e.g. @mycorp"},
+							"registry": {"type": "string", "description": "Registry URL for the scope"},
+							"token_env": {"type": "string", "description": "Environment variable holding the token ephemeral_npmrc writes for this registry"}
+						},
+						"required": ["scope", "registry"]
+					}
+				},
+				"types_package": {
+					"type": "object",
+					"description": "Publish the declaration files as a version-locked types companion package",
+					"properties": {
+						"style": {"type": "string", "enum": ["suffix", "types_scope"], "default": "suffix", "description": "<name>-types or @types/<name>"},
+						"name": {"type": "string", "description": "Companion package name; overrides style"},
+						"dir": {"type": "string", "description": "Declarations directory relative to package_dir"},
+						"manifest": {"type": "object", "description": "Fields merged into the generated package.json"}
+					}
+				},
+				"docs_tarball": {
+					"type": "object",
+					"description": "Pack a documentation directory into <name>-docs-<version>.tgz",
+					"properties": {
+						"dir": {"type": "string", "description": "Documentation directory relative to package_dir"},
+						"destination": {"type": "string", "default": ".", "description": "Directory the tarball is written to"},
+						"publish": {"type": "boolean", "default": false, "description": "Also publish it as the <name>-docs package"},
+						"attach": {"type": "boolean", "default": false, "description": "List it in the response artifacts for release asset plugins"}
+					}
+				},
+				"consumers": {
+					"type": "object",
+					"description": "Repositories notified after publishing so they can bump to the new version",
+					"properties": {
+						"repos": {"type": "array", "items": {"type": "string"}, "description": "Consumer repositories as owner/name"},
+						"url": {"type": "string", "description": "Endpoint template with {{.Repo}}; defaults to GitHub repository_dispatch"},
+						"event_type": {"type": "string", "description": "Dispatch event type", "default": "npm_package_published"},
+						"token_env": {"type": "string", "description": "Environment variable holding the bearer token", "default": "GITHUB_TOKEN"}
+					}
+				},
+				"catalog": {
+					"type": "object",
+					"description": "Internal package catalog receiving a POST of the package metadata after publishing",
+					"properties": {
+						"url": {"type": "string", "description": "Catalog endpoint"},
+						"token_env": {"type": "string", "description": "Environment variable holding the catalog token"},
+						"header": {"type": "string", "description": "Header carrying the token", "default": "Authorization"}
+					}
+				},
+				"rollback": {
+					"type": "object",
+					"description": "Undo the publish from the on-error hook when a later release step fails",
+					"properties": {
+						"action": {"type": "string", "enum": ["deprecate", "unpublish"], "description": "unpublish falls back to deprecate outside npm's 72 hour window"},
+						"message": {"type": "string", "description": "Deprecation message template"}
+					}
+				},
+				"deprecate": {
+					"type": "object",
+					"description": "Deprecate superseded versions after publishing",
+					"properties": {
+						"ranges": {"type": "array", "items": {"type": "string"}, "description": "semver ranges of versions to deprecate; the published version is excluded"},
+						"message": {"type": "string", "description": "Deprecation message template", "default": "Superseded by {{.Name}}@{{.Version}}"}
+					}
+				},
+				"dist_tags": {
+					"type": "object",
+					"description": "Dist-tags to add, move or remove after publishing, in addition to tag",
+					"properties": {
+						"add": {"type": "array", "items": {"type": "string"}, "description": "Dist-tags pointed at the published version"},
+						"remove": {"type": "array", "items": {"type": "string"}, "description": "Dist-tags deleted from the package"}
+					}
+				},
+				"binaries": {
+					"type": "object",
+					"description": "Publish prebuilt binaries as platform packages or behind an install script",
+					"properties": {
+						"name": {"type": "string", "description": "Command name; defaults to the unscoped package name"},
+						"mode": {"type": "string", "enum": ["optional_dependencies", "install_script"], "default": "optional_dependencies"},
+						"platforms": {
+							"type": "array",
+							"items": {
+								"type": "object",
+								"properties": {
+									"os": {"type": "string", "description": "process.platform value, e.g. linux"},
+									"cpu": {"type": "string", "description": "process.arch value, e.g. x64"},
+									"path": {"type": "string", "description": "Prebuilt binary path"}
+								}
+							}
+						}
+					}
+				},
+				"inputs": {
+					"type": "object",
+					"description": "Variables from earlier plugins (release context environment, then process environment) supplying paths",
+					"properties": {
+						"package_dir": {"type": "string", "description": "Variable holding the package directory"},
+						"tarball": {"type": "string", "description": "Variable holding a prebuilt tarball to publish as-is"}
+					}
+				},
+				"skip_existing": {"type": "boolean", "description": "Succeed without publishing when the version is already published with the same integrity", "default": false},
+				"acknowledge_public_publish": {"type": "boolean", "description": "Allow the first publish of a package to the public npm registry", "default": false},
+				"trusted_publishing": {"type": "boolean", "description": "Exchange the CI OIDC ID token for a short-lived publish token instead of using NPM_TOKEN", "default": false},
+				"token_check": {"type": "boolean", "description": "Check the publish token is valid, unexpired and allowed to publish the package before publishing", "default": false},
+				"token_exchange": {"type": "boolean", "description": "Mint a package-scoped publish token with NPM_ADMIN_TOKEN and revoke it after publishing", "default": false},
+				"sso": {
+					"type": "object",
+					"description": "Registries behind enterprise SSO: fail early instead of waiting for a browser login",
+					"properties": {
+						"detect": {"type": "boolean", "description": "Probe the registry before publishing and fail with guidance when it demands a web login", "default": false},
+						"token_env": {"type": "string", "description": "Environment variable holding a token pre-authorized through the identity provider"},
+						"header": {"type": "string", "description": "Header carrying the token instead of Authorization (publish_method api only)"}
+					}
+				},
+				"registry_diff": {"type": "boolean", "description": "In dry runs, diff dependencies, engines, exports and file count against the published predecessor", "default": false},
+				"skip_on": {
+					"type": "object",
+					"description": "Skip publishing (but still update the version) for matching releases",
+					"properties": {
+						"release_types": {"type": "array", "items": {"type": "string"}, "description": "Release types, or commit types all release commits must share, e.g. docs and chore"},
+						"commit_marker": {"type": "string", "description": "Commit message marker such as [skip npm]"}
+					}
+				},
+				"end_of_life": {
+					"type": "object",
+					"description": "Deprecate every version of the package instead of publishing",
+					"properties": {
+						"enabled": {"type": "boolean", "description": "Switch post-publish to end-of-life mode", "default": false},
+						"message": {"type": "string", "description": "Deprecation message template"},
+						"successor": {"type": "string", "description": "Package replacing this one"},
+						"publish_stub": {"type": "boolean", "description": "Publish the release version as a stub pointing at the successor", "default": false},
+						"depend_on_successor": {"type": "boolean", "description": "Make the stub depend on the successor's latest version", "default": false}
+					}
+				},
+				"debug_transcript": {"type": "boolean", "description": "Output every executed command with its exit code and duration", "default": false},
+				"superseded_by": {"type": "string", "description": "Package this one was renamed to; publishes a final stub depending on it and deprecates this package"},
+				"readme_badge": {"type": "string", "description": "Badge added to the top of the packed README: version, provenance or a Markdown template"},
+				"name_pattern": {"type": "string", "description": "Regular expression or template (e.g. @myorg/{{.RepoName}}-*) the package name must match"},
+				"env_file": {"type": "string", "description": "File receiving PACKAGE_NAME, PACKAGE_VERSION, TARBALL_PATH and PACKAGE_URL after publishing"},
+				"env_file_format": {"type": "string", "enum": ["dotenv", "github_output"], "description": "Env file format; github_output appends to $GITHUB_OUTPUT by default", "default": "dotenv"},
+				"publish_plan": {"type": "string", "description": "Signed publish plan written by pre-publish and required by post-publish (key from RELICTA_NPM_PLAN_KEY)"},
+				"provenance": {"type": "boolean", "description": "Publish with --provenance; requires GitHub Actions or GitLab CI with OIDC", "default": false},
+				"publish_method": {"type": "string", "enum": ["npm", "api"], "description": "Publish with npm or by PUTting the tarball to the registry API without npm", "default": "npm"},
+				"publish_target": {"type": "string", "enum": ["registry", "artifact_store"], "description": "Publish to the registry or upload the tarball to artifact_store", "default": "registry"},
+				"artifact_store": {"type": "string", "description": "s3://, gs:// or https:// URL template the tarball is uploaded to when publish_target is artifact_store"},
+				"registry_pin": {
+					"type": "object",
+					"description": "Pin the publish registry before uploading",
+					"properties": {
+						"ip_ranges": {"type": "array", "items": {"type": "string"}, "description": "CIDRs the registry host must resolve within"},
+						"cert_sha256": {"type": "array", "items": {"type": "string"}, "description": "Accepted SHA-256 fingerprints of the registry certificate"}
+					}
+				},
 				"tag": {"type": "string", "description": "dist-tag for the package", "default": "latest"},
 				"access": {"type": "string", "enum": ["public", "restricted"], "description": "Package access level"},
-				"otp": {"type": "string", "description": "OTP for 2FA"},
-				"dry_run": {"type": "boolean", "description": "Perform dry-run", "default": false},
+				"otp": {"type": "string", "description": "OTP for 2FA (or NPM_OTP env var)"},
+				"otp_policy": {
+					"type": "object",
+					"description": "Accepted OTP format",
+					"properties": {
+						"min_length": {"type": "integer", "description": "Minimum OTP length", "default": 6},
+						"max_length": {"type": "integer", "description": "Maximum OTP length", "default": 8},
+						"alphanumeric": {"type": "boolean", "description": "Accept letters as well as digits", "default": false},
+						"bypass_marker": {"type": "string", "description": "OTP value meaning no code; --otp is omitted"}
+					}
+				},
+				"dry_run": {"oneOf": [{"type": "boolean"}, {"type": "string", "enum": ["validate", "pack", "full"]}], "description": "Perform dry-run: true or validate runs the checks, pack also builds the tarball, full runs npm publish --dry-run", "default": false},
 				"package_dir": {"type": "string", "description": "Directory containing package.json"},
-				"update_version": {"type": "boolean", "description": "Update package.json version", "default": true}
+				"dist_dir": {"type": "string", "description": "Build output directory published instead of package_dir; its package.json must match the source version"},
+				"tarball_path": {"type": "string", "description": "Prebuilt .tgz published as-is instead of packing package_dir (or use NPM_TARBALL_PATH env); it must hold the released name and version"},
+				"workspaces": {"type": "array", "items": {"type": "string"}, "description": "Package directories or globs published together in dependency order"},
+				"lerna": {"type": "boolean", "description": "Read lerna.json and publish like lerna publish from-package", "default": false},
+				"rush": {"type": "boolean", "description": "Publish the rush.json projects marked shouldPublish, honoring version policies", "default": false},
+				"only_changed": {"type": "boolean", "description": "Only publish packages whose directories changed since the previous release tag", "default": false},
+				"dependent_ranges": {"type": "string", "enum": ["exact", "caret", "tilde"], "description": "Update the ranges sibling workspace packages use for a released package"},
+				"update_version": {"type": "boolean", "description": "Update package.json version", "default": true},
+				"version_check": {"type": "string", "enum": ["lenient", "strict", "off"], "description": "Compare package.json with the release version when update_version is disabled", "default": "lenient"},
+				"version_tool": {"type": "string", "enum": ["npm", "yarn", "auto"], "description": "Tool applying the version update; yarn uses yarn version apply and constraints", "default": "npm"},
+				"expect_current_version": {"type": ["boolean", "string"], "description": "Version package.json must hold before update: true/\"previous\" or a literal version"},
+				"readme_versions": {"type": "string", "enum": ["update", "fail"], "description": "Update or fail on stale package@version references in README.md"},
+				"changelog_check": {"type": "string", "enum": ["warn", "fail"], "description": "Warn or fail when the changelog has no entry for the version"},
+				"preflight": {"type": "string", "enum": ["warn", "fail"], "description": "Check for a README, a license, packed files/main/exports paths and an empty package on pre-publish"},
+				"changelog_file": {"type": "string", "description": "Changelog path relative to package_dir"},
+				"cdn_purge": {"type": "array", "items": {"type": "string"}, "description": "CDN presets (jsdelivr, unpkg) or URL templates to purge after publish"},
+				"verify_publish": {"type": "boolean", "description": "Poll the registry after publishing until the new version appears", "default": false},
+				"verify_timeout": {"type": "integer", "description": "Seconds verify_publish waits for the version to appear", "default": 60},
+				"verification": {
+					"type": "object",
+					"description": "Post-publish checks combined into a verification verdict",
+					"properties": {
+						"required": {"type": "array", "items": {"type": "string", "enum": ["version_visible", "tag_correct", "install", "provenance"]}, "description": "Checks that fail post-publish"},
+						"advisory": {"type": "array", "items": {"type": "string", "enum": ["version_visible", "tag_correct", "install", "provenance"]}, "description": "Checks that only add warnings"}
+					}
+				},
+				"publish_at": {"type": "string", "description": "RFC 3339 time to delay the registry upload until (or use NPM_PUBLISH_AT env)"},
+				"publish_at_max_wait": {"type": "integer", "description": "Seconds publish_at may delay the upload", "default": 21600},
+				"release_train": {
+					"type": "object",
+					"description": "Wait for other ecosystems of a multi-language release to signal ready before uploading",
+					"properties": {
+						"url": {"type": "string", "description": "URL template polled until it reports ready; a JSON status of aborted cancels the publish"},
+						"file": {"type": "string", "description": "Marker file template checked until it exists"},
+						"ready_file": {"type": "string", "description": "File template written when this package is ready, and rewritten as aborted on failure"},
+						"token_env": {"type": "string", "description": "Environment variable holding a bearer token for url"},
+						"timeout": {"type": "integer", "description": "Seconds to wait for the train", "default": 1800},
+						"interval": {"type": "integer", "description": "Seconds between checks", "default": 10}
+					}
+				},
+				"verify_latest": {"type": "boolean", "description": "Verify dist-tag and tarball integrity when the release succeeds", "default": false},
+				"replication_lag_threshold": {"type": "integer", "description": "Seconds after publishing beyond which slow registry propagation is reported", "default": 0},
+				"replication_lag_webhook": {"type": "string", "description": "URL receiving a JSON POST when replication_lag_threshold is exceeded"},
+				"lock": {"type": "boolean", "description": "Hold a sentinel dist-tag while publishing", "default": false},
+				"lock_tag": {"type": "string", "description": "Prefix of the lock ticket dist-tags", "default": "releasing"},
+				"allow_semver_tags": {"type": "boolean", "description": "Accept publish tags that parse as a semver range, for legacy registries", "default": false},
+				"retries": {"type": "integer", "description": "Times a publish failing with a transient network or registry error is retried", "default": 0},
+				"retry_delay": {"type": "integer", "description": "Seconds before the first retry, doubling for each further one", "default": 2},
+				"lock_timeout": {"type": "integer", "description": "Seconds to wait for another release's lock (0 fails fast)", "default": 0},
+				"lock_ttl": {"type": "integer", "description": "Seconds after which a lock ticket is considered stale and removed", "default": 3600},
+				"pack_manifest": {"type": "string", "description": "Path to write the canonical JSON manifest of the published tarball"},
+				"missing_manifest": {"type": "string", "enum": ["fail", "skip", "generate"], "description": "Behavior when package_dir has no package.json", "default": "fail"},
+				"package_name": {"type": "string", "description": "Package name for a generated package.json"},
+				"manifest_template": {"type": "string", "description": "Inline JSON template or file rendered with the release into the generated package.json; implies missing_manifest generate"},
+				"version_source": {"type": "string", "enum": ["context", "package_json", "env", "command"], "description": "Where the published version comes from", "default": "context"},
+				"version_env": {"type": "string", "description": "Environment variable holding the version (version_source: env)"},
+				"version_command": {"type": "array", "items": {"type": "string"}, "description": "Command whose output is the version (version_source: command)"},
+				"version_template": {"type": "string", "description": "Template deriving the published version, e.g. ${version}-${branch}.${sha:0:7}"},
+				"prerelease_iteration": {"type": "boolean", "description": "Compute the next free prerelease iteration from the registry", "default": false},
+				"graduation_report": {"type": "boolean", "description": "Report prereleases superseded by a stable release", "default": false},
+				"major_tag": {"type": "string", "description": "Dist-tag template such as latest-{{.Major}} kept on the newest stable version of each major line"},
+				"graduate_tags": {"type": "boolean", "description": "Move dist-tags from superseded prereleases to the stable release", "default": false},
+				"verify_checkout": {"type": "boolean", "description": "Fail when the git checkout does not match the release branch and commit", "default": false},
+				"pack_destination": {"type": "string", "description": "Directory to pack the tarball into before publishing it"},
+				"ephemeral_npmrc": {
+					"type": "object",
+					"description": "Write a temporary npmrc with a token scoped to the publish registry and delete it afterwards",
+					"properties": {
+						"enabled": {"type": "boolean", "default": false},
+						"token_env": {"type": "string", "description": "Environment variable holding the token", "default": "NPM_TOKEN"},
+						"token": {"type": "string", "description": "Literal token; prefer token_env"}
+					}
+				},
+				"userconfig": {"type": "string", "description": "npmrc file passed to npm as --userconfig"},
+				"globalconfig": {"type": "string", "description": "npmrc file passed to npm as --globalconfig"},
+				"code_scan": {"type": "string", "enum": ["warn", "fail"], "description": "Scan packed JavaScript for debugger statements and banned patterns"},
+				"banned_patterns": {"type": "array", "items": {"type": "string"}, "description": "Regular expressions the code scan reports"},
+				"sourcemaps": {"type": "string", "enum": ["include", "exclude", "external"], "description": "Source map policy enforced on the pack contents"},
+				"module_check": {"type": "string", "enum": ["warn", "fail"], "description": "Check entry points for ESM/CommonJS type mismatches and dual package hazards"},
+				"copy_files": {"type": "array", "items": {"type": "string"}, "description": "Files such as the root LICENSE copied into the package for packing and removed afterwards"},
+				"resolve_local_deps": {"type": "boolean", "description": "Rewrite workspace:, file: and link: dependencies to concrete versions in the published package.json", "default": true},
+				"license_check": {"type": "string", "enum": ["check", "fix"], "description": "Validate the license field as an SPDX expression; fix rewrites common mistakes in the published package.json"},
+				"allowed_licenses": {"type": "array", "items": {"type": "string"}, "description": "License identifiers license_check accepts"},
+				"manifest_leaks": {
+					"type": "object",
+					"description": "Fail when the published package.json exposes internal infrastructure",
+					"properties": {
+						"enabled": {"type": "boolean", "description": "Check with the built-in patterns only"},
+						"fields": {"type": "array", "items": {"type": "string"}, "description": "Dotted field paths that must not be published"},
+						"patterns": {"type": "array", "items": {"type": "string"}, "description": "Regular expressions no manifest string may match"}
+					}
+				},
+				"strip_fields": {"type": "array", "items": {"type": "string"}, "description": "package.json fields (dotted paths such as overrides or scripts.prepare) removed from the published manifest"},
+				"expected_outputs": {"type": "array", "items": {"type": "string"}, "description": "Globs that must match non-empty packed files"},
+				"bundled_deps": {"type": "string", "enum": ["check", "vendor"], "description": "Fail on or vendor bundled dependencies hoisted out of the package"},
+				"ignore_scripts": {"type": "boolean", "description": "Skip lifecycle scripts during pack and publish", "default": false},
+				"foreground_scripts": {"type": "boolean", "description": "Run lifecycle scripts in the foreground", "default": false},
+				"sandbox": {"type": "string", "enum": ["auto", "required"], "description": "Run pack and publish in a filesystem sandbox (bubblewrap or sandbox-exec)"},
+				"dependency_notes": {"type": "boolean", "description": "Add dependency changes since the previous published version to the release notes", "default": false},
+				"publish_history": {"type": "boolean", "description": "Output the previously published version and time since it was published", "default": false},
+				"test_registry": {"type": "boolean", "description": "Publish to a throwaway registry and verify the package installs", "default": false},
+				"test_registry_url": {"type": "string", "description": "Existing registry (e.g. Verdaccio) used by test_registry instead of the embedded one"},
+				"messages": {
+					"type": "object",
+					"description": "Templates for publish messages",
+					"properties": {
+						"published": {"type": "string", "description": "Message after a successful publish"},
+						"skipped": {"type": "string", "description": "Message when publishing is skipped"},
+						"failed": {"type": "string", "description": "Error message when publishing fails"}
+					}
+				},
+				"blackout_windows": {
+					"type": "array",
+					"description": "Change freezes during which publishes are refused or delayed",
+					"items": {
+						"type": "object",
+						"properties": {
+							"start": {"type": "string", "description": "Start of a fixed window (2006-01-02 or 2006-01-02T15:04)"},
+							"end": {"type": "string", "description": "End of a fixed window; a date-only end includes the whole day"},
+							"days": {"type": "array", "items": {"type": "string", "enum": ["mon", "tue", "wed", "thu", "fri", "sat", "sun"]}, "description": "Weekdays of a recurring window"},
+							"from": {"type": "string", "description": "Daily start of a recurring window (15:04)"},
+							"to": {"type": "string", "description": "Daily end of a recurring window (15:04)"},
+							"timezone": {"type": "string", "description": "IANA time zone", "default": "UTC"},
+							"reason": {"type": "string", "description": "Shown when a publish is refused"}
+						}
+					}
+				},
+				"blackout_mode": {"type": "string", "enum": ["refuse", "wait"], "description": "Refuse publishes inside a blackout window or wait for it to end", "default": "refuse"},
+				"blackout_max_wait": {"type": "integer", "description": "Seconds wait mode waits for a window to end", "default": 3600},
+				"tag_policy": {
+					"type": "array",
+					"description": "Rules selecting the dist-tag; the first match overrides tag",
+					"items": {
+						"type": "object",
+						"properties": {
+							"range": {"type": "string", "description": "semver range the version must satisfy"},
+							"release_type": {"type": "string", "description": "Release type to match (major, minor, patch)"},
+							"prerelease": {"type": "boolean", "description": "Match only prerelease (true) or stable (false) versions"},
+							"tag": {"type": "string", "description": "dist-tag to use"}
+						},
+						"required": ["tag"]
+					}
+				},
+				"tag_map": {
+					"type": "object",
+					"description": "dist-tags by branch and release type, used when no tag_policy rule matches; prereleases never get latest",
+					"properties": {
+						"branches": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Branch names or globs to dist-tags"},
+						"release_types": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Release types (major, minor, patch) to dist-tags"},
+						"prerelease": {"type": "string", "description": "dist-tag for prereleases no branch matched"}
+					}
+				}
 			}
 		}`,
 	}
 }
 
 // Execute runs the plugin for a given hook.
-func (p *NpmPlugin) Execute(ctx context.Context, req plugin.ExecuteRequest) (*plugin.ExecuteResponse, error) {
+func (p *NpmPlugin) Execute(ctx context.Context, req plugin.ExecuteRequest) (resp *plugin.ExecuteResponse, err error) {
 	cfg := p.parseConfig(req.Config)
 
-	switch req.Hook {
-	case plugin.HookPrePublish:
-		if cfg.UpdateVersion {
-			return p.updatePackageVersion(ctx, cfg, req.Context, req.DryRun)
+	if cfg.DebugTranscript {
+		var tr *transcript
+		ctx, tr = withTranscript(ctx)
+		defer func() {
+			if resp == nil {
+				return
+			}
+			if resp.Outputs == nil {
+				resp.Outputs = map[string]any{}
+			}
+			resp.Outputs["transcript"] = tr.Entries()
+		}()
+	}
+
+	inputs, err := applyPluginInputs(cfg, req.Context)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	if len(inputs) > 0 {
+		defer func() {
+			if resp != nil {
+				setOutput(resp, "inputs", inputs)
+			}
+		}()
+	}
+
+	if cfg.Lerna {
+		if err := applyLernaConfig(cfg); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+	}
+	if cfg.Rush {
+		if err := applyRushConfig(cfg); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
 		}
+	}
+
+	mode := missingManifestMode(cfg)
+	missing := req.Hook != plugin.HookPostNotes && mode != missingManifestFail && manifestMissing(cfg)
+	if missing && mode == missingManifestSkip {
+		return skipResponse("missing_manifest", "No package.json in package directory, skipping npm"), nil
+	}
+
+	releaseCtx, err := resolveVersion(ctx, cfg, req.Context)
+	if err != nil {
 		return &plugin.ExecuteResponse{
-			Success: true,
-			Message: "Version update disabled",
+			Success: false,
+			Error:   fmt.Sprintf("failed to resolve version: %v", err),
+		}, nil
+	}
+
+	if err := applyRegistryPreset(cfg, releaseCtx); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("registry preset failed: %v", err),
+		}, nil
+	}
+	if err := applyRegistryProvider(ctx, cfg, req.Hook, req.DryRun); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("registry preset failed: %v", err),
+		}, nil
+	}
+
+	releaseCtx, err = applyPrereleaseIteration(ctx, cfg, releaseCtx)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to compute prerelease iteration: %v", err),
+		}, nil
+	}
+
+	if tag, ok := matchTagPolicy(cfg.TagPolicy, releaseCtx); ok {
+		cfg.Tag, cfg.policyTag = tag, true
+	} else if tag, ok, err := matchTagMap(cfg.TagMap, releaseCtx); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
 		}, nil
+	} else if ok {
+		cfg.Tag, cfg.policyTag = tag, true
+	}
+
+	if len(cfg.Workspaces) == 0 {
+		dir, err := validatePackageDir(cfg.PackageDir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid package directory: %v", err),
+			}, nil
+		}
+		applied, err := p.applyPackageDefaults(cfg, dir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		if len(applied) > 0 {
+			defer func() {
+				if resp != nil {
+					setOutput(resp, "package_defaults", applied)
+				}
+			}()
+		}
+	}
+
+	if missing && mode == missingManifestGenerate {
+		if err := validateMissingManifest(cfg); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("configuration validation failed: %v", err),
+			}, nil
+		}
+		if err := generateManifest(cfg, releaseCtx); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		defer func() {
+			if resp != nil {
+				setOutput(resp, "manifest_generated", true)
+			}
+		}()
+	}
+
+	if cfg.SimulateFailure.enabled() {
+		if err := validateSimulatedFailure(cfg.SimulateFailure); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("simulate_failure validation failed: %v", err),
+			}, nil
+		}
+		if resp := simulatedFailure(cfg.SimulateFailure, req.Hook); resp != nil {
+			return resp, nil
+		}
+		// The hooks before the failing step run, but never write
+		cfg.DryRun = true
+		req.DryRun = true
+	}
+
+	if cfg.Idempotency != "" && idempotentHooks[req.Hook] && !req.DryRun && !cfg.DryRun {
+		duplicate, guard, err := checkIdempotency(cfg, req.Hook, releaseCtx)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("idempotency check failed: %v", err),
+			}, nil
+		}
+		if duplicate != nil {
+			return duplicate, nil
+		}
+		defer func() {
+			if resp != nil && resp.Success {
+				guard.record(resp, time.Now())
+			}
+		}()
+	}
+
+	switch req.Hook {
+	case plugin.HookPostNotes:
+		return p.dependencyNotes(ctx, cfg, releaseCtx)
+
+	case plugin.HookPrePublish:
+		if cfg.lerna != nil {
+			return p.lernaPrePublish(ctx, cfg, releaseCtx, req.DryRun)
+		}
+		if cfg.rush != nil {
+			return p.rushPrePublish(ctx, cfg, releaseCtx, req.DryRun)
+		}
+		if len(cfg.Workspaces) > 0 {
+			return p.runWorkspaces(ctx, cfg, releaseCtx, req.DryRun, workspaceScope{}, p.prePublish)
+		}
+		return p.prePublish(ctx, cfg, releaseCtx, req.DryRun)
 
 	case plugin.HookPostPublish:
-		return p.publishPackage(ctx, cfg, req.Context, req.DryRun || cfg.DryRun)
+		dryRun := req.DryRun || cfg.DryRun
+		var skipReason string
+		if len(cfg.SkipOn.ReleaseTypes) > 0 || cfg.SkipOn.CommitMarker != "" {
+			dir, _ := validatePackageDir(cfg.PackageDir)
+			skipReason = skipOnReason(ctx, cfg.SkipOn, dir, releaseCtx)
+		}
+		var changes *releaseChanges
+		var changesWarning string
+		if cfg.OnlyChanged {
+			var diffErr error
+			if changes, diffErr = diffSinceRelease(ctx, releaseCtx); diffErr != nil {
+				changesWarning = fmt.Sprintf("only_changed ignored, publishing everything: %v", diffErr)
+			}
+		}
+		unchanged := len(cfg.Workspaces) == 0 && !changes.touches(cfg.PackageDir)
+		var blackoutWarning string
+		var blackoutWaited time.Duration
+		if len(cfg.BlackoutWindows) > 0 && skipReason == "" && !unchanged && !cfg.TestRegistry {
+			if dryRun {
+				if w, until, ok := activeBlackout(cfg.BlackoutWindows, time.Now()); ok {
+					blackoutWarning = "publish would be refused, " + blackoutMessage(w, until)
+				}
+			} else if blackoutWaited, err = checkBlackout(ctx, cfg, time.Now()); err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("publish refused: %v", err),
+				}, nil
+			}
+		}
+		if cfg.DistDir != "" && skipReason == "" && !unchanged {
+			if err := applyDistDir(cfg); err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   err.Error(),
+				}, nil
+			}
+		}
+		if cfg.TarballPath != "" && skipReason == "" && !unchanged {
+			if err := applyTarballPath(cfg); err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   err.Error(),
+				}, nil
+			}
+			if err := checkTarballManifest(cfg, releaseCtx.Version); err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("tarball_path: %v", err),
+				}, nil
+			}
+		}
+		var resp *plugin.ExecuteResponse
+		switch {
+		case skipReason != "":
+			resp = skipResponse("skip_on", fmt.Sprintf("Skipping npm publish: %s", skipReason))
+		case unchanged:
+			resp = skipResponse("unchanged", fmt.Sprintf("Skipping npm publish: no changes since %s", changes.Base))
+		case cfg.EndOfLife.Enabled || cfg.SupersededBy != "":
+			resp, err = p.endOfLife(ctx, cfg, releaseCtx, dryRun)
+		case cfg.TestRegistry:
+			resp, err = p.testRegistryPublish(ctx, cfg, releaseCtx)
+		case len(cfg.Workspaces) > 0:
+			scope := workspaceScope{changes: changes, fromPackage: cfg.lerna != nil}
+			if cfg.rush != nil {
+				scope = rushPublishScope(cfg, changes)
+			}
+			resp, err = p.runWorkspaces(ctx, cfg, releaseCtx, dryRun, scope, p.publishPackage)
+		default:
+			resp, err = p.publishPackage(ctx, cfg, releaseCtx, dryRun)
+		}
+		if resp != nil && changesWarning != "" {
+			if resp.Outputs == nil {
+				resp.Outputs = map[string]any{}
+			}
+			appendWarning(resp.Outputs, changesWarning)
+		}
+		if resp != nil && (blackoutWarning != "" || blackoutWaited > 0) {
+			if resp.Outputs == nil {
+				resp.Outputs = map[string]any{}
+			}
+			if blackoutWarning != "" {
+				appendWarning(resp.Outputs, blackoutWarning)
+			}
+			if blackoutWaited > 0 {
+				resp.Outputs["blackout_wait_seconds"] = int(blackoutWaited.Round(time.Second) / time.Second)
+			}
+		}
+		applyMessageTemplates(cfg, releaseCtx, resp, dryRun)
+		return resp, err
+
+	case plugin.HookOnSuccess:
+		return p.verifyLatest(ctx, cfg, releaseCtx, req.DryRun || cfg.DryRun)
+
+	case plugin.HookOnError:
+		if cfg.Rollback.Action != "" && len(cfg.Workspaces) > 0 {
+			return p.runWorkspaces(ctx, cfg, releaseCtx, req.DryRun || cfg.DryRun, workspaceScope{}, p.rollback)
+		}
+		return p.rollback(ctx, cfg, releaseCtx, req.DryRun || cfg.DryRun)
 
 	default:
 		return &plugin.ExecuteResponse{
@@ -106,6 +1142,199 @@ func (p *NpmPlugin) Execute(ctx context.Context, req plugin.ExecuteRequest) (*pl
 	}
 }
 
+// prePublish runs the pre-publish steps: the package.json version update
+// followed by the optional README version sync.
+func (p *NpmPlugin) prePublish(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
+	resp := &plugin.ExecuteResponse{
+		Success: true,
+		Message: "Version update disabled",
+	}
+	if cfg.UpdateVersion {
+		var err error
+		if useYarnVersion(cfg) {
+			resp, err = p.yarnVersion(ctx, cfg, releaseCtx, dryRun)
+		} else {
+			resp, err = p.updatePackageVersion(ctx, cfg, releaseCtx, dryRun)
+		}
+		if err != nil || !resp.Success {
+			return resp, err
+		}
+		if cfg.DependentRanges != "" && len(cfg.Workspaces) > 0 {
+			packageDir, err := validatePackageDir(cfg.PackageDir)
+			if err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("invalid package directory: %v", err),
+				}, nil
+			}
+			pkg, err := readPackageJSON(packageDir)
+			if err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   err.Error(),
+				}, nil
+			}
+			updated, err := updateDependentRanges(cfg, pkg.Name, releaseCtx.Version, dryRun)
+			if err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("failed to update dependent ranges: %v", err),
+				}, nil
+			}
+			if len(updated) > 0 {
+				setOutput(resp, "dependent_ranges_updated", updated)
+			}
+		}
+	}
+
+	if cfg.ReadmeVersions != "" {
+		packageDir, err := validatePackageDir(cfg.PackageDir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid package directory: %v", err),
+			}, nil
+		}
+		pkg, err := readPackageJSON(packageDir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+
+		update := cfg.ReadmeVersions == "update" && !dryRun
+		stale, err := syncReadmeVersions(packageDir, pkg.Name, releaseCtx.Version, update)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to sync README versions: %v", err),
+			}, nil
+		}
+		if len(stale) > 0 && cfg.ReadmeVersions == "fail" {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("README.md references outdated versions: %s", strings.Join(stale, ", ")),
+			}, nil
+		}
+		setOutput(resp, "readme_stale_references", stale)
+	}
+
+	if cfg.ChangelogCheck != "" {
+		packageDir, err := validatePackageDir(cfg.PackageDir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid package directory: %v", err),
+			}, nil
+		}
+		if err := validateChangelogFile(cfg.ChangelogFile); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid changelog_file: %v", err),
+			}, nil
+		}
+
+		problem, err := checkChangelog(packageDir, cfg.ChangelogFile, releaseCtx.Version)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to check changelog: %v", err),
+			}, nil
+		}
+		if problem != "" {
+			if cfg.ChangelogCheck == "fail" {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   problem,
+				}, nil
+			}
+			setOutput(resp, "changelog_current", false)
+			appendWarning(resp.Outputs, problem)
+		} else {
+			setOutput(resp, "changelog_current", true)
+		}
+	}
+
+	if cfg.Preflight != "" {
+		packageDir, err := validatePackageDir(cfg.PackageDir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid package directory: %v", err),
+			}, nil
+		}
+		manifest, err := readManifest(packageDir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		files, err := listPackFiles(ctx, cfg, packageDir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("preflight failed: %v", err),
+				Outputs: scriptFailureOutputs(err),
+			}, nil
+		}
+		problems := checkPreflight(manifest, files)
+		if len(problems) > 0 && cfg.Preflight == "fail" {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("preflight checks failed: %s", strings.Join(problems, "; ")),
+				Outputs: map[string]any{"preflight_findings": problems},
+			}, nil
+		}
+		setOutput(resp, "preflight_findings", problems)
+		for _, problem := range problems {
+			appendWarning(resp.Outputs, "preflight: "+problem)
+		}
+	}
+
+	if cfg.PublishPlan != "" {
+		if err := validateOutputPath(cfg.PublishPlan); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid publish_plan: %v", err),
+			}, nil
+		}
+		packageDir, err := validatePackageDir(cfg.PackageDir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid package directory: %v", err),
+			}, nil
+		}
+		pkg, err := readPackageJSON(packageDir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		// Post-publish checks the plan against the scope-routed config
+		approved := newApprovedPlan(scopedConfig(cfg, pkg.Name), pkg.Name, releaseCtx.Version, time.Now())
+		if !dryRun {
+			if err := writeApprovedPlan(cfg.PublishPlan, approved); err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   err.Error(),
+				}, nil
+			}
+		}
+		setOutput(resp, "publish_plan", approved)
+	}
+
+	setOutput(resp, "skipped_checks", prePublishSkippedChecks(cfg))
+	if plan := prePublishPlan(cfg, releaseCtx.Version); dryRun && len(plan) > 0 {
+		setOutput(resp, "plan", plan)
+	}
+
+	return resp, nil
+}
+
 // updatePackageVersion updates the version in package.json.
 func (p *NpmPlugin) updatePackageVersion(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
 	// Validate and sanitize package directory (security check)
@@ -138,11 +1367,33 @@ func (p *NpmPlugin) updatePackageVersion(ctx context.Context, cfg *Config, relea
 
 	oldVersion := pkg["version"]
 
-	if dryRun {
+	current, _ := oldVersion.(string)
+	if err := checkCurrentVersion(cfg, releaseCtx, current); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	// Lockfiles are checked before package.json is written, so one that
+	// cannot be parsed leaves both untouched
+	lockfiles, err := updateLockfileVersions(packageDir, releaseCtx.Version, true)
+	if err != nil {
 		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	if dryRun {
+		resp := &plugin.ExecuteResponse{
 			Success: true,
 			Message: fmt.Sprintf("Would update package.json version from %v to %s", oldVersion, releaseCtx.Version),
-		}, nil
+		}
+		if len(lockfiles) > 0 {
+			setOutput(resp, "lockfiles_updated", lockfiles)
+		}
+		return resp, nil
 	}
 
 	// Update version
@@ -164,25 +1415,43 @@ func (p *NpmPlugin) updatePackageVersion(ctx context.Context, cfg *Config, relea
 		}, nil
 	}
 
+	outputs := map[string]any{
+		"old_version": oldVersion,
+		"new_version": releaseCtx.Version,
+	}
+	if len(lockfiles) > 0 {
+		if _, err := updateLockfileVersions(packageDir, releaseCtx.Version, false); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		outputs["lockfiles_updated"] = lockfiles
+	}
+
 	return &plugin.ExecuteResponse{
 		Success: true,
 		Message: fmt.Sprintf("Updated package.json version to %s", releaseCtx.Version),
-		Outputs: map[string]any{
-			"old_version": oldVersion,
-			"new_version": releaseCtx.Version,
-		},
+		Outputs: outputs,
 	}, nil
 }
 
 // validateRegistry validates and sanitizes npm registry URL.
 func validateRegistry(registry string) error {
-	if registry == "" {
+	return validateEndpointURL(registry, "registry")
+}
+
+// validateEndpointURL validates a URL the plugin will contact. Only HTTPS is
+// allowed, except plain HTTP to localhost for development. kind names the
+// URL in error messages.
+func validateEndpointURL(rawURL, kind string) error {
+	if rawURL == "" {
 		return nil
 	}
 
-	parsedURL, err := url.Parse(registry)
+	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
-		return fmt.Errorf("invalid registry URL: %w", err)
+		return fmt.Errorf("invalid %s URL: %w", kind, err)
 	}
 
 	// Only allow https URLs (or http for localhost during development)
@@ -190,20 +1459,35 @@ func validateRegistry(registry string) error {
 		if parsedURL.Scheme == "http" && (parsedURL.Host == "localhost" || strings.HasPrefix(parsedURL.Host, "127.0.0.1") || strings.HasPrefix(parsedURL.Host, "localhost:")) {
 			// Allow http for local development registries
 		} else {
-			return fmt.Errorf("registry must use HTTPS (got %s)", parsedURL.Scheme)
+			return fmt.Errorf("%s must use HTTPS (got %s)", kind, parsedURL.Scheme)
 		}
 	}
 
 	// Prevent injection via URL components
-	if strings.ContainsAny(registry, "\n\r\t") {
-		return fmt.Errorf("registry URL contains invalid characters")
+	if strings.ContainsAny(rawURL, "\n\r\t") {
+		return fmt.Errorf("%s URL contains invalid characters", kind)
 	}
 
 	return nil
 }
 
-// validateTag validates npm dist-tag format.
+// validateInstanceID validates the plugin instance id.
+func validateInstanceID(id string) error {
+	if id != "" && !idPattern.MatchString(id) {
+		return fmt.Errorf("id must be 1-64 alphanumeric, dot, hyphen or underscore characters")
+	}
+	return nil
+}
+
+// validateTag validates npm dist-tag format. Tags npm would parse as a
+// semver range ("1.0.0", "v2") are refused, as npm itself refuses them.
 func validateTag(tag string) error {
+	return checkTag(tag, false)
+}
+
+// checkTag validates a dist-tag, accepting semver-like tags when
+// allowSemver is set for legacy registries that take them.
+func checkTag(tag string, allowSemver bool) error {
 	if tag == "" {
 		return nil
 	}
@@ -213,6 +1497,9 @@ func validateTag(tag string) error {
 	if !tagPattern.MatchString(tag) {
 		return fmt.Errorf("tag contains invalid characters (must be alphanumeric, hyphens, underscores, dots)")
 	}
+	if !allowSemver && semverRangeLike(tag) {
+		return fmt.Errorf("tag %q would be parsed as a semver range, which npm refuses as a dist-tag", tag)
+	}
 	return nil
 }
 
@@ -224,15 +1511,9 @@ func validateAccess(access string) error {
 	return nil
 }
 
-// validateOTP validates one-time password format.
+// validateOTP validates one-time password format under the default policy.
 func validateOTP(otp string) error {
-	if otp == "" {
-		return nil
-	}
-	if !otpPattern.MatchString(otp) {
-		return fmt.Errorf("OTP must be 6-8 digits")
-	}
-	return nil
+	return validateOTPWithPolicy(otp, OTPPolicy{})
 }
 
 // validatePackageDir validates and sanitizes package directory path.
@@ -299,137 +1580,1315 @@ func validatePackageDir(dir string) (string, error) {
 
 // validateConfig performs security validation on all config fields.
 func (p *NpmPlugin) validateConfig(cfg *Config) error {
+	if len(cfg.parseErrors) > 0 {
+		return errors.Join(cfg.parseErrors...)
+	}
+	if err := validateInstanceID(cfg.ID); err != nil {
+		return fmt.Errorf("id validation failed: %w", err)
+	}
 	if err := validateRegistry(cfg.Registry); err != nil {
 		return fmt.Errorf("registry validation failed: %w", err)
 	}
-	if err := validateTag(cfg.Tag); err != nil {
+	if err := checkRegistryPolicy(registryURL(cfg), cfg.AllowedRegistries); err != nil {
+		return fmt.Errorf("registry validation failed: %w", err)
+	}
+	if err := validateRegions(cfg); err != nil {
+		return fmt.Errorf("regional_registries validation failed: %w", err)
+	}
+	if err := validateCodeArtifact(cfg); err != nil {
+		return fmt.Errorf("codeartifact validation failed: %w", err)
+	}
+	if err := validateScopeRegistries(cfg); err != nil {
+		return fmt.Errorf("scope_registries validation failed: %w", err)
+	}
+	if cfg.PublishURL != "" {
+		if err := validateEndpointURL(cfg.PublishURL, "publish_url"); err != nil {
+			return fmt.Errorf("publish_url validation failed: %w", err)
+		}
+		if err := checkRegistryPolicy(cfg.PublishURL, cfg.AllowedRegistries); err != nil {
+			return fmt.Errorf("publish_url validation failed: %w", err)
+		}
+	}
+	if err := checkTag(cfg.Tag, cfg.AllowSemverTags); err != nil {
 		return fmt.Errorf("tag validation failed: %w", err)
 	}
 	if err := validateAccess(cfg.Access); err != nil {
 		return fmt.Errorf("access validation failed: %w", err)
 	}
-	if err := validateOTP(cfg.OTP); err != nil {
+	if err := validateOTPPolicy(cfg.OTPPolicy); err != nil {
+		return fmt.Errorf("otp_policy validation failed: %w", err)
+	}
+	if err := validateOTPWithPolicy(cfg.OTP, cfg.OTPPolicy); err != nil {
 		return fmt.Errorf("OTP validation failed: %w", err)
 	}
-	return nil
-}
-
-// publishPackage publishes the package to npm.
-func (p *NpmPlugin) publishPackage(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
-	// Validate all config fields first (security check)
-	if err := p.validateConfig(cfg); err != nil {
-		return &plugin.ExecuteResponse{
-			Success: false,
-			Error:   fmt.Sprintf("configuration validation failed: %v", err),
-		}, nil
+	if err := checkTag(cfg.LockTag, cfg.AllowSemverTags); err != nil {
+		return fmt.Errorf("lock_tag validation failed: %w", err)
 	}
-
-	// Validate and sanitize package directory
-	packageDir, err := validatePackageDir(cfg.PackageDir)
-	if err != nil {
-		return &plugin.ExecuteResponse{
-			Success: false,
-			Error:   fmt.Sprintf("invalid package directory: %v", err),
-		}, nil
+	if err := validateOutputPath(cfg.PackManifest); err != nil {
+		return fmt.Errorf("pack_manifest validation failed: %w", err)
 	}
-
-	// Check if package.json exists
-	packagePath := filepath.Join(packageDir, "package.json")
-	data, err := os.ReadFile(packagePath)
-	if err != nil {
-		return &plugin.ExecuteResponse{
-			Success: false,
-			Error:   fmt.Sprintf("failed to read package.json: %v", err),
-		}, nil
+	if err := validateOutputPath(cfg.PackDestination); err != nil {
+		return fmt.Errorf("pack_destination validation failed: %w", err)
 	}
-
-	var pkg PackageJSON
-	if err := json.Unmarshal(data, &pkg); err != nil {
-		return &plugin.ExecuteResponse{
-			Success: false,
-			Error:   fmt.Sprintf("failed to parse package.json: %v", err),
-		}, nil
+	if err := validateTagPolicy(cfg.TagPolicy, cfg.AllowSemverTags); err != nil {
+		return fmt.Errorf("tag_policy validation failed: %w", err)
 	}
-
-	if pkg.Private {
-		return &plugin.ExecuteResponse{
-			Success: true,
-			Message: "Package is private, skipping npm publish",
-		}, nil
+	if err := validateTagMap(cfg.TagMap, cfg.AllowSemverTags); err != nil {
+		return fmt.Errorf("tag_map validation failed: %w", err)
 	}
-
-	// Build npm publish command with validated arguments
-	args := []string{"publish"}
-
-	if cfg.Registry != "" {
-		args = append(args, "--registry", cfg.Registry)
+	if err := validateBlackoutWindows(cfg.BlackoutWindows); err != nil {
+		return fmt.Errorf("blackout_windows validation failed: %w", err)
 	}
-
-	if cfg.Tag != "" {
-		args = append(args, "--tag", cfg.Tag)
+	switch cfg.BlackoutMode {
+	case "", blackoutRefuse, blackoutWait:
+	default:
+		return fmt.Errorf("blackout_mode validation failed: unknown mode %q", cfg.BlackoutMode)
 	}
-
-	if cfg.Access != "" {
-		args = append(args, "--access", cfg.Access)
+	if cfg.BlackoutMaxWait < 0 {
+		return fmt.Errorf("blackout_max_wait validation failed: must not be negative")
 	}
-
-	if cfg.OTP != "" {
-		args = append(args, "--otp", cfg.OTP)
+	switch cfg.Sourcemaps {
+	case "", sourcemapsInclude, sourcemapsExclude, sourcemapsExternal:
+	default:
+		return fmt.Errorf("sourcemaps validation failed: unknown policy %q", cfg.Sourcemaps)
 	}
-
-	if dryRun {
-		args = append(args, "--dry-run")
+	switch cfg.VersionCheck {
+	case "", versionCheckLenient, versionCheckStrict, versionCheckOff:
+	default:
+		return fmt.Errorf("version_check validation failed: unknown mode %q", cfg.VersionCheck)
 	}
-
-	// Log command (redact OTP in logs)
-	logArgs := make([]string, len(args))
-	copy(logArgs, args)
-	for i := range logArgs {
-		if i > 0 && logArgs[i-1] == "--otp" {
-			logArgs[i] = "[REDACTED]"
-		}
+	switch cfg.DependentRanges {
+	case "", dependentRangesExact, dependentRangesCaret, dependentRangesTilde:
+	default:
+		return fmt.Errorf("dependent_ranges validation failed: unknown strategy %q", cfg.DependentRanges)
 	}
-	cmdStr := fmt.Sprintf("npm %s", strings.Join(logArgs, " "))
-
+	if err := validateRegistryPin(cfg.RegistryPin); err != nil {
+		return fmt.Errorf("registry_pin validation failed: %w", err)
+	}
+	if cfg.ReplicationLagThreshold < 0 {
+		return fmt.Errorf("replication_lag_threshold must not be negative")
+	}
+	if cfg.Retries < 0 || cfg.RetryDelay < 0 {
+		return fmt.Errorf("retries and retry_delay must not be negative")
+	}
+	if cfg.VerifyTimeout < 0 {
+		return fmt.Errorf("verify_timeout must not be negative")
+	}
+	if cfg.PublishAt != "" {
+		if _, err := parsePublishAt(cfg.PublishAt); err != nil {
+			return fmt.Errorf("publish_at validation failed: %w", err)
+		}
+	}
+	if cfg.PublishAtMaxWait < 0 {
+		return fmt.Errorf("publish_at_max_wait must not be negative")
+	}
+	if err := validateEndpointURL(cfg.ReplicationLagWebhook, "replication_lag_webhook"); err != nil {
+		return err
+	}
+	if err := validateMissingManifest(cfg); err != nil {
+		return err
+	}
+	if err := validateMajorTag(cfg.MajorTag); err != nil {
+		return err
+	}
+	if err := validateBinaryDist(cfg.Binaries); err != nil {
+		return fmt.Errorf("binaries validation failed: %w", err)
+	}
+	if err := validateTypesPackage(cfg.TypesPackage); err != nil {
+		return fmt.Errorf("types_package validation failed: %w", err)
+	}
+	if err := validateDocsTarball(cfg.DocsTarball); err != nil {
+		return fmt.Errorf("docs_tarball validation failed: %w", err)
+	}
+	if err := validateDistTags(cfg.DistTags, cfg.Tag); err != nil {
+		return fmt.Errorf("dist_tags validation failed: %w", err)
+	}
+	if err := validateConsumers(cfg.Consumers); err != nil {
+		return fmt.Errorf("consumers validation failed: %w", err)
+	}
+	if err := validateDeprecate(cfg.Deprecate); err != nil {
+		return fmt.Errorf("deprecate validation failed: %w", err)
+	}
+	if err := validateCatalog(cfg.Catalog); err != nil {
+		return fmt.Errorf("catalog validation failed: %w", err)
+	}
+	if err := validateReleaseTrain(cfg.ReleaseTrain); err != nil {
+		return fmt.Errorf("release_train validation failed: %w", err)
+	}
+	if err := validateRollback(cfg.Rollback); err != nil {
+		return fmt.Errorf("rollback validation failed: %w", err)
+	}
+	if err := validateEndOfLife(cfg.EndOfLife); err != nil {
+		return fmt.Errorf("end_of_life validation failed: %w", err)
+	}
+	if err := validateSimulatedFailure(cfg.SimulateFailure); err != nil {
+		return fmt.Errorf("simulate_failure validation failed: %w", err)
+	}
+	if cfg.SupersededBy != "" && !packageNamePattern.MatchString(cfg.SupersededBy) {
+		return fmt.Errorf("superseded_by validation failed: invalid package name %q", cfg.SupersededBy)
+	}
+	if _, ok := readmeBadgePresets[cfg.ReadmeBadge]; !ok && cfg.ReadmeBadge != "" {
+		if _, err := renderReadmeBadge(cfg.ReadmeBadge, templateData{}); err != nil {
+			return fmt.Errorf("readme_badge validation failed: %w", err)
+		}
+	}
+	if cfg.NamePattern != "" && !strings.Contains(cfg.NamePattern, "{{") {
+		if _, err := compileNamePattern(cfg.NamePattern, templateData{}); err != nil {
+			return err
+		}
+	}
+	if err := validateOutputPath(cfg.EnvFile); err != nil {
+		return fmt.Errorf("env_file validation failed: %w", err)
+	}
+	switch cfg.EnvFileFormat {
+	case "", envFileGitHubOutput:
+	case envFileDotenv:
+		if cfg.EnvFile == "" {
+			return fmt.Errorf("env_file_format %q requires env_file", cfg.EnvFileFormat)
+		}
+	default:
+		return fmt.Errorf("env_file_format validation failed: unknown format %q", cfg.EnvFileFormat)
+	}
+	if err := validateOutputPath(cfg.PublishPlan); err != nil {
+		return fmt.Errorf("publish_plan validation failed: %w", err)
+	}
+	switch cfg.PublishTarget {
+	case "", publishTargetRegistry:
+	case publishTargetArtifactStore:
+		if cfg.ArtifactStore == "" {
+			return fmt.Errorf("publish_target %q requires artifact_store", cfg.PublishTarget)
+		}
+	default:
+		return fmt.Errorf("publish_target validation failed: unknown target %q", cfg.PublishTarget)
+	}
+	if err := validateArtifactStore(cfg.ArtifactStore); err != nil {
+		return fmt.Errorf("artifact_store validation failed: %w", err)
+	}
+	switch cfg.PublishMethod {
+	case "", publishMethodNpm:
+	case publishMethodAPI:
+		if names := apiIncompatibleOptions(cfg); len(names) > 0 {
+			return fmt.Errorf("publish_method api cannot be combined with options that run npm: %s", strings.Join(names, ", "))
+		}
+	default:
+		return fmt.Errorf("publish_method validation failed: unknown method %q", cfg.PublishMethod)
+	}
+	if err := validateDistDir(cfg); err != nil {
+		return fmt.Errorf("dist_dir validation failed: %w", err)
+	}
+	if err := validateTarballPath(cfg); err != nil {
+		return fmt.Errorf("tarball_path validation failed: %w", err)
+	}
+	if cfg.Provenance {
+		if _, err := provenanceProvider(); err != nil {
+			return fmt.Errorf("provenance validation failed: %w", err)
+		}
+	}
+	if err := validateSSO(cfg); err != nil {
+		return fmt.Errorf("sso validation failed: %w", err)
+	}
+	if err := validateVerification(cfg.Verification); err != nil {
+		return fmt.Errorf("verification validation failed: %w", err)
+	}
+	if cfg.TokenCheck && (cfg.TrustedPublishing || cfg.TokenExchange) {
+		return fmt.Errorf("token_check cannot be combined with trusted_publishing or token_exchange, which mint the publish token")
+	}
+	if cfg.TrustedPublishing {
+		if cfg.TokenExchange {
+			return fmt.Errorf("trusted_publishing cannot be combined with token_exchange")
+		}
+		if _, err := trustedPublishingProvider(); err != nil {
+			return fmt.Errorf("trusted_publishing validation failed: %w", err)
+		}
+	}
+	switch cfg.Sandbox {
+	case "", sandboxAuto, sandboxRequired:
+	default:
+		return fmt.Errorf("sandbox validation failed: unknown mode %q", cfg.Sandbox)
+	}
+	if cfg.TestRegistryURL != "" {
+		if err := validateEndpointURL(cfg.TestRegistryURL, "test_registry_url"); err != nil {
+			return fmt.Errorf("test_registry_url validation failed: %w", err)
+		}
+	}
+	if _, err := compileBannedPatterns(cfg.BannedPatterns); err != nil {
+		return fmt.Errorf("banned_patterns validation failed: %w", err)
+	}
+	if _, err := compileGlobs("expected_outputs", cfg.ExpectedOutputs); err != nil {
+		return fmt.Errorf("expected_outputs validation failed: %w", err)
+	}
+	if err := validateCopyFiles(cfg.CopyFiles); err != nil {
+		return fmt.Errorf("copy_files validation failed: %w", err)
+	}
+	if err := validateStripFields(cfg.StripFields); err != nil {
+		return fmt.Errorf("strip_fields validation failed: %w", err)
+	}
+	if err := validateManifestLeaks(cfg.ManifestLeaks); err != nil {
+		return fmt.Errorf("manifest_leaks validation failed: %w", err)
+	}
+	if err := validateNpmrcPath(cfg.UserConfig); err != nil {
+		return fmt.Errorf("userconfig validation failed: %w", err)
+	}
+	if err := validateEphemeralNpmrc(cfg.EphemeralNpmrc, cfg.UserConfig); err != nil {
+		return fmt.Errorf("ephemeral_npmrc validation failed: %w", err)
+	}
+	if err := validateNpmrcPath(cfg.GlobalConfig); err != nil {
+		return fmt.Errorf("globalconfig validation failed: %w", err)
+	}
+	if err := validateMessageTemplates(cfg.Messages); err != nil {
+		return fmt.Errorf("messages validation failed: %w", err)
+	}
+	return nil
+}
+
+// publishPackage publishes the package to npm.
+func (p *NpmPlugin) publishPackage(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
+	// Validate all config fields first (security check)
+	if err := p.validateConfig(cfg); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("configuration validation failed: %v", err),
+		}, nil
+	}
+
+	// Validate and sanitize package directory
+	packageDir, err := validatePackageDir(cfg.PackageDir)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid package directory: %v", err),
+		}, nil
+	}
+
+	// Check if package.json exists
+	packagePath := filepath.Join(packageDir, "package.json")
+	data, err := os.ReadFile(packagePath)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to read package.json: %v", err),
+		}, nil
+	}
+
+	var pkg PackageJSON
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to parse package.json: %v", err),
+		}, nil
+	}
+
+	if pkg.Private {
+		resp := skipResponse("private", "Package is private, skipping npm publish")
+		resp.Outputs["package"] = pkg.Name
+		return resp, nil
+	}
+
+	if err := checkPackageVersion(cfg, releaseCtx, pkg.Version); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	routed := scopedConfig(cfg, pkg.Name)
+	scopeRouted := routed != cfg
+	cfg = routed
+
+	if cfg.RegistryPreset == registryPresetGitHub {
+		if err := checkGitHubPackageName(cfg, pkg.Name); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+	}
+
+	if cfg.NamePattern != "" {
+		data := newTemplateData(pkg.Name, cfg, releaseCtx)
+		if data.RepoOwner == "" || data.RepoName == "" {
+			owner, name := remoteRepo(ctx, packageDir)
+			if data.RepoOwner == "" {
+				data.RepoOwner = owner
+			}
+			if data.RepoName == "" {
+				data.RepoName = name
+			}
+		}
+		if err := checkPackageName(pkg.Name, cfg.NamePattern, data); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+	}
+
+	if cfg.PublishPlan != "" {
+		if err := checkPublishPlan(cfg, pkg.Name, releaseCtx.Version, dryRun); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+	}
+
+	if cfg.VerifyCheckout {
+		if err := verifyCheckout(ctx, packageDir, releaseCtx); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("checkout verification failed: %v", err),
+			}, nil
+		}
+	}
+
+	outputs := map[string]any{"skipped_checks": publishSkippedChecks(cfg, dryRun)}
+	if scopeRouted {
+		outputs["scope_registry"] = cfg.Registry
+	}
+	applyRegion(cfg, outputs)
+
+	done, err := useEphemeralNpmrc(cfg, outputs, dryRun)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	defer done()
+
+	if len(cfg.CopyFiles) > 0 {
+		copied, remove, err := copyIntoPackage(packageDir, cfg.CopyFiles)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		outputs["copied_files"] = copied
+		defer func() {
+			if err := remove(); err != nil {
+				appendWarning(outputs, err.Error())
+			}
+		}()
+	}
+
+	if cfg.ResolveLocalDeps && cfg.inputTarball == "" {
+		lookup := newWorkspaceVersionLookup(cfg)
+		restore, err := editPublishManifest(packageDir, dryRun, func(manifest map[string]any) (bool, error) {
+			resolved, err := resolveLocalDeps(manifest, packageDir, lookup)
+			if len(resolved) > 0 {
+				outputs["resolved_dependencies"] = resolved
+			}
+			return len(resolved) > 0, err
+		})
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to resolve local dependencies: %v", err),
+			}, nil
+		}
+		defer func() {
+			if err := restore(); err != nil {
+				appendWarning(outputs, err.Error())
+			}
+		}()
+	}
+
+	if cfg.LicenseCheck != "" && cfg.inputTarball == "" {
+		var license any
+		restore, err := editPublishManifest(packageDir, dryRun, func(manifest map[string]any) (bool, error) {
+			changed, err := applyLicensePolicy(manifest, cfg.LicenseCheck, cfg.AllowedLicenses, isPublicPackage(cfg, pkg.Name))
+			license = manifest["license"]
+			if changed {
+				outputs["license_fixed"] = true
+			}
+			return changed, err
+		})
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("license check failed: %v", err),
+			}, nil
+		}
+		if license != nil {
+			outputs["license"] = license
+		}
+		defer func() {
+			if err := restore(); err != nil {
+				appendWarning(outputs, err.Error())
+			}
+		}()
+	}
+
+	if len(cfg.StripFields) > 0 && cfg.inputTarball == "" {
+		restore, err := editPublishManifest(packageDir, dryRun, func(manifest map[string]any) (bool, error) {
+			stripped := stripManifestFields(manifest, cfg.StripFields)
+			outputs["stripped_fields"] = stripped
+			return len(stripped) > 0, nil
+		})
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to strip package.json fields: %v", err),
+			}, nil
+		}
+		defer func() {
+			if err := restore(); err != nil {
+				appendWarning(outputs, err.Error())
+			}
+		}()
+	}
+
+	if cfg.ManifestLeaks.enabled() && cfg.inputTarball == "" {
+		manifest, err := readManifest(packageDir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		// Dry runs leave package.json untouched, so strip here too
+		stripManifestFields(manifest, cfg.StripFields)
+		leaks, err := findManifestLeaks(manifest, cfg.ManifestLeaks)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("manifest leak check failed: %v", err),
+			}, nil
+		}
+		if len(leaks) > 0 {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("published package.json exposes internal details: %s", strings.Join(leaks, "; ")),
+				Outputs: map[string]any{"manifest_leaks": leaks},
+			}, nil
+		}
+	}
+
+	checksStart := time.Now()
+
+	if cfg.BundledDeps != "" {
+		issues, err := checkBundledDeps(packageDir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("bundled dependency check failed: %v", err),
+			}, nil
+		}
+		if len(issues) > 0 && cfg.BundledDeps == bundledDepsCheck {
+			problems := make([]string, len(issues))
+			for i, issue := range issues {
+				problems[i] = issue.String()
+			}
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   strings.Join(problems, "; "),
+			}, nil
+		}
+		if len(issues) > 0 && !dryRun {
+			vendored, err := vendorBundledDeps(packageDir, issues)
+			if err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("failed to vendor bundled dependencies: %v", err),
+				}, nil
+			}
+			outputs["bundled_vendored"] = vendored
+		} else if len(issues) > 0 {
+			names := make([]string, len(issues))
+			for i, issue := range issues {
+				names[i] = issue.Name
+			}
+			outputs["bundled_vendored"] = names
+		}
+	}
+
+	tool, warning, err := resolveSandbox(cfg)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	if warning != "" {
+		appendWarning(outputs, warning)
+		addSkippedCheck(outputs, "sandbox", skipNoTool)
+	}
+	if tool != "" {
+		cfg.sandbox = tool
+		outputs["sandbox"] = tool
+	}
+
+	var files []packFile
+	if needsPackFiles(cfg) {
+		files, err = listPackFiles(ctx, cfg, packageDir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to list package files: %v", err),
+			}, nil
+		}
+	}
+
+	if cfg.CodeScan != "" {
+		banned, _ := compileBannedPatterns(cfg.BannedPatterns) // validated above
+		findings, err := scanPackedCode(packageDir, files, banned)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("code scan failed: %v", err),
+			}, nil
+		}
+		if len(findings) > 0 && cfg.CodeScan == "fail" {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("code scan found %d problem(s): %s", len(findings), strings.Join(findings, "; ")),
+			}, nil
+		}
+		for _, finding := range findings {
+			appendWarning(outputs, finding)
+		}
+		outputs["code_scan_findings"] = findings
+	}
+
+	if cfg.Sourcemaps != "" {
+		problems, err := checkSourcemaps(packageDir, files, cfg.Sourcemaps)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("source map check failed: %v", err),
+			}, nil
+		}
+		if len(problems) > 0 {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("source map policy violated: %s", strings.Join(problems, "; ")),
+			}, nil
+		}
+	}
+
+	if cfg.ModuleCheck != "" {
+		manifest, err := readManifest(packageDir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		problems, err := checkModuleFormats(packageDir, manifest, files)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("module check failed: %v", err),
+			}, nil
+		}
+		if len(problems) > 0 && cfg.ModuleCheck == "fail" {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("module check found %d problem(s): %s", len(problems), strings.Join(problems, "; ")),
+			}, nil
+		}
+		for _, problem := range problems {
+			appendWarning(outputs, problem)
+		}
+		outputs["module_check_findings"] = problems
+	}
+
+	if len(cfg.ExpectedOutputs) > 0 {
+		problems, _ := checkExpectedOutputs(files, cfg.ExpectedOutputs) // validated above
+		if len(problems) > 0 {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("expected build outputs missing: %s", strings.Join(problems, "; ")),
+			}, nil
+		}
+	}
+
+	var types *generatedPackage
+	if cfg.TypesPackage.enabled() && cfg.PublishTarget != publishTargetArtifactStore {
+		types, err = prepareTypesPackage(packageDir, pkg.Name, releaseCtx.Version, cfg.TypesPackage)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("types package: %v", err),
+			}, nil
+		}
+		outputs["types_package"] = types.name
+	}
+
+	var docs *generatedPackage
+	if cfg.DocsTarball.enabled() {
+		docs, err = prepareDocsPackage(packageDir, pkg.Name, releaseCtx.Version, cfg.DocsTarball)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("docs tarball: %v", err),
+			}, nil
+		}
+	}
+
+	checks := time.Since(checksStart)
+
+	if cfg.SSO.enabled() && cfg.PublishTarget != publishTargetArtifactStore {
+		registry := publishRegistry(cfg)
+		if registry == "" {
+			registry = defaultRegistry
+		}
+		cleanup, err := applySSO(cfg, registry)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("sso: %v", err),
+			}, nil
+		}
+		defer func() {
+			if err := cleanup(); err != nil {
+				appendWarning(outputs, err.Error())
+			}
+		}()
+		if cfg.SSO.Detect {
+			if err := detectSSO(ctx, cfg, registry); err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("sso: %v", ssoGuidance(cfg, registry, err)),
+				}, nil
+			}
+		}
+	}
+
+	if cfg.TokenCheck && cfg.PublishTarget != publishTargetArtifactStore {
+		now := time.Now()
+		info, err := checkPublishToken(ctx, cfg, pkg.Name, now)
+		if err != nil {
+			if !dryRun {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("token check failed: %v", err),
+				}, nil
+			}
+			appendWarning(outputs, "token check failed: "+err.Error())
+		}
+		if info != nil {
+			outputs["token_user"] = info.User
+			if info.Expires != "" {
+				outputs["token_expires"] = info.Expires
+			}
+			if err == nil && tokenExpiresSoon(info, now) {
+				appendWarning(outputs, fmt.Sprintf("the publish token expires at %s", info.Expires))
+			}
+		}
+	}
+
+	// Build npm publish command with validated arguments. --json lets the
+	// uploaded tarball integrity be recorded for later verification.
+	args := append([]string{"publish", "--json"}, npmConfigArgs(cfg)...)
+
+	if registry := publishRegistry(cfg); registry != "" {
+		args = append(args, "--registry", registry)
+	}
+
+	if cfg.Tag != "" {
+		args = append(args, "--tag", cfg.Tag)
+	}
+
+	if cfg.Access != "" {
+		args = append(args, "--access", cfg.Access)
+	}
+
+	if cfg.Provenance {
+		provider, _ := provenanceProvider() // validated above
+		args = append(args, "--provenance")
+		outputs["provenance"] = provider
+	}
+
+	args = append(args, otpArgs(cfg)...)
+
+	args = append(args, scriptArgs(cfg)...)
+
+	// The command a real run would execute, for the dry-run plan
+	realCmd := fmt.Sprintf("npm %s", strings.Join(redactArgs(args), " "))
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+
+	// Log command (redact OTP in logs)
+	cmdStr := fmt.Sprintf("npm %s", strings.Join(redactArgs(args), " "))
+	if cfg.PublishMethod == publishMethodAPI {
+		registry := publishRegistry(cfg)
+		if registry == "" {
+			registry = defaultRegistry
+		}
+		realCmd = "PUT " + packumentURL(registry, pkg.Name)
+		cmdStr = realCmd
+		outputs["publish_method"] = publishMethodAPI
+	}
+
+	// Expand CDN purge targets up front so template errors fail before publishing
+	purgeURLs, err := cdnPurgeURLs(cfg.CDNPurge, newTemplateData(pkg.Name, cfg, releaseCtx))
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid cdn_purge configuration: %v", err),
+		}, nil
+	}
+	consumerEndpoints, err := consumerURLs(cfg.Consumers, newTemplateData(pkg.Name, cfg, releaseCtx))
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid consumers configuration: %v", err),
+		}, nil
+	}
+
+	// Query publish history before publishing so the new version is not
+	// mistaken for the previous one
+	if cfg.PublishHistory {
+		addPublishHistory(ctx, cfg, outputs, pkg.Name, releaseCtx.Version, time.Now())
+	}
+
+	if cfg.PublishTarget != publishTargetArtifactStore {
+		if err := checkPublicFirstPublish(ctx, cfg, pkg.Name); err != nil {
+			if !dryRun {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("publish refused: %v", err),
+				}, nil
+			}
+			appendWarning(outputs, "publish would be refused: "+err.Error())
+		}
+	}
+
 	if dryRun {
+		outputs["package"] = pkg.Name
+		outputs["version"] = releaseCtx.Version
+		outputs["command"] = cmdStr
+		if cfg.PublishAt != "" {
+			at, _, err := publishDelay(cfg, time.Now())
+			if err != nil {
+				appendWarning(outputs, err.Error())
+			}
+			outputs["publish_at"] = at.UTC().Format(time.RFC3339)
+		}
+		outputs["package_dir"] = packageDir
+		outputs["update_hint"] = newUpdateHint(pkg.Name, releaseCtx.Version, releaseCtx.PreviousVersion, releaseCtx.ReleaseType, cfg.Tag)
+		if len(purgeURLs) > 0 {
+			outputs["cdn_purge_urls"] = purgeURLs
+		}
+		if len(consumerEndpoints) > 0 {
+			outputs["consumer_urls"] = consumerEndpoints
+		}
+		if docs != nil {
+			outputs["docs_tarball"] = docsTarballPath(cfg.DocsTarball, pkg.Name, releaseCtx.Version)
+			if cfg.DocsTarball.Publish && cfg.PublishTarget != publishTargetArtifactStore {
+				outputs["docs_package"] = docs.name
+			}
+		}
+		if cfg.inputTarball != "" {
+			outputs["tarball"] = cfg.inputTarball
+		} else if cfg.PackDestination != "" && cfg.PublishMethod != publishMethodAPI {
+			outputs["pack_command"] = "npm " + strings.Join(packArgs(cfg, cfg.PackDestination), " ")
+		}
+		if cfg.ReadmeBadge != "" {
+			badge, err := renderReadmeBadge(cfg.ReadmeBadge, newTemplateData(pkg.Name, cfg, releaseCtx))
+			if err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("invalid readme_badge: %v", err),
+				}, nil
+			}
+			outputs["readme_badge"] = badge
+		}
+		if cfg.GraduationReport || cfg.GraduateTags {
+			addGraduationReport(ctx, cfg, outputs, packageDir, pkg.Name, releaseCtx.Version, true)
+		}
+		if cfg.MajorTag != "" {
+			reconcileMajorTags(ctx, cfg, outputs, packageDir, pkg.Name, releaseCtx.Version, true)
+		}
+		if cfg.DistTags.enabled() {
+			applyDistTags(ctx, cfg, outputs, packageDir, pkg.Name, releaseCtx.Version, true)
+		}
+		if len(cfg.Deprecate.Ranges) > 0 {
+			deprecateSuperseded(ctx, cfg, outputs, packageDir, pkg.Name, releaseCtx.Version, newTemplateData(pkg.Name, cfg, releaseCtx), true)
+		}
+		if cfg.RegistryDiff {
+			diff, err := diffAgainstRegistry(ctx, cfg, packageDir, pkg.Name, releaseCtx, files)
+			if err != nil {
+				appendWarning(outputs, fmt.Sprintf("registry diff unavailable: %v", err))
+			} else {
+				outputs["registry_diff"] = diff
+			}
+		}
+		if len(cfg.Binaries.Platforms) > 0 {
+			outputs["binary_mode"] = binaryMode(cfg.Binaries)
+			outputs["platform_packages"] = platformPackageNames(cfg.Binaries, pkg.Name)
+		}
+		level := dryRunLevel(cfg)
+		outputs["dry_run_level"] = level
+		var levelErr error
+		switch {
+		case level == dryRunFull && cfg.PublishMethod != publishMethodAPI && cfg.PublishTarget != publishTargetArtifactStore:
+			levelErr = dryRunPublish(ctx, cfg, packageDir, pkg.Name, args, outputs)
+		case level == dryRunFull, level == dryRunPack:
+			// Without npm publish there is nothing more to run than the pack
+			levelErr = dryRunPackage(ctx, cfg, packageDir, newTemplateData(pkg.Name, cfg, releaseCtx), outputs)
+		default:
+			// A report, not a check: the publish itself would surface why
+			// the contents cannot be listed
+			if result, err := packContents(ctx, cfg, packageDir); err != nil {
+				appendWarning(outputs, fmt.Sprintf("could not list the package contents: %v", err))
+			} else {
+				addPackReport(outputs, result)
+			}
+		}
+		if levelErr != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("%s dry run failed: %v", level, levelErr),
+				Outputs: scriptFailureOutputs(levelErr),
+			}, nil
+		}
+		outputs["plan"] = publishPlan(cfg, pkg.Name, releaseCtx.Version, realCmd, purgeURLs, checks)
+		if cfg.PublishTarget == publishTargetArtifactStore {
+			outputs["artifact_store"] = cfg.ArtifactStore
+			return &plugin.ExecuteResponse{
+				Success: true,
+				Message: fmt.Sprintf("Would upload %s@%s to %s (in %s)", pkg.Name, releaseCtx.Version, cfg.ArtifactStore, packageDir),
+				Outputs: outputs,
+			}, nil
+		}
+		message := fmt.Sprintf("Would run: %s (in %s)", cmdStr, packageDir)
+		if level == dryRunFull && cfg.PublishMethod != publishMethodAPI {
+			message = fmt.Sprintf("Ran: %s (in %s)", cmdStr, packageDir)
+		}
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: message,
+			Outputs: outputs,
+		}, nil
+	}
+
+	if err := waitForPublishAt(ctx, cfg, outputs, time.Now()); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+			Outputs: outputs,
+		}, nil
+	}
+	if cfg.ReleaseTrain.enabled() {
+		if err := waitForTrain(ctx, cfg.ReleaseTrain, newTemplateData(pkg.Name, cfg, releaseCtx), outputs); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+				Outputs: outputs,
+			}, nil
+		}
+	}
+
+	if cfg.PublishTarget == publishTargetArtifactStore {
+		if err := publishToArtifactStore(ctx, cfg, packageDir, newTemplateData(pkg.Name, cfg, releaseCtx), outputs); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("artifact store upload failed: %v", err),
+				Outputs: scriptFailureOutputs(err),
+			}, nil
+		}
+		if cfg.EnvFile != "" || cfg.EnvFileFormat != "" {
+			addEnvFile(cfg, outputs)
+		}
 		return &plugin.ExecuteResponse{
 			Success: true,
-			Message: fmt.Sprintf("Would run: %s (in %s)", cmdStr, packageDir),
-			Outputs: map[string]any{
-				"package":     pkg.Name,
-				"version":     releaseCtx.Version,
-				"command":     cmdStr,
-				"package_dir": packageDir,
-			},
+			Message: fmt.Sprintf("Uploaded %s@%s to %s", pkg.Name, releaseCtx.Version, outputs["artifact_url"]),
+			Outputs: outputs,
 		}, nil
 	}
 
-	// Execute npm publish
-	cmd := exec.CommandContext(ctx, "npm", args...)
-	cmd.Dir = packageDir
+	if cfg.RegistryPreset == registryPresetGitHub {
+		if err := checkGitHubToken(ctx); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+	}
+
+	if len(cfg.RegistryPin.IPRanges) > 0 || len(cfg.RegistryPin.CertSHA256) > 0 {
+		registry := publishRegistry(cfg)
+		if registry == "" {
+			registry = defaultRegistry
+		}
+		if err := verifyRegistryPin(ctx, registry, cfg.RegistryPin); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("registry pin verification failed: %v", err),
+			}, nil
+		}
+		outputs["registry_pinned"] = true
+	}
+
+	if cfg.SkipExisting && cfg.PublishTarget != publishTargetArtifactStore {
+		exists, err := checkExistingVersion(ctx, cfg, packageDir, pkg.Name, releaseCtx.Version)
+		switch {
+		case errors.Is(err, errPublishedDiffers):
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		case err != nil:
+			appendWarning(outputs, err.Error())
+		case exists:
+			outputs["package"] = pkg.Name
+			outputs["version"] = releaseCtx.Version
+			outputs["skipped"] = true
+			outputs["skip_reason"] = "already_published"
+			return &plugin.ExecuteResponse{
+				Success: true,
+				Message: fmt.Sprintf("%s@%s is already published with matching integrity", pkg.Name, releaseCtx.Version),
+				Outputs: outputs,
+			}, nil
+		}
+	}
+
+	if cfg.TrustedPublishing {
+		token, err := trustedPublishToken(ctx, cfg, pkg.Name)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("trusted publishing failed: %v", err),
+			}, nil
+		}
+		registry := publishRegistry(cfg)
+		if registry == "" {
+			registry = defaultRegistry
+		}
+		cleanup, err := useAuthToken(cfg, registry, token)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("trusted publishing failed: %v", err),
+			}, nil
+		}
+		defer func() {
+			if err := cleanup(); err != nil {
+				appendWarning(outputs, err.Error())
+			}
+		}()
+		args = setFlag(args, "--userconfig", cfg.UserConfig)
+		outputs["trusted_publishing"] = true
+	}
+
+	if cfg.TokenExchange {
+		registry, token, revoke, err := exchangePublishToken(ctx, cfg, pkg.Name, releaseCtx.Version)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("token exchange failed: %v", err),
+			}, nil
+		}
+		defer func() {
+			if err := revoke(); err != nil {
+				appendWarning(outputs, err.Error())
+				outputs["token_revoked"] = false
+			} else {
+				outputs["token_revoked"] = true
+			}
+		}()
+		// Every later npm call, including platform packages, uses the
+		// package-scoped token instead of the ambient credentials
+		cleanup, err := useAuthToken(cfg, registry, token)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("token exchange failed: %v", err),
+			}, nil
+		}
+		defer func() {
+			if err := cleanup(); err != nil {
+				appendWarning(outputs, err.Error())
+			}
+		}()
+		args = setFlag(args, "--userconfig", cfg.UserConfig)
+	}
+
+	restoreBinaries := func() error { return nil }
+	if cfg.Lock {
+		lock, err := acquireReleaseLock(ctx, cfg, packageDir, pkg.Name)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		if lock != nil {
+			defer func() {
+				if err := lock.release(ctx); err != nil {
+					appendWarning(outputs, err.Error())
+				}
+			}()
+		}
+	}
+
+	if len(cfg.Binaries.Platforms) > 0 {
+		outputs["binary_mode"] = binaryMode(cfg.Binaries)
+		if binaryMode(cfg.Binaries) == binaryModeOptionalDeps {
+			published, err := publishPlatformPackages(ctx, cfg, pkg.Name, releaseCtx.Version)
+			outputs["platform_packages"] = published
+			if err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   err.Error(),
+					Outputs: outputs,
+				}, nil
+			}
+		}
+		restore, err := scaffoldBinaries(packageDir, pkg.Name, releaseCtx.Version, cfg.Binaries)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+				Outputs: outputs,
+			}, nil
+		}
+		restoreBinaries = restore
+		defer func() { _ = restoreBinaries() }()
+	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// The README badge is injected into a packed tarball only, so a badge
+	// also means packing first. A tarball from an earlier plugin is published
+	// as built.
+	if cfg.inputTarball != "" {
+		if cfg.ReadmeBadge != "" || cfg.PackDestination != "" {
+			appendWarning(outputs, "readme_badge and pack_destination are ignored when publishing the input tarball")
+		}
+		args = append([]string{"publish", cfg.inputTarball}, args[1:]...)
+		outputs["tarball"] = cfg.inputTarball
+	} else if cfg.PublishMethod != publishMethodAPI && (cfg.PackDestination != "" || cfg.ReadmeBadge != "") {
+		dest := cfg.PackDestination
+		if dest == "" {
+			tmp, err := os.MkdirTemp("", "relicta-npm-pack-")
+			if err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("failed to create pack directory: %v", err),
+				}, nil
+			}
+			defer func() { _ = os.RemoveAll(tmp) }()
+			dest = tmp
+		}
+		_, tarball, err := packForPublish(ctx, cfg, packageDir, dest, newTemplateData(pkg.Name, cfg, releaseCtx), outputs)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("npm pack failed: %v", err),
+				Outputs: scriptFailureOutputs(err),
+			}, nil
+		}
+		args = append([]string{"publish", tarball}, args[1:]...)
+		if cfg.PackDestination != "" {
+			outputs["tarball"] = tarball
+		}
+	}
 
-	err = cmd.Run()
+	// Execute npm publish, retrying transient failures
+	publish := func() (string, error) {
+		if cfg.PublishMethod != publishMethodAPI {
+			return runScriptedNpm(ctx, cfg, packageDir, args...)
+		}
+		result, err := publishViaAPI(ctx, cfg, packageDir)
+		if err != nil {
+			return "", err
+		}
+		data, _ := json.Marshal(result)
+		if cfg.PackDestination != "" && cfg.inputTarball == "" {
+			outputs["tarball"] = filepath.Join(cfg.PackDestination, result.Filename)
+		}
+		return string(data), nil
+	}
+	stdout, attempts, err := withRetries(ctx, cfg, publish)
+	if err != nil && cfg.primaryRegistry != "" && writeRejected(err) {
+		appendWarning(outputs, fmt.Sprintf("regional registry %s rejected the publish, failing over to %s: %v", cfg.Registry, cfg.primaryRegistry, err))
+		args = failoverToPrimary(cfg, args)
+		outputs["region_failover"] = true
+		stdout, attempts, err = withRetries(ctx, cfg, publish)
+	}
+	if cfg.Retries > 0 {
+		outputs["publish_attempts"] = attempts
+	}
+	if rerr := restoreBinaries(); rerr != nil {
+		appendWarning(outputs, rerr.Error())
+	}
 	if err != nil {
 		return &plugin.ExecuteResponse{
 			Success: false,
-			Error:   fmt.Sprintf("npm publish failed: %v\nstderr: %s", err, stderr.String()),
+			Error:   err.Error(),
+			Outputs: scriptFailureOutputs(err),
 		}, nil
 	}
 
+	outputs["package"] = pkg.Name
+	outputs["version"] = releaseCtx.Version
+	outputs["registry"] = cfg.Registry
+	outputs["tag"] = cfg.Tag
+	outputs["stdout"] = stdout
+	outputs["update_hint"] = newUpdateHint(pkg.Name, releaseCtx.Version, releaseCtx.PreviousVersion, releaseCtx.ReleaseType, cfg.Tag)
+	result := parsePublishOutput(stdout, pkg.Name)
+	if result.Integrity != "" {
+		outputs["integrity"] = result.Integrity
+		if digest, err := integrityDigest(result.Integrity); err == nil {
+			addProvenanceSubject(outputs, npmPurl(pkg.Name, releaseCtx.Version), digest)
+		}
+	}
+	rec := &publishRecord{
+		Name:        pkg.Name,
+		Version:     releaseCtx.Version,
+		Tag:         cfg.Tag,
+		Registry:    registryURL(cfg),
+		Integrity:   result.Integrity,
+		Shasum:      result.Shasum,
+		PublishedAt: time.Now().UTC(),
+	}
+	if err := saveRecord(cfg, rec); err != nil {
+		appendWarning(outputs, err.Error())
+	}
+
+	if cfg.VerifyPublish {
+		wait, err := waitForVersion(ctx, cfg, pkg.Name, releaseCtx.Version, time.Duration(cfg.VerifyTimeout)*time.Second)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("publish verification failed: %v", err),
+				Outputs: outputs,
+			}, nil
+		}
+		outputs["verify_publish_seconds"] = wait.Seconds()
+	}
+
+	if cfg.Verification.enabled() {
+		verdict := verifyRelease(ctx, cfg, pkg.Name, releaseCtx.Version)
+		outputs["verification"] = verdict
+		for _, failed := range verdict.failedChecks(false) {
+			appendWarning(outputs, fmt.Sprintf("advisory verification failed: %s", failed))
+		}
+		if verdict.Verdict == verdictFailed {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("verification failed for %s@%s: %s", pkg.Name, releaseCtx.Version, strings.Join(verdict.failedChecks(true), "; ")),
+				Outputs: outputs,
+			}, nil
+		}
+	}
+
+	if cfg.Catalog.URL != "" {
+		manifest, _ := readManifest(packageDir)
+		entry := newCatalogEntry(rec, manifest, releaseCtx.CommitSHA, releaseCtx.Branch)
+		err := updateCatalog(ctx, cfg.Catalog, entry)
+		if err != nil {
+			appendWarning(outputs, fmt.Sprintf("package catalog not updated: %v", err))
+		}
+		outputs["catalog_updated"] = err == nil
+	}
+
+	if cfg.PackManifest != "" {
+		manifest, err := newPackManifest(result)
+		if err == nil {
+			err = writePackManifest(cfg.PackManifest, manifest)
+		}
+		if err != nil {
+			appendWarning(outputs, fmt.Sprintf("pack manifest not written: %v", err))
+		} else {
+			outputs["pack_manifest"] = cfg.PackManifest
+			outputs["pack_digest"] = manifest.Digest
+		}
+	}
+
+	if types != nil {
+		if err := publishTypesPackage(ctx, cfg, types); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("published %s@%s but not its types package: %v", pkg.Name, releaseCtx.Version, err),
+				Outputs: outputs,
+			}, nil
+		}
+	}
+
+	var artifacts []plugin.Artifact
+	if docs != nil {
+		tarball := docsTarballPath(cfg.DocsTarball, pkg.Name, releaseCtx.Version)
+		artifact, err := writeDocsTarball(docs, tarball)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("published %s@%s but not its docs tarball: %v", pkg.Name, releaseCtx.Version, err),
+				Outputs: outputs,
+			}, nil
+		}
+		outputs["docs_tarball"] = tarball
+		subject := artifact.Name
+		if cfg.DocsTarball.Publish && cfg.PublishTarget != publishTargetArtifactStore {
+			if err := publishDocsTarball(ctx, cfg, tarball); err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("published %s@%s but not its docs package: %v", pkg.Name, releaseCtx.Version, err),
+					Outputs: outputs,
+				}, nil
+			}
+			outputs["docs_package"] = docs.name
+			subject = npmPurl(docs.name, releaseCtx.Version)
+		}
+		addProvenanceSubject(outputs, subject, artifact.Checksum)
+		if cfg.DocsTarball.Attach {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+
+	if len(purgeURLs) > 0 {
+		outputs["cdn_purge"] = purgeCDNs(ctx, purgeURLs)
+	}
+
+	if len(consumerEndpoints) > 0 {
+		update := consumerUpdate{
+			updateHint:      outputs["update_hint"].(updateHint),
+			PreviousVersion: releaseCtx.PreviousVersion,
+		}
+		if releaseCtx.RepositoryOwner != "" && releaseCtx.RepositoryName != "" {
+			update.Source = releaseCtx.RepositoryOwner + "/" + releaseCtx.RepositoryName
+		}
+		results := notifyConsumers(ctx, cfg.Consumers, consumerEndpoints, update)
+		var failed []string
+		for _, result := range results {
+			if result.Error != "" {
+				failed = append(failed, result.Repo)
+			}
+		}
+		if len(failed) > 0 {
+			appendWarning(outputs, fmt.Sprintf("failed to notify consumers: %s", strings.Join(failed, ", ")))
+		}
+		outputs["consumers"] = results
+	}
+
+	if cfg.GraduationReport || cfg.GraduateTags {
+		addGraduationReport(ctx, cfg, outputs, packageDir, pkg.Name, releaseCtx.Version, false)
+	}
+
+	if cfg.MajorTag != "" {
+		reconcileMajorTags(ctx, cfg, outputs, packageDir, pkg.Name, releaseCtx.Version, false)
+	}
+
+	if cfg.DistTags.enabled() {
+		applyDistTags(ctx, cfg, outputs, packageDir, pkg.Name, releaseCtx.Version, false)
+	}
+
+	if len(cfg.Deprecate.Ranges) > 0 {
+		deprecateSuperseded(ctx, cfg, outputs, packageDir, pkg.Name, releaseCtx.Version, newTemplateData(pkg.Name, cfg, releaseCtx), false)
+	}
+
+	if cfg.EnvFile != "" || cfg.EnvFileFormat != "" {
+		addEnvFile(cfg, outputs)
+	}
+
 	return &plugin.ExecuteResponse{
-		Success: true,
-		Message: fmt.Sprintf("Published %s@%s to npm", pkg.Name, releaseCtx.Version),
-		Outputs: map[string]any{
-			"package":  pkg.Name,
-			"version":  releaseCtx.Version,
-			"registry": cfg.Registry,
-			"tag":      cfg.Tag,
-			"stdout":   stdout.String(),
-		},
+		Success:   true,
+		Message:   fmt.Sprintf("Published %s@%s to npm", pkg.Name, releaseCtx.Version),
+		Outputs:   outputs,
+		Artifacts: artifacts,
 	}, nil
 }
 
@@ -442,31 +2901,438 @@ func (p *NpmPlugin) parseConfig(raw map[string]any) *Config {
 		tag = "latest"
 	}
 
-	return &Config{
-		Registry:      parser.GetString("registry", "", ""),
-		Tag:           tag,
-		Access:        parser.GetString("access", "", ""),
-		OTP:           parser.GetString("otp", "", ""),
-		DryRun:        parser.GetBool("dry_run", false),
-		PackageDir:    parser.GetString("package_dir", "", ""),
-		UpdateVersion: parser.GetBool("update_version", true),
+	cfg := &Config{
+		ID:                       parser.GetString("id", "", ""),
+		Idempotency:              parser.GetString("idempotency", "", ""),
+		ReleaseID:                parser.GetString("release_id", "RELICTA_RELEASE_ID", ""),
+		Registry:                 parser.GetString("registry", "", ""),
+		RegistryPreset:           parser.GetString("registry_preset", "", ""),
+		PublishURL:               parser.GetString("publish_url", "", ""),
+		AllowedRegistries:        parser.GetStringSlice("allowed_registries", nil),
+		Region:                   parser.GetString("region", "NPM_REGISTRY_REGION", ""),
+		DebugTranscript:          parser.GetBool("debug_transcript", false),
+		SupersededBy:             parser.GetString("superseded_by", "", ""),
+		ReadmeBadge:              parser.GetString("readme_badge", "", ""),
+		NamePattern:              parser.GetString("name_pattern", "", ""),
+		EnvFile:                  parser.GetString("env_file", "", ""),
+		EnvFileFormat:            parser.GetString("env_file_format", "", ""),
+		PublishPlan:              parser.GetString("publish_plan", "", ""),
+		PublishTarget:            parser.GetString("publish_target", "", publishTargetRegistry),
+		PublishMethod:            parser.GetString("publish_method", "", publishMethodNpm),
+		Provenance:               parser.GetBool("provenance", false),
+		ArtifactStore:            parser.GetString("artifact_store", "", ""),
+		Tag:                      tag,
+		Access:                   parser.GetString("access", "", ""),
+		OTP:                      parser.GetString("otp", "NPM_OTP", ""),
+		PackageDir:               parser.GetString("package_dir", "", ""),
+		DistDir:                  parser.GetString("dist_dir", "", ""),
+		TarballPath:              parser.GetString("tarball_path", "NPM_TARBALL_PATH", ""),
+		UpdateVersion:            parser.GetBool("update_version", true),
+		VersionCheck:             parser.GetString("version_check", "", versionCheckLenient),
+		VersionTool:              parser.GetString("version_tool", "", versionToolNpm),
+		ReadmeVersions:           parser.GetString("readme_versions", "", ""),
+		ChangelogCheck:           parser.GetString("changelog_check", "", ""),
+		Preflight:                parser.GetString("preflight", "", ""),
+		ChangelogFile:            parser.GetString("changelog_file", "", ""),
+		CDNPurge:                 parser.GetStringSlice("cdn_purge", nil),
+		VerifyLatest:             parser.GetBool("verify_latest", false),
+		VerifyPublish:            parser.GetBool("verify_publish", false),
+		VerifyTimeout:            parser.GetInt("verify_timeout", 60),
+		PublishAt:                parser.GetString("publish_at", "NPM_PUBLISH_AT", ""),
+		PublishAtMaxWait:         parser.GetInt("publish_at_max_wait", defaultPublishAtMaxWait),
+		BlackoutMode:             parser.GetString("blackout_mode", "", blackoutRefuse),
+		BlackoutMaxWait:          parser.GetInt("blackout_max_wait", 3600),
+		ReplicationLagThreshold:  parser.GetInt("replication_lag_threshold", 0),
+		ReplicationLagWebhook:    parser.GetString("replication_lag_webhook", "", ""),
+		Lock:                     parser.GetBool("lock", false),
+		LockTag:                  parser.GetString("lock_tag", "", ""),
+		AllowSemverTags:          parser.GetBool("allow_semver_tags", false),
+		LockTimeout:              parser.GetInt("lock_timeout", 0),
+		LockTTL:                  parser.GetInt("lock_ttl", 0),
+		Retries:                  parser.GetInt("retries", 0),
+		RetryDelay:               parser.GetInt("retry_delay", 2),
+		PackManifest:             parser.GetString("pack_manifest", "", ""),
+		MissingManifest:          parser.GetString("missing_manifest", "", ""),
+		PackageName:              parser.GetString("package_name", "", ""),
+		RegistryDiff:             parser.GetBool("registry_diff", false),
+		TokenExchange:            parser.GetBool("token_exchange", false),
+		TrustedPublishing:        parser.GetBool("trusted_publishing", false),
+		TokenCheck:               parser.GetBool("token_check", false),
+		SkipExisting:             parser.GetBool("skip_existing", false),
+		AcknowledgePublicPublish: parser.GetBool("acknowledge_public_publish", false),
+		MajorTag:                 parser.GetString("major_tag", "", ""),
+		ManifestTemplate:         parser.GetString("manifest_template", "", ""),
+		VersionSource:            parser.GetString("version_source", "", ""),
+		VersionEnv:               parser.GetString("version_env", "", ""),
+		VersionCommand:           parser.GetStringSlice("version_command", nil),
+		VersionTemplate:          parser.GetString("version_template", "", ""),
+		VerifyCheckout:           parser.GetBool("verify_checkout", false),
+		PrereleaseIteration:      parser.GetBool("prerelease_iteration", false),
+		GraduationReport:         parser.GetBool("graduation_report", false),
+		GraduateTags:             parser.GetBool("graduate_tags", false),
+		PackDestination:          parser.GetString("pack_destination", "", ""),
+		UserConfig:               parser.GetString("userconfig", "", ""),
+		GlobalConfig:             parser.GetString("globalconfig", "", ""),
+		CodeScan:                 parser.GetString("code_scan", "", ""),
+		BannedPatterns:           parser.GetStringSlice("banned_patterns", nil),
+		Sourcemaps:               parser.GetString("sourcemaps", "", ""),
+		ModuleCheck:              parser.GetString("module_check", "", ""),
+		ExpectedOutputs:          parser.GetStringSlice("expected_outputs", nil),
+		CopyFiles:                parser.GetStringSlice("copy_files", nil),
+		ResolveLocalDeps:         parser.GetBool("resolve_local_deps", true),
+		LicenseCheck:             parser.GetString("license_check", "", ""),
+		AllowedLicenses:          parser.GetStringSlice("allowed_licenses", nil),
+		StripFields:              parser.GetStringSlice("strip_fields", nil),
+		Workspaces:               parser.GetStringSlice("workspaces", nil),
+		OnlyChanged:              parser.GetBool("only_changed", false),
+		DependentRanges:          parser.GetString("dependent_ranges", "", ""),
+		Lerna:                    parser.GetBool("lerna", false),
+		Rush:                     parser.GetBool("rush", false),
+		BundledDeps:              parser.GetString("bundled_deps", "", ""),
+		IgnoreScripts:            parser.GetBool("ignore_scripts", false),
+		ForegroundScripts:        parser.GetBool("foreground_scripts", false),
+		Sandbox:                  parser.GetString("sandbox", "", ""),
+		DependencyNotes:          parser.GetBool("dependency_notes", false),
+		PublishHistory:           parser.GetBool("publish_history", false),
+		TestRegistry:             parser.GetBool("test_registry", false),
+		TestRegistryURL:          parser.GetString("test_registry_url", "", ""),
+	}
+
+	switch v := raw["expect_current_version"].(type) {
+	case bool:
+		if v {
+			cfg.ExpectCurrentVersion = expectCurrentPrevious
+		}
+	case string:
+		cfg.ExpectCurrentVersion = v
+	}
+
+	if err := decodeConfigValue(raw, "blackout_windows", &cfg.BlackoutWindows); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "tag_policy", &cfg.TagPolicy); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "tag_map", &cfg.TagMap); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "messages", &cfg.Messages); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "registry_pin", &cfg.RegistryPin); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "regional_registries", &cfg.RegionalRegistries); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "scope_registries", &cfg.ScopeRegistries); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "inputs", &cfg.Inputs); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "binaries", &cfg.Binaries); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "types_package", &cfg.TypesPackage); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "codeartifact", &cfg.CodeArtifact); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "docs_tarball", &cfg.DocsTarball); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "dist_tags", &cfg.DistTags); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "consumers", &cfg.Consumers); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "deprecate", &cfg.Deprecate); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "catalog", &cfg.Catalog); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if dryRun, level, err := parseDryRun(raw["dry_run"]); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	} else {
+		cfg.DryRun, cfg.DryRunLevel = dryRun, level
+	}
+	if err := decodeConfigValue(raw, "release_train", &cfg.ReleaseTrain); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "sso", &cfg.SSO); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "verification", &cfg.Verification); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "rollback", &cfg.Rollback); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "manifest_leaks", &cfg.ManifestLeaks); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "ephemeral_npmrc", &cfg.EphemeralNpmrc); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
 	}
+	if err := decodeConfigValue(raw, "skip_on", &cfg.SkipOn); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "end_of_life", &cfg.EndOfLife); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "simulate_failure", &cfg.SimulateFailure); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+	if err := decodeConfigValue(raw, "otp_policy", &cfg.OTPPolicy); err != nil {
+		cfg.parseErrors = append(cfg.parseErrors, err)
+	}
+
+	cfg.raw = raw
+
+	return cfg
 }
 
 // Validate validates the plugin configuration using the shared ValidationBuilder.
-func (p *NpmPlugin) Validate(_ context.Context, config map[string]any) (*plugin.ValidateResponse, error) {
+func (p *NpmPlugin) Validate(ctx context.Context, config map[string]any) (*plugin.ValidateResponse, error) {
 	vb := helpers.NewValidationBuilder()
 
 	// Check access level if provided
 	vb.ValidateOneOf(config, "access", []string{"public", "restricted"})
+	vb.ValidateOneOf(config, "registry_preset", []string{registryPresetGitHub, registryPresetCodeArtifact})
+	vb.ValidateOneOf(config, "readme_versions", []string{"update", "fail"})
+	vb.ValidateOneOf(config, "version_check", []string{versionCheckLenient, versionCheckStrict, versionCheckOff})
+	vb.ValidateOneOf(config, "dependent_ranges", []string{dependentRangesExact, dependentRangesCaret, dependentRangesTilde})
+	vb.ValidateOneOf(config, "version_tool", []string{versionToolNpm, versionToolYarn, versionToolAuto})
+	vb.ValidateOneOf(config, "changelog_check", []string{"warn", "fail"})
+	vb.ValidateOneOf(config, "preflight", []string{"warn", "fail"})
+	vb.ValidateOneOf(config, "idempotency", []string{idempotencySkip, idempotencyRefuse})
+	vb.ValidateOneOf(config, "code_scan", []string{"warn", "fail"})
+	vb.ValidateOneOf(config, "module_check", []string{"warn", "fail"})
+	vb.ValidateOneOf(config, "bundled_deps", []string{bundledDepsCheck, bundledDepsVendor})
+	vb.ValidateOneOf(config, "license_check", []string{licenseCheck, licenseFix})
+	vb.ValidateOneOf(config, "sandbox", []string{sandboxAuto, sandboxRequired})
+	vb.ValidateOneOf(config, "blackout_mode", []string{blackoutRefuse, blackoutWait})
+	vb.ValidateOneOf(config, "sourcemaps", []string{sourcemapsInclude, sourcemapsExclude, sourcemapsExternal})
+	vb.ValidateOneOf(config, "version_source", []string{"context", "package_json", "env", "command"})
 
 	// Verify npm is available
 	if _, err := exec.LookPath("npm"); err != nil {
 		vb.AddError("", "npm command not found in PATH")
 	}
 
-	// Check package_dir exists if provided
 	parser := helpers.NewConfigParser(config)
+	if err := validateInstanceID(parser.GetString("id", "", "")); err != nil {
+		vb.AddError("id", err.Error())
+	}
+
+	// Reject registries outside the allowlist or on the denylist
+	allowed := parser.GetStringSlice("allowed_registries", nil)
+	registry := parser.GetString("registry", "", "")
+	if err := validateRegistry(registry); err != nil {
+		vb.AddError("registry", err.Error())
+	} else if err := checkRegistryPolicy(registryURL(&Config{Registry: registry}), allowed); err != nil {
+		vb.AddError("registry", err.Error())
+	}
+	if publishURL := parser.GetString("publish_url", "", ""); publishURL != "" {
+		if err := validateEndpointURL(publishURL, "publish_url"); err != nil {
+			vb.AddError("publish_url", err.Error())
+		} else if err := checkRegistryPolicy(publishURL, allowed); err != nil {
+			vb.AddError("publish_url", err.Error())
+		}
+	}
+	regions := &Config{
+		PublishURL:        parser.GetString("publish_url", "", ""),
+		RegistryPreset:    parser.GetString("registry_preset", "", ""),
+		AllowedRegistries: allowed,
+	}
+	if err := decodeConfigValue(config, "regional_registries", &regions.RegionalRegistries); err != nil {
+		vb.AddError("regional_registries", err.Error())
+	} else if err := validateRegions(regions); err != nil {
+		vb.AddError("regional_registries", err.Error())
+	}
+	if err := decodeConfigValue(config, "ephemeral_npmrc", &regions.EphemeralNpmrc); err != nil {
+		// Reported under ephemeral_npmrc; don't also fail scope token_env
+		// for lack of it
+		regions.EphemeralNpmrc = EphemeralNpmrc{Enabled: true}
+	}
+	if err := decodeConfigValue(config, "scope_registries", &regions.ScopeRegistries); err != nil {
+		vb.AddError("scope_registries", err.Error())
+	} else if err := validateScopeRegistries(regions); err != nil {
+		vb.AddError("scope_registries", err.Error())
+	}
+	vb.ValidateOneOf(config, "publish_target", []string{publishTargetRegistry, publishTargetArtifactStore})
+	vb.ValidateOneOf(config, "publish_method", []string{publishMethodNpm, publishMethodAPI})
+	if parser.GetBool("provenance", false) {
+		if _, err := provenanceProvider(); err != nil {
+			vb.AddError("provenance", err.Error())
+		}
+	}
+	if parser.GetBool("token_check", false) && (parser.GetBool("trusted_publishing", false) || parser.GetBool("token_exchange", false)) {
+		vb.AddError("token_check", "token_check cannot be combined with trusted_publishing or token_exchange, which mint the publish token")
+	}
+	if parser.GetBool("trusted_publishing", false) {
+		if parser.GetBool("token_exchange", false) {
+			vb.AddError("trusted_publishing", "trusted_publishing cannot be combined with token_exchange")
+		} else if _, err := trustedPublishingProvider(); err != nil {
+			vb.AddError("trusted_publishing", err.Error())
+		}
+	}
+	var sso SSO
+	if err := decodeConfigValue(config, "sso", &sso); err != nil {
+		vb.AddError("sso", err.Error())
+	} else if err := validateSSO(&Config{SSO: sso, PublishMethod: parser.GetString("publish_method", "", publishMethodNpm)}); err != nil {
+		vb.AddError("sso", err.Error())
+	}
+	var verification Verification
+	if err := decodeConfigValue(config, "verification", &verification); err != nil {
+		vb.AddError("verification", err.Error())
+	} else if err := validateVerification(verification); err != nil {
+		vb.AddError("verification", err.Error())
+	}
+	vb.ValidateOneOf(config, "env_file_format", []string{envFileDotenv, envFileGitHubOutput})
+	if err := validateOutputPath(parser.GetString("env_file", "", "")); err != nil {
+		vb.AddError("env_file", err.Error())
+	}
+	if err := validateOutputPath(parser.GetString("publish_plan", "", "")); err != nil {
+		vb.AddError("publish_plan", err.Error())
+	}
+	store := parser.GetString("artifact_store", "", "")
+	if err := validateArtifactStore(store); err != nil {
+		vb.AddError("artifact_store", err.Error())
+	} else if store == "" && parser.GetString("publish_target", "", "") == publishTargetArtifactStore {
+		vb.AddError("artifact_store", "artifact_store is required when publish_target is artifact_store")
+	}
+
+	if parser.GetInt("replication_lag_threshold", 0) < 0 {
+		vb.AddError("replication_lag_threshold", "replication_lag_threshold must not be negative")
+	}
+	if at := parser.GetString("publish_at", "NPM_PUBLISH_AT", ""); at != "" {
+		if _, err := parsePublishAt(at); err != nil {
+			vb.AddError("publish_at", err.Error())
+		}
+	}
+	for _, key := range []string{"retries", "retry_delay", "verify_timeout", "publish_at_max_wait"} {
+		if parser.GetInt(key, 0) < 0 {
+			vb.AddError(key, key+" must not be negative")
+		}
+	}
+	if err := validateEndpointURL(parser.GetString("replication_lag_webhook", "", ""), "replication_lag_webhook"); err != nil {
+		vb.AddError("replication_lag_webhook", err.Error())
+	}
+
+	if err := validateMajorTag(parser.GetString("major_tag", "", "")); err != nil {
+		vb.AddError("major_tag", err.Error())
+	}
+
+	var inputs PluginInputs
+	if err := decodeConfigValue(config, "inputs", &inputs); err != nil {
+		vb.AddError("inputs", err.Error())
+	} else if err := validatePluginInputs(inputs); err != nil {
+		vb.AddError("inputs", err.Error())
+	}
+
+	var binaries BinaryDist
+	if err := decodeConfigValue(config, "binaries", &binaries); err != nil {
+		vb.AddError("binaries", err.Error())
+	} else if err := validateBinaryDist(binaries); err != nil {
+		vb.AddError("binaries", err.Error())
+	}
+
+	var types TypesPackage
+	if err := decodeConfigValue(config, "types_package", &types); err != nil {
+		vb.AddError("types_package", err.Error())
+	} else if err := validateTypesPackage(types); err != nil {
+		vb.AddError("types_package", err.Error())
+	}
+
+	var docs DocsTarball
+	if err := decodeConfigValue(config, "docs_tarball", &docs); err != nil {
+		vb.AddError("docs_tarball", err.Error())
+	} else if err := validateDocsTarball(docs); err != nil {
+		vb.AddError("docs_tarball", err.Error())
+	}
+
+	var distTags DistTags
+	if err := decodeConfigValue(config, "dist_tags", &distTags); err != nil {
+		vb.AddError("dist_tags", err.Error())
+	} else if err := validateDistTags(distTags, parser.GetString("tag", "", "latest")); err != nil {
+		vb.AddError("dist_tags", err.Error())
+	}
+
+	var consumers Consumers
+	if err := decodeConfigValue(config, "consumers", &consumers); err != nil {
+		vb.AddError("consumers", err.Error())
+	} else if err := validateConsumers(consumers); err != nil {
+		vb.AddError("consumers", err.Error())
+	}
+
+	var deprecate Deprecate
+	if err := decodeConfigValue(config, "deprecate", &deprecate); err != nil {
+		vb.AddError("deprecate", err.Error())
+	} else if err := validateDeprecate(deprecate); err != nil {
+		vb.AddError("deprecate", err.Error())
+	}
+
+	var catalog Catalog
+	if err := decodeConfigValue(config, "catalog", &catalog); err != nil {
+		vb.AddError("catalog", err.Error())
+	} else if err := validateCatalog(catalog); err != nil {
+		vb.AddError("catalog", err.Error())
+	}
+
+	if _, _, err := parseDryRun(config["dry_run"]); err != nil {
+		vb.AddError("dry_run", err.Error())
+	}
+
+	var train ReleaseTrain
+	if err := decodeConfigValue(config, "release_train", &train); err != nil {
+		vb.AddError("release_train", err.Error())
+	} else if err := validateReleaseTrain(train); err != nil {
+		vb.AddError("release_train", err.Error())
+	}
+
+	var rollback Rollback
+	if err := decodeConfigValue(config, "rollback", &rollback); err != nil {
+		vb.AddError("rollback", err.Error())
+	} else if err := validateRollback(rollback); err != nil {
+		vb.AddError("rollback", err.Error())
+	}
+
+	var skipOn SkipOn
+	if err := decodeConfigValue(config, "skip_on", &skipOn); err != nil {
+		vb.AddError("skip_on", err.Error())
+	}
+
+	var eol EndOfLife
+	if err := decodeConfigValue(config, "end_of_life", &eol); err != nil {
+		vb.AddError("end_of_life", err.Error())
+	} else if err := validateEndOfLife(eol); err != nil {
+		vb.AddError("end_of_life", err.Error())
+	}
+
+	var simulate SimulatedFailure
+	if err := decodeConfigValue(config, "simulate_failure", &simulate); err != nil {
+		vb.AddError("simulate_failure", err.Error())
+	} else if err := validateSimulatedFailure(simulate); err != nil {
+		vb.AddError("simulate_failure", err.Error())
+	}
+
+	if successor := parser.GetString("superseded_by", "", ""); successor != "" && !packageNamePattern.MatchString(successor) {
+		vb.AddError("superseded_by", fmt.Sprintf("invalid package name %q", successor))
+	}
+
+	var pin RegistryPin
+	if err := decodeConfigValue(config, "registry_pin", &pin); err != nil {
+		vb.AddError("registry_pin", err.Error())
+	} else if err := validateRegistryPin(pin); err != nil {
+		vb.AddError("registry_pin", err.Error())
+	}
+
+	// Check package_dir exists if provided
 	if dir := parser.GetString("package_dir", "", ""); dir != "" {
 		packagePath := filepath.Join(dir, "package.json")
 		if _, err := os.Stat(packagePath); err != nil {
@@ -474,5 +3340,178 @@ func (p *NpmPlugin) Validate(_ context.Context, config map[string]any) (*plugin.
 		}
 	}
 
+	if parser.GetString("dist_dir", "", "") != "" && len(parser.GetStringSlice("workspaces", nil)) > 0 {
+		vb.AddError("dist_dir", "dist_dir cannot be combined with workspaces")
+	}
+
+	if path := parser.GetString("tarball_path", "NPM_TARBALL_PATH", ""); path != "" {
+		tarballCfg := &Config{
+			TarballPath: path,
+			DistDir:     parser.GetString("dist_dir", "", ""),
+			Workspaces:  parser.GetStringSlice("workspaces", nil),
+			Inputs:      inputs,
+		}
+		if err := validateTarballPath(tarballCfg); err != nil {
+			vb.AddError("tarball_path", err.Error())
+		}
+	}
+
+	// Enforce the naming convention on the package as it is now, so new
+	// packages are caught before their first publish
+	if pattern := parser.GetString("name_pattern", "", ""); pattern != "" {
+		dir := parser.GetString("package_dir", "", ".")
+		data := templateData{Registry: parser.GetString("registry", "", "")}
+		data.RepoOwner, data.RepoName = remoteRepo(ctx, dir)
+		if pkg, err := readPackageJSON(dir); err == nil {
+			if err := checkPackageName(pkg.Name, pattern, data); err != nil {
+				vb.AddError("name_pattern", err.Error())
+			}
+		} else if _, err := compileNamePattern(pattern, data); err != nil {
+			vb.AddError("name_pattern", err.Error())
+		}
+	}
+
+	if _, err := compileBannedPatterns(parser.GetStringSlice("banned_patterns", nil)); err != nil {
+		vb.AddError("banned_patterns", err.Error())
+	}
+
+	if testURL := parser.GetString("test_registry_url", "", ""); testURL != "" {
+		if err := validateEndpointURL(testURL, "test_registry_url"); err != nil {
+			vb.AddError("test_registry_url", err.Error())
+		}
+	}
+
+	if _, err := compileGlobs("expected_outputs", parser.GetStringSlice("expected_outputs", nil)); err != nil {
+		vb.AddError("expected_outputs", err.Error())
+	}
+	if err := validateCopyFiles(parser.GetStringSlice("copy_files", nil)); err != nil {
+		vb.AddError("copy_files", err.Error())
+	}
+	if err := validateStripFields(parser.GetStringSlice("strip_fields", nil)); err != nil {
+		vb.AddError("strip_fields", err.Error())
+	}
+
+	var npmrc EphemeralNpmrc
+	if err := decodeConfigValue(config, "ephemeral_npmrc", &npmrc); err != nil {
+		vb.AddError("ephemeral_npmrc", err.Error())
+	} else if err := validateEphemeralNpmrc(npmrc, parser.GetString("userconfig", "", "")); err != nil {
+		vb.AddError("ephemeral_npmrc", err.Error())
+	} else {
+		codeArtifact := &Config{
+			RegistryPreset:    parser.GetString("registry_preset", "", ""),
+			EphemeralNpmrc:    npmrc,
+			UserConfig:        parser.GetString("userconfig", "", ""),
+			TrustedPublishing: parser.GetBool("trusted_publishing", false),
+			TokenExchange:     parser.GetBool("token_exchange", false),
+		}
+		if err := decodeConfigValue(config, "codeartifact", &codeArtifact.CodeArtifact); err != nil {
+			vb.AddError("codeartifact", err.Error())
+		} else if err := validateCodeArtifact(codeArtifact); err != nil {
+			vb.AddError("codeartifact", err.Error())
+		}
+	}
+
+	var leaks ManifestLeaks
+	if err := decodeConfigValue(config, "manifest_leaks", &leaks); err != nil {
+		vb.AddError("manifest_leaks", err.Error())
+	} else if err := validateManifestLeaks(leaks); err != nil {
+		vb.AddError("manifest_leaks", err.Error())
+	}
+
+	for _, pattern := range parser.GetStringSlice("workspaces", nil) {
+		if err := validateWorkspacePattern(pattern); err != nil {
+			vb.AddError("workspaces", err.Error())
+		}
+	}
+
+	if parser.GetBool("lerna", false) && parser.GetBool("rush", false) {
+		vb.AddError("rush", "rush cannot be combined with lerna")
+	}
+
+	for _, key := range []string{"userconfig", "globalconfig"} {
+		if err := validateNpmrcPath(parser.GetString(key, "", "")); err != nil {
+			vb.AddError(key, err.Error())
+		}
+	}
+
+	if err := validateChangelogFile(parser.GetString("changelog_file", "", "")); err != nil {
+		vb.AddError("changelog_file", err.Error())
+	}
+
+	if v, ok := config["expect_current_version"].(string); ok && v != "" && v != expectCurrentPrevious {
+		if _, err := parseSemver(v); err != nil {
+			vb.AddError("expect_current_version", err.Error())
+		}
+	}
+
+	// ValidateOneOf reports an unknown mode
+	vb.ValidateOneOf(config, "missing_manifest", []string{missingManifestFail, missingManifestSkip, missingManifestGenerate})
+	if err := validateMissingManifest(&Config{
+		MissingManifest:  parser.GetString("missing_manifest", "", ""),
+		PackageName:      parser.GetString("package_name", "", ""),
+		ManifestTemplate: parser.GetString("manifest_template", "", ""),
+		VersionSource:    parser.GetString("version_source", "", ""),
+	}); err != nil && !errors.Is(err, errUnknownMissingManifest) {
+		vb.AddError("missing_manifest", err.Error())
+	}
+
+	if err := validateVersionTemplate(parser.GetString("version_template", "", "")); err != nil {
+		vb.AddError("version_template", err.Error())
+	}
+
+	switch parser.GetString("version_source", "", "") {
+	case versionSourceEnv:
+		vb.RequireString(config, "version_env", "")
+	case versionSourceCommand:
+		if len(parser.GetStringSlice("version_command", nil)) == 0 {
+			vb.AddError("version_command", "version_command is required when version_source is command")
+		}
+	}
+
+	var otpPolicy OTPPolicy
+	if err := decodeConfigValue(config, "otp_policy", &otpPolicy); err != nil {
+		vb.AddError("otp_policy", err.Error())
+	} else if err := validateOTPPolicy(otpPolicy); err != nil {
+		vb.AddError("otp_policy", err.Error())
+	} else if err := validateOTPWithPolicy(parser.GetString("otp", "NPM_OTP", ""), otpPolicy); err != nil {
+		vb.AddError("otp", err.Error())
+	}
+
+	var messages MessageTemplates
+	if err := decodeConfigValue(config, "messages", &messages); err != nil {
+		vb.AddError("messages", err.Error())
+	} else if err := validateMessageTemplates(messages); err != nil {
+		vb.AddError("messages", err.Error())
+	}
+
+	var windows []BlackoutWindow
+	if err := decodeConfigValue(config, "blackout_windows", &windows); err != nil {
+		vb.AddError("blackout_windows", err.Error())
+	} else if err := validateBlackoutWindows(windows); err != nil {
+		vb.AddError("blackout_windows", err.Error())
+	}
+	if parser.GetInt("blackout_max_wait", 3600) < 0 {
+		vb.AddError("blackout_max_wait", "must not be negative")
+	}
+
+	allowSemverTags := parser.GetBool("allow_semver_tags", false)
+	if err := checkTag(parser.GetString("tag", "", ""), allowSemverTags); err != nil {
+		vb.AddError("tag", err.Error())
+	}
+
+	var rules []TagRule
+	if err := decodeConfigValue(config, "tag_policy", &rules); err != nil {
+		vb.AddError("tag_policy", err.Error())
+	} else if err := validateTagPolicy(rules, allowSemverTags); err != nil {
+		vb.AddError("tag_policy", err.Error())
+	}
+
+	var tagMap TagMap
+	if err := decodeConfigValue(config, "tag_map", &tagMap); err != nil {
+		vb.AddError("tag_map", err.Error())
+	} else if err := validateTagMap(tagMap, allowSemverTags); err != nil {
+		vb.AddError("tag_map", err.Error())
+	}
+
 	return vb.Build(), nil
 }
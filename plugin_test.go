@@ -40,7 +40,7 @@ func TestGetInfo(t *testing.T) {
 	})
 
 	t.Run("hooks", func(t *testing.T) {
-		expectedHooks := []plugin.Hook{plugin.HookPrePublish, plugin.HookPostPublish}
+		expectedHooks := []plugin.Hook{plugin.HookPostNotes, plugin.HookPrePublish, plugin.HookPostPublish, plugin.HookOnSuccess, plugin.HookOnError}
 		if len(info.Hooks) != len(expectedHooks) {
 			t.Errorf("expected %d hooks, got %d", len(expectedHooks), len(info.Hooks))
 			return
@@ -111,6 +111,15 @@ func TestValidate(t *testing.T) {
 			},
 			wantValid: true,
 		},
+		{
+			name: "registry_not_in_allowlist",
+			config: map[string]any{
+				"registry":           "https://npm.attacker.example",
+				"allowed_registries": []any{"registry.npmjs.org"},
+			},
+			wantValid:  false,
+			wantErrors: []string{"registry"},
+		},
 		{
 			name: "package_dir_not_found",
 			config: map[string]any{
@@ -422,7 +431,7 @@ func TestExecute(t *testing.T) {
 
 	t.Run("unhandled_hook", func(t *testing.T) {
 		req := plugin.ExecuteRequest{
-			Hook:    plugin.HookPostNotes,
+			Hook:    plugin.HookPreNotes,
 			Config:  map[string]any{},
 			Context: releaseCtx,
 			DryRun:  true,
@@ -436,7 +445,7 @@ func TestExecute(t *testing.T) {
 		if !resp.Success {
 			t.Errorf("Execute failed: %s", resp.Error)
 		}
-		if resp.Message != "Hook post-notes not handled" {
+		if resp.Message != "Hook pre-notes not handled" {
 			t.Errorf("unexpected message: %q", resp.Message)
 		}
 	})
@@ -499,6 +508,17 @@ func TestValidateRegistry(t *testing.T) {
 	}
 }
 
+func TestCheckTagAllowSemver(t *testing.T) {
+	for _, tag := range []string{"1.0.0", "v2", "1.x"} {
+		if err := checkTag(tag, true); err != nil {
+			t.Errorf("checkTag(%q, true) error = %v", tag, err)
+		}
+	}
+	if err := checkTag("tag@1", true); err == nil {
+		t.Error("checkTag() accepted invalid characters")
+	}
+}
+
 func TestValidateTag(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -511,7 +531,11 @@ func TestValidateTag(t *testing.T) {
 		{"alpha_1", "alpha.1", false},
 		{"next_major", "next-major", false},
 		{"underscore", "my_tag", false},
-		{"numeric_prefix", "1.0.0", false},
+		{"numeric_prefix", "1.0.0", true},
+		{"major", "v2", true},
+		{"x_range", "1.x", true},
+		{"prerelease_version", "1.2.3-beta", true},
+		{"numeric_prefix_word", "2024-lts", false},
 		{"too_long", string(make([]byte, 129)), true},
 		{"special_chars", "tag@123", true},
 		{"spaces", "my tag", true},
@@ -680,6 +704,21 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid_publish_url",
+			config: Config{
+				Registry:   "https://npm.example.com/api/npm/meta/",
+				PublishURL: "https://npm.example.com/api/npm/publish/",
+			},
+			wantErr: false,
+		},
+		{
+			name: "insecure_publish_url",
+			config: Config{
+				PublishURL: "http://npm.example.com/publish/",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1049,6 +1088,16 @@ func TestPublishPackage(t *testing.T) {
 	})
 }
 
+// chdir changes the working directory to dir for the duration of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	origWd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change to %s: %v", dir, err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(origWd) })
+}
+
 // Helper function
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// alwaysPackedPrefixes are the top-level files npm packs whatever the files
+// field says; a tarball holding nothing else is empty in practice.
+var alwaysPackedPrefixes = []string{"package.json", "readme", "license", "licence", "changelog"}
+
+// isAlwaysPacked reports whether npm packs p regardless of the files field.
+func isAlwaysPacked(p string) bool {
+	if strings.Contains(p, "/") {
+		return false
+	}
+	lower := strings.ToLower(p)
+	for _, prefix := range alwaysPackedPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPreflight looks for mistakes that would burn a version number: a
+// missing README or license, files/main/exports entries that are not in the
+// package, and a package with no content. files is what npm would pack.
+// It returns the problems found.
+func checkPreflight(manifest map[string]any, files []packFile) []string {
+	packed := make(map[string]bool, len(files))
+	hasReadme, hasContent := false, false
+	for _, f := range files {
+		packed[f.Path] = true
+		if !strings.Contains(f.Path, "/") && strings.HasPrefix(strings.ToLower(f.Path), "readme") {
+			hasReadme = true
+		}
+		if !isAlwaysPacked(f.Path) {
+			hasContent = true
+		}
+	}
+
+	var problems []string
+	if !hasReadme {
+		problems = append(problems, "README.md is missing")
+	}
+	if license, _ := manifest["license"].(string); strings.TrimSpace(license) == "" {
+		problems = append(problems, `package.json has no "license" field`)
+	}
+
+	if entries, ok := manifest["files"].([]any); ok {
+		for _, e := range entries {
+			entry, ok := e.(string)
+			if !ok || entry == "" || strings.HasPrefix(entry, "!") {
+				continue
+			}
+			if !packedEntry(entry, files) {
+				problems = append(problems, fmt.Sprintf("files entry %q matches nothing in the package", entry))
+			}
+		}
+	}
+
+	if main, ok := manifest["main"].(string); ok && main != "" && !packedMain(main, packed) {
+		problems = append(problems, fmt.Sprintf("main points at %s, which is not in the package", main))
+	}
+	if exports, ok := manifest["exports"]; ok {
+		for _, target := range exportTargets(exports) {
+			if !packed[path.Clean(strings.TrimPrefix(target, "./"))] {
+				problems = append(problems, fmt.Sprintf("exports target %s is not in the package", target))
+			}
+		}
+	}
+
+	if !hasContent {
+		problems = append(problems, "the package contains nothing besides package.json, README, LICENSE and CHANGELOG")
+	}
+	return problems
+}
+
+// packedEntry reports whether a files entry, a file, directory or glob,
+// matches any packed file.
+func packedEntry(entry string, files []packFile) bool {
+	re, err := compileGlob(strings.TrimSuffix(entry, "/"))
+	if err != nil {
+		return false
+	}
+	for _, f := range files {
+		// A directory entry includes everything below it
+		for p := f.Path; ; p = path.Dir(p) {
+			if re.MatchString(p) {
+				return true
+			}
+			if !strings.Contains(p, "/") {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// packedMain reports whether main resolves to a packed file, trying the
+// extensions and index files Node would.
+func packedMain(main string, packed map[string]bool) bool {
+	p := path.Clean(strings.TrimPrefix(main, "./"))
+	for _, candidate := range []string{p, p + ".js", p + ".json", p + ".node", path.Join(p, "index.js"), path.Join(p, "index.json")} {
+		if packed[candidate] {
+			return true
+		}
+	}
+	return false
+}
+
+// exportTargets returns the file targets of an exports value, sorted and
+// without duplicates. Subpath patterns are skipped.
+func exportTargets(v any) []string {
+	seen := map[string]bool{}
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case string:
+			if strings.HasPrefix(v, "./") && !strings.Contains(v, "*") {
+				seen[v] = true
+			}
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]any:
+			for k, item := range v {
+				if !strings.Contains(k, "*") {
+					walk(item)
+				}
+			}
+		}
+	}
+	walk(v)
+	targets := make([]string, 0, len(seen))
+	for t := range seen {
+		targets = append(targets, t)
+	}
+	sort.Strings(targets)
+	return targets
+}
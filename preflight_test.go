@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestCheckPreflight(t *testing.T) {
+	packed := func(paths ...string) []packFile {
+		files := make([]packFile, len(paths))
+		for i, p := range paths {
+			files[i] = packFile{Path: p}
+		}
+		return files
+	}
+	tests := []struct {
+		name     string
+		manifest string
+		files    []packFile
+		want     []string
+	}{
+		{
+			name:     "complete",
+			manifest: `{"license":"MIT","main":"dist/index","files":["dist/","types/*.d.ts"],"exports":{".":{"types":"./types/index.d.ts","default":"./dist/index.js"},"./feature/*":"./dist/feature/*.js"}}`,
+			files:    packed("package.json", "README.md", "dist/index.js", "types/index.d.ts"),
+		},
+		{
+			name:     "directory main",
+			manifest: `{"license":"MIT","main":"lib"}`,
+			files:    packed("package.json", "readme.markdown", "lib/index.js"),
+		},
+		{
+			name:     "no readme or license",
+			manifest: `{"main":"index.js"}`,
+			files:    packed("package.json", "index.js"),
+			want:     []string{"README.md is missing", `package.json has no "license" field`},
+		},
+		{
+			name:     "missing paths",
+			manifest: `{"license":"MIT","main":"dist/index.js","files":["dist","bin/*.js","!dist/test"],"exports":{"import":"./dist/index.mjs","require":"./dist/index.js"}}`,
+			files:    packed("package.json", "README.md", "dist/other.js"),
+			want: []string{
+				`files entry "bin/*.js" matches nothing in the package`,
+				"main points at dist/index.js, which is not in the package",
+				"exports target ./dist/index.js is not in the package",
+				"exports target ./dist/index.mjs is not in the package",
+			},
+		},
+		{
+			name:     "empty",
+			manifest: `{"license":"MIT"}`,
+			files:    packed("package.json", "README.md", "LICENSE", "CHANGELOG.md"),
+			want:     []string{"the package contains nothing besides package.json, README, LICENSE and CHANGELOG"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var manifest map[string]any
+			if err := json.Unmarshal([]byte(tt.manifest), &manifest); err != nil {
+				t.Fatal(err)
+			}
+			if got := checkPreflight(manifest, tt.files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkPreflight() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreflightPrePublish(t *testing.T) {
+	fakeNpm(t, `if [ "$1" = pack ]; then echo '[{"files":[{"path":"package.json"},{"path":"index.js"}]}]'; fi`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0","main":"dist/index.js"}`)
+	chdir(t, dir)
+
+	execute := func(mode string) *plugin.ExecuteResponse {
+		t.Helper()
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPrePublish,
+			Config:  map[string]any{"preflight": mode, "update_version": false},
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := execute("fail")
+	if resp.Success || !strings.Contains(resp.Error, "preflight checks failed") || !strings.Contains(resp.Error, "main points at dist/index.js") {
+		t.Errorf("expected preflight failure, got %+v", resp)
+	}
+
+	resp = execute("warn")
+	if !resp.Success {
+		t.Fatalf("warn mode failed: %+v", resp)
+	}
+	if findings, _ := resp.Outputs["preflight_findings"].([]string); len(findings) != 3 {
+		t.Errorf("preflight_findings = %v", resp.Outputs["preflight_findings"])
+	}
+	if warnings, _ := resp.Outputs["warnings"].([]string); len(warnings) != 3 || !strings.HasPrefix(warnings[0], "preflight: ") {
+		t.Errorf("warnings = %v", resp.Outputs["warnings"])
+	}
+}
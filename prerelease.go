@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// splitPrerelease splits a prerelease such as "beta.3" into its identifier
+// prefix ("beta") and iteration (3). The iteration is -1 when the prerelease
+// does not end in a number.
+func splitPrerelease(pre string) (string, int) {
+	i := strings.LastIndex(pre, ".")
+	if n, err := strconv.Atoi(pre[i+1:]); err == nil && i >= 0 {
+		return pre[:i], n
+	}
+	return pre, -1
+}
+
+// nextPrereleaseIteration returns v with its iteration raised above every
+// published prerelease of the same version and identifier, so pipelines
+// computing iterations independently cannot collide. A prerelease without
+// an iteration ("2.0.0-beta") gets one, starting at 0 as npm version does.
+func nextPrereleaseIteration(doc *packument, v semver) semver {
+	prefix, n := splitPrerelease(v.Prerelease)
+	if n < 0 {
+		n = 0
+	}
+
+	if doc != nil {
+		for published := range doc.Versions {
+			pv, err := parseSemver(published)
+			if err != nil || pv.Major != v.Major || pv.Minor != v.Minor || pv.Patch != v.Patch {
+				continue
+			}
+			if pp, pn := splitPrerelease(pv.Prerelease); pp == prefix && pn >= n {
+				n = pn + 1
+			}
+		}
+	}
+
+	v.Prerelease = fmt.Sprintf("%s.%d", prefix, n)
+	return v
+}
+
+// prereleaseIteration is the persisted iteration chosen for a release, so
+// every hook of the release publishes the same version.
+type prereleaseIteration struct {
+	Base    string `json:"base"`
+	Version string `json:"version"`
+}
+
+// applyPrereleaseIteration replaces a prerelease Version with the next free
+// iteration in the registry. The choice is saved on first use and reused by
+// later hooks of the same release until it is published.
+func applyPrereleaseIteration(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext) (plugin.ReleaseContext, error) {
+	if !cfg.PrereleaseIteration {
+		return releaseCtx, nil
+	}
+	v, err := parseSemver(releaseCtx.Version)
+	if err != nil || !v.IsPrerelease() {
+		return releaseCtx, nil
+	}
+
+	packageDir, err := validatePackageDir(cfg.PackageDir)
+	if err != nil {
+		return releaseCtx, fmt.Errorf("invalid package directory: %w", err)
+	}
+	pkg, err := readPackageJSON(packageDir)
+	if err != nil {
+		return releaseCtx, err
+	}
+	if pkg.Private {
+		return releaseCtx, nil
+	}
+	cfg = scopedConfig(cfg, pkg.Name)
+
+	if err := validateRegistry(cfg.Registry); err != nil {
+		return releaseCtx, err
+	}
+	if err := checkRegistryPolicy(registryURL(cfg), cfg.AllowedRegistries); err != nil {
+		return releaseCtx, err
+	}
+
+	doc, err := fetchPackument(ctx, registryURL(cfg), pkg.Name)
+	if err != nil && !errors.Is(err, errPackageNotFound) {
+		return releaseCtx, fmt.Errorf("failed to fetch %s from registry: %w", pkg.Name, err)
+	}
+
+	// Reuse the iteration chosen by an earlier hook unless another pipeline
+	// has since published it
+	var saved prereleaseIteration
+	ok, err := loadState(cfg, pkg.Name, "iteration", &saved)
+	if err != nil {
+		return releaseCtx, err
+	}
+	if ok && saved.Base == releaseCtx.Version {
+		rec, err := loadRecord(cfg, pkg.Name)
+		if err != nil {
+			return releaseCtx, err
+		}
+		publishedByUs := rec != nil && rec.Version == saved.Version
+		if doc == nil || !hasVersion(doc, saved.Version) || publishedByUs {
+			releaseCtx.Version = saved.Version
+			return releaseCtx, nil
+		}
+	}
+
+	next := nextPrereleaseIteration(doc, v).String()
+	if err := saveState(cfg, pkg.Name, "iteration", prereleaseIteration{Base: releaseCtx.Version, Version: next}); err != nil {
+		return releaseCtx, err
+	}
+	releaseCtx.Version = next
+	return releaseCtx, nil
+}
+
+// hasVersion reports whether the packument lists version.
+func hasVersion(doc *packument, version string) bool {
+	_, ok := doc.Versions[version]
+	return ok
+}
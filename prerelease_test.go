@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestNextPrereleaseIteration(t *testing.T) {
+	doc := &packument{Versions: map[string]packumentVersion{
+		"2.0.0-beta.0":  {},
+		"2.0.0-beta.4":  {},
+		"2.0.0-beta.10": {},
+		"2.0.0-rc.0":    {},
+		"1.9.0-beta.20": {},
+		"2.0.0-beta.x":  {},
+	}}
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"2.0.0-beta.2", "2.0.0-beta.11"},
+		{"2.0.0-beta.15", "2.0.0-beta.15"},
+		{"2.0.0-beta", "2.0.0-beta.11"},
+		{"2.0.0-rc.0", "2.0.0-rc.1"},
+		{"2.0.0-alpha", "2.0.0-alpha.0"},
+		{"2.1.0-beta.0", "2.1.0-beta.0"},
+	}
+
+	for _, tt := range tests {
+		v, _ := parseSemver(tt.version)
+		if got := nextPrereleaseIteration(doc, v).String(); got != tt.want {
+			t.Errorf("nextPrereleaseIteration(%s) = %s, want %s", tt.version, got, tt.want)
+		}
+	}
+
+	v, _ := parseSemver("3.0.0-canary.1")
+	if got := nextPrereleaseIteration(nil, v).String(); got != "3.0.0-canary.1" {
+		t.Errorf("unpublished package iteration = %s", got)
+	}
+}
+
+func TestApplyPrereleaseIteration(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	ctx := context.Background()
+
+	docs := map[string]*packument{
+		"pkg": {Name: "pkg", Versions: map[string]packumentVersion{"1.0.0-beta.3": {}}},
+	}
+	server := newTestRegistry(t, docs)
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name":"pkg","version":"0.9.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, tmpDir)
+
+	cfg := &Config{Registry: server.URL, PrereleaseIteration: true}
+	releaseCtx := plugin.ReleaseContext{Version: "1.0.0-beta.1"}
+
+	got, err := applyPrereleaseIteration(ctx, cfg, releaseCtx)
+	if err != nil {
+		t.Fatalf("applyPrereleaseIteration returned error: %v", err)
+	}
+	if got.Version != "1.0.0-beta.4" {
+		t.Fatalf("Version = %s, want 1.0.0-beta.4", got.Version)
+	}
+
+	// A later hook reuses the choice even after this release published it
+	docs["pkg"].Versions["1.0.0-beta.4"] = packumentVersion{}
+	if err := saveRecord(cfg, &publishRecord{Name: "pkg", Version: "1.0.0-beta.4"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := applyPrereleaseIteration(ctx, cfg, releaseCtx); got.Version != "1.0.0-beta.4" {
+		t.Errorf("later hook Version = %s, want 1.0.0-beta.4", got.Version)
+	}
+
+	// Another pipeline publishing it forces a new iteration
+	if err := saveRecord(cfg, &publishRecord{Name: "pkg", Version: "1.0.0-beta.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := applyPrereleaseIteration(ctx, cfg, releaseCtx); got.Version != "1.0.0-beta.5" {
+		t.Errorf("Version after collision = %s, want 1.0.0-beta.5", got.Version)
+	}
+
+	stable := plugin.ReleaseContext{Version: "1.0.0"}
+	if got, _ := applyPrereleaseIteration(ctx, cfg, stable); got.Version != "1.0.0" {
+		t.Errorf("stable version changed to %s", got.Version)
+	}
+}
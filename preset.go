@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// registryPresetGitHub configures publishing to GitHub Packages.
+const registryPresetGitHub = "github"
+
+// githubPackagesRegistry is the GitHub Packages npm registry.
+const githubPackagesRegistry = "https://npm.pkg.github.com/"
+
+// githubAPIURL is the GitHub REST API base, overridden in tests.
+var githubAPIURL = "https://api.github.com"
+
+// applyRegistryPreset fills in registry settings for the configured preset.
+// For GitHub Packages the registry defaults to npm.pkg.github.com and the
+// repository owner's scope is mapped to it.
+func applyRegistryPreset(cfg *Config, releaseCtx plugin.ReleaseContext) error {
+	switch cfg.RegistryPreset {
+	case "":
+		return nil
+	case registryPresetGitHub:
+		owner := strings.ToLower(releaseCtx.RepositoryOwner)
+		if owner == "" {
+			return fmt.Errorf("registry_preset %q requires the repository owner", cfg.RegistryPreset)
+		}
+		if cfg.Registry == "" {
+			cfg.Registry = githubPackagesRegistry
+		}
+		cfg.presetScope = "@" + owner
+		return nil
+	case registryPresetCodeArtifact:
+		// The endpoint and token come from applyRegistryProvider
+		return nil
+	default:
+		return fmt.Errorf("unknown registry_preset %q", cfg.RegistryPreset)
+	}
+}
+
+// checkGitHubPackageName verifies the package is in the repository owner's
+// scope, which GitHub Packages requires.
+func checkGitHubPackageName(cfg *Config, name string) error {
+	if !strings.HasPrefix(name, cfg.presetScope+"/") {
+		return fmt.Errorf("GitHub Packages requires the package name to be scoped to the repository owner (%s/...), got %q", cfg.presetScope, name)
+	}
+	return nil
+}
+
+// checkGitHubToken verifies a token is available and can write packages.
+func checkGitHubToken(ctx context.Context) error {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		token = registryToken()
+	}
+	if token == "" {
+		return fmt.Errorf("GITHUB_TOKEN or NPM_TOKEN is required for GitHub Packages")
+	}
+	return checkGitHubTokenScopes(ctx, token)
+}
+
+// checkGitHubTokenScopes asserts a classic token has write:packages. Tokens
+// that do not report OAuth scopes (fine-grained and Actions tokens) pass;
+// their permissions are enforced by the registry.
+func checkGitHubTokenScopes(ctx context.Context, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(githubAPIURL, "/")+"/user", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("GitHub token check failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("GitHub token is invalid or expired")
+	}
+
+	header, ok := resp.Header["X-Oauth-Scopes"]
+	if !ok {
+		return nil
+	}
+	for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+		if strings.TrimSpace(scope) == "write:packages" {
+			return nil
+		}
+	}
+	return fmt.Errorf("GitHub token lacks the write:packages scope (has %q)", strings.Join(header, ","))
+}
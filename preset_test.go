@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestApplyRegistryPreset(t *testing.T) {
+	cfg := &Config{RegistryPreset: registryPresetGitHub}
+	if err := applyRegistryPreset(cfg, plugin.ReleaseContext{RepositoryOwner: "Relicta-Tech"}); err != nil {
+		t.Fatalf("applyRegistryPreset returned error: %v", err)
+	}
+	if cfg.Registry != githubPackagesRegistry {
+		t.Errorf("Registry = %q, want %q", cfg.Registry, githubPackagesRegistry)
+	}
+	if got := strings.Join(scopeRegistryArgs(cfg), " "); got != "--@relicta-tech:registry=https://npm.pkg.github.com/" {
+		t.Errorf("scopeRegistryArgs() = %q", got)
+	}
+
+	if err := applyRegistryPreset(&Config{RegistryPreset: registryPresetGitHub}, plugin.ReleaseContext{}); err == nil {
+		t.Error("expected error without repository owner")
+	}
+	if err := applyRegistryPreset(&Config{RegistryPreset: "gitlab"}, plugin.ReleaseContext{RepositoryOwner: "o"}); err == nil {
+		t.Error("expected error for unknown preset")
+	}
+
+	cfg = &Config{}
+	if err := applyRegistryPreset(cfg, plugin.ReleaseContext{RepositoryOwner: "o"}); err != nil || cfg.Registry != "" || scopeRegistryArgs(cfg) != nil {
+		t.Errorf("expected no changes without a preset, got %+v, %v", cfg, err)
+	}
+}
+
+func TestCheckGitHubPackageName(t *testing.T) {
+	cfg := &Config{presetScope: "@acme"}
+	if err := checkGitHubPackageName(cfg, "@acme/lib"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"lib", "@other/lib", "@acme-corp/lib"} {
+		if err := checkGitHubPackageName(cfg, name); err == nil {
+			t.Errorf("expected error for %q", name)
+		}
+	}
+}
+
+func TestCheckGitHubTokenScopes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer classic-ok":
+			w.Header().Set("X-OAuth-Scopes", "repo, write:packages")
+		case "Bearer classic-read":
+			w.Header().Set("X-OAuth-Scopes", "repo, read:packages")
+		case "Bearer fine-grained":
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	orig := githubAPIURL
+	githubAPIURL = server.URL
+	t.Cleanup(func() { githubAPIURL = orig })
+
+	tests := []struct {
+		token   string
+		wantErr bool
+	}{
+		{"classic-ok", false},
+		{"classic-read", true},
+		{"fine-grained", false},
+		{"bogus", true},
+	}
+
+	for _, tt := range tests {
+		err := checkGitHubTokenScopes(context.Background(), tt.token)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkGitHubTokenScopes(%q) error = %v, wantErr %v", tt.token, err, tt.wantErr)
+		}
+	}
+
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("NPM_TOKEN", "")
+	if err := checkGitHubToken(context.Background()); err == nil {
+		t.Error("expected error without a token")
+	}
+}
+
+func TestPublishGitHubPreset(t *testing.T) {
+	p := &NpmPlugin{}
+	tmpDir := t.TempDir()
+	writeFile(t, tmpDir+"/package.json", `{"name":"@acme/lib","version":"1.0.0"}`)
+	chdir(t, tmpDir)
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"registry_preset": "github"},
+		Context: plugin.ReleaseContext{Version: "1.0.0", RepositoryOwner: "acme"},
+		DryRun:  true,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %s", err, resp.Error)
+	}
+	cmd, _ := resp.Outputs["command"].(string)
+	if !strings.Contains(cmd, "--registry https://npm.pkg.github.com/") || !strings.Contains(cmd, "--@acme:registry=https://npm.pkg.github.com/") {
+		t.Errorf("unexpected command: %s", cmd)
+	}
+}
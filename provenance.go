@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// CI providers npm can generate provenance statements on.
+const (
+	provenanceGitHubActions = "github_actions"
+	provenanceGitLab        = "gitlab"
+)
+
+// provenanceProvider returns the CI provider whose OIDC identity npm will use
+// for --provenance. npm signs the statement with an ID token, so the CI job
+// must be allowed to mint one: `id-token: write` on GitHub Actions, an
+// `id_tokens` entry named SIGSTORE_ID_TOKEN on GitLab.
+func provenanceProvider() (string, error) {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		if os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL") == "" || os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN") == "" {
+			return "", fmt.Errorf("provenance on GitHub Actions requires the id-token: write permission")
+		}
+		return provenanceGitHubActions, nil
+	case os.Getenv("GITLAB_CI") == "true":
+		if os.Getenv("SIGSTORE_ID_TOKEN") == "" {
+			return "", fmt.Errorf("provenance on GitLab CI requires an id_tokens entry named SIGSTORE_ID_TOKEN with aud sigstore")
+		}
+		return provenanceGitLab, nil
+	}
+	return "", fmt.Errorf("provenance requires GitHub Actions or GitLab CI with OIDC enabled")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// setCIEnv clears the CI variables provenanceProvider reads and sets env.
+func setCIEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, key := range []string{"GITHUB_ACTIONS", "ACTIONS_ID_TOKEN_REQUEST_URL", "ACTIONS_ID_TOKEN_REQUEST_TOKEN", "GITLAB_CI", "SIGSTORE_ID_TOKEN"} {
+		t.Setenv(key, env[key])
+	}
+}
+
+func TestProvenanceProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "github actions",
+			env:  map[string]string{"GITHUB_ACTIONS": "true", "ACTIONS_ID_TOKEN_REQUEST_URL": "https://token.actions.example", "ACTIONS_ID_TOKEN_REQUEST_TOKEN": "t"},
+			want: provenanceGitHubActions,
+		},
+		{name: "github actions without id-token", env: map[string]string{"GITHUB_ACTIONS": "true"}, wantErr: "id-token: write"},
+		{name: "gitlab", env: map[string]string{"GITLAB_CI": "true", "SIGSTORE_ID_TOKEN": "t"}, want: provenanceGitLab},
+		{name: "gitlab without id token", env: map[string]string{"GITLAB_CI": "true"}, wantErr: "SIGSTORE_ID_TOKEN"},
+		{name: "local", wantErr: "GitHub Actions or GitLab CI"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setCIEnv(t, tt.env)
+			got, err := provenanceProvider()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("provenanceProvider() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("provenanceProvider() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishProvenance(t *testing.T) {
+	setCIEnv(t, map[string]string{"GITLAB_CI": "true", "SIGSTORE_ID_TOKEN": "t"})
+	tmpDir := t.TempDir()
+	writeFile(t, tmpDir+"/package.json", `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, tmpDir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"provenance": true},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+		DryRun:  true,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %s", err, resp.Error)
+	}
+	if cmd, _ := resp.Outputs["command"].(string); !strings.Contains(cmd, " --provenance") {
+		t.Errorf("command %q lacks --provenance", cmd)
+	}
+	if resp.Outputs["provenance"] != provenanceGitLab {
+		t.Errorf("provenance = %v", resp.Outputs["provenance"])
+	}
+
+	setCIEnv(t, nil)
+	vr, err := (&NpmPlugin{}).Validate(context.Background(), map[string]any{"provenance": true})
+	if err != nil || vr.Valid {
+		t.Errorf("Validate() accepted provenance outside CI: %v %+v", err, vr)
+	}
+}
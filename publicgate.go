@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// publicRegistry is where first publishes to the public registry are looked
+// up; tests serve it locally.
+var publicRegistry = defaultRegistry
+
+// targetsPublicRegistry reports whether publishes go to the public npm
+// registry, including when no registry is configured and npm's default
+// applies.
+func targetsPublicRegistry(cfg *Config) bool {
+	registry := publishRegistry(cfg)
+	return registry == "" || sameRegistry(registry, defaultRegistry)
+}
+
+// checkPublicFirstPublish refuses the first publish of a package to the
+// public registry unless acknowledge_public_publish is set, so a registry
+// left at its default cannot open-source internal code. A failed lookup
+// also refuses, as it cannot rule out a first publish.
+func checkPublicFirstPublish(ctx context.Context, cfg *Config, name string) error {
+	if cfg.AcknowledgePublicPublish || !targetsPublicRegistry(cfg) {
+		return nil
+	}
+	_, err := fetchPackument(ctx, publicRegistry, name)
+	switch {
+	case errors.Is(err, errPackageNotFound):
+		return fmt.Errorf("%s has never been published to the public npm registry; set acknowledge_public_publish: true to publish it publicly, or configure the intended registry", name)
+	case err != nil:
+		return fmt.Errorf("could not confirm %s was published to the public npm registry before (%v); set acknowledge_public_publish: true to publish anyway", name, err)
+	}
+	return nil
+}
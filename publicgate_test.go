@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// servePublicRegistry points the public registry lookup at a server
+// answering with status.
+func servePublicRegistry(t *testing.T, status int) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"versions":{}}`))
+	}))
+	orig := publicRegistry
+	publicRegistry = server.URL + "/"
+	t.Cleanup(func() {
+		publicRegistry = orig
+		server.Close()
+	})
+}
+
+func TestTargetsPublicRegistry(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		want bool
+	}{
+		{cfg: Config{}, want: true},
+		{cfg: Config{Registry: "https://registry.npmjs.org"}, want: true},
+		{cfg: Config{Registry: "https://npm.corp.example.com/"}},
+		{cfg: Config{Registry: "https://registry.npmjs.org/", PublishURL: "https://npm.corp.example.com/"}},
+	}
+	for _, tt := range tests {
+		if got := targetsPublicRegistry(&tt.cfg); got != tt.want {
+			t.Errorf("targetsPublicRegistry(%+v) = %v, want %v", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPublicFirstPublish(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		cfg     Config
+		wantErr string
+	}{
+		{name: "published before", status: http.StatusOK},
+		{name: "first publish", status: http.StatusNotFound, wantErr: "lib has never been published to the public npm registry"},
+		{name: "acknowledged", status: http.StatusNotFound, cfg: Config{AcknowledgePublicPublish: true}},
+		{name: "private registry", status: http.StatusNotFound, cfg: Config{Registry: "https://npm.corp.example.com/"}},
+		{name: "lookup failed", status: http.StatusBadGateway, wantErr: "could not confirm lib was published to the public npm registry before"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			servePublicRegistry(t, tt.status)
+			err := checkPublicFirstPublish(context.Background(), &tt.cfg, "lib")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkPublicFirstPublish() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("checkPublicFirstPublish() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFirstPublicPublishRefused(t *testing.T) {
+	servePublicRegistry(t, http.StatusNotFound)
+	logPath := fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"internal-tool","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	execute := func(config map[string]any, dryRun bool) *plugin.ExecuteResponse {
+		t.Helper()
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  config,
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+			DryRun:  dryRun,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := execute(map[string]any{}, true)
+	warned := false
+	warnings, _ := resp.Outputs["warnings"].([]string)
+	for _, w := range warnings {
+		warned = warned || strings.HasPrefix(w, "publish would be refused: internal-tool has never been published")
+	}
+	if !resp.Success || !warned {
+		t.Errorf("expected a dry-run warning, got %+v", resp)
+	}
+
+	resp = execute(map[string]any{}, false)
+	if resp.Success || !strings.HasPrefix(resp.Error, "publish refused: internal-tool has never been published") {
+		t.Errorf("expected refusal, got %+v", resp)
+	}
+	for _, call := range npmCalls(t, logPath) {
+		if strings.HasPrefix(call, "publish") && !strings.Contains(call, "--dry-run") {
+			t.Errorf("published despite refusal: %s", call)
+		}
+	}
+
+	if resp := execute(map[string]any{"acknowledge_public_publish": true}, false); !resp.Success {
+		t.Errorf("acknowledged publish failed: %+v", resp)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultPublishAtMaxWait is the longest publish_at delay by default, in
+// seconds: GitHub Actions cancels jobs after six hours.
+const defaultPublishAtMaxWait = 6 * 60 * 60
+
+// parsePublishAt parses a publish_at timestamp.
+func parsePublishAt(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q, want RFC 3339 such as 2024-06-01T16:00:00Z", s)
+	}
+	return t, nil
+}
+
+// publishDelay returns when cfg.PublishAt schedules the upload and how long
+// that is from now, zero when it has passed. A delay beyond PublishAtMaxWait
+// is an error: the job would most likely be cancelled before it ends.
+func publishDelay(cfg *Config, now time.Time) (time.Time, time.Duration, error) {
+	at, err := parsePublishAt(cfg.PublishAt)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	wait := at.Sub(now)
+	if wait <= 0 {
+		return at, 0, nil
+	}
+	if limit := time.Duration(cfg.PublishAtMaxWait) * time.Second; wait > limit {
+		return at, wait, fmt.Errorf("publish_at %s is %s away, more than publish_at_max_wait (%s)", at.UTC().Format(time.RFC3339), wait.Round(time.Second), limit)
+	}
+	return at, wait, nil
+}
+
+// waitForPublishAt delays the upload until cfg.PublishAt, after the package
+// has been checked, so a coordinated launch only waits on the upload itself.
+func waitForPublishAt(ctx context.Context, cfg *Config, outputs map[string]any, now time.Time) error {
+	if cfg.PublishAt == "" {
+		return nil
+	}
+	at, wait, err := publishDelay(cfg, now)
+	if err != nil {
+		return err
+	}
+	outputs["publish_at"] = at.UTC().Format(time.RFC3339)
+	if wait > 0 {
+		if err := retrySleep(ctx, wait); err != nil {
+			return fmt.Errorf("waiting for publish_at: %w", err)
+		}
+	}
+	outputs["publish_delay_seconds"] = int(wait.Round(time.Second) / time.Second)
+	return nil
+}
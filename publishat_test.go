@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestPublishDelay(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		at       string
+		maxWait  int
+		wantWait time.Duration
+		wantErr  bool
+	}{
+		{name: "future", at: "2024-06-01T12:30:00Z", maxWait: 3600, wantWait: 30 * time.Minute},
+		{name: "offset", at: "2024-06-01T14:30:00+02:00", maxWait: 3600, wantWait: 30 * time.Minute},
+		{name: "past", at: "2024-06-01T11:00:00Z", maxWait: 3600},
+		{name: "beyond max wait", at: "2024-06-01T14:00:00Z", maxWait: 3600, wantErr: true},
+		{name: "not a timestamp", at: "tomorrow", maxWait: 3600, wantErr: true},
+		{name: "missing zone", at: "2024-06-01T12:30:00", maxWait: 3600, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, wait, err := publishDelay(&Config{PublishAt: tt.at, PublishAtMaxWait: tt.maxWait}, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("publishDelay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && wait != tt.wantWait {
+				t.Errorf("publishDelay() wait = %v, want %v", wait, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestPublishAtDelaysUpload(t *testing.T) {
+	sleeps := recordSleeps(t)
+	logPath := fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+	at := time.Now().Add(10 * time.Minute).UTC().Format(time.RFC3339)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"publish_at": at},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] < 9*time.Minute || (*sleeps)[0] > 10*time.Minute {
+		t.Errorf("sleeps = %v, want one of about 10m", *sleeps)
+	}
+	if resp.Outputs["publish_at"] != at {
+		t.Errorf("publish_at = %v, want %s", resp.Outputs["publish_at"], at)
+	}
+	if delay, _ := resp.Outputs["publish_delay_seconds"].(int); delay < 540 {
+		t.Errorf("publish_delay_seconds = %v", resp.Outputs["publish_delay_seconds"])
+	}
+	if calls := npmCalls(t, logPath); len(calls) == 0 || !strings.HasPrefix(calls[len(calls)-1], "publish") {
+		t.Errorf("npm calls = %v, want publish last", calls)
+	}
+}
+
+func TestPublishAtBeyondMaxWait(t *testing.T) {
+	sleeps := recordSleeps(t)
+	logPath := fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+	config := map[string]any{
+		"publish_at":          time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339),
+		"publish_at_max_wait": 3600,
+	}
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+		DryRun:  true,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("dry run failed: %v %+v", err, resp)
+	}
+	if warnings, _ := resp.Outputs["warnings"].([]string); len(warnings) == 0 || !strings.Contains(warnings[0], "publish_at_max_wait") {
+		t.Errorf("warnings = %v", resp.Outputs["warnings"])
+	}
+
+	resp, err = (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "publish_at_max_wait") {
+		t.Errorf("expected failure, got %+v", resp)
+	}
+	if len(*sleeps) != 0 {
+		t.Errorf("slept %v", *sleeps)
+	}
+	for _, call := range npmCalls(t, logPath) {
+		if strings.HasPrefix(call, "publish") {
+			t.Errorf("published despite failure: %v", call)
+		}
+	}
+}
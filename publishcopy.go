@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// editPublishManifest applies edit to packageDir/package.json for the
+// publish only: it returns a func restoring the original file, which callers
+// defer. When edit reports no change the file is not rewritten. With dryRun
+// the edit runs on the parsed manifest but nothing is written.
+func editPublishManifest(packageDir string, dryRun bool, edit func(manifest map[string]any) (bool, error)) (func() error, error) {
+	noop := func() error { return nil }
+	manifestPath := filepath.Join(packageDir, "package.json")
+	info, err := os.Stat(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat package.json: %w", err)
+	}
+	original, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	var manifest map[string]any
+	if err := json.Unmarshal(original, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+	changed, err := edit(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if !changed || dryRun {
+		return noop, nil
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal package.json: %w", err)
+	}
+	restored := false
+	restore := func() error {
+		if restored {
+			return nil
+		}
+		restored = true
+		if err := os.WriteFile(manifestPath, original, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to restore package.json: %w", err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(manifestPath, append(data, '\n'), info.Mode().Perm()); err != nil {
+		_ = restore()
+		return nil, fmt.Errorf("failed to write package.json: %w", err)
+	}
+	return restore, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// versionPattern matches a full semver version (not a range).
+const versionPattern = `\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?`
+
+// readmeVersionRegexp matches "name@version" references for the given package,
+// such as CDN links or install snippets.
+func readmeVersionRegexp(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?:^|[^\w.-])(` + regexp.QuoteMeta(name) + `@)(` + versionPattern + `)`)
+}
+
+// scopeSuffixPattern detects a preceding "@scope/", meaning an unscoped name
+// matched as the tail of a different, scoped package.
+var scopeSuffixPattern = regexp.MustCompile(`@[\w.-]+/$`)
+
+// syncReadmeVersions finds references to the package pinned at a version other
+// than version in README.md and, when update is true, rewrites them in place.
+// It returns the stale references found. A missing README is not an error.
+func syncReadmeVersions(packageDir, name, version string, update bool) ([]string, error) {
+	if name == "" || version == "" {
+		return nil, nil
+	}
+
+	readmePath := filepath.Join(packageDir, "README.md")
+	info, err := os.Stat(readmePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat README.md: %w", err)
+	}
+
+	data, err := os.ReadFile(readmePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read README.md: %w", err)
+	}
+
+	var stale []string
+	var updated []byte
+	last := 0
+	for _, m := range readmeVersionRegexp(name).FindAllSubmatchIndex(data, -1) {
+		nameStart, verStart, verEnd := m[2], m[4], m[5]
+		if string(data[verStart:verEnd]) == version || scopeSuffixPattern.Match(data[:nameStart]) {
+			continue
+		}
+		stale = append(stale, string(data[nameStart:verEnd]))
+		updated = append(updated, data[last:verStart]...)
+		updated = append(updated, version...)
+		last = verEnd
+	}
+
+	if len(stale) == 0 || !update {
+		return stale, nil
+	}
+
+	updated = append(updated, data[last:]...)
+	if err := os.WriteFile(readmePath, updated, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to write README.md: %w", err)
+	}
+	return stale, nil
+}
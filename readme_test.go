@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestSyncReadmeVersions(t *testing.T) {
+	readme := strings.Join([]string{
+		"<script src=\"https://cdn.jsdelivr.net/npm/my-lib@1.2.2/dist/index.js\"></script>",
+		"npm install my-lib@1.2.2",
+		"Already current: my-lib@1.2.3",
+		"Other package: @scope/my-lib@1.0.0",
+		"Range is left alone: my-lib@^1.0.0",
+	}, "\n")
+
+	tests := []struct {
+		name      string
+		pkgName   string
+		update    bool
+		wantStale []string
+		wantBody  string
+	}{
+		{
+			name:      "report_only",
+			pkgName:   "my-lib",
+			update:    false,
+			wantStale: []string{"my-lib@1.2.2", "my-lib@1.2.2"},
+			wantBody:  readme,
+		},
+		{
+			name:      "update_rewrites",
+			pkgName:   "my-lib",
+			update:    true,
+			wantStale: []string{"my-lib@1.2.2", "my-lib@1.2.2"},
+			wantBody:  strings.ReplaceAll(readme, "my-lib@1.2.2", "my-lib@1.2.3"),
+		},
+		{
+			name:      "scoped_name",
+			pkgName:   "@scope/my-lib",
+			update:    true,
+			wantStale: []string{"@scope/my-lib@1.0.0"},
+			wantBody:  strings.ReplaceAll(readme, "@scope/my-lib@1.0.0", "@scope/my-lib@1.2.3"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "README.md")
+			if err := os.WriteFile(path, []byte(readme), 0644); err != nil {
+				t.Fatalf("failed to write README.md: %v", err)
+			}
+
+			stale, err := syncReadmeVersions(dir, tt.pkgName, "1.2.3", tt.update)
+			if err != nil {
+				t.Fatalf("syncReadmeVersions returned error: %v", err)
+			}
+			if strings.Join(stale, ",") != strings.Join(tt.wantStale, ",") {
+				t.Errorf("stale = %v, want %v", stale, tt.wantStale)
+			}
+
+			data, _ := os.ReadFile(path)
+			if string(data) != tt.wantBody {
+				t.Errorf("README.md = %q, want %q", data, tt.wantBody)
+			}
+		})
+	}
+
+	t.Run("missing_readme", func(t *testing.T) {
+		stale, err := syncReadmeVersions(t.TempDir(), "my-lib", "1.2.3", true)
+		if err != nil || stale != nil {
+			t.Errorf("expected no error and no stale refs, got %v, %v", stale, err)
+		}
+	})
+}
+
+func TestPrePublishReadmeVersionsFail(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name":"my-lib","version":"1.2.2"}`), 0644); err != nil {
+		t.Fatalf("failed to write package.json: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "README.md"), []byte("npm i my-lib@1.2.2"), 0644); err != nil {
+		t.Fatalf("failed to write README.md: %v", err)
+	}
+
+	chdir(t, tmpDir)
+
+	p := &NpmPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPrePublish,
+		Config:  map[string]any{"readme_versions": "fail", "update_version": false},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if resp.Success {
+		t.Error("expected failure for stale README reference")
+	}
+	if !strings.Contains(resp.Error, "my-lib@1.2.2") {
+		t.Errorf("expected stale reference in error, got %q", resp.Error)
+	}
+}
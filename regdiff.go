@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// manifestChange is one field-level difference between the published
+// predecessor and the package about to be published. An empty From or To
+// means the field was added or removed.
+type manifestChange struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// registryDiff is the dry-run comparison against the registry.
+type registryDiff struct {
+	Base    string           `json:"base"`
+	Changes []manifestChange `json:"changes"`
+}
+
+// diffBaseVersion picks the published version to compare against: the
+// release's previous version when it is published, otherwise the version the
+// target dist-tag (or latest) points at.
+func diffBaseVersion(doc *packument, tag, previous string) string {
+	if _, ok := doc.Versions[previous]; ok && previous != "" {
+		return previous
+	}
+	if tag == "" {
+		tag = "latest"
+	}
+	if v := doc.DistTags[tag]; v != "" {
+		return v
+	}
+	return doc.DistTags["latest"]
+}
+
+// diffStringMaps reports added, removed and changed keys under field.
+func diffStringMaps(field string, from, to map[string]string) []manifestChange {
+	keys := map[string]bool{}
+	for k := range from {
+		keys[k] = true
+	}
+	for k := range to {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []manifestChange
+	for _, k := range sorted {
+		if from[k] != to[k] {
+			changes = append(changes, manifestChange{Field: field + "." + k, From: from[k], To: to[k]})
+		}
+	}
+	return changes
+}
+
+// compactJSON returns raw JSON without insignificant whitespace, or "" when
+// it is empty or null.
+func compactJSON(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil || buf.String() == "null" {
+		return ""
+	}
+	return buf.String()
+}
+
+// diffEngines compares engines per key when both are objects, and as a whole
+// otherwise.
+func diffEngines(from, to json.RawMessage) []manifestChange {
+	var fromMap, toMap map[string]string
+	if (len(from) == 0 || json.Unmarshal(from, &fromMap) == nil) && (len(to) == 0 || json.Unmarshal(to, &toMap) == nil) {
+		return diffStringMaps("engines", fromMap, toMap)
+	}
+	if f, t := compactJSON(from), compactJSON(to); f != t {
+		return []manifestChange{{Field: "engines", From: f, To: t}}
+	}
+	return nil
+}
+
+// diffManifests compares the fields that matter for release review. File
+// counts are compared only when both are known.
+func diffManifests(from, to packumentVersion) []manifestChange {
+	var changes []manifestChange
+	changes = append(changes, diffStringMaps("dependencies", from.Dependencies, to.Dependencies)...)
+	changes = append(changes, diffStringMaps("peerDependencies", from.PeerDependencies, to.PeerDependencies)...)
+	changes = append(changes, diffStringMaps("optionalDependencies", from.OptionalDependencies, to.OptionalDependencies)...)
+	changes = append(changes, diffEngines(from.Engines, to.Engines)...)
+	if f, t := compactJSON(from.Exports), compactJSON(to.Exports); f != t {
+		changes = append(changes, manifestChange{Field: "exports", From: f, To: t})
+	}
+	if from.Dist.FileCount > 0 && to.Dist.FileCount > 0 && from.Dist.FileCount != to.Dist.FileCount {
+		changes = append(changes, manifestChange{
+			Field: "files",
+			From:  strconv.Itoa(from.Dist.FileCount),
+			To:    strconv.Itoa(to.Dist.FileCount),
+		})
+	}
+	return changes
+}
+
+// diffAgainstRegistry compares the local package.json (and the pack file
+// count) with the published predecessor.
+func diffAgainstRegistry(ctx context.Context, cfg *Config, packageDir, name string, releaseCtx plugin.ReleaseContext, files []packFile) (*registryDiff, error) {
+	doc, err := fetchPackument(ctx, registryURL(cfg), name)
+	if errors.Is(err, errPackageNotFound) {
+		return nil, fmt.Errorf("%s is not published yet", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	base := diffBaseVersion(doc, cfg.Tag, releaseCtx.PreviousVersion)
+	published, ok := doc.Versions[base]
+	if !ok {
+		return nil, fmt.Errorf("no published version of %s to compare with", name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(packageDir, "package.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	var local packumentVersion
+	if err := json.Unmarshal(data, &local); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+	if files == nil {
+		if files, err = listPackFiles(ctx, cfg, packageDir); err != nil {
+			return nil, err
+		}
+	}
+	local.Dist.FileCount = len(files)
+
+	changes := diffManifests(published, local)
+	if changes == nil {
+		changes = []manifestChange{}
+	}
+	return &registryDiff{Base: base, Changes: changes}, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestDiffBaseVersion(t *testing.T) {
+	doc := &packument{
+		DistTags: map[string]string{"latest": "1.2.0", "next": "2.0.0-beta.1"},
+		Versions: map[string]packumentVersion{"1.1.0": {}, "1.2.0": {}, "2.0.0-beta.1": {}},
+	}
+	tests := []struct {
+		tag, previous, want string
+	}{
+		{"", "1.1.0", "1.1.0"},
+		{"", "0.9.0", "1.2.0"},
+		{"next", "", "2.0.0-beta.1"},
+		{"beta", "", "1.2.0"},
+	}
+	for _, tt := range tests {
+		if got := diffBaseVersion(doc, tt.tag, tt.previous); got != tt.want {
+			t.Errorf("diffBaseVersion(%q, %q) = %q, want %q", tt.tag, tt.previous, got, tt.want)
+		}
+	}
+}
+
+func TestDiffManifests(t *testing.T) {
+	from := packumentVersion{
+		Dependencies: map[string]string{"lodash": "^4.17.20", "left-pad": "^1.0.0"},
+		Engines:      json.RawMessage(`{"node": ">=16"}`),
+		Exports:      json.RawMessage(`{".": "./index.js"}`),
+		Dist:         packumentDist{FileCount: 12},
+	}
+	to := packumentVersion{
+		Dependencies:     map[string]string{"lodash": "^4.17.21"},
+		PeerDependencies: map[string]string{"react": ">=18"},
+		Engines:          json.RawMessage(`{"node":">=18"}`),
+		Exports:          json.RawMessage(`{".":"./index.js"}`),
+		Dist:             packumentDist{FileCount: 14},
+	}
+
+	want := []manifestChange{
+		{Field: "dependencies.left-pad", From: "^1.0.0"},
+		{Field: "dependencies.lodash", From: "^4.17.20", To: "^4.17.21"},
+		{Field: "peerDependencies.react", To: ">=18"},
+		{Field: "engines.node", From: ">=16", To: ">=18"},
+		{Field: "files", From: "12", To: "14"},
+	}
+	if got := diffManifests(from, to); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffManifests() = %+v\nwant %+v", got, want)
+	}
+
+	legacy := packumentVersion{Engines: json.RawMessage(`["node >=0.8"]`), Exports: json.RawMessage(`"./index.js"`)}
+	got := diffManifests(legacy, to)
+	if len(got) < 2 || got[len(got)-2].Field != "engines" || got[len(got)-1].Field != "exports" {
+		t.Errorf("diffManifests(legacy) = %+v", got)
+	}
+}
+
+func TestDryRunRegistryDiff(t *testing.T) {
+	server := newTestRegistry(t, map[string]*packument{
+		"pkg": {
+			Name:     "pkg",
+			DistTags: map[string]string{"latest": "1.0.0"},
+			Versions: map[string]packumentVersion{"1.0.0": {
+				Name:         "pkg",
+				Version:      "1.0.0",
+				Dependencies: map[string]string{"lodash": "^4.0.0"},
+				Dist:         packumentDist{FileCount: 3},
+			}},
+		},
+	})
+	fakeNpm(t, `if [ "$1" = pack ]; then echo '[{"files":[{"path":"a.js"},{"path":"b.js"},{"path":"c.js"},{"path":"package.json"}]}]'; fi`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"1.1.0","dependencies":{"lodash":"^4.0.0"},"engines":{"node":">=18"}}`)
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"registry": server.URL, "registry_diff": true},
+		Context: plugin.ReleaseContext{Version: "1.1.0", PreviousVersion: "1.0.0"},
+		DryRun:  true,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	diff, ok := resp.Outputs["registry_diff"].(*registryDiff)
+	if !ok {
+		t.Fatalf("registry_diff = %#v (warnings %v)", resp.Outputs["registry_diff"], resp.Outputs["warnings"])
+	}
+	want := []manifestChange{{Field: "engines.node", To: ">=18"}, {Field: "files", From: "3", To: "4"}}
+	if diff.Base != "1.0.0" || !reflect.DeepEqual(diff.Changes, want) {
+		t.Errorf("registry_diff = %+v", diff)
+	}
+
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"unpublished","version":"1.1.0"}`)
+	resp, _ = (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"registry": server.URL, "registry_diff": true},
+		Context: plugin.ReleaseContext{Version: "1.1.0"},
+		DryRun:  true,
+	})
+	if !resp.Success || resp.Outputs["registry_diff"] != nil || resp.Outputs["warnings"] == nil {
+		t.Errorf("expected a warning for an unpublished package, got %+v", resp.Outputs)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// writeRejectedNpmCodes are npm error codes of a registry refusing writes,
+// as read-only replicas do.
+var writeRejectedNpmCodes = map[string]bool{"E403": true, "E405": true, "E501": true}
+
+// validateRegions checks the regional registry URLs against the same rules
+// as registry. Regions replace registry, so they cannot be combined with
+// publish_url or a registry preset, which pick the publish registry too.
+func validateRegions(cfg *Config) error {
+	if len(cfg.RegionalRegistries) == 0 {
+		return nil
+	}
+	if cfg.PublishURL != "" || cfg.RegistryPreset != "" {
+		return fmt.Errorf("regional_registries cannot be combined with publish_url or registry_preset")
+	}
+	for region, registry := range cfg.RegionalRegistries {
+		if region == "" || registry == "" {
+			return fmt.Errorf("regional_registries entries need a region and a registry URL")
+		}
+		if err := validateEndpointURL(registry, "regional registry"); err != nil {
+			return err
+		}
+		if err := checkRegistryPolicy(registry, cfg.AllowedRegistries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyRegion switches the registry to the replica configured for
+// cfg.Region, keeping the original as the failover primary. An unknown
+// region is a warning and the primary is used.
+func applyRegion(cfg *Config, outputs map[string]any) {
+	if cfg.Region == "" || len(cfg.RegionalRegistries) == 0 {
+		return
+	}
+	regional, ok := cfg.RegionalRegistries[cfg.Region]
+	if !ok {
+		regions := make([]string, 0, len(cfg.RegionalRegistries))
+		for region := range cfg.RegionalRegistries {
+			regions = append(regions, region)
+		}
+		sort.Strings(regions)
+		appendWarning(outputs, fmt.Sprintf("no registry configured for region %q (have %v); using the primary registry", cfg.Region, regions))
+		return
+	}
+	cfg.primaryRegistry = registryURL(cfg)
+	cfg.Registry = regional
+	outputs["region"] = cfg.Region
+}
+
+// writeRejected reports whether a failed publish was refused by the
+// registry in a way another registry might accept: a read-only replica, or
+// one that stayed unreachable through the retries.
+func writeRejected(err error) bool {
+	var npmErr *npmError
+	if errors.As(err, &npmErr) && npmErr.Script == "" {
+		if m := npmCodeRegexp.FindStringSubmatch(npmErr.Stderr); m != nil && writeRejectedNpmCodes[m[1]] {
+			return true
+		}
+	}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case 403, 405, 501:
+			return true
+		}
+	}
+	return retryablePublishError(err)
+}
+
+// failoverToPrimary points cfg and the publish args back at the primary
+// registry after the regional one rejected the publish.
+func failoverToPrimary(cfg *Config, args []string) []string {
+	regional := cfg.Registry
+	cfg.Registry = cfg.primaryRegistry
+	cfg.primaryRegistry = ""
+	out := make([]string, len(args))
+	copy(out, args)
+	for i := 0; i+1 < len(out); i++ {
+		if out[i] == "--registry" && out[i+1] == regional {
+			out[i+1] = cfg.Registry
+		}
+	}
+	return out
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestApplyRegion(t *testing.T) {
+	regions := map[string]string{"eu": "https://npm-eu.example.com/", "us": "https://npm-us.example.com/"}
+	tests := []struct {
+		name         string
+		region       string
+		wantRegistry string
+		wantPrimary  string
+		wantWarning  bool
+	}{
+		{name: "regional", region: "eu", wantRegistry: "https://npm-eu.example.com/", wantPrimary: "https://npm.example.com/"},
+		{name: "unknown", region: "ap", wantRegistry: "https://npm.example.com/", wantWarning: true},
+		{name: "unset", wantRegistry: "https://npm.example.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Registry: "https://npm.example.com/", Region: tt.region, RegionalRegistries: regions}
+			outputs := map[string]any{}
+			applyRegion(cfg, outputs)
+			if cfg.Registry != tt.wantRegistry || cfg.primaryRegistry != tt.wantPrimary {
+				t.Errorf("registry = %q, primary = %q", cfg.Registry, cfg.primaryRegistry)
+			}
+			if (outputs["warnings"] != nil) != tt.wantWarning {
+				t.Errorf("warnings = %v", outputs["warnings"])
+			}
+		})
+	}
+}
+
+func TestWriteRejected(t *testing.T) {
+	exit := errors.New("exit status 1")
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"read-only replica", newNpmError("publish", exit, "", "npm error code E405\nnpm error 405 Method Not Allowed"), true},
+		{"unauthorized", newNpmError("publish", exit, "", simulatedErrors["auth"]), false},
+		{"unreachable", newNpmError("publish", exit, "", simulatedErrors["network"]), true},
+		{"lifecycle script", newNpmError("publish", exit, "", simulatedErrors["script"]), false},
+		{"api 405", &httpStatusError{StatusCode: 405}, true},
+		{"api 400", &httpStatusError{StatusCode: 400}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := writeRejected(tt.err); got != tt.want {
+				t.Errorf("writeRejected() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegionFailover(t *testing.T) {
+	logPath := fakeNpm(t, `case "$*" in
+*npm-eu.example.com*) echo "npm error code E405" >&2; exit 1 ;;
+esac
+echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("NPM_REGISTRY_REGION", "eu")
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"registry":            "https://npm.example.com/",
+			"regional_registries": map[string]any{"eu": "https://npm-eu.example.com/"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	var registries []string
+	for _, call := range npmCalls(t, logPath) {
+		if strings.HasPrefix(call, "publish") {
+			_, after, _ := strings.Cut(call, "--registry ")
+			registries = append(registries, strings.Fields(after)[0])
+		}
+	}
+	if len(registries) != 2 || registries[0] != "https://npm-eu.example.com/" || registries[1] != "https://npm.example.com/" {
+		t.Errorf("publish registries = %v", registries)
+	}
+	if resp.Outputs["region"] != "eu" || resp.Outputs["region_failover"] != true || resp.Outputs["registry"] != "https://npm.example.com/" {
+		t.Errorf("outputs = %v", resp.Outputs)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultRegistry is the public npm registry used when none is configured.
+const defaultRegistry = "https://registry.npmjs.org/"
+
+// httpClient is used for all HTTP calls made by the plugin.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// errPackageNotFound is returned when the registry has no such package.
+var errPackageNotFound = errors.New("package not found in registry")
+
+// packument is the subset of registry package metadata used by the plugin.
+type packument struct {
+	Name     string                      `json:"name"`
+	DistTags map[string]string           `json:"dist-tags"`
+	Versions map[string]packumentVersion `json:"versions"`
+	Time     map[string]string           `json:"time"`
+}
+
+// packumentVersion is the per-version manifest inside a packument.
+type packumentVersion struct {
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+	Deprecated   string            `json:"deprecated,omitempty"`
+	Dist         packumentDist     `json:"dist"`
+	// The fields below are only compared by the dry-run registry diff. Engines
+	// and exports stay raw since old manifests use other shapes.
+	PeerDependencies     map[string]string `json:"peerDependencies,omitempty"`
+	OptionalDependencies map[string]string `json:"optionalDependencies,omitempty"`
+	Engines              json.RawMessage   `json:"engines,omitempty"`
+	Exports              json.RawMessage   `json:"exports,omitempty"`
+}
+
+// packumentDist describes a published tarball.
+type packumentDist struct {
+	Tarball   string `json:"tarball"`
+	Shasum    string `json:"shasum"`
+	Integrity string `json:"integrity"`
+	FileCount int    `json:"fileCount,omitempty"`
+	// Attestations links the provenance and publish attestations, when any.
+	Attestations json.RawMessage `json:"attestations,omitempty"`
+}
+
+// registryURL returns the configured registry or the public npm registry.
+func registryURL(cfg *Config) string {
+	if cfg.Registry != "" {
+		return cfg.Registry
+	}
+	return defaultRegistry
+}
+
+// registryToken returns the auth token used for direct registry requests.
+func registryToken() string {
+	return os.Getenv("NPM_TOKEN")
+}
+
+// packumentURL builds the metadata URL for a package. Scoped names keep the
+// leading "@" but escape the slash, as the registry expects.
+func packumentURL(registry, name string) string {
+	return strings.TrimSuffix(registry, "/") + "/" + strings.Replace(url.PathEscape(name), "%40", "@", 1)
+}
+
+// fetchPackument retrieves the package metadata document from the registry.
+func fetchPackument(ctx context.Context, registry, name string) (*packument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, packumentURL(registry, name), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if token := registryToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("registry request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errPackageNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("registry returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var doc packument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode registry response: %w", err)
+	}
+	return &doc, nil
+}
+
+// deniedRegistryHosts are request-capture and tunnelling services that are
+// never legitimate registries; publishing there exfiltrates the package.
+var deniedRegistryHosts = []string{
+	"*.ngrok.io",
+	"*.ngrok-free.app",
+	"*.ngrok.app",
+	"*.localhost.run",
+	"*.loca.lt",
+	"*.trycloudflare.com",
+	"webhook.site",
+	"*.webhook.site",
+	"*.requestbin.net",
+	"*.pipedream.net",
+	"*.burpcollaborator.net",
+	"*.oastify.com",
+	"*.interact.sh",
+	"*.oast.fun",
+	"*.oast.pro",
+}
+
+// hostMatches reports whether host matches pattern, which is a hostname or a
+// "*.domain" wildcard matching any subdomain.
+func hostMatches(host, pattern string) bool {
+	pattern = strings.ToLower(pattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
+
+// patternHost normalizes an allowlist entry, which may be a hostname,
+// wildcard or full URL, to a host pattern.
+func patternHost(entry string) string {
+	if u, err := url.Parse(entry); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	return entry
+}
+
+// checkRegistryPolicy rejects registries on the built-in denylist and, when
+// allowed is non-empty, any registry whose host is not on the allowlist.
+func checkRegistryPolicy(registry string, allowed []string) error {
+	u, err := url.Parse(registry)
+	if err != nil {
+		return fmt.Errorf("invalid registry URL: %w", err)
+	}
+	host := strings.ToLower(u.Hostname())
+
+	for _, denied := range deniedRegistryHosts {
+		if hostMatches(host, denied) {
+			return fmt.Errorf("registry host %s is not allowed", host)
+		}
+	}
+
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, entry := range allowed {
+		if hostMatches(host, patternHost(entry)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("registry host %s is not in allowed_registries", host)
+}
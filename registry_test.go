@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newTestRegistry serves the given packuments keyed by package name.
+func newTestRegistry(t *testing.T, docs map[string]*packument) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, _ := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/"))
+		doc, ok := docs[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(doc)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPackumentURL(t *testing.T) {
+	tests := []struct {
+		registry string
+		name     string
+		want     string
+	}{
+		{"https://registry.npmjs.org/", "lodash", "https://registry.npmjs.org/lodash"},
+		{"https://registry.npmjs.org", "@scope/pkg", "https://registry.npmjs.org/@scope%2Fpkg"},
+		{"https://npm.example.com/repo/", "@scope/pkg", "https://npm.example.com/repo/@scope%2Fpkg"},
+	}
+
+	for _, tt := range tests {
+		if got := packumentURL(tt.registry, tt.name); got != tt.want {
+			t.Errorf("packumentURL(%q, %q) = %q, want %q", tt.registry, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFetchPackument(t *testing.T) {
+	server := newTestRegistry(t, map[string]*packument{
+		"@scope/pkg": {
+			Name:     "@scope/pkg",
+			DistTags: map[string]string{"latest": "1.0.0"},
+			Versions: map[string]packumentVersion{"1.0.0": {Version: "1.0.0"}},
+		},
+	})
+	ctx := context.Background()
+
+	doc, err := fetchPackument(ctx, server.URL, "@scope/pkg")
+	if err != nil {
+		t.Fatalf("fetchPackument returned error: %v", err)
+	}
+	if doc.DistTags["latest"] != "1.0.0" {
+		t.Errorf("expected latest 1.0.0, got %q", doc.DistTags["latest"])
+	}
+
+	if _, err := fetchPackument(ctx, server.URL, "missing"); !errors.Is(err, errPackageNotFound) {
+		t.Errorf("expected errPackageNotFound, got %v", err)
+	}
+}
+
+func TestCheckRegistryPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry string
+		allowed  []string
+		wantErr  bool
+	}{
+		{"no_allowlist", "https://registry.npmjs.org/", nil, false},
+		{"allowed_host", "https://npm.example.com/", []string{"npm.example.com"}, false},
+		{"allowed_url_entry", "https://npm.example.com/repo/", []string{"https://npm.example.com"}, false},
+		{"allowed_wildcard", "https://eu.npm.example.com/", []string{"*.example.com"}, false},
+		{"wildcard_excludes_apex", "https://example.com/", []string{"*.example.com"}, true},
+		{"not_allowed", "https://evil.example.org/", []string{"npm.example.com"}, true},
+		{"denied_without_allowlist", "https://abc123.ngrok.io/", nil, true},
+		{"denied_even_if_allowed", "https://webhook.site/", []string{"webhook.site"}, true},
+		{"case_insensitive", "https://NPM.Example.com/", []string{"npm.example.com"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRegistryPolicy(tt.registry, tt.allowed)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkRegistryPolicy(%q, %v) error = %v, wantErr %v", tt.registry, tt.allowed, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllowedRegistriesConfig(t *testing.T) {
+	p := &NpmPlugin{}
+	cfg := p.parseConfig(map[string]any{
+		"registry":           "https://evil.example.org/",
+		"allowed_registries": []any{"npm.example.com"},
+	})
+	if err := p.validateConfig(cfg); err == nil {
+		t.Error("expected registry outside allowed_registries to fail validation")
+	}
+}
+
+func TestPublishURLConfig(t *testing.T) {
+	p := &NpmPlugin{}
+	cfg := p.parseConfig(map[string]any{
+		"registry":           "https://npm.example.com/meta/",
+		"publish_url":        "https://evil.example.org/publish/",
+		"allowed_registries": []any{"npm.example.com"},
+	})
+	if cfg.PublishURL != "https://evil.example.org/publish/" {
+		t.Fatalf("PublishURL = %q", cfg.PublishURL)
+	}
+	if err := p.validateConfig(cfg); err == nil {
+		t.Error("expected publish_url outside allowed_registries to fail validation")
+	}
+}
@@ -0,0 +1,289 @@
+// Package registrytest provides an embedded, in-memory npm registry for
+// end-to-end publish tests. It implements the subset of the registry API
+// used by npm publish, npm install and npm dist-tag, and can proxy unknown
+// packages to an upstream registry the way Verdaccio uplinks do.
+package registrytest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token is the auth token the registry accepts for writes.
+const Token = "registrytest-token"
+
+// Registry is an embedded npm registry. The zero value is not usable; create
+// one with New and release it with Close.
+type Registry struct {
+	server *httptest.Server
+	uplink string
+
+	mu       sync.Mutex
+	docs     map[string]map[string]any
+	tarballs map[string][]byte
+}
+
+// Option configures a Registry.
+type Option func(*Registry)
+
+// WithUplink proxies metadata requests for packages the registry does not
+// hold to upstream, so dependencies of published packages can be installed.
+func WithUplink(upstream string) Option {
+	return func(r *Registry) { r.uplink = strings.TrimSuffix(upstream, "/") }
+}
+
+// New starts a registry listening on a loopback address.
+func New(opts ...Option) *Registry {
+	r := &Registry{
+		docs:     map[string]map[string]any{},
+		tarballs: map[string][]byte{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.server = httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+	return r
+}
+
+// URL returns the registry URL with a trailing slash, as npm expects.
+func (r *Registry) URL() string {
+	return r.server.URL + "/"
+}
+
+// AuthArgs returns the npm flags authenticating writes to the registry.
+func (r *Registry) AuthArgs() []string {
+	return []string{"--" + strings.TrimPrefix(r.URL(), "http:") + ":_authToken=" + Token}
+}
+
+// Close shuts the registry down.
+func (r *Registry) Close() {
+	r.server.Close()
+}
+
+// Versions returns the published versions of a package, sorted.
+func (r *Registry) Versions(name string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.docs[name]
+	if !ok {
+		return nil
+	}
+	versions := make([]string, 0)
+	for v := range doc["versions"].(map[string]any) {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// DistTags returns the dist-tags of a package.
+func (r *Registry) DistTags(name string) map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tags := map[string]string{}
+	if doc, ok := r.docs[name]; ok {
+		for tag, v := range doc["dist-tags"].(map[string]any) {
+			tags[tag], _ = v.(string)
+		}
+	}
+	return tags
+}
+
+func (r *Registry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	p, err := url.PathUnescape(strings.TrimPrefix(req.URL.EscapedPath(), "/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		if req.Header.Get("Authorization") != "Bearer "+Token {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "authentication required"})
+			return
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(p, "-/package/") && strings.Contains(p, "/dist-tags"):
+		r.serveDistTags(w, req, p)
+	case strings.Contains(p, "/-/"):
+		r.serveTarball(w, p)
+	case req.Method == http.MethodPut:
+		r.servePublish(w, req, p)
+	case req.Method == http.MethodGet || req.Method == http.MethodHead:
+		r.servePackument(w, req, p)
+	default:
+		http.Error(w, "unsupported request", http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *Registry) servePackument(w http.ResponseWriter, req *http.Request, name string) {
+	r.mu.Lock()
+	doc, ok := r.docs[name]
+	var body []byte
+	if ok {
+		body, _ = json.Marshal(doc)
+	}
+	r.mu.Unlock()
+
+	if ok {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+		return
+	}
+	if r.uplink == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+
+	upstream, err := http.NewRequestWithContext(req.Context(), http.MethodGet, r.uplink+"/"+strings.Replace(url.PathEscape(name), "%40", "@", 1), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	upstream.Header.Set("Accept", req.Header.Get("Accept"))
+	resp, err := http.DefaultClient.Do(upstream)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+func (r *Registry) serveTarball(w http.ResponseWriter, p string) {
+	r.mu.Lock()
+	data, ok := r.tarballs[p]
+	r.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(data)
+}
+
+// publishBody is the document npm publish uploads.
+type publishBody struct {
+	Name        string                    `json:"name"`
+	DistTags    map[string]string         `json:"dist-tags"`
+	Versions    map[string]map[string]any `json:"versions"`
+	Attachments map[string]struct {
+		Data string `json:"data"`
+	} `json:"_attachments"`
+}
+
+func (r *Registry) servePublish(w http.ResponseWriter, req *http.Request, name string) {
+	var body publishBody
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Name != name {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid publish document"})
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.docs[name]
+	if !ok {
+		doc = map[string]any{
+			"name":      name,
+			"dist-tags": map[string]any{},
+			"versions":  map[string]any{},
+			"time":      map[string]any{"created": now()},
+		}
+	}
+	versions := doc["versions"].(map[string]any)
+	for v := range body.Versions {
+		if _, exists := versions[v]; exists {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": fmt.Sprintf("cannot publish over previously published version %q", v)})
+			return
+		}
+	}
+
+	for v, manifest := range body.Versions {
+		for filename, att := range body.Attachments {
+			data, err := base64.StdEncoding.DecodeString(att.Data)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid attachment"})
+				return
+			}
+			key := name + "/-/" + path.Base(filename)
+			r.tarballs[key] = data
+			if dist, ok := manifest["dist"].(map[string]any); ok {
+				dist["tarball"] = r.URL() + key
+			}
+		}
+		versions[v] = manifest
+		doc["time"].(map[string]any)[v] = now()
+	}
+	for tag, v := range body.DistTags {
+		doc["dist-tags"].(map[string]any)[tag] = v
+	}
+	doc["time"].(map[string]any)["modified"] = now()
+	r.docs[name] = doc
+
+	writeJSON(w, http.StatusCreated, map[string]bool{"ok": true})
+}
+
+func (r *Registry) serveDistTags(w http.ResponseWriter, req *http.Request, p string) {
+	rest := strings.TrimPrefix(p, "-/package/")
+	i := strings.LastIndex(rest, "/dist-tags")
+	name, tag := rest[:i], strings.TrimPrefix(rest[i+len("/dist-tags"):], "/")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.docs[name]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+	tags := doc["dist-tags"].(map[string]any)
+
+	switch req.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, tags)
+	case http.MethodPut, http.MethodPost:
+		var version string
+		if err := json.NewDecoder(req.Body).Decode(&version); err != nil || tag == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid dist-tag request"})
+			return
+		}
+		if _, ok := doc["versions"].(map[string]any)[version]; !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "version not found"})
+			return
+		}
+		tags[tag] = version
+		writeJSON(w, http.StatusCreated, map[string]bool{"ok": true})
+	case http.MethodDelete:
+		delete(tags, tag)
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	default:
+		http.Error(w, "unsupported request", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,95 @@
+package registrytest
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// npm runs npm in dir against the registry, failing the test on error.
+func npm(t *testing.T, r *Registry, dir string, args ...string) string {
+	t.Helper()
+	args = append(append(args, "--registry", r.URL()), r.AuthArgs()...)
+	cmd := exec.Command("npm", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "npm_config_cache="+filepath.Join(t.TempDir(), "cache"), "npm_config_update_notifier=false")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("npm %v failed: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func TestPublishInstallDistTag(t *testing.T) {
+	if _, err := exec.LookPath("npm"); err != nil {
+		t.Skip("npm not available")
+	}
+
+	r := New()
+	defer r.Close()
+
+	pkgDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(pkgDir, "package.json"), []byte(`{"name":"@acme/lib","version":"1.0.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pkgDir, "index.js"), []byte("module.exports = 42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	npm(t, r, pkgDir, "publish", "--access", "public")
+
+	if got := r.Versions("@acme/lib"); len(got) != 1 || got[0] != "1.0.0" {
+		t.Fatalf("Versions() = %v", got)
+	}
+	if got := r.DistTags("@acme/lib")["latest"]; got != "1.0.0" {
+		t.Errorf("latest = %q, want 1.0.0", got)
+	}
+
+	npm(t, r, pkgDir, "dist-tag", "add", "@acme/lib@1.0.0", "stable")
+	if got := r.DistTags("@acme/lib")["stable"]; got != "1.0.0" {
+		t.Errorf("stable = %q, want 1.0.0", got)
+	}
+
+	installDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(installDir, "package.json"), []byte(`{"name":"consumer","private":true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	npm(t, r, installDir, "install", "--no-audit", "--no-fund", "@acme/lib@1.0.0")
+
+	data, err := os.ReadFile(filepath.Join(installDir, "node_modules", "@acme", "lib", "package.json"))
+	if err != nil {
+		t.Fatalf("package not installed: %v", err)
+	}
+	var installed struct{ Version string }
+	if err := json.Unmarshal(data, &installed); err != nil || installed.Version != "1.0.0" {
+		t.Errorf("installed version = %q, %v", installed.Version, err)
+	}
+}
+
+func TestRejectsRepublishAndUnauthenticated(t *testing.T) {
+	if _, err := exec.LookPath("npm"); err != nil {
+		t.Skip("npm not available")
+	}
+
+	r := New()
+	defer r.Close()
+
+	pkgDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(pkgDir, "package.json"), []byte(`{"name":"lib","version":"1.0.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	npm(t, r, pkgDir, "publish")
+
+	cmd := exec.Command("npm", append([]string{"publish", "--registry", r.URL()}, r.AuthArgs()...)...)
+	cmd.Dir = pkgDir
+	if out, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("expected republish to fail:\n%s", out)
+	}
+
+	cmd = exec.Command("npm", "dist-tag", "add", "lib@1.0.0", "beta", "--registry", r.URL(), "--//"+r.server.Listener.Addr().String()+"/:_authToken=wrong")
+	cmd.Dir = pkgDir
+	if out, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("expected unauthenticated write to fail:\n%s", out)
+	}
+}
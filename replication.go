@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// replicationPollInterval is how often verification re-fetches the packument
+// while waiting for a publish to propagate. It is a variable so tests can
+// shorten it.
+var replicationPollInterval = 5 * time.Second
+
+// replicationLagEvent is posted to the replication lag webhook.
+type replicationLagEvent struct {
+	Package          string  `json:"package"`
+	Version          string  `json:"version"`
+	Tag              string  `json:"tag"`
+	Registry         string  `json:"registry"`
+	LagSeconds       float64 `json:"lag_seconds"`
+	ThresholdSeconds int     `json:"threshold_seconds"`
+	Visible          bool    `json:"visible"`
+}
+
+// replicated reports whether the registry serves version under tag.
+func replicated(doc *packument, version, tag string) bool {
+	if doc == nil {
+		return false
+	}
+	_, ok := doc.Versions[version]
+	return ok && doc.DistTags[tag] == version
+}
+
+// waitForReplication polls the registry until it serves version under tag,
+// giving up after twice the lag threshold measured from since (the publish
+// time). It returns the last packument fetched (empty if the package never
+// appeared), the lag and whether the version became visible.
+func waitForReplication(ctx context.Context, cfg *Config, name, version string, since time.Time) (*packument, time.Duration, bool, error) {
+	deadline := since.Add(2 * time.Duration(cfg.ReplicationLagThreshold) * time.Second)
+	for {
+		doc, err := fetchPackument(ctx, registryURL(cfg), name)
+		if err != nil && !errors.Is(err, errPackageNotFound) {
+			return nil, 0, false, err
+		}
+		lag := time.Since(since)
+		if replicated(doc, version, cfg.Tag) {
+			return doc, lag, true, nil
+		}
+		if !time.Now().Before(deadline) {
+			if doc == nil {
+				doc = &packument{}
+			}
+			return doc, lag, false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, lag, false, ctx.Err()
+		case <-time.After(replicationPollInterval):
+		}
+	}
+}
+
+// notifyReplicationLag posts the event to the webhook.
+func notifyReplicationLag(ctx context.Context, webhook string, event replicationLagEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal replication lag event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// checkReplicationLag waits for the publish to propagate and records the lag.
+// Exceeding the threshold only warns (and calls the webhook); verification
+// itself still decides whether the release is healthy.
+func checkReplicationLag(ctx context.Context, cfg *Config, outputs map[string]any, name, version string, since time.Time) (*packument, error) {
+	doc, lag, visible, err := waitForReplication(ctx, cfg, name, version, since)
+	if err != nil {
+		return nil, err
+	}
+	threshold := time.Duration(cfg.ReplicationLagThreshold) * time.Second
+	exceeded := !visible || lag > threshold
+	outputs["replication_lag_exceeded"] = exceeded
+	if visible {
+		outputs["replication_lag_seconds"] = lag.Seconds()
+	}
+	if !exceeded {
+		return doc, nil
+	}
+
+	if visible {
+		appendWarning(outputs, fmt.Sprintf("registry replication took %s, over the %s threshold", lag.Round(time.Second), threshold))
+	} else {
+		appendWarning(outputs, fmt.Sprintf("%s@%s was not visible as %q after %s", name, version, cfg.Tag, lag.Round(time.Second)))
+	}
+	if cfg.ReplicationLagWebhook != "" {
+		event := replicationLagEvent{
+			Package:          name,
+			Version:          version,
+			Tag:              cfg.Tag,
+			Registry:         registryURL(cfg),
+			LagSeconds:       lag.Seconds(),
+			ThresholdSeconds: cfg.ReplicationLagThreshold,
+			Visible:          visible,
+		}
+		if err := notifyReplicationLag(ctx, cfg.ReplicationLagWebhook, event); err != nil {
+			appendWarning(outputs, fmt.Sprintf("replication lag webhook failed: %v", err))
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckReplicationLag(t *testing.T) {
+	orig := replicationPollInterval
+	replicationPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { replicationPollInterval = orig })
+
+	doc := &packument{
+		Name:     "pkg",
+		DistTags: map[string]string{"latest": "1.2.3"},
+		Versions: map[string]packumentVersion{"1.2.3": {Version: "1.2.3"}},
+	}
+	// The registry 404s for the first `delay` requests, as a lagging
+	// replica would.
+	newLaggingRegistry := func(delay int32) *httptest.Server {
+		var requests atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) <= delay {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(doc)
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	var events []replicationLagEvent
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event replicationLagEvent
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		events = append(events, event)
+	}))
+	defer webhook.Close()
+
+	tests := []struct {
+		name         string
+		delay        int32
+		publishedAgo time.Duration
+		wantExceeded bool
+		wantVisible  bool
+	}{
+		{"propagated within threshold", 2, 0, false, true},
+		{"propagated late", 0, 3 * time.Second, true, true},
+		{"never propagated", 1000, 5 * time.Second, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events = nil
+			cfg := &Config{
+				Registry:                newLaggingRegistry(tt.delay).URL,
+				Tag:                     "latest",
+				ReplicationLagThreshold: 1,
+				ReplicationLagWebhook:   webhook.URL,
+			}
+			outputs := map[string]any{}
+			got, err := checkReplicationLag(context.Background(), cfg, outputs, "pkg", "1.2.3", time.Now().Add(-tt.publishedAgo))
+			if err != nil {
+				t.Fatalf("checkReplicationLag() error = %v", err)
+			}
+			if replicated(got, "1.2.3", "latest") != tt.wantVisible {
+				t.Errorf("visible = %v, want %v", !tt.wantVisible, tt.wantVisible)
+			}
+			if outputs["replication_lag_exceeded"] != tt.wantExceeded {
+				t.Errorf("replication_lag_exceeded = %v, want %v", outputs["replication_lag_exceeded"], tt.wantExceeded)
+			}
+			if _, ok := outputs["replication_lag_seconds"]; ok != tt.wantVisible {
+				t.Errorf("replication_lag_seconds present = %v, want %v", ok, tt.wantVisible)
+			}
+			if tt.wantExceeded {
+				if len(events) != 1 || events[0].Visible != tt.wantVisible || events[0].ThresholdSeconds != 1 {
+					t.Errorf("unexpected webhook events: %+v", events)
+				}
+				if warnings, _ := outputs["warnings"].([]string); len(warnings) != 1 {
+					t.Errorf("expected one warning, got %v", outputs["warnings"])
+				}
+			} else if len(events) != 0 || outputs["warnings"] != nil {
+				t.Errorf("unexpected alert: events=%+v warnings=%v", events, outputs["warnings"])
+			}
+		})
+	}
+}
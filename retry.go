@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// maxRetryDelay caps the exponential backoff between publish attempts.
+const maxRetryDelay = time.Minute
+
+// fatalNpmCodes are npm error codes retrying cannot fix: auth, permission,
+// validation and version conflicts.
+var fatalNpmCodes = map[string]bool{
+	"E400": true, "E401": true, "E402": true, "E403": true, "E404": true,
+	"E409": true, "E413": true, "E422": true, "EOTP": true,
+	"EPUBLISHCONFLICT": true, "ENEEDAUTH": true,
+}
+
+// retryableNpmCodes are npm error codes of transient network or registry
+// failures.
+var retryableNpmCodes = map[string]bool{
+	"ECONNRESET": true, "ECONNREFUSED": true, "ETIMEDOUT": true,
+	"ESOCKETTIMEDOUT": true, "EAI_AGAIN": true, "EPIPE": true,
+	"ENETUNREACH": true, "EHOSTUNREACH": true,
+	"E408": true, "E429": true, "E500": true, "E502": true, "E503": true, "E504": true,
+}
+
+// npmCodeRegexp matches the error code line npm prints on failure.
+var npmCodeRegexp = regexp.MustCompile(`(?m)^npm (?:ERR!|error) code (\S+)`)
+
+// httpStatusError is a registry response with a failure status.
+type httpStatusError struct {
+	StatusCode int
+	msg        string
+}
+
+func (e *httpStatusError) Error() string {
+	return e.msg
+}
+
+// retrySleep waits between attempts; tests replace it.
+var retrySleep = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// retryablePublishError reports whether a failed publish may succeed when
+// tried again. Lifecycle script failures and unknown errors are fatal.
+func retryablePublishError(err error) bool {
+	var npmErr *npmError
+	if errors.As(err, &npmErr) {
+		if npmErr.Script != "" {
+			return false
+		}
+		m := npmCodeRegexp.FindStringSubmatch(npmErr.Stderr)
+		if m == nil {
+			return false
+		}
+		return retryableNpmCodes[m[1]] && !fatalNpmCodes[m[1]]
+	}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == 408 || statusErr.StatusCode == 429 || statusErr.StatusCode >= 500
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && !errors.Is(err, context.Canceled)
+}
+
+// retryDelay returns the wait before retry number attempt (1-based):
+// base doubled per attempt, capped at maxRetryDelay.
+func retryDelay(base time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < maxRetryDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryDelay)
+}
+
+// withRetries runs publish, retrying transient failures up to cfg.Retries
+// times with exponential backoff. It returns the number of attempts made.
+func withRetries(ctx context.Context, cfg *Config, publish func() (string, error)) (string, int, error) {
+	base := time.Duration(cfg.RetryDelay) * time.Second
+	for attempt := 1; ; attempt++ {
+		stdout, err := publish()
+		if err == nil || attempt > cfg.Retries || !retryablePublishError(err) {
+			if err != nil && attempt > 1 {
+				err = fmt.Errorf("publish failed after %d attempts (an earlier attempt may have reached the registry): %w", attempt, err)
+			}
+			return stdout, attempt, err
+		}
+		if serr := retrySleep(ctx, retryDelay(base, attempt)); serr != nil {
+			return stdout, attempt, err
+		}
+	}
+}
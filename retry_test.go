@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestRetryablePublishError(t *testing.T) {
+	exit := errors.New("exit status 1")
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"econnreset", newNpmError("publish", exit, "", simulatedErrors["network"]), true},
+		{"etimedout", newNpmError("publish", exit, "", simulatedErrors["timeout"]), true},
+		{"registry 503", newNpmError("publish", exit, "", "npm error code E503\nnpm error 503 Service Unavailable"), true},
+		{"unauthorized", newNpmError("publish", exit, "", simulatedErrors["auth"]), false},
+		{"conflict", newNpmError("publish", exit, "", simulatedErrors["conflict"]), false},
+		{"publish conflict", newNpmError("publish", exit, "", "npm ERR! code EPUBLISHCONFLICT"), false},
+		{"lifecycle script", newNpmError("publish", exit, "", simulatedErrors["script"]), false},
+		{"no code", newNpmError("publish", exit, "", "something broke"), false},
+		{"api 502", &httpStatusError{StatusCode: 502}, true},
+		{"api 429", &httpStatusError{StatusCode: 429}, true},
+		{"api 403", &httpStatusError{StatusCode: 403}, false},
+		{"transport", &url.Error{Op: "Put", URL: "https://r", Err: errors.New("connection reset")}, true},
+		{"other", errors.New("package.json needs a name and version"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryablePublishError(tt.err); got != tt.want {
+				t.Errorf("retryablePublishError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	var got []time.Duration
+	for attempt := 1; attempt <= 7; attempt++ {
+		got = append(got, retryDelay(2*time.Second, attempt))
+	}
+	want := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second, time.Minute, time.Minute}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("delays = %v, want %v", got, want)
+	}
+}
+
+// recordSleeps replaces retrySleep, recording the requested delays.
+func recordSleeps(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var sleeps []time.Duration
+	orig := retrySleep
+	retrySleep = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	t.Cleanup(func() { retrySleep = orig })
+	return &sleeps
+}
+
+func TestPublishRetries(t *testing.T) {
+	sleeps := recordSleeps(t)
+	// Fails with ECONNRESET twice, then succeeds
+	logPath := fakeNpm(t, `count="$(dirname "$0")/count"
+n=$(cat "$count" 2>/dev/null || echo 0)
+echo $((n + 1)) > "$count"
+if [ "$n" -lt 2 ]; then
+  echo "npm ERR! code ECONNRESET" >&2
+  exit 1
+fi
+echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"retries": 3, "retry_delay": 1},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success || resp.Outputs["publish_attempts"] != 3 {
+		t.Fatalf("unexpected response: %v %+v", err, resp)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second}; !reflect.DeepEqual(*sleeps, want) {
+		t.Errorf("sleeps = %v, want %v", *sleeps, want)
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 3 {
+		t.Errorf("npm ran %d times, want 3", len(calls))
+	}
+}
+
+func TestPublishRetriesFatal(t *testing.T) {
+	sleeps := recordSleeps(t)
+	logPath := fakeNpm(t, `echo "npm ERR! code E403" >&2
+exit 1`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"retries": 3},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || resp.Success || !strings.Contains(resp.Error, "E403") {
+		t.Fatalf("expected E403 failure, got %v %+v", err, resp)
+	}
+	if len(*sleeps) != 0 || len(npmCalls(t, logPath)) != 1 {
+		t.Errorf("fatal error was retried: sleeps %v", *sleeps)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Rollback actions.
+const (
+	rollbackDeprecate = "deprecate"
+	rollbackUnpublish = "unpublish"
+)
+
+// defaultRollbackMessage is the deprecation message template for rolled back
+// versions.
+const defaultRollbackMessage = "{{.Name}}@{{.Version}} was rolled back after a failed release"
+
+// unpublishWindow is how long after publishing npm allows a version to be
+// unpublished.
+const unpublishWindow = 72 * time.Hour
+
+// Rollback undoes a publish when a later release step fails, from the
+// on-error hook.
+type Rollback struct {
+	// Action is "deprecate" or "unpublish". Unpublish falls back to
+	// deprecate outside npm's 72 hour window or when the registry refuses.
+	Action string `json:"action,omitempty"`
+	// Message is the deprecation message template; it defaults to
+	// "{{.Name}}@{{.Version}} was rolled back after a failed release".
+	Message string `json:"message,omitempty"`
+}
+
+// validateRollback checks the action and message template.
+func validateRollback(r Rollback) error {
+	switch r.Action {
+	case "":
+		if r.Message != "" {
+			return fmt.Errorf("action is required")
+		}
+		return nil
+	case rollbackDeprecate, rollbackUnpublish:
+	default:
+		return fmt.Errorf("action must be %s or %s", rollbackDeprecate, rollbackUnpublish)
+	}
+	_, err := rollbackMessage(r, templateData{})
+	return err
+}
+
+// rollbackMessage renders the deprecation message for a rolled back version.
+func rollbackMessage(r Rollback, data templateData) (string, error) {
+	message := r.Message
+	if message == "" {
+		message = defaultRollbackMessage
+	}
+	return renderTemplate(message, data)
+}
+
+// rollbackAction returns the action to take for rec: unpublish only applies
+// while the version is inside npm's unpublish window.
+func rollbackAction(r Rollback, rec *publishRecord, now time.Time) string {
+	if r.Action == rollbackUnpublish && !rec.PublishedAt.IsZero() && now.Sub(rec.PublishedAt) < unpublishWindow {
+		return rollbackUnpublish
+	}
+	return rollbackDeprecate
+}
+
+// rollback undoes this release's publish after a later pipeline step failed.
+// It only acts on the version recorded by post-publish, so a failure before
+// the upload leaves the registry alone.
+func (p *NpmPlugin) rollback(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
+	if cfg.Rollback.Action == "" {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: "Rollback disabled",
+		}, nil
+	}
+
+	if err := p.validateConfig(cfg); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("configuration validation failed: %v", err),
+		}, nil
+	}
+
+	packageDir, err := validatePackageDir(cfg.PackageDir)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid package directory: %v", err),
+		}, nil
+	}
+
+	pkg, err := readPackageJSON(packageDir)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	cfg = scopedConfig(cfg, pkg.Name)
+
+	rec, err := loadRecord(cfg, pkg.Name)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	if rec == nil || rec.Version != releaseCtx.Version {
+		return skipResponse("not_published", fmt.Sprintf("%s@%s was not published by this release, nothing to roll back", pkg.Name, releaseCtx.Version)), nil
+	}
+
+	data := newTemplateData(pkg.Name, cfg, releaseCtx)
+	message, err := rollbackMessage(cfg.Rollback, data)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("rollback failed: %v", err),
+		}, nil
+	}
+
+	action := rollbackAction(cfg.Rollback, rec, time.Now())
+	outputs := map[string]any{
+		"package":         pkg.Name,
+		"version":         rec.Version,
+		"rollback_action": action,
+	}
+	if cfg.Rollback.Action == rollbackUnpublish && action != rollbackUnpublish {
+		appendWarning(outputs, fmt.Sprintf("%s@%s is outside the %s unpublish window, deprecating instead", pkg.Name, rec.Version, unpublishWindow))
+	}
+
+	if dryRun {
+		outputs["rolled_back"] = false
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("Would %s %s@%s", action, pkg.Name, rec.Version),
+			Outputs: outputs,
+		}, nil
+	}
+
+	done, err := useEphemeralNpmrc(cfg, outputs, dryRun)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	defer done()
+
+	if action == rollbackUnpublish {
+		args := append([]string{"unpublish", pkg.Name + "@" + rec.Version}, registryArgs(cfg)...)
+		if _, err := runNpm(ctx, packageDir, args...); err != nil {
+			appendWarning(outputs, fmt.Sprintf("unpublish failed, deprecating instead: %v", err))
+			action = rollbackDeprecate
+			outputs["rollback_action"] = action
+		}
+	}
+
+	if action == rollbackDeprecate {
+		outputs["deprecation_message"] = message
+		if err := deprecateVersion(ctx, cfg, packageDir, pkg.Name, rec.Version, message); err != nil {
+			outputs["rolled_back"] = false
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("rollback failed: %v", err),
+				Outputs: outputs,
+			}, nil
+		}
+		restoreTag(ctx, cfg, outputs, packageDir, rec, releaseCtx.PreviousVersion)
+	}
+
+	if err := deleteRecord(cfg, pkg.Name); err != nil {
+		appendWarning(outputs, err.Error())
+	}
+	outputs["rolled_back"] = true
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Rolled back %s@%s with npm %s", pkg.Name, rec.Version, action),
+		Outputs: outputs,
+	}, nil
+}
+
+// deprecateVersion deprecates a single version with npm deprecate, or
+// through the registry API under publish_method api.
+func deprecateVersion(ctx context.Context, cfg *Config, packageDir, name, version, message string) error {
+	if cfg.PublishMethod == publishMethodAPI {
+		return deprecateViaAPI(ctx, cfg, name, []string{version}, message)
+	}
+	args := append([]string{"deprecate", name + "@" + version, message}, registryArgs(cfg)...)
+	_, err := runNpm(ctx, packageDir, args...)
+	return err
+}
+
+// restoreTag points the dist-tag the rolled back version was published under
+// back at the previous version, so installs stop resolving to the deprecated
+// release. It leaves the tag alone when it has moved on or there is no
+// published previous version. Failures are warnings.
+func restoreTag(ctx context.Context, cfg *Config, outputs map[string]any, packageDir string, rec *publishRecord, previous string) {
+	if rec.Tag == "" || previous == "" {
+		return
+	}
+	doc, err := fetchPackument(ctx, registryURL(cfg), rec.Name)
+	if errors.Is(err, errPackageNotFound) {
+		return
+	}
+	if err != nil {
+		appendWarning(outputs, fmt.Sprintf("dist-tag %q not restored: %v", rec.Tag, err))
+		return
+	}
+	if doc.DistTags[rec.Tag] != rec.Version || !hasVersion(doc, previous) {
+		return
+	}
+	if err := setDistTag(ctx, cfg, packageDir, rec.Name, rec.Tag, previous); err != nil {
+		appendWarning(outputs, fmt.Sprintf("dist-tag %q not restored: %v", rec.Tag, err))
+		return
+	}
+	outputs["restored_tag"] = rec.Tag
+	outputs["restored_version"] = previous
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateRollback(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       Rollback
+		wantErr bool
+	}{
+		{name: "unset", r: Rollback{}},
+		{name: "deprecate", r: Rollback{Action: "deprecate", Message: "{{.Version}} is broken"}},
+		{name: "unpublish", r: Rollback{Action: "unpublish"}},
+		{name: "unknown action", r: Rollback{Action: "delete"}, wantErr: true},
+		{name: "bad template", r: Rollback{Action: "deprecate", Message: "{{.Nope}}"}, wantErr: true},
+		{name: "message without action", r: Rollback{Message: "broken"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRollback(tt.r); (err != nil) != tt.wantErr {
+				t.Errorf("validateRollback() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRollbackAction(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	unpublish := Rollback{Action: "unpublish"}
+	tests := []struct {
+		name string
+		r    Rollback
+		age  time.Duration
+		want string
+	}{
+		{"inside window", unpublish, time.Hour, "unpublish"},
+		{"outside window", unpublish, 73 * time.Hour, "deprecate"},
+		{"deprecate", Rollback{Action: "deprecate"}, time.Hour, "deprecate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &publishRecord{PublishedAt: now.Add(-tt.age)}
+			if got := rollbackAction(tt.r, rec, now); got != tt.want {
+				t.Errorf("rollbackAction() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := rollbackAction(unpublish, &publishRecord{}, now); got != "deprecate" {
+		t.Errorf("rollbackAction() without publish time = %q, want deprecate", got)
+	}
+}
+
+func TestRollbackOnError(t *testing.T) {
+	tests := []struct {
+		name        string
+		action      string
+		npm         string
+		publishedAt time.Time
+		wantCalls   []string
+		wantAction  string
+		wantTag     bool
+	}{
+		{
+			name:        "unpublish",
+			action:      "unpublish",
+			npm:         `echo '{}'`,
+			publishedAt: time.Now().Add(-time.Hour),
+			wantCalls:   []string{"unpublish lib@1.1.0"},
+			wantAction:  "unpublish",
+		},
+		{
+			name:        "unpublish refused",
+			action:      "unpublish",
+			npm:         `case "$1" in unpublish) echo "npm error code E405" >&2; exit 1 ;; esac`,
+			publishedAt: time.Now().Add(-time.Hour),
+			wantCalls:   []string{"unpublish lib@1.1.0", "deprecate lib@1.1.0", "dist-tag add lib@1.0.0 latest"},
+			wantAction:  "deprecate",
+			wantTag:     true,
+		},
+		{
+			name:        "outside unpublish window",
+			action:      "unpublish",
+			npm:         `echo '{}'`,
+			publishedAt: time.Now().Add(-100 * time.Hour),
+			wantCalls:   []string{"deprecate lib@1.1.0", "dist-tag add lib@1.0.0 latest"},
+			wantAction:  "deprecate",
+			wantTag:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := fakeNpm(t, tt.npm)
+			t.Setenv("TMPDIR", t.TempDir())
+			server := newTestRegistry(t, map[string]*packument{"lib": {
+				DistTags: map[string]string{"latest": "1.1.0"},
+				Versions: map[string]packumentVersion{"1.0.0": {}, "1.1.0": {}},
+			}})
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.1.0"}`)
+			chdir(t, dir)
+
+			config := map[string]any{"registry": server.URL, "rollback": map[string]any{"action": tt.action}}
+			cfg := (&NpmPlugin{}).parseConfig(config)
+			if err := saveRecord(cfg, &publishRecord{Name: "lib", Version: "1.1.0", Tag: "latest", PublishedAt: tt.publishedAt}); err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookOnError,
+				Config:  config,
+				Context: plugin.ReleaseContext{Version: "1.1.0", PreviousVersion: "1.0.0"},
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("unexpected failure: %v %+v", err, resp)
+			}
+			calls := npmCalls(t, logPath)
+			if len(calls) != len(tt.wantCalls) {
+				t.Fatalf("npm calls = %q, want %q", calls, tt.wantCalls)
+			}
+			for i, want := range tt.wantCalls {
+				if !strings.HasPrefix(calls[i], want) {
+					t.Errorf("npm call %d = %q, want prefix %q", i, calls[i], want)
+				}
+			}
+			if resp.Outputs["rolled_back"] != true || resp.Outputs["rollback_action"] != tt.wantAction {
+				t.Errorf("outputs = %v", resp.Outputs)
+			}
+			if (resp.Outputs["restored_tag"] == "latest") != tt.wantTag {
+				t.Errorf("restored_tag = %v", resp.Outputs["restored_tag"])
+			}
+			if rec, _ := loadRecord(cfg, "lib"); rec != nil {
+				t.Errorf("publish record not removed: %+v", rec)
+			}
+		})
+	}
+}
+
+func TestRollbackSkipsUnpublishedVersion(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.1.0"}`)
+	chdir(t, dir)
+
+	config := map[string]any{"rollback": map[string]any{"action": "deprecate"}}
+	cfg := (&NpmPlugin{}).parseConfig(config)
+	if err := saveRecord(cfg, &publishRecord{Name: "lib", Version: "1.0.0", PublishedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookOnError,
+		Config:  config,
+		Context: plugin.ReleaseContext{Version: "1.1.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if resp.Outputs["skip_reason"] != "not_published" {
+		t.Errorf("outputs = %v", resp.Outputs)
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 0 {
+		t.Errorf("unexpected npm calls: %q", calls)
+	}
+}
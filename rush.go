@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// rushVersionPoliciesFile holds Rush's version policies.
+var rushVersionPoliciesFile = filepath.Join("common", "config", "rush", "version-policies.json")
+
+// Rush version policy kinds.
+const (
+	rushLockStep   = "lockStepVersion"
+	rushIndividual = "individualVersion"
+)
+
+// rushProject is a project entry of rush.json.
+type rushProject struct {
+	PackageName       string `json:"packageName"`
+	ProjectFolder     string `json:"projectFolder"`
+	ShouldPublish     bool   `json:"shouldPublish"`
+	VersionPolicyName string `json:"versionPolicyName"`
+}
+
+// rushVersionPolicy is an entry of version-policies.json.
+type rushVersionPolicy struct {
+	DefinitionName string `json:"definitionName"`
+	PolicyName     string `json:"policyName"`
+	Version        string `json:"version"`
+	LockedMajor    *int   `json:"lockedMajor"`
+}
+
+// rushConfig is what the Rush mode reads from rush.json and the version
+// policies.
+type rushConfig struct {
+	Projects []rushProject `json:"projects"`
+	policies map[string]rushVersionPolicy
+}
+
+// stripJSONComments removes // and /* */ comments outside strings, as Rush's
+// config files allow them.
+func stripJSONComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString, escaped := false, false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			out = append(out, c)
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/') {
+				i++
+			}
+			i++
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// readJSONC decodes a JSON-with-comments file into v.
+func readJSONC(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(stripJSONComments(data), v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// readRushConfig reads rush.json and, when projects use version policies,
+// version-policies.json from the working directory.
+func readRushConfig() (*rushConfig, error) {
+	var r rushConfig
+	if err := readJSONC("rush.json", &r); err != nil {
+		return nil, fmt.Errorf("rush mode requires rush.json: %w", err)
+	}
+	r.policies = map[string]rushVersionPolicy{}
+	var policies []rushVersionPolicy
+	if err := readJSONC(rushVersionPoliciesFile, &policies); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, policy := range policies {
+		r.policies[policy.PolicyName] = policy
+	}
+	for _, project := range r.Projects {
+		if name := project.VersionPolicyName; name != "" {
+			if _, ok := r.policies[name]; !ok {
+				return nil, fmt.Errorf("%s uses unknown version policy %q", project.PackageName, name)
+			}
+		}
+	}
+	return &r, nil
+}
+
+// publishable returns the projects Rush publishes: those marked
+// shouldPublish or governed by a version policy.
+func (r *rushConfig) publishable() []rushProject {
+	var projects []rushProject
+	for _, project := range r.Projects {
+		if project.ShouldPublish || project.VersionPolicyName != "" {
+			projects = append(projects, project)
+		}
+	}
+	return projects
+}
+
+// policyFor returns the version policy governing the named package, if any.
+func (r *rushConfig) policyFor(name string) (rushVersionPolicy, bool) {
+	for _, project := range r.Projects {
+		if project.PackageName == name && project.VersionPolicyName != "" {
+			return r.policies[project.VersionPolicyName], true
+		}
+	}
+	return rushVersionPolicy{}, false
+}
+
+// lockStep reports whether the named package is versioned in lock step,
+// taking the release version.
+func (r *rushConfig) lockStep(name string) bool {
+	policy, ok := r.policyFor(name)
+	return ok && policy.DefinitionName == rushLockStep
+}
+
+// checkLockedMajor enforces an individual policy's lockedMajor on version.
+func (r *rushConfig) checkLockedMajor(name, version string) error {
+	policy, ok := r.policyFor(name)
+	if !ok || policy.DefinitionName != rushIndividual || policy.LockedMajor == nil {
+		return nil
+	}
+	v, err := parseSemver(version)
+	if err != nil {
+		return fmt.Errorf("invalid version %q: %w", version, err)
+	}
+	if v.Major != *policy.LockedMajor {
+		return fmt.Errorf("version %s violates lockedMajor %d of version policy %q", version, *policy.LockedMajor, policy.PolicyName)
+	}
+	return nil
+}
+
+// applyRushConfig makes the publishable Rush projects the workspaces, unless
+// workspaces are configured explicitly.
+func applyRushConfig(cfg *Config) error {
+	r, err := readRushConfig()
+	if err != nil {
+		return err
+	}
+	cfg.rush = r
+	if len(cfg.Workspaces) == 0 {
+		for _, project := range r.publishable() {
+			cfg.Workspaces = append(cfg.Workspaces, project.ProjectFolder)
+		}
+		if len(cfg.Workspaces) == 0 {
+			return fmt.Errorf("rush.json has no projects to publish")
+		}
+	}
+	return nil
+}
+
+// writeRushPolicyVersions sets version on the lock-step policies in
+// version-policies.json, editing the file in place to keep its comments.
+func writeRushPolicyVersions(r *rushConfig, version string) error {
+	data, err := os.ReadFile(rushVersionPoliciesFile)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", rushVersionPoliciesFile, err)
+	}
+	for name, policy := range r.policies {
+		if policy.DefinitionName != rushLockStep {
+			continue
+		}
+		nameRe := regexp.MustCompile(`"policyName"\s*:\s*` + regexp.QuoteMeta(fmt.Sprintf("%q", name)))
+		loc := nameRe.FindIndex(data)
+		if loc == nil {
+			return fmt.Errorf("version policy %q not found in %s", name, rushVersionPoliciesFile)
+		}
+		start, end := enclosingObject(data, loc[0])
+		if start < 0 {
+			return fmt.Errorf("version policy %q not found in %s", name, rushVersionPoliciesFile)
+		}
+		obj := lernaVersionRegexp.ReplaceAll(data[start:end], []byte(fmt.Sprintf("${1}%q", version)))
+		data = append(append(append([]byte{}, data[:start]...), obj...), data[end:]...)
+	}
+	if err := os.WriteFile(rushVersionPoliciesFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", rushVersionPoliciesFile, err)
+	}
+	return nil
+}
+
+// enclosingObject returns the bounds of the innermost JSON object around
+// offset, or -1 when there is none. Braces inside strings are not expected in
+// version policies.
+func enclosingObject(data []byte, offset int) (int, int) {
+	depth := 0
+	start := -1
+	for i := offset; i >= 0; i-- {
+		if data[i] == '}' {
+			depth++
+		} else if data[i] == '{' {
+			if depth == 0 {
+				start = i
+				break
+			}
+			depth--
+		}
+	}
+	if start < 0 {
+		return -1, -1
+	}
+	depth = 0
+	for i := start; i < len(data); i++ {
+		switch data[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return start, i + 1
+			}
+		}
+	}
+	return -1, -1
+}
+
+// rushPrePublish runs pre-publish for every Rush project. Lock-step projects
+// take the release version, which is also written to their policies;
+// individually versioned projects keep theirs, within any lockedMajor.
+func (p *NpmPlugin) rushPrePublish(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
+	scope := workspaceScope{prepare: func(pkg workspacePackage, pkgCfg *Config) error {
+		if cfg.rush.lockStep(pkg.Name) {
+			return nil
+		}
+		pkgCfg.UpdateVersion = false
+		return cfg.rush.checkLockedMajor(pkg.Name, pkg.Version)
+	}}
+	resp, err := p.runWorkspaces(ctx, cfg, releaseCtx, dryRun, scope, p.prePublish)
+	if err != nil || !resp.Success {
+		return resp, err
+	}
+	lockStep := false
+	for _, policy := range cfg.rush.policies {
+		lockStep = lockStep || policy.DefinitionName == rushLockStep
+	}
+	if lockStep && cfg.UpdateVersion && !dryRun {
+		if err := writeRushPolicyVersions(cfg.rush, releaseCtx.Version); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+	}
+	return resp, nil
+}
+
+// rushPublishScope publishes like `rush publish`: each project at its
+// package.json version unless already published, within any lockedMajor.
+func rushPublishScope(cfg *Config, changes *releaseChanges) workspaceScope {
+	return workspaceScope{
+		changes:     changes,
+		fromPackage: true,
+		prepare: func(pkg workspacePackage, _ *Config) error {
+			return cfg.rush.checkLockedMajor(pkg.Name, pkg.Version)
+		},
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+const testRushJSON = `// rush.json
+{
+  "$schema": "https://developer.microsoft.com/json-schemas/rush/v5/rush.schema.json",
+  /* projects */
+  "projects": [
+    {"packageName": "core", "projectFolder": "libs/core", "versionPolicyName": "main"},
+    {"packageName": "ui", "projectFolder": "libs/ui", "versionPolicyName": "main"},
+    {"packageName": "cli", "projectFolder": "tools/cli", "versionPolicyName": "tools"},
+    {"packageName": "util", "projectFolder": "libs/util", "shouldPublish": true},
+    {"packageName": "site", "projectFolder": "apps/site"}
+  ]
+}
+`
+
+const testRushPolicies = `[
+  // Libraries release together.
+  {
+    "definitionName": "lockStepVersion",
+    "policyName": "main",
+    "version": "1.0.0",
+    "nextBump": "minor"
+  },
+  {"definitionName": "individualVersion", "policyName": "tools", "lockedMajor": 2}
+]
+`
+
+func writeRushRepo(t *testing.T, dir string) {
+	t.Helper()
+	writeFile(t, filepath.Join(dir, "rush.json"), testRushJSON)
+	writeFile(t, filepath.Join(dir, rushVersionPoliciesFile), testRushPolicies)
+	writeFile(t, filepath.Join(dir, "libs", "core", "package.json"), `{"name":"core","version":"1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "libs", "ui", "package.json"), `{"name":"ui","version":"1.0.0","dependencies":{"core":"1.0.0"}}`)
+	writeFile(t, filepath.Join(dir, "libs", "util", "package.json"), `{"name":"util","version":"0.3.0"}`)
+	writeFile(t, filepath.Join(dir, "tools", "cli", "package.json"), `{"name":"cli","version":"2.4.0","dependencies":{"ui":"1.0.0"}}`)
+	writeFile(t, filepath.Join(dir, "apps", "site", "package.json"), `{"name":"site","version":"0.0.1"}`)
+}
+
+func TestStripJSONComments(t *testing.T) {
+	in := "{\n  // line\n  \"url\": \"https://example.com/*x*/\", /* block */ \"a\": \"\\\"//\"\n}"
+	want := "{\n  \n  \"url\": \"https://example.com/*x*/\",  \"a\": \"\\\"//\"\n}"
+	if got := string(stripJSONComments([]byte(in))); got != want {
+		t.Errorf("stripJSONComments() = %q, want %q", got, want)
+	}
+}
+
+func TestApplyRushConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeRushRepo(t, dir)
+	chdir(t, dir)
+
+	cfg := Config{}
+	if err := applyRushConfig(&cfg); err != nil {
+		t.Fatalf("applyRushConfig() error = %v", err)
+	}
+	if want := []string{"libs/core", "libs/ui", "tools/cli", "libs/util"}; !reflect.DeepEqual(cfg.Workspaces, want) {
+		t.Errorf("workspaces = %v, want %v", cfg.Workspaces, want)
+	}
+	if !cfg.rush.lockStep("ui") || cfg.rush.lockStep("cli") || cfg.rush.lockStep("util") {
+		t.Error("lockStep() does not follow the version policies")
+	}
+	if err := cfg.rush.checkLockedMajor("cli", "3.0.0"); err == nil {
+		t.Error("checkLockedMajor() accepted a version outside lockedMajor")
+	}
+
+	writeFile(t, filepath.Join(dir, "rush.json"), `{"projects":[{"packageName":"x","projectFolder":"x","versionPolicyName":"missing"}]}`)
+	if err := applyRushConfig(&Config{}); err == nil {
+		t.Error("applyRushConfig() accepted an unknown version policy")
+	}
+	writeFile(t, filepath.Join(dir, "rush.json"), `{"projects":[{"packageName":"x","projectFolder":"x"}]}`)
+	if err := applyRushConfig(&Config{}); err == nil {
+		t.Error("applyRushConfig() accepted a repo with nothing to publish")
+	}
+}
+
+func TestRushPrePublish(t *testing.T) {
+	dir := t.TempDir()
+	writeRushRepo(t, dir)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPrePublish,
+		Config:  map[string]any{"rush": true},
+		Context: plugin.ReleaseContext{Version: "1.1.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	versions := map[string]string{"libs/core": "1.1.0", "libs/ui": "1.1.0", "tools/cli": "2.4.0", "libs/util": "0.3.0"}
+	for pkgDir, want := range versions {
+		if pkg, _ := readPackageJSON(filepath.Join(dir, pkgDir)); pkg.Version != want {
+			t.Errorf("%s version = %s, want %s", pkgDir, pkg.Version, want)
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, rushVersionPoliciesFile))
+	if want := strings.Replace(testRushPolicies, `"1.0.0"`, `"1.1.0"`, 1); string(data) != want {
+		t.Errorf("version-policies.json = %s", data)
+	}
+}
+
+func TestRushPrePublishLockedMajor(t *testing.T) {
+	dir := t.TempDir()
+	writeRushRepo(t, dir)
+	writeFile(t, filepath.Join(dir, "tools", "cli", "package.json"), `{"name":"cli","version":"3.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPrePublish,
+		Config:  map[string]any{"rush": true},
+		Context: plugin.ReleaseContext{Version: "1.1.0"},
+		DryRun:  true,
+	})
+	if err != nil || resp.Success || !strings.Contains(resp.Error, "lockedMajor 2") || resp.Outputs["failed_package"] != "cli" {
+		t.Fatalf("expected a lockedMajor failure, got %v %+v", err, resp)
+	}
+}
+
+func TestRushPublish(t *testing.T) {
+	logPath := fakeNpm(t, `[ "$1" = publish ] && basename "$PWD" >> "$(dirname "$0")/published.log"
+echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	server := newTestRegistry(t, map[string]*packument{
+		"util": {Versions: map[string]packumentVersion{"0.3.0": {}}},
+	})
+	dir := t.TempDir()
+	writeRushRepo(t, dir)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"rush": true, "registry": server.URL},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	published := npmCalls(t, filepath.Join(filepath.Dir(logPath), "published.log"))
+	if want := []string{"core", "ui", "cli"}; !reflect.DeepEqual(published, want) {
+		t.Errorf("published = %v, want %v", published, want)
+	}
+}
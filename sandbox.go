@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Sandbox modes.
+const (
+	sandboxAuto     = "auto"
+	sandboxRequired = "required"
+)
+
+// Sandbox tools.
+const (
+	sandboxBwrap       = "bwrap"
+	sandboxSandboxExec = "sandbox-exec"
+)
+
+// lookupSandbox returns the sandbox tool available on this platform, or ""
+// when there is none. It is a variable so tests can stub it.
+var lookupSandbox = func() string {
+	tool := sandboxBwrap
+	if runtime.GOOS == "darwin" {
+		tool = sandboxSandboxExec
+	}
+	if _, err := exec.LookPath(tool); err != nil {
+		return ""
+	}
+	return tool
+}
+
+// resolveSandbox picks the sandbox tool for cfg.Sandbox. "auto" falls back
+// to running npm directly with a warning; "required" fails instead.
+func resolveSandbox(cfg *Config) (tool, warning string, err error) {
+	if cfg.Sandbox == "" {
+		return "", "", nil
+	}
+	tool = lookupSandbox()
+	if tool != "" {
+		return tool, "", nil
+	}
+	if cfg.Sandbox == sandboxRequired {
+		return "", "", fmt.Errorf("sandbox required but no sandbox tool is available (bubblewrap on Linux, sandbox-exec on macOS)")
+	}
+	return "", "sandbox unavailable (install bubblewrap on Linux); running npm without it", nil
+}
+
+// sandboxSystemPaths are the parts of the root filesystem exposed read-only
+// inside bubblewrap: the system toolchain and libraries, and the /etc files
+// name resolution and TLS need. Missing ones are skipped.
+var sandboxSystemPaths = []string{
+	"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64",
+	"/etc/resolv.conf", "/etc/hosts", "/etc/nsswitch.conf", "/etc/passwd", "/etc/group",
+	"/etc/ssl", "/etc/pki", "/etc/ca-certificates",
+}
+
+// sandboxEnvVars are the environment variables passed into the sandbox;
+// everything else, including credentials for other services, is dropped.
+var sandboxEnvVars = []string{
+	"PATH", "HOME", "USER", "TMPDIR", "LANG", "LC_ALL", "LC_CTYPE", "TZ", "TERM", "CI",
+	"NODE_ENV", "NODE_OPTIONS", "NODE_EXTRA_CA_CERTS", "SSL_CERT_FILE", "SSL_CERT_DIR",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy",
+}
+
+// sandboxEnv returns the allowed environment as "name=value" pairs: the
+// sandboxEnvVars, npm's own npm_config_ settings and the token variables the
+// ephemeral npmrc references.
+func sandboxEnv(cfg *Config) []string {
+	names := append([]string{}, sandboxEnvVars...)
+	if cfg.EphemeralNpmrc.Enabled && cfg.EphemeralNpmrc.Token == "" {
+		names = append(names, cfg.EphemeralNpmrc.tokenEnv())
+	}
+	for _, s := range cfg.ScopeRegistries {
+		if s.TokenEnv != "" {
+			names = append(names, s.TokenEnv)
+		}
+	}
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(strings.ToLower(name), "npm_config_") {
+			names = append(names, name)
+		}
+	}
+
+	var env []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// sandboxPaths returns the paths npm may write to and the extra paths it may
+// read when running args in dir: the package directory, the npm cache, the
+// pack destination, the npmrc files passed on the command line, the CA
+// bundles named in the environment and the node and npm installs.
+func sandboxPaths(cfg *Config, dir string, args []string) (writable, readable []string) {
+	abs := func(p string) string {
+		if a, err := filepath.Abs(p); err == nil {
+			return a
+		}
+		return p
+	}
+	writable = append(writable, abs(dir), npmCacheDir())
+	for i, arg := range args {
+		if arg == "--pack-destination" && i+1 < len(args) {
+			writable = append(writable, abs(args[i+1]))
+		}
+	}
+	if wd, err := os.Getwd(); err == nil {
+		readable = append(readable, wd)
+	}
+	for _, f := range []string{cfg.UserConfig, cfg.GlobalConfig, os.Getenv("NODE_EXTRA_CA_CERTS"), os.Getenv("SSL_CERT_FILE"), os.Getenv("SSL_CERT_DIR")} {
+		if f != "" {
+			readable = append(readable, abs(f))
+		}
+	}
+	readable = append(readable, toolPrefixes()...)
+	return writable, readable
+}
+
+// npmCacheDir returns npm's cache directory.
+func npmCacheDir() string {
+	if dir := os.Getenv("npm_config_cache"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".npm")
+}
+
+// toolPrefixes returns the install prefixes of node and npm, which may live
+// under the (hidden) home directory when installed by a version manager.
+func toolPrefixes() []string {
+	var prefixes []string
+	for _, tool := range []string{"node", "npm"} {
+		path, err := exec.LookPath(tool)
+		if err != nil {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		prefixes = append(prefixes, filepath.Dir(filepath.Dir(path)))
+	}
+	return prefixes
+}
+
+// sandboxCommand returns the command line running npm args in dir under
+// tool, with only the sandboxEnv environment. Under bubblewrap only the
+// system toolchain and the sandboxPaths are mounted, with empty home and
+// temporary directories; sandbox-exec, which cannot hide the root
+// filesystem, denies writes and reads of the home and state directories
+// instead.
+func sandboxCommand(cfg *Config, tool, dir string, args []string) []string {
+	writable, readable := sandboxPaths(cfg, dir, args)
+	home, _ := os.UserHomeDir()
+	env := sandboxEnv(cfg)
+	npm := append([]string{"npm"}, args...)
+
+	if tool == sandboxSandboxExec {
+		var profile strings.Builder
+		profile.WriteString("(version 1)(allow default)(deny file-write*)")
+		fmt.Fprintf(&profile, "(deny file-read* (subpath %q))", home)
+		fmt.Fprintf(&profile, "(deny file-read* file-write* (subpath %q))", filepath.Dir(stateDir(cfg)))
+		profile.WriteString("(allow file-write* (subpath \"/dev\"))")
+		for _, p := range append(writable, readable...) {
+			fmt.Fprintf(&profile, "(allow file-read* (subpath %q))", p)
+		}
+		for _, p := range writable {
+			fmt.Fprintf(&profile, "(allow file-write* (subpath %q))", p)
+		}
+		argv := append([]string{sandboxSandboxExec, "-p", profile.String(), "env", "-i"}, env...)
+		return append(argv, npm...)
+	}
+
+	argv := []string{sandboxBwrap, "--die-with-parent", "--clearenv"}
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		argv = append(argv, "--setenv", name, value)
+	}
+	for _, p := range sandboxSystemPaths {
+		argv = append(argv, "--ro-bind-try", p, p)
+	}
+	argv = append(argv, "--dev", "/dev", "--proc", "/proc", "--tmpfs", "/tmp")
+	if tmp := os.TempDir(); tmp != "/tmp" {
+		argv = append(argv, "--tmpfs", tmp)
+	}
+	if home != "" {
+		argv = append(argv, "--tmpfs", home)
+	}
+	for _, p := range readable {
+		argv = append(argv, "--ro-bind-try", p, p)
+	}
+	for _, p := range writable {
+		argv = append(argv, "--bind-try", p, p)
+	}
+	argv = append(argv, "--chdir", dir)
+	return append(argv, npm...)
+}
+
+// runScriptedNpm runs an npm command that may execute lifecycle scripts
+// (pack and publish), inside the sandbox when one is configured.
+func runScriptedNpm(ctx context.Context, cfg *Config, dir string, args ...string) (string, error) {
+	if cfg.sandbox == "" {
+		return runNpm(ctx, dir, args...)
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	argv := sandboxCommand(cfg, cfg.sandbox, dir, args)
+	return runCommand(ctx, dir, argv[0], argv[1:], args[0])
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func stubSandbox(t *testing.T, tool string) {
+	t.Helper()
+	orig := lookupSandbox
+	lookupSandbox = func() string { return tool }
+	t.Cleanup(func() { lookupSandbox = orig })
+}
+
+func TestResolveSandbox(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		available   string
+		wantTool    string
+		wantWarning bool
+		wantErr     bool
+	}{
+		{"disabled", "", sandboxBwrap, "", false, false},
+		{"auto available", sandboxAuto, sandboxBwrap, sandboxBwrap, false, false},
+		{"auto unavailable", sandboxAuto, "", "", true, false},
+		{"required available", sandboxRequired, sandboxSandboxExec, sandboxSandboxExec, false, false},
+		{"required unavailable", sandboxRequired, "", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubSandbox(t, tt.available)
+			tool, warning, err := resolveSandbox(&Config{Sandbox: tt.mode})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveSandbox() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tool != tt.wantTool {
+				t.Errorf("tool = %q, want %q", tool, tt.wantTool)
+			}
+			if (warning != "") != tt.wantWarning {
+				t.Errorf("warning = %q, wantWarning %v", warning, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestSandboxCommand(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("npm_config_cache", filepath.Join(home, ".npm"))
+	t.Setenv("NPM_TOKEN", "npm-secret")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "aws-secret")
+	dir := t.TempDir()
+	cfg := &Config{UserConfig: "/etc/ci.npmrc", EphemeralNpmrc: EphemeralNpmrc{Enabled: true}}
+
+	t.Run("bwrap", func(t *testing.T) {
+		argv := strings.Join(sandboxCommand(cfg, sandboxBwrap, dir, []string{"pack", "--pack-destination", "/srv/artifacts"}), " ")
+		if strings.Contains(argv, "--ro-bind / /") || strings.Contains(argv, "aws-secret") {
+			t.Errorf("command exposes the root filesystem or environment:\n%s", argv)
+		}
+		for _, want := range []string{
+			"bwrap --die-with-parent --clearenv ",
+			"--setenv HOME " + home + " ",
+			"--setenv NPM_TOKEN npm-secret ",
+			"--ro-bind-try /usr /usr ",
+			"--tmpfs " + home,
+			"--bind-try " + dir + " " + dir,
+			"--bind-try " + filepath.Join(home, ".npm") + " ",
+			"--bind-try /srv/artifacts /srv/artifacts",
+			"--ro-bind-try /etc/ci.npmrc /etc/ci.npmrc",
+			"--chdir " + dir + " npm pack --pack-destination /srv/artifacts",
+		} {
+			if !strings.Contains(argv, want) {
+				t.Errorf("command missing %q:\n%s", want, argv)
+			}
+		}
+	})
+
+	t.Run("sandbox-exec", func(t *testing.T) {
+		argv := sandboxCommand(cfg, sandboxSandboxExec, dir, []string{"publish"})
+		if argv[0] != sandboxSandboxExec || argv[1] != "-p" || argv[3] != "env" || argv[4] != "-i" || strings.Join(argv[len(argv)-2:], " ") != "npm publish" {
+			t.Fatalf("unexpected command: %q", argv)
+		}
+		env := strings.Join(argv[5:len(argv)-2], " ")
+		if !strings.Contains(env, "NPM_TOKEN=npm-secret") || strings.Contains(env, "aws-secret") {
+			t.Errorf("environment not limited to the allowlist: %s", env)
+		}
+		for _, want := range []string{
+			"(deny file-write*)",
+			`(deny file-read* (subpath "` + home + `"))`,
+			`(allow file-write* (subpath "` + dir + `"))`,
+			`(allow file-read* (subpath "/etc/ci.npmrc"))`,
+		} {
+			if !strings.Contains(argv[2], want) {
+				t.Errorf("profile missing %q:\n%s", want, argv[2])
+			}
+		}
+	})
+}
+
+func TestRunScriptedNpmSandboxed(t *testing.T) {
+	// A fake bwrap logs its arguments and runs the wrapped npm command.
+	logPath := fakeNpm(t, `echo '{"ok":true}'`)
+	binDir := filepath.Dir(logPath)
+	wrapper := "#!/bin/sh\necho \"$@\" >> " + filepath.Join(binDir, "bwrap.log") + "\nwhile [ \"$1\" != npm ]; do shift; done\nexec \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(binDir, "bwrap"), []byte(wrapper), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	cfg := &Config{sandbox: sandboxBwrap}
+	out, err := runScriptedNpm(context.Background(), cfg, dir, "publish", "--json")
+	if err != nil {
+		t.Fatalf("runScriptedNpm() error = %v", err)
+	}
+	if strings.TrimSpace(out) != `{"ok":true}` {
+		t.Errorf("output = %q", out)
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 1 || calls[0] != "publish --json" {
+		t.Errorf("npm calls = %q", calls)
+	}
+	data, err := os.ReadFile(filepath.Join(binDir, "bwrap.log"))
+	if err != nil || !strings.Contains(string(data), "--chdir "+dir) {
+		t.Errorf("bwrap not invoked with package dir: %q (%v)", data, err)
+	}
+}
+
+func TestPublishSandboxRequired(t *testing.T) {
+	stubSandbox(t, "")
+	logPath := fakeNpm(t, "")
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"1.0.0"}`)
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	p := &NpmPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"sandbox": sandboxRequired},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "sandbox required") {
+		t.Errorf("expected sandbox failure, got %+v", resp)
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 0 {
+		t.Errorf("npm should not run, got %q", calls)
+	}
+}
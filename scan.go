@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// scanExtensions are the packed file types checked by the code scan.
+var scanExtensions = map[string]bool{".js": true, ".mjs": true, ".cjs": true, ".jsx": true}
+
+// debuggerRegexp matches a debugger statement rather than the word in prose.
+var debuggerRegexp = regexp.MustCompile(`(?:^|[;{}\s])debugger\s*(?:;|}|$)`)
+
+// maxScanLine bounds line length so minified bundles can still be scanned.
+const maxScanLine = 16 * 1024 * 1024
+
+// compileBannedPatterns compiles the configured banned_patterns.
+func compileBannedPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid banned pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// scanPackedCode checks the packed JavaScript files for debugger statements
+// and banned patterns, returning findings as "path:line: description".
+func scanPackedCode(packageDir string, files []packFile, banned []*regexp.Regexp) ([]string, error) {
+	var findings []string
+	for _, f := range files {
+		if !scanExtensions[strings.ToLower(filepath.Ext(f.Path))] {
+			continue
+		}
+		found, err := scanFile(packageDir, f.Path, banned)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, found...)
+	}
+	return findings, nil
+}
+
+// scanFile scans a single packed file line by line.
+func scanFile(packageDir, path string, banned []*regexp.Regexp) ([]string, error) {
+	file, err := os.Open(filepath.Join(packageDir, filepath.FromSlash(path)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var findings []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxScanLine)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if debuggerRegexp.MatchString(text) {
+			findings = append(findings, fmt.Sprintf("%s:%d: debugger statement", path, line))
+		}
+		for _, re := range banned {
+			if re.MatchString(text) {
+				findings = append(findings, fmt.Sprintf("%s:%d: matches banned pattern %q", path, line, re.String()))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", path, err)
+	}
+	return findings, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestScanPackedCode(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.js":     "export function f() {\n  debugger;\n  return 1\n}\n",
+		"lib/util.mjs": "// the debugger keyword is fine in comments\nconsole.log('x')\n",
+		"min.js":       "function a(){debugger}",
+		"README.md":    "debugger;\n",
+	}
+	var packed []packFile
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		packed = append(packed, packFile{Path: name})
+	}
+
+	banned, err := compileBannedPatterns([]string{`console\.log\(`})
+	if err != nil {
+		t.Fatalf("compileBannedPatterns returned error: %v", err)
+	}
+	findings, err := scanPackedCode(dir, packed, banned)
+	if err != nil {
+		t.Fatalf("scanPackedCode returned error: %v", err)
+	}
+
+	got := strings.Join(findings, "\n")
+	for _, want := range []string{"index.js:2: debugger statement", "min.js:1: debugger statement", `lib/util.mjs:2: matches banned pattern`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("findings missing %q:\n%s", want, got)
+		}
+	}
+	if len(findings) != 3 {
+		t.Errorf("expected 3 findings, got %d:\n%s", len(findings), got)
+	}
+
+	if _, err := compileBannedPatterns([]string{"("}); err == nil {
+		t.Error("expected invalid pattern to fail")
+	}
+}
+
+func TestPublishCodeScan(t *testing.T) {
+	p := &NpmPlugin{}
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name":"pkg","version":"1.0.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "index.js"), []byte("debugger;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, tmpDir)
+
+	fakeNpm(t, `echo '[{"name":"pkg","version":"1.0.0","files":[{"path":"index.js"},{"path":"package.json"}]}]'`)
+
+	resp, err := p.publishPackage(ctx, &Config{Tag: "latest", CodeScan: "fail"}, plugin.ReleaseContext{Version: "1.0.0"}, true)
+	if err != nil {
+		t.Fatalf("publishPackage returned error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "index.js:1: debugger statement") {
+		t.Errorf("expected code scan failure, got %+v", resp)
+	}
+
+	resp, _ = p.publishPackage(ctx, &Config{Tag: "latest", CodeScan: "warn"}, plugin.ReleaseContext{Version: "1.0.0"}, true)
+	if !resp.Success {
+		t.Fatalf("expected success with warnings, got error: %s", resp.Error)
+	}
+	if warnings, _ := resp.Outputs["warnings"].([]string); len(warnings) != 1 {
+		t.Errorf("expected one warning, got %v", resp.Outputs["warnings"])
+	}
+}
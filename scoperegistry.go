@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// scopePattern validates npm scope names.
+var scopePattern = regexp.MustCompile(`^@[a-z0-9~-][a-z0-9._~-]*$`)
+
+// ScopeRegistry routes the packages of one scope to their own registry, e.g.
+// @mycorp to GitHub Packages while everything else goes to npmjs.
+type ScopeRegistry struct {
+	// Scope is the npm scope, including the "@".
+	Scope    string `json:"scope"`
+	Registry string `json:"registry"`
+	// TokenEnv names the variable holding the token ephemeral_npmrc writes
+	// for this registry; it defaults to ephemeral_npmrc's own.
+	TokenEnv string `json:"token_env,omitempty"`
+}
+
+// validateScopeRegistries checks the scope names and registry URLs. Like
+// regions, scope routing picks the publish registry, so it cannot be
+// combined with a registry preset, which maps a scope itself.
+func validateScopeRegistries(cfg *Config) error {
+	if len(cfg.ScopeRegistries) == 0 {
+		return nil
+	}
+	if cfg.RegistryPreset != "" {
+		return fmt.Errorf("cannot be combined with registry_preset")
+	}
+	seen := map[string]bool{}
+	for _, s := range cfg.ScopeRegistries {
+		if !scopePattern.MatchString(s.Scope) {
+			return fmt.Errorf("invalid scope %q, want a lowercase npm scope such as @mycorp", s.Scope)
+		}
+		if seen[s.Scope] {
+			return fmt.Errorf("scope %s is listed twice", s.Scope)
+		}
+		seen[s.Scope] = true
+		if s.Registry == "" {
+			return fmt.Errorf("scope %s needs a registry URL", s.Scope)
+		}
+		if err := validateEndpointURL(s.Registry, "scope registry"); err != nil {
+			return err
+		}
+		if err := checkRegistryPolicy(s.Registry, cfg.AllowedRegistries); err != nil {
+			return err
+		}
+		if s.TokenEnv != "" {
+			if !inputNamePattern.MatchString(s.TokenEnv) {
+				return fmt.Errorf("token_env %q is not a valid environment variable name", s.TokenEnv)
+			}
+			if !cfg.EphemeralNpmrc.Enabled {
+				return fmt.Errorf("token_env requires ephemeral_npmrc")
+			}
+		}
+	}
+	return nil
+}
+
+// packageScope returns the scope of a package name, "" when unscoped.
+func packageScope(name string) string {
+	if scope, _, ok := strings.Cut(name, "/"); ok && strings.HasPrefix(scope, "@") {
+		return scope
+	}
+	return ""
+}
+
+// scopedConfig returns cfg routed to the registry of name's scope. The
+// routed config is a copy, so routing one workspace package leaves the
+// others alone. Regional replicas mirror the default registry and do not
+// apply to routed packages.
+func scopedConfig(cfg *Config, name string) *Config {
+	scope := packageScope(name)
+	if scope == "" {
+		return cfg
+	}
+	for _, s := range cfg.ScopeRegistries {
+		if s.Scope != scope {
+			continue
+		}
+		routed := *cfg
+		routed.Registry = s.Registry
+		routed.PublishURL = ""
+		routed.RegionalRegistries = nil
+		if s.TokenEnv != "" {
+			routed.EphemeralNpmrc.TokenEnv = s.TokenEnv
+			routed.EphemeralNpmrc.Token = ""
+		}
+		return &routed
+	}
+	return cfg
+}
+
+// scopeRegistries returns the scope to registry mappings passed to npm:
+// the configured scopes and the registry preset's scope.
+func scopeRegistries(cfg *Config) []ScopeRegistry {
+	scopes := append([]ScopeRegistry{}, cfg.ScopeRegistries...)
+	if cfg.presetScope != "" {
+		scopes = append(scopes, ScopeRegistry{Scope: cfg.presetScope, Registry: registryURL(cfg)})
+	}
+	sort.Slice(scopes, func(i, j int) bool { return scopes[i].Scope < scopes[j].Scope })
+	return scopes
+}
+
+// scopeRegistryArgs maps scopes to their registries, so scoped packages
+// resolve and publish there regardless of the user's npmrc.
+func scopeRegistryArgs(cfg *Config) []string {
+	var args []string
+	for _, s := range scopeRegistries(cfg) {
+		args = append(args, fmt.Sprintf("--%s:registry=%s", s.Scope, s.Registry))
+	}
+	return args
+}
+
+// sameRegistry reports whether two registry URLs differ at most in a
+// trailing slash.
+func sameRegistry(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
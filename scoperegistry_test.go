@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateScopeRegistries(t *testing.T) {
+	github := "https://npm.pkg.github.com/"
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "scope", cfg: Config{ScopeRegistries: []ScopeRegistry{{Scope: "@mycorp", Registry: github}}}},
+		{
+			name: "token env",
+			cfg: Config{
+				ScopeRegistries: []ScopeRegistry{{Scope: "@mycorp", Registry: github, TokenEnv: "GITHUB_TOKEN"}},
+				EphemeralNpmrc:  EphemeralNpmrc{Enabled: true},
+			},
+		},
+		{name: "token env without npmrc", cfg: Config{ScopeRegistries: []ScopeRegistry{{Scope: "@mycorp", Registry: github, TokenEnv: "GITHUB_TOKEN"}}}, wantErr: true},
+		{name: "missing at", cfg: Config{ScopeRegistries: []ScopeRegistry{{Scope: "mycorp", Registry: github}}}, wantErr: true},
+		{name: "uppercase", cfg: Config{ScopeRegistries: []ScopeRegistry{{Scope: "@MyCorp", Registry: github}}}, wantErr: true},
+		{name: "with package", cfg: Config{ScopeRegistries: []ScopeRegistry{{Scope: "@mycorp/lib", Registry: github}}}, wantErr: true},
+		{name: "duplicate", cfg: Config{ScopeRegistries: []ScopeRegistry{{Scope: "@mycorp", Registry: github}, {Scope: "@mycorp", Registry: github}}}, wantErr: true},
+		{name: "missing registry", cfg: Config{ScopeRegistries: []ScopeRegistry{{Scope: "@mycorp"}}}, wantErr: true},
+		{name: "plain http", cfg: Config{ScopeRegistries: []ScopeRegistry{{Scope: "@mycorp", Registry: "http://npm.example.com/"}}}, wantErr: true},
+		{
+			name: "not allowed",
+			cfg: Config{
+				ScopeRegistries:   []ScopeRegistry{{Scope: "@mycorp", Registry: github}},
+				AllowedRegistries: []string{"registry.npmjs.org"},
+			},
+			wantErr: true,
+		},
+		{name: "with preset", cfg: Config{ScopeRegistries: []ScopeRegistry{{Scope: "@mycorp", Registry: github}}, RegistryPreset: "github"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateScopeRegistries(&tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateScopeRegistries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateScopeRegistriesIndependently(t *testing.T) {
+	fields := func(config map[string]any) []string {
+		t.Helper()
+		resp, err := (&NpmPlugin{}).Validate(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields []string
+		for _, e := range resp.Errors {
+			fields = append(fields, e.Field)
+		}
+		return fields
+	}
+
+	got := fields(map[string]any{
+		"ephemeral_npmrc":  map[string]any{"enabled": "yes"},
+		"scope_registries": []any{map[string]any{"scope": "mycorp", "registry": "https://npm.pkg.github.com/"}},
+	})
+	if !containsString(got, "ephemeral_npmrc") || !containsString(got, "scope_registries") {
+		t.Errorf("errors on %v, want both ephemeral_npmrc and scope_registries", got)
+	}
+
+	got = fields(map[string]any{
+		"ephemeral_npmrc":  map[string]any{"enabled": "yes"},
+		"scope_registries": []any{map[string]any{"scope": "@mycorp", "registry": "https://npm.pkg.github.com/", "token_env": "GITHUB_TOKEN"}},
+	})
+	if containsString(got, "scope_registries") {
+		t.Errorf("errors on %v, want token_env left to the ephemeral_npmrc error", got)
+	}
+}
+
+func TestScopedConfig(t *testing.T) {
+	cfg := &Config{
+		Registry:           "https://registry.npmjs.org/",
+		PublishURL:         "https://registry.npmjs.org/publish/",
+		RegionalRegistries: map[string]string{"eu": "https://npm-eu.example.com/"},
+		EphemeralNpmrc:     EphemeralNpmrc{Enabled: true},
+		ScopeRegistries:    []ScopeRegistry{{Scope: "@mycorp", Registry: "https://npm.pkg.github.com/", TokenEnv: "GITHUB_TOKEN"}},
+	}
+	for _, name := range []string{"lib", "@other/lib"} {
+		if got := scopedConfig(cfg, name); got != cfg {
+			t.Errorf("scopedConfig(%q) routed an unmapped package", name)
+		}
+	}
+
+	got := scopedConfig(cfg, "@mycorp/lib")
+	if got == cfg {
+		t.Fatal("scopedConfig() did not route @mycorp/lib")
+	}
+	if publishRegistry(got) != "https://npm.pkg.github.com/" || registryURL(got) != "https://npm.pkg.github.com/" {
+		t.Errorf("routed registries = %q, %q", publishRegistry(got), registryURL(got))
+	}
+	if got.RegionalRegistries != nil {
+		t.Error("regional registries kept for a routed package")
+	}
+	if got.EphemeralNpmrc.tokenEnv() != "GITHUB_TOKEN" {
+		t.Errorf("token env = %q", got.EphemeralNpmrc.tokenEnv())
+	}
+	if cfg.Registry != "https://registry.npmjs.org/" || cfg.EphemeralNpmrc.tokenEnv() != "NPM_TOKEN" {
+		t.Error("scopedConfig() modified the original config")
+	}
+}
+
+func TestScopeRegistryArgs(t *testing.T) {
+	cfg := &Config{
+		Registry:    "https://npm.pkg.github.com/",
+		presetScope: "@owner",
+		ScopeRegistries: []ScopeRegistry{
+			{Scope: "@zeta", Registry: "https://npm.zeta.example.com/"},
+			{Scope: "@alpha", Registry: "https://npm.alpha.example.com/"},
+		},
+	}
+	want := "--@alpha:registry=https://npm.alpha.example.com/ --@owner:registry=https://npm.pkg.github.com/ --@zeta:registry=https://npm.zeta.example.com/"
+	if got := strings.Join(scopeRegistryArgs(cfg), " "); got != want {
+		t.Errorf("scopeRegistryArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestEphemeralNpmrcScopeRegistries(t *testing.T) {
+	cfg := &Config{
+		EphemeralNpmrc: EphemeralNpmrc{Enabled: true},
+		ScopeRegistries: []ScopeRegistry{
+			{Scope: "@mycorp", Registry: "https://npm.pkg.github.com/", TokenEnv: "GITHUB_TOKEN"},
+			{Scope: "@internal", Registry: "https://npm.example.com/"},
+		},
+	}
+	want := "@internal:registry=https://npm.example.com/\n" +
+		"@mycorp:registry=https://npm.pkg.github.com/\n" +
+		"//registry.npmjs.org/:_authToken=${NPM_TOKEN}\n" +
+		"//npm.pkg.github.com/:_authToken=${GITHUB_TOKEN}\n" +
+		"//npm.example.com/:_authToken=${NPM_TOKEN}\n"
+	if got := ephemeralNpmrcContent(cfg); got != want {
+		t.Errorf("ephemeralNpmrcContent() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestScopeRegistryPublish(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"@mycorp/lib","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"registry":         "https://registry.npmjs.org/",
+			"scope_registries": []any{map[string]any{"scope": "@mycorp", "registry": "https://npm.pkg.github.com/"}},
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if resp.Outputs["scope_registry"] != "https://npm.pkg.github.com/" {
+		t.Errorf("scope_registry = %v", resp.Outputs["scope_registry"])
+	}
+	var publish string
+	for _, call := range npmCalls(t, logPath) {
+		if strings.HasPrefix(call, "publish") {
+			publish = call
+		}
+	}
+	if !strings.Contains(publish, "--registry https://npm.pkg.github.com/") || strings.Contains(publish, "registry.npmjs.org") {
+		t.Errorf("publish call = %q", publish)
+	}
+	if !strings.Contains(publish, "--@mycorp:registry=https://npm.pkg.github.com/") {
+		t.Errorf("publish call lacks the scope mapping: %q", publish)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// scriptBannerRegexp matches the "> name@version script" line npm prints
+// before running a lifecycle script.
+var scriptBannerRegexp = regexp.MustCompile(`^> \S+@\S+ ([\w:.-]+)$`)
+
+// maxScriptOutputLines bounds the script output attached to errors.
+const maxScriptOutputLines = 40
+
+// scriptFailure finds the lifecycle script that failed an npm command and the
+// output it produced. Scripts write stdout ahead of npm's --json error object
+// and stderr after npm's "> name@version script" banner; npm's own
+// "npm error" lines are dropped. It returns empty strings when no script ran.
+func scriptFailure(stdout, stderr string) (script, output string) {
+	errLines := strings.Split(strings.ReplaceAll(stderr, "\r\n", "\n"), "\n")
+	start := -1
+	for i, line := range errLines {
+		if m := scriptBannerRegexp.FindStringSubmatch(line); m != nil {
+			script, start = m[1], i+1
+		}
+	}
+	if script == "" {
+		return "", ""
+	}
+
+	var lines []string
+	for _, line := range strings.Split(stripJSONError(stdout), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	for _, line := range errLines[start:] {
+		if strings.HasPrefix(line, "npm error") || strings.HasPrefix(line, "npm ERR!") {
+			break
+		}
+		if strings.HasPrefix(line, "> ") || strings.TrimSpace(line) == "" {
+			continue // the command echo following the banner
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) > maxScriptOutputLines {
+		lines = append([]string{"..."}, lines[len(lines)-maxScriptOutputLines:]...)
+	}
+	return script, strings.Join(lines, "\n")
+}
+
+// stripJSONError removes the trailing JSON object npm --json prints on
+// failure, leaving any script output that preceded it.
+func stripJSONError(stdout string) string {
+	if i := strings.LastIndex(stdout, "\n{\n"); i >= 0 {
+		return stdout[:i]
+	}
+	if strings.HasPrefix(stdout, "{\n") {
+		return ""
+	}
+	return stdout
+}
+
+// scriptFailureOutputs exposes a failed lifecycle script as outputs, or
+// returns nil when err was not caused by one.
+func scriptFailureOutputs(err error) map[string]any {
+	var npmErr *npmError
+	if !errors.As(err, &npmErr) || npmErr.Script == "" {
+		return nil
+	}
+	return map[string]any{
+		"failed_script": npmErr.Script,
+		"script_output": npmErr.ScriptOutput,
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestScriptFailure(t *testing.T) {
+	stdout := "building\n{\n  \"error\": {\n    \"code\": 3\n  }\n}\n"
+	stderr := "\n> x@1.0.0 prepublishOnly\n> tsc && exit 3\n\nsrc/a.ts(1,7): error TS2322\nnpm error code 3\nnpm error command failed\n"
+
+	script, output := scriptFailure(stdout, stderr)
+	if script != "prepublishOnly" {
+		t.Errorf("script = %q, want prepublishOnly", script)
+	}
+	if output != "building\nsrc/a.ts(1,7): error TS2322" {
+		t.Errorf("output = %q", output)
+	}
+
+	if script, _ := scriptFailure("", "npm error code E403\nnpm error 403 Forbidden\n"); script != "" {
+		t.Errorf("expected no script for registry errors, got %q", script)
+	}
+
+	var long strings.Builder
+	for i := 0; i < 100; i++ {
+		long.WriteString("line\n")
+	}
+	_, output = scriptFailure(long.String(), "> x@1.0.0 prepack\n> build\n")
+	if n := strings.Count(output, "\n") + 1; n != maxScriptOutputLines+1 {
+		t.Errorf("expected output truncated to %d lines, got %d", maxScriptOutputLines+1, n)
+	}
+}
+
+func TestPublishScriptFailureOutput(t *testing.T) {
+	if _, err := exec.LookPath("npm"); err != nil {
+		t.Skip("npm not available")
+	}
+	t.Setenv("npm_config_cache", filepath.Join(t.TempDir(), "cache"))
+
+	p := &NpmPlugin{}
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "package.json"), `{"name":"x","version":"1.0.0","scripts":{"prepublishOnly":"echo compiling; echo TS2322 type error >&2; exit 3"}}`)
+	chdir(t, tmpDir)
+
+	// A real (non dry-run) publish against an unreachable registry: the
+	// script fails before any network access.
+	cfg := &Config{Tag: "latest", Registry: "http://127.0.0.1:9/"}
+	resp, err := p.publishPackage(context.Background(), cfg, plugin.ReleaseContext{Version: "1.0.0"}, false)
+	if err != nil {
+		t.Fatalf("publishPackage returned error: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("expected failure")
+	}
+	if !strings.Contains(resp.Error, `lifecycle script "prepublishOnly" failed`) || !strings.Contains(resp.Error, "TS2322 type error") {
+		t.Errorf("error lacks script output: %s", resp.Error)
+	}
+	if resp.Outputs["failed_script"] != "prepublishOnly" {
+		t.Errorf("failed_script = %v", resp.Outputs["failed_script"])
+	}
+}
+
+func TestNpmErrorUnwrap(t *testing.T) {
+	inner := errors.New("exit status 1")
+	err := newNpmError("publish", inner, "", "npm error E404\n")
+	if !errors.Is(err, inner) {
+		t.Error("expected npmError to unwrap to the exec error")
+	}
+	if !strings.HasPrefix(err.Error(), "npm publish failed: exit status 1\nstderr: npm error E404") {
+		t.Errorf("unexpected message: %q", err.Error())
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// semverRegexp parses a semantic version with optional "v" prefix.
+var semverRegexp = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+
+// semver is a parsed semantic version.
+type semver struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+}
+
+// parseSemver parses a semantic version string.
+func parseSemver(s string) (semver, error) {
+	m := semverRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return semver{}, fmt.Errorf("invalid semantic version %q", s)
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	patch, _ := strconv.Atoi(m[3])
+	return semver{Major: major, Minor: minor, Patch: patch, Prerelease: m[4], Build: m[5]}, nil
+}
+
+// String formats the version without a "v" prefix.
+func (v semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// IsPrerelease reports whether the version has a prerelease component.
+func (v semver) IsPrerelease() bool {
+	return v.Prerelease != ""
+}
+
+// Compare returns -1, 0 or 1 following semver precedence (build metadata ignored).
+func (v semver) Compare(o semver) int {
+	for _, d := range [3][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if d[0] != d[1] {
+			if d[0] < d[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// comparePrerelease compares prerelease strings; a release sorts after any prerelease.
+func comparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+
+	ap, bp := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(ap) && i < len(bp); i++ {
+		ai, aErr := strconv.Atoi(ap[i])
+		bi, bErr := strconv.Atoi(bp[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if ai != bi {
+				if ai < bi {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(ap[i], bp[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(ap) < len(bp):
+		return -1
+	case len(ap) > len(bp):
+		return 1
+	}
+	return 0
+}
+
+// semverRange is a parsed npm-style version range: a set of alternatives
+// (joined by "||"), each a set of comparators that must all hold.
+type semverRange [][]comparator
+
+// comparator is a single "<op><version>" constraint. Implicit comparators
+// are the exclusive upper bounds derived from caret, tilde and x-ranges.
+type comparator struct {
+	op       string
+	version  semver
+	implicit bool
+}
+
+// partialRegexp matches a possibly partial version such as "1", "1.x" or "1.2.*".
+var partialRegexp = regexp.MustCompile(`^v?(\d+|[xX*])(?:\.(\d+|[xX*]))?(?:\.(\d+|[xX*]))?(?:-([0-9A-Za-z.-]+))?$`)
+
+// parseRange parses the subset of npm range syntax the plugin supports:
+// exact versions, comparators (>, >=, <, <=, =), caret, tilde, x-ranges and
+// "||" alternatives.
+func parseRange(s string) (semverRange, error) {
+	var r semverRange
+	for _, alt := range strings.Split(s, "||") {
+		var set []comparator
+		for _, part := range strings.Fields(alt) {
+			cs, err := parseComparator(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid range %q: %w", s, err)
+			}
+			set = append(set, cs...)
+		}
+		r = append(r, set)
+	}
+	return r, nil
+}
+
+// parseComparator expands a single range token into comparators.
+func parseComparator(tok string) ([]comparator, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(tok, candidate) {
+			op = candidate
+			tok = tok[len(candidate):]
+			break
+		}
+	}
+
+	m := partialRegexp.FindStringSubmatch(tok)
+	if m == nil {
+		return nil, fmt.Errorf("invalid version %q", tok)
+	}
+
+	wild := func(s string) bool { return s == "" || s == "x" || s == "X" || s == "*" }
+	num := func(s string) int { n, _ := strconv.Atoi(s); return n }
+
+	if wild(m[1]) {
+		if op == "<" || op == ">" {
+			// "<*" and ">*" match nothing
+			return []comparator{{"<", semver{Prerelease: "0"}, true}}, nil
+		}
+		return nil, nil // "*" matches everything
+	}
+	major, minor, patch := num(m[1]), num(m[2]), num(m[3])
+	lower := semver{Major: major, Minor: minor, Patch: patch, Prerelease: m[4]}
+
+	// A partial version covers a span: 1 is [1.0.0, 2.0.0), 1.2 is
+	// [1.2.0, 1.3.0). Operators apply to the span, as in node-semver.
+	if wild(m[2]) || wild(m[3]) {
+		next := semver{Major: major + 1}
+		if !wild(m[2]) {
+			next = semver{Major: major, Minor: minor + 1}
+		}
+		if op == "^" && (major != 0 || wild(m[2])) {
+			next = semver{Major: major + 1}
+		}
+		switch op {
+		case ">":
+			return []comparator{{">=", next, false}}, nil
+		case ">=":
+			return []comparator{{">=", lower, false}}, nil
+		case "<":
+			lower.Prerelease = "0"
+			return []comparator{{"<", lower, true}}, nil
+		case "<=":
+			next.Prerelease = "0"
+			return []comparator{{"<", next, true}}, nil
+		default: // "", "=", "^", "~"
+			next.Prerelease = "0"
+			return []comparator{{">=", lower, false}, {"<", next, true}}, nil
+		}
+	}
+
+	switch op {
+	case "^":
+		upper := semver{Major: major + 1}
+		if major == 0 {
+			upper = semver{Minor: minor + 1}
+			if minor == 0 {
+				upper = semver{Patch: patch + 1}
+			}
+		}
+		upper.Prerelease = "0"
+		return []comparator{{">=", lower, false}, {"<", upper, true}}, nil
+	case "~":
+		return []comparator{{">=", lower, false}, {"<", semver{Major: major, Minor: minor + 1, Prerelease: "0"}, true}}, nil
+	case "":
+		op = "="
+	}
+	return []comparator{{op, lower, false}}, nil
+}
+
+// Contains reports whether v satisfies the range. As in npm, prerelease
+// versions only match comparators on the same major.minor.patch that
+// themselves carry a prerelease.
+func (r semverRange) Contains(v semver) bool {
+	for _, set := range r {
+		if setContains(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// setContains reports whether v satisfies every comparator in set.
+func setContains(set []comparator, v semver) bool {
+	prereleaseAllowed := !v.IsPrerelease()
+	for _, c := range set {
+		var ok bool
+		switch cmp := v.Compare(c.version); c.op {
+		case "=":
+			ok = cmp == 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+		if c.version.IsPrerelease() && !c.implicit &&
+			c.version.Major == v.Major && c.version.Minor == v.Minor && c.version.Patch == v.Patch {
+			prereleaseAllowed = true
+		}
+	}
+	return prereleaseAllowed
+}
@@ -0,0 +1,171 @@
+package main
+
+import "testing"
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"1.2.3", "1.2.3", false},
+		{"v1.2.3", "1.2.3", false},
+		{"1.2.3-beta.1+build.5", "1.2.3-beta.1+build.5", false},
+		{"1.2", "", true},
+		{"latest", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			v, err := parseSemver(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSemver(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && v.String() != tt.want {
+				t.Errorf("parseSemver(%q) = %q, want %q", tt.in, v.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0.0", "2.0.0", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta.11", 1},
+		{"1.0.0+build", "1.0.0", 0},
+	}
+
+	for _, tt := range tests {
+		a, _ := parseSemver(tt.a)
+		b, _ := parseSemver(tt.b)
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSemverRange(t *testing.T) {
+	tests := []struct {
+		rng     string
+		version string
+		want    bool
+	}{
+		{"*", "1.2.3", true},
+		{"*", "1.2.3-beta.1", false},
+		{"1.x", "1.9.0", true},
+		{"1.x", "2.0.0", false},
+		{"1.2.x", "1.2.9", true},
+		{"1.2.x", "1.3.0", false},
+		{"^1.2.3", "1.9.9", true},
+		{"^1.2.3", "2.0.0", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"~1.2.3", "1.2.9", true},
+		{"~1.2.3", "1.3.0", false},
+		{">=1.0.0 <2.0.0", "1.5.0", true},
+		{">=1.0.0 <2.0.0", "2.0.0", false},
+		{"1.0.0 || 2.0.0", "2.0.0", true},
+		{"1.0.0", "1.0.1", false},
+		{">=2.0.0-0", "2.0.0-beta.1", true},
+		{">=2.0.0-0", "3.0.0-beta.1", false},
+		{">=2.0.0-beta.0", "2.0.0-beta.1", true},
+		{"^1.0.0", "2.0.0-beta.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rng+"_"+tt.version, func(t *testing.T) {
+			r, err := parseRange(tt.rng)
+			if err != nil {
+				t.Fatalf("parseRange(%q) returned error: %v", tt.rng, err)
+			}
+			v, _ := parseSemver(tt.version)
+			if got := r.Contains(v); got != tt.want {
+				t.Errorf("%q contains %q = %v, want %v", tt.rng, tt.version, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseRange(">=foo"); err == nil {
+		t.Error("expected error for invalid range")
+	}
+}
+
+func TestSemverRangePartialComparators(t *testing.T) {
+	tests := []struct {
+		rng string
+		in  []string
+		out []string
+	}{
+		// Major only: 1 spans [1.0.0, 2.0.0)
+		{"1", []string{"1.0.0", "1.9.9"}, []string{"0.9.9", "2.0.0"}},
+		{"=1", []string{"1.0.0", "1.9.9"}, []string{"0.9.9", "2.0.0"}},
+		{">1", []string{"2.0.0", "3.1.0"}, []string{"1.0.0", "1.9.9"}},
+		{">=1", []string{"1.0.0", "2.5.0"}, []string{"0.9.9"}},
+		{"<2", []string{"1.9.9", "0.1.0"}, []string{"2.0.0", "2.5.0", "2.0.0-beta.1"}},
+		{"<=1", []string{"1.9.9", "0.1.0"}, []string{"2.0.0", "2.0.0-beta.1"}},
+		{"^1", []string{"1.0.0", "1.9.9"}, []string{"0.9.9", "2.0.0"}},
+		{"~1", []string{"1.0.0", "1.9.9"}, []string{"0.9.9", "2.0.0"}},
+		// Major with x: same span as major only
+		{"1.x", []string{"1.0.0", "1.9.9"}, []string{"0.9.9", "2.0.0"}},
+		{">1.x", []string{"2.0.0"}, []string{"1.9.9"}},
+		{">=1.x", []string{"1.0.0", "2.0.0"}, []string{"0.9.9"}},
+		{"<1.x", []string{"0.9.9"}, []string{"1.0.0", "1.5.0"}},
+		{"<=1.x", []string{"1.9.9"}, []string{"2.0.0"}},
+		{"^1.x", []string{"1.0.0", "1.9.9"}, []string{"2.0.0"}},
+		{"~1.x", []string{"1.0.0", "1.9.9"}, []string{"2.0.0"}},
+		// Major and minor: 1.2 spans [1.2.0, 1.3.0)
+		{"1.2", []string{"1.2.0", "1.2.9"}, []string{"1.1.9", "1.3.0"}},
+		{"=1.2", []string{"1.2.0", "1.2.9"}, []string{"1.1.9", "1.3.0"}},
+		{">1.2", []string{"1.3.0", "2.0.0"}, []string{"1.2.0", "1.2.9"}},
+		{">=1.2", []string{"1.2.0", "2.0.0"}, []string{"1.1.9"}},
+		{"<1.2", []string{"1.1.9"}, []string{"1.2.0", "1.2.5"}},
+		{"<=1.2", []string{"1.2.9", "1.0.0"}, []string{"1.3.0", "1.3.0-rc.1"}},
+		{"^1.2", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0"}},
+		{"^0.2", []string{"0.2.0", "0.2.9"}, []string{"0.1.9", "0.3.0"}},
+		{"~1.2", []string{"1.2.0", "1.2.9"}, []string{"1.1.9", "1.3.0"}},
+		// Major, minor and x
+		{"1.2.x", []string{"1.2.0", "1.2.9"}, []string{"1.3.0"}},
+		{">1.2.x", []string{"1.3.0"}, []string{"1.2.9"}},
+		{">=1.2.x", []string{"1.2.0"}, []string{"1.1.9"}},
+		{"<1.2.x", []string{"1.1.9"}, []string{"1.2.0"}},
+		{"<=1.2.x", []string{"1.2.9"}, []string{"1.3.0"}},
+		{"^1.2.x", []string{"1.9.9"}, []string{"2.0.0"}},
+		{"~1.2.x", []string{"1.2.9"}, []string{"1.3.0"}},
+		// Wildcard major
+		{">=*", []string{"0.0.0", "9.9.9"}, nil},
+		{"<*", nil, []string{"0.0.0", "9.9.9"}},
+		{">*", nil, []string{"0.0.0", "9.9.9"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rng, func(t *testing.T) {
+			r, err := parseRange(tt.rng)
+			if err != nil {
+				t.Fatalf("parseRange(%q) returned error: %v", tt.rng, err)
+			}
+			for _, want := range []bool{true, false} {
+				versions := tt.in
+				if !want {
+					versions = tt.out
+				}
+				for _, s := range versions {
+					v, err := parseSemver(s)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got := r.Contains(v); got != want {
+						t.Errorf("%q contains %q = %v, want %v", tt.rng, s, got, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Steps a simulated failure can be injected at.
+const (
+	simulateStepVersion = "version"
+	simulateStepPack    = "pack"
+	simulateStepPublish = "publish"
+	simulateStepVerify  = "verify"
+)
+
+// simulatedErrors are the npm error classes a simulated failure can take,
+// with the stderr npm prints for them.
+var simulatedErrors = map[string]string{
+	"network":  "npm ERR! code ECONNRESET\nnpm ERR! network aborted\nnpm ERR! network This is a problem related to network connectivity.",
+	"timeout":  "npm ERR! code ETIMEDOUT\nnpm ERR! network request to the registry failed, reason: connect ETIMEDOUT",
+	"auth":     "npm ERR! code E401\nnpm ERR! 401 Unauthorized - PUT - authentication token is invalid or expired",
+	"conflict": "npm ERR! code E403\nnpm ERR! 403 Forbidden - You cannot publish over the previously published versions",
+	"script":   "> pkg@0.0.0 prepublishOnly\n> npm test\n\nsimulated test failure\nnpm ERR! code 1",
+}
+
+// SimulatedFailure injects a failure at a release step so pipelines can
+// rehearse rollback and retry handling. While it is set every hook runs as a
+// dry run, so the registry is never written, and the hook owning the step
+// fails without running npm.
+type SimulatedFailure struct {
+	// Step is version, pack, publish or verify.
+	Step string `json:"step,omitempty"`
+	// Error is the error class: network, timeout, auth, conflict or script.
+	Error string `json:"error,omitempty"`
+}
+
+// enabled reports whether a failure is configured.
+func (s SimulatedFailure) enabled() bool {
+	return s.Step != "" || s.Error != ""
+}
+
+// validateSimulatedFailure checks the step and error class.
+func validateSimulatedFailure(s SimulatedFailure) error {
+	if !s.enabled() {
+		return nil
+	}
+	if _, ok := simulateSteps[s.Step]; !ok {
+		return fmt.Errorf("unknown step %q", s.Step)
+	}
+	if _, ok := simulatedErrors[s.Error]; !ok {
+		return fmt.Errorf("unknown error class %q", s.Error)
+	}
+	return nil
+}
+
+// simulateSteps maps each step to the hook it fails in and the npm command
+// reported as failing.
+var simulateSteps = map[string]struct {
+	hook    plugin.Hook
+	command string
+}{
+	simulateStepVersion: {plugin.HookPrePublish, "version"},
+	simulateStepPack:    {plugin.HookPostPublish, "pack"},
+	simulateStepPublish: {plugin.HookPostPublish, "publish"},
+	simulateStepVerify:  {plugin.HookOnSuccess, "view"},
+}
+
+// simulatedFailure returns the injected failure response for hook, or nil
+// when the configured failure belongs to another hook. The error is built the
+// way a real npm failure is, so script failures carry the same outputs.
+func simulatedFailure(s SimulatedFailure, hook plugin.Hook) *plugin.ExecuteResponse {
+	step, ok := simulateSteps[s.Step]
+	if !ok || step.hook != hook {
+		return nil
+	}
+	err := newNpmError(step.command, errors.New("exit status 1"), "", simulatedErrors[s.Error])
+	outputs := scriptFailureOutputs(err)
+	if outputs == nil {
+		outputs = map[string]any{}
+	}
+	outputs["simulated_failure"] = s.Step + "/" + s.Error
+	return &plugin.ExecuteResponse{
+		Success: false,
+		Error:   "[simulated] " + err.Error(),
+		Outputs: outputs,
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateSimulatedFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		sim     SimulatedFailure
+		wantErr bool
+	}{
+		{"unset", SimulatedFailure{}, false},
+		{"publish network", SimulatedFailure{Step: "publish", Error: "network"}, false},
+		{"verify timeout", SimulatedFailure{Step: "verify", Error: "timeout"}, false},
+		{"unknown step", SimulatedFailure{Step: "deploy", Error: "network"}, true},
+		{"missing step", SimulatedFailure{Error: "auth"}, true},
+		{"unknown error", SimulatedFailure{Step: "pack", Error: "disk"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSimulatedFailure(tt.sim); (err != nil) != tt.wantErr {
+				t.Errorf("validateSimulatedFailure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSimulatedFailure(t *testing.T) {
+	if resp := simulatedFailure(SimulatedFailure{Step: "verify", Error: "auth"}, plugin.HookPostPublish); resp != nil {
+		t.Errorf("failure injected in the wrong hook: %+v", resp)
+	}
+
+	resp := simulatedFailure(SimulatedFailure{Step: "publish", Error: "conflict"}, plugin.HookPostPublish)
+	if resp == nil || resp.Success {
+		t.Fatalf("expected a failure, got %+v", resp)
+	}
+	if !strings.HasPrefix(resp.Error, "[simulated] npm publish failed") || !strings.Contains(resp.Error, "E403") {
+		t.Errorf("Error = %q", resp.Error)
+	}
+	if resp.Outputs["simulated_failure"] != "publish/conflict" {
+		t.Errorf("outputs = %v", resp.Outputs)
+	}
+
+	resp = simulatedFailure(SimulatedFailure{Step: "pack", Error: "script"}, plugin.HookPostPublish)
+	if resp.Outputs["failed_script"] != "prepublishOnly" || resp.Outputs["script_output"] != "simulated test failure" {
+		t.Errorf("outputs = %v", resp.Outputs)
+	}
+}
+
+func TestSimulateFailureExecute(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	manifest := `{"name":"pkg","version":"1.0.0"}`
+	writeFile(t, filepath.Join(dir, "package.json"), manifest)
+	chdir(t, dir)
+
+	config := map[string]any{"simulate_failure": map[string]any{"step": "publish", "error": "network"}}
+	release := plugin.ReleaseContext{Version: "1.0.1"}
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPrePublish,
+		Config:  config,
+		Context: release,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("pre-publish should run ahead of the failing step: %v %+v", err, resp)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "package.json")); string(data) != manifest {
+		t.Errorf("pre-publish wrote package.json during a rehearsal: %s", data)
+	}
+
+	resp, err = (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  config,
+		Context: release,
+	})
+	if err != nil || resp.Success {
+		t.Fatalf("expected a simulated failure: %v %+v", err, resp)
+	}
+	if !strings.Contains(resp.Error, "ECONNRESET") {
+		t.Errorf("Error = %q", resp.Error)
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 0 {
+		t.Errorf("npm ran during a rehearsal: %v", calls)
+	}
+
+	resp, err = (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"simulate_failure": map[string]any{"step": "publish", "error": "oops"}},
+		Context: release,
+	})
+	if err != nil || resp.Success || !strings.Contains(resp.Error, "simulate_failure") {
+		t.Errorf("expected a validation failure: %v %+v", err, resp)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// SkipOn lists release filters under which post-publish intentionally skips
+// publishing. The version update in pre-publish still runs.
+type SkipOn struct {
+	// ReleaseTypes skips releases whose type is listed (e.g. "patch"), or
+	// whose commits are all of listed conventional types (e.g. "docs",
+	// "chore").
+	ReleaseTypes []string `json:"release_types,omitempty"`
+	// CommitMarker skips releases whose HEAD commit or release commits carry
+	// this marker, e.g. "[skip npm]".
+	CommitMarker string `json:"commit_marker,omitempty"`
+}
+
+// releaseCommits returns every commit in the release's categorized changes.
+// Docs commits without a type are reported as "docs".
+func releaseCommits(changes *plugin.CategorizedChanges) []plugin.ConventionalCommit {
+	if changes == nil {
+		return nil
+	}
+	var commits []plugin.ConventionalCommit
+	for _, group := range [][]plugin.ConventionalCommit{
+		changes.Features, changes.Fixes, changes.Breaking, changes.Performance,
+		changes.Refactor, changes.Other,
+	} {
+		commits = append(commits, group...)
+	}
+	for _, c := range changes.Docs {
+		if c.Type == "" {
+			c.Type = "docs"
+		}
+		commits = append(commits, c)
+	}
+	return commits
+}
+
+// skipOnReason returns why the release matches skip_on, or "" when it should
+// be published. The HEAD commit message is read from dir when a marker is
+// configured; a directory outside git only checks the release commits.
+func skipOnReason(ctx context.Context, skip SkipOn, dir string, releaseCtx plugin.ReleaseContext) string {
+	types := map[string]bool{}
+	for _, t := range skip.ReleaseTypes {
+		types[strings.ToLower(t)] = true
+	}
+	if releaseCtx.ReleaseType != "" && types[strings.ToLower(releaseCtx.ReleaseType)] {
+		return fmt.Sprintf("release type %q is listed in skip_on", releaseCtx.ReleaseType)
+	}
+
+	commits := releaseCommits(releaseCtx.Changes)
+	if len(types) > 0 && len(commits) > 0 {
+		all := true
+		for _, c := range commits {
+			if !types[strings.ToLower(c.Type)] {
+				all = false
+				break
+			}
+		}
+		if all {
+			return "all release commits have types listed in skip_on"
+		}
+	}
+
+	if skip.CommitMarker != "" {
+		for _, c := range commits {
+			if strings.Contains(c.Description, skip.CommitMarker) || strings.Contains(c.Body, skip.CommitMarker) {
+				return fmt.Sprintf("commit %s is marked %q", shortHash(c.Hash), skip.CommitMarker)
+			}
+		}
+		if msg, err := runGit(ctx, dir, "log", "-1", "--format=%B"); err == nil && strings.Contains(msg, skip.CommitMarker) {
+			return fmt.Sprintf("HEAD commit is marked %q", skip.CommitMarker)
+		}
+	}
+	return ""
+}
+
+// shortHash abbreviates a commit hash for messages.
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestSkipOnReason(t *testing.T) {
+	dir, _ := initGitRepo(t)
+	if _, err := runGit(context.Background(), dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "chore: bump [skip npm]"); err != nil {
+		t.Fatalf("git commit failed: %v", err)
+	}
+	docsOnly := &plugin.CategorizedChanges{
+		Docs:  []plugin.ConventionalCommit{{Hash: "abcdef123", Description: "fix typo"}},
+		Other: []plugin.ConventionalCommit{{Hash: "123456789", Type: "chore", Description: "update deps"}},
+	}
+	withFix := &plugin.CategorizedChanges{
+		Docs:  docsOnly.Docs,
+		Fixes: []plugin.ConventionalCommit{{Hash: "fedcba987", Type: "fix", Description: "crash [skip npm]"}},
+	}
+
+	tests := []struct {
+		name    string
+		skip    SkipOn
+		dir     string
+		release plugin.ReleaseContext
+		want    string
+	}{
+		{"release type", SkipOn{ReleaseTypes: []string{"Patch"}}, t.TempDir(), plugin.ReleaseContext{ReleaseType: "patch"}, "release type"},
+		{"docs and chore only", SkipOn{ReleaseTypes: []string{"docs", "chore"}}, t.TempDir(), plugin.ReleaseContext{ReleaseType: "patch", Changes: docsOnly}, "all release commits"},
+		{"has fix", SkipOn{ReleaseTypes: []string{"docs", "chore"}}, t.TempDir(), plugin.ReleaseContext{ReleaseType: "patch", Changes: withFix}, ""},
+		{"no changes", SkipOn{ReleaseTypes: []string{"docs"}}, t.TempDir(), plugin.ReleaseContext{ReleaseType: "minor"}, ""},
+		{"release commit marker", SkipOn{CommitMarker: "[skip npm]"}, t.TempDir(), plugin.ReleaseContext{Changes: withFix}, "commit fedcba9"},
+		{"head marker", SkipOn{CommitMarker: "[skip npm]"}, dir, plugin.ReleaseContext{}, "HEAD commit"},
+		{"no marker", SkipOn{CommitMarker: "[no publish]"}, dir, plugin.ReleaseContext{Changes: withFix}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := skipOnReason(context.Background(), tt.skip, tt.dir, tt.release)
+			if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+				t.Errorf("skipOnReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipOnPostPublish(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:   plugin.HookPostPublish,
+		Config: map[string]any{"skip_on": map[string]any{"release_types": []any{"docs"}}},
+		Context: plugin.ReleaseContext{
+			Version: "1.0.1",
+			Changes: &plugin.CategorizedChanges{Docs: []plugin.ConventionalCommit{{Description: "readme"}}},
+		},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if resp.Outputs["skipped"] != true || resp.Outputs["skip_reason"] != "skip_on" {
+		t.Errorf("outputs = %v", resp.Outputs)
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 0 {
+		t.Errorf("npm ran for a skipped release: %v", calls)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+// Reasons a check was not evaluated.
+const (
+	skipDisabled = "disabled by config"
+	skipTarball  = "publishing a prebuilt tarball; package.json is not edited"
+	skipDryRun   = "dry run"
+	skipNoTool   = "no sandbox tool is available"
+)
+
+// skippedCheck is a gate that was not evaluated, so auditors can tell it
+// apart from one that passed.
+type skippedCheck struct {
+	Check  string `json:"check"`
+	Reason string `json:"reason"`
+}
+
+// prePublishSkippedChecks returns the pre-publish checks the config leaves
+// out.
+func prePublishSkippedChecks(cfg *Config) []skippedCheck {
+	skipped := []skippedCheck{}
+	disabled := func(check string, off bool) {
+		if off {
+			skipped = append(skipped, skippedCheck{Check: check, Reason: skipDisabled})
+		}
+	}
+	disabled("expect_current_version", cfg.UpdateVersion && cfg.ExpectCurrentVersion == "")
+	disabled("readme_versions", cfg.ReadmeVersions == "")
+	disabled("changelog_check", cfg.ChangelogCheck == "")
+	disabled("preflight", cfg.Preflight == "")
+	return skipped
+}
+
+// publishSkippedChecks returns the post-publish checks the config leaves
+// out or that do not apply to this publish.
+func publishSkippedChecks(cfg *Config, dryRun bool) []skippedCheck {
+	skipped := []skippedCheck{}
+	add := func(check, reason string) {
+		skipped = append(skipped, skippedCheck{Check: check, Reason: reason})
+	}
+	disabled := func(check string, off bool) {
+		if off {
+			add(check, skipDisabled)
+		}
+	}
+	disabled("verify_checkout", !cfg.VerifyCheckout)
+	disabled("name_pattern", cfg.NamePattern == "")
+	disabled("publish_plan", cfg.PublishPlan == "")
+	switch {
+	case cfg.LicenseCheck == "":
+		add("license_check", skipDisabled)
+	case cfg.inputTarball != "":
+		add("license_check", skipTarball)
+	}
+	switch {
+	case !cfg.ManifestLeaks.enabled():
+		add("manifest_leaks", skipDisabled)
+	case cfg.inputTarball != "":
+		add("manifest_leaks", skipTarball)
+	}
+	disabled("bundled_deps", cfg.BundledDeps == "")
+	disabled("code_scan", cfg.CodeScan == "")
+	disabled("sourcemaps", cfg.Sourcemaps == "")
+	disabled("module_check", cfg.ModuleCheck == "")
+	disabled("expected_outputs", len(cfg.ExpectedOutputs) == 0)
+	disabled("sandbox", cfg.Sandbox == "")
+	switch {
+	case !cfg.VerifyPublish:
+		add("verify_publish", skipDisabled)
+	case dryRun:
+		add("verify_publish", skipDryRun)
+	}
+	switch {
+	case !cfg.Verification.enabled():
+		add("verification", skipDisabled)
+	case dryRun:
+		add("verification", skipDryRun)
+	}
+	return skipped
+}
+
+// addSkippedCheck appends a check skipped at run time to outputs.
+func addSkippedCheck(outputs map[string]any, check, reason string) {
+	skipped, _ := outputs["skipped_checks"].([]skippedCheck)
+	outputs["skipped_checks"] = append(skipped, skippedCheck{Check: check, Reason: reason})
+}
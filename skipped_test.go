@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestPublishSkippedChecks(t *testing.T) {
+	checks := func(skipped []skippedCheck) map[string]string {
+		m := map[string]string{}
+		for _, s := range skipped {
+			m[s.Check] = s.Reason
+		}
+		return m
+	}
+
+	all := checks(publishSkippedChecks(&Config{}, false))
+	for _, check := range []string{"verify_checkout", "name_pattern", "license_check", "manifest_leaks", "code_scan", "sourcemaps", "module_check", "expected_outputs", "sandbox", "verify_publish"} {
+		if all[check] != skipDisabled {
+			t.Errorf("%s: reason = %q, want %q", check, all[check], skipDisabled)
+		}
+	}
+
+	cfg := &Config{
+		VerifyCheckout: true,
+		LicenseCheck:   licenseCheck,
+		CodeScan:       "warn",
+		VerifyPublish:  true,
+		inputTarball:   "lib-1.0.0.tgz",
+	}
+	got := checks(publishSkippedChecks(cfg, true))
+	if _, ok := got["verify_checkout"]; ok {
+		t.Error("verify_checkout reported as skipped while enabled")
+	}
+	if _, ok := got["code_scan"]; ok {
+		t.Error("code_scan reported as skipped while enabled")
+	}
+	if got["license_check"] != skipTarball {
+		t.Errorf("license_check: reason = %q", got["license_check"])
+	}
+	if got["verify_publish"] != skipDryRun {
+		t.Errorf("verify_publish: reason = %q", got["verify_publish"])
+	}
+}
+
+func TestPrePublishSkippedChecks(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"0.9.0"}`)
+	writeFile(t, filepath.Join(dir, "CHANGELOG.md"), "## 1.0.0\n")
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPrePublish,
+		Config:  map[string]any{"changelog_check": "fail"},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+		DryRun:  true,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	want := []skippedCheck{
+		{Check: "expect_current_version", Reason: skipDisabled},
+		{Check: "readme_versions", Reason: skipDisabled},
+		{Check: "preflight", Reason: skipDisabled},
+	}
+	if got := resp.Outputs["skipped_checks"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("skipped_checks = %v, want %v", got, want)
+	}
+}
+
+func TestSandboxUnavailableSkipped(t *testing.T) {
+	stubSandbox(t, "")
+	fakeNpm(t, `echo '[{"files":[{"path":"package.json"}]}]'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"sandbox": sandboxAuto},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+		DryRun:  true,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	skipped, _ := resp.Outputs["skipped_checks"].([]skippedCheck)
+	if len(skipped) == 0 || skipped[len(skipped)-1] != (skippedCheck{Check: "sandbox", Reason: skipNoTool}) {
+		t.Errorf("skipped_checks = %v", skipped)
+	}
+}
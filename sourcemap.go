@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Source map policies.
+const (
+	sourcemapsInclude  = "include"
+	sourcemapsExclude  = "exclude"
+	sourcemapsExternal = "external"
+)
+
+// sourceMappingURLRegexp matches "//# sourceMappingURL=..." and the CSS
+// "/*# sourceMappingURL=... */" form.
+var sourceMappingURLRegexp = regexp.MustCompile(`(?m)(?://|/\*)[#@][ \t]*sourceMappingURL=([^\s*]+)`)
+
+// sourcemapExtensions are the packed file types that may reference a map.
+var sourcemapExtensions = map[string]bool{".js": true, ".mjs": true, ".cjs": true, ".css": true}
+
+// checkSourcemaps validates the packed files against the source map policy:
+//
+//   - include: every relative sourceMappingURL must point at a packed file
+//   - exclude: no .map files and no sourceMappingURL comments at all
+//   - external: no .map files; references must be absolute URLs
+//
+// It returns the violations found.
+func checkSourcemaps(packageDir string, files []packFile, policy string) ([]string, error) {
+	packed := make(map[string]bool, len(files))
+	for _, f := range files {
+		packed[f.Path] = true
+	}
+
+	var problems []string
+	for _, f := range files {
+		if strings.HasSuffix(f.Path, ".map") && policy != sourcemapsInclude {
+			problems = append(problems, fmt.Sprintf("%s: source map shipped with sourcemaps %q", f.Path, policy))
+		}
+		if !sourcemapExtensions[strings.ToLower(filepath.Ext(f.Path))] {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(packageDir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Path, err)
+		}
+		for _, m := range sourceMappingURLRegexp.FindAllSubmatch(data, -1) {
+			ref := string(m[1])
+			absolute := strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://")
+			inline := strings.HasPrefix(ref, "data:")
+
+			switch {
+			case policy == sourcemapsExclude:
+				problems = append(problems, fmt.Sprintf("%s: references source map %q with sourcemaps \"exclude\"", f.Path, truncate(ref, 64)))
+			case policy == sourcemapsExternal && !absolute:
+				problems = append(problems, fmt.Sprintf("%s: source map reference %q is not an absolute URL", f.Path, truncate(ref, 64)))
+			case policy == sourcemapsInclude && !absolute && !inline:
+				target := path.Join(path.Dir(f.Path), strings.SplitN(ref, "?", 2)[0])
+				if !packed[target] {
+					problems = append(problems, fmt.Sprintf("%s: source map %s is not in the package", f.Path, target))
+				}
+			}
+		}
+	}
+	return problems, nil
+}
+
+// truncate shortens s to at most n bytes for messages.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func writePackFiles(t *testing.T, dir string, files map[string]string) []packFile {
+	t.Helper()
+	var packed []packFile
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		packed = append(packed, packFile{Path: name})
+	}
+	return packed
+}
+
+func TestCheckSourcemaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		files    map[string]string
+		problems int
+	}{
+		{
+			name:   "include_ok",
+			policy: sourcemapsInclude,
+			files:  map[string]string{"dist/index.js": "x\n//# sourceMappingURL=index.js.map\n", "dist/index.js.map": "{}"},
+		},
+		{
+			name:     "include_missing_map",
+			policy:   sourcemapsInclude,
+			files:    map[string]string{"dist/index.js": "x\n//# sourceMappingURL=index.js.map\n"},
+			problems: 1,
+		},
+		{
+			name:   "include_inline",
+			policy: sourcemapsInclude,
+			files:  map[string]string{"dist/index.js": "x\n//# sourceMappingURL=data:application/json;base64,e30=\n"},
+		},
+		{
+			name:     "exclude_map_shipped",
+			policy:   sourcemapsExclude,
+			files:    map[string]string{"dist/index.js": "x\n//# sourceMappingURL=index.js.map\n", "dist/index.js.map": "{}"},
+			problems: 2,
+		},
+		{
+			name:   "exclude_ok",
+			policy: sourcemapsExclude,
+			files:  map[string]string{"dist/index.js": "x\n", "dist/style.css": "a{}\n"},
+		},
+		{
+			name:   "external_ok",
+			policy: sourcemapsExternal,
+			files:  map[string]string{"dist/index.js": "x\n//# sourceMappingURL=https://maps.example.com/index.js.map\n"},
+		},
+		{
+			name:     "external_relative",
+			policy:   sourcemapsExternal,
+			files:    map[string]string{"dist/style.css": "a{}\n/*# sourceMappingURL=style.css.map */\n"},
+			problems: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			problems, err := checkSourcemaps(dir, writePackFiles(t, dir, tt.files), tt.policy)
+			if err != nil {
+				t.Fatalf("checkSourcemaps returned error: %v", err)
+			}
+			if len(problems) != tt.problems {
+				t.Errorf("got %d problems, want %d: %v", len(problems), tt.problems, problems)
+			}
+		})
+	}
+}
+
+func TestPublishSourcemapPolicy(t *testing.T) {
+	p := &NpmPlugin{}
+	tmpDir := t.TempDir()
+	writePackFiles(t, tmpDir, map[string]string{
+		"package.json": `{"name":"pkg","version":"1.0.0"}`,
+		"index.js":     "x\n//# sourceMappingURL=index.js.map\n",
+		"index.js.map": "{}",
+	})
+	chdir(t, tmpDir)
+	fakeNpm(t, `echo '[{"name":"pkg","files":[{"path":"index.js"},{"path":"index.js.map"},{"path":"package.json"}]}]'`)
+
+	resp, err := p.publishPackage(context.Background(), &Config{Tag: "latest", Sourcemaps: sourcemapsExclude}, plugin.ReleaseContext{Version: "1.0.0"}, true)
+	if err != nil {
+		t.Fatalf("publishPackage returned error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "source map policy violated") {
+		t.Errorf("expected policy failure, got %+v", resp)
+	}
+
+	if err := p.validateConfig(&Config{Tag: "latest", Sourcemaps: "sometimes"}); err == nil {
+		t.Error("expected unknown sourcemaps policy to fail validation")
+	}
+}
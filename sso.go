@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// SSO covers registries behind enterprise single sign-on, which expect an
+// interactive web login npm would otherwise wait on for a browser.
+type SSO struct {
+	// Detect probes the publish registry before publishing and fails with
+	// guidance when it demands a web login.
+	Detect bool `json:"detect"`
+	// TokenEnv names the variable holding a token pre-authorized through
+	// the identity provider, used instead of the ambient credentials.
+	TokenEnv string `json:"token_env,omitempty"`
+	// Header sends the token in this header instead of Authorization:
+	// Bearer, for registries whose SSO proxy expects its own. npm cannot
+	// send custom headers, so it requires publish_method api.
+	Header string `json:"header,omitempty"`
+}
+
+// enabled reports whether any SSO handling is configured.
+func (s SSO) enabled() bool {
+	return s.Detect || s.TokenEnv != "" || s.Header != ""
+}
+
+// token returns the pre-authorized token, if any.
+func (s SSO) token() string {
+	if s.TokenEnv == "" {
+		return ""
+	}
+	return os.Getenv(s.TokenEnv)
+}
+
+// errSSORequired reports a registry demanding an interactive web login.
+var errSSORequired = errors.New("registry requires an SSO web login")
+
+// httpHeaderPattern matches HTTP header field names.
+var httpHeaderPattern = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`)
+
+// ssoMarkers are the words SSO registries use in their 401 responses when
+// pointing at the browser login.
+var ssoMarkers = []string{"sso", "saml", "oauth", "loginurl", "web login", "weblogin"}
+
+// validateSSO checks the token variable and header names.
+func validateSSO(cfg *Config) error {
+	s := cfg.SSO
+	if s.TokenEnv != "" && !inputNamePattern.MatchString(s.TokenEnv) {
+		return fmt.Errorf("token_env %q is not a valid environment variable name", s.TokenEnv)
+	}
+	if s.Header == "" {
+		return nil
+	}
+	if s.TokenEnv == "" {
+		return fmt.Errorf("header requires token_env")
+	}
+	if cfg.PublishMethod != publishMethodAPI {
+		return fmt.Errorf("header requires publish_method api, as npm only sends Authorization")
+	}
+	if !httpHeaderPattern.MatchString(s.Header) {
+		return fmt.Errorf("header %q is not a valid HTTP header name", s.Header)
+	}
+	return nil
+}
+
+// applySSO switches npm to non-interactive authentication, so a login
+// challenge fails instead of waiting for a browser, and authenticates it
+// with the pre-authorized token through useAuthToken, keeping the token out
+// of npm's arguments. The returned func deletes the token npmrc; callers
+// defer it.
+func applySSO(cfg *Config, registry string) (func() error, error) {
+	cfg.authArgs = append(cfg.authArgs, "--auth-type=legacy")
+	if cfg.SSO.TokenEnv == "" {
+		return func() error { return nil }, nil
+	}
+	token := cfg.SSO.token()
+	if token == "" {
+		return nil, fmt.Errorf("%s is not set", cfg.SSO.TokenEnv)
+	}
+	return useAuthToken(cfg, registry, token)
+}
+
+// setAPIAuth authenticates a direct registry request: with the SSO header
+// when configured, otherwise as a bearer token.
+func setAPIAuth(req *http.Request, cfg *Config) {
+	token := apiAuthToken(cfg)
+	switch {
+	case token == "":
+	case cfg.SSO.Header != "" && token == cfg.SSO.token():
+		req.Header.Set(cfg.SSO.Header, token)
+	default:
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// ssoLoginURL extracts the browser login URL from a registry error body.
+func ssoLoginURL(body []byte) string {
+	var doc struct {
+		SSO      string `json:"sso"`
+		LoginURL string `json:"loginUrl"`
+	}
+	if json.Unmarshal(body, &doc) != nil {
+		return ""
+	}
+	if doc.LoginURL != "" {
+		return doc.LoginURL
+	}
+	return doc.SSO
+}
+
+// detectSSO asks the registry who the credentials belong to. A 401 or 403
+// pointing at a web login means npm would hang in CI, so it returns
+// errSSORequired with the login URL when the registry gives one. Other
+// failures are left for the publish itself to report.
+func detectSSO(ctx context.Context, cfg *Config, registry string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(registry, "/")+"/-/whoami", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create whoami request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	setAPIAuth(req, cfg)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	challenge := strings.ToLower(resp.Header.Get("WWW-Authenticate") + " " + string(body))
+	for _, marker := range ssoMarkers {
+		if strings.Contains(challenge, marker) {
+			if url := ssoLoginURL(body); url != "" {
+				return fmt.Errorf("%w (%s)", errSSORequired, url)
+			}
+			return errSSORequired
+		}
+	}
+	return nil
+}
+
+// ssoGuidance turns a detected web login into an actionable error.
+func ssoGuidance(cfg *Config, registry string, err error) error {
+	if cfg.SSO.TokenEnv != "" {
+		return fmt.Errorf("%s: %v; the token in %s was not accepted, mint a new one through the identity provider", registry, err, cfg.SSO.TokenEnv)
+	}
+	return fmt.Errorf("%s: %v, which cannot complete in CI; sign in through the identity provider to mint a pre-authorized token and set sso.token_env to the variable holding it", registry, err)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateSSO(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "detect", cfg: Config{SSO: SSO{Detect: true}}},
+		{name: "token", cfg: Config{SSO: SSO{TokenEnv: "SSO_TOKEN"}}},
+		{name: "api header", cfg: Config{SSO: SSO{TokenEnv: "SSO_TOKEN", Header: "X-Auth-Token"}, PublishMethod: publishMethodAPI}},
+		{name: "bad token env", cfg: Config{SSO: SSO{TokenEnv: "SSO-TOKEN"}}, wantErr: true},
+		{name: "header without token", cfg: Config{SSO: SSO{Header: "X-Auth-Token"}, PublishMethod: publishMethodAPI}, wantErr: true},
+		{name: "header with npm", cfg: Config{SSO: SSO{TokenEnv: "SSO_TOKEN", Header: "X-Auth-Token"}, PublishMethod: publishMethodNpm}, wantErr: true},
+		{name: "bad header", cfg: Config{SSO: SSO{TokenEnv: "SSO_TOKEN", Header: "X Auth"}, PublishMethod: publishMethodAPI}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSSO(&tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateSSO() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDetectSSO(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		challenge string
+		body      string
+		wantErr   string
+	}{
+		{name: "authenticated", status: http.StatusOK, body: `{"username":"ci"}`},
+		{name: "web login", status: http.StatusUnauthorized, body: `{"error":"web login required","loginUrl":"https://sso.example.com/login/123"}`, wantErr: "registry requires an SSO web login (https://sso.example.com/login/123)"},
+		{name: "saml challenge", status: http.StatusForbidden, challenge: `SAML realm="corp"`, wantErr: "registry requires an SSO web login"},
+		{name: "otp", status: http.StatusUnauthorized, challenge: "OTP"},
+		{name: "bad token", status: http.StatusUnauthorized, body: `{"error":"invalid token"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/-/whoami" {
+					t.Errorf("unexpected request %s", r.URL.Path)
+				}
+				if tt.challenge != "" {
+					w.Header().Set("WWW-Authenticate", tt.challenge)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			err := detectSSO(context.Background(), &Config{}, server.URL+"/")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("detectSSO() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, errSSORequired) || err.Error() != tt.wantErr {
+				t.Errorf("detectSSO() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetAPIAuth(t *testing.T) {
+	t.Setenv("SSO_TOKEN", "sso-secret")
+	t.Setenv("NPM_TOKEN", "npm-secret")
+
+	t.Setenv("TMPDIR", t.TempDir())
+
+	cfg := &Config{SSO: SSO{TokenEnv: "SSO_TOKEN", Header: "X-Auth-Token"}}
+	cleanup, err := applySSO(cfg, "https://npm.corp.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cleanup() }()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	setAPIAuth(req, cfg)
+	if req.Header.Get("X-Auth-Token") != "sso-secret" || req.Header.Get("Authorization") != "" {
+		t.Errorf("headers = %v", req.Header)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	setAPIAuth(req, &Config{})
+	if req.Header.Get("Authorization") != "Bearer npm-secret" {
+		t.Errorf("headers = %v", req.Header)
+	}
+}
+
+func TestSSOPublish(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer sso-secret" {
+			_, _ = w.Write([]byte(`{"username":"ci"}`))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"SSO required","sso":"https://sso.example.com/start"}`))
+	}))
+	defer server.Close()
+
+	npmrcLog := filepath.Join(t.TempDir(), "npmrc")
+	execute := func(t *testing.T, sso map[string]any) (*plugin.ExecuteResponse, []string) {
+		t.Helper()
+		logPath := fakeNpm(t, `while [ $# -gt 0 ]; do
+  if [ "$1" = --userconfig ]; then cat "$2" > `+npmrcLog+`; fi
+  shift
+done
+echo '{}'`)
+		t.Setenv("TMPDIR", t.TempDir())
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+		chdir(t, dir)
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"registry": server.URL + "/", "sso": sso},
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, npmCalls(t, logPath)
+	}
+
+	t.Run("web login", func(t *testing.T) {
+		resp, calls := execute(t, map[string]any{"detect": true})
+		if resp.Success || !strings.Contains(resp.Error, "requires an SSO web login (https://sso.example.com/start), which cannot complete in CI") {
+			t.Errorf("expected early failure, got %+v", resp)
+		}
+		if len(calls) != 0 {
+			t.Errorf("npm called: %v", calls)
+		}
+	})
+
+	t.Run("pre-authorized token", func(t *testing.T) {
+		t.Setenv("SSO_TOKEN", "sso-secret")
+		resp, calls := execute(t, map[string]any{"detect": true, "token_env": "SSO_TOKEN"})
+		if !resp.Success {
+			t.Fatalf("unexpected failure: %+v", resp)
+		}
+		if len(calls) != 1 || !strings.Contains(calls[0], "--auth-type=legacy") {
+			t.Errorf("npm calls = %v", calls)
+		}
+		for _, call := range calls {
+			if strings.Contains(call, "sso-secret") {
+				t.Errorf("token on the npm command line: %s", call)
+			}
+		}
+		npmrc, err := os.ReadFile(npmrcLog)
+		if err != nil || !strings.HasSuffix(string(npmrc), strings.TrimPrefix(registryAuthArg(server.URL+"/", "sso-secret"), "--")+"\n") {
+			t.Errorf("publish npmrc = %q, %v; want the SSO token", npmrc, err)
+		}
+		if out := fmt.Sprintf("%s %v", resp.Message, resp.Outputs); strings.Contains(out, "sso-secret") {
+			t.Errorf("token leaked into the response: %s", out)
+		}
+	})
+
+	t.Run("token unset", func(t *testing.T) {
+		resp, _ := execute(t, map[string]any{"token_env": "SSO_TOKEN_UNSET"})
+		if resp.Success || resp.Error != "sso: SSO_TOKEN_UNSET is not set" {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// unsafeFileChars matches characters not allowed in state file names.
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// publishRecord captures what post-publish uploaded so later hooks can act on
+// it without re-deriving it (each hook is a separate Execute call).
+type publishRecord struct {
+	Name        string    `json:"name"`
+	Version     string    `json:"version"`
+	Tag         string    `json:"tag"`
+	Registry    string    `json:"registry"`
+	Integrity   string    `json:"integrity,omitempty"`
+	Shasum      string    `json:"shasum,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// defaultInstanceID namespaces state when no instance id is configured.
+const defaultInstanceID = "default"
+
+// stateDir returns the directory holding the plugin's state files. Each
+// plugin instance (see Config.ID) gets its own directory so parallel
+// instances in one release never share state.
+func stateDir(cfg *Config) string {
+	id := cfg.ID
+	if id == "" {
+		id = defaultInstanceID
+	}
+	return filepath.Join(os.TempDir(), "relicta-npm", unsafeFileChars.ReplaceAllString(id, "_"))
+}
+
+// statePath returns the path of a package's state file of the given kind.
+func statePath(cfg *Config, name, kind string) string {
+	return filepath.Join(stateDir(cfg), unsafeFileChars.ReplaceAllString(name, "_")+"."+kind+".json")
+}
+
+// saveState persists v as the package's state of the given kind.
+func saveState(cfg *Config, name, kind string, v any) error {
+	if err := os.MkdirAll(stateDir(cfg), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s state: %w", kind, err)
+	}
+	if err := os.WriteFile(statePath(cfg, name, kind), data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s state: %w", kind, err)
+	}
+	return nil
+}
+
+// loadState reads the package's state of the given kind into v. It reports
+// false without error when no state exists.
+func loadState(cfg *Config, name, kind string, v any) (bool, error) {
+	data, err := os.ReadFile(statePath(cfg, name, kind))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s state: %w", kind, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse %s state: %w", kind, err)
+	}
+	return true, nil
+}
+
+// saveRecord persists a publish record.
+func saveRecord(cfg *Config, rec *publishRecord) error {
+	return saveState(cfg, rec.Name, "publish", rec)
+}
+
+// loadRecord reads the publish record for a package. It returns nil without
+// error when no record exists.
+func loadRecord(cfg *Config, name string) (*publishRecord, error) {
+	var rec publishRecord
+	ok, err := loadState(cfg, name, "publish", &rec)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// deleteRecord removes the publish record for a package.
+func deleteRecord(cfg *Config, name string) error {
+	if err := os.Remove(statePath(cfg, name, "publish")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove publish state: %w", err)
+	}
+	return nil
+}
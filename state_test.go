@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordsNamespacedByInstance(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	npmCfg := &Config{ID: "npmjs"}
+	ghCfg := &Config{ID: "github"}
+
+	if err := saveRecord(npmCfg, &publishRecord{Name: "@scope/pkg", Version: "1.0.0", Registry: "https://registry.npmjs.org/"}); err != nil {
+		t.Fatalf("saveRecord returned error: %v", err)
+	}
+	if err := saveRecord(ghCfg, &publishRecord{Name: "@scope/pkg", Version: "1.0.0", Registry: "https://npm.pkg.github.com/"}); err != nil {
+		t.Fatalf("saveRecord returned error: %v", err)
+	}
+
+	rec, err := loadRecord(npmCfg, "@scope/pkg")
+	if err != nil || rec == nil {
+		t.Fatalf("loadRecord returned %v, %v", rec, err)
+	}
+	if rec.Registry != "https://registry.npmjs.org/" {
+		t.Errorf("instance records collided: got registry %q", rec.Registry)
+	}
+
+	if rec, err := loadRecord(&Config{}, "@scope/pkg"); err != nil || rec != nil {
+		t.Errorf("expected no record for default instance, got %v, %v", rec, err)
+	}
+}
+
+func TestStateDirSanitizesID(t *testing.T) {
+	dir := stateDir(&Config{ID: "../../etc"})
+	if strings.Contains(filepath.Base(dir), "/") || filepath.Base(filepath.Dir(dir)) != "relicta-npm" {
+		t.Errorf("state dir escaped its root: %s", dir)
+	}
+}
+
+func TestValidateInstanceID(t *testing.T) {
+	tests := []struct {
+		id      string
+		wantErr bool
+	}{
+		{"", false},
+		{"npmjs", false},
+		{"github-packages_2", false},
+		{"../escape", true},
+		{"with space", true},
+		{strings.Repeat("a", 65), true},
+	}
+
+	for _, tt := range tests {
+		if err := validateInstanceID(tt.id); (err != nil) != tt.wantErr {
+			t.Errorf("validateInstanceID(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+		}
+	}
+}
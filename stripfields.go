@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// requiredManifestFields cannot be stripped: npm needs them to publish.
+var requiredManifestFields = []string{"name", "version"}
+
+// validateFieldPath checks a dotted package.json field path.
+func validateFieldPath(field string) error {
+	if field == "" || strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") || strings.Contains(field, "..") {
+		return fmt.Errorf("invalid field path %q", field)
+	}
+	return nil
+}
+
+// validateStripFields checks the strip_fields paths.
+func validateStripFields(fields []string) error {
+	for _, field := range fields {
+		if err := validateFieldPath(field); err != nil {
+			return err
+		}
+		if containsString(requiredManifestFields, field) {
+			return fmt.Errorf("%q is required to publish and cannot be stripped", field)
+		}
+	}
+	return nil
+}
+
+// stripManifestFields removes the fields at the given dotted paths (e.g.
+// "overrides", "scripts.prepare") from manifest and returns the paths that
+// were present.
+func stripManifestFields(manifest map[string]any, fields []string) []string {
+	stripped := []string{}
+	for _, field := range fields {
+		parts := strings.Split(field, ".")
+		obj := manifest
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := obj[part].(map[string]any)
+			if !ok {
+				obj = nil
+				break
+			}
+			obj = next
+		}
+		last := parts[len(parts)-1]
+		if _, ok := obj[last]; !ok {
+			continue
+		}
+		delete(obj, last)
+		stripped = append(stripped, field)
+	}
+	return stripped
+}
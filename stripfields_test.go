@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateStripFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  []string
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "fields", fields: []string{"overrides", "resolutions", "scripts.prepare"}},
+		{name: "empty", fields: []string{""}, wantErr: true},
+		{name: "trailing dot", fields: []string{"scripts."}, wantErr: true},
+		{name: "empty segment", fields: []string{"scripts..prepare"}, wantErr: true},
+		{name: "name", fields: []string{"name"}, wantErr: true},
+		{name: "version", fields: []string{"version"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateStripFields(tt.fields); (err != nil) != tt.wantErr {
+				t.Errorf("validateStripFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStripManifestFields(t *testing.T) {
+	var manifest map[string]any
+	if err := json.Unmarshal([]byte(`{
+		"name": "lib",
+		"overrides": {"semver": "7.5.4"},
+		"scripts": {"prepare": "husky install", "test": "vitest"},
+		"pnpm": {"overrides": {}}
+	}`), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	got := stripManifestFields(manifest, []string{"overrides", "resolutions", "scripts.prepare", "pnpm.overrides", "private.flag"})
+	if want := []string{"overrides", "scripts.prepare", "pnpm.overrides"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stripped = %v, want %v", got, want)
+	}
+	if _, ok := manifest["overrides"]; ok {
+		t.Error("overrides not removed")
+	}
+	if scripts := manifest["scripts"].(map[string]any); !reflect.DeepEqual(scripts, map[string]any{"test": "vitest"}) {
+		t.Errorf("scripts = %v", scripts)
+	}
+}
+
+func TestStripFieldsRestoresManifest(t *testing.T) {
+	manifestLog := filepath.Join(t.TempDir(), "published.json")
+	logPath := fakeNpm(t, `if [ "$1" = publish ]; then cat package.json > `+manifestLog+`; fi; echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	original := `{"name":"lib","version":"1.0.0","overrides":{"semver":"7.5.4"},"resolutions":{"semver":"7.5.4"}}`
+	writeFile(t, filepath.Join(dir, "package.json"), original)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"strip_fields": []any{"overrides", "resolutions"}},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if got := resp.Outputs["stripped_fields"]; !reflect.DeepEqual(got, []string{"overrides", "resolutions"}) {
+		t.Errorf("stripped_fields = %v", got)
+	}
+	if len(npmCalls(t, logPath)) == 0 {
+		t.Fatal("npm was not called")
+	}
+	var published map[string]any
+	data, err := os.ReadFile(manifestLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &published); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := published["overrides"]; ok {
+		t.Errorf("published manifest = %s", data)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "package.json")); string(got) != original {
+		t.Errorf("package.json not restored: %s", got)
+	}
+}
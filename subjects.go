@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// provenanceSubject is an in-toto statement subject: what an attestation is
+// about, identified by name and digest.
+type provenanceSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// npmPurl returns the package URL npm names provenance subjects with, e.g.
+// "pkg:npm/%40acme/lib@1.0.0".
+func npmPurl(name, version string) string {
+	return "pkg:npm/" + strings.Replace(name, "@", "%40", 1) + "@" + version
+}
+
+// newProvenanceSubject builds the subject for a tarball from its digest
+// ("sha512:<hex>"), the form integrityDigest returns.
+func newProvenanceSubject(name, digest string) (provenanceSubject, error) {
+	algo, sum, ok := strings.Cut(digest, ":")
+	if !ok || algo != "sha512" || sum == "" {
+		return provenanceSubject{}, fmt.Errorf("subject %s needs a sha512 digest, got %q", name, digest)
+	}
+	return provenanceSubject{Name: name, Digest: map[string]string{algo: sum}}, nil
+}
+
+// addProvenanceSubject appends a subject to the provenance_subjects output,
+// warning instead when the digest cannot be used.
+func addProvenanceSubject(outputs map[string]any, name, digest string) {
+	subject, err := newProvenanceSubject(name, digest)
+	if err != nil {
+		appendWarning(outputs, err.Error())
+		return
+	}
+	subjects, _ := outputs["provenance_subjects"].([]provenanceSubject)
+	outputs["provenance_subjects"] = append(subjects, subject)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestNpmPurl(t *testing.T) {
+	tests := []struct {
+		name, version, want string
+	}{
+		{"lib", "1.0.0", "pkg:npm/lib@1.0.0"},
+		{"@acme/lib", "2.0.0-rc.1", "pkg:npm/%40acme/lib@2.0.0-rc.1"},
+	}
+	for _, tt := range tests {
+		if got := npmPurl(tt.name, tt.version); got != tt.want {
+			t.Errorf("npmPurl(%q, %q) = %q, want %q", tt.name, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestNewProvenanceSubject(t *testing.T) {
+	got, err := newProvenanceSubject("pkg:npm/lib@1.0.0", "sha512:deadbeef")
+	want := provenanceSubject{Name: "pkg:npm/lib@1.0.0", Digest: map[string]string{"sha512": "deadbeef"}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("newProvenanceSubject() = %+v, %v; want %+v", got, err, want)
+	}
+	for _, digest := range []string{"sha1:deadbeef", "sha512:", "deadbeef"} {
+		if _, err := newProvenanceSubject("lib", digest); err == nil {
+			t.Errorf("newProvenanceSubject(%q) accepted the digest", digest)
+		}
+	}
+}
+
+func TestAddProvenanceSubject(t *testing.T) {
+	outputs := map[string]any{}
+	addProvenanceSubject(outputs, "pkg:npm/lib@1.0.0", "sha512:aa")
+	addProvenanceSubject(outputs, "lib-docs-1.0.0.tgz", "sha512:bb")
+	addProvenanceSubject(outputs, "legacy", "sha1:cc")
+	want := []provenanceSubject{
+		{Name: "pkg:npm/lib@1.0.0", Digest: map[string]string{"sha512": "aa"}},
+		{Name: "lib-docs-1.0.0.tgz", Digest: map[string]string{"sha512": "bb"}},
+	}
+	if !reflect.DeepEqual(outputs["provenance_subjects"], want) {
+		t.Errorf("provenance_subjects = %+v, want %+v", outputs["provenance_subjects"], want)
+	}
+	if warnings, _ := outputs["warnings"].([]string); len(warnings) != 1 {
+		t.Errorf("warnings = %v, want the unusable digest", warnings)
+	}
+}
+
+func TestPublishProvenanceSubjects(t *testing.T) {
+	fakeNpm(t, `echo '{"name":"@acme/lib","version":"1.0.0","integrity":"sha512-3q2+7w=="}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"@acme/lib","version":"1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "docs", "index.md"), "# lib\n")
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"docs_tarball": map[string]any{"dir": "docs"}},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected response: %v %+v", err, resp)
+	}
+	subjects, _ := resp.Outputs["provenance_subjects"].([]provenanceSubject)
+	if len(subjects) != 2 {
+		t.Fatalf("provenance_subjects = %+v, want the package and docs tarballs", resp.Outputs["provenance_subjects"])
+	}
+	if want := (provenanceSubject{Name: "pkg:npm/%40acme/lib@1.0.0", Digest: map[string]string{"sha512": "deadbeef"}}); !reflect.DeepEqual(subjects[0], want) {
+		t.Errorf("package subject = %+v, want %+v", subjects[0], want)
+	}
+	if subjects[1].Name != "acme-lib-docs-1.0.0.tgz" || len(subjects[1].Digest["sha512"]) != 128 {
+		t.Errorf("docs subject = %+v", subjects[1])
+	}
+}
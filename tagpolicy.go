@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TagRule maps release characteristics to a dist-tag. All set conditions must
+// match; unset conditions match anything.
+type TagRule struct {
+	// Range is an npm-style semver range the release version must satisfy.
+	Range string `json:"range,omitempty"`
+	// ReleaseType matches ReleaseContext.ReleaseType (major, minor, patch).
+	ReleaseType string `json:"release_type,omitempty"`
+	// Prerelease, when set, matches only prerelease (true) or stable (false) versions.
+	Prerelease *bool `json:"prerelease,omitempty"`
+	// Tag is the dist-tag to use when the rule matches.
+	Tag string `json:"tag"`
+}
+
+// decodeConfigValue decodes a nested config value (list or object) into v.
+func decodeConfigValue(raw map[string]any, key string, v any) error {
+	val, ok := raw[key]
+	if !ok || val == nil {
+		return nil
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return nil
+}
+
+// validateTagPolicy checks that every rule has a valid tag and range.
+func validateTagPolicy(rules []TagRule, allowSemver bool) error {
+	for i, rule := range rules {
+		if rule.Tag == "" {
+			return fmt.Errorf("rule %d: tag is required", i)
+		}
+		if err := checkTag(rule.Tag, allowSemver); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+		if rule.Range != "" {
+			if _, err := parseRange(rule.Range); err != nil {
+				return fmt.Errorf("rule %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// matchTagPolicy returns the tag of the first rule matching the release, if any.
+func matchTagPolicy(rules []TagRule, releaseCtx plugin.ReleaseContext) (string, bool) {
+	version, err := parseSemver(releaseCtx.Version)
+	if err != nil {
+		return "", false
+	}
+
+	for _, rule := range rules {
+		if rule.ReleaseType != "" && rule.ReleaseType != releaseCtx.ReleaseType {
+			continue
+		}
+		if rule.Prerelease != nil && *rule.Prerelease != version.IsPrerelease() {
+			continue
+		}
+		if rule.Range != "" {
+			r, err := parseRange(rule.Range)
+			if err != nil || !r.Contains(version) {
+				continue
+			}
+		}
+		return rule.Tag, true
+	}
+	return "", false
+}
+
+// TagMap picks the dist-tag from the branch and release type when no
+// TagPolicy rule matches.
+type TagMap struct {
+	// Branches maps branch names or path.Match globs ("release/*") to tags.
+	Branches map[string]string `json:"branches,omitempty"`
+	// ReleaseTypes maps release types (major, minor, patch) to tags.
+	ReleaseTypes map[string]string `json:"release_types,omitempty"`
+	// Prerelease is the tag for prerelease versions no branch matched.
+	Prerelease string `json:"prerelease,omitempty"`
+}
+
+// validateTagMap checks the tags and branch globs, and that prereleases
+// cannot be mapped to latest.
+func validateTagMap(m TagMap, allowSemver bool) error {
+	for branch, tag := range m.Branches {
+		if _, err := path.Match(branch, ""); err != nil {
+			return fmt.Errorf("branch %q: invalid pattern: %w", branch, err)
+		}
+		if tag == "" {
+			return fmt.Errorf("branch %q: tag is required", branch)
+		}
+		if err := checkTag(tag, allowSemver); err != nil {
+			return fmt.Errorf("branch %q: %w", branch, err)
+		}
+	}
+	for releaseType, tag := range m.ReleaseTypes {
+		if tag == "" {
+			return fmt.Errorf("release type %q: tag is required", releaseType)
+		}
+		if err := checkTag(tag, allowSemver); err != nil {
+			return fmt.Errorf("release type %q: %w", releaseType, err)
+		}
+	}
+	if err := checkTag(m.Prerelease, allowSemver); err != nil {
+		return fmt.Errorf("prerelease: %w", err)
+	}
+	if m.Prerelease == "latest" {
+		return fmt.Errorf("prerelease cannot be mapped to latest")
+	}
+	return nil
+}
+
+// matchTagMap returns the tag for the release: an exact branch entry, else
+// the first matching branch glob in sorted order, else Prerelease for
+// prereleases, else the release type's tag. It fails when a prerelease
+// would be tagged latest.
+func matchTagMap(m TagMap, releaseCtx plugin.ReleaseContext) (string, bool, error) {
+	prerelease := false
+	if v, err := parseSemver(releaseCtx.Version); err == nil {
+		prerelease = v.IsPrerelease()
+	}
+
+	tag, ok := m.Branches[releaseCtx.Branch]
+	if !ok && releaseCtx.Branch != "" {
+		patterns := make([]string, 0, len(m.Branches))
+		for pattern := range m.Branches {
+			patterns = append(patterns, pattern)
+		}
+		sort.Strings(patterns)
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, releaseCtx.Branch); matched {
+				tag, ok = m.Branches[pattern], true
+				break
+			}
+		}
+	}
+	if !ok && prerelease && m.Prerelease != "" {
+		tag, ok = m.Prerelease, true
+	}
+	if !ok {
+		tag, ok = m.ReleaseTypes[releaseCtx.ReleaseType]
+	}
+	if ok && prerelease && tag == "latest" {
+		return "", false, fmt.Errorf("tag_map selects latest for prerelease %s", releaseCtx.Version)
+	}
+	return tag, ok, nil
+}
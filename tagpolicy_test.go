@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestMatchTagPolicy(t *testing.T) {
+	yes := true
+	rules := []TagRule{
+		{Range: ">=2.0.0-0", Prerelease: &yes, Tag: "next"},
+		{Range: "1.x", ReleaseType: "patch", Tag: "1.x"},
+		{Prerelease: &yes, Tag: "beta"},
+	}
+
+	tests := []struct {
+		name        string
+		version     string
+		releaseType string
+		wantTag     string
+		wantMatch   bool
+	}{
+		{"major_prerelease", "2.0.0-rc.1", "major", "next", true},
+		{"patch_on_1x", "1.4.2", "patch", "1.x", true},
+		{"minor_on_1x_no_match", "1.5.0", "minor", "", false},
+		{"other_prerelease", "1.5.0-alpha.1", "minor", "beta", true},
+		{"invalid_version", "not-semver", "patch", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, ok := matchTagPolicy(rules, plugin.ReleaseContext{Version: tt.version, ReleaseType: tt.releaseType})
+			if ok != tt.wantMatch || tag != tt.wantTag {
+				t.Errorf("matchTagPolicy() = %q, %v; want %q, %v", tag, ok, tt.wantTag, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestTagPolicyConfig(t *testing.T) {
+	p := &NpmPlugin{}
+	raw := map[string]any{
+		"tag": "latest",
+		"tag_policy": []any{
+			map[string]any{"range": "1.x", "tag": "1.x"},
+		},
+	}
+
+	cfg := p.parseConfig(raw)
+	if len(cfg.TagPolicy) != 1 || cfg.TagPolicy[0].Tag != "1.x" {
+		t.Fatalf("unexpected tag policy: %+v", cfg.TagPolicy)
+	}
+
+	resp, err := p.Validate(context.Background(), map[string]any{
+		"tag_policy": []any{map[string]any{"range": "1.x"}},
+	})
+	if err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+	if resp.Valid {
+		t.Error("expected rule without tag to be invalid")
+	}
+
+	bad := p.parseConfig(map[string]any{"tag_policy": "not-a-list"})
+	if err := p.validateConfig(bad); err == nil {
+		t.Error("expected validateConfig to reject malformed tag_policy")
+	}
+}
+
+func TestMatchTagMap(t *testing.T) {
+	m := TagMap{
+		Branches:     map[string]string{"main": "latest", "next": "next", "release/*": "maintenance"},
+		ReleaseTypes: map[string]string{"major": "major"},
+		Prerelease:   "beta",
+	}
+
+	tests := []struct {
+		name        string
+		branch      string
+		version     string
+		releaseType string
+		wantTag     string
+		wantMatch   bool
+		wantErr     bool
+	}{
+		{name: "main", branch: "main", version: "1.2.0", releaseType: "minor", wantTag: "latest", wantMatch: true},
+		{name: "branch glob", branch: "release/1.x", version: "1.1.5", releaseType: "patch", wantTag: "maintenance", wantMatch: true},
+		{name: "next prerelease", branch: "next", version: "2.0.0-rc.1", releaseType: "major", wantTag: "next", wantMatch: true},
+		{name: "other prerelease", branch: "feature", version: "1.3.0-alpha.1", releaseType: "minor", wantTag: "beta", wantMatch: true},
+		{name: "release type", branch: "feature", version: "2.0.0", releaseType: "major", wantTag: "major", wantMatch: true},
+		{name: "no match", branch: "feature", version: "1.3.0", releaseType: "minor"},
+		{name: "prerelease on main", branch: "main", version: "1.3.0-beta.1", releaseType: "minor", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, ok, err := matchTagMap(m, plugin.ReleaseContext{Branch: tt.branch, Version: tt.version, ReleaseType: tt.releaseType})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("matchTagMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.wantMatch || tag != tt.wantTag {
+				t.Errorf("matchTagMap() = %q, %v; want %q, %v", tag, ok, tt.wantTag, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestValidateTagMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       TagMap
+		wantErr bool
+	}{
+		{name: "valid", m: TagMap{Branches: map[string]string{"main": "latest"}, Prerelease: "next"}},
+		{name: "prerelease latest", m: TagMap{Prerelease: "latest"}, wantErr: true},
+		{name: "empty tag", m: TagMap{ReleaseTypes: map[string]string{"major": ""}}, wantErr: true},
+		{name: "invalid tag", m: TagMap{Branches: map[string]string{"main": "a b"}}, wantErr: true},
+		{name: "invalid glob", m: TagMap{Branches: map[string]string{"release/[": "old"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateTagMap(tt.m, false); (err != nil) != tt.wantErr {
+				t.Errorf("validateTagMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// validateTarballPath rejects tarball_path values that cannot be published
+// and options that conflict with publishing a prebuilt tarball.
+func validateTarballPath(cfg *Config) error {
+	if cfg.TarballPath == "" {
+		return nil
+	}
+	switch {
+	case !strings.HasSuffix(cfg.TarballPath, ".tgz") && !strings.HasSuffix(cfg.TarballPath, ".tar.gz"):
+		return fmt.Errorf("%s is not a .tgz file", cfg.TarballPath)
+	case cfg.Inputs.Tarball != "":
+		return fmt.Errorf("tarball_path cannot be combined with inputs.tarball")
+	case cfg.DistDir != "":
+		return fmt.Errorf("tarball_path cannot be combined with dist_dir")
+	case len(cfg.Workspaces) > 0:
+		return fmt.Errorf("tarball_path cannot be combined with workspaces")
+	}
+	return validateOutputPath(cfg.TarballPath)
+}
+
+// resolveTarball returns the absolute path of a prebuilt tarball, which must
+// exist inside the working directory.
+func resolveTarball(path string) (string, error) {
+	if err := validateOutputPath(path); err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return "", err
+	}
+	return abs, nil
+}
+
+// applyTarballPath points cfg at the prebuilt tarball so post-publish
+// uploads it as-is instead of packing package_dir.
+func applyTarballPath(cfg *Config) error {
+	if err := validateTarballPath(cfg); err != nil {
+		return fmt.Errorf("tarball_path validation failed: %w", err)
+	}
+	abs, err := resolveTarball(cfg.TarballPath)
+	if err != nil {
+		return fmt.Errorf("tarball_path: %w", err)
+	}
+	cfg.inputTarball = abs
+	return nil
+}
+
+// checkTarballManifest checks that the prebuilt tarball holds the package
+// being released, name and version, so a stale artifact from another build
+// is never uploaded.
+func checkTarballManifest(cfg *Config, version string) error {
+	data, err := os.ReadFile(cfg.inputTarball)
+	if err != nil {
+		return fmt.Errorf("failed to read tarball: %w", err)
+	}
+	manifest, err := tarballManifest(data)
+	if err != nil {
+		return err
+	}
+	name, _ := manifest["name"].(string)
+	tarballVersion, _ := manifest["version"].(string)
+
+	packageDir, err := validatePackageDir(cfg.PackageDir)
+	if err != nil {
+		return fmt.Errorf("invalid package directory: %w", err)
+	}
+	source, err := readPackageJSON(packageDir)
+	if err != nil {
+		return err
+	}
+	if name != source.Name {
+		return fmt.Errorf("tarball package %q does not match source package %q", name, source.Name)
+	}
+	if tarballVersion != version {
+		return fmt.Errorf("tarball version %s does not match release version %s", tarballVersion, version)
+	}
+	return nil
+}
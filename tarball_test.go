@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// writeTarball packs a package.json holding manifest into path.
+func writeTarball(t *testing.T, path, manifest string) {
+	t.Helper()
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "package.json"), manifest)
+	data, _, _, err := buildTarball(src, []string{"package.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateTarballPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "tgz", cfg: Config{TarballPath: "build/lib-1.0.0.tgz"}},
+		{name: "tar.gz", cfg: Config{TarballPath: "build/lib.tar.gz"}},
+		{name: "not a tarball", cfg: Config{TarballPath: "build/lib.zip"}, wantErr: true},
+		{name: "outside cwd", cfg: Config{TarballPath: "../lib-1.0.0.tgz"}, wantErr: true},
+		{name: "with inputs tarball", cfg: Config{TarballPath: "lib.tgz", Inputs: PluginInputs{Tarball: "BUILD_TARBALL"}}, wantErr: true},
+		{name: "with dist_dir", cfg: Config{TarballPath: "lib.tgz", DistDir: "dist"}, wantErr: true},
+		{name: "with workspaces", cfg: Config{TarballPath: "lib.tgz", Workspaces: []string{"packages/*"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateTarballPath(&tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateTarballPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublishTarballPath(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	writeTarball(t, filepath.Join(dir, "artifacts", "lib-1.0.0.tgz"), `{"name":"lib","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"tarball_path": "artifacts/lib-1.0.0.tgz"},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	tarball := filepath.Join(dir, "artifacts", "lib-1.0.0.tgz")
+	calls := npmCalls(t, logPath)
+	if len(calls) != 1 || !strings.HasPrefix(calls[0], "publish "+tarball+" --json") {
+		t.Errorf("npm calls = %v", calls)
+	}
+	if resp.Outputs["tarball"] != tarball {
+		t.Errorf("tarball = %v", resp.Outputs["tarball"])
+	}
+}
+
+func TestPublishTarballPathMismatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+	}{
+		{name: "stale version", manifest: `{"name":"lib","version":"0.9.0"}`, want: "tarball_path: tarball version 0.9.0 does not match release version 1.0.0"},
+		{name: "other package", manifest: `{"name":"other","version":"1.0.0"}`, want: `tarball_path: tarball package "other" does not match source package "lib"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := fakeNpm(t, `echo '{}'`)
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+			writeTarball(t, filepath.Join(dir, "lib.tgz"), tt.manifest)
+			chdir(t, dir)
+
+			resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  map[string]any{"tarball_path": "lib.tgz"},
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Success || resp.Error != tt.want {
+				t.Errorf("error = %q, want %q", resp.Error, tt.want)
+			}
+			if calls := npmCalls(t, logPath); len(calls) != 0 {
+				t.Errorf("npm called: %v", calls)
+			}
+		})
+	}
+}
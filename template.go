@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// templateData is the data exposed to user-supplied templates (URLs,
+// messages). Fields use Go template syntax, e.g. "{{.Name}}@{{.Version}}".
+type templateData struct {
+	Name            string
+	Version         string
+	PreviousVersion string
+	Tag             string
+	Registry        string
+	TagName         string
+	ReleaseType     string
+	Branch          string
+	CommitSHA       string
+	RepoOwner       string
+	RepoName        string
+	// Message and Error are only set for message templates.
+	Message string
+	Error   string
+	// Repo is only set for consumer notification URLs.
+	Repo string
+}
+
+// newTemplateData builds template data for a package from config and release context.
+func newTemplateData(name string, cfg *Config, releaseCtx plugin.ReleaseContext) templateData {
+	return templateData{
+		Name:            name,
+		Version:         releaseCtx.Version,
+		PreviousVersion: releaseCtx.PreviousVersion,
+		Tag:             cfg.Tag,
+		Registry:        cfg.Registry,
+		TagName:         releaseCtx.TagName,
+		ReleaseType:     releaseCtx.ReleaseType,
+		Branch:          releaseCtx.Branch,
+		CommitSHA:       releaseCtx.CommitSHA,
+		RepoOwner:       releaseCtx.RepositoryOwner,
+		RepoName:        releaseCtx.RepositoryName,
+	}
+}
+
+// renderTemplate renders text with data. Unknown fields are an error rather
+// than silently rendering as "<no value>".
+func renderTemplate(text string, data templateData) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", text, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %w", text, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+
+	"github.com/relicta-tech/plugin-npm/registrytest"
+)
+
+// testRegistryPublish publishes to a throwaway registry instead of the real
+// one and verifies the package installs from it. The registry is embedded
+// unless TestRegistryURL points at one (e.g. a Verdaccio service container).
+// Nothing reaches the configured registry, so this runs even in dry-run.
+func (p *NpmPlugin) testRegistryPublish(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext) (*plugin.ExecuteResponse, error) {
+	if err := p.validateConfig(cfg); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("configuration validation failed: %v", err),
+		}, nil
+	}
+
+	testCfg := *cfg
+	testCfg.Registry = cfg.TestRegistryURL
+	if testCfg.Registry == "" {
+		reg := registrytest.New(registrytest.WithUplink(registryURL(cfg)))
+		defer reg.Close()
+		testCfg.Registry = reg.URL()
+		testCfg.authArgs = reg.AuthArgs()
+		testCfg.authToken = registrytest.Token
+		testCfg.EphemeralNpmrc = EphemeralNpmrc{}
+	}
+
+	// Side effects that only make sense against the real registry are off
+	testCfg.ID = testInstanceID(cfg)
+	testCfg.PublishURL = ""
+	testCfg.AllowedRegistries = nil
+	testCfg.RegionalRegistries = nil
+	testCfg.RegistryPreset = ""
+	testCfg.Lock = false
+	testCfg.CDNPurge = nil
+	testCfg.PublishHistory = false
+	testCfg.PackManifest = ""
+
+	resp, err := p.publishPackage(ctx, &testCfg, releaseCtx, false)
+	if err != nil || !resp.Success || resp.Outputs["skipped"] == true {
+		return resp, err
+	}
+
+	name, _ := resp.Outputs["package"].(string)
+	if err := verifyInstall(ctx, &testCfg, name, releaseCtx.Version); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("published %s@%s to the test registry but it does not install: %v", name, releaseCtx.Version, err),
+			Outputs: resp.Outputs,
+		}, nil
+	}
+
+	resp.Outputs["test_registry"] = testCfg.Registry
+	resp.Outputs["installed"] = true
+	resp.Message = fmt.Sprintf("Published %s@%s to test registry %s and verified it installs", name, releaseCtx.Version, testCfg.Registry)
+	return resp, nil
+}
+
+// testInstanceID namespaces test-registry state apart from real publishes.
+func testInstanceID(cfg *Config) string {
+	id := cfg.ID
+	if id == "" {
+		id = defaultInstanceID
+	}
+	if len(id) > 58 {
+		id = id[:58]
+	}
+	return id + "-test"
+}
+
+// verifyInstall installs name@version from the configured registry into a
+// scratch project and checks the installed version.
+func verifyInstall(ctx context.Context, cfg *Config, name, version string) error {
+	dir, err := os.MkdirTemp("", "relicta-npm-install-")
+	if err != nil {
+		return fmt.Errorf("failed to create install directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name":"relicta-install-check","private":true}`), 0644); err != nil {
+		return fmt.Errorf("failed to write package.json: %w", err)
+	}
+
+	args := append([]string{"install", "--no-audit", "--no-fund", "--registry", cfg.Registry}, npmConfigArgs(cfg)...)
+	if _, err := runNpm(ctx, dir, append(args, name+"@"+version)...); err != nil {
+		return err
+	}
+
+	pkg, err := readPackageJSON(filepath.Join(dir, "node_modules", filepath.FromSlash(name)))
+	if err != nil {
+		return fmt.Errorf("installed package: %w", err)
+	}
+	if pkg.Version != version {
+		return fmt.Errorf("installed version %s, expected %s", pkg.Version, version)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestTestRegistryPublish(t *testing.T) {
+	if _, err := exec.LookPath("npm"); err != nil {
+		t.Skip("npm not available")
+	}
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("npm_config_cache", filepath.Join(t.TempDir(), "cache"))
+	t.Setenv("npm_config_update_notifier", "false")
+
+	p := &NpmPlugin{}
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "package.json"), `{"name":"@acme/e2e","version":"0.0.0"}`)
+	writeFile(t, filepath.Join(tmpDir, "index.js"), "module.exports = 1\n")
+	chdir(t, tmpDir)
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"test_registry":      true,
+			"access":             "public",
+			"allowed_registries": []any{"registry.npmjs.org"},
+		},
+		Context: plugin.ReleaseContext{Version: "0.0.0"},
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got error: %s", resp.Error)
+	}
+	if resp.Outputs["installed"] != true {
+		t.Errorf("expected installed output, got %v", resp.Outputs)
+	}
+	if registry, _ := resp.Outputs["test_registry"].(string); !strings.HasPrefix(registry, "http://127.0.0.1:") {
+		t.Errorf("test_registry = %q, want embedded loopback registry", registry)
+	}
+	if !strings.Contains(resp.Message, "verified it installs") {
+		t.Errorf("unexpected message: %q", resp.Message)
+	}
+}
+
+func TestTestInstanceID(t *testing.T) {
+	if got := testInstanceID(&Config{}); got != "default-test" {
+		t.Errorf("testInstanceID() = %q", got)
+	}
+	if got := testInstanceID(&Config{ID: strings.Repeat("a", 64)}); validateInstanceID(got) != nil {
+		t.Errorf("testInstanceID() = %q is not a valid id", got)
+	}
+}
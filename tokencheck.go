@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// tokenExpiryWarning is how close to its expiry a publish token gets a
+// warning.
+const tokenExpiryWarning = 7 * 24 * time.Hour
+
+// publishPermissions are the collaborator permissions that allow publishing.
+var publishPermissions = []string{"read-write", "write"}
+
+// tokenMasks are the runs registries put in the middle of a listed token.
+var tokenMasks = []string{"...", "…", "*"}
+
+// tokenInfo is what the registry reports about the publish token.
+type tokenInfo struct {
+	User     string `json:"user"`
+	Readonly bool   `json:"readonly,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+}
+
+// listedToken is an entry of the registry's token list.
+type listedToken struct {
+	Token    string `json:"token"`
+	Key      string `json:"key"`
+	Readonly bool   `json:"readonly"`
+	Expires  string `json:"expires,omitempty"`
+}
+
+// getRegistryJSON GETs url with the publish credentials and decodes a 200
+// response into v, returning the status code.
+func getRegistryJSON(ctx context.Context, cfg *Config, url string, v any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create registry request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	setAPIAuth(req, cfg)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("registry request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to decode %s: %w", url, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// tokenMatches reports whether a masked token from the token list, e.g.
+// "npm_ab...yz", is token.
+func tokenMatches(masked, token string) bool {
+	for _, mask := range tokenMasks {
+		if head, rest, ok := strings.Cut(masked, mask); ok {
+			tail := strings.TrimLeft(rest, mask)
+			return head != "" && strings.HasPrefix(token, head) && strings.HasSuffix(token, tail)
+		}
+	}
+	return masked != "" && strings.HasPrefix(token, masked)
+}
+
+// checkPublishToken asks the registry about the token the publish would use:
+// that it is accepted, not read-only or expired, and that its user can
+// publish name. Registries that do not implement the token list,
+// collaborator or org endpoints are given the benefit of the doubt, leaving
+// the publish to report what they refuse.
+func checkPublishToken(ctx context.Context, cfg *Config, name string, now time.Time) (*tokenInfo, error) {
+	registry := publishRegistry(cfg)
+	if registry == "" {
+		registry = defaultRegistry
+	}
+	registry = strings.TrimSuffix(registry, "/")
+	token := apiAuthToken(cfg)
+	if token == "" {
+		return nil, fmt.Errorf("no token to check; set NPM_TOKEN")
+	}
+
+	var whoami struct {
+		Username string `json:"username"`
+	}
+	status, err := getRegistryJSON(ctx, cfg, registry+"/-/whoami", &whoami)
+	switch {
+	case err != nil:
+		return nil, err
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return nil, fmt.Errorf("%s rejected the token (%d); it is invalid, revoked or expired", registry, status)
+	case status != http.StatusOK || whoami.Username == "":
+		return nil, fmt.Errorf("%s did not identify the token's user (%d)", registry, status)
+	}
+	info := &tokenInfo{User: whoami.Username}
+
+	var tokens struct {
+		Objects []listedToken `json:"objects"`
+	}
+	if status, err := getRegistryJSON(ctx, cfg, tokensURL(registry), &tokens); err == nil && status == http.StatusOK {
+		for _, t := range tokens.Objects {
+			if !tokenMatches(t.Token, token) {
+				continue
+			}
+			info.Readonly = t.Readonly
+			info.Expires = t.Expires
+			if t.Readonly {
+				return info, fmt.Errorf("the token of %s is read-only; publishing needs an automation or read-write granular token", info.User)
+			}
+			if expires, err := time.Parse(time.RFC3339, t.Expires); err == nil && !expires.After(now) {
+				return info, fmt.Errorf("the token of %s expired at %s", info.User, expires.UTC().Format(time.RFC3339))
+			}
+			break
+		}
+	}
+
+	var collaborators map[string]string
+	status, err = getRegistryJSON(ctx, cfg, packumentURL(registry+"/-/package", name)+"/collaborators", &collaborators)
+	if err != nil {
+		return info, err
+	}
+	switch status {
+	case http.StatusOK:
+		permission, ok := collaborators[info.User]
+		if !ok {
+			return info, fmt.Errorf("%s is not a maintainer of %s", info.User, name)
+		}
+		if !containsString(publishPermissions, permission) {
+			return info, fmt.Errorf("%s has %s access to %s; publishing needs read-write", info.User, permission, name)
+		}
+	case http.StatusNotFound:
+		// A first publish: the token's user needs the scope
+		scope, _, scoped := strings.Cut(strings.TrimPrefix(name, "@"), "/")
+		if !strings.HasPrefix(name, "@") || !scoped || scope == info.User {
+			break
+		}
+		var members map[string]string
+		status, err := getRegistryJSON(ctx, cfg, registry+"/-/org/"+scope+"/user", &members)
+		if err != nil {
+			return info, err
+		}
+		if _, ok := members[info.User]; status == http.StatusOK && !ok {
+			return info, fmt.Errorf("%s is not a member of the @%s org, so cannot publish %s", info.User, scope, name)
+		}
+	}
+	return info, nil
+}
+
+// tokenExpiresSoon reports whether the token expires within
+// tokenExpiryWarning of now.
+func tokenExpiresSoon(info *tokenInfo, now time.Time) bool {
+	expires, err := time.Parse(time.RFC3339, info.Expires)
+	return err == nil && expires.Sub(now) < tokenExpiryWarning
+}
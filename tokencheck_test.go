@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestTokenMatches(t *testing.T) {
+	tests := []struct {
+		masked string
+		want   bool
+	}{
+		{"npm_ab...yz", true},
+		{"npm_ab…yz", true},
+		{"npm_ab****yz", true},
+		{"npm_ab", true},
+		{"npm_ab...xx", false},
+		{"npm_cd...yz", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := tokenMatches(tt.masked, "npm_abcdefxyz"); got != tt.want {
+			t.Errorf("tokenMatches(%q) = %v, want %v", tt.masked, got, tt.want)
+		}
+	}
+}
+
+// tokenRegistry serves the endpoints the token check queries; a missing
+// route answers 404.
+func tokenRegistry(t *testing.T, routes map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer npm_abcdefxyz" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, ok := routes[r.URL.EscapedPath()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckPublishToken(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	whoami := `{"username":"ci"}`
+	tests := []struct {
+		name    string
+		token   string
+		pkg     string
+		routes  map[string]string
+		wantErr string
+	}{
+		{name: "maintainer", pkg: "lib", routes: map[string]string{
+			"/-/whoami":                    whoami,
+			"/-/package/lib/collaborators": `{"ci":"read-write"}`,
+		}},
+		{name: "rejected", token: "npm_other", pkg: "lib", wantErr: "rejected the token"},
+		{name: "read-only", pkg: "lib", routes: map[string]string{
+			"/-/whoami":        whoami,
+			"/-/npm/v1/tokens": `{"objects":[{"token":"npm_zz...zz","readonly":false},{"token":"npm_ab...yz","readonly":true}]}`,
+		}, wantErr: "read-only"},
+		{name: "expired", pkg: "lib", routes: map[string]string{
+			"/-/whoami":        whoami,
+			"/-/npm/v1/tokens": `{"objects":[{"token":"npm_ab...yz","expires":"2026-02-01T00:00:00Z"}]}`,
+		}, wantErr: "expired at 2026-02-01T00:00:00Z"},
+		{name: "not a maintainer", pkg: "lib", routes: map[string]string{
+			"/-/whoami":                    whoami,
+			"/-/package/lib/collaborators": `{"alice":"read-write"}`,
+		}, wantErr: "ci is not a maintainer of lib"},
+		{name: "read access", pkg: "lib", routes: map[string]string{
+			"/-/whoami":                    whoami,
+			"/-/package/lib/collaborators": `{"ci":"read-only"}`,
+		}, wantErr: "ci has read-only access to lib"},
+		{name: "new unscoped package", pkg: "lib", routes: map[string]string{"/-/whoami": whoami}},
+		{name: "own scope", pkg: "@ci/lib", routes: map[string]string{"/-/whoami": whoami}},
+		{name: "org member", pkg: "@acme/lib", routes: map[string]string{
+			"/-/whoami":        whoami,
+			"/-/org/acme/user": `{"ci":"developer"}`,
+			"/-/npm/v1/tokens": `{"objects":[]}`,
+		}},
+		{name: "not an org member", pkg: "@acme/lib", routes: map[string]string{
+			"/-/whoami":        whoami,
+			"/-/org/acme/user": `{"alice":"owner"}`,
+		}, wantErr: "ci is not a member of the @acme org"},
+		{name: "org unknown to registry", pkg: "@acme/lib", routes: map[string]string{"/-/whoami": whoami}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.token
+			if token == "" {
+				token = "npm_abcdefxyz"
+			}
+			t.Setenv("NPM_TOKEN", token)
+			server := tokenRegistry(t, tt.routes)
+			_, err := checkPublishToken(context.Background(), &Config{Registry: server.URL}, tt.pkg, now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkPublishToken() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkPublishToken() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPublishTokenMissing(t *testing.T) {
+	t.Setenv("NPM_TOKEN", "")
+	if _, err := checkPublishToken(context.Background(), &Config{}, "lib", time.Now()); err == nil || !strings.Contains(err.Error(), "NPM_TOKEN") {
+		t.Errorf("checkPublishToken() error = %v, want a missing token error", err)
+	}
+}
+
+func TestTokenExpiresSoon(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	if !tokenExpiresSoon(&tokenInfo{Expires: "2026-03-03T00:00:00Z"}, now) {
+		t.Error("tokenExpiresSoon() = false for a token expiring in two days")
+	}
+	if tokenExpiresSoon(&tokenInfo{Expires: "2026-06-01T00:00:00Z"}, now) || tokenExpiresSoon(&tokenInfo{}, now) {
+		t.Error("tokenExpiresSoon() = true for a distant or unknown expiry")
+	}
+}
+
+func TestTokenCheckPublish(t *testing.T) {
+	server := tokenRegistry(t, map[string]string{
+		"/-/whoami":                    `{"username":"ci"}`,
+		"/-/package/lib/collaborators": `{"alice":"read-write"}`,
+	})
+	execute := func(t *testing.T, dryRun bool) (*plugin.ExecuteResponse, []string) {
+		t.Helper()
+		logPath := fakeNpm(t, `echo '{}'`)
+		t.Setenv("NPM_TOKEN", "npm_abcdefxyz")
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+		chdir(t, dir)
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"registry": server.URL, "token_check": true},
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+			DryRun:  dryRun,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, npmCalls(t, logPath)
+	}
+
+	resp, calls := execute(t, false)
+	if resp.Success || !strings.Contains(resp.Error, "token check failed: ci is not a maintainer of lib") {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	for _, call := range calls {
+		if strings.HasPrefix(call, "publish") {
+			t.Errorf("published despite the failed token check: %v", calls)
+		}
+	}
+
+	resp, _ = execute(t, true)
+	if !resp.Success || resp.Outputs["token_user"] != "ci" {
+		t.Fatalf("unexpected dry-run response: %+v", resp)
+	}
+	warnings, _ := resp.Outputs["warnings"].([]string)
+	if !strings.Contains(strings.Join(warnings, "\n"), "token check failed") {
+		t.Errorf("warnings = %v, want the token check failure", warnings)
+	}
+}
+
+func TestValidateTokenCheck(t *testing.T) {
+	resp, err := (&NpmPlugin{}).Validate(context.Background(), map[string]any{"token_check": true, "token_exchange": true})
+	if err != nil || resp.Valid {
+		t.Errorf("Validate() = %+v, %v; want token_check with token_exchange rejected", resp, err)
+	}
+}
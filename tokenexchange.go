@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// adminTokenEnv holds the long-lived token that may only mint and revoke
+// publish tokens when token_exchange is enabled.
+const adminTokenEnv = "NPM_ADMIN_TOKEN"
+
+// tokenExchangeExpiryDays is the lifetime of a minted publish token; it is
+// revoked right after publishing, the expiry only bounds a failed revoke.
+const tokenExchangeExpiryDays = 1
+
+// publishToken is a short-lived granular token minted for one publish.
+type publishToken struct {
+	Token string `json:"token"`
+	Key   string `json:"key"`
+}
+
+// tokensURL returns the registry's token API endpoint.
+func tokensURL(registry string) string {
+	return strings.TrimSuffix(registry, "/") + "/-/npm/v1/tokens"
+}
+
+// registryAuthArg returns the npm flag authenticating to registry with token,
+// e.g. "--//registry.npmjs.org/:_authToken=...".
+func registryAuthArg(registry, token string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(registry, "https:"), "http:")
+	if !strings.HasSuffix(host, "/") {
+		host += "/"
+	}
+	return "--" + host + ":_authToken=" + token
+}
+
+// mintPublishToken uses the admin token to create a read-write granular token
+// scoped to the single package being published.
+func mintPublishToken(ctx context.Context, registry, adminToken, name, version string) (*publishToken, error) {
+	body, err := json.Marshal(map[string]any{
+		"name":        fmt.Sprintf("relicta %s@%s", name, version),
+		"packages":    []string{name},
+		"permission":  "read-write",
+		"expires":     tokenExchangeExpiryDays,
+		"readonly":    false,
+		"description": "Short-lived publish token, revoked after publishing",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal token request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokensURL(registry), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("registry returned %d minting a publish token: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var tok publishToken
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tok.Token == "" || tok.Key == "" {
+		return nil, fmt.Errorf("registry returned no token or token key")
+	}
+	return &tok, nil
+}
+
+// revokePublishToken deletes a minted token by its key.
+func revokePublishToken(ctx context.Context, registry, adminToken, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, tokensURL(registry)+"/token/"+url.PathEscape(key), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create revoke request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("revoke request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("registry returned %d revoking the publish token: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// exchangePublishToken mints a package-scoped token and returns it with the
+// registry it is for, plus a func revoking it.
+func exchangePublishToken(ctx context.Context, cfg *Config, name, version string) (string, string, func() error, error) {
+	adminToken := os.Getenv(adminTokenEnv)
+	if adminToken == "" {
+		return "", "", nil, fmt.Errorf("token_exchange requires %s", adminTokenEnv)
+	}
+	registry := publishRegistry(cfg)
+	if registry == "" {
+		registry = defaultRegistry
+	}
+	tok, err := mintPublishToken(ctx, registry, adminToken, name, version)
+	if err != nil {
+		return "", "", nil, err
+	}
+	revoke := func() error {
+		// Revoke even when the publish was cancelled
+		return revokePublishToken(context.WithoutCancel(ctx), registry, adminToken, tok.Key)
+	}
+	return registry, tok.Token, revoke, nil
+}
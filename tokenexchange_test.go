@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestRegistryAuthArg(t *testing.T) {
+	tests := []struct{ registry, want string }{
+		{"https://registry.npmjs.org/", "--//registry.npmjs.org/:_authToken=tok"},
+		{"https://npm.example.com/repo", "--//npm.example.com/repo/:_authToken=tok"},
+		{"http://localhost:4873/", "--//localhost:4873/:_authToken=tok"},
+	}
+	for _, tt := range tests {
+		if got := registryAuthArg(tt.registry, "tok"); got != tt.want {
+			t.Errorf("registryAuthArg(%q) = %q, want %q", tt.registry, got, tt.want)
+		}
+	}
+}
+
+// tokenServer fakes the registry token API, recording requests.
+func tokenServer(t *testing.T, mintStatus int) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer admin" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/-/npm/v1/tokens":
+			var body struct {
+				Packages []string `json:"packages"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			requests = append(requests, "mint "+strings.Join(body.Packages, ","))
+			w.WriteHeader(mintStatus)
+			_, _ = w.Write([]byte(`{"token":"npm_short","key":"k1"}`))
+		case r.Method == http.MethodDelete:
+			requests = append(requests, "revoke "+strings.TrimPrefix(r.URL.Path, "/-/npm/v1/tokens/token/"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestTokenExchangePublish(t *testing.T) {
+	server, requests := tokenServer(t, http.StatusCreated)
+	npmrcLog := filepath.Join(t.TempDir(), "npmrc")
+	logPath := fakeNpm(t, `while [ $# -gt 0 ]; do
+  if [ "$1" = --userconfig ]; then cat "$2" > `+npmrcLog+`; fi
+  shift
+done
+echo '{"id":"@acme/lib@1.0.0","name":"@acme/lib","version":"1.0.0"}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"@acme/lib","version":"1.0.0"}`)
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv(adminTokenEnv, "admin")
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"registry": server.URL, "token_exchange": true},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if resp.Outputs["token_revoked"] != true {
+		t.Errorf("token_revoked = %v", resp.Outputs["token_revoked"])
+	}
+	if strings.Join(*requests, "; ") != "mint @acme/lib; revoke k1" {
+		t.Errorf("token API requests = %v", *requests)
+	}
+	calls := npmCalls(t, logPath)
+	if len(calls) != 1 || strings.Contains(calls[0], "npm_short") {
+		t.Errorf("npm calls = %v, want one publish without the token in its arguments", calls)
+	}
+	npmrc, err := os.ReadFile(npmrcLog)
+	if err != nil || !strings.HasSuffix(string(npmrc), strings.TrimPrefix(registryAuthArg(server.URL, "npm_short"), "--")+"\n") {
+		t.Errorf("publish npmrc = %q, %v; want the minted token", npmrc, err)
+	}
+	if strings.Contains(resp.Outputs["stdout"].(string), "npm_short") {
+		t.Error("token leaked into outputs")
+	}
+}
+
+func TestTokenExchangeFailures(t *testing.T) {
+	server, _ := tokenServer(t, http.StatusForbidden)
+	logPath := fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	run := func() *plugin.ExecuteResponse {
+		resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"registry": server.URL, "token_exchange": true},
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+		})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		return resp
+	}
+
+	t.Setenv(adminTokenEnv, "")
+	if resp := run(); resp.Success || !strings.Contains(resp.Error, adminTokenEnv) {
+		t.Errorf("expected missing admin token error, got %+v", resp)
+	}
+	t.Setenv(adminTokenEnv, "admin")
+	if resp := run(); resp.Success || !strings.Contains(resp.Error, "403") {
+		t.Errorf("expected mint failure, got %+v", resp)
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 0 {
+		t.Errorf("npm ran without a publish token: %v", calls)
+	}
+}
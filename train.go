@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Release train defaults, in seconds.
+const (
+	defaultTrainTimeout  = 30 * 60
+	defaultTrainInterval = 10
+)
+
+// Release train signal states.
+const (
+	trainWaiting = "waiting"
+	trainReady   = "ready"
+	trainAborted = "aborted"
+)
+
+// ReleaseTrain holds the upload until the other ecosystems of a
+// multi-language release are ready too, so they go live together or not at
+// all. Each participant signals readiness through a URL or a marker file;
+// the signal is ready once it exists, unless its JSON body carries another
+// "status": "aborted" (or "failed") cancels the publish and anything else
+// keeps waiting.
+type ReleaseTrain struct {
+	// URL is polled until it reports the train ready; a 404 means not yet.
+	URL string `json:"url,omitempty"`
+	// File is a marker path checked until it exists.
+	File string `json:"file,omitempty"`
+	// ReadyFile is written once this package is checked and about to wait,
+	// so other participants can wait on it; it is rewritten as aborted when
+	// the train does not leave.
+	ReadyFile string `json:"ready_file,omitempty"`
+	// TokenEnv names the variable holding a bearer token for URL.
+	TokenEnv string `json:"token_env,omitempty"`
+	// Timeout bounds the wait, in seconds (default 1800).
+	Timeout int `json:"timeout,omitempty"`
+	// Interval is the time between checks, in seconds (default 10).
+	Interval int `json:"interval,omitempty"`
+}
+
+// enabled reports whether a release train is configured.
+func (t ReleaseTrain) enabled() bool {
+	return t.URL != "" || t.File != "" || t.ReadyFile != ""
+}
+
+// trainSignal is the optional JSON body of a URL or marker file.
+type trainSignal struct {
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// validateReleaseTrain checks the signal sources and timing.
+func validateReleaseTrain(t ReleaseTrain) error {
+	if !t.enabled() {
+		if t.TokenEnv != "" || t.Timeout != 0 || t.Interval != 0 {
+			return fmt.Errorf("url, file or ready_file is required")
+		}
+		return nil
+	}
+	if t.URL != "" && t.File != "" {
+		return fmt.Errorf("url and file are mutually exclusive")
+	}
+	data := templateData{}
+	if t.URL != "" {
+		url, err := renderTemplate(t.URL, data)
+		if err != nil {
+			return err
+		}
+		if err := validateEndpointURL(url, "release train url"); err != nil {
+			return err
+		}
+	}
+	if _, err := renderTemplate(t.File, data); err != nil {
+		return err
+	}
+	readyFile, err := renderTemplate(t.ReadyFile, data)
+	if err != nil {
+		return err
+	}
+	if err := validateOutputPath(readyFile); err != nil {
+		return fmt.Errorf("ready_file: %w", err)
+	}
+	if t.TokenEnv != "" {
+		if t.URL == "" {
+			return fmt.Errorf("token_env requires url")
+		}
+		if !inputNamePattern.MatchString(t.TokenEnv) {
+			return fmt.Errorf("token_env %q is not a valid environment variable name", t.TokenEnv)
+		}
+	}
+	if t.Timeout < 0 || t.Interval < 0 {
+		return fmt.Errorf("timeout and interval must not be negative")
+	}
+	return nil
+}
+
+// parseTrainSignal reads a signal body. An empty or non-JSON body, or one
+// without a status, means ready.
+func parseTrainSignal(body []byte) (string, string) {
+	var signal trainSignal
+	if len(strings.TrimSpace(string(body))) == 0 || json.Unmarshal(body, &signal) != nil {
+		return trainReady, ""
+	}
+	switch strings.ToLower(signal.Status) {
+	case "", trainReady:
+		return trainReady, ""
+	case trainAborted, "abort", "failed":
+		return trainAborted, signal.Reason
+	}
+	return trainWaiting, signal.Reason
+}
+
+// trainState checks the URL or marker file once. Errors are transient: the
+// caller keeps waiting and reports the last one on timeout.
+func trainState(ctx context.Context, t ReleaseTrain, url, file string) (string, string, error) {
+	if file != "" {
+		body, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			return trainWaiting, "", nil
+		}
+		if err != nil {
+			return trainWaiting, "", err
+		}
+		state, reason := parseTrainSignal(body)
+		return state, reason, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return trainWaiting, "", fmt.Errorf("failed to create release train request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if t.TokenEnv != "" {
+		if token := os.Getenv(t.TokenEnv); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return trainWaiting, "", fmt.Errorf("release train request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return trainWaiting, "", nil
+	case resp.StatusCode >= 300:
+		return trainWaiting, "", fmt.Errorf("release train returned %d", resp.StatusCode)
+	}
+	state, reason := parseTrainSignal(body)
+	return state, reason, nil
+}
+
+// writeTrainSignal writes this package's signal to the ready file.
+func writeTrainSignal(path string, signal trainSignal) error {
+	data, err := json.MarshalIndent(signal, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal release train signal: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create ready_file directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write ready_file: %w", err)
+	}
+	return nil
+}
+
+// waitForTrain signals this package ready and waits for the rest of the
+// train, returning an error when it is aborted or does not become ready in
+// time. The ready file is then rewritten as aborted, so participants waiting
+// on this package abort too.
+func waitForTrain(ctx context.Context, t ReleaseTrain, data templateData, outputs map[string]any) error {
+	url, err := renderTemplate(t.URL, data)
+	if err != nil {
+		return err
+	}
+	file, err := renderTemplate(t.File, data)
+	if err != nil {
+		return err
+	}
+	readyFile, err := renderTemplate(t.ReadyFile, data)
+	if err != nil {
+		return err
+	}
+	if readyFile != "" {
+		if err := validateOutputPath(readyFile); err != nil {
+			return fmt.Errorf("ready_file: %w", err)
+		}
+		if err := writeTrainSignal(readyFile, trainSignal{Status: trainReady, Name: data.Name, Version: data.Version}); err != nil {
+			return err
+		}
+	}
+	if url == "" && file == "" {
+		return nil
+	}
+
+	err = awaitTrain(ctx, t, url, file, outputs)
+	if err != nil && readyFile != "" {
+		if werr := writeTrainSignal(readyFile, trainSignal{Status: trainAborted, Reason: err.Error(), Name: data.Name, Version: data.Version}); werr != nil {
+			appendWarning(outputs, werr.Error())
+		}
+	}
+	return err
+}
+
+// awaitTrain polls the signal until it is ready, aborted or Timeout passes.
+func awaitTrain(ctx context.Context, t ReleaseTrain, url, file string, outputs map[string]any) error {
+	timeout := time.Duration(t.Timeout) * time.Second
+	if t.Timeout == 0 {
+		timeout = defaultTrainTimeout * time.Second
+	}
+	interval := time.Duration(t.Interval) * time.Second
+	if t.Interval == 0 {
+		interval = defaultTrainInterval * time.Second
+	}
+
+	var lastErr error
+	for waited := time.Duration(0); ; waited += interval {
+		state, reason, err := trainState(ctx, t, url, file)
+		if err != nil {
+			lastErr = err
+		}
+		switch state {
+		case trainReady:
+			outputs["release_train_wait_seconds"] = int(waited / time.Second)
+			return nil
+		case trainAborted:
+			if reason == "" {
+				reason = "no reason given"
+			}
+			return fmt.Errorf("release train aborted: %s", reason)
+		}
+		if waited >= timeout {
+			msg := fmt.Sprintf("release train not ready after %s", timeout)
+			if reason != "" {
+				msg += ": " + reason
+			}
+			if lastErr != nil {
+				msg += fmt.Sprintf(" (last error: %v)", lastErr)
+			}
+			return errors.New(msg)
+		}
+		if err := retrySleep(ctx, interval); err != nil {
+			return fmt.Errorf("waiting for release train: %w", err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateReleaseTrain(t *testing.T) {
+	tests := []struct {
+		name    string
+		train   ReleaseTrain
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "url", train: ReleaseTrain{URL: "https://train.example.com/{{.Version}}", TokenEnv: "TRAIN_TOKEN", Timeout: 600}},
+		{name: "file", train: ReleaseTrain{File: "/shared/pypi-{{.Version}}.ready", ReadyFile: "signals/npm.ready"}},
+		{name: "ready file only", train: ReleaseTrain{ReadyFile: "npm.ready"}},
+		{name: "url and file", train: ReleaseTrain{URL: "https://train.example.com/", File: "x"}, wantErr: true},
+		{name: "http url", train: ReleaseTrain{URL: "http://train.example.com/"}, wantErr: true},
+		{name: "bad template", train: ReleaseTrain{File: "{{.Nope}}"}, wantErr: true},
+		{name: "ready file outside cwd", train: ReleaseTrain{ReadyFile: "../npm.ready"}, wantErr: true},
+		{name: "token env without url", train: ReleaseTrain{File: "x", TokenEnv: "TRAIN_TOKEN"}, wantErr: true},
+		{name: "bad token env", train: ReleaseTrain{URL: "https://train.example.com/", TokenEnv: "TRAIN-TOKEN"}, wantErr: true},
+		{name: "negative timeout", train: ReleaseTrain{File: "x", Timeout: -1}, wantErr: true},
+		{name: "settings without source", train: ReleaseTrain{Timeout: 60}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateReleaseTrain(tt.train); (err != nil) != tt.wantErr {
+				t.Errorf("validateReleaseTrain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseTrainSignal(t *testing.T) {
+	tests := []struct {
+		body       string
+		want       string
+		wantReason string
+	}{
+		{"", trainReady, ""},
+		{"ok\n", trainReady, ""},
+		{`{"version":"1.0.0"}`, trainReady, ""},
+		{`{"status":"READY"}`, trainReady, ""},
+		{`{"status":"pending","reason":"crates.io still uploading"}`, trainWaiting, "crates.io still uploading"},
+		{`{"status":"failed","reason":"PyPI rejected the wheel"}`, trainAborted, "PyPI rejected the wheel"},
+		{`{"status":"aborted"}`, trainAborted, ""},
+	}
+	for _, tt := range tests {
+		if got, reason := parseTrainSignal([]byte(tt.body)); got != tt.want || reason != tt.wantReason {
+			t.Errorf("parseTrainSignal(%q) = %q, %q, want %q, %q", tt.body, got, reason, tt.want, tt.wantReason)
+		}
+	}
+}
+
+func TestReleaseTrainURL(t *testing.T) {
+	sleeps := recordSleeps(t)
+	t.Setenv("TRAIN_TOKEN", "secret")
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/trains/1.2.0" || r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("unexpected request %s %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		polls++
+		switch polls {
+		case 1:
+			w.WriteHeader(http.StatusNotFound)
+		case 2:
+			_, _ = w.Write([]byte(`{"status":"waiting"}`))
+		default:
+			_, _ = w.Write([]byte(`{"status":"ready"}`))
+		}
+	}))
+	defer server.Close()
+
+	outputs := map[string]any{}
+	train := ReleaseTrain{URL: server.URL + "/trains/{{.Version}}", TokenEnv: "TRAIN_TOKEN", Interval: 5}
+	if err := waitForTrain(context.Background(), train, templateData{Name: "lib", Version: "1.2.0"}, outputs); err != nil {
+		t.Fatalf("waitForTrain() error = %v", err)
+	}
+	if polls != 3 || len(*sleeps) != 2 || (*sleeps)[0] != 5*time.Second {
+		t.Errorf("polls = %d, sleeps = %v", polls, *sleeps)
+	}
+	if outputs["release_train_wait_seconds"] != 10 {
+		t.Errorf("release_train_wait_seconds = %v", outputs["release_train_wait_seconds"])
+	}
+}
+
+func TestReleaseTrainTimeout(t *testing.T) {
+	sleeps := recordSleeps(t)
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	train := ReleaseTrain{File: filepath.Join(dir, "pypi.ready"), ReadyFile: "npm.ready", Timeout: 30, Interval: 10}
+	err := waitForTrain(context.Background(), train, templateData{Name: "lib", Version: "1.0.0"}, map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), "not ready after 30s") {
+		t.Fatalf("waitForTrain() error = %v", err)
+	}
+	if len(*sleeps) != 3 {
+		t.Errorf("sleeps = %v", *sleeps)
+	}
+	var signal trainSignal
+	data, _ := os.ReadFile(filepath.Join(dir, "npm.ready"))
+	if err := json.Unmarshal(data, &signal); err != nil || signal.Status != trainAborted || signal.Version != "1.0.0" {
+		t.Errorf("ready_file = %s", data)
+	}
+}
+
+func TestReleaseTrainMarkerAppears(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "crates.ready")
+	orig := retrySleep
+	retrySleep = func(context.Context, time.Duration) error {
+		return os.WriteFile(marker, nil, 0644)
+	}
+	t.Cleanup(func() { retrySleep = orig })
+
+	if err := waitForTrain(context.Background(), ReleaseTrain{File: marker}, templateData{}, map[string]any{}); err != nil {
+		t.Errorf("waitForTrain() error = %v", err)
+	}
+}
+
+func TestReleaseTrainAbortsPublish(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "pypi.ready"), `{"status":"aborted","reason":"PyPI upload failed"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"release_train": map[string]any{"file": "pypi.ready", "ready_file": "npm.ready"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || resp.Error != "release train aborted: PyPI upload failed" {
+		t.Errorf("expected abort, got %+v", resp)
+	}
+	for _, call := range npmCalls(t, logPath) {
+		if strings.HasPrefix(call, "publish") {
+			t.Errorf("published despite abort: %v", call)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "npm.ready")); !strings.Contains(string(data), `"status": "aborted"`) {
+		t.Errorf("ready_file = %s", data)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// transcriptEntry records one executed command for debug_transcript.
+type transcriptEntry struct {
+	Command    string `json:"command"`
+	Dir        string `json:"dir,omitempty"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// transcript collects the commands run while handling one hook.
+type transcript struct {
+	mu      sync.Mutex
+	entries []transcriptEntry
+}
+
+// transcriptKey is the context key holding the active transcript.
+type transcriptKey struct{}
+
+// withTranscript returns a context in which executed commands are recorded
+// into the returned transcript.
+func withTranscript(ctx context.Context) (context.Context, *transcript) {
+	t := &transcript{}
+	return context.WithValue(ctx, transcriptKey{}, t), t
+}
+
+// Entries returns the recorded commands.
+func (t *transcript) Entries() []transcriptEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]transcriptEntry{}, t.entries...)
+}
+
+// recordCommand adds a finished command to the context's transcript, if any.
+func recordCommand(ctx context.Context, dir, name string, args []string, start time.Time, err error) {
+	t, ok := ctx.Value(transcriptKey{}).(*transcript)
+	if !ok {
+		return
+	}
+	entry := transcriptEntry{
+		Command:    strings.Join(append([]string{name}, redactArgs(args)...), " "),
+		Dir:        dir,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		entry.ExitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			entry.ExitCode = exitErr.ExitCode()
+		}
+		entry.Error = err.Error()
+	}
+
+	t.mu.Lock()
+	t.entries = append(t.entries, entry)
+	t.mu.Unlock()
+}
+
+// secretConfigKeys are npmrc keys whose values are credentials when passed
+// as "--key=value" flags.
+var secretConfigKeys = []string{"_authToken=", "_auth=", "_password=", "token="}
+
+// redactArgs returns args with OTPs and inline npmrc credentials replaced.
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = arg
+		if i > 0 && args[i-1] == "--otp" {
+			redacted[i] = "[REDACTED]"
+			continue
+		}
+		if strings.HasPrefix(arg, "--otp=") {
+			redacted[i] = "--otp=[REDACTED]"
+			continue
+		}
+		for _, key := range secretConfigKeys {
+			if idx := strings.Index(arg, key); idx >= 0 && strings.HasPrefix(arg, "-") {
+				redacted[i] = arg[:idx+len(key)] + "[REDACTED]"
+				break
+			}
+		}
+	}
+	return redacted
+}
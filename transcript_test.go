@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestRedactArgs(t *testing.T) {
+	args := []string{"publish", "--otp", "123456", "--otp=654321", "--//localhost:4873/:_authToken=secret", "--tag", "latest"}
+	want := []string{"publish", "--otp", "[REDACTED]", "--otp=[REDACTED]", "--//localhost:4873/:_authToken=[REDACTED]", "--tag", "latest"}
+	if got := redactArgs(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("redactArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestRecordCommand(t *testing.T) {
+	recordCommand(context.Background(), "", "npm", []string{"view"}, time.Now(), nil)
+
+	ctx, tr := withTranscript(context.Background())
+	fakeNpm(t, `[ "$1" = view ] || exit 3`)
+	if _, err := runNpm(ctx, t.TempDir(), "view", "pkg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runNpm(ctx, t.TempDir(), "publish", "--otp", "123456"); err == nil {
+		t.Fatal("expected publish to fail")
+	}
+
+	entries := tr.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	if entries[0].Command != "npm view pkg" || entries[0].ExitCode != 0 || entries[0].Error != "" {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Command != "npm publish --otp [REDACTED]" || entries[1].ExitCode != 3 || entries[1].Error == "" {
+		t.Errorf("unexpected second entry: %+v", entries[1])
+	}
+}
+
+func TestDebugTranscriptOutput(t *testing.T) {
+	fakeNpm(t, `echo '{"id":"pkg@1.0.0"}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"1.0.0"}`)
+	chdir(t, dir)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"debug_transcript": true, "otp": "123456"},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %+v %v", resp, err)
+	}
+	entries, ok := resp.Outputs["transcript"].([]transcriptEntry)
+	if !ok || len(entries) != 1 {
+		t.Fatalf("unexpected transcript: %#v", resp.Outputs["transcript"])
+	}
+	if !strings.HasPrefix(entries[0].Command, "npm publish") || strings.Contains(entries[0].Command, "123456") {
+		t.Errorf("unexpected transcript command: %q", entries[0].Command)
+	}
+}
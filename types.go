@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Naming styles for the types companion package.
+const (
+	typesStyleSuffix = "suffix"
+	typesStyleScope  = "types_scope"
+)
+
+// declarationSuffixes are the files copied into the types package.
+var declarationSuffixes = []string{".d.ts", ".d.mts", ".d.cts"}
+
+// typesManifestInherited are the runtime package.json fields the types
+// package carries over.
+var typesManifestInherited = []string{"license", "repository", "homepage", "bugs", "author"}
+
+// TypesPackage publishes the package's declaration files as a companion
+// package in the same release, at the same version, with an exact peer
+// dependency on the runtime package so the two stay locked together.
+type TypesPackage struct {
+	// Style names the package: "suffix" (default) is "<name>-types",
+	// "types_scope" is "@types/<name>" with a scope folded in as
+	// DefinitelyTyped does ("@acme/lib" becomes "@types/acme__lib").
+	Style string `json:"style,omitempty"`
+	// Name overrides the generated name.
+	Name string `json:"name,omitempty"`
+	// Dir holds the declaration files, relative to the package directory;
+	// it defaults to the package directory itself.
+	Dir string `json:"dir,omitempty"`
+	// Manifest is merged into the generated package.json, e.g. a
+	// description or keywords. name and version cannot be set.
+	Manifest map[string]any `json:"manifest,omitempty"`
+}
+
+// enabled reports whether a types package is configured.
+func (t TypesPackage) enabled() bool {
+	return t.Style != "" || t.Name != "" || t.Dir != "" || len(t.Manifest) > 0
+}
+
+// validateTypesPackage checks the types_package configuration.
+func validateTypesPackage(t TypesPackage) error {
+	switch t.Style {
+	case "", typesStyleSuffix, typesStyleScope:
+	default:
+		return fmt.Errorf("unknown style %q", t.Style)
+	}
+	if t.Dir != "" && !filepath.IsLocal(t.Dir) {
+		return fmt.Errorf("dir %q must be inside the package directory", t.Dir)
+	}
+	for _, key := range []string{"name", "version"} {
+		if _, ok := t.Manifest[key]; ok {
+			return fmt.Errorf("manifest cannot set %q", key)
+		}
+	}
+	return nil
+}
+
+// typesPackageName returns the companion package name for pkgName.
+func typesPackageName(t TypesPackage, pkgName string) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	if t.Style == typesStyleScope {
+		return "@types/" + strings.Replace(strings.TrimPrefix(pkgName, "@"), "/", "__", 1)
+	}
+	return pkgName + "-types"
+}
+
+// isDeclarationFile reports whether name is a TypeScript declaration file.
+func isDeclarationFile(name string) bool {
+	for _, suffix := range declarationSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectDeclarations lists the declaration files under root as slash
+// paths, skipping node_modules and hidden directories.
+func collectDeclarations(root string) ([]string, error) {
+	files, err := collectFiles(root, isDeclarationFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no declaration files in %s", root)
+	}
+	return files, nil
+}
+
+// collectFiles lists the regular files under root whose names keep accepts
+// (all of them when keep is nil) as sorted slash paths, skipping
+// node_modules and hidden directories.
+func collectFiles(root string, keep func(name string) bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && (d.Name() == "node_modules" || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && (keep == nil || keep(d.Name())) {
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// typesEntry returns the types entry point of the companion package: the
+// runtime package's types (or typings) field relative to the declarations
+// directory, falling back to index.d.ts and then the first file.
+func typesEntry(manifest map[string]any, dir string, files []string) string {
+	candidates := []string{"index.d.ts"}
+	for _, key := range []string{"typings", "types"} {
+		if v, ok := manifest[key].(string); ok && v != "" {
+			rel := strings.TrimPrefix(path.Clean(strings.TrimPrefix(v, "./")), path.Clean(filepath.ToSlash(dir))+"/")
+			candidates = append([]string{rel}, candidates...)
+		}
+	}
+	for _, c := range candidates {
+		if containsString(files, c) {
+			return c
+		}
+	}
+	return files[0]
+}
+
+// generatedPackage is a companion package (types or docs) ready to be
+// written: files copied from root plus a generated manifest.
+type generatedPackage struct {
+	name     string
+	root     string
+	files    []string
+	manifest map[string]any
+}
+
+// prepareTypesPackage collects the declaration files and builds the manifest
+// of the types package for pkgName@version, so a missing or empty
+// declarations directory fails before the runtime package is published.
+func prepareTypesPackage(packageDir, pkgName, version string, t TypesPackage) (*generatedPackage, error) {
+	data, err := os.ReadFile(filepath.Join(packageDir, "package.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	var runtime map[string]any
+	if err := json.Unmarshal(data, &runtime); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+	root := filepath.Join(packageDir, t.Dir)
+	files, err := collectDeclarations(root)
+	if err != nil {
+		return nil, err
+	}
+
+	name := typesPackageName(t, pkgName)
+	manifest := map[string]any{
+		"description": fmt.Sprintf("TypeScript declarations for %s", pkgName),
+	}
+	for _, key := range typesManifestInherited {
+		if v, ok := runtime[key]; ok {
+			manifest[key] = v
+		}
+	}
+	for k, v := range t.Manifest {
+		manifest[k] = v
+	}
+	peers := map[string]any{}
+	if existing, ok := manifest["peerDependencies"].(map[string]any); ok {
+		for k, v := range existing {
+			peers[k] = v
+		}
+	}
+	peers[pkgName] = version
+	manifest["peerDependencies"] = peers
+	manifest["name"] = name
+	manifest["version"] = version
+	manifest["types"] = typesEntry(runtime, t.Dir, files)
+	return &generatedPackage{name: name, root: root, files: files, manifest: manifest}, nil
+}
+
+// write lays out the package in dir.
+func (tp *generatedPackage) write(dir string) error {
+	for _, f := range tp.files {
+		data, err := os.ReadFile(filepath.Join(tp.root, filepath.FromSlash(f)))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f, err)
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(dest), err)
+		}
+		if err := os.WriteFile(dest, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f, err)
+		}
+	}
+	data, err := json.MarshalIndent(tp.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal package.json: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write package.json: %w", err)
+	}
+	return nil
+}
+
+// publishTypesPackage generates and publishes the types package. It goes out
+// after the runtime package so its peer dependency resolves.
+func publishTypesPackage(ctx context.Context, cfg *Config, tp *generatedPackage) error {
+	dir, err := os.MkdirTemp("", "relicta-npm-types-")
+	if err != nil {
+		return fmt.Errorf("failed to create types package directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	if err := tp.write(dir); err != nil {
+		return err
+	}
+	if err := publishGeneratedPackage(ctx, cfg, dir); err != nil {
+		return fmt.Errorf("failed to publish %s: %w", tp.name, err)
+	}
+	return nil
+}
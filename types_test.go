@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestTypesPackageName(t *testing.T) {
+	tests := []struct {
+		types TypesPackage
+		pkg   string
+		want  string
+	}{
+		{TypesPackage{}, "lib", "lib-types"},
+		{TypesPackage{Style: typesStyleSuffix}, "@acme/lib", "@acme/lib-types"},
+		{TypesPackage{Style: typesStyleScope}, "lib", "@types/lib"},
+		{TypesPackage{Style: typesStyleScope}, "@acme/lib", "@types/acme__lib"},
+		{TypesPackage{Style: typesStyleScope, Name: "@acme/lib-typings"}, "@acme/lib", "@acme/lib-typings"},
+	}
+	for _, tt := range tests {
+		if got := typesPackageName(tt.types, tt.pkg); got != tt.want {
+			t.Errorf("typesPackageName(%+v, %q) = %q, want %q", tt.types, tt.pkg, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTypesPackage(t *testing.T) {
+	tests := []struct {
+		name    string
+		types   TypesPackage
+		wantErr bool
+	}{
+		{name: "defaults", types: TypesPackage{Dir: "dist"}},
+		{name: "unknown style", types: TypesPackage{Style: "prefix"}, wantErr: true},
+		{name: "dir escapes", types: TypesPackage{Dir: "../types"}, wantErr: true},
+		{name: "manifest version", types: TypesPackage{Manifest: map[string]any{"version": "0.0.0"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateTypesPackage(tt.types); (err != nil) != tt.wantErr {
+				t.Errorf("validateTypesPackage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrepareTypesPackage(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"@acme/lib","version":"2.0.0","license":"MIT","types":"./dist/main.d.ts","dependencies":{"x":"1"}}`)
+	writeFile(t, filepath.Join(dir, "dist", "main.d.ts"), "export {};\n")
+	writeFile(t, filepath.Join(dir, "dist", "util", "fmt.d.mts"), "export {};\n")
+	writeFile(t, filepath.Join(dir, "dist", "main.js"), "")
+	writeFile(t, filepath.Join(dir, "dist", "node_modules", "dep", "index.d.ts"), "")
+
+	tp, err := prepareTypesPackage(dir, "@acme/lib", "2.0.0", TypesPackage{Dir: "dist", Manifest: map[string]any{"keywords": []any{"types"}}})
+	if err != nil {
+		t.Fatalf("prepareTypesPackage() error = %v", err)
+	}
+	if want := []string{"main.d.ts", "util/fmt.d.mts"}; !reflect.DeepEqual(tp.files, want) {
+		t.Errorf("files = %v, want %v", tp.files, want)
+	}
+	want := map[string]any{
+		"name":             "@acme/lib-types",
+		"version":          "2.0.0",
+		"description":      "TypeScript declarations for @acme/lib",
+		"license":          "MIT",
+		"keywords":         []any{"types"},
+		"types":            "main.d.ts",
+		"peerDependencies": map[string]any{"@acme/lib": "2.0.0"},
+	}
+	if !reflect.DeepEqual(tp.manifest, want) {
+		t.Errorf("manifest = %v, want %v", tp.manifest, want)
+	}
+
+	if _, err := prepareTypesPackage(dir, "@acme/lib", "2.0.0", TypesPackage{Dir: "dist/util/none"}); err == nil {
+		t.Error("prepareTypesPackage() accepted a missing directory")
+	}
+	writeFile(t, filepath.Join(dir, "empty", "a.js"), "")
+	if _, err := prepareTypesPackage(dir, "@acme/lib", "2.0.0", TypesPackage{Dir: "empty"}); err == nil || !strings.Contains(err.Error(), "no declaration files") {
+		t.Errorf("prepareTypesPackage() error = %v, want no declaration files", err)
+	}
+}
+
+func TestPublishTypesPackage(t *testing.T) {
+	logPath := fakeNpm(t, `[ "$1" = publish ] && grep -o '"name": *"[^"]*"' package.json | head -1 >> "$(dirname "$0")/published.log"
+echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name": "lib", "version": "1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "index.d.ts"), "export {};\n")
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"types_package": map[string]any{"style": "types_scope"}},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success || resp.Outputs["types_package"] != "@types/lib" {
+		t.Fatalf("unexpected response: %v %+v", err, resp)
+	}
+	published := npmCalls(t, filepath.Join(filepath.Dir(logPath), "published.log"))
+	if want := []string{`"name": "lib"`, `"name": "@types/lib"`}; !reflect.DeepEqual(published, want) {
+		t.Errorf("published = %v, want the runtime package then its types", published)
+	}
+}
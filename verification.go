@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Verification checks run after publishing.
+const (
+	verificationVersionVisible = "version_visible"
+	verificationTagCorrect     = "tag_correct"
+	verificationInstall        = "install"
+	verificationProvenance     = "provenance"
+)
+
+// verificationChecks lists the checks in the order they run: the version
+// must be visible before its metadata or tarball can be checked.
+var verificationChecks = []string{verificationVersionVisible, verificationTagCorrect, verificationInstall, verificationProvenance}
+
+// Verification verdicts.
+const (
+	verdictPassed   = "passed"
+	verdictDegraded = "passed_with_warnings"
+	verdictFailed   = "failed"
+)
+
+// Verification selects the post-publish checks that decide whether a
+// release succeeded. Required checks fail post-publish; advisory checks
+// only add warnings.
+type Verification struct {
+	Required []string `json:"required,omitempty"`
+	Advisory []string `json:"advisory,omitempty"`
+}
+
+// enabled reports whether any check is configured.
+func (v Verification) enabled() bool {
+	return len(v.Required) > 0 || len(v.Advisory) > 0
+}
+
+// verificationResult is one check's entry in the verdict output.
+type verificationResult struct {
+	Check    string `json:"check"`
+	Required bool   `json:"required"`
+	Passed   bool   `json:"passed"`
+	Error    string `json:"error,omitempty"`
+}
+
+// verificationVerdict combines the check results into the verification
+// output.
+type verificationVerdict struct {
+	Verdict string               `json:"verdict"`
+	Checks  []verificationResult `json:"checks"`
+}
+
+// validateVerification rejects unknown checks and checks listed twice.
+func validateVerification(v Verification) error {
+	seen := map[string]string{}
+	for _, list := range []struct {
+		name   string
+		checks []string
+	}{{"required", v.Required}, {"advisory", v.Advisory}} {
+		for _, check := range list.checks {
+			if !containsString(verificationChecks, check) {
+				return fmt.Errorf("unknown check %q in %s, expected one of %s", check, list.name, strings.Join(verificationChecks, ", "))
+			}
+			if other, ok := seen[check]; ok {
+				return fmt.Errorf("check %q is listed in both %s and %s", check, other, list.name)
+			}
+			seen[check] = list.name
+		}
+	}
+	return nil
+}
+
+// verifyRelease runs the configured checks against the registry and
+// combines them into a verdict, which fails only when a required check
+// does.
+func verifyRelease(ctx context.Context, cfg *Config, name, version string) verificationVerdict {
+	required := map[string]bool{}
+	for _, check := range cfg.Verification.Required {
+		required[check] = true
+	}
+	selected := map[string]bool{}
+	for _, check := range append(append([]string{}, cfg.Verification.Required...), cfg.Verification.Advisory...) {
+		selected[check] = true
+	}
+
+	verdict := verificationVerdict{Verdict: verdictPassed}
+	var doc *packument
+	var docErr error
+	for _, check := range verificationChecks {
+		if !selected[check] {
+			continue
+		}
+		var err error
+		switch check {
+		case verificationVersionVisible:
+			_, err = waitForVersion(ctx, cfg, name, version, time.Duration(cfg.VerifyTimeout)*time.Second)
+		case verificationTagCorrect, verificationProvenance:
+			if doc == nil && docErr == nil {
+				doc, docErr = fetchPackument(ctx, registryURL(cfg), name)
+			}
+			err = docErr
+			if err == nil {
+				err = checkPublishedMetadata(doc, check, cfg.Tag, version)
+			}
+		case verificationInstall:
+			installCfg := *cfg
+			installCfg.Registry = registryURL(cfg)
+			err = verifyInstall(ctx, &installCfg, name, version)
+		}
+		result := verificationResult{Check: check, Required: required[check], Passed: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+			switch {
+			case result.Required:
+				verdict.Verdict = verdictFailed
+			case verdict.Verdict == verdictPassed:
+				verdict.Verdict = verdictDegraded
+			}
+		}
+		verdict.Checks = append(verdict.Checks, result)
+	}
+	return verdict
+}
+
+// checkPublishedMetadata checks the dist-tag or provenance of the
+// published version in the packument.
+func checkPublishedMetadata(doc *packument, check, tag, version string) error {
+	published, ok := doc.Versions[version]
+	if !ok {
+		return fmt.Errorf("version %s not found in registry", version)
+	}
+	if check == verificationTagCorrect {
+		if got := doc.DistTags[tag]; got != version {
+			return fmt.Errorf("dist-tag %q points at %q, expected %q", tag, got, version)
+		}
+		return nil
+	}
+	if len(published.Dist.Attestations) == 0 || string(published.Dist.Attestations) == "null" {
+		return fmt.Errorf("no provenance attestation attached to %s", version)
+	}
+	return nil
+}
+
+// failedChecks returns the failed checks, required or advisory.
+func (v verificationVerdict) failedChecks(required bool) []string {
+	var failed []string
+	for _, result := range v.Checks {
+		if !result.Passed && result.Required == required {
+			failed = append(failed, fmt.Sprintf("%s: %s", result.Check, result.Error))
+		}
+	}
+	return failed
+}
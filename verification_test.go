@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateVerification(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       Verification
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "split", v: Verification{Required: []string{"version_visible", "tag_correct"}, Advisory: []string{"install", "provenance"}}},
+		{name: "unknown", v: Verification{Required: []string{"signature"}}, wantErr: true},
+		{name: "both lists", v: Verification{Required: []string{"install"}, Advisory: []string{"install"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateVerification(tt.v); (err != nil) != tt.wantErr {
+				t.Errorf("validateVerification() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerification(t *testing.T) {
+	tests := []struct {
+		name         string
+		verification map[string]any
+		attestations string
+		wantVerdict  string
+		wantErr      string
+	}{
+		{
+			name:         "all required pass",
+			verification: map[string]any{"required": []any{"version_visible", "tag_correct", "install", "provenance"}},
+			attestations: `{"url":"https://registry.example.com/-/npm/v1/attestations/lib@1.0.0"}`,
+			wantVerdict:  verdictPassed,
+		},
+		{
+			name:         "advisory provenance missing",
+			verification: map[string]any{"required": []any{"version_visible", "tag_correct"}, "advisory": []any{"provenance"}},
+			wantVerdict:  verdictDegraded,
+		},
+		{
+			name:         "required provenance missing",
+			verification: map[string]any{"required": []any{"provenance"}},
+			wantVerdict:  verdictFailed,
+			wantErr:      "verification failed for lib@1.0.0: provenance: no provenance attestation attached to 1.0.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version := packumentVersion{Version: "1.0.0"}
+			if tt.attestations != "" {
+				version.Dist.Attestations = json.RawMessage(tt.attestations)
+			}
+			server := newTestRegistry(t, map[string]*packument{"lib": {
+				Name:     "lib",
+				DistTags: map[string]string{"latest": "1.0.0"},
+				Versions: map[string]packumentVersion{"1.0.0": version},
+			}})
+			fakeNpm(t, `if [ "$1" = install ]; then
+  mkdir -p node_modules/lib && echo '{"name":"lib","version":"1.0.0"}' > node_modules/lib/package.json
+fi
+echo '{}'`)
+			t.Setenv("TMPDIR", t.TempDir())
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+			chdir(t, dir)
+
+			resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  map[string]any{"registry": server.URL, "verification": tt.verification},
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			verdict, _ := resp.Outputs["verification"].(verificationVerdict)
+			if verdict.Verdict != tt.wantVerdict {
+				t.Errorf("verdict = %+v, want %s", verdict, tt.wantVerdict)
+			}
+			if resp.Error != tt.wantErr || resp.Success != (tt.wantErr == "") {
+				t.Errorf("error = %q, want %q", resp.Error, tt.wantErr)
+			}
+			warnings, _ := resp.Outputs["warnings"].([]string)
+			if degraded := tt.wantVerdict == verdictDegraded; degraded != (len(warnings) == 1 && strings.HasPrefix(warnings[0], "advisory verification failed: provenance")) {
+				t.Errorf("warnings = %v", warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// publishResult is the subset of `npm publish --json` output the plugin uses.
+type publishResult struct {
+	Name         string     `json:"name"`
+	Version      string     `json:"version"`
+	Filename     string     `json:"filename"`
+	Size         int64      `json:"size"`
+	UnpackedSize int64      `json:"unpackedSize"`
+	Shasum       string     `json:"shasum"`
+	Integrity    string     `json:"integrity"`
+	Files        []packFile `json:"files"`
+}
+
+// parsePublishOutput extracts the publish result from `npm publish --json`.
+// Depending on the npm version the result is either the top-level object or
+// nested under the package name.
+func parsePublishOutput(stdout, name string) publishResult {
+	var result publishResult
+	if err := json.Unmarshal([]byte(stdout), &result); err == nil && result.Integrity != "" {
+		return result
+	}
+
+	var nested map[string]publishResult
+	if err := json.Unmarshal([]byte(stdout), &nested); err == nil {
+		if r, ok := nested[name]; ok {
+			return r
+		}
+	}
+	return publishResult{}
+}
+
+// verifyLatest runs on release success and asserts that the configured
+// dist-tag still points at the version just published and that the registry
+// holds the tarball that was uploaded. It catches concurrent publishes from
+// other pipelines racing this release.
+func (p *NpmPlugin) verifyLatest(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
+	if !cfg.VerifyLatest {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: "Release verification disabled",
+		}, nil
+	}
+
+	if err := p.validateConfig(cfg); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("configuration validation failed: %v", err),
+		}, nil
+	}
+
+	packageDir, err := validatePackageDir(cfg.PackageDir)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid package directory: %v", err),
+		}, nil
+	}
+
+	pkg, err := readPackageJSON(packageDir)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	if pkg.Private {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: "Package is private, skipping release verification",
+		}, nil
+	}
+	cfg = scopedConfig(cfg, pkg.Name)
+
+	if dryRun {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("Would verify dist-tag %q points at %s@%s", cfg.Tag, pkg.Name, releaseCtx.Version),
+		}, nil
+	}
+
+	rec, err := loadRecord(cfg, pkg.Name)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	outputs := map[string]any{}
+	var doc *packument
+	if cfg.ReplicationLagThreshold > 0 {
+		since := time.Now()
+		if rec != nil && rec.Version == releaseCtx.Version && !rec.PublishedAt.IsZero() {
+			since = rec.PublishedAt
+		}
+		doc, err = checkReplicationLag(ctx, cfg, outputs, pkg.Name, releaseCtx.Version, since)
+	} else {
+		doc, err = fetchPackument(ctx, registryURL(cfg), pkg.Name)
+	}
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to fetch %s from registry: %v", pkg.Name, err),
+			Outputs: outputs,
+		}, nil
+	}
+
+	var problems []string
+	if got := doc.DistTags[cfg.Tag]; got != releaseCtx.Version {
+		problems = append(problems, fmt.Sprintf("dist-tag %q points at %q, expected %q", cfg.Tag, got, releaseCtx.Version))
+	}
+
+	integrityChecked := false
+	published, ok := doc.Versions[releaseCtx.Version]
+	switch {
+	case !ok:
+		problems = append(problems, fmt.Sprintf("version %s not found in registry", releaseCtx.Version))
+	case rec != nil && rec.Version == releaseCtx.Version && rec.Integrity != "":
+		integrityChecked = true
+		if published.Dist.Integrity != rec.Integrity {
+			problems = append(problems, fmt.Sprintf("tarball integrity %q does not match uploaded %q", published.Dist.Integrity, rec.Integrity))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("release verification failed for %s@%s: %s", pkg.Name, releaseCtx.Version, strings.Join(problems, "; ")),
+			Outputs: outputs,
+		}, nil
+	}
+
+	outputs["package"] = pkg.Name
+	outputs["version"] = releaseCtx.Version
+	outputs["tag"] = cfg.Tag
+	outputs["integrity"] = published.Dist.Integrity
+	outputs["integrity_checked"] = integrityChecked
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Verified %s@%s is %q in the registry", pkg.Name, releaseCtx.Version, cfg.Tag),
+		Outputs: outputs,
+	}, nil
+}
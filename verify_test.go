@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestParsePublishOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		stdout string
+		want   string
+	}{
+		{"top_level", `{"name":"pkg","version":"1.0.0","integrity":"sha512-abc"}`, "sha512-abc"},
+		{"nested", `{"pkg":{"name":"pkg","version":"1.0.0","integrity":"sha512-def"}}`, "sha512-def"},
+		{"not_json", "+ pkg@1.0.0", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePublishOutput(tt.stdout, "pkg").Integrity; got != tt.want {
+				t.Errorf("integrity = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyLatest(t *testing.T) {
+	p := &NpmPlugin{}
+	ctx := context.Background()
+	t.Setenv("TMPDIR", t.TempDir())
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name":"pkg","version":"1.2.3"}`), 0644); err != nil {
+		t.Fatalf("failed to write package.json: %v", err)
+	}
+	chdir(t, tmpDir)
+
+	server := newTestRegistry(t, map[string]*packument{
+		"pkg": {
+			Name:     "pkg",
+			DistTags: map[string]string{"latest": "1.2.3", "next": "2.0.0-beta.1"},
+			Versions: map[string]packumentVersion{
+				"1.2.3":        {Version: "1.2.3", Dist: packumentDist{Integrity: "sha512-good"}},
+				"2.0.0-beta.1": {Version: "2.0.0-beta.1"},
+			},
+		},
+	})
+	releaseCtx := plugin.ReleaseContext{Version: "1.2.3"}
+
+	tests := []struct {
+		name      string
+		tag       string
+		integrity string
+		wantOK    bool
+		wantError string
+	}{
+		{"matches", "latest", "sha512-good", true, ""},
+		{"no_record_integrity", "latest", "", true, ""},
+		{"tag_moved", "next", "sha512-good", false, "dist-tag"},
+		{"integrity_mismatch", "latest", "sha512-other", false, "integrity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Registry: server.URL, Tag: tt.tag, VerifyLatest: true}
+			if err := saveRecord(cfg, &publishRecord{Name: "pkg", Version: "1.2.3", Integrity: tt.integrity, PublishedAt: time.Now()}); err != nil {
+				t.Fatalf("saveRecord returned error: %v", err)
+			}
+
+			resp, err := p.verifyLatest(ctx, cfg, releaseCtx, false)
+			if err != nil {
+				t.Fatalf("verifyLatest returned error: %v", err)
+			}
+			if resp.Success != tt.wantOK {
+				t.Fatalf("Success = %v, want %v (error: %s)", resp.Success, tt.wantOK, resp.Error)
+			}
+			if !tt.wantOK && !strings.Contains(resp.Error, tt.wantError) {
+				t.Errorf("expected error containing %q, got %q", tt.wantError, resp.Error)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		resp, _ := p.verifyLatest(ctx, &Config{Tag: "latest"}, releaseCtx, false)
+		if !resp.Success || resp.Message != "Release verification disabled" {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+	})
+}
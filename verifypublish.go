@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// verifyPublishPollInterval is how often verify_publish re-fetches the
+// packument. It is a variable so tests can shorten it.
+var verifyPublishPollInterval = 2 * time.Second
+
+// waitForVersion polls the registry metadata until it lists version, giving
+// up after timeout. It returns how long the version took to appear.
+// Registries are eventually consistent, so downstream jobs installing the
+// release right away could otherwise miss it.
+func waitForVersion(ctx context.Context, cfg *Config, name, version string, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	deadline := start.Add(timeout)
+	for {
+		doc, err := fetchPackument(ctx, registryURL(cfg), name)
+		if err != nil && !errors.Is(err, errPackageNotFound) {
+			return 0, err
+		}
+		if doc != nil {
+			if _, ok := doc.Versions[version]; ok {
+				return time.Since(start), nil
+			}
+		}
+		if !time.Now().Before(deadline) {
+			return 0, fmt.Errorf("%s@%s did not appear in the registry within %s", name, version, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(verifyPublishPollInterval):
+		}
+	}
+}
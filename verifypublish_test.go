@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestVerifyPublish(t *testing.T) {
+	orig := verifyPublishPollInterval
+	verifyPublishPollInterval = time.Millisecond
+	t.Cleanup(func() { verifyPublishPollInterval = orig })
+
+	tests := []struct {
+		name    string
+		visible int32 // fetch from which the version is listed, 0 for never
+		timeout int
+		wantErr bool
+	}{
+		{name: "eventually visible", visible: 3, timeout: 10},
+		{name: "never visible", timeout: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetches atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := fetches.Add(1)
+				if tt.visible == 0 || n < tt.visible {
+					_, _ = w.Write([]byte(`{"name":"lib","versions":{"0.9.0":{}}}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"name":"lib","versions":{"0.9.0":{},"1.0.0":{}}}`))
+			}))
+			t.Cleanup(server.Close)
+			fakeNpm(t, `echo '{}'`)
+			t.Setenv("TMPDIR", t.TempDir())
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.0.0"}`)
+			chdir(t, dir)
+
+			resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  map[string]any{"registry": server.URL, "verify_publish": true, "verify_timeout": tt.timeout},
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr {
+				if resp.Success || !strings.Contains(resp.Error, "did not appear in the registry") {
+					t.Fatalf("expected verification failure, got %+v", resp)
+				}
+				return
+			}
+			if !resp.Success || resp.Outputs["verify_publish_seconds"] == nil {
+				t.Fatalf("unexpected response: %+v", resp)
+			}
+			if fetches.Load() != tt.visible {
+				t.Errorf("fetches = %d, want %d", fetches.Load(), tt.visible)
+			}
+		})
+	}
+}
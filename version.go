@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Version sources supported by version_source.
+const (
+	versionSourceContext     = "context"
+	versionSourcePackageJSON = "package_json"
+	versionSourceEnv         = "env"
+	versionSourceCommand     = "command"
+)
+
+// resolveVersion returns the release context with Version replaced according
+// to cfg.VersionSource, then cfg.VersionTemplate. The resolved version must
+// be strict semver.
+func resolveVersion(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext) (plugin.ReleaseContext, error) {
+	var version string
+
+	switch cfg.VersionSource {
+	case "", versionSourceContext:
+		return applyVersionTemplate(cfg, releaseCtx, time.Now())
+
+	case versionSourcePackageJSON:
+		packageDir, err := validatePackageDir(cfg.PackageDir)
+		if err != nil {
+			return releaseCtx, fmt.Errorf("invalid package directory: %w", err)
+		}
+		pkg, err := readPackageJSON(packageDir)
+		if err != nil {
+			return releaseCtx, err
+		}
+		version = pkg.Version
+
+	case versionSourceEnv:
+		if cfg.VersionEnv == "" {
+			return releaseCtx, fmt.Errorf("version_env is required when version_source is %q", versionSourceEnv)
+		}
+		version = os.Getenv(cfg.VersionEnv)
+		if version == "" {
+			return releaseCtx, fmt.Errorf("environment variable %s is not set", cfg.VersionEnv)
+		}
+
+	case versionSourceCommand:
+		if len(cfg.VersionCommand) == 0 {
+			return releaseCtx, fmt.Errorf("version_command is required when version_source is %q", versionSourceCommand)
+		}
+		packageDir, err := validatePackageDir(cfg.PackageDir)
+		if err != nil {
+			return releaseCtx, fmt.Errorf("invalid package directory: %w", err)
+		}
+
+		// Run the argv directly (no shell) so config cannot inject commands
+		cmd := exec.CommandContext(ctx, cfg.VersionCommand[0], cfg.VersionCommand[1:]...)
+		cmd.Dir = packageDir
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		start := time.Now()
+		err = cmd.Run()
+		recordCommand(ctx, packageDir, cfg.VersionCommand[0], cfg.VersionCommand[1:], start, err)
+		if err != nil {
+			return releaseCtx, fmt.Errorf("version_command failed: %v\nstderr: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		version = strings.TrimSpace(stdout.String())
+
+	default:
+		return releaseCtx, fmt.Errorf("unknown version_source %q", cfg.VersionSource)
+	}
+
+	parsed, err := parseSemver(version)
+	if err != nil {
+		return releaseCtx, fmt.Errorf("version from %s: %w", cfg.VersionSource, err)
+	}
+	releaseCtx.Version = parsed.String()
+	return applyVersionTemplate(cfg, releaseCtx, time.Now())
+}
+
+// expectCurrentPrevious is the expect_current_version value (also set by
+// "true") meaning the release's previous version.
+const expectCurrentPrevious = "previous"
+
+// checkCurrentVersion verifies package.json holds the expected version before
+// it is overwritten: the previous release version or a configured literal.
+// The new version is also accepted so a re-run after a partial failure
+// passes. Any other value means the version was edited out of band.
+func checkCurrentVersion(cfg *Config, releaseCtx plugin.ReleaseContext, current string) error {
+	expected := cfg.ExpectCurrentVersion
+	if expected == "" {
+		return nil
+	}
+	if expected == expectCurrentPrevious {
+		expected = releaseCtx.PreviousVersion
+		if expected == "" {
+			return fmt.Errorf("expect_current_version requires the previous release version")
+		}
+	}
+
+	if sameVersion(current, expected) || sameVersion(current, releaseCtx.Version) {
+		return nil
+	}
+	return fmt.Errorf("package.json version is %q, expected %q; it was changed outside the release pipeline", current, expected)
+}
+
+// sameVersion compares versions semantically when both parse (so "v1.2.3"
+// equals "1.2.3"), otherwise literally.
+func sameVersion(a, b string) bool {
+	va, errA := parseSemver(a)
+	vb, errB := parseSemver(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return va.Compare(vb) == 0 && va.Build == vb.Build
+}
+
+// Modes for version_check.
+const (
+	versionCheckLenient = "lenient"
+	versionCheckStrict  = "strict"
+	versionCheckOff     = "off"
+)
+
+// checkPackageVersion verifies that package.json already holds the release
+// version when update_version is disabled, as npm publishes whatever it
+// holds. strict compares the strings; lenient compares them as semver,
+// ignoring a "v" prefix and build metadata.
+func checkPackageVersion(cfg *Config, releaseCtx plugin.ReleaseContext, current string) error {
+	if cfg.UpdateVersion || cfg.VersionCheck == versionCheckOff || cfg.inputTarball != "" {
+		return nil
+	}
+	if current == releaseCtx.Version {
+		return nil
+	}
+	if cfg.VersionCheck != versionCheckStrict {
+		a, errA := parseSemver(current)
+		b, errB := parseSemver(releaseCtx.Version)
+		if errA == nil && errB == nil && a.Compare(b) == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("package.json version is %q but the release version is %q and update_version is disabled; update package.json or enable update_version", current, releaseCtx.Version)
+}
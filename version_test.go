@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestResolveVersion(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name":"pkg","version":"3.1.4"}`), 0644); err != nil {
+		t.Fatalf("failed to write package.json: %v", err)
+	}
+	chdir(t, tmpDir)
+	t.Setenv("PKG_VERSION", "v2.0.0-rc.1")
+	t.Setenv("BAD_VERSION", "nightly")
+
+	releaseCtx := plugin.ReleaseContext{Version: "1.0.0", TagName: "v1.0.0"}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    string
+		wantErr bool
+	}{
+		{"default_context", Config{}, "1.0.0", false},
+		{"package_json", Config{VersionSource: "package_json"}, "3.1.4", false},
+		{"env", Config{VersionSource: "env", VersionEnv: "PKG_VERSION"}, "2.0.0-rc.1", false},
+		{"env_not_semver", Config{VersionSource: "env", VersionEnv: "BAD_VERSION"}, "", true},
+		{"env_unset", Config{VersionSource: "env", VersionEnv: "UNSET_VERSION_VAR"}, "", true},
+		{"env_missing_name", Config{VersionSource: "env"}, "", true},
+		{"command_missing", Config{VersionSource: "command"}, "", true},
+		{"unknown", Config{VersionSource: "git"}, "", true},
+		{"command", Config{VersionSource: "command", VersionCommand: []string{"echo", "4.5.6"}}, "4.5.6", false},
+		{"command_fails", Config{VersionSource: "command", VersionCommand: []string{"false"}}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cfg.VersionCommand != nil && runtime.GOOS == "windows" {
+				t.Skip("requires POSIX echo/false")
+			}
+			got, err := resolveVersion(ctx, &tt.cfg, releaseCtx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Version != tt.want {
+				t.Errorf("Version = %q, want %q", got.Version, tt.want)
+			}
+			if got.TagName != "v1.0.0" {
+				t.Errorf("other context fields should be preserved, got TagName %q", got.TagName)
+			}
+		})
+	}
+}
+
+func TestCheckCurrentVersion(t *testing.T) {
+	releaseCtx := plugin.ReleaseContext{Version: "1.3.0", PreviousVersion: "v1.2.0"}
+
+	tests := []struct {
+		name    string
+		expect  string
+		current string
+		wantErr bool
+	}{
+		{"disabled", "", "9.9.9", false},
+		{"previous_matches", expectCurrentPrevious, "1.2.0", false},
+		{"already_updated", expectCurrentPrevious, "1.3.0", false},
+		{"drifted", expectCurrentPrevious, "1.2.5", true},
+		{"literal", "0.0.0-development", "0.0.0-development", false},
+		{"literal_mismatch", "0.0.0-development", "1.2.0", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCurrentVersion(&Config{ExpectCurrentVersion: tt.expect}, releaseCtx, tt.current)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkCurrentVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := checkCurrentVersion(&Config{ExpectCurrentVersion: expectCurrentPrevious}, plugin.ReleaseContext{Version: "1.0.0"}, "0.9.0"); err == nil {
+		t.Error("expected error without a previous version")
+	}
+}
+
+func TestExpectCurrentVersionConfig(t *testing.T) {
+	p := &NpmPlugin{}
+	if got := p.parseConfig(map[string]any{"expect_current_version": true}).ExpectCurrentVersion; got != expectCurrentPrevious {
+		t.Errorf("true parsed as %q", got)
+	}
+	if got := p.parseConfig(map[string]any{"expect_current_version": "1.0.0"}).ExpectCurrentVersion; got != "1.0.0" {
+		t.Errorf("literal parsed as %q", got)
+	}
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(`{"name":"pkg","version":"2.0.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, tmpDir)
+
+	cfg := &Config{UpdateVersion: true, ExpectCurrentVersion: expectCurrentPrevious}
+	resp, _ := p.updatePackageVersion(context.Background(), cfg, plugin.ReleaseContext{Version: "1.1.0", PreviousVersion: "1.0.0"}, false)
+	if resp.Success || !strings.Contains(resp.Error, "changed outside the release pipeline") {
+		t.Errorf("expected drift failure, got %+v", resp)
+	}
+}
+
+func TestCheckPackageVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		current string
+		wantErr bool
+	}{
+		{name: "update enabled", cfg: Config{UpdateVersion: true}, current: "0.9.0"},
+		{name: "match", current: "1.0.0"},
+		{name: "mismatch", current: "0.9.0", wantErr: true},
+		{name: "lenient prefix", current: "v1.0.0"},
+		{name: "lenient build", current: "1.0.0+sha.abc"},
+		{name: "strict prefix", cfg: Config{VersionCheck: versionCheckStrict}, current: "v1.0.0", wantErr: true},
+		{name: "off", cfg: Config{VersionCheck: versionCheckOff}, current: "0.9.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPackageVersion(&tt.cfg, plugin.ReleaseContext{Version: "1.0.0"}, tt.current)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkPackageVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublishVersionMismatch(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"0.9.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"update_version": false},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !strings.HasPrefix(resp.Error, `package.json version is "0.9.0" but the release version is "1.0.0"`) {
+		t.Errorf("expected version mismatch, got %+v", resp)
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 0 {
+		t.Errorf("npm called: %v", calls)
+	}
+}
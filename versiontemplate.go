@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// versionTemplateRef matches a version template reference: "${name}",
+// "${name:offset}" or "${name:offset:length}", taking a substring like the
+// shell does.
+var versionTemplateRef = regexp.MustCompile(`\$\{([a-z_]+)(?::(\d+)(?::(\d+))?)?\}`)
+
+// versionTemplateUnsafe matches the characters a branch name may hold that
+// a semver identifier cannot.
+var versionTemplateUnsafe = regexp.MustCompile(`[^0-9A-Za-z.-]`)
+
+// versionTemplateVars returns the values a version template can reference.
+func versionTemplateVars(releaseCtx plugin.ReleaseContext, now time.Time) map[string]string {
+	now = now.UTC()
+	return map[string]string{
+		"version":          releaseCtx.Version,
+		"previous_version": releaseCtx.PreviousVersion,
+		"release_type":     releaseCtx.ReleaseType,
+		"branch":           versionTemplateUnsafe.ReplaceAllString(releaseCtx.Branch, "-"),
+		"sha":              releaseCtx.CommitSHA,
+		"date":             now.Format("20060102"),
+		"timestamp":        now.Format("20060102150405"),
+	}
+}
+
+// validateVersionTemplate checks that a version template only references
+// known variables.
+func validateVersionTemplate(text string) error {
+	_, err := renderVersionTemplate(text, versionTemplateVars(plugin.ReleaseContext{}, time.Time{}))
+	return err
+}
+
+// renderVersionTemplate substitutes the references in text. Substrings
+// outside the value are clamped to it, as in the shell.
+func renderVersionTemplate(text string, vars map[string]string) (string, error) {
+	var unknown []string
+	out := versionTemplateRef.ReplaceAllStringFunc(text, func(ref string) string {
+		m := versionTemplateRef.FindStringSubmatch(ref)
+		value, ok := vars[m[1]]
+		if !ok {
+			unknown = append(unknown, m[1])
+			return ref
+		}
+		if m[2] != "" {
+			offset, _ := strconv.Atoi(m[2])
+			value = value[min(offset, len(value)):]
+		}
+		if m[3] != "" {
+			length, _ := strconv.Atoi(m[3])
+			value = value[:min(length, len(value))]
+		}
+		return value
+	})
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", fmt.Errorf("unknown variable %s in %q", strings.Join(unknown, ", "), text)
+	}
+	if strings.Contains(out, "${") {
+		return "", fmt.Errorf("invalid reference in %q", text)
+	}
+	return out, nil
+}
+
+// applyVersionTemplate replaces the release version with cfg.VersionTemplate
+// rendered against the release, for nightly and branch builds. The result
+// must be strict semver.
+func applyVersionTemplate(cfg *Config, releaseCtx plugin.ReleaseContext, now time.Time) (plugin.ReleaseContext, error) {
+	if cfg.VersionTemplate == "" {
+		return releaseCtx, nil
+	}
+	version, err := renderVersionTemplate(cfg.VersionTemplate, versionTemplateVars(releaseCtx, now))
+	if err != nil {
+		return releaseCtx, fmt.Errorf("version_template: %w", err)
+	}
+	if err := checkStrictSemver(version); err != nil {
+		return releaseCtx, fmt.Errorf("version_template rendered %w", err)
+	}
+	releaseCtx.Version = version
+	return releaseCtx, nil
+}
+
+// checkStrictSemver rejects what parseSemver tolerates but is not strict
+// semver: a "v" prefix, surrounding space, empty identifiers and numbers
+// with leading zeros.
+func checkStrictSemver(version string) error {
+	m := semverRegexp.FindStringSubmatch(version)
+	if m == nil || strings.HasPrefix(version, "v") {
+		return fmt.Errorf("invalid semantic version %q", version)
+	}
+	identifiers := []string{m[1], m[2], m[3]}
+	if m[4] != "" {
+		identifiers = append(identifiers, strings.Split(m[4], ".")...)
+	}
+	for _, id := range identifiers {
+		if len(id) > 1 && id[0] == '0' && strings.Trim(id, "0123456789") == "" {
+			return fmt.Errorf("invalid semantic version %q: %s has a leading zero", version, id)
+		}
+	}
+	for _, part := range []string{m[4], m[5]} {
+		if part != "" && slices.Contains(strings.Split(part, "."), "") {
+			return fmt.Errorf("invalid semantic version %q: empty identifier", version)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestRenderVersionTemplate(t *testing.T) {
+	vars := map[string]string{"version": "1.2.0", "branch": "feature-login", "sha": "a1b2c3d4e5f6"}
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{text: "${version}-${branch}.${sha:0:7}", want: "1.2.0-feature-login.a1b2c3d"},
+		{text: "${version}+${sha:6}", want: "1.2.0+d4e5f6"},
+		{text: "${version}-${sha:4:100}", want: "1.2.0-c3d4e5f6"},
+		{text: "${version}-${sha:100}", want: "1.2.0-"},
+		{text: "${version}-${build}", wantErr: true},
+		{text: "${version}-${sha:x}", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := renderVersionTemplate(tt.text, vars)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("renderVersionTemplate(%q) = %q, %v, want %q", tt.text, got, err, tt.want)
+		}
+	}
+}
+
+func TestCheckStrictSemver(t *testing.T) {
+	tests := map[string]bool{
+		"1.2.0":                   true,
+		"1.2.0-main.a1b2c3d":      true,
+		"1.2.0-nightly.20240101":  true,
+		"1.2.0+build.7":           true,
+		"v1.2.0":                  false,
+		"1.02.0":                  false,
+		"1.2.0-nightly.0123":      false,
+		"1.2.0-":                  false,
+		"1.2.0-main..a1b2c3d":     false,
+		"1.2.0+":                  false,
+		"1.2.0-feature/login.abc": false,
+	}
+	for version, want := range tests {
+		if err := checkStrictSemver(version); (err == nil) != want {
+			t.Errorf("checkStrictSemver(%q) error = %v, want valid %v", version, err, want)
+		}
+	}
+}
+
+func TestApplyVersionTemplate(t *testing.T) {
+	now := time.Date(2024, 3, 9, 22, 15, 0, 0, time.FixedZone("PST", -8*3600))
+	releaseCtx := plugin.ReleaseContext{Version: "1.2.0", Branch: "feature/login", CommitSHA: "a1b2c3d4e5f6"}
+	tests := []struct {
+		template string
+		want     string
+		wantErr  string
+	}{
+		{template: "", want: "1.2.0"},
+		{template: "${version}-${branch}.${sha:0:7}", want: "1.2.0-feature-login.a1b2c3d"},
+		{template: "${version}-nightly.${date}", want: "1.2.0-nightly.20240310"},
+		{template: "${version}-nightly.0${date}", wantErr: `version_template rendered invalid semantic version "1.2.0-nightly.020240310": 020240310 has a leading zero`},
+		{template: "${version}.${sha:0:7}", wantErr: `version_template rendered invalid semantic version "1.2.0.a1b2c3d"`},
+	}
+	for _, tt := range tests {
+		got, err := applyVersionTemplate(&Config{VersionTemplate: tt.template}, releaseCtx, now)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("applyVersionTemplate(%q) = %q, %v, want %q", tt.template, got.Version, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got.Version != tt.want {
+			t.Errorf("applyVersionTemplate(%q) = %q, %v, want %q", tt.template, got.Version, err, tt.want)
+		}
+	}
+}
+
+func TestPublishVersionTemplate(t *testing.T) {
+	logPath := fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"lib","version":"1.2.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPrePublish,
+		Config:  map[string]any{"version_template": "${version}-${branch}.${sha:0:7}"},
+		Context: plugin.ReleaseContext{Version: "1.2.0", Branch: "main", CommitSHA: "f00dfacecafe"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	pkg, err := readPackageJSON(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Version != "1.2.0-main.f00dfac" {
+		t.Errorf("package.json version = %q", pkg.Version)
+	}
+	if calls := npmCalls(t, logPath); len(calls) != 0 {
+		t.Errorf("npm calls = %v", calls)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// workspaceStep runs one hook's work for a single package.
+type workspaceStep func(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error)
+
+// workspaceScope selects which workspace packages a run covers.
+type workspaceScope struct {
+	// changes, when set, skips packages it does not touch.
+	changes *releaseChanges
+	// fromPackage publishes each package at its package.json version and
+	// skips versions already in the registry, like
+	// `lerna publish from-package`.
+	fromPackage bool
+	// prepare, when set, adjusts a package's config before its step runs;
+	// an error fails the run at that package.
+	prepare func(pkg workspacePackage, cfg *Config) error
+}
+
+// validateWorkspaces checks the workspace patterns and rejects options that
+// name a single package or file and so cannot apply to several.
+func validateWorkspaces(cfg *Config) error {
+	if cfg.Lerna && cfg.Rush {
+		return fmt.Errorf("rush cannot be combined with lerna")
+	}
+	if len(cfg.Workspaces) == 0 {
+		return nil
+	}
+	for _, pattern := range cfg.Workspaces {
+		if err := validateWorkspacePattern(pattern); err != nil {
+			return err
+		}
+	}
+	switch {
+	case cfg.PublishPlan != "":
+		return fmt.Errorf("workspaces cannot be combined with publish_plan")
+	case cfg.Inputs.PackageDir != "" || cfg.Inputs.Tarball != "":
+		return fmt.Errorf("workspaces cannot be combined with inputs")
+	case missingManifestMode(cfg) == missingManifestGenerate:
+		return fmt.Errorf("workspaces cannot be combined with missing_manifest generate")
+	}
+	return nil
+}
+
+// validateWorkspacePattern checks that a workspace pattern is a valid glob
+// that stays inside the working directory.
+func validateWorkspacePattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("workspaces: empty pattern")
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("workspaces: invalid pattern %q: %w", pattern, err)
+	}
+	clean := filepath.Clean(pattern)
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("workspaces: pattern %q escapes the working directory", pattern)
+	}
+	return nil
+}
+
+// runWorkspaces runs step for every workspace package in dependency order,
+// stopping at the first failure so no dependent is published before its
+// dependencies. Packages outside scope are skipped.
+func (p *NpmPlugin) runWorkspaces(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool, scope workspaceScope, step workspaceStep) (*plugin.ExecuteResponse, error) {
+	if err := validateWorkspaces(cfg); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("configuration validation failed: %v", err),
+		}, nil
+	}
+	pkgs, err := loadWorkspacePackages(cfg.Workspaces)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	ordered, err := publishOrder(pkgs)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	order := make([]string, len(ordered))
+	for i, pkg := range ordered {
+		order[i] = pkg.Name
+	}
+	results := make([]map[string]any, 0, len(ordered))
+	unchanged := []string{}
+	var artifacts []plugin.Artifact
+	skipped := 0
+	skip := func(pkg workspacePackage, reason string) {
+		skipped++
+		results = append(results, map[string]any{
+			"name":        pkg.Name,
+			"dir":         pkg.Dir,
+			"success":     true,
+			"skipped":     true,
+			"skip_reason": reason,
+		})
+	}
+	for _, pkg := range ordered {
+		if !scope.changes.touches(pkg.Dir) {
+			unchanged = append(unchanged, pkg.Name)
+			skip(pkg, "unchanged")
+			continue
+		}
+		pkgCfg := *cfg
+		pkgCfg.PackageDir = pkg.Dir
+		defaults, err := p.applyPackageDefaults(&pkgCfg, pkg.Dir)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("%s: %v", pkg.Name, err),
+				Outputs: map[string]any{
+					"workspace_order": order,
+					"packages":        results,
+					"failed_package":  pkg.Name,
+				},
+			}, nil
+		}
+		if scope.prepare != nil {
+			if err := scope.prepare(pkg, &pkgCfg); err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("%s: %v", pkg.Name, err),
+					Outputs: map[string]any{
+						"workspace_order": order,
+						"packages":        results,
+						"failed_package":  pkg.Name,
+					},
+				}, nil
+			}
+		}
+		pkgCtx := releaseCtx
+		if scope.fromPackage && !pkg.Private {
+			published, err := publishedVersion(ctx, &pkgCfg, pkg.Name, pkg.Version)
+			if err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("%s: failed to check the registry: %v", pkg.Name, err),
+				}, nil
+			}
+			if published {
+				skip(pkg, "already_published")
+				continue
+			}
+			pkgCtx.Version = pkg.Version
+		}
+		resp, err := step(ctx, &pkgCfg, pkgCtx, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pkg.Name, err)
+		}
+		if len(defaults) > 0 {
+			setOutput(resp, "package_defaults", defaults)
+		}
+		result := map[string]any{
+			"name":    pkg.Name,
+			"dir":     pkg.Dir,
+			"success": resp.Success,
+			"outputs": resp.Outputs,
+		}
+		if resp.Message != "" {
+			result["message"] = resp.Message
+		}
+		if resp.Outputs["skipped"] == true {
+			skipped++
+			result["skipped"] = true
+			result["skip_reason"] = resp.Outputs["skip_reason"]
+		}
+		results = append(results, result)
+		artifacts = append(artifacts, resp.Artifacts...)
+		if !resp.Success {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("%s: %s", pkg.Name, resp.Error),
+				Outputs: map[string]any{
+					"workspace_order": order,
+					"packages":        results,
+					"failed_package":  pkg.Name,
+				},
+			}, nil
+		}
+	}
+
+	resp := &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Processed %d workspace packages in dependency order: %s", len(ordered)-skipped, strings.Join(order, ", ")),
+		Outputs: map[string]any{
+			"workspace_order": order,
+			"packages":        results,
+		},
+		Artifacts: artifacts,
+	}
+	if scope.changes != nil {
+		resp.Outputs["changed_since"] = scope.changes.Base
+		resp.Outputs["unchanged_packages"] = unchanged
+	}
+	return resp, nil
+}
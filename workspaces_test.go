@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+func TestValidateWorkspaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"unset", Config{}, false},
+		{"globs", Config{Workspaces: []string{"packages/*", "tools/cli"}}, false},
+		{"bad glob", Config{Workspaces: []string{"packages/["}}, true},
+		{"escapes", Config{Workspaces: []string{"../other/*"}}, true},
+		{"absolute", Config{Workspaces: []string{"/srv/pkg"}}, true},
+		{"publish plan", Config{Workspaces: []string{"packages/*"}, PublishPlan: "plan.json"}, true},
+		{"tarball input", Config{Workspaces: []string{"packages/*"}, Inputs: PluginInputs{Tarball: "TARBALL"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateWorkspaces(&tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateWorkspaces() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWorkspacesPostPublish(t *testing.T) {
+	logPath := fakeNpm(t, `[ "$1" = publish ] && basename "$PWD" >> "$(dirname "$0")/published.log"
+echo '{}'`)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	chdir(t, dir)
+	writeFile(t, filepath.Join(dir, "packages", "a-app", "package.json"), `{"name":"a-app","version":"1.0.0","dependencies":{"z-core":"^1.0.0"}}`)
+	writeFile(t, filepath.Join(dir, "packages", "z-core", "package.json"), `{"name":"z-core","version":"1.0.0"}`)
+	server := newTestRegistry(t, map[string]*packument{})
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"workspaces": []any{"packages/*"}, "registry": server.URL},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if order := resp.Outputs["workspace_order"]; !reflect.DeepEqual(order, []string{"z-core", "a-app"}) {
+		t.Errorf("workspace_order = %v", order)
+	}
+	published := npmCalls(t, filepath.Join(filepath.Dir(logPath), "published.log"))
+	if !reflect.DeepEqual(published, []string{"z-core", "a-app"}) {
+		t.Errorf("published = %v, want dependencies first", published)
+	}
+}
+
+func TestWorkspacesCycle(t *testing.T) {
+	fakeNpm(t, `echo '{}'`)
+	dir := t.TempDir()
+	chdir(t, dir)
+	writeFile(t, filepath.Join(dir, "packages", "a", "package.json"), `{"name":"a","dependencies":{"b":"*"}}`)
+	writeFile(t, filepath.Join(dir, "packages", "b", "package.json"), `{"name":"b","peerDependencies":{"a":"*"}}`)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"workspaces": []any{"packages/*"}},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil || resp.Success || !strings.Contains(resp.Error, "a -> b -> a") {
+		t.Errorf("expected a cycle error: %v %+v", err, resp)
+	}
+}
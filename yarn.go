@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// Tools that can apply the release version to package.json.
+const (
+	versionToolNpm  = "npm"
+	versionToolYarn = "yarn"
+	versionToolAuto = "auto"
+)
+
+// yarnConstraintsFiles configure Yarn constraints: yarn.config.cjs from Yarn
+// 4, constraints.pro before it.
+var yarnConstraintsFiles = []string{"yarn.config.cjs", "constraints.pro"}
+
+// isYarnBerry reports whether the project in the working directory uses Yarn
+// 2 or later: it has a .yarnrc.yml, or package.json pins yarn >= 2 in
+// packageManager.
+func isYarnBerry() bool {
+	if _, err := os.Stat(".yarnrc.yml"); err == nil {
+		return true
+	}
+	data, err := os.ReadFile("package.json")
+	if err != nil {
+		return false
+	}
+	var pkg struct {
+		PackageManager string `json:"packageManager"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return false
+	}
+	version, ok := strings.CutPrefix(pkg.PackageManager, "yarn@")
+	if !ok {
+		return false
+	}
+	v, err := parseSemver(strings.SplitN(version, "+", 2)[0])
+	return err == nil && v.Major >= 2
+}
+
+// useYarnVersion reports whether the version update goes through Yarn's
+// version plugin.
+func useYarnVersion(cfg *Config) bool {
+	switch cfg.VersionTool {
+	case versionToolYarn:
+		return true
+	case versionToolAuto:
+		return isYarnBerry()
+	}
+	return false
+}
+
+// hasYarnConstraints reports whether the project defines Yarn constraints.
+func hasYarnConstraints() bool {
+	for _, name := range yarnConstraintsFiles {
+		if _, err := os.Stat(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// runYarn runs yarn with args in dir. Yarn reports errors on stdout, so both
+// streams are included in the error.
+func runYarn(ctx context.Context, dir string, args ...string) (string, error) {
+	out, err := runCommand(ctx, dir, "yarn", args, args[0])
+	var npmErr *npmError
+	if errors.As(err, &npmErr) {
+		return out, fmt.Errorf("yarn %s failed: %v\n%s", strings.Join(args, " "), npmErr.Err, strings.TrimSpace(out+"\n"+npmErr.Stderr))
+	}
+	return out, err
+}
+
+// checkYarnConstraints runs `yarn constraints` from the project root when
+// constraints are defined, returning "passed" or "not_configured".
+func checkYarnConstraints(ctx context.Context) (string, error) {
+	if !hasYarnConstraints() {
+		return "not_configured", nil
+	}
+	if _, err := runYarn(ctx, ".", "constraints"); err != nil {
+		return "", err
+	}
+	return "passed", nil
+}
+
+// yarnVersion applies the release version through Yarn's version plugin
+// instead of editing package.json: the version is recorded as a deferred bump
+// and applied with `yarn version apply`, which also updates the ranges other
+// workspaces use for the package. Constraints are checked afterwards so the
+// bumped manifests still satisfy them.
+func (p *NpmPlugin) yarnVersion(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
+	packageDir, err := validatePackageDir(cfg.PackageDir)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid package directory: %v", err),
+		}, nil
+	}
+	pkg, err := readPackageJSON(packageDir)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	if err := checkCurrentVersion(cfg, releaseCtx, pkg.Version); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	if dryRun {
+		constraints, err := checkYarnConstraints(ctx)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("Would apply version %s to %s with yarn version", releaseCtx.Version, pkg.Name),
+			Outputs: map[string]any{
+				"version_tool":     versionToolYarn,
+				"yarn_constraints": constraints,
+			},
+		}, nil
+	}
+
+	if pkg.Version != releaseCtx.Version {
+		if _, err := runYarn(ctx, packageDir, "version", releaseCtx.Version, "--deferred"); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		if _, err := runYarn(ctx, packageDir, "version", "apply"); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+	}
+	constraints, err := checkYarnConstraints(ctx)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Applied version %s with yarn version", releaseCtx.Version),
+		Outputs: map[string]any{
+			"old_version":      pkg.Version,
+			"new_version":      releaseCtx.Version,
+			"version_tool":     versionToolYarn,
+			"yarn_constraints": constraints,
+		},
+	}, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// fakeYarn puts a yarn script running body on PATH and returns its log path.
+func fakeYarn(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake yarn script requires a POSIX shell")
+	}
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "yarn.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + logPath + "\n" + body + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "yarn"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake yarn: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+func TestIsYarnBerry(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  bool
+	}{
+		{"yarnrc", map[string]string{".yarnrc.yml": "nodeLinker: node-modules\n"}, true},
+		{"package manager 4", map[string]string{"package.json": `{"packageManager":"yarn@4.1.0+sha256.abc"}`}, true},
+		{"classic", map[string]string{"package.json": `{"packageManager":"yarn@1.22.19"}`}, false},
+		{"pnpm", map[string]string{"package.json": `{"packageManager":"pnpm@9.0.0"}`}, false},
+		{"plain npm", map[string]string{"package.json": `{"name":"pkg"}`}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+			chdir(t, dir)
+			if got := isYarnBerry(); got != tt.want {
+				t.Errorf("isYarnBerry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYarnVersion(t *testing.T) {
+	logPath := fakeYarn(t, `echo "➤ YN0000: done"`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".yarnrc.yml"), "nodeLinker: node-modules\n")
+	writeFile(t, filepath.Join(dir, "yarn.config.cjs"), "module.exports = {}\n")
+	writeFile(t, filepath.Join(dir, "packages", "core", "package.json"), `{"name":"core","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPrePublish,
+		Config:  map[string]any{"version_tool": "auto", "package_dir": "packages/core"},
+		Context: plugin.ReleaseContext{Version: "1.1.0"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("unexpected failure: %v %+v", err, resp)
+	}
+	if resp.Outputs["version_tool"] != "yarn" || resp.Outputs["yarn_constraints"] != "passed" {
+		t.Errorf("outputs = %v", resp.Outputs)
+	}
+	want := []string{"version 1.1.0 --deferred", "version apply", "constraints"}
+	if calls := npmCalls(t, logPath); !reflect.DeepEqual(calls, want) {
+		t.Errorf("yarn calls = %v, want %v", calls, want)
+	}
+}
+
+func TestYarnVersionConstraintsFailure(t *testing.T) {
+	fakeYarn(t, `[ "$1" = constraints ] && { echo "➤ YN0000: core must depend on tslib"; exit 1; }
+exit 0`)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "constraints.pro"), "% constraints\n")
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"core","version":"1.0.0"}`)
+	chdir(t, dir)
+
+	resp, err := (&NpmPlugin{}).Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPrePublish,
+		Config:  map[string]any{"version_tool": "yarn"},
+		Context: plugin.ReleaseContext{Version: "1.1.0"},
+		DryRun:  true,
+	})
+	if err != nil || resp.Success {
+		t.Fatalf("expected a constraints failure: %v %+v", err, resp)
+	}
+	if !strings.Contains(resp.Error, "yarn constraints failed") || !strings.Contains(resp.Error, "must depend on tslib") {
+		t.Errorf("Error = %q", resp.Error)
+	}
+}